@@ -17,29 +17,61 @@ package actions
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/Netflix/p2plab"
 	"github.com/Netflix/p2plab/metadata"
 	cid "github.com/ipfs/go-cid"
 )
 
+// Parse returns the action named a, see Split.
 func Parse(objects map[string]cid.Cid, a string) (p2plab.Action, error) {
-	return &dummyAction{objects[a].String()}, nil
+	if _, ok := objects[a]; ok {
+		return &dummyAction{metadata.TaskGet, objects[a].String()}, nil
+	}
+
+	taskType, object := Split(a)
+	if object == "" {
+		return &dummyAction{taskType, ""}, nil
+	}
+	return &dummyAction{taskType, objects[object].String()}, nil
+}
+
+// Split returns the task type and the object of an action, which is either the
+// name of an object to get, or a task type of get, pin, unpin or gc followed by
+// the name of an object, such as "pin image". The actions "drop-cache" and
+// "gc" have no object, gc then keeps only pinned content.
+func Split(a string) (metadata.TaskType, string) {
+	fields := strings.Fields(a)
+	if len(fields) == 1 {
+		switch taskType := metadata.TaskType(fields[0]); taskType {
+		case metadata.TaskDropCache, metadata.TaskGC:
+			return taskType, ""
+		}
+	}
+	if len(fields) == 2 {
+		switch taskType := metadata.TaskType(fields[0]); taskType {
+		case metadata.TaskGet, metadata.TaskPin, metadata.TaskUnpin, metadata.TaskGC:
+			return taskType, fields[1]
+		}
+	}
+	return metadata.TaskGet, a
 }
 
 type dummyAction struct {
-	subject string
+	taskType metadata.TaskType
+	subject  string
 }
 
 func (a *dummyAction) String() string {
-	return fmt.Sprintf("get %q", a.subject)
+	return fmt.Sprintf("%s %q", a.taskType, a.subject)
 }
 
 func (a *dummyAction) Tasks(ctx context.Context, ns []p2plab.Node) (map[string]metadata.Task, error) {
 	taskMap := make(map[string]metadata.Task)
 	for _, n := range ns {
 		taskMap[n.Metadata().ID] = metadata.Task{
-			Type:    metadata.TaskGet,
+			Type:    a.taskType,
 			Subject: a.subject,
 		}
 	}
@@ -0,0 +1,107 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package p2plab
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/Netflix/p2plab/metadata"
+)
+
+// AdminAPI defines API for administering labd.
+type AdminAPI interface {
+	// Backup streams a consistent snapshot of labd's metadata store.
+	Backup(ctx context.Context) (io.ReadCloser, error)
+
+	// Restore replaces labd's metadata store with a backup.
+	Restore(ctx context.Context, r io.Reader, opts ...RestoreOption) error
+
+	// Logs streams the recent structured logs of labd as newline-delimited
+	// zerolog events.
+	Logs(ctx context.Context, opts ...DaemonLogsOption) (io.ReadCloser, error)
+
+	// Audit returns the records of requests that modified resources, in the
+	// order they were made.
+	Audit(ctx context.Context, opts ...AuditOption) ([]metadata.AuditRecord, error)
+}
+
+type RestoreOption func(*RestoreSettings) error
+
+type RestoreSettings struct {
+	Force bool
+}
+
+// WithRestoreForce overwrites a metadata store that is not empty.
+func WithRestoreForce() RestoreOption {
+	return func(s *RestoreSettings) error {
+		s.Force = true
+		return nil
+	}
+}
+
+// DaemonLogsOption is an option to modify daemon logs settings.
+type DaemonLogsOption func(*DaemonLogsSettings) error
+
+// DaemonLogsSettings specify which logs of labd are streamed.
+type DaemonLogsSettings struct {
+	// Level is the minimum level of the logs streamed, defaulting to all
+	// levels.
+	Level string
+
+	// Since excludes logs written before it.
+	Since time.Time
+
+	// Follow keeps the stream open for new logs.
+	Follow bool
+}
+
+func WithDaemonLogsLevel(level string) DaemonLogsOption {
+	return func(s *DaemonLogsSettings) error {
+		s.Level = level
+		return nil
+	}
+}
+
+func WithDaemonLogsSince(since time.Time) DaemonLogsOption {
+	return func(s *DaemonLogsSettings) error {
+		s.Since = since
+		return nil
+	}
+}
+
+func WithDaemonLogsFollow() DaemonLogsOption {
+	return func(s *DaemonLogsSettings) error {
+		s.Follow = true
+		return nil
+	}
+}
+
+// AuditOption is an option to modify audit settings.
+type AuditOption func(*AuditSettings) error
+
+// AuditSettings specify which audit records are returned.
+type AuditSettings struct {
+	// Since excludes records of requests made before it.
+	Since time.Time
+}
+
+func WithAuditSince(since time.Time) AuditOption {
+	return func(s *AuditSettings) error {
+		s.Since = since
+		return nil
+	}
+}
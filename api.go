@@ -16,6 +16,8 @@ package p2plab
 
 import (
 	"context"
+	"io"
+	"time"
 
 	"github.com/Netflix/p2plab/metadata"
 	peerstore "github.com/libp2p/go-libp2p-peerstore"
@@ -37,12 +39,41 @@ type ControlAPI interface {
 
 	// Experiment returns an implementation of Experiment API.
 	Experiment() ExperimentAPI
+
+	// Admin returns an implementation of Admin API.
+	Admin() AdminAPI
+
+	// Health returns the status of labd and of the components it depends on.
+	Health(ctx context.Context) (metadata.Health, error)
 }
 
 type AgentAPI interface {
+	// Healthcheck waits for the agent to be reachable and returns whether it is
+	// ready.
 	Healthcheck(ctx context.Context) bool
 
-	Update(ctx context.Context, id, link string, pdef metadata.PeerDefinition) error
+	// Health returns the status of the agent and of the p2p app it supervises.
+	Health(ctx context.Context) (metadata.Health, error)
+
+	Update(ctx context.Context, id, link string, pdef metadata.PeerDefinition, opts ...UpdateOption) error
+
+	// ShapeNetwork replaces the network impairments of the node. The zero spec
+	// resets the network to its baseline. Returns errdefs.ErrNotImplemented if
+	// the node doesn't support network shaping.
+	ShapeNetwork(ctx context.Context, spec metadata.NetworkSpec) error
+
+	// Exec runs a command on the node, streaming its combined stdout and stderr
+	// to w, and returns its exit code.
+	Exec(ctx context.Context, args []string, w io.Writer) (int, error)
+
+	// Logs streams the output of the p2p app as newline-delimited lines,
+	// returning the sequence number of the first line in the stream.
+	Logs(ctx context.Context, opts ...LogsOption) (uint64, io.ReadCloser, error)
+
+	// SampleResources samples the CPU and memory usage of the p2p app every
+	// interval until the context is done, and returns the samples. Returns
+	// errdefs.ErrNotImplemented if the node doesn't support sampling.
+	SampleResources(ctx context.Context, interval time.Duration) (metadata.ResourceUsage, error)
 
 	// SSH creates a SSH connection to the node.
 	SSH(ctx context.Context, opts ...SSHOption) error
@@ -53,6 +84,30 @@ type AppAPI interface {
 
 	Report(ctx context.Context) (metadata.ReportNode, error)
 
+	// Stats returns a snapshot of the repo and network of the node, cheap
+	// enough to be polled frequently.
+	Stats(ctx context.Context) (metadata.PeerStats, error)
+
 	// Run executes an task on the node.
 	Run(ctx context.Context, task metadata.Task) error
+
+	// RunAsync queues a task to be executed on the node, and returns its
+	// status without waiting for it to finish.
+	RunAsync(ctx context.Context, task metadata.Task) (metadata.TaskStatus, error)
+
+	// GetTask returns the status of a task queued with RunAsync.
+	GetTask(ctx context.Context, id string) (metadata.TaskStatus, error)
+
+	// Connect dials the peers.
+	Connect(ctx context.Context, peerInfos []peerstore.PeerInfo) error
+
+	// Disconnect hangs up on the peers.
+	Disconnect(ctx context.Context, peerInfos []peerstore.PeerInfo) error
+
+	// Ping dials the peer and returns the round trip times of count pings.
+	Ping(ctx context.Context, peerInfo peerstore.PeerInfo, count int) ([]time.Duration, error)
+
+	// BenchLink sends a synthetic payload of the given size to the peer, and
+	// returns how long it took the peer to receive it.
+	BenchLink(ctx context.Context, peerInfo peerstore.PeerInfo, size int64) (time.Duration, error)
 }
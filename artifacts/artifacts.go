@@ -0,0 +1,104 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package artifacts
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/artifacts/filestore"
+	"github.com/Netflix/p2plab/artifacts/s3store"
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/metadata"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+)
+
+// New returns the artifact store at the uri, either "s3://bucket/prefix" or
+// "file:///path". An empty uri stores artifacts on the local filesystem under
+// root. S3 credentials are resolved from the environment.
+func New(root, uri string, client *http.Client) (p2plab.ArtifactStore, error) {
+	if uri == "" {
+		return filestore.New(filepath.Join(root, "artifacts"))
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "invalid artifact store %q", uri)
+	}
+
+	switch u.Scheme {
+	case "file":
+		return filestore.New(u.Path)
+	case "s3":
+		if u.Host == "" {
+			return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "artifact store %q is missing a bucket", uri)
+		}
+		return s3store.New(client, u.Host, strings.Trim(u.Path, "/"))
+	default:
+		return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "unrecognized artifact store %q, must be s3:// or file://", uri)
+	}
+}
+
+// Upload stores the content as an artifact of the benchmark, recording its
+// size and digest so that downloads can be verified.
+func Upload(ctx context.Context, store p2plab.ArtifactStore, bid, name string, r io.Reader) (metadata.Artifact, error) {
+	artifact := metadata.Artifact{
+		Name: name,
+	}
+
+	err := metadata.ValidateArtifactName(name)
+	if err != nil {
+		return artifact, err
+	}
+
+	// Spool the content to learn its size and digest before it is stored, as
+	// artifacts may not fit in memory.
+	f, err := ioutil.TempFile("", "p2plab-artifact")
+	if err != nil {
+		return artifact, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	digester := digest.Canonical.Digester()
+	artifact.Size, err = io.Copy(io.MultiWriter(f, digester.Hash()), r)
+	if err != nil {
+		return artifact, err
+	}
+	artifact.Digest = digester.Digest()
+
+	_, err = f.Seek(0, io.SeekStart)
+	if err != nil {
+		return artifact, err
+	}
+
+	artifact.Link, err = store.Put(ctx, path.Join(bid, name), f)
+	if err != nil {
+		return artifact, errors.Wrapf(err, "failed to store artifact %q", name)
+	}
+	artifact.CreatedAt = time.Now()
+
+	return artifact, nil
+}
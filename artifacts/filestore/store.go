@@ -0,0 +1,115 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filestore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/pkg/errors"
+)
+
+type store struct {
+	root string
+}
+
+// New returns an artifact store keeping artifacts under the root directory.
+func New(root string) (p2plab.ArtifactStore, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.MkdirAll(root, 0711)
+	if err != nil {
+		return nil, err
+	}
+
+	return &store{root}, nil
+}
+
+func (s *store) Close() error {
+	return nil
+}
+
+func (s *store) Put(ctx context.Context, key string, r io.Reader) (link string, err error) {
+	target := filepath.Join(s.root, filepath.FromSlash(key))
+	err = os.MkdirAll(filepath.Dir(target), 0711)
+	if err != nil {
+		return "", err
+	}
+
+	// Write to a temporary file so that a failed write never leaves a partial
+	// artifact behind.
+	f, err := ioutil.TempFile(filepath.Dir(target), ".artifact")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	_, err = io.Copy(f, r)
+	if err != nil {
+		return "", err
+	}
+
+	err = f.Close()
+	if err != nil {
+		return "", err
+	}
+
+	err = os.Rename(f.Name(), target)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("file://%s", target), nil
+}
+
+func (s *store) Get(ctx context.Context, link string, offset int64) (io.ReadCloser, error) {
+	u, err := url.Parse(link)
+	if err != nil || u.Scheme != "file" {
+		return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "invalid file artifact link %q", link)
+	}
+
+	target := filepath.Clean(u.Path)
+	if !strings.HasPrefix(target, s.root+string(filepath.Separator)) {
+		return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "artifact link %q is outside of the store", link)
+	}
+
+	f, err := os.Open(target)
+	if os.IsNotExist(err) {
+		return nil, errors.Wrapf(errdefs.ErrNotFound, "artifact %q", link)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = f.Seek(offset, io.SeekStart)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return f, nil
+}
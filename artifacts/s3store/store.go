@@ -0,0 +1,105 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3store
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/pkg/logutil"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/external"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/s3manager"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+type store struct {
+	bucket        string
+	prefix        string
+	client        *s3.Client
+	uploadManager *s3manager.Uploader
+}
+
+// New returns an artifact store keeping artifacts in an S3 bucket under the
+// prefix. The credentials and region are resolved from the environment.
+func New(client *http.Client, bucket, prefix string) (p2plab.ArtifactStore, error) {
+	cfg, err := external.LoadDefaultAWSConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load aws config")
+	}
+	cfg.HTTPClient = client
+
+	return &store{
+		bucket:        bucket,
+		prefix:        prefix,
+		client:        s3.New(cfg),
+		uploadManager: s3manager.NewUploader(cfg),
+	}, nil
+}
+
+func (s *store) Close() error {
+	return nil
+}
+
+func (s *store) Put(ctx context.Context, key string, r io.Reader) (link string, err error) {
+	key = path.Join(s.prefix, key)
+	logger := zerolog.Ctx(ctx).With().Str("bucket", s.bucket).Str("key", key).Logger()
+	ectx, cancel := context.WithCancel(logger.WithContext(ctx))
+	defer cancel()
+
+	go logutil.Elapsed(ectx, 20*time.Second, "Uploading S3 artifact")
+
+	_, err = s.uploadManager.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Body:   r,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("s3://%s/%s", s.bucket, key), nil
+}
+
+func (s *store) Get(ctx context.Context, link string, offset int64) (io.ReadCloser, error) {
+	u, err := url.Parse(link)
+	if err != nil || u.Scheme != "s3" || len(u.Path) == 0 {
+		return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "invalid s3 artifact link %q", link)
+	}
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(u.Host),
+		Key:    aws.String(u.Path[1:]),
+	}
+	if offset > 0 {
+		input.Range = aws.String(fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := s.client.GetObjectRequest(input).Send(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
@@ -16,8 +16,11 @@ package p2plab
 
 import (
 	"context"
+	"io"
 
+	"github.com/Netflix/p2plab/errdefs"
 	"github.com/Netflix/p2plab/metadata"
+	"github.com/pkg/errors"
 )
 
 // BenchmarkAPI defines API for benchmark operations.
@@ -25,6 +28,10 @@ type BenchmarkAPI interface {
 	// Create creates a benchmark of a scenario on a cluster.
 	Create(ctx context.Context, cluster, scenario string, opts ...StartBenchmarkOption) (id string, err error)
 
+	// DryRun validates a benchmark of a scenario on a cluster, resolving its
+	// queries and transforming its objects, without executing any task.
+	DryRun(ctx context.Context, cluster, scenario string) (metadata.DryRun, error)
+
 	// Get returns a benchmark.
 	Get(ctx context.Context, id string) (Benchmark, error)
 
@@ -33,7 +40,30 @@ type BenchmarkAPI interface {
 	// List returns available benchmarks.
 	List(ctx context.Context, opts ...ListOption) ([]Benchmark, error)
 
-	Remove(ctx context.Context, ids ...string) error
+	// Remove deletes benchmarks and their reports. Benchmarks that are trials
+	// of an experiment are not removed unless cascaded or forced.
+	Remove(ctx context.Context, ids []string, opts ...RemoveOption) error
+
+	// Cancel stops a running benchmark.
+	Cancel(ctx context.Context, id string) error
+
+	// Retry re-executes the benchmark on the nodes that failed.
+	Retry(ctx context.Context, id string) error
+
+	// Watch streams the progress of a benchmark as newline-delimited JSON
+	// metadata.BenchmarkEvent until it finishes, starting from the event with
+	// sequence number since. It returns the sequence number of the first event
+	// in the stream, which is greater than since if events were discarded.
+	Watch(ctx context.Context, id string, since uint64) (uint64, io.ReadCloser, error)
+
+	// Artifacts returns the artifacts stored for a benchmark.
+	Artifacts(ctx context.Context, id string) ([]metadata.Artifact, error)
+
+	// DownloadArtifact streams the content of a benchmark artifact from the
+	// byte at offset, returning the offset the stream actually starts at. When
+	// the stream starts at zero, reading it to the end fails if the content
+	// doesn't match its digest, otherwise the caller must verify the content.
+	DownloadArtifact(ctx context.Context, id, name string, offset int64) (int64, io.ReadCloser, error)
 }
 
 // Benchmark is an execution of a scenario on a cluster.
@@ -48,7 +78,15 @@ type Benchmark interface {
 type StartBenchmarkOption func(*StartBenchmarkSettings) error
 
 type StartBenchmarkSettings struct {
-	NoReset bool
+	NoReset        bool
+	MaxConcurrency int
+	IdempotencyKey string
+	NotifyURL      string
+	Parameters     map[string]string
+
+	// Tags are key=value labels added to the benchmark, so that it can be
+	// found by queries.
+	Tags []string
 }
 
 func WithBenchmarkNoReset() StartBenchmarkOption {
@@ -57,3 +95,57 @@ func WithBenchmarkNoReset() StartBenchmarkOption {
 		return nil
 	}
 }
+
+// WithBenchmarkIdempotencyKey sets a key identifying the request, so that
+// retrying it returns the benchmark already created instead of starting a new
+// one.
+func WithBenchmarkIdempotencyKey(key string) StartBenchmarkOption {
+	return func(s *StartBenchmarkSettings) error {
+		s.IdempotencyKey = key
+		return nil
+	}
+}
+
+// WithBenchmarkNotifyURL sets the webhook notified when the benchmark
+// finishes, instead of the default of the daemon.
+func WithBenchmarkNotifyURL(url string) StartBenchmarkOption {
+	return func(s *StartBenchmarkSettings) error {
+		s.NotifyURL = url
+		return nil
+	}
+}
+
+// WithBenchmarkMaxConcurrency overrides how many nodes of the scenario execute
+// a stage in parallel.
+func WithBenchmarkMaxConcurrency(n int) StartBenchmarkOption {
+	return func(s *StartBenchmarkSettings) error {
+		if n < 0 {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "max concurrency must not be negative, got %d", n)
+		}
+		s.MaxConcurrency = n
+		return nil
+	}
+}
+
+// WithBenchmarkTags adds key=value tags to the benchmark.
+func WithBenchmarkTags(tags ...string) StartBenchmarkOption {
+	return func(s *StartBenchmarkSettings) error {
+		for _, tag := range tags {
+			_, _, err := metadata.ParseKeyValueLabel(tag)
+			if err != nil {
+				return err
+			}
+		}
+		s.Tags = append(s.Tags, tags...)
+		return nil
+	}
+}
+
+// WithBenchmarkParameters records the template variables the scenario was
+// expanded with on the benchmark.
+func WithBenchmarkParameters(params map[string]string) StartBenchmarkOption {
+	return func(s *StartBenchmarkSettings) error {
+		s.Parameters = params
+		return nil
+	}
+}
@@ -16,8 +16,12 @@ package p2plab
 
 import (
 	"context"
+	"encoding/json"
+	"time"
 
+	"github.com/Netflix/p2plab/errdefs"
 	"github.com/Netflix/p2plab/metadata"
+	"github.com/pkg/errors"
 )
 
 // ClusterAPI defines API for cluster operations.
@@ -34,8 +38,15 @@ type ClusterAPI interface {
 	// List returns available clusters.
 	List(ctx context.Context, opts ...ListOption) ([]Cluster, error)
 
-	// Remove destroys clusters permanently.
-	Remove(ctx context.Context, names ...string) error
+	// Scale adds or removes nodes until the cluster has the given size.
+	Scale(ctx context.Context, name string, size int) error
+
+	// Extend pushes out the expiry of a cluster by the given TTL.
+	Extend(ctx context.Context, name string, ttl time.Duration) (Cluster, error)
+
+	// Remove destroys clusters permanently. Clusters that benchmarks ran on
+	// are not removed unless cascaded or forced.
+	Remove(ctx context.Context, names []string, opts ...RemoveOption) error
 }
 
 // Cluster is a group of instances connected in a p2p network. They can be
@@ -60,7 +71,24 @@ type CreateClusterSettings struct {
 	Size              int
 	InstanceType      string
 	Region            string
+	Provider          string
+	CPUs              string
+	Memory            string
+	Network           string
+	Stack             metadata.StackDefinition
 	ClusterDefinition metadata.ClusterDefinition
+
+	// Labels are key=value labels added to the cluster, so that it can be
+	// selected by queries.
+	Labels []string
+
+	// TTL is how long until the cluster is destroyed by labd, or zero if the
+	// cluster never expires.
+	TTL time.Duration
+
+	// NoWait returns once the cluster is created without waiting for its nodes
+	// to be ready, whose states are then polled from their metadata.
+	NoWait bool
 }
 
 func WithClusterDefinition(definition string) CreateClusterOption {
@@ -70,6 +98,15 @@ func WithClusterDefinition(definition string) CreateClusterOption {
 	}
 }
 
+// WithClusterDefinitionValue creates the cluster from a cluster definition
+// already parsed, instead of a definition file.
+func WithClusterDefinitionValue(cdef metadata.ClusterDefinition) CreateClusterOption {
+	return func(s *CreateClusterSettings) error {
+		s.ClusterDefinition = cdef
+		return nil
+	}
+}
+
 func WithClusterSize(size int) CreateClusterOption {
 	return func(s *CreateClusterSettings) error {
 		s.Size = size
@@ -91,10 +128,100 @@ func WithClusterRegion(region string) CreateClusterOption {
 	}
 }
 
+// WithClusterResources constrains the CPUs and memory of each node, for
+// providers that run nodes as containers.
+func WithClusterResources(cpus, memory string) CreateClusterOption {
+	return func(s *CreateClusterSettings) error {
+		s.CPUs = cpus
+		s.Memory = memory
+		return nil
+	}
+}
+
+// WithClusterNetwork attaches nodes to a named network, for providers that run
+// nodes as containers.
+func WithClusterNetwork(network string) CreateClusterOption {
+	return func(s *CreateClusterSettings) error {
+		s.Network = network
+		return nil
+	}
+}
+
+// WithClusterProvider overrides the node provider used to create the cluster.
+func WithClusterProvider(provider string) CreateClusterOption {
+	return func(s *CreateClusterSettings) error {
+		s.Provider = provider
+		return nil
+	}
+}
+
+// WithClusterStack overrides the libp2p transports, muxers and security
+// transports of every node group.
+func WithClusterStack(stack metadata.StackDefinition) CreateClusterOption {
+	return func(s *CreateClusterSettings) error {
+		s.Stack = stack
+		return nil
+	}
+}
+
+// WithClusterTTL destroys the cluster once the TTL runs out, unless the
+// cluster is extended.
+func WithClusterTTL(ttl time.Duration) CreateClusterOption {
+	return func(s *CreateClusterSettings) error {
+		if ttl < 0 {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid ttl %s", ttl)
+		}
+		s.TTL = ttl
+		return nil
+	}
+}
+
+// WithClusterNoWait returns from Create without waiting for the nodes of the
+// cluster to be ready.
+func WithClusterNoWait() CreateClusterOption {
+	return func(s *CreateClusterSettings) error {
+		s.NoWait = true
+		return nil
+	}
+}
+
+// WithClusterLabels adds key=value labels to the cluster. Labels may not use
+// the reserved metadata.NodeLabelPrefix.
+func WithClusterLabels(labels ...string) CreateClusterOption {
+	return func(s *CreateClusterSettings) error {
+		for _, label := range labels {
+			_, _, err := metadata.ParseKeyValueLabel(label)
+			if err != nil {
+				return err
+			}
+		}
+		err := metadata.ValidateUserLabels(labels)
+		if err != nil {
+			return err
+		}
+		s.Labels = append(s.Labels, labels...)
+		return nil
+	}
+}
+
 type ListOption func(*ListSettings) error
 
 type ListSettings struct {
-	Query string
+	Query     string
+	Limit     int
+	Offset    int
+	PageToken string
+	OrderBy   string
+	Desc      bool
+	Fields    []string
+
+	// NextPageToken receives the token to request the next page, which is
+	// empty when there are no more results.
+	NextPageToken *string
+
+	// Records receives every result as it is streamed, instead of the results
+	// being returned once the list is complete.
+	Records func(record json.RawMessage) error
 }
 
 func WithQuery(q string) ListOption {
@@ -104,6 +231,94 @@ func WithQuery(q string) ListOption {
 	}
 }
 
+// WithLimit limits the number of results, zero means no limit unless labd
+// caps the page size.
+func WithLimit(limit int) ListOption {
+	return func(s *ListSettings) error {
+		s.Limit = limit
+		return nil
+	}
+}
+
+// WithOffset skips a number of results.
+func WithOffset(offset int) ListOption {
+	return func(s *ListSettings) error {
+		s.Offset = offset
+		return nil
+	}
+}
+
+// WithPageToken continues a paginated list from the page token of a previous
+// list.
+func WithPageToken(token string) ListOption {
+	return func(s *ListSettings) error {
+		s.PageToken = token
+		return nil
+	}
+}
+
+// WithOrderBy orders the results by a field, in descending order if desc is
+// true.
+func WithOrderBy(field string, desc bool) ListOption {
+	return func(s *ListSettings) error {
+		s.OrderBy = field
+		s.Desc = desc
+		return nil
+	}
+}
+
+// WithFields requests only the given fields of the results.
+func WithFields(fields ...string) ListOption {
+	return func(s *ListSettings) error {
+		s.Fields = fields
+		return nil
+	}
+}
+
+// WithNextPageToken stores the token to request the next page in token.
+func WithNextPageToken(token *string) ListOption {
+	return func(s *ListSettings) error {
+		s.NextPageToken = token
+		return nil
+	}
+}
+
+// WithRecords streams the results to fn as they arrive. The list returns no
+// results itself.
+func WithRecords(fn func(record json.RawMessage) error) ListOption {
+	return func(s *ListSettings) error {
+		s.Records = fn
+		return nil
+	}
+}
+
+type RemoveOption func(*RemoveSettings) error
+
+type RemoveSettings struct {
+	// Cascade removes the resources that depend on the removed ones.
+	Cascade bool
+
+	// Force removes resources even if others still depend on them.
+	Force bool
+}
+
+// WithRemoveCascade removes the dependents of the removed resources along
+// with them, e.g. the benchmarks that ran on a cluster.
+func WithRemoveCascade() RemoveOption {
+	return func(s *RemoveSettings) error {
+		s.Cascade = true
+		return nil
+	}
+}
+
+// WithRemoveForce removes resources regardless of their dependents.
+func WithRemoveForce() RemoveOption {
+	return func(s *RemoveSettings) error {
+		s.Force = true
+		return nil
+	}
+}
+
 type QueryOption func(*QuerySettings) error
 
 type QuerySettings struct {
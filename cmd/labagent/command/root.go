@@ -58,6 +58,12 @@ func App(ctx context.Context) *cli.App {
 			Value:  fmt.Sprintf("http://localhost:%d", terraform.DefaultAppPort),
 			EnvVar: "LABAGENT_APP_ADDRESS",
 		},
+		cli.StringFlag{
+			Name:   "network-interface",
+			Usage:  "network interface impaired by network shaping, disabled if empty",
+			Value:  "eth0",
+			EnvVar: "LABAGENT_NETWORK_INTERFACE",
+		},
 		cli.StringFlag{
 			Name:   "log-level,l",
 			Usage:  "set the logging level [debug, info, warn, error, fatal, panic]",
@@ -92,6 +98,7 @@ func agentAction(c *cli.Context) error {
 				Region: c.String("downloader.s3.region"),
 			},
 		}),
+		labagent.WithNetworkInterface(c.String("network-interface")),
 	)
 	if err != nil {
 		return err
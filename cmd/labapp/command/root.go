@@ -81,9 +81,19 @@ func App(ctx context.Context) *cli.App {
 		},
 		cli.StringFlag{
 			Name:   "libp2p-routing",
-			Usage:  "routing for libp2p [nil, kaddht]",
+			Usage:  "routing for libp2p [nil, kaddht, delegated, mdns]",
 			EnvVar: "LABAPP_LIBP2P_ROUTING",
 		},
+		cli.StringFlag{
+			Name:   "libp2p-routing-endpoint",
+			Usage:  "IPFS HTTP API endpoint for the delegated routing",
+			EnvVar: "LABAPP_LIBP2P_ROUTING_ENDPOINT",
+		},
+		cli.StringFlag{
+			Name:   "datastore",
+			Usage:  "datastore backing the blockstore [badger, flatfs, memory]",
+			EnvVar: "LABAPP_DATASTORE",
+		},
 		cli.StringFlag{
 			Name:   "log-level,l",
 			Usage:  "set the logging level [debug, info, warn, error, fatal, panic, none]",
@@ -131,6 +141,8 @@ func appAction(c *cli.Context) error {
 		Muxers:             c.GlobalStringSlice("libp2p-muxers"),
 		SecurityTransports: c.GlobalStringSlice("libp2p-security-transports"),
 		Routing:            c.GlobalString("libp2p-routing"),
+		RoutingEndpoint:    c.GlobalString("libp2p-routing-endpoint"),
+		Datastore:          c.GlobalString("datastore"),
 	})
 	if err != nil {
 		return err
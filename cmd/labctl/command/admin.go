@@ -0,0 +1,251 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/Netflix/p2plab/pkg/cliutil"
+	"github.com/Netflix/p2plab/printer"
+	humanize "github.com/dustin/go-humanize"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/urfave/cli"
+)
+
+var adminCommand = cli.Command{
+	Name:  "admin",
+	Usage: "Administer labd and its metadata store.",
+	Subcommands: []cli.Command{
+		{
+			Name:      "backup",
+			Usage:     "Writes a consistent backup of labd's metadata store to a file.",
+			ArgsUsage: "<file>",
+			Action:    backupAction,
+		},
+		{
+			Name:      "restore",
+			Usage:     "Restores labd's metadata store from a backup.",
+			ArgsUsage: "<file>",
+			Action:    restoreAction,
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name:  "force",
+					Usage: "overwrite a metadata store that is not empty",
+				},
+			},
+		},
+		{
+			Name:      "audit",
+			Usage:     "Lists the audited requests that modified resources.",
+			ArgsUsage: " ",
+			Action:    auditAction,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "since",
+					Usage: "lists requests made after a RFC 3339 timestamp or a duration ago, e.g. 24h",
+				},
+			},
+		},
+		{
+			Name:      "migrate",
+			Usage:     "Applies pending migrations directly to the metadata store, labd must not be running.",
+			ArgsUsage: " ",
+			Action:    migrateAction,
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "report pending migrations without applying them",
+				},
+				&cli.StringFlag{
+					Name:   "root",
+					Usage:  "path to labd's state directory",
+					Value:  "./tmp/labd",
+					EnvVar: "LABD_ROOT",
+				},
+				&cli.StringFlag{
+					Name:   "metadata-backend",
+					Usage:  "set the backend of the metadata store [bolt, postgres]",
+					Value:  "bolt",
+					EnvVar: "LABD_METADATA_BACKEND",
+				},
+				&cli.StringFlag{
+					Name:   "metadata-dsn",
+					Usage:  "set the data source name of SQL metadata backends",
+					EnvVar: "LABD_METADATA_DSN",
+				},
+			},
+		},
+	},
+}
+
+func backupAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("file must be provided")
+	}
+
+	control, err := ResolveControl(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := cliutil.CommandContext(c)
+	rc, err := control.Admin().Backup(ctx)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	// Write to a temporary file first so that a failed backup never leaves a
+	// truncated file behind.
+	path := c.Args().First()
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	n, err := io.Copy(f, rc)
+	if err != nil {
+		f.Close()
+		return errors.Wrap(err, "failed to write backup")
+	}
+
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(f.Name(), path)
+	if err != nil {
+		return err
+	}
+
+	zerolog.Ctx(ctx).Info().Str("path", path).Str("size", humanize.Bytes(uint64(n))).Msg("Backed up metadata")
+	return nil
+}
+
+func restoreAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("file must be provided")
+	}
+
+	control, err := ResolveControl(c)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(c.Args().First())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var opts []p2plab.RestoreOption
+	if c.Bool("force") {
+		opts = append(opts, p2plab.WithRestoreForce())
+	}
+
+	ctx := cliutil.CommandContext(c)
+	err = control.Admin().Restore(ctx, f, opts...)
+	if err != nil {
+		return err
+	}
+
+	zerolog.Ctx(ctx).Info().Str("path", c.Args().First()).Msg("Restored metadata")
+	return nil
+}
+
+func auditAction(c *cli.Context) error {
+	p, err := CommandPrinter(c, printer.OutputTable)
+	if err != nil {
+		return err
+	}
+
+	var opts []p2plab.AuditOption
+	if c.IsSet("since") {
+		since, err := parseSince(c.String("since"), time.Now())
+		if err != nil {
+			return err
+		}
+		opts = append(opts, p2plab.WithAuditSince(since))
+	}
+
+	control, err := ResolveControl(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := cliutil.CommandContext(c)
+	records, err := control.Admin().Audit(ctx, opts...)
+	if err != nil {
+		return err
+	}
+
+	l := make([]interface{}, len(records))
+	for i, record := range records {
+		l[i] = record
+	}
+
+	return p.Print(l)
+}
+
+func openMetadata(c *cli.Context) (metadata.DB, error) {
+	ctx := cliutil.CommandContext(c)
+	return metadata.GetDB(ctx, c.String("root"), c.String("metadata-backend"), c.String("metadata-dsn"))
+}
+
+func migrateAction(c *cli.Context) error {
+	db, err := openMetadata(c)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := cliutil.CommandContext(c)
+	logger := zerolog.Ctx(ctx)
+	if c.Bool("dry-run") {
+		pending, err := db.PendingMigrations(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range pending {
+			logger.Info().Int("version", migration.Version).Str("description", migration.Description).Msg("Would apply metadata migration")
+		}
+		if len(pending) == 0 {
+			logger.Info().Int("version", metadata.LatestSchemaVersion()).Msg("Metadata schema is up to date")
+		}
+		return nil
+	}
+
+	applied, err := db.Migrate(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(applied) == 0 {
+		logger.Info().Int("version", metadata.LatestSchemaVersion()).Msg("Metadata schema is up to date")
+	} else {
+		logger.Info().Int("version", metadata.LatestSchemaVersion()).Int("applied", len(applied)).Msg("Migrated metadata schema")
+	}
+	return nil
+}
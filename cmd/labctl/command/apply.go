@@ -0,0 +1,276 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/Netflix/p2plab/pkg/cliutil"
+	"github.com/Netflix/p2plab/printer"
+	"github.com/Netflix/p2plab/scenarios"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/urfave/cli"
+)
+
+var applyCommand = cli.Command{
+	Name:      "apply",
+	Usage:     "Creates a scenario or cluster from a JSON or YAML definition file, or updates it to match if it exists.",
+	ArgsUsage: "[<filename>]",
+	Action:    applyAction,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "file,f",
+			Usage: "Definition file to apply, or - to read it from stdin.",
+		},
+		&cli.StringFlag{
+			Name:  "name",
+			Usage: "Name of the resource, by default takes the name of the definition. Required when reading from stdin.",
+		},
+		&cli.StringFlag{
+			Name:  "kind",
+			Usage: "Kind of resource the definition is for, one of [scenario, cluster]. Detected from the definition by default.",
+		},
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "Prints the changes without applying them.",
+		},
+		&cli.StringSliceFlag{
+			Name:  "var",
+			Usage: "Sets a ${key} or ${key:-default} variable of a templated definition as key=value, overriding the environment. Escape a literal $ as $$",
+		},
+	},
+}
+
+func applyAction(c *cli.Context) error {
+	filename, ok := definitionFilename(c)
+	if !ok {
+		return errors.New("definition must be provided as argument or with --file")
+	}
+
+	var err error
+	name := c.String("name")
+	if name == "" {
+		name, err = ExtractNameFromFilename(filename)
+		if err != nil {
+			return err
+		}
+	}
+
+	lookup, err := scenarioVars(c)
+	if err != nil {
+		return err
+	}
+
+	content, err := scenarios.ReadTemplate(filename, lookup)
+	if err != nil {
+		return err
+	}
+
+	docs, err := scenarios.SplitDocuments(content)
+	if err != nil {
+		return errors.Wrap(err, filename)
+	}
+	if len(docs) != 1 {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "%s: apply takes a single definition, got %d documents", filename, len(docs))
+	}
+	content = docs[0]
+
+	kind := c.String("kind")
+	if kind == "" {
+		kind, err = definitionKind(content)
+		if err != nil {
+			return errors.Wrap(err, filename)
+		}
+	}
+
+	p, err := CommandPrinter(c, printer.OutputTable)
+	if err != nil {
+		return err
+	}
+
+	control, err := ResolveControl(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := cliutil.CommandContext(c)
+	switch kind {
+	case "scenario":
+		return applyScenario(ctx, p, control, name, content, c.Bool("dry-run"))
+	case "cluster":
+		return applyCluster(ctx, p, control, name, content, c.Bool("dry-run"))
+	default:
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "unknown kind %q, must be one of [scenario, cluster]", kind)
+	}
+}
+
+// definitionKind detects whether a definition is for a cluster, which has
+// cluster groups, or a scenario.
+func definitionKind(content []byte) (string, error) {
+	var fields map[string]json.RawMessage
+	err := json.Unmarshal(content, &fields)
+	if err != nil {
+		return "", errors.Wrapf(errdefs.ErrInvalidArgument, "invalid definition: %s", err)
+	}
+
+	for key := range fields {
+		if key == "Groups" || key == "groups" {
+			return "cluster", nil
+		}
+	}
+	return "scenario", nil
+}
+
+func applyScenario(ctx context.Context, p printer.Printer, control p2plab.ControlAPI, name string, content []byte, dryRun bool) error {
+	sdef, err := metadata.ParseScenarioDefinition(content)
+	if err != nil {
+		return err
+	}
+
+	var current metadata.ScenarioDefinition
+	scenario, err := control.Scenario().Get(ctx, name)
+	exists := err == nil
+	if exists {
+		current = scenario.Metadata().Definition
+	} else if !errdefs.IsNotFound(err) {
+		return err
+	}
+
+	changes, err := metadata.DiffDefinitions(current, sdef)
+	if err != nil {
+		return err
+	}
+
+	logger := zerolog.Ctx(ctx).With().Str("scenario", name).Logger()
+	switch {
+	case exists && len(changes) == 0:
+		logger.Info().Msg("Scenario is up to date")
+		return nil
+	case dryRun:
+		logger.Info().Bool("exists", exists).Msg("Changes to apply")
+		return p.Print(changeRows(changes))
+	case exists:
+		_, err = control.Scenario().Update(ctx, name, sdef)
+		if err != nil {
+			return err
+		}
+		logger.Info().Msg("Updated scenario")
+	default:
+		_, err = control.Scenario().Create(ctx, name, sdef)
+		if err != nil {
+			return err
+		}
+		logger.Info().Msg("Created scenario")
+	}
+
+	return p.Print(changeRows(changes))
+}
+
+// applyCluster creates the cluster, or scales it if its definition only
+// differs by the size of its last cluster group, which is where nodes are
+// added or removed. Other changes require recreating the cluster.
+func applyCluster(ctx context.Context, p printer.Printer, control p2plab.ControlAPI, name string, content []byte, dryRun bool) error {
+	var cdef metadata.ClusterDefinition
+	err := json.Unmarshal(content, &cdef)
+	if err != nil {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid cluster definition: %s", err)
+	}
+
+	if len(cdef.Groups) == 0 {
+		return errors.Wrap(errdefs.ErrInvalidArgument, "cluster definition must have at least one cluster group")
+	}
+	for i, group := range cdef.Groups {
+		if group.Peer == nil {
+			cdef.Groups[i].Peer = &metadata.DefaultPeerDefinition
+		}
+	}
+
+	var current metadata.ClusterDefinition
+	cluster, err := control.Cluster().Get(ctx, name)
+	exists := err == nil
+	if exists {
+		current = cluster.Metadata().Definition
+		if cdef.Provider == "" {
+			cdef.Provider = current.Provider
+		}
+	} else if !errdefs.IsNotFound(err) {
+		return err
+	}
+
+	changes, err := metadata.DiffDefinitions(current, cdef)
+	if err != nil {
+		return err
+	}
+
+	logger := zerolog.Ctx(ctx).With().Str("cluster", name).Logger()
+	switch {
+	case exists && len(changes) == 0:
+		logger.Info().Msg("Cluster is up to date")
+		return nil
+	case dryRun:
+		logger.Info().Bool("exists", exists).Msg("Changes to apply")
+		return p.Print(changeRows(changes))
+	case exists:
+		scaled := fmt.Sprintf("Groups[%d].Size", len(current.Groups)-1)
+		for _, change := range changes {
+			if change.Path != scaled {
+				return errors.Wrapf(errdefs.ErrFailedPrecondition, "cluster %q cannot change %s in place, remove and recreate it", name, change.Path)
+			}
+		}
+
+		logger.Info().Int("size", cdef.Size()).Msg("Scaling cluster")
+		err = control.Cluster().Scale(ctx, name, cdef.Size())
+		if err != nil {
+			return err
+		}
+	default:
+		_, err = control.Cluster().Create(ctx, name, p2plab.WithClusterDefinitionValue(cdef))
+		if err != nil {
+			return err
+		}
+		logger.Info().Msg("Created cluster")
+	}
+
+	return p.Print(changeRows(changes))
+}
+
+// changeRows tabulates the changes of a definition, with values in JSON.
+func changeRows(changes []metadata.Change) []interface{} {
+	format := func(v interface{}) string {
+		if v == nil {
+			return ""
+		}
+		content, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(content)
+	}
+
+	l := make([]interface{}, len(changes))
+	for i, change := range changes {
+		l[i] = printer.Projection{
+			Fields: []string{"path", "old", "new"},
+			Values: []interface{}{change.Path, format(change.Old), format(change.New)},
+		}
+	}
+	return l
+}
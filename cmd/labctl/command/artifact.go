@@ -0,0 +1,113 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// downloadArtifact downloads an artifact under dir through a ".part" file,
+// resuming from what the file already holds when interrupted. The file is only
+// renamed to its final name once its content matches the artifact's digest.
+func downloadArtifact(ctx context.Context, api p2plab.BenchmarkAPI, id string, artifact metadata.Artifact, dir string, retries int, progress *progressReporter) error {
+	err := metadata.ValidateArtifactName(artifact.Name)
+	if err != nil {
+		return err
+	}
+
+	target := filepath.Join(dir, filepath.FromSlash(artifact.Name))
+	err = os.MkdirAll(filepath.Dir(target), 0755)
+	if err != nil {
+		return err
+	}
+
+	part := target + ".part"
+	for attempt := 0; ; attempt++ {
+		err = resumeArtifact(ctx, api, id, artifact, part, progress)
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil || attempt >= retries {
+			return errors.Wrapf(err, "failed to download artifact %q, rerun to resume", artifact.Name)
+		}
+		zerolog.Ctx(ctx).Warn().Err(err).Str("artifact", artifact.Name).Msg("Download interrupted, resuming")
+	}
+	progress.Stop()
+
+	f, err := os.Open(part)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	verifier := artifact.Digest.Verifier()
+	_, err = io.Copy(verifier, f)
+	if err != nil {
+		return err
+	}
+
+	if !verifier.Verified() {
+		os.Remove(part)
+		return errors.Errorf("artifact %q doesn't match its digest %s", artifact.Name, artifact.Digest)
+	}
+
+	return os.Rename(part, target)
+}
+
+// resumeArtifact appends the rest of the artifact to the part file.
+func resumeArtifact(ctx context.Context, api p2plab.BenchmarkAPI, id string, artifact metadata.Artifact, part string, progress *progressReporter) error {
+	var offset int64
+	fi, err := os.Stat(part)
+	if err == nil && fi.Size() <= artifact.Size {
+		offset = fi.Size()
+	}
+	if offset == artifact.Size && offset > 0 {
+		progress.Set(offset, artifact.Size)
+		return nil
+	}
+
+	offset, rc, err := api.DownloadArtifact(ctx, id, artifact.Name, offset)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	flag := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if offset == 0 {
+		flag |= os.O_TRUNC
+	}
+
+	f, err := os.OpenFile(part, flag, 0644)
+	if err != nil {
+		return err
+	}
+
+	progress.Set(offset, artifact.Size)
+	_, err = io.Copy(io.MultiWriter(f, progress), rc)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
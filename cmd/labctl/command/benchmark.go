@@ -15,12 +15,26 @@
 package command
 
 import (
-	"errors"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/metadata"
 	"github.com/Netflix/p2plab/pkg/cliutil"
 	"github.com/Netflix/p2plab/printer"
 	"github.com/Netflix/p2plab/query"
+	"github.com/Netflix/p2plab/reports"
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"github.com/urfave/cli"
 )
@@ -32,7 +46,7 @@ var benchmarkCommand = cli.Command{
 	Subcommands: []cli.Command{
 		{
 			Name:      "create",
-			Aliases:   []string{"s"},
+			Aliases:   []string{"s", "run"},
 			Usage:     "Benchmarks a scenario on a cluster.",
 			ArgsUsage: "<cluster> <scenario>",
 			Action:    createBenchmarkAction,
@@ -41,6 +55,82 @@ var benchmarkCommand = cli.Command{
 					Name:  "no-reset",
 					Usage: "Skips resetting the cluster to maintain a stale state",
 				},
+				&cli.IntFlag{
+					Name:  "max-concurrency",
+					Usage: "Overrides how many nodes of the scenario execute a stage in parallel",
+				},
+				&cli.StringFlag{
+					Name:  "notify-url",
+					Usage: "Webhook notified when the benchmark finishes, instead of the default of the daemon",
+				},
+				&cli.StringFlag{
+					Name:  "idempotency-key",
+					Usage: "Identifies the request so that retrying it returns the benchmark already created, generated if not set",
+				},
+				&cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "Validates the scenario, resolves its queries and transforms its objects without executing anything",
+				},
+				&cli.StringSliceFlag{
+					Name:  "tag,t",
+					Usage: "Adds a key=value tag to the benchmark, e.g. branch=feature-x",
+				},
+				&cli.StringFlag{
+					Name:  "baseline",
+					Usage: "Benchmark id or experiment label to compare the results against, failing if they regressed",
+				},
+				&cli.StringFlag{
+					Name:  "max-regression",
+					Usage: "Increase in percent of a metric over the baseline beyond which the benchmark fails",
+					Value: "5%",
+				},
+				&cli.StringSliceFlag{
+					Name:  "metric",
+					Usage: fmt.Sprintf("Metric compared against the baseline, failing the benchmark if it regressed, one of [%s]. Defaults to %s, regressions of other metrics are only logged", strings.Join(reports.Metrics, ", "), reports.HeadlineMetric),
+				},
+			},
+		},
+		{
+			Name:      "artifacts",
+			Usage:     "Lists the artifacts of a benchmark, or downloads them with get.",
+			ArgsUsage: "[get] <id> [name...]",
+			Action:    benchmarkArtifactsAction,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "dir,d",
+					Usage: "Directory where get downloads the artifacts",
+					Value: ".",
+				},
+				&cli.IntFlag{
+					Name:  "retries",
+					Usage: "Number of times get resumes an interrupted download",
+					Value: 5,
+				},
+			},
+		},
+		{
+			Name:      "cancel",
+			Usage:     "Cancels a running benchmark.",
+			ArgsUsage: "<id>",
+			Action:    cancelBenchmarkAction,
+			Flags: []cli.Flag{
+				&cli.BoolTFlag{
+					Name:  "wait",
+					Usage: "Waits until the benchmark is canceled.",
+				},
+			},
+		},
+		{
+			Name:      "export",
+			Usage:     "Exports a benchmark's metrics.",
+			ArgsUsage: "<id>",
+			Action:    exportBenchmarkAction,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "format",
+					Usage: "Format of the exported metrics [prometheus]",
+					Value: "prometheus",
+				},
 			},
 		},
 		{
@@ -49,6 +139,12 @@ var benchmarkCommand = cli.Command{
 			Usage:     "Displays detailed information on a benchmark.",
 			ArgsUsage: "<id>",
 			Action:    inspectBenchmarkAction,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "metrics",
+					Usage: "Displays metrics collected by the benchmark instead of its metadata, one of [resources, latency].",
+				},
+			},
 		},
 		{
 			Name:      "label",
@@ -73,12 +169,12 @@ var benchmarkCommand = cli.Command{
 			Usage:     "List benchmarks",
 			ArgsUsage: " ",
 			Action:    listBenchmarkAction,
-			Flags: []cli.Flag{
+			Flags: append([]cli.Flag{
 				&cli.StringFlag{
 					Name:  "query,q",
 					Usage: "Runs a query to filter the listed benchmarks.",
 				},
-			},
+			}, listFlags...),
 		},
 		{
 			Name:      "report",
@@ -87,12 +183,38 @@ var benchmarkCommand = cli.Command{
 			ArgsUsage: "<id>",
 			Action:    benchmarkReportAction,
 		},
+		{
+			Name:      "retry",
+			Usage:     "Re-executes a benchmark on the nodes that failed.",
+			ArgsUsage: "<id>",
+			Action:    retryBenchmarkAction,
+		},
+		{
+			Name:      "tag",
+			Usage:     "Sets key=value tags on benchmarks, replacing the previous value of their keys.",
+			ArgsUsage: "<id>... [key=value]...",
+			Action:    tagBenchmarksAction,
+			Flags: []cli.Flag{
+				&cli.StringSliceFlag{
+					Name:  "remove,rm",
+					Usage: "Removes the tag with a key.",
+				},
+			},
+		},
+		{
+			Name:      "watch",
+			Aliases:   []string{"w"},
+			Usage:     "Streams the progress of a benchmark until it finishes.",
+			ArgsUsage: "<id>",
+			Action:    watchBenchmarkAction,
+		},
 		{
 			Name:      "remove",
 			Aliases:   []string{"rm"},
 			Usage:     "Remove benchmarks.",
 			ArgsUsage: "[<id> ...]",
 			Action:    removeBenchmarksAction,
+			Flags:     removeFlags("experiments"),
 		},
 	},
 }
@@ -114,16 +236,77 @@ func createBenchmarkAction(c *cli.Context) error {
 
 	ctx := cliutil.CommandContext(c)
 	cluster, scenario := c.Args().Get(0), c.Args().Get(1)
+	TagSpan(c, tagCluster, cluster)
+	TagSpan(c, tagScenario, scenario)
+
+	if c.Bool("dry-run") {
+		dryRun, err := control.Benchmark().DryRun(ctx, cluster, scenario)
+		if err != nil {
+			return err
+		}
+		return p.Print(dryRun)
+	}
+
+	var (
+		baseline  p2plab.Benchmark
+		threshold float64
+		metrics   []string
+	)
+	if c.IsSet("baseline") {
+		threshold, err = reports.ParseThreshold(c.String("max-regression"))
+		if err != nil {
+			return err
+		}
+
+		metrics = c.StringSlice("metric")
+		if len(metrics) == 0 {
+			metrics = []string{reports.HeadlineMetric}
+		}
+		err = reports.ValidateMetrics(metrics)
+		if err != nil {
+			return err
+		}
+
+		baseline, err = resolveBaseline(ctx, control, c.String("baseline"))
+		if err != nil {
+			return err
+		}
+	}
 
 	var opts []p2plab.StartBenchmarkOption
 	if c.Bool("no-reset") {
 		opts = append(opts, p2plab.WithBenchmarkNoReset())
 	}
 
-	id, err := control.Benchmark().Create(ctx, cluster, scenario, opts...)
+	if c.IsSet("max-concurrency") {
+		opts = append(opts, p2plab.WithBenchmarkMaxConcurrency(c.Int("max-concurrency")))
+	}
+
+	if c.IsSet("notify-url") {
+		opts = append(opts, p2plab.WithBenchmarkNotifyURL(c.String("notify-url")))
+	}
+
+	if c.IsSet("tag") {
+		opts = append(opts, p2plab.WithBenchmarkTags(c.StringSlice("tag")...))
+	}
+
+	key := c.String("idempotency-key")
+	if key == "" {
+		key, err = newIdempotencyKey()
+		if err != nil {
+			return err
+		}
+	}
+	zerolog.Ctx(ctx).Info().Str("key", key).Msg("Creating benchmark, rerun with --idempotency-key to retry")
+	opts = append(opts, p2plab.WithBenchmarkIdempotencyKey(key))
+
+	pctx, progress := startProgress(ctx, c, "Running tasks", formatCount)
+	id, err := control.Benchmark().Create(pctx, cluster, scenario, opts...)
+	progress.Stop()
 	if err != nil {
 		return err
 	}
+	TagSpan(c, tagBenchmark, id)
 
 	benchmark, err := control.Benchmark().Get(ctx, id)
 	if err != nil {
@@ -136,7 +319,239 @@ func createBenchmarkAction(c *cli.Context) error {
 		return err
 	}
 
-	return p.Print(report)
+	err = p.Print(report)
+	if err != nil {
+		return err
+	}
+
+	if baseline == nil {
+		return nil
+	}
+	return compareBaseline(ctx, baseline, benchmark, report, threshold, metrics)
+}
+
+// resolveBaseline returns the benchmark with the given id, or else the only
+// benchmark ran by the most recent experiment labelled with it.
+func resolveBaseline(ctx context.Context, control p2plab.ControlAPI, ref string) (p2plab.Benchmark, error) {
+	benchmark, err := control.Benchmark().Get(ctx, ref)
+	if err == nil || !errdefs.IsNotFound(err) {
+		return benchmark, err
+	}
+
+	experiments, err := control.Experiment().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var latest *metadata.Experiment
+	for _, e := range experiments {
+		experiment := e.Metadata()
+		for _, label := range experiment.Labels {
+			if label == ref && (latest == nil || experiment.CreatedAt.After(latest.CreatedAt)) {
+				latest = &experiment
+			}
+		}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("baseline %q is neither a benchmark nor an experiment label", ref)
+	}
+
+	var ids []string
+	for _, trial := range latest.Trials {
+		if trial.Benchmark != "" {
+			ids = append(ids, trial.Benchmark)
+		}
+	}
+	if len(ids) != 1 {
+		return nil, fmt.Errorf("baseline experiment %q must have exactly one benchmark, has %d", latest.ID, len(ids))
+	}
+
+	return control.Benchmark().Get(ctx, ids[0])
+}
+
+// compareBaseline fails if any of the metrics of the benchmark regressed over
+// the baseline beyond the threshold. Improvements and regressions of other
+// metrics are only logged.
+func compareBaseline(ctx context.Context, baseline, benchmark p2plab.Benchmark, report metadata.Report, threshold float64, metrics []string) error {
+	baselineReport, err := baseline.Report(ctx)
+	if err != nil {
+		return err
+	}
+
+	logger := zerolog.Ctx(ctx)
+	diff := reports.Diff(baseline.Metadata(), benchmark.Metadata(), baselineReport, report, threshold)
+	for _, warning := range diff.Warnings {
+		logger.Warn().Msg(warning)
+	}
+
+	gated := make(map[string]bool)
+	for _, name := range metrics {
+		gated[name] = true
+	}
+
+	regressed, improved := reports.Regressions(diff)
+	for _, m := range improved {
+		logger.Info().Str("metric", m.Name).Float64("baseline", m.A).Float64("value", m.B).Msgf("Improved over baseline %q", baseline.ID())
+	}
+
+	var names []string
+	for _, m := range regressed {
+		if !gated[m.Name] {
+			logger.Warn().Str("metric", m.Name).Float64("baseline", m.A).Float64("value", m.B).Msgf("Regressed over baseline %q", baseline.ID())
+			continue
+		}
+		logger.Error().Str("metric", m.Name).Float64("baseline", m.A).Float64("value", m.B).Msgf("Regressed over baseline %q", baseline.ID())
+		names = append(names, m.Name)
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	return fmt.Errorf("benchmark %q regressed beyond %g%% over baseline %q: %s", benchmark.ID(), threshold, baseline.ID(), strings.Join(names, ", "))
+}
+
+// benchmarkArtifactsAction lists the artifacts of a benchmark, or downloads
+// them with "get <id> [name...]".
+func benchmarkArtifactsAction(c *cli.Context) error {
+	args := c.Args()
+	get := args.First() == "get"
+	if get {
+		args = args.Tail()
+	}
+
+	if len(args) < 1 {
+		return errors.New("benchmark id must be provided")
+	}
+
+	p, err := CommandPrinter(c, printer.OutputTable)
+	if err != nil {
+		return err
+	}
+
+	control, err := ResolveControl(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := cliutil.CommandContext(c)
+	id := args[0]
+	artifacts, err := control.Benchmark().Artifacts(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if len(args) > 1 {
+		byName := make(map[string]metadata.Artifact)
+		for _, artifact := range artifacts {
+			byName[artifact.Name] = artifact
+		}
+
+		artifacts = nil
+		for _, name := range args[1:] {
+			artifact, ok := byName[name]
+			if !ok {
+				return fmt.Errorf("benchmark %q has no artifact %q", id, name)
+			}
+			artifacts = append(artifacts, artifact)
+		}
+	}
+
+	if !get {
+		l := make([]interface{}, len(artifacts))
+		for i, artifact := range artifacts {
+			l[i] = artifact
+		}
+		return p.Print(l)
+	}
+
+	for _, artifact := range artifacts {
+		pctx, progress := startProgress(ctx, c, artifact.Name, formatBytes)
+		progress.Set(0, artifact.Size)
+
+		err = downloadArtifact(pctx, control.Benchmark(), id, artifact, c.String("dir"), c.Int("retries"), progress)
+		progress.Stop()
+		if err != nil {
+			return err
+		}
+		zerolog.Ctx(ctx).Info().Str("artifact", artifact.Name).Msg("Downloaded artifact")
+	}
+
+	return nil
+}
+
+func cancelBenchmarkAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("benchmark id must be provided")
+	}
+
+	p, err := CommandPrinter(c, printer.OutputID)
+	if err != nil {
+		return err
+	}
+
+	control, err := ResolveControl(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := cliutil.CommandContext(c)
+	id := c.Args().First()
+	TagSpan(c, tagBenchmark, id)
+	err = control.Benchmark().Cancel(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	benchmark, err := control.Benchmark().Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	for c.BoolT("wait") && !benchmark.Metadata().Status.Terminal() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+
+		benchmark, err = control.Benchmark().Get(ctx, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	zerolog.Ctx(ctx).Info().Msgf("Benchmark %q is %s", id, benchmark.Metadata().Status)
+	return p.Print(benchmark.Metadata())
+}
+
+func exportBenchmarkAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("benchmark id must be provided")
+	}
+
+	if c.String("format") != "prometheus" {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "unsupported export format %q", c.String("format"))
+	}
+
+	control, err := ResolveControl(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := cliutil.CommandContext(c)
+	TagSpan(c, tagBenchmark, c.Args().First())
+	benchmark, err := control.Benchmark().Get(ctx, c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	report, err := benchmark.Report(ctx)
+	if err != nil {
+		return err
+	}
+
+	return reports.WritePrometheus(os.Stdout, []metadata.Benchmark{benchmark.Metadata()}, map[string]metadata.Report{
+		benchmark.ID(): report,
+	})
 }
 
 func inspectBenchmarkAction(c *cli.Context) error {
@@ -156,12 +571,80 @@ func inspectBenchmarkAction(c *cli.Context) error {
 
 	ctx := cliutil.CommandContext(c)
 	id := c.Args().First()
+	TagSpan(c, tagBenchmark, id)
 	benchmark, err := control.Benchmark().Get(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	return p.Print(benchmark.Metadata())
+	switch c.String("metrics") {
+	case "":
+		return p.Print(benchmark.Metadata())
+	case "resources":
+		return p.Print(resourceMetrics(benchmark.Metadata()))
+	case "latency":
+		return p.Print(latencyMetrics(benchmark.Metadata()))
+	default:
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "unknown metrics %q, must be one of [resources, latency]", c.String("metrics"))
+	}
+}
+
+// resourceMetrics summarizes the resource usage of each node of the benchmark.
+func resourceMetrics(benchmark metadata.Benchmark) []interface{} {
+	var ids []string
+	for id, result := range benchmark.Nodes {
+		if result.Resources != nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	round := func(v float64) float64 {
+		return math.Round(v*100) / 100
+	}
+
+	var l []interface{}
+	for _, id := range ids {
+		usage := benchmark.Nodes[id].Resources
+		cpu, rss := usage.CPUSummary(), usage.RSSSummary()
+		l = append(l, printer.Projection{
+			Fields: []string{"node", "interval", "samples", "cpuMin", "cpuAvg", "cpuMax", "rssMin", "rssAvg", "rssMax"},
+			Values: []interface{}{
+				id, usage.Interval.String(), len(usage.CPU),
+				round(cpu.Min), round(cpu.Avg), round(cpu.Max),
+				uint64(rss.Min), uint64(rss.Avg), uint64(rss.Max),
+			},
+		})
+	}
+	return l
+}
+
+// latencyMetrics summarizes the latency percentiles measured across the nodes
+// of the benchmark.
+func latencyMetrics(benchmark metadata.Benchmark) []interface{} {
+	var l []interface{}
+	for _, metric := range []struct {
+		name    string
+		latency *metadata.ReportLatency
+	}{
+		{"ttfb", reports.ComputeTimeToFirstBlock(benchmark.Nodes)},
+		{"block", reports.ComputeBlockLatency(benchmark.Nodes)},
+		{"task", reports.ComputeTaskDuration(benchmark.Nodes)},
+	} {
+		if metric.latency == nil {
+			continue
+		}
+
+		latency := metric.latency
+		l = append(l, printer.Projection{
+			Fields: []string{"metric", "samples", "p50", "p90", "p95", "p99", "p99.9"},
+			Values: []interface{}{
+				metric.name, latency.Histogram.Count,
+				latency.P50.String(), latency.P90.String(), latency.P95.String(), latency.P99.String(), latency.P999.String(),
+			},
+		})
+	}
+	return l
 }
 
 func labelBenchmarksAction(c *cli.Context) error {
@@ -169,6 +652,7 @@ func labelBenchmarksAction(c *cli.Context) error {
 	for i := 0; i < c.NArg(); i++ {
 		ids = append(ids, c.Args().Get(i))
 	}
+	TagSpan(c, tagBenchmark, ids...)
 
 	p, err := CommandPrinter(c, printer.OutputTable)
 	if err != nil {
@@ -194,6 +678,67 @@ func labelBenchmarksAction(c *cli.Context) error {
 	return p.Print(l)
 }
 
+// tagBenchmarksAction sets key=value tags on benchmarks. Tags are labels, so
+// benchmarks can be listed by tag with a query such as "branch in (feature-x)".
+func tagBenchmarksAction(c *cli.Context) error {
+	var ids, tags []string
+	for i := 0; i < c.NArg(); i++ {
+		arg := c.Args().Get(i)
+		if strings.Contains(arg, "=") {
+			tags = append(tags, arg)
+		} else {
+			ids = append(ids, arg)
+		}
+	}
+
+	if len(ids) == 0 {
+		return errors.New("benchmark id must be provided")
+	}
+
+	p, err := CommandPrinter(c, printer.OutputTable)
+	if err != nil {
+		return err
+	}
+
+	control, err := ResolveControl(c)
+	if err != nil {
+		return err
+	}
+
+	keys := c.StringSlice("remove")
+	for _, tag := range tags {
+		key, _, err := metadata.ParseKeyValueLabel(tag)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+	}
+
+	ctx := cliutil.CommandContext(c)
+	removes, err := keyedLabels(ids, keys, func(id string) ([]string, error) {
+		benchmark, err := control.Benchmark().Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return benchmark.Labels(), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	benchmarks, err := control.Benchmark().Label(ctx, ids, tags, removes)
+	if err != nil {
+		return err
+	}
+
+	l := make([]interface{}, len(benchmarks))
+	for i, b := range benchmarks {
+		l[i] = b.Metadata()
+	}
+
+	return p.Print(l)
+}
+
 func listBenchmarkAction(c *cli.Context) error {
 	p, err := CommandPrinter(c, printer.OutputTable)
 	if err != nil {
@@ -216,6 +761,9 @@ func listBenchmarkAction(c *cli.Context) error {
 		opts = append(opts, p2plab.WithQuery(q.String()))
 	}
 
+	var next string
+	opts = append(opts, listOptions(c, p, &next)...)
+
 	benchmarks, err := control.Benchmark().List(ctx, opts...)
 	if err != nil {
 		return err
@@ -226,7 +774,7 @@ func listBenchmarkAction(c *cli.Context) error {
 		l[i] = b.Metadata()
 	}
 
-	return p.Print(l)
+	return printList(c, p, l, next)
 }
 
 func benchmarkReportAction(c *cli.Context) error {
@@ -246,6 +794,7 @@ func benchmarkReportAction(c *cli.Context) error {
 
 	ctx := cliutil.CommandContext(c)
 	id := c.Args().First()
+	TagSpan(c, tagBenchmark, id)
 	benchmark, err := control.Benchmark().Get(ctx, id)
 	if err != nil {
 		return err
@@ -259,11 +808,160 @@ func benchmarkReportAction(c *cli.Context) error {
 	return p.Print(report)
 }
 
+func retryBenchmarkAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("benchmark id must be provided")
+	}
+
+	p, err := CommandPrinter(c, printer.OutputTable)
+	if err != nil {
+		return err
+	}
+
+	control, err := ResolveControl(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := cliutil.CommandContext(c)
+	id := c.Args().First()
+	TagSpan(c, tagBenchmark, id)
+	err = control.Benchmark().Retry(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	benchmark, err := control.Benchmark().Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	zerolog.Ctx(ctx).Info().Msgf("Retried benchmark %q, generation %d is %s", id, benchmark.Metadata().Generation, benchmark.Metadata().Status)
+
+	report, err := benchmark.Report(ctx)
+	if err != nil {
+		return err
+	}
+
+	return p.Print(report)
+}
+
+const (
+	watchBackoffMin = time.Second
+	watchBackoffMax = 30 * time.Second
+)
+
+// watchBenchmarkAction prints the progress of a benchmark on every event until
+// it finishes. The JSON and JSON lines printers print the events themselves. When the stream
+// is interrupted, it reconnects with backoff and resumes after the last event
+// received.
+func watchBenchmarkAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("benchmark id must be provided")
+	}
+
+	p, err := CommandPrinter(c, printer.OutputUnix)
+	if err != nil {
+		return err
+	}
+	output := printer.OutputType(c.GlobalString("output"))
+	printEvents := output == printer.OutputJSON || output == printer.OutputJSONL
+
+	control, err := ResolveControl(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := cliutil.CommandContext(c)
+	id := c.Args().First()
+	TagSpan(c, tagBenchmark, id)
+	logger := zerolog.Ctx(ctx).With().Str("bid", id).Logger()
+
+	var (
+		progress    = metadata.BenchmarkProgress{ID: id}
+		next        uint64
+		reconnected bool
+		backoff     = watchBackoffMin
+	)
+	for {
+		offset, rc, err := control.Benchmark().Watch(ctx, id, next)
+		if err == nil {
+			if reconnected {
+				if offset > next {
+					logger.Warn().Uint64("missed", offset-next).Msg("Reconnected to benchmark, events were missed")
+				} else {
+					logger.Info().Msg("Reconnected to benchmark")
+				}
+			}
+			backoff = watchBackoffMin
+
+			var count uint64
+			count, err = decodeBenchmarkEvents(rc, func(evt metadata.BenchmarkEvent) error {
+				progress.Apply(evt)
+				if printEvents {
+					return p.Print(evt)
+				}
+				return p.Print(progress)
+			})
+			rc.Close()
+			next = offset + count
+		}
+
+		if progress.Status.Terminal() {
+			logger.Info().Msgf("Benchmark %q is %s", id, progress.Status)
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+		logger.Warn().Err(err).Dur("backoff", backoff).Msg("Lost connection to benchmark, reconnecting")
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > watchBackoffMax {
+			backoff = watchBackoffMax
+		}
+		reconnected = true
+	}
+}
+
+// decodeBenchmarkEvents calls fn with every event of a progress stream, and
+// returns the number of events decoded.
+func decodeBenchmarkEvents(r io.Reader, fn func(metadata.BenchmarkEvent) error) (uint64, error) {
+	dec := json.NewDecoder(r)
+
+	var count uint64
+	for {
+		var evt metadata.BenchmarkEvent
+		err := dec.Decode(&evt)
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return count, err
+		}
+		count++
+
+		err = fn(evt)
+		if err != nil {
+			return count, err
+		}
+	}
+}
+
 func removeBenchmarksAction(c *cli.Context) error {
 	var ids []string
 	for i := 0; i < c.NArg(); i++ {
 		ids = append(ids, c.Args().Get(i))
 	}
+	TagSpan(c, tagBenchmark, ids...)
 
 	control, err := ResolveControl(c)
 	if err != nil {
@@ -271,10 +969,19 @@ func removeBenchmarksAction(c *cli.Context) error {
 	}
 
 	ctx := cliutil.CommandContext(c)
-	err = control.Benchmark().Remove(ctx, ids...)
+	err = control.Benchmark().Remove(ctx, ids, removeOptions(c)...)
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+func newIdempotencyKey() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
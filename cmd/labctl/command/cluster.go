@@ -16,8 +16,13 @@ package command
 
 import (
 	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/metadata"
 	"github.com/Netflix/p2plab/pkg/cliutil"
 	"github.com/Netflix/p2plab/printer"
 	"github.com/Netflix/p2plab/query"
@@ -39,7 +44,7 @@ var clusterCommand = cli.Command{
 			Flags: []cli.Flag{
 				&cli.StringFlag{
 					Name:  "definition,d",
-					Usage: "Create cluster from a cluster definition.",
+					Usage: "Create cluster from a cluster definition file, or - to read it from stdin. Unlike scenario create, the file must hold a single JSON definition, not multiple documents separated by ---.",
 				},
 				&cli.IntFlag{
 					Name:  "size,s",
@@ -56,6 +61,46 @@ var clusterCommand = cli.Command{
 					Usage: "AWS Region to deploy to.",
 					Value: "us-west-2",
 				},
+				&cli.StringFlag{
+					Name:  "cpus",
+					Usage: "Number of CPUs of each node, for container providers.",
+				},
+				&cli.StringFlag{
+					Name:  "memory,m",
+					Usage: "Memory limit of each node (e.g. 512m), for container providers.",
+				},
+				&cli.StringFlag{
+					Name:  "network,n",
+					Usage: "Named network to attach nodes to, for container providers.",
+				},
+				&cli.StringFlag{
+					Name:  "provider,p",
+					Usage: "Node provider to create the cluster with [inmemory, terraform, docker]. Defaults to the labd provider.",
+				},
+				&cli.StringSliceFlag{
+					Name:  "transports",
+					Usage: "Transports for libp2p of every node [tcp, ws, quic]",
+				},
+				&cli.StringSliceFlag{
+					Name:  "muxers",
+					Usage: "Muxers for libp2p of every node [mplex, yamux]",
+				},
+				&cli.StringSliceFlag{
+					Name:  "security-transports,st",
+					Usage: "Security transports for libp2p of every node [tls, secio]",
+				},
+				&cli.StringSliceFlag{
+					Name:  "label,l",
+					Usage: "Adds a key=value label to the cluster, e.g. env=staging.",
+				},
+				&cli.DurationFlag{
+					Name:  "ttl",
+					Usage: "Destroys the cluster after the duration, e.g. 8h, unless extended.",
+				},
+				&cli.BoolTFlag{
+					Name:  "wait",
+					Usage: "Waits for every node to be ready, or with --wait=false returns once the nodes are requested to poll with cluster status.",
+				},
 			},
 		},
 		{
@@ -68,8 +113,8 @@ var clusterCommand = cli.Command{
 		{
 			Name:      "label",
 			Aliases:   []string{"l"},
-			Usage:     "Add or remove labels from clusters.",
-			ArgsUsage: " ",
+			Usage:     "Add or remove labels from clusters, key=value arguments replace the value of the key.",
+			ArgsUsage: "<name>... [key=value]...",
 			Action:    labelClustersAction,
 			Flags: []cli.Flag{
 				&cli.StringSliceFlag{
@@ -95,12 +140,60 @@ var clusterCommand = cli.Command{
 				},
 			},
 		},
+		{
+			Name:      "peers",
+			Usage:     "Retrieves the peer info of every node in a cluster.",
+			ArgsUsage: "<name>",
+			Action:    peersClusterAction,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "query,q",
+					Usage: "Runs a query to retrieve the peer info of a subset of nodes.",
+				},
+				&cli.IntFlag{
+					Name:  "parallel,p",
+					Usage: "Maximum number of nodes queried at once.",
+					Value: 16,
+				},
+			},
+		},
+		{
+			Name:      "status",
+			Usage:     "Displays the provisioning state of every node in a cluster.",
+			ArgsUsage: "<name>",
+			Action:    statusClusterAction,
+		},
 		{
 			Name:      "remove",
 			ArgsUsage: "[<name> ...]",
 			Aliases:   []string{"rm"},
 			Usage:     "Remove clusters.",
 			Action:    removeClustersAction,
+			Flags:     removeFlags("benchmarks"),
+		},
+		{
+			Name:      "scale",
+			Usage:     "Adds or removes nodes from a cluster.",
+			ArgsUsage: "<name>",
+			Action:    scaleClusterAction,
+			Flags: []cli.Flag{
+				&cli.IntFlag{
+					Name:  "size,s",
+					Usage: "Desired size of cluster.",
+				},
+			},
+		},
+		{
+			Name:      "extend",
+			Usage:     "Pushes out the expiry of a cluster.",
+			ArgsUsage: "<name>",
+			Action:    extendClusterAction,
+			Flags: []cli.Flag{
+				&cli.DurationFlag{
+					Name:  "ttl",
+					Usage: "Duration to add to the expiry of the cluster, or to now if it expired.",
+				},
+			},
 		},
 	},
 }
@@ -131,11 +224,43 @@ func createClusterAction(c *cli.Context) error {
 			p2plab.WithClusterSize(c.Int("size")),
 			p2plab.WithClusterInstanceType(c.String("instance-type")),
 			p2plab.WithClusterRegion(c.String("region")),
+			p2plab.WithClusterResources(c.String("cpus"), c.String("memory")),
+			p2plab.WithClusterNetwork(c.String("network")),
 		)
 	}
 
+	if c.IsSet("provider") {
+		options = append(options, p2plab.WithClusterProvider(c.String("provider")))
+	}
+
+	if c.IsSet("label") {
+		options = append(options, p2plab.WithClusterLabels(c.StringSlice("label")...))
+	}
+
+	if c.IsSet("ttl") {
+		options = append(options, p2plab.WithClusterTTL(c.Duration("ttl")))
+	}
+
+	if !c.BoolT("wait") {
+		options = append(options, p2plab.WithClusterNoWait())
+	}
+
+	stack := metadata.StackDefinition{
+		Transports:         c.StringSlice("transports"),
+		Muxers:             c.StringSlice("muxers"),
+		SecurityTransports: c.StringSlice("security-transports"),
+	}
+	err = stack.Validate()
+	if err != nil {
+		return err
+	}
+	options = append(options, p2plab.WithClusterStack(stack))
+
 	name := c.Args().First()
-	id, err := control.Cluster().Create(ctx, name, options...)
+	TagSpan(c, tagCluster, name)
+	pctx, progress := startProgress(ctx, c, "Provisioning nodes", formatCount)
+	id, err := control.Cluster().Create(pctx, name, options...)
+	progress.Stop()
 	if err != nil {
 		return err
 	}
@@ -145,7 +270,224 @@ func createClusterAction(c *cli.Context) error {
 		return err
 	}
 
-	zerolog.Ctx(ctx).Info().Msgf("Created cluster %q", cluster.Metadata().ID)
+	if c.BoolT("wait") {
+		zerolog.Ctx(ctx).Info().Msgf("Created cluster %q", cluster.Metadata().ID)
+	} else {
+		zerolog.Ctx(ctx).Info().Msgf("Provisioning cluster %q, see labctl cluster status %s", cluster.Metadata().ID, cluster.Metadata().ID)
+	}
+	return p.Print(cluster.Metadata())
+}
+
+// statusClusterAction prints the provisioning state of every node of a
+// cluster, followed by a summary counting the nodes in each state. Nodes the
+// provider hasn't created yet are counted as pending.
+func statusClusterAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("cluster name must be provided")
+	}
+
+	p, err := CommandPrinter(c, printer.OutputTable)
+	if err != nil {
+		return err
+	}
+
+	control, err := ResolveControl(c)
+	if err != nil {
+		return err
+	}
+	ctx := cliutil.CommandContext(c)
+
+	TagSpan(c, tagCluster, c.Args().First())
+	cluster, err := control.Cluster().Get(ctx, c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	ns, err := control.Node().List(ctx, cluster.ID())
+	if err != nil {
+		return err
+	}
+
+	counts := make(map[metadata.NodeStatus]int)
+	l := make([]interface{}, len(ns))
+	for i, n := range ns {
+		md := n.Metadata()
+		status := md.Status
+		if status == "" {
+			// Nodes created before provisioning states were recorded.
+			status = metadata.NodeReady
+		}
+		counts[status]++
+
+		l[i] = printer.Projection{
+			Fields: []string{"node", "address", "status", "reason"},
+			Values: []interface{}{md.ID, md.Address, status, md.Reason},
+		}
+	}
+
+	size := cluster.Metadata().Definition.Size()
+	if cluster.Metadata().Status == metadata.ClusterCreating && size > len(ns) {
+		counts[metadata.NodePending] += size - len(ns)
+	}
+
+	err = p.Print(l)
+	if err != nil {
+		return err
+	}
+
+	var summary []string
+	for _, status := range []metadata.NodeStatus{metadata.NodeReady, metadata.NodeProvisioning, metadata.NodePending, metadata.NodeFailed} {
+		summary = append(summary, fmt.Sprintf("%d %s", counts[status], status))
+	}
+	zerolog.Ctx(ctx).Info().Msgf("Cluster %q is %s: %s", cluster.ID(), cluster.Metadata().Status, strings.Join(summary, ", "))
+	return nil
+}
+
+// peersClusterAction prints the peer info of the nodes of a cluster. Nodes
+// whose peer info can't be retrieved are printed with the error instead, so
+// that one unreachable node doesn't hide the others.
+func peersClusterAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("cluster name must be provided")
+	}
+
+	p, err := CommandPrinter(c, printer.OutputTable)
+	if err != nil {
+		return err
+	}
+
+	control, err := ResolveControl(c)
+	if err != nil {
+		return err
+	}
+
+	var opts []p2plab.ListOption
+	ctx := cliutil.CommandContext(c)
+	if c.IsSet("query") {
+		q, err := query.Parse(ctx, c.String("query"))
+		if err != nil {
+			return err
+		}
+
+		opts = append(opts, p2plab.WithQuery(q.String()))
+	}
+
+	TagSpan(c, tagCluster, c.Args().First())
+	ns, err := control.Node().List(ctx, c.Args().First(), opts...)
+	if err != nil {
+		return err
+	}
+	TagSpanCount(c, tagNodes, len(ns))
+
+	parallel := c.Int("parallel")
+	if parallel <= 0 {
+		parallel = len(ns)
+	}
+
+	var (
+		sem = make(chan struct{}, parallel)
+		wg  sync.WaitGroup
+		l   = make([]interface{}, len(ns))
+	)
+	for i, n := range ns {
+		i, n := i, n
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			var (
+				peerID string
+				addrs  []string
+				errMsg string
+			)
+			peerInfo, err := n.PeerInfo(ctx)
+			if err != nil {
+				zerolog.Ctx(ctx).Warn().Str("node", n.ID()).Err(err).Msg("Failed to retrieve peer info")
+				errMsg = err.Error()
+			} else {
+				peerID = peerInfo.ID.Pretty()
+				for _, addr := range peerInfo.Addrs {
+					addrs = append(addrs, addr.String())
+				}
+			}
+
+			l[i] = printer.Projection{
+				Fields: []string{"node", "peer", "addrs", "error"},
+				Values: []interface{}{n.ID(), peerID, strings.Join(addrs, ","), errMsg},
+			}
+		}()
+	}
+	wg.Wait()
+
+	return p.Print(l)
+}
+
+func scaleClusterAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("cluster name must be provided")
+	}
+
+	if !c.IsSet("size") {
+		return errors.New("cluster size must be provided")
+	}
+
+	p, err := CommandPrinter(c, printer.OutputID)
+	if err != nil {
+		return err
+	}
+
+	control, err := ResolveControl(c)
+	if err != nil {
+		return err
+	}
+	ctx := cliutil.CommandContext(c)
+
+	name := c.Args().First()
+	TagSpan(c, tagCluster, name)
+	err = control.Cluster().Scale(ctx, name, c.Int("size"))
+	if err != nil {
+		return err
+	}
+
+	cluster, err := control.Cluster().Get(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	zerolog.Ctx(ctx).Info().Msgf("Scaled cluster %q to %d nodes", name, cluster.Metadata().Definition.Size())
+	return p.Print(cluster.Metadata())
+}
+
+func extendClusterAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("cluster name must be provided")
+	}
+
+	if c.Duration("ttl") <= 0 {
+		return errors.New("cluster ttl must be provided")
+	}
+
+	p, err := CommandPrinter(c, printer.OutputID)
+	if err != nil {
+		return err
+	}
+
+	control, err := ResolveControl(c)
+	if err != nil {
+		return err
+	}
+	ctx := cliutil.CommandContext(c)
+
+	name := c.Args().First()
+	TagSpan(c, tagCluster, name)
+	cluster, err := control.Cluster().Extend(ctx, name, c.Duration("ttl"))
+	if err != nil {
+		return err
+	}
+
+	zerolog.Ctx(ctx).Info().Msgf("Extended cluster %q until %s", name, cluster.Metadata().ExpiresAt.Local().Format(time.RFC1123))
 	return p.Print(cluster.Metadata())
 }
 
@@ -166,6 +508,7 @@ func inspectClusterAction(c *cli.Context) error {
 
 	ctx := cliutil.CommandContext(c)
 	name := c.Args().First()
+	TagSpan(c, tagCluster, name)
 	cluster, err := control.Cluster().Get(ctx, name)
 	if err != nil {
 		return err
@@ -175,9 +518,14 @@ func inspectClusterAction(c *cli.Context) error {
 }
 
 func labelClustersAction(c *cli.Context) error {
-	var names []string
+	var names, keyValues []string
 	for i := 0; i < c.NArg(); i++ {
-		names = append(names, c.Args().Get(i))
+		arg := c.Args().Get(i)
+		if strings.Contains(arg, "=") {
+			keyValues = append(keyValues, arg)
+		} else {
+			names = append(names, arg)
+		}
 	}
 
 	p, err := CommandPrinter(c, printer.OutputTable)
@@ -191,7 +539,24 @@ func labelClustersAction(c *cli.Context) error {
 	}
 
 	ctx := cliutil.CommandContext(c)
-	cs, err := control.Cluster().Label(ctx, names, c.StringSlice("add"), c.StringSlice("remove"))
+	adds, removes := c.StringSlice("add"), c.StringSlice("remove")
+	if len(keyValues) > 0 {
+		var replaced []string
+		replaced, err = replacedLabels(names, keyValues, func(name string) ([]string, error) {
+			cluster, err := control.Cluster().Get(ctx, name)
+			if err != nil {
+				return nil, err
+			}
+			return cluster.Labels(), nil
+		})
+		if err != nil {
+			return err
+		}
+		adds = append(adds, keyValues...)
+		removes = append(removes, replaced...)
+	}
+
+	cs, err := control.Cluster().Label(ctx, names, adds, removes)
 	if err != nil {
 		return err
 	}
@@ -204,6 +569,45 @@ func labelClustersAction(c *cli.Context) error {
 	return p.Print(l)
 }
 
+// replacedLabels returns the labels of the named objects with the keys of the
+// key=value labels, so that setting a key replaces its previous value.
+func replacedLabels(names, keyValues []string, labelsOf func(name string) ([]string, error)) ([]string, error) {
+	var keys []string
+	for _, label := range keyValues {
+		key, _, err := metadata.ParseKeyValueLabel(label)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keyedLabels(names, keys, labelsOf)
+}
+
+// keyedLabels returns the key=value labels of the named objects with one of
+// the keys.
+func keyedLabels(names, keys []string, labelsOf func(name string) ([]string, error)) ([]string, error) {
+	set := make(map[string]struct{})
+	for _, key := range keys {
+		set[key] = struct{}{}
+	}
+
+	var keyed []string
+	for _, name := range names {
+		labels, err := labelsOf(name)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, label := range labels {
+			parts := strings.SplitN(label, "=", 2)
+			if _, ok := set[parts[0]]; ok && len(parts) == 2 {
+				keyed = append(keyed, label)
+			}
+		}
+	}
+	return keyed, nil
+}
+
 func listClusterAction(c *cli.Context) error {
 	control, err := ResolveControl(c)
 	if err != nil {
@@ -239,11 +643,38 @@ func listClusterAction(c *cli.Context) error {
 	return p.Print(l)
 }
 
+// removeFlags returns the flags of a remove command whose resources may be
+// referenced by dependents.
+func removeFlags(dependents string) []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "cascade",
+			Usage: fmt.Sprintf("Also removes the %s that depend on them.", dependents),
+		},
+		&cli.BoolFlag{
+			Name:  "force",
+			Usage: fmt.Sprintf("Removes them even if %s depend on them.", dependents),
+		},
+	}
+}
+
+func removeOptions(c *cli.Context) []p2plab.RemoveOption {
+	var opts []p2plab.RemoveOption
+	if c.Bool("cascade") {
+		opts = append(opts, p2plab.WithRemoveCascade())
+	}
+	if c.Bool("force") {
+		opts = append(opts, p2plab.WithRemoveForce())
+	}
+	return opts
+}
+
 func removeClustersAction(c *cli.Context) error {
 	var names []string
 	for i := 0; i < c.NArg(); i++ {
 		names = append(names, c.Args().Get(i))
 	}
+	TagSpan(c, tagCluster, names...)
 
 	control, err := ResolveControl(c)
 	if err != nil {
@@ -251,7 +682,7 @@ func removeClustersAction(c *cli.Context) error {
 	}
 
 	ctx := cliutil.CommandContext(c)
-	err = control.Cluster().Remove(ctx, names...)
+	err = control.Cluster().Remove(ctx, names, removeOptions(c)...)
 	if err != nil {
 		return err
 	}
@@ -17,11 +17,15 @@ package command
 import (
 	"context"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/metadata"
 	"github.com/Netflix/p2plab/pkg/cliutil"
 	"github.com/Netflix/p2plab/pkg/httputil"
 	"github.com/Netflix/p2plab/pkg/logutil"
@@ -31,15 +35,23 @@ import (
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"github.com/urfave/cli"
+	"golang.org/x/term"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
+// AttachAppContext sets up the logger, tracer and context of every command.
+// The first interrupt cancels the command context so that commands clean up,
+// and another one terminates labctl after finishing the command's span.
 func AttachAppContext(ctx context.Context, app *cli.App) {
 	var (
-		logger *zerolog.Logger
-		writer io.Writer
-		tracer opentracing.Tracer
-		closer io.Closer
-		span   opentracing.Span
+		logger    *zerolog.Logger
+		writer    io.Writer
+		tracer    opentracing.Tracer
+		closer    io.Closer
+		span      opentracing.Span
+		cancel    context.CancelFunc
+		interrupt context.CancelFunc
+		ih        *cliutil.InterruptHandler
 	)
 
 	before := app.Before
@@ -55,38 +67,78 @@ func AttachAppContext(ctx context.Context, app *cli.App) {
 		if err != nil {
 			return err
 		}
+		c.App.Metadata["logger"] = logger
 
 		ctx, tracer, closer = traceutil.New(ctx, "labctl", nil)
+
+		ctx, interrupt = context.WithCancel(ctx)
+		ih = cliutil.NewInterruptHandler(interrupt, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM)
+		ih.OnExit(func() {
+			if span != nil {
+				span.SetTag("interrupted", true)
+				span.Finish()
+			}
+			if closer != nil {
+				closer.Close()
+			}
+		})
+
+		// Bound the command context so a hung daemon doesn't block forever.
+		if timeout := c.GlobalDuration("timeout"); timeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+		}
 		return nil
 	}
 
+	withContext := func(name string, before cli.BeforeFunc) cli.BeforeFunc {
+		return func(c *cli.Context) error {
+			if before != nil {
+				if err := before(c); err != nil {
+					return err
+				}
+			}
+
+			span = tracer.StartSpan(name)
+			span.SetTag("command", strings.Join(os.Args, " "))
+			ctx = opentracing.ContextWithSpan(ctx, span)
+
+			// Every request of the command carries the same ID, so that its
+			// logs in labd and the agents can be found.
+			id := httputil.NewRequestID(ctx)
+			span.SetTag("request_id", id)
+			ctx = httputil.WithRequestID(ctx, id)
+
+			ctx = logger.WithContext(ctx)
+			ctx = logutil.WithLogWriter(ctx, writer)
+
+			c.App.Metadata["context"] = ctx
+			return nil
+		}
+	}
+
 	for i, cmd := range app.Commands {
+		if len(cmd.Subcommands) == 0 {
+			app.Commands[i].Before = withContext(cmd.Name, cmd.Before)
+			continue
+		}
+
 		for j, subcmd := range cmd.Subcommands {
-			func(before cli.BeforeFunc) {
-				name := strings.Join([]string{cmd.Name, subcmd.Name}, " ")
-				app.Commands[i].Subcommands[j].Before = func(c *cli.Context) error {
-					if before != nil {
-						if err := before(c); err != nil {
-							return err
-						}
-					}
-
-					span = tracer.StartSpan(name)
-					span.SetTag("command", strings.Join(os.Args, " "))
-
-					ctx = logger.WithContext(ctx)
-					ctx = logutil.WithLogWriter(ctx, writer)
-					ctx = opentracing.ContextWithSpan(ctx, span)
-
-					c.App.Metadata["context"] = ctx
-					return nil
-				}
-			}(subcmd.Before)
+			name := strings.Join([]string{cmd.Name, subcmd.Name}, " ")
+			app.Commands[i].Subcommands[j].Before = withContext(name, subcmd.Before)
 		}
 	}
 
 	after := app.After
 	app.After = func(c *cli.Context) error {
+		interrupted := ctx.Err() == context.Canceled
+		if cancel != nil {
+			defer cancel()
+		}
+		if ih != nil {
+			ih.Close()
+			interrupt()
+		}
+
 		if after != nil {
 			if err := after(c); err != nil {
 				return err
@@ -94,8 +146,15 @@ func AttachAppContext(ctx context.Context, app *cli.App) {
 		}
 
 		if span != nil {
+			if interrupted {
+				span.SetTag("interrupted", true)
+			}
 			span.Finish()
 		}
+
+		if closer == nil {
+			return nil
+		}
 		return closer.Close()
 	}
 }
@@ -104,14 +163,45 @@ func AttachAppClient(app *cli.App) {
 	app.Before = cliutil.JoinBefore(app.Before, func(c *cli.Context) error {
 		var opts []httputil.ClientOption
 		if c.GlobalString("log-level") == "debug" {
-			logger, _, err := newLogger(c)
-			if err != nil {
-				return err
+			logger, ok := app.Metadata["logger"].(*zerolog.Logger)
+			if !ok {
+				var err error
+				logger, _, err = newLogger(c)
+				if err != nil {
+					return err
+				}
 			}
 
 			opts = append(opts, httputil.WithLogger(logger))
 		}
 
+		if c.GlobalIsSet("tls-cert") || c.GlobalIsSet("tls-key") || c.GlobalIsSet("tls-ca") {
+			opts = append(opts, httputil.WithClientCert(c.GlobalString("tls-cert"), c.GlobalString("tls-key"), c.GlobalString("tls-ca")))
+		}
+
+		if timeout := c.GlobalDuration("request-timeout"); timeout > 0 {
+			opts = append(opts, httputil.WithTimeout(timeout))
+		}
+
+		if c.GlobalBool("compress") {
+			opts = append(opts, httputil.WithCompression())
+		}
+
+		if retries := c.GlobalInt("retries"); retries > 0 {
+			opts = append(opts, httputil.WithRetry(retries, time.Second))
+		}
+
+		if token := c.GlobalString("token"); token != "" {
+			opts = append(opts, httputil.WithBearerToken(token))
+		}
+
+		if !c.GlobalBool("no-cache") {
+			home, err := os.UserHomeDir()
+			if err == nil {
+				opts = append(opts, httputil.WithCache(filepath.Join(home, ".labctl", "cache"), c.GlobalDuration("cache-ttl")))
+			}
+		}
+
 		client, err := httputil.NewClient(httputil.NewHTTPClient(), opts...)
 		if err != nil {
 			return err
@@ -123,7 +213,24 @@ func AttachAppClient(app *cli.App) {
 }
 
 func CommandPrinter(c *cli.Context, auto printer.OutputType) (printer.Printer, error) {
-	return printer.GetPrinter(printer.OutputType(c.GlobalString("output")), auto)
+	var opts []printer.PrinterOption
+	if c.GlobalIsSet("format") {
+		opts = append(opts, printer.WithFormat(c.GlobalString("format")))
+	} else if c.GlobalIsSet("format-file") {
+		content, err := ioutil.ReadFile(c.GlobalString("format-file"))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read format file")
+		}
+		opts = append(opts, printer.WithFormat(string(content)))
+	}
+
+	output := printer.OutputType(c.GlobalString("output"))
+	if output == printer.OutputAuto {
+		output = auto
+	}
+	TagSpan(c, tagOutput, string(output))
+
+	return printer.GetPrinter(printer.OutputType(c.GlobalString("output")), auto, opts...)
 }
 
 func CommandClient(c *cli.Context) *httputil.Client {
@@ -131,14 +238,9 @@ func CommandClient(c *cli.Context) *httputil.Client {
 }
 
 func newLogger(c *cli.Context) (*zerolog.Logger, io.Writer, error) {
-	var out io.Writer
-	switch c.GlobalString("log-writer") {
-	case "console":
-		out = zerolog.ConsoleWriter{Out: os.Stderr}
-	case "json":
-		out = os.Stderr
-	default:
-		return nil, nil, errors.Wrapf(errdefs.ErrInvalidArgument, "unknown log writer %q", c.GlobalString("log-writer"))
+	out, err := newLogWriter(c)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	level, err := zerolog.ParseLevel(c.GlobalString("log-level"))
@@ -153,6 +255,81 @@ func newLogger(c *cli.Context) (*zerolog.Logger, io.Writer, error) {
 	return &logger, out, nil
 }
 
-func ExtractNameFromFilename(filename string) string {
-	return strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+func newLogWriter(c *cli.Context) (io.Writer, error) {
+	logWriter := c.GlobalString("log-writer")
+	switch logWriter {
+	case "console", "json":
+	default:
+		return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "unknown log writer %q", logWriter)
+	}
+
+	logOutput := c.GlobalString("log-output")
+	if logOutput == "" {
+		logOutput = "stderr"
+		if c.GlobalIsSet("log-file") {
+			logOutput = "file"
+		}
+	}
+
+	var outs []io.Writer
+	switch logOutput {
+	case "stderr":
+		outs = append(outs, os.Stderr)
+	case "file", "multi":
+		filename := c.GlobalString("log-file")
+		if filename == "" {
+			return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "log output %q requires a log file", logOutput)
+		}
+
+		if logOutput == "multi" {
+			outs = append(outs, os.Stderr)
+		}
+		outs = append(outs, &lumberjack.Logger{
+			Filename:   filename,
+			MaxSize:    c.GlobalInt("log-max-size"),
+			MaxBackups: c.GlobalInt("log-max-backups"),
+			MaxAge:     c.GlobalInt("log-max-age"),
+		})
+	default:
+		return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "unknown log output %q", logOutput)
+	}
+
+	// Only colorize console output to stderr when it's an interactive terminal.
+	noColor := c.GlobalBool("no-color") || !term.IsTerminal(int(os.Stderr.Fd()))
+
+	var writers []io.Writer
+	for _, out := range outs {
+		if logWriter == "console" {
+			out = zerolog.ConsoleWriter{Out: out, NoColor: noColor || out != os.Stderr}
+		}
+		writers = append(writers, out)
+	}
+
+	if len(writers) == 1 {
+		return writers[0], nil
+	}
+	return zerolog.MultiLevelWriter(writers...), nil
+}
+
+// definitionFilename returns the definition file of a create command, given
+// either as its only argument or with --file.
+func definitionFilename(c *cli.Context) (string, bool) {
+	switch {
+	case c.IsSet("file") && c.NArg() == 0:
+		return c.String("file"), true
+	case !c.IsSet("file") && c.NArg() == 1:
+		return c.Args().First(), true
+	default:
+		return "", false
+	}
+}
+
+// ExtractNameFromFilename derives the name of a resource from the base name of
+// its definition file, without its extension and sanitized to a valid name.
+// A definition read from the standard input has no name to derive.
+func ExtractNameFromFilename(filename string) (string, error) {
+	if filename == cliutil.Stdin {
+		return "", errors.Wrap(errdefs.ErrInvalidArgument, "--name is required when reading a definition from stdin")
+	}
+	return metadata.SanitizeName(strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename)))
 }
@@ -0,0 +1,287 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/pkg/cliutil"
+	"github.com/urfave/cli"
+)
+
+var completionCommand = cli.Command{
+	Name:  "completion",
+	Usage: "Generates shell completion scripts.",
+	Subcommands: []cli.Command{
+		{
+			Name:      "bash",
+			Usage:     "Generates a bash completion script.",
+			ArgsUsage: " ",
+			Action:    completionAction(bashCompletion),
+		},
+		{
+			Name:      "zsh",
+			Usage:     "Generates a zsh completion script.",
+			ArgsUsage: " ",
+			Action:    completionAction(zshCompletion),
+		},
+		{
+			Name:      "fish",
+			Usage:     "Generates a fish completion script.",
+			ArgsUsage: " ",
+			Action:    completionAction(fishCompletion),
+		},
+	},
+}
+
+var completeCommand = cli.Command{
+	Name:            "__complete",
+	Usage:           "Prints completion candidates for the given words.",
+	ArgsUsage:       "[<word> ...]",
+	Hidden:          true,
+	SkipFlagParsing: true,
+	Action:          completeAction,
+}
+
+var completionTimeout = 2 * time.Second
+
+const bashCompletion = `_labctl_complete() {
+	local cur="${COMP_WORDS[COMP_CWORD]}"
+	local words=("${COMP_WORDS[@]:1:COMP_CWORD}")
+	COMPREPLY=($(compgen -W "$(labctl __complete "${words[@]}" 2>/dev/null)" -- "$cur"))
+}
+
+complete -o default -F _labctl_complete labctl
+`
+
+const zshCompletion = `#compdef labctl
+
+_labctl() {
+	local -a candidates
+	candidates=(${(f)"$(labctl __complete "${(@)words[2,CURRENT]}" 2>/dev/null)"})
+	compadd -a candidates
+}
+
+compdef _labctl labctl
+`
+
+const fishCompletion = `function __labctl_complete
+	set -l tokens (commandline -opc)
+	set -e tokens[1]
+	labctl __complete $tokens (commandline -ct) 2>/dev/null
+end
+
+complete -c labctl -f -a '(__labctl_complete)'
+`
+
+func completionAction(script string) func(c *cli.Context) error {
+	return func(c *cli.Context) error {
+		fmt.Print(script)
+		return nil
+	}
+}
+
+// completeAction prints candidates for the last word given, which may be
+// empty. Subcommands and flags are derived from the app's command tree, and
+// arguments are completed by querying labd for the resources named in a
+// command's ArgsUsage.
+func completeAction(c *cli.Context) error {
+	words := []string(c.Args())
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	current := words[len(words)-1]
+
+	var (
+		cmd, subcmd *cli.Command
+		args        []string
+	)
+	for _, word := range words[:len(words)-1] {
+		if strings.HasPrefix(word, "-") {
+			continue
+		}
+
+		switch {
+		case cmd == nil:
+			cmd = findCommand(c.App.Commands, word)
+		case subcmd == nil:
+			subcmd = findCommand(cmd.Subcommands, word)
+		default:
+			args = append(args, word)
+		}
+
+		// Stop completing if a word doesn't match the command tree.
+		if cmd == nil || (subcmd == nil && len(args) > 0) {
+			return nil
+		}
+	}
+
+	var candidates []string
+	switch {
+	case strings.HasPrefix(current, "-"):
+		flags := c.App.Flags
+		if subcmd != nil {
+			flags = subcmd.Flags
+		} else if cmd != nil {
+			flags = cmd.Flags
+		}
+		candidates = flagNames(flags)
+	case cmd == nil:
+		candidates = commandNames(c.App.Commands)
+	case subcmd == nil:
+		candidates = commandNames(cmd.Subcommands)
+	default:
+		control, err := ResolveControl(c)
+		if err != nil {
+			return nil
+		}
+
+		// Completion must stay responsive even if labd is unreachable.
+		ctx, cancel := context.WithTimeout(cliutil.CommandContext(c), completionTimeout)
+		defer cancel()
+
+		resource := argResource(cmd.Name, subcmd.ArgsUsage, len(args))
+		candidates, err = resourceNames(ctx, control, resource, args)
+		if err != nil {
+			return nil
+		}
+	}
+
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, current) {
+			fmt.Println(candidate)
+		}
+	}
+	return nil
+}
+
+func findCommand(cmds []cli.Command, name string) *cli.Command {
+	for i := range cmds {
+		if cmds[i].HasName(name) {
+			return &cmds[i]
+		}
+	}
+	return nil
+}
+
+func commandNames(cmds []cli.Command) []string {
+	var names []string
+	for _, cmd := range cmds {
+		if cmd.Hidden {
+			continue
+		}
+		names = append(names, cmd.Name)
+	}
+	return names
+}
+
+func flagNames(flags []cli.Flag) []string {
+	var names []string
+	for _, flag := range flags {
+		for _, name := range strings.Split(flag.GetName(), ",") {
+			name = strings.TrimSpace(name)
+			if len(name) == 1 {
+				names = append(names, "-"+name)
+			} else {
+				names = append(names, "--"+name)
+			}
+		}
+	}
+	return names
+}
+
+// argResource returns the resource type of the nth argument described by
+// argsUsage, e.g. "<cluster> <id>" or "[<name> ...]".
+func argResource(cmdName, argsUsage string, n int) string {
+	var usage []string
+	for _, field := range strings.Fields(argsUsage) {
+		if field == "..." || field == "...]" {
+			continue
+		}
+		usage = append(usage, strings.Trim(field, "[<>]"))
+	}
+	if len(usage) == 0 {
+		return ""
+	}
+
+	variadic := strings.Contains(argsUsage, "...")
+	if n >= len(usage) {
+		if !variadic {
+			return ""
+		}
+		n = len(usage) - 1
+	}
+
+	switch usage[n] {
+	case "name", "id":
+		return cmdName
+	default:
+		return usage[n]
+	}
+}
+
+func resourceNames(ctx context.Context, control p2plab.ControlAPI, resource string, args []string) ([]string, error) {
+	var names []string
+	switch resource {
+	case "cluster":
+		cs, err := control.Cluster().List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range cs {
+			names = append(names, c.Metadata().ID)
+		}
+	case "node":
+		if len(args) == 0 {
+			return nil, nil
+		}
+		ns, err := control.Node().List(ctx, args[0])
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range ns {
+			names = append(names, n.Metadata().ID)
+		}
+	case "scenario":
+		ss, err := control.Scenario().List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range ss {
+			names = append(names, s.Metadata().ID)
+		}
+	case "benchmark":
+		bs, err := control.Benchmark().List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range bs {
+			names = append(names, b.Metadata().ID)
+		}
+	case "experiment":
+		es, err := control.Experiment().List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range es {
+			names = append(names, e.Metadata().ID)
+		}
+	}
+	return names, nil
+}
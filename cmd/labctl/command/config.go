@@ -0,0 +1,96 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/Netflix/p2plab/pkg/cliutil"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+	"sigs.k8s.io/yaml"
+)
+
+// Config holds defaults for labctl's global flags.
+type Config struct {
+	Address   string `json:"address,omitempty"`
+	LogLevel  string `json:"log-level,omitempty"`
+	LogWriter string `json:"log-writer,omitempty"`
+	Output    string `json:"output,omitempty"`
+	Token     string `json:"token,omitempty"`
+}
+
+// AttachAppConfig sets up an app.Before that populates global flags from a
+// config file. Flags set explicitly on the command line or through their
+// environment variable take precedence over the config file.
+func AttachAppConfig(app *cli.App) {
+	app.Before = cliutil.JoinBefore(app.Before, func(c *cli.Context) error {
+		path := c.GlobalString("config")
+		if path == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil
+			}
+			path = filepath.Join(home, ".labctl", "config.yaml")
+		}
+
+		cfg, err := ParseConfig(path)
+		if err != nil {
+			if os.IsNotExist(errors.Cause(err)) {
+				return nil
+			}
+			return errors.Wrapf(err, "failed to parse config %q", path)
+		}
+
+		for _, field := range []struct {
+			name  string
+			value string
+		}{
+			{"address", cfg.Address},
+			{"log-level", cfg.LogLevel},
+			{"log-writer", cfg.LogWriter},
+			{"output", cfg.Output},
+			{"token", cfg.Token},
+		} {
+			if field.value == "" || c.GlobalIsSet(field.name) {
+				continue
+			}
+
+			err = c.GlobalSet(field.name, field.value)
+			if err != nil {
+				return errors.Wrapf(err, "failed to set %q from config", field.name)
+			}
+		}
+
+		return nil
+	})
+}
+
+func ParseConfig(filename string) (Config, error) {
+	var cfg Config
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return cfg, err
+	}
+
+	err = yaml.Unmarshal(content, &cfg)
+	if err != nil {
+		return cfg, err
+	}
+
+	return cfg, nil
+}
@@ -15,12 +15,19 @@
 package command
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"time"
 
+	"github.com/Netflix/p2plab"
 	"github.com/Netflix/p2plab/metadata"
 	"github.com/Netflix/p2plab/pkg/cliutil"
+	"github.com/Netflix/p2plab/pkg/httputil"
+	"github.com/Netflix/p2plab/printer"
+	"github.com/rs/zerolog"
 	"github.com/urfave/cli"
 )
 
@@ -44,11 +51,62 @@ var debugCommand = cli.Command{
 				},
 			},
 		},
+		{
+			Name:      "stats",
+			Aliases:   []string{"s"},
+			Usage:     "Retrieves the repo and network stats of a labapp.",
+			ArgsUsage: " ",
+			Action:    statsAction,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "app-addr",
+					Usage: "address for labapp's HTTP server",
+					Value: "http://localhost:7003",
+				},
+			},
+		},
+		{
+			Name:      "update",
+			Aliases:   []string{"u"},
+			Usage:     "Updates a labagent to a labapp binary and waits for it to be healthy.",
+			ArgsUsage: "<link>",
+			Action:    updateAgentAction,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "agent-addr",
+					Usage: "address for labagent's HTTP server",
+					Value: "http://localhost:7002",
+				},
+				&cli.StringFlag{
+					Name:  "app-addr",
+					Usage: "address for labapp's HTTP server",
+					Value: "http://localhost:7003",
+				},
+				&cli.StringFlag{
+					Name:  "id",
+					Usage: "node id to pass to labapp",
+					Value: "debug",
+				},
+				&cli.StringFlag{
+					Name:  "peer,p",
+					Usage: "Update to a peer definition, otherwise the default is used.",
+				},
+				&cli.StringFlag{
+					Name:  "sha256",
+					Usage: "expected sha256 digest of the binary, the agent refuses to update on mismatch",
+				},
+				&cli.DurationFlag{
+					Name:  "wait",
+					Usage: "duration to wait for labapp to be healthy",
+					Value: 30 * time.Second,
+				},
+			},
+		},
 		{
 			Name:      "run",
 			Aliases:   []string{"r"},
 			Usage:     "Runs a task on a labapp.",
-			ArgsUsage: "<task> <subject>",
+			ArgsUsage: "<task> <subject> [<subject> ...]",
 			Action:    runTaskAction,
 			Flags: []cli.Flag{
 				&cli.StringFlag{
@@ -56,6 +114,29 @@ var debugCommand = cli.Command{
 					Usage: "address for labapp's HTTP server",
 					Value: "http://localhost:7003",
 				},
+				&cli.BoolFlag{
+					Name:  "async",
+					Usage: "queue the tasks and print their IDs without waiting for them, see debug task",
+				},
+			},
+		},
+		{
+			Name:      "task",
+			Aliases:   []string{"t"},
+			Usage:     "Waits for a task queued on a labapp with run --async to finish.",
+			ArgsUsage: "<id>",
+			Action:    taskAction,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "app-addr",
+					Usage: "address for labapp's HTTP server",
+					Value: "http://localhost:7003",
+				},
+				&cli.DurationFlag{
+					Name:  "interval",
+					Usage: "how often the status of the task is polled",
+					Value: time.Second,
+				},
 			},
 		},
 	},
@@ -82,9 +163,102 @@ func peerInfoAction(c *cli.Context) error {
 	return nil
 }
 
+func statsAction(c *cli.Context) error {
+	p, err := CommandPrinter(c, printer.OutputTable)
+	if err != nil {
+		return err
+	}
+
+	app, err := ResolveApp(c, c.String("app-addr"))
+	if err != nil {
+		return err
+	}
+
+	ctx := cliutil.CommandContext(c)
+	stats, err := app.Stats(ctx)
+	if err != nil {
+		return err
+	}
+
+	return p.Print(stats)
+}
+
+func updateAgentAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("link must be provided")
+	}
+
+	pdef := metadata.DefaultPeerDefinition
+	if c.IsSet("peer") {
+		content, err := ioutil.ReadFile(c.String("peer"))
+		if err != nil {
+			return err
+		}
+
+		err = json.Unmarshal(content, &pdef)
+		if err != nil {
+			return err
+		}
+	}
+
+	agent, err := ResolveAgent(c, c.String("agent-addr"))
+	if err != nil {
+		return err
+	}
+
+	var opts []p2plab.UpdateOption
+	if c.IsSet("sha256") {
+		opts = append(opts, p2plab.WithUpdateSHA256(c.String("sha256")))
+	}
+
+	ctx := cliutil.CommandContext(c)
+	err = agent.Update(ctx, c.String("id"), c.Args().First(), pdef, opts...)
+	if err != nil {
+		return err
+	}
+
+	return waitHealthy(ctx, CommandClient(c), c.String("app-addr"), c.Duration("wait"))
+}
+
+// waitHealthy polls the healthcheck endpoint with exponential backoff until it
+// succeeds or the wait duration has elapsed.
+func waitHealthy(ctx context.Context, client *httputil.Client, addr string, wait time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+
+	backoff := 250 * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		req := client.NewRequest("GET", fmt.Sprintf("%s/healthcheck", addr), httputil.WithRetryMax(0))
+		resp, err := req.Send(ctx)
+		if err == nil {
+			resp.Body.Close()
+			return nil
+		}
+		zerolog.Ctx(ctx).Debug().Err(err).Int("attempt", attempt).Str("addr", addr).Msg("labapp unhealthy")
+
+		select {
+		case <-ctx.Done():
+			return errors.New("labapp unhealthy")
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > 5*time.Second {
+			backoff = 5 * time.Second
+		}
+	}
+}
+
 func runTaskAction(c *cli.Context) error {
-	if c.NArg() != 2 {
-		return errors.New("task type and subject must be provided")
+	taskType := metadata.TaskType(c.Args().Get(0))
+	subjects := c.Args().Tail()
+	if len(subjects) == 0 {
+		// Garbage collection keeps nothing without a subject, and dropping the
+		// page cache takes none.
+		if taskType != metadata.TaskGC && taskType != metadata.TaskDropCache {
+			return errors.New("task type and subject must be provided")
+		}
+		subjects = []string{""}
 	}
 
 	app, err := ResolveApp(c, c.String("app-addr"))
@@ -93,13 +267,95 @@ func runTaskAction(c *cli.Context) error {
 	}
 
 	ctx := cliutil.CommandContext(c)
-	err = app.Run(ctx, metadata.Task{
-		Type:    metadata.TaskType(c.Args().Get(0)),
-		Subject: c.Args().Get(1),
-	})
+	if c.Bool("async") {
+		for _, subject := range subjects {
+			status, err := app.RunAsync(ctx, metadata.Task{
+				Type:    taskType,
+				Subject: subject,
+			})
+			if err != nil {
+				return err
+			}
+			fmt.Println(status.ID)
+		}
+		return nil
+	}
+
+	if len(subjects) == 1 {
+		return app.Run(ctx, metadata.Task{
+			Type:    taskType,
+			Subject: subjects[0],
+		})
+	}
+
+	// Run the task against every subject, so that one failure doesn't abort the
+	// rest.
+	var failed int
+	for _, subject := range subjects {
+		err = app.Run(ctx, metadata.Task{
+			Type:    taskType,
+			Subject: subject,
+		})
+		if err != nil {
+			failed++
+			fmt.Printf("%s\tfailed: %s\n", subject, err)
+			continue
+		}
+		fmt.Printf("%s\tok\n", subject)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d tasks failed", failed, len(subjects))
+	}
+
+	return nil
+}
+
+func taskAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("task id must be provided")
+	}
+
+	app, err := ResolveApp(c, c.String("app-addr"))
+	if err != nil {
+		return err
+	}
+
+	ctx := cliutil.CommandContext(c)
+	id := c.Args().First()
+
+	_, progress := startProgress(ctx, c, "Running task", formatCount)
+	status, err := pollTask(ctx, app, id, c.Duration("interval"), progress.Set)
+	progress.Stop()
 	if err != nil {
 		return err
 	}
 
+	fmt.Printf("%s\t%s\t%s\n", status.ID, status.State, status.FinishedAt.Sub(status.StartedAt))
+	if status.State == metadata.TaskFailed {
+		return fmt.Errorf("task %q failed: %s", status.ID, status.Error)
+	}
 	return nil
 }
+
+// pollTask polls the status of a task until it is finished, reporting its
+// progress to fn.
+func pollTask(ctx context.Context, app p2plab.AppAPI, id string, interval time.Duration, fn func(completed, total int64)) (metadata.TaskStatus, error) {
+	for {
+		status, err := app.GetTask(ctx, id)
+		if err != nil {
+			return status, err
+		}
+		fn(status.Completed, status.Total)
+
+		if status.Finished() {
+			return status, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return status, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
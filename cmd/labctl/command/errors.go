@@ -0,0 +1,161 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/pkg/cliutil"
+	"github.com/Netflix/p2plab/pkg/httputil"
+	"github.com/Netflix/p2plab/printer"
+	"github.com/urfave/cli"
+)
+
+// ErrorOutput is the structured form of an error printed by machine-readable
+// printers.
+type ErrorOutput struct {
+	Error     string `json:"error"`
+	Code      string `json:"code"`
+	RequestID string `json:"requestId,omitempty"`
+}
+
+type printedError struct {
+	error
+}
+
+func (e *printedError) Cause() error {
+	return e.error
+}
+
+// requestError annotates the error of a command with its request ID, so that
+// it can be looked up in the logs of labd.
+type requestError struct {
+	error
+	id string
+}
+
+func (e *requestError) Error() string {
+	return fmt.Sprintf("%s (request ID %s)", e.error, e.id)
+}
+
+func (e *requestError) Cause() error {
+	return e.error
+}
+
+// IsPrintedError returns true if the error has already been printed by the
+// configured printer.
+func IsPrintedError(err error) bool {
+	_, ok := err.(*printedError)
+	return ok
+}
+
+// AttachAppErrorPrinter wraps every command's action so that failures are
+// printed as structured errors when a machine-readable output is configured.
+func AttachAppErrorPrinter(app *cli.App) {
+	attachErrorPrinter(app.Commands)
+}
+
+func attachErrorPrinter(cmds cli.Commands) {
+	for i, cmd := range cmds {
+		attachErrorPrinter(cmd.Subcommands)
+
+		action, ok := cmd.Action.(func(*cli.Context) error)
+		if !ok {
+			continue
+		}
+		cmds[i].Action = errorPrinterAction(action)
+	}
+}
+
+func errorPrinterAction(action func(*cli.Context) error) func(*cli.Context) error {
+	return func(c *cli.Context) error {
+		err := action(c)
+		if err == nil {
+			return nil
+		}
+
+		id := httputil.RequestID(cliutil.CommandContext(c))
+		output := printer.OutputType(c.GlobalString("output"))
+		if output != printer.OutputJSON && output != printer.OutputJSONL && output != printer.OutputYAML {
+			if id == "" {
+				return err
+			}
+			return &requestError{err, id}
+		}
+
+		p, perr := printer.GetPrinter(output, "")
+		if perr != nil {
+			return err
+		}
+
+		perr = p.Print(ErrorOutput{
+			Error:     err.Error(),
+			Code:      ErrorCode(err),
+			RequestID: id,
+		})
+		if perr != nil {
+			return err
+		}
+
+		return &printedError{err}
+	}
+}
+
+// ErrorCode returns a machine-readable code classifying the error.
+func ErrorCode(err error) string {
+	return string(errdefs.Code(err))
+}
+
+// ExitCode returns the exit status of labctl when a command fails with the
+// error, so that scripts can branch on the type of failure. The exit code of a
+// cli.ExitCoder in the chain of causes of err takes precedence, such as the
+// exit code of a command executed on nodes.
+func ExitCode(err error) int {
+	for cause := err; cause != nil; {
+		if exitCoder, ok := cause.(cli.ExitCoder); ok {
+			return exitCoder.ExitCode()
+		}
+
+		causer, ok := cause.(interface{ Cause() error })
+		if !ok {
+			break
+		}
+		cause = causer.Cause()
+	}
+
+	switch errdefs.Code(err) {
+	case errdefs.CodeInvalidArgument:
+		return 2
+	case errdefs.CodeNotFound:
+		return 3
+	case errdefs.CodeAlreadyExists:
+		return 4
+	case errdefs.CodeFailedPrecondition:
+		return 5
+	case errdefs.CodeUnavailable:
+		return 6
+	case errdefs.CodeNotImplemented:
+		return 7
+	case errdefs.CodeUnauthorized:
+		return 8
+	case errdefs.CodeRateLimited:
+		return 9
+	case errdefs.CodeCancelled:
+		return 130
+	default:
+		return 1
+	}
+}
@@ -19,9 +19,11 @@ import (
 
 	"github.com/Netflix/p2plab"
 	"github.com/Netflix/p2plab/experiments"
+	"github.com/Netflix/p2plab/metadata"
 	"github.com/Netflix/p2plab/pkg/cliutil"
 	"github.com/Netflix/p2plab/printer"
 	"github.com/Netflix/p2plab/query"
+	"github.com/Netflix/p2plab/reports"
 	"github.com/rs/zerolog"
 	"github.com/urfave/cli"
 )
@@ -35,12 +37,16 @@ var experimentCommand = cli.Command{
 			Name:      "create",
 			Aliases:   []string{"s"},
 			Usage:     "Creates an experiment from a definition file",
-			ArgsUsage: "<filename>",
+			ArgsUsage: "[<filename>]",
 			Action:    createExperimentAction,
 			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "file,f",
+					Usage: "Definition file to create the experiment from, or - to read it from stdin. Unlike scenario create, the file must hold a single JSON definition, not multiple documents separated by ---.",
+				},
 				&cli.StringFlag{
 					Name:  "name",
-					Usage: "Name of the experiment, by default takes the name of the experiment definition.",
+					Usage: "Name of the experiment, by default takes the name of the experiment definition. Required when reading from stdin.",
 				},
 			},
 		},
@@ -88,12 +94,55 @@ var experimentCommand = cli.Command{
 			ArgsUsage: "[<id> ...]",
 			Action:    removeExperimentsAction,
 		},
+		{
+			Name:      "sweep",
+			Usage:     "Benchmarks a scenario template across a grid of variable values.",
+			ArgsUsage: "<cluster>",
+			Action:    sweepExperimentAction,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "scenario",
+					Usage: "Scenario definition file with template variables, or - to read it from stdin.",
+				},
+				&cli.StringSliceFlag{
+					Name:  "var",
+					Usage: "Sweeps a template variable over values in the form key=v1,v2.",
+				},
+				&cli.StringFlag{
+					Name:  "name",
+					Usage: "Name of the experiment, by default takes the name of the scenario definition.",
+				},
+				&cli.IntFlag{
+					Name:  "concurrency",
+					Usage: "Number of benchmarks to run in parallel.",
+					Value: 1,
+				},
+				&cli.StringSliceFlag{
+					Name:  "tag,t",
+					Usage: "Adds a key=value tag to the experiment and its benchmarks, e.g. branch=feature-x.",
+				},
+			},
+		},
+		{
+			Name:      "diff",
+			Usage:     "Compares the results of two benchmark runs.",
+			ArgsUsage: "<benchmark-a> <benchmark-b>",
+			Action:    diffExperimentAction,
+			Flags: []cli.Flag{
+				cli.Float64Flag{
+					Name:  "threshold",
+					Usage: "Increase in percent beyond which a metric is highlighted as a regression.",
+					Value: reports.DefaultRegressionThreshold,
+				},
+			},
+		},
 	},
 }
 
 func createExperimentAction(c *cli.Context) error {
-	if c.NArg() != 1 {
-		return errors.New("experiment definition must be provided")
+	filename, ok := definitionFilename(c)
+	if !ok {
+		return errors.New("experiment definition must be provided as argument or with --file")
 	}
 
 	p, err := CommandPrinter(c, printer.OutputID)
@@ -101,10 +150,12 @@ func createExperimentAction(c *cli.Context) error {
 		return err
 	}
 
-	filename := c.Args().First()
 	name := c.String("name")
 	if name == "" {
-		name = ExtractNameFromFilename(filename)
+		name, err = ExtractNameFromFilename(filename)
+		if err != nil {
+			return err
+		}
 	}
 
 	edef, err := experiments.Parse(filename)
@@ -236,3 +287,47 @@ func removeExperimentsAction(c *cli.Context) error {
 
 	return nil
 }
+
+func diffExperimentAction(c *cli.Context) error {
+	if c.NArg() != 2 {
+		return errors.New("two benchmark ids must be provided")
+	}
+
+	p, err := CommandPrinter(c, printer.OutputTable)
+	if err != nil {
+		return err
+	}
+
+	control, err := ResolveControl(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := cliutil.CommandContext(c)
+
+	var (
+		benchmarks []metadata.Benchmark
+		rs         []metadata.Report
+	)
+	for _, id := range c.Args() {
+		benchmark, err := control.Benchmark().Get(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		report, err := benchmark.Report(ctx)
+		if err != nil {
+			return err
+		}
+
+		benchmarks = append(benchmarks, benchmark.Metadata())
+		rs = append(rs, report)
+	}
+
+	diff := reports.Diff(benchmarks[0], benchmarks[1], rs[0], rs[1], c.Float64("threshold"))
+	for _, warning := range diff.Warnings {
+		zerolog.Ctx(ctx).Warn().Msg(warning)
+	}
+
+	return p.Print(diff)
+}
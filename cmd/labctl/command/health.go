@@ -0,0 +1,96 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/Netflix/p2plab/pkg/cliutil"
+	"github.com/Netflix/p2plab/printer"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/urfave/cli"
+)
+
+var healthCommand = cli.Command{
+	Name:      "health",
+	Usage:     "Displays the health of labd and of the components it depends on.",
+	ArgsUsage: " ",
+	Action:    healthAction,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "agent-addr",
+			Usage: "Displays the health of the labagent at this address instead.",
+		},
+	},
+}
+
+func healthAction(c *cli.Context) error {
+	p, err := CommandPrinter(c, printer.OutputTable)
+	if err != nil {
+		return err
+	}
+
+	ctx := cliutil.CommandContext(c)
+	name := "labd"
+
+	var health metadata.Health
+	if c.IsSet("agent-addr") {
+		agent, err := ResolveAgent(c, c.String("agent-addr"))
+		if err != nil {
+			return err
+		}
+
+		name = "labagent"
+		health, err = agent.Health(ctx)
+		if err != nil {
+			return err
+		}
+	} else {
+		control, err := ResolveControl(c)
+		if err != nil {
+			return err
+		}
+
+		health, err = control.Health(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch printer.OutputType(c.GlobalString("output")) {
+	case printer.OutputJSON, printer.OutputJSONL, printer.OutputYAML, printer.OutputTemplate:
+		err = p.Print(health)
+	default:
+		l := make([]interface{}, len(health.Components))
+		for i, component := range health.Components {
+			l[i] = component
+		}
+		err = p.Print(l)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !health.Ready {
+		return errors.Wrapf(errdefs.ErrUnavailable, "%s is live but not ready", name)
+	}
+
+	zerolog.Ctx(ctx).Info().Msgf("%s is live and ready", name)
+	for _, limit := range health.RateLimits {
+		zerolog.Ctx(ctx).Info().Str("class", limit.Class).Str("limit", limit.String()).Msg("Requests are rate limited")
+	}
+	return nil
+}
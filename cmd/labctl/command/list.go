@@ -0,0 +1,93 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/pkg/stringutil"
+	"github.com/Netflix/p2plab/printer"
+	"github.com/urfave/cli"
+)
+
+var listFlags = []cli.Flag{
+	cli.IntFlag{
+		Name:  "limit",
+		Usage: "Lists at most this many results.",
+	},
+	cli.IntFlag{
+		Name:  "offset",
+		Usage: "Skips this many results.",
+	},
+	cli.StringFlag{
+		Name:  "page-token",
+		Usage: "Continues listing from the page token of a previous list.",
+	},
+	cli.StringFlag{
+		Name:  "order-by",
+		Usage: "Orders results by a field [name, created, updated, duration].",
+	},
+	cli.BoolFlag{
+		Name:  "desc",
+		Usage: "Orders results in descending order.",
+	},
+	cli.StringFlag{
+		Name:  "fields",
+		Usage: "Projects results to a comma-separated list of fields, e.g. name,address.",
+	},
+}
+
+func listOptions(c *cli.Context, p printer.Printer, next *string) []p2plab.ListOption {
+	opts := []p2plab.ListOption{
+		p2plab.WithLimit(c.Int("limit")),
+		p2plab.WithOffset(c.Int("offset")),
+		p2plab.WithPageToken(c.String("page-token")),
+		p2plab.WithOrderBy(c.String("order-by"), c.Bool("desc")),
+		p2plab.WithFields(listFields(c)...),
+		p2plab.WithNextPageToken(next),
+	}
+
+	// Streaming printers print results as labd streams them, so the list
+	// returns none left to print.
+	if sp, ok := p.(printer.StreamPrinter); ok {
+		opts = append(opts, p2plab.WithRecords(func(record json.RawMessage) error {
+			return sp.PrintRecord(record)
+		}))
+	}
+	return opts
+}
+
+func listFields(c *cli.Context) []string {
+	return stringutil.Coalesce(strings.Split(c.String("fields"), ","))
+}
+
+// printList prints a list projected to the requested fields, and as a page if
+// it was paginated, either on request or because labd caps the page size.
+func printList(c *cli.Context, p printer.Printer, l []interface{}, next string) error {
+	if fields := listFields(c); len(fields) > 0 {
+		var err error
+		l, err = printer.Project(l, fields)
+		if err != nil {
+			return err
+		}
+	}
+
+	if next == "" && !c.IsSet("limit") && !c.IsSet("offset") && !c.IsSet("page-token") {
+		return p.Print(l)
+	}
+	return p.Print(printer.Page{Items: l, NextPageToken: next})
+}
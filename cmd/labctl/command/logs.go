@@ -0,0 +1,131 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"time"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/pkg/cliutil"
+	"github.com/Netflix/p2plab/pkg/logutil"
+	"github.com/Netflix/p2plab/printer"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/urfave/cli"
+)
+
+var logsCommand = cli.Command{
+	Name:      "logs",
+	Usage:     "Displays the recent logs of labd.",
+	ArgsUsage: " ",
+	Action:    logsAction,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "level",
+			Usage: "Displays logs of this level and above [debug, info, warn, error, fatal, panic].",
+		},
+		&cli.StringFlag{
+			Name:  "since",
+			Usage: "Displays logs written after a RFC 3339 timestamp or a duration ago, e.g. 10m.",
+		},
+		&cli.BoolFlag{
+			Name:  "follow,f",
+			Usage: "Keeps streaming new logs.",
+		},
+	},
+}
+
+func logsAction(c *cli.Context) error {
+	var opts []p2plab.DaemonLogsOption
+	if c.IsSet("level") {
+		_, err := zerolog.ParseLevel(c.String("level"))
+		if err != nil {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid level %q", c.String("level"))
+		}
+		opts = append(opts, p2plab.WithDaemonLogsLevel(c.String("level")))
+	}
+	if c.IsSet("since") {
+		since, err := parseSince(c.String("since"), time.Now())
+		if err != nil {
+			return err
+		}
+		opts = append(opts, p2plab.WithDaemonLogsSince(since))
+	}
+	if c.Bool("follow") {
+		opts = append(opts, p2plab.WithDaemonLogsFollow())
+	}
+
+	control, err := ResolveControl(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := cliutil.CommandContext(c)
+	rc, err := control.Admin().Logs(ctx, opts...)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	// Machine-readable outputs receive the structured records untouched,
+	// otherwise they are rendered by the log writer.
+	var w io.Writer
+	switch printer.OutputType(c.GlobalString("output")) {
+	case printer.OutputJSON, printer.OutputJSONL:
+		w = os.Stdout
+	default:
+		w = logutil.LogWriter(ctx)
+		if w == nil {
+			w = os.Stderr
+		}
+	}
+
+	scanner := bufio.NewScanner(rc)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		_, err = w.Write(append(scanner.Bytes(), '\n'))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = scanner.Err()
+	if err != nil && ctx.Err() == nil {
+		return errors.Wrap(err, "failed to stream logs")
+	}
+
+	if c.Bool("follow") && ctx.Err() == nil {
+		zerolog.Ctx(ctx).Warn().Msg("Lost connection to labd")
+	}
+	return nil
+}
+
+// parseSince parses a RFC 3339 timestamp, or a duration before now.
+func parseSince(s string, now time.Time) (time.Time, error) {
+	d, err := time.ParseDuration(s)
+	if err == nil {
+		return now.Add(-d), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, errors.Wrapf(errdefs.ErrInvalidArgument, "invalid since %q, expected a RFC 3339 timestamp or a duration", s)
+	}
+	return t, nil
+}
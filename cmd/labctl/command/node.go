@@ -15,15 +15,28 @@
 package command
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/errdefs"
 	"github.com/Netflix/p2plab/metadata"
 	"github.com/Netflix/p2plab/pkg/cliutil"
+	"github.com/Netflix/p2plab/pkg/logutil"
 	"github.com/Netflix/p2plab/printer"
 	"github.com/Netflix/p2plab/query"
+	humanize "github.com/dustin/go-humanize"
 	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
 	"github.com/urfave/cli"
 )
 
@@ -62,12 +75,12 @@ var nodeCommand = cli.Command{
 			Usage:     "List nodes.",
 			ArgsUsage: "<cluster>",
 			Action:    listNodeAction,
-			Flags: []cli.Flag{
+			Flags: append([]cli.Flag{
 				cli.StringFlag{
 					Name:  "query,q",
 					Usage: "Runs a query to filter the listed nodes.",
 				},
-			},
+			}, listFlags...),
 		},
 		{
 			Name:      "update",
@@ -102,7 +115,114 @@ var nodeCommand = cli.Command{
 				},
 				cli.StringFlag{
 					Name:  "routing,r",
-					Usage: "Routing for libp2p [nil, kaddht]",
+					Usage: "Routing for libp2p [nil, kaddht, delegated, mdns]",
+				},
+				cli.StringFlag{
+					Name:  "routing-endpoint",
+					Usage: "IPFS HTTP API endpoint for the delegated routing.",
+				},
+				cli.StringFlag{
+					Name:  "datastore",
+					Usage: "Datastore backing the blockstore [badger, flatfs, memory]",
+				},
+			},
+		},
+		{
+			Name:      "exec",
+			Aliases:   []string{"x"},
+			Usage:     "Executes a command on nodes.",
+			ArgsUsage: "<cluster> -- <cmd> [args...]",
+			Action:    execNodesAction,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "query,q",
+					Usage: "Runs a query to execute on a subset of nodes.",
+				},
+				cli.IntFlag{
+					Name:  "parallel,p",
+					Usage: "Maximum number of nodes executing the command at once, unlimited if 0.",
+				},
+			},
+		},
+		{
+			Name:      "connect",
+			Usage:     "Connects nodes to other nodes.",
+			ArgsUsage: "<cluster>",
+			Action:    connectNodesAction,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "query,q",
+					Usage: "Runs a query to select the nodes dialing.",
+				},
+				cli.StringFlag{
+					Name:  "to",
+					Usage: "Runs a query to select the nodes being dialed.",
+				},
+				cli.BoolFlag{
+					Name:  "disconnect",
+					Usage: "Tears down the connections instead.",
+				},
+			},
+		},
+		{
+			Name:      "ping",
+			Usage:     "Measures the libp2p latency between every pair of nodes.",
+			ArgsUsage: "<cluster>",
+			Action:    pingNodesAction,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "query,q",
+					Usage: "Runs a query to ping between a subset of nodes.",
+				},
+				cli.IntFlag{
+					Name:  "count,c",
+					Usage: "Number of pings between each pair of nodes.",
+					Value: 3,
+				},
+				cli.IntFlag{
+					Name:  "parallel,p",
+					Usage: "Maximum number of pairs pinging at once, labd's default if 0.",
+				},
+			},
+		},
+		{
+			Name:      "bench-link",
+			Usage:     "Measures the libp2p throughput from nodes to other nodes.",
+			ArgsUsage: "<cluster>",
+			Action:    benchLinkNodesAction,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "from",
+					Usage: "Runs a query to select the nodes sending the payload.",
+				},
+				cli.StringFlag{
+					Name:  "to",
+					Usage: "Runs a query to select the nodes receiving the payload.",
+				},
+				cli.StringFlag{
+					Name:  "size",
+					Usage: "Size of the synthetic payload sent between each pair, such as \"100MB\".",
+					Value: "100MB",
+				},
+				cli.BoolFlag{
+					Name:  "bidirectional",
+					Usage: "Also measures the throughput from the receiving nodes back to the sending nodes.",
+				},
+			},
+		},
+		{
+			Name:      "logs",
+			Usage:     "Streams the p2p app logs of nodes.",
+			ArgsUsage: "<cluster>",
+			Action:    logsNodesAction,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "query,q",
+					Usage: "Runs a query to stream logs of a subset of nodes.",
+				},
+				cli.BoolFlag{
+					Name:  "follow,f",
+					Usage: "Keeps streaming new logs, reconnecting to nodes when disconnected.",
 				},
 			},
 		},
@@ -132,6 +252,7 @@ func inspectNodeAction(c *cli.Context) error {
 
 	ctx := cliutil.CommandContext(c)
 	cluster := c.Args().First()
+	TagSpan(c, tagCluster, cluster)
 	id := c.Args().Get(1)
 	node, err := control.Node().Get(ctx, cluster, id)
 	if err != nil {
@@ -163,6 +284,7 @@ func labelNodesAction(c *cli.Context) error {
 
 	ctx := cliutil.CommandContext(c)
 	cluster := c.Args().First()
+	TagSpan(c, tagCluster, cluster)
 	nodes, err := control.Node().Label(ctx, cluster, ids, c.StringSlice("add"), c.StringSlice("remove"))
 	if err != nil {
 		return err
@@ -225,6 +347,12 @@ func updateNodesAction(c *cli.Context) error {
 	if c.IsSet("routing") {
 		pdef.Routing = c.String("routing")
 	}
+	if c.IsSet("routing-endpoint") {
+		pdef.RoutingEndpoint = c.String("routing-endpoint")
+	}
+	if c.IsSet("datastore") {
+		pdef.Datastore = c.String("datastore")
+	}
 
 	control, err := ResolveControl(c)
 	if err != nil {
@@ -232,6 +360,7 @@ func updateNodesAction(c *cli.Context) error {
 	}
 
 	cid := c.Args().Get(0)
+	TagSpan(c, tagCluster, cid)
 	cluster, err := control.Cluster().Get(ctx, cid)
 	if err != nil {
 		return err
@@ -276,18 +405,516 @@ func listNodeAction(c *cli.Context) error {
 		opts = append(opts, p2plab.WithQuery(q.String()))
 	}
 
+	var next string
+	opts = append(opts, listOptions(c, p, &next)...)
+
 	cluster := c.Args().First()
+	TagSpan(c, tagCluster, cluster)
 	nodes, err := control.Node().List(ctx, cluster, opts...)
 	if err != nil {
 		return err
 	}
+	TagSpanCount(c, tagNodes, len(nodes))
 
 	l := make([]interface{}, len(nodes))
 	for i, n := range nodes {
 		l[i] = n.Metadata()
 	}
 
-	return p.Print(l)
+	return printList(c, p, l, next)
+}
+
+func execNodesAction(c *cli.Context) error {
+	args := c.Args()
+	if len(args) < 1 {
+		return errors.New("cluster id must be provided")
+	}
+	cluster, args := args[0], args[1:]
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return errors.New("command must be provided")
+	}
+
+	control, err := ResolveControl(c)
+	if err != nil {
+		return err
+	}
+
+	var opts []p2plab.ListOption
+	ctx := cliutil.CommandContext(c)
+	if c.IsSet("query") {
+		q, err := query.Parse(ctx, c.String("query"))
+		if err != nil {
+			return err
+		}
+
+		opts = append(opts, p2plab.WithQuery(q.String()))
+	}
+
+	TagSpan(c, tagCluster, cluster)
+	ns, err := control.Node().List(ctx, cluster, opts...)
+	if err != nil {
+		return err
+	}
+	TagSpanCount(c, tagNodes, len(ns))
+
+	parallel := c.Int("parallel")
+	if parallel <= 0 {
+		parallel = len(ns)
+	}
+
+	var (
+		mux   = logutil.NewLineMux(os.Stdout)
+		sem   = make(chan struct{}, parallel)
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		codes = make(map[string]int)
+	)
+	for _, n := range ns {
+		n := n
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			w := mux.Writer(n.ID())
+			defer w.Close()
+
+			code, err := n.Exec(ctx, args, w)
+			if err != nil {
+				zerolog.Ctx(ctx).Error().Str("node", n.ID()).Err(err).Msg("Failed to execute command")
+				code = 1
+			}
+			// Commands killed by a signal without an exit code still failed.
+			if code < 0 {
+				code = 1
+			}
+
+			mu.Lock()
+			codes[n.ID()] = code
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	var (
+		failed   []string
+		exitCode int
+	)
+	for id, code := range codes {
+		if code == 0 {
+			continue
+		}
+		failed = append(failed, id)
+		if code > exitCode {
+			exitCode = code
+		}
+	}
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return cli.NewExitError(fmt.Sprintf("command failed on %d of %d nodes: %s", len(failed), len(ns), strings.Join(failed, ",")), exitCode)
+	}
+
+	return nil
+}
+
+func connectNodesAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("cluster name must be provided")
+	}
+
+	p, err := CommandPrinter(c, printer.OutputTable)
+	if err != nil {
+		return err
+	}
+
+	control, err := ResolveControl(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := cliutil.CommandContext(c)
+	var queries []string
+	for _, name := range []string{"query", "to"} {
+		q, err := query.Parse(ctx, c.String(name))
+		if err != nil {
+			return err
+		}
+		queries = append(queries, q.String())
+	}
+
+	var (
+		opts   []p2plab.ConnectOption
+		status = "connected"
+	)
+	if c.Bool("disconnect") {
+		opts = append(opts, p2plab.WithDisconnect())
+		status = "disconnected"
+	}
+
+	TagSpan(c, tagCluster, c.Args().First())
+	conns, err := control.Node().Connect(ctx, c.Args().First(), queries[0], queries[1], opts...)
+	if err != nil {
+		return err
+	}
+
+	var failed int
+	l := make([]interface{}, len(conns))
+	for i, conn := range conns {
+		s := status
+		if conn.Error != "" {
+			s = "failed"
+			failed++
+		}
+		l[i] = printer.Projection{
+			Fields: []string{"source", "target", "status", "error"},
+			Values: []interface{}{conn.Source, conn.Target, s, conn.Error},
+		}
+	}
+
+	err = p.Print(l)
+	if err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return errors.Errorf("failed %d of %d connections", failed, len(conns))
+	}
+
+	return nil
+}
+
+func pingNodesAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("cluster name must be provided")
+	}
+
+	p, err := CommandPrinter(c, printer.OutputTable)
+	if err != nil {
+		return err
+	}
+
+	control, err := ResolveControl(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := cliutil.CommandContext(c)
+	q, err := query.Parse(ctx, c.String("query"))
+	if err != nil {
+		return err
+	}
+
+	TagSpan(c, tagCluster, c.Args().First())
+	pings, err := control.Node().Ping(ctx, c.Args().First(), q.String(),
+		p2plab.WithPingCount(c.Int("count")),
+		p2plab.WithPingParallel(c.Int("parallel")),
+	)
+	if err != nil {
+		return err
+	}
+
+	var unreachable int
+	for _, ping := range pings {
+		if ping.Error != "" {
+			zerolog.Ctx(ctx).Warn().Str("source", ping.Source).Str("target", ping.Target).Str("error", ping.Error).Msg("Node is unreachable")
+			unreachable++
+		}
+	}
+
+	matrix := metadata.NewLatencyMatrix(pings)
+	TagSpanCount(c, tagNodes, len(matrix))
+	switch printer.OutputType(c.GlobalString("output")) {
+	case printer.OutputJSON, printer.OutputJSONL, printer.OutputYAML, printer.OutputTemplate:
+		err = p.Print(matrix)
+	default:
+		err = p.Print(latencyRows(matrix))
+	}
+	if err != nil {
+		return err
+	}
+
+	if unreachable > 0 {
+		return errors.Errorf("%d of %d pairs of nodes are unreachable", unreachable, len(pings))
+	}
+	return nil
+}
+
+// latencyRows returns a row for every source node of a latency matrix, with a
+// column for every target node. A node's latency to itself is "-".
+func latencyRows(matrix metadata.LatencyMatrix) []interface{} {
+	set := make(map[string]struct{})
+	for source, row := range matrix {
+		set[source] = struct{}{}
+		for target := range row {
+			set[target] = struct{}{}
+		}
+	}
+
+	var ids []string
+	for id := range set {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	rows := make([]interface{}, len(ids))
+	for i, source := range ids {
+		row := printer.Projection{
+			Fields: append([]string{"source"}, ids...),
+			Values: []interface{}{source},
+		}
+		for _, target := range ids {
+			latency, ok := matrix[source][target]
+			if !ok {
+				latency = "-"
+			}
+			row.Values = append(row.Values, latency)
+		}
+		rows[i] = row
+	}
+	return rows
+}
+
+func benchLinkNodesAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("cluster name must be provided")
+	}
+
+	size, err := humanize.ParseBytes(c.String("size"))
+	if err != nil || size == 0 {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid size %q", c.String("size"))
+	}
+
+	p, err := CommandPrinter(c, printer.OutputTable)
+	if err != nil {
+		return err
+	}
+
+	control, err := ResolveControl(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := cliutil.CommandContext(c)
+	var queries []string
+	for _, name := range []string{"from", "to"} {
+		q, err := query.Parse(ctx, c.String(name))
+		if err != nil {
+			return err
+		}
+		queries = append(queries, q.String())
+	}
+
+	var opts []p2plab.BenchLinkOption
+	if c.Bool("bidirectional") {
+		opts = append(opts, p2plab.WithBidirectional())
+	}
+
+	TagSpan(c, tagCluster, c.Args().First())
+	links, err := control.Node().BenchLink(ctx, c.Args().First(), queries[0], queries[1], int64(size), opts...)
+	if err != nil {
+		return err
+	}
+
+	var failed int
+	l := make([]interface{}, len(links))
+	for i, link := range links {
+		mbps := fmt.Sprintf("%.1f", link.Mbps())
+		if link.Error != "" {
+			mbps = "failed"
+			failed++
+		}
+		l[i] = printer.Projection{
+			Fields: []string{"source", "target", "bytes", "duration", "mbps", "error"},
+			Values: []interface{}{link.Source, link.Target, link.Bytes, link.Duration, mbps, link.Error},
+		}
+	}
+
+	err = p.Print(l)
+	if err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return errors.Errorf("failed %d of %d links", failed, len(links))
+	}
+	return nil
+}
+
+func logsNodesAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("cluster id must be provided")
+	}
+
+	control, err := ResolveControl(c)
+	if err != nil {
+		return err
+	}
+
+	var opts []p2plab.ListOption
+	ctx := cliutil.CommandContext(c)
+	if c.IsSet("query") {
+		q, err := query.Parse(ctx, c.String("query"))
+		if err != nil {
+			return err
+		}
+
+		opts = append(opts, p2plab.WithQuery(q.String()))
+	}
+
+	TagSpan(c, tagCluster, c.Args().First())
+	ns, err := control.Node().List(ctx, c.Args().First(), opts...)
+	if err != nil {
+		return err
+	}
+	TagSpanCount(c, tagNodes, len(ns))
+
+	writer := logutil.LogWriter(ctx)
+	if writer == nil {
+		writer = os.Stderr
+	}
+	nw := &nodeLogWriter{w: writer}
+
+	var wg sync.WaitGroup
+	for _, n := range ns {
+		n := n
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			streamNodeLogs(ctx, n, c.Bool("follow"), nw)
+		}()
+	}
+	wg.Wait()
+
+	return nil
+}
+
+const (
+	logsBackoffMin = time.Second
+	logsBackoffMax = 30 * time.Second
+)
+
+// streamNodeLogs writes the logs of a node until the stream ends. When
+// following, it reconnects with backoff and resumes after the last line
+// received, warning about any lines missed in the meantime.
+func streamNodeLogs(ctx context.Context, n p2plab.Node, follow bool, nw *nodeLogWriter) {
+	logger := zerolog.Ctx(ctx).With().Str("node", n.ID()).Logger()
+
+	var (
+		next        uint64
+		reconnected bool
+		backoff     = logsBackoffMin
+	)
+	for {
+		opts := []p2plab.LogsOption{p2plab.WithLogsSince(next)}
+		if follow {
+			opts = append(opts, p2plab.WithLogsFollow())
+		}
+
+		offset, rc, err := n.Logs(ctx, opts...)
+		if err == nil {
+			if reconnected {
+				if offset > next {
+					logger.Warn().Uint64("missed", offset-next).Msg("Reconnected to node, lines were missed")
+				} else if offset < next {
+					logger.Warn().Msg("Reconnected to node after it restarted, lines may have been missed")
+				} else {
+					logger.Info().Msg("Reconnected to node")
+				}
+			}
+			backoff = logsBackoffMin
+
+			var count uint64
+			count, err = nw.copy(ctx, n.ID(), rc)
+			rc.Close()
+			next = offset + count
+		}
+
+		if !follow || ctx.Err() != nil {
+			if err != nil && ctx.Err() == nil {
+				logger.Error().Err(err).Msg("Failed to stream logs")
+			}
+			return
+		}
+
+		if err != nil {
+			logger.Warn().Err(err).Dur("backoff", backoff).Msg("Lost connection to node, reconnecting")
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+
+			backoff *= 2
+			if backoff > logsBackoffMax {
+				backoff = logsBackoffMax
+			}
+		}
+		reconnected = true
+	}
+}
+
+// nodeLogWriter writes log lines from multiple nodes to the log writer, adding
+// the node ID as a field so that both console and JSON log writers attribute
+// each line to its node.
+type nodeLogWriter struct {
+	w  io.Writer
+	mu sync.Mutex
+}
+
+func (nw *nodeLogWriter) copy(ctx context.Context, id string, r io.Reader) (uint64, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+
+	var count uint64
+	for scanner.Scan() {
+		count++
+		err := nw.writeLine(ctx, id, scanner.Bytes())
+		if err != nil {
+			return count, err
+		}
+	}
+
+	return count, scanner.Err()
+}
+
+func (nw *nodeLogWriter) writeLine(ctx context.Context, id string, line []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.UseNumber()
+
+	var evt map[string]interface{}
+	err := decoder.Decode(&evt)
+	if err != nil {
+		// Lines that aren't zerolog events are logged verbatim.
+		evt = map[string]interface{}{
+			zerolog.LevelFieldName:     zerolog.InfoLevel.String(),
+			zerolog.TimestampFieldName: time.Now().Format(zerolog.TimeFieldFormat),
+			zerolog.MessageFieldName:   string(line),
+		}
+	}
+
+	levelStr, ok := evt[zerolog.LevelFieldName].(string)
+	if ok {
+		level, _ := zerolog.ParseLevel(levelStr)
+		if zerolog.Ctx(ctx).WithLevel(level) == nil {
+			return nil
+		}
+	}
+	evt["node"] = id
+
+	content, err := json.Marshal(&evt)
+	if err != nil {
+		return err
+	}
+
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+
+	_, err = nw.w.Write(append(content, '\n'))
+	return err
 }
 
 func sshNodeAction(c *cli.Context) error {
@@ -301,6 +928,7 @@ func sshNodeAction(c *cli.Context) error {
 	}
 
 	ctx := cliutil.CommandContext(c)
+	TagSpan(c, tagCluster, c.Args().Get(0))
 	node, err := control.Node().Get(ctx, c.Args().Get(0), c.Args().Get(1))
 	if err != nil {
 		return err
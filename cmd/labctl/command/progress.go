@@ -0,0 +1,235 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Netflix/p2plab/pkg/logutil"
+	"github.com/Netflix/p2plab/printer"
+	humanize "github.com/dustin/go-humanize"
+	"github.com/rs/zerolog"
+	"github.com/urfave/cli"
+	"golang.org/x/term"
+)
+
+var (
+	// progressDrawInterval is how often interactive progress is redrawn.
+	progressDrawInterval = 100 * time.Millisecond
+
+	// progressLogInterval is how often progress is logged when the output
+	// isn't interactive.
+	progressLogInterval = 10 * time.Second
+
+	spinnerFrames = []string{"|", "/", "-", "\\"}
+
+	progressBarWidth = 30
+)
+
+// formatCount and formatBytes format the units of a progress.
+func formatCount(n int64) string { return strconv.FormatInt(n, 10) }
+func formatBytes(n int64) string { return humanize.Bytes(uint64(n)) }
+
+// progressReporter renders the progress of a long operation on stderr. When
+// stdout is a terminal and the output is not machine-readable, it draws a
+// progress bar, or a spinner until the total is known. Otherwise, it logs the
+// progress periodically.
+type progressReporter struct {
+	label       string
+	format      func(int64) string
+	interactive bool
+	out         io.Writer
+
+	mu        sync.Mutex
+	completed int64
+	total     int64
+	frame     int
+	done      bool
+
+	stop    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// startProgress starts reporting the progress of an operation until Stop is
+// called or the context is canceled. The returned context reports the
+// progress of remote operations, and its logs clear the progress bar so that
+// they are not garbled.
+func startProgress(ctx context.Context, c *cli.Context, label string, format func(int64) string) (context.Context, *progressReporter) {
+	output := printer.OutputType(c.GlobalString("output"))
+	p := &progressReporter{
+		label:  label,
+		format: format,
+		interactive: term.IsTerminal(int(os.Stdout.Fd())) && term.IsTerminal(int(os.Stderr.Fd())) &&
+			output != printer.OutputJSON && output != printer.OutputJSONL && output != printer.OutputYAML,
+		out:     os.Stderr,
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	if p.interactive {
+		writer := logutil.LogWriter(ctx)
+		if writer == nil {
+			writer = os.Stderr
+		}
+		writer = &progressWriter{p, writer}
+
+		logger := zerolog.Ctx(ctx).Output(writer)
+		ctx = logutil.WithLogWriter(logger.WithContext(ctx), writer)
+	}
+	ctx = logutil.WithProgress(ctx, p.Set)
+
+	go p.run(ctx)
+	return ctx, p
+}
+
+// Set updates the progress to completed out of total units, where a zero total
+// is unknown.
+func (p *progressReporter) Set(completed, total int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.completed, p.total = completed, total
+}
+
+// Write counts the bytes written as completed, to report the progress of
+// copies.
+func (p *progressReporter) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.completed += int64(len(b))
+	return len(b), nil
+}
+
+// Stop stops reporting, leaving the final progress bar on its own line.
+func (p *progressReporter) Stop() {
+	p.once.Do(func() {
+		close(p.stop)
+	})
+	<-p.stopped
+}
+
+func (p *progressReporter) run(ctx context.Context) {
+	defer close(p.stopped)
+
+	interval := progressLogInterval
+	if p.interactive {
+		interval = progressDrawInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if p.interactive {
+				p.mu.Lock()
+				p.frame++
+				p.draw()
+				p.mu.Unlock()
+			} else {
+				p.log(ctx)
+			}
+		case <-p.stop:
+			p.finish()
+			return
+		case <-ctx.Done():
+			// Clear the bar so that the cancellation error is readable.
+			p.mu.Lock()
+			p.clear()
+			p.done = true
+			p.mu.Unlock()
+			return
+		}
+	}
+}
+
+func (p *progressReporter) finish() {
+	if !p.interactive {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.draw()
+	fmt.Fprintln(p.out)
+	p.done = true
+}
+
+func (p *progressReporter) log(ctx context.Context) {
+	p.mu.Lock()
+	completed, total := p.completed, p.total
+	p.mu.Unlock()
+
+	event := zerolog.Ctx(ctx).Info().Str(logutil.CompletedFieldName, p.format(completed))
+	if total > 0 {
+		event = event.Str(logutil.TotalFieldName, p.format(total))
+	}
+	event.Msg(p.label)
+}
+
+// draw redraws the progress on its line, the caller must hold the lock.
+func (p *progressReporter) draw() {
+	if p.done {
+		return
+	}
+
+	if p.total <= 0 {
+		fmt.Fprintf(p.out, "\r\033[K%s %s %s", p.label, spinnerFrames[p.frame%len(spinnerFrames)], p.format(p.completed))
+		return
+	}
+
+	ratio := float64(p.completed) / float64(p.total)
+	if ratio > 1 {
+		ratio = 1
+	}
+	filled := int(ratio * float64(progressBarWidth))
+
+	fmt.Fprintf(p.out, "\r\033[K%s [%s%s] %3.0f%% %s/%s", p.label,
+		strings.Repeat("=", filled), strings.Repeat(" ", progressBarWidth-filled),
+		ratio*100, p.format(p.completed), p.format(p.total))
+}
+
+// clear erases the progress from its line, the caller must hold the lock.
+func (p *progressReporter) clear() {
+	if p.done {
+		return
+	}
+	fmt.Fprint(p.out, "\r\033[K")
+}
+
+// progressWriter clears the progress bar before writing logs, and redraws it
+// after.
+type progressWriter struct {
+	p *progressReporter
+	w io.Writer
+}
+
+func (w *progressWriter) Write(b []byte) (int, error) {
+	w.p.mu.Lock()
+	defer w.p.mu.Unlock()
+
+	w.p.clear()
+	n, err := w.w.Write(b)
+	w.p.draw()
+	return n, err
+}
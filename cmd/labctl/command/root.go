@@ -16,6 +16,7 @@ package command
 
 import (
 	"context"
+	"time"
 
 	"github.com/Netflix/p2plab/version"
 	"github.com/urfave/cli"
@@ -26,6 +27,11 @@ func App(ctx context.Context) *cli.App {
 	app.Name = "labctl"
 	app.Version = version.Version
 	app.Flags = []cli.Flag{
+		cli.StringFlag{
+			Name:   "config",
+			Usage:  "path to labctl config file (default: ~/.labctl/config.yaml)",
+			EnvVar: "LABCTL_CONFIG",
+		},
 		cli.StringFlag{
 			Name:   "address,a",
 			Usage:  "address for labd",
@@ -44,22 +50,129 @@ func App(ctx context.Context) *cli.App {
 			Value:  "console",
 			EnvVar: "LABCTL_LOG_WRITER",
 		},
+		cli.BoolFlag{
+			Name:   "no-color",
+			Usage:  "disable colored console logs",
+			EnvVar: "LABCTL_NO_COLOR",
+		},
+		cli.StringFlag{
+			Name:   "log-file",
+			Usage:  "write logs to a file rotated by size",
+			EnvVar: "LABCTL_LOG_FILE",
+		},
+		cli.StringFlag{
+			Name:   "log-output",
+			Usage:  "set the log output [stderr, file, multi] (default: file if --log-file is set, otherwise stderr)",
+			EnvVar: "LABCTL_LOG_OUTPUT",
+		},
+		cli.IntFlag{
+			Name:   "log-max-size",
+			Usage:  "maximum size in megabytes of the log file before it is rotated",
+			Value:  100,
+			EnvVar: "LABCTL_LOG_MAX_SIZE",
+		},
+		cli.IntFlag{
+			Name:   "log-max-backups",
+			Usage:  "maximum number of rotated log files to retain, zero retains all",
+			Value:  3,
+			EnvVar: "LABCTL_LOG_MAX_BACKUPS",
+		},
+		cli.IntFlag{
+			Name:   "log-max-age",
+			Usage:  "maximum number of days to retain rotated log files, zero retains all",
+			Value:  28,
+			EnvVar: "LABCTL_LOG_MAX_AGE",
+		},
+		cli.DurationFlag{
+			Name:   "timeout",
+			Usage:  "timeout for the command, zero means no timeout",
+			EnvVar: "LABCTL_TIMEOUT",
+		},
+		cli.DurationFlag{
+			Name:   "request-timeout",
+			Usage:  "timeout for each request to labd, zero means no timeout",
+			EnvVar: "LABCTL_REQUEST_TIMEOUT",
+		},
+		cli.StringFlag{
+			Name:   "tls-cert",
+			Usage:  "path to TLS client certificate for labd",
+			EnvVar: "LABCTL_TLS_CERT",
+		},
+		cli.StringFlag{
+			Name:   "tls-key",
+			Usage:  "path to TLS client key for labd",
+			EnvVar: "LABCTL_TLS_KEY",
+		},
+		cli.StringFlag{
+			Name:   "tls-ca",
+			Usage:  "path to TLS CA certificate to verify labd",
+			EnvVar: "LABCTL_TLS_CA",
+		},
+		cli.IntFlag{
+			Name:   "retries",
+			Usage:  "number of times to retry failed requests to labd",
+			EnvVar: "LABCTL_RETRIES",
+		},
+		cli.BoolFlag{
+			Name:   "compress",
+			Usage:  "compress requests and responses to labd with gzip",
+			EnvVar: "LABCTL_COMPRESS",
+		},
+		cli.StringFlag{
+			Name:   "token",
+			Usage:  "bearer token authenticating requests to labd",
+			EnvVar: "LABCTL_TOKEN",
+		},
+		cli.BoolFlag{
+			Name:   "no-cache",
+			Usage:  "disable caching responses from labd in ~/.labctl/cache",
+			EnvVar: "LABCTL_NO_CACHE",
+		},
+		cli.DurationFlag{
+			Name:   "cache-ttl",
+			Usage:  "time after which cached responses from labd are discarded",
+			Value:  24 * time.Hour,
+			EnvVar: "LABCTL_CACHE_TTL",
+		},
 		cli.StringFlag{
 			Name:   "output,o",
-			Usage:  "set the output printer [auto, id, unix, json, table]",
+			Usage:  "set the output printer [auto, id, unix, json, jsonl, yaml, csv, go-template, table]",
 			Value:  "auto",
 			EnvVar: "LABCTL_OUTPUT",
 		},
+		cli.StringFlag{
+			Name:   "format",
+			Usage:  "set the go template used by the go-template printer",
+			EnvVar: "LABCTL_FORMAT",
+		},
+		cli.StringFlag{
+			Name:   "format-file",
+			Usage:  "read the go template used by the go-template printer from a file",
+			EnvVar: "LABCTL_FORMAT_FILE",
+		},
 	}
 	app.Commands = []cli.Command{
 		clusterCommand,
 		nodeCommand,
 		scenarioCommand,
+		applyCommand,
 		benchmarkCommand,
 		experimentCommand,
+		adminCommand,
 		debugCommand,
+		healthCommand,
+		logsCommand,
+		versionCommand,
+		completionCommand,
+		completeCommand,
 	}
 
+	// Setup structured error output for machine-readable printers.
+	AttachAppErrorPrinter(app)
+
+	// Setup global flag defaults from config file.
+	AttachAppConfig(app)
+
 	// Setup tracers and context.
 	AttachAppContext(ctx, app)
 
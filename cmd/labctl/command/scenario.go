@@ -15,13 +15,19 @@
 package command
 
 import (
-	"errors"
+	"context"
+	"fmt"
+	"os"
+	"strings"
 
 	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/metadata"
 	"github.com/Netflix/p2plab/pkg/cliutil"
 	"github.com/Netflix/p2plab/printer"
 	"github.com/Netflix/p2plab/query"
 	"github.com/Netflix/p2plab/scenarios"
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"github.com/urfave/cli"
 )
@@ -34,13 +40,29 @@ var scenarioCommand = cli.Command{
 		{
 			Name:      "create",
 			Aliases:   []string{"c"},
-			Usage:     "Creates a new scenario from a definition file.",
-			ArgsUsage: "<filename>",
+			Usage:     "Creates scenarios from a definition file, one for each of its documents separated by ---.",
+			ArgsUsage: "[<filename>]",
 			Action:    createScenarioAction,
 			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "file,f",
+					Usage: "Definition file to create the scenario from, or - to read it from stdin.",
+				},
 				&cli.StringFlag{
 					Name:  "name",
-					Usage: "Name of the scenario, by default takes the name of the scenario definition.",
+					Usage: "Name of the scenario, by default takes the name of the scenario definition. Required when reading from stdin. Documents of a multi-document file are suffixed with their index.",
+				},
+				&cli.BoolFlag{
+					Name:  "continue-on-error",
+					Usage: "Keeps creating the documents of a multi-document file after one fails, instead of removing the scenarios already created.",
+				},
+				&cli.StringSliceFlag{
+					Name:  "var",
+					Usage: "Sets a ${key} or ${key:-default} variable of a templated scenario as key=value, overriding the environment. Escape a literal $ as $$",
+				},
+				&cli.StringFlag{
+					Name:  "sample-interval",
+					Usage: "Samples the CPU and memory usage of nodes at this interval during benchmarks, overriding the scenario definition.",
 				},
 			},
 		},
@@ -87,13 +109,27 @@ var scenarioCommand = cli.Command{
 			Usage:     "Remove scenarios.",
 			ArgsUsage: "[<name> ...]",
 			Action:    removeScenariosAction,
+			Flags:     removeFlags("benchmarks"),
+		},
+		{
+			Name:      "validate",
+			Usage:     "Validates scenario definitions without a daemon, reporting every problem.",
+			ArgsUsage: "<filename> [<filename> ...]",
+			Action:    validateScenariosAction,
+			Flags: []cli.Flag{
+				&cli.StringSliceFlag{
+					Name:  "var",
+					Usage: "Sets a ${key} or ${key:-default} variable of a templated scenario as key=value, overriding the environment. Escape a literal $ as $$",
+				},
+			},
 		},
 	},
 }
 
 func createScenarioAction(c *cli.Context) error {
-	if c.NArg() != 1 {
-		return errors.New("scenario definition must be provided")
+	filename, ok := definitionFilename(c)
+	if !ok {
+		return errors.New("scenario definition must be provided as argument or with --file")
 	}
 
 	p, err := CommandPrinter(c, printer.OutputID)
@@ -101,30 +137,109 @@ func createScenarioAction(c *cli.Context) error {
 		return err
 	}
 
-	filename := c.Args().First()
 	name := c.String("name")
 	if name == "" {
-		name = ExtractNameFromFilename(filename)
+		name, err = ExtractNameFromFilename(filename)
+		if err != nil {
+			return err
+		}
+	}
+
+	TagSpan(c, tagScenario, name)
+
+	lookup, err := scenarioVars(c)
+	if err != nil {
+		return err
 	}
 
-	sdef, err := scenarios.Parse(filename)
+	content, err := scenarios.ReadTemplate(filename, lookup)
 	if err != nil {
 		return err
 	}
 
+	docs, err := scenarios.SplitDocuments(content)
+	if err != nil {
+		return errors.Wrap(err, filename)
+	}
+	if len(docs) == 0 {
+		return fmt.Errorf("%s: no scenario definition", filename)
+	}
+
 	control, err := ResolveControl(c)
 	if err != nil {
 		return err
 	}
 
 	ctx := cliutil.CommandContext(c)
-	scenario, err := control.Scenario().Create(ctx, name, sdef)
+	if len(docs) == 1 {
+		scenario, err := createScenario(ctx, c, control, name, docs[0])
+		if err != nil {
+			return err
+		}
+
+		zerolog.Ctx(ctx).Info().Msgf("Created scenario %q", scenario.Metadata().ID)
+		return p.Print(scenario.Metadata())
+	}
+
+	var (
+		created []string
+		l       []interface{}
+		failed  int
+	)
+	for i, doc := range docs {
+		docName := fmt.Sprintf("%s-%d", name, i+1)
+		logger := zerolog.Ctx(ctx).With().Int("document", i+1).Logger()
+
+		scenario, err := createScenario(ctx, c, control, docName, doc)
+		if err != nil {
+			logger.Error().Err(err).Msgf("Failed to create scenario %q", docName)
+			failed++
+			if c.Bool("continue-on-error") {
+				continue
+			}
+
+			if len(created) > 0 {
+				logger.Warn().Strs("scenarios", created).Msg("Removing scenarios created from the file")
+				rerr := control.Scenario().Remove(ctx, created)
+				if rerr != nil {
+					logger.Error().Err(rerr).Msg("Failed to remove scenarios")
+				}
+			}
+			return errors.Wrapf(err, "%s: document %d", filename, i+1)
+		}
+
+		logger.Info().Msgf("Created scenario %q", scenario.Metadata().ID)
+		created = append(created, scenario.Metadata().ID)
+		l = append(l, scenario.Metadata())
+	}
+
+	err = p.Print(l)
 	if err != nil {
 		return err
 	}
 
-	zerolog.Ctx(ctx).Info().Msgf("Created scenario %q", scenario.Metadata().ID)
-	return p.Print(scenario.Metadata())
+	if failed > 0 {
+		return fmt.Errorf("%s: failed to create %d of %d scenarios", filename, failed, len(docs))
+	}
+	return nil
+}
+
+// createScenario creates a scenario from a document of a definition file.
+func createScenario(ctx context.Context, c *cli.Context, control p2plab.ControlAPI, name string, doc []byte) (p2plab.Scenario, error) {
+	sdef, err := metadata.ParseScenarioDefinition(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.IsSet("sample-interval") {
+		sdef.SampleInterval = c.String("sample-interval")
+		err = sdef.Validate()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return control.Scenario().Create(ctx, name, sdef)
 }
 
 func inspectScenarioAction(c *cli.Context) error {
@@ -144,6 +259,7 @@ func inspectScenarioAction(c *cli.Context) error {
 
 	ctx := cliutil.CommandContext(c)
 	name := c.Args().Get(0)
+	TagSpan(c, tagScenario, name)
 	scenario, err := control.Scenario().Get(ctx, name)
 	if err != nil {
 		return err
@@ -157,6 +273,7 @@ func labelScenariosAction(c *cli.Context) error {
 	for i := 0; i < c.NArg(); i++ {
 		names = append(names, c.Args().Get(i))
 	}
+	TagSpan(c, tagScenario, names...)
 
 	p, err := CommandPrinter(c, printer.OutputTable)
 	if err != nil {
@@ -222,6 +339,7 @@ func removeScenariosAction(c *cli.Context) error {
 	for i := 0; i < c.NArg(); i++ {
 		names = append(names, c.Args().Get(i))
 	}
+	TagSpan(c, tagScenario, names...)
 
 	control, err := ResolveControl(c)
 	if err != nil {
@@ -229,7 +347,7 @@ func removeScenariosAction(c *cli.Context) error {
 	}
 
 	ctx := cliutil.CommandContext(c)
-	err = control.Scenario().Remove(ctx, names...)
+	err = control.Scenario().Remove(ctx, names, removeOptions(c)...)
 	if err != nil {
 		return err
 	}
@@ -237,3 +355,61 @@ func removeScenariosAction(c *cli.Context) error {
 	zerolog.Ctx(ctx).Info().Strs("names", names).Msg("Removed scenarios")
 	return nil
 }
+
+// validateScenariosAction lints scenario definitions client-side, printing
+// every problem found and failing if there is any.
+func validateScenariosAction(c *cli.Context) error {
+	if c.NArg() == 0 {
+		return errors.New("scenario definition must be provided")
+	}
+
+	lookup, err := scenarioVars(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := cliutil.CommandContext(c)
+	problems := 0
+	for _, filename := range c.Args() {
+		content, err := scenarios.ReadTemplate(filename, lookup)
+		if err != nil {
+			fmt.Printf("%s: %s\n", filename, err)
+			problems++
+			continue
+		}
+
+		errs := scenarios.Lint(ctx, content)
+		for _, err := range errs {
+			fmt.Printf("%s: %s\n", filename, err)
+		}
+		problems += len(errs)
+	}
+
+	if problems > 0 {
+		return fmt.Errorf("found %d problems in scenario definitions", problems)
+	}
+
+	zerolog.Ctx(ctx).Info().Msg("Scenario definitions are valid")
+	return nil
+}
+
+// scenarioVars returns the lookup of template variables, set by the --var
+// flags or else by the environment.
+func scenarioVars(c *cli.Context) (scenarios.LookupFunc, error) {
+	vars := make(map[string]string)
+	for _, v := range c.StringSlice("var") {
+		parts := strings.SplitN(v, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "variable must be in the form key=value, got %q", v)
+		}
+		vars[parts[0]] = parts[1]
+	}
+
+	return func(name string) (string, bool) {
+		value, ok := vars[name]
+		if ok {
+			return value, true
+		}
+		return os.LookupEnv(name)
+	}, nil
+}
@@ -0,0 +1,267 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/experiments"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/Netflix/p2plab/pkg/cliutil"
+	"github.com/Netflix/p2plab/pkg/logutil"
+	"github.com/Netflix/p2plab/printer"
+	"github.com/Netflix/p2plab/scenarios"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/urfave/cli"
+)
+
+// sweepExperimentAction runs a scenario template once per combination of the
+// swept variables, grouping the benchmarks under one experiment, and prints a
+// matrix of their total time.
+func sweepExperimentAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("cluster name must be provided")
+	}
+
+	filename := c.String("scenario")
+	if filename == "" {
+		return errors.New("scenario definition must be provided with --scenario")
+	}
+
+	concurrency := c.Int("concurrency")
+	if concurrency < 1 {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "concurrency must be positive, got %d", concurrency)
+	}
+
+	var vars []experiments.Variable
+	for _, s := range c.StringSlice("var") {
+		v, err := experiments.ParseVariable(s)
+		if err != nil {
+			return err
+		}
+		vars = append(vars, v)
+	}
+	if len(vars) == 0 {
+		return errors.New("at least one variable must be swept with --var")
+	}
+
+	combinations, err := experiments.Combinations(vars)
+	if err != nil {
+		return err
+	}
+
+	// Read the template once, since it may come from stdin, and expand every
+	// combination before running any, so that a broken template does not fail
+	// the sweep halfway.
+	content, err := cliutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	sdefs := make([]metadata.ScenarioDefinition, len(combinations))
+	for i, params := range combinations {
+		params := params
+		sdefs[i], err = scenarios.ParseTemplate(filename, content, func(name string) (string, bool) {
+			value, ok := params[name]
+			if ok {
+				return value, true
+			}
+			return os.LookupEnv(name)
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %s", formatParameters(vars, params), err)
+		}
+	}
+
+	p, err := CommandPrinter(c, printer.OutputTable)
+	if err != nil {
+		return err
+	}
+
+	control, err := ResolveControl(c)
+	if err != nil {
+		return err
+	}
+
+	name := c.String("name")
+	if name == "" {
+		name, err = ExtractNameFromFilename(filename)
+		if err != nil {
+			return err
+		}
+	}
+
+	trials := make([]metadata.ExperimentTrial, len(combinations))
+	for i, params := range combinations {
+		trials[i].Parameters = params
+	}
+
+	tags := c.StringSlice("tag")
+	for _, tag := range tags {
+		_, _, err = metadata.ParseKeyValueLabel(tag)
+		if err != nil {
+			return err
+		}
+	}
+
+	ctx := cliutil.CommandContext(c)
+	experiment, err := control.Experiment().Sweep(ctx, name, trials)
+	if err != nil {
+		return err
+	}
+	zerolog.Ctx(ctx).Info().Int("trials", len(trials)).Msgf("Created experiment %q", name)
+
+	if len(tags) > 0 {
+		_, err = control.Experiment().Label(ctx, []string{name}, tags, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The benchmarks run concurrently, so their own progress is not reported.
+	pctx, progress := startProgress(ctx, c, "Running trials", formatCount)
+	pctx = logutil.WithProgress(pctx, nil)
+	progress.Set(0, int64(len(trials)))
+
+	var (
+		sem       = make(chan struct{}, concurrency)
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		completed int64
+		reports   = make([]*metadata.Report, len(trials))
+	)
+	for i := range trials {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			trial := trials[i]
+			logger := zerolog.Ctx(pctx).With().Str("trial", formatParameters(vars, trial.Parameters)).Logger()
+
+			report, err := runTrial(logger.WithContext(pctx), control, c.Args().First(), fmt.Sprintf("%s-%d", name, i), sdefs[i], &trial, tags)
+			if err != nil {
+				logger.Warn().Err(err).Msg("Trial failed")
+				trial.Error = err.Error()
+			}
+
+			updated, err := control.Experiment().RecordTrial(ctx, name, i, trial)
+			if err != nil {
+				logger.Warn().Err(err).Msg("Failed to record trial")
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				experiment = updated
+			}
+			reports[i] = report
+			completed++
+			progress.Set(completed, int64(len(trials)))
+		}()
+	}
+	wg.Wait()
+	progress.Stop()
+
+	zerolog.Ctx(ctx).Info().Msgf("Completed experiment %q with status %q", name, experiment.Metadata().Status)
+	return p.Print(sweepMatrix(vars, combinations, reports))
+}
+
+// runTrial creates the scenario of a trial and benchmarks it on the cluster
+// with the tags of the experiment, recording the benchmark on the trial.
+func runTrial(ctx context.Context, control p2plab.ControlAPI, cluster, name string, sdef metadata.ScenarioDefinition, trial *metadata.ExperimentTrial, tags []string) (*metadata.Report, error) {
+	scenario, err := control.Scenario().Create(ctx, name, sdef)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := control.Benchmark().Create(ctx, cluster, scenario.ID(), p2plab.WithBenchmarkParameters(trial.Parameters), p2plab.WithBenchmarkTags(tags...))
+	if id != "" {
+		trial.Benchmark = id
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	benchmark, err := control.Benchmark().Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := benchmark.Report(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+// sweepMatrix tabulates the total time of each trial, with a row for every
+// combination of the variables but the last, and a column for every value of
+// the last variable.
+func sweepMatrix(vars []experiments.Variable, combinations []map[string]string, reports []*metadata.Report) []interface{} {
+	var (
+		rows    = vars[:len(vars)-1]
+		columns = vars[len(vars)-1]
+		fields  []string
+	)
+	for _, v := range rows {
+		fields = append(fields, v.Name)
+	}
+	for _, value := range columns.Values {
+		fields = append(fields, fmt.Sprintf("%s=%s", columns.Name, value))
+	}
+
+	var l []interface{}
+	for i := 0; i < len(combinations); i += len(columns.Values) {
+		var values []interface{}
+		for _, v := range rows {
+			values = append(values, combinations[i][v.Name])
+		}
+
+		for j := range columns.Values {
+			cell := "error"
+			if report := reports[i+j]; report != nil {
+				cell = report.Summary.TotalTime.String()
+			}
+			values = append(values, cell)
+		}
+
+		l = append(l, printer.Projection{
+			Fields: fields,
+			Values: values,
+		})
+	}
+
+	return l
+}
+
+// formatParameters formats parameters in the order of the swept variables.
+func formatParameters(vars []experiments.Variable, params map[string]string) string {
+	var parts []string
+	for _, v := range vars {
+		parts = append(parts, fmt.Sprintf("%s=%s", v.Name, params[v.Name]))
+	}
+	return strings.Join(parts, ",")
+}
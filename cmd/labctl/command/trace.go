@@ -0,0 +1,53 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"strings"
+
+	"github.com/Netflix/p2plab/pkg/cliutil"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/urfave/cli"
+)
+
+// Tags of the span of a command that describe the resources it targets.
+const (
+	tagCluster   = "cluster"
+	tagScenario  = "scenario"
+	tagBenchmark = "benchmark"
+	tagNodes     = "nodes"
+	tagOutput    = "output"
+)
+
+// TagSpan tags the span of the command once it resolves its targets, so that
+// its traces can be filtered by them. Multiple values are joined with commas.
+// It does nothing when the command has no span.
+func TagSpan(c *cli.Context, key string, values ...string) {
+	span := opentracing.SpanFromContext(cliutil.CommandContext(c))
+	if span == nil || len(values) == 0 {
+		return
+	}
+	span.SetTag(key, strings.Join(values, ","))
+}
+
+// TagSpanCount tags the span of the command with a count, such as the number
+// of nodes it targets.
+func TagSpanCount(c *cli.Context, key string, n int) {
+	span := opentracing.SpanFromContext(cliutil.CommandContext(c))
+	if span == nil {
+		return
+	}
+	span.SetTag(key, n)
+}
@@ -0,0 +1,87 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Netflix/p2plab/pkg/cliutil"
+	"github.com/Netflix/p2plab/pkg/httputil"
+	"github.com/Netflix/p2plab/printer"
+	"github.com/Netflix/p2plab/version"
+	"github.com/urfave/cli"
+)
+
+var versionCommand = cli.Command{
+	Name:      "version",
+	Usage:     "Displays the version of labctl and labd.",
+	ArgsUsage: " ",
+	Action:    versionAction,
+}
+
+type versionOutput struct {
+	Client version.Info  `json:"client"`
+	Daemon *version.Info `json:"daemon,omitempty"`
+	Error  string        `json:"error,omitempty"`
+}
+
+func versionAction(c *cli.Context) error {
+	v := versionOutput{
+		Client: version.Get(),
+	}
+
+	ctx := cliutil.CommandContext(c)
+	req := CommandClient(c).NewRequest("GET", fmt.Sprintf("%s/version", c.GlobalString("address")), httputil.WithRetryMax(0))
+	resp, err := req.Send(ctx)
+	if err == nil {
+		defer resp.Body.Close()
+
+		var daemonInfo version.Info
+		err = json.NewDecoder(resp.Body).Decode(&daemonInfo)
+		if err == nil {
+			v.Daemon = &daemonInfo
+		}
+	}
+	if err != nil {
+		v.Error = fmt.Sprintf("daemon unreachable: %s", err)
+	}
+
+	output := printer.OutputType(c.GlobalString("output"))
+	if output != printer.OutputAuto {
+		p, err := CommandPrinter(c, printer.OutputJSON)
+		if err != nil {
+			return err
+		}
+		return p.Print(v)
+	}
+
+	printVersion("Client", &v.Client)
+	if v.Daemon == nil {
+		fmt.Printf("\nDaemon:\n  %s\n", v.Error)
+		return nil
+	}
+
+	fmt.Println()
+	printVersion("Daemon", v.Daemon)
+	return nil
+}
+
+func printVersion(name string, info *version.Info) {
+	fmt.Printf("%s:\n", name)
+	fmt.Printf("  Version:    %s\n", info.Version)
+	fmt.Printf("  Revision:   %s\n", info.Revision)
+	fmt.Printf("  Go version: %s\n", info.GoVersion)
+}
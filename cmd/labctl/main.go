@@ -18,10 +18,8 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"syscall"
 
 	"github.com/Netflix/p2plab/cmd/labctl/command"
-	"github.com/Netflix/p2plab/pkg/cliutil"
 	"github.com/rs/zerolog"
 )
 
@@ -33,14 +31,12 @@ func init() {
 }
 
 func main() {
-	ctx, cancel := context.WithCancel(context.Background())
-
-	ih := cliutil.NewInterruptHandler(cancel, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM)
-	defer ih.Close()
-
-	app := command.App(ctx)
+	// Interrupts are handled by the command context.
+	app := command.App(context.Background())
 	if err := app.Run(os.Args); err != nil {
-		fmt.Fprintf(os.Stderr, "labctl: %s\n", err)
-		os.Exit(1)
+		if !command.IsPrintedError(err) {
+			fmt.Fprintf(os.Stderr, "labctl: %s\n", err)
+		}
+		os.Exit(command.ExitCode(err))
 	}
 }
@@ -16,10 +16,15 @@ package command
 
 import (
 	"context"
+	"io"
 	"os"
+	"time"
 
 	"github.com/Netflix/p2plab/labd"
 	"github.com/Netflix/p2plab/pkg/cliutil"
+	"github.com/Netflix/p2plab/pkg/logutil"
+	"github.com/Netflix/p2plab/providers"
+	"github.com/Netflix/p2plab/providers/docker"
 	"github.com/Netflix/p2plab/uploaders"
 	"github.com/Netflix/p2plab/uploaders/fileuploader"
 	"github.com/Netflix/p2plab/uploaders/s3uploader"
@@ -28,6 +33,10 @@ import (
 	"github.com/urfave/cli"
 )
 
+// logBacklog is the number of lines of logs retained for clients fetching
+// labd's logs.
+const logBacklog = 10000
+
 func App(ctx context.Context) *cli.App {
 	app := cli.NewApp()
 	app.Name = "labd"
@@ -59,10 +68,111 @@ func App(ctx context.Context) *cli.App {
 		},
 		cli.StringFlag{
 			Name:   "provider,p",
-			Usage:  "set the provider to create node groups [inmemory, terraform]",
+			Usage:  "set the provider to create node groups [inmemory, terraform, docker]",
 			Value:  "inmemory",
 			EnvVar: "LABD_PROVIDER",
 		},
+		cli.StringFlag{
+			Name:   "provider.docker.image",
+			Usage:  "labagent image for docker provider",
+			Value:  docker.DefaultImage,
+			EnvVar: "LABD_PROVIDER_DOCKER_IMAGE",
+		},
+		cli.StringFlag{
+			Name:   "metadata-backend",
+			Usage:  "set the backend of the metadata store [bolt, postgres]",
+			Value:  "bolt",
+			EnvVar: "LABD_METADATA_BACKEND",
+		},
+		cli.StringFlag{
+			Name:   "metadata-dsn",
+			Usage:  "set the data source name of SQL metadata backends",
+			EnvVar: "LABD_METADATA_DSN",
+		},
+		cli.IntFlag{
+			Name:   "max-page-size",
+			Usage:  "cap the number of results of paginated lists, zero means no cap",
+			EnvVar: "LABD_MAX_PAGE_SIZE",
+		},
+		cli.DurationFlag{
+			Name:   "idempotency-window",
+			Usage:  "set how long idempotency keys of benchmark requests are remembered",
+			Value:  24 * time.Hour,
+			EnvVar: "LABD_IDEMPOTENCY_WINDOW",
+		},
+		cli.DurationFlag{
+			Name:   "grace-period",
+			Usage:  "set how long executing benchmarks are given to be interrupted on shutdown",
+			Value:  30 * time.Second,
+			EnvVar: "LABD_GRACE_PERIOD",
+		},
+		cli.DurationFlag{
+			Name:   "reap-interval",
+			Usage:  "set how often clusters are checked for an expired ttl",
+			Value:  time.Minute,
+			EnvVar: "LABD_REAP_INTERVAL",
+		},
+		cli.DurationFlag{
+			Name:   "expiry-warning",
+			Usage:  "set how long before a cluster expires the default webhook is warned",
+			Value:  15 * time.Minute,
+			EnvVar: "LABD_EXPIRY_WARNING",
+		},
+		cli.StringFlag{
+			Name:   "notify-url",
+			Usage:  "set the default webhook notified when benchmarks finish",
+			EnvVar: "LABD_NOTIFY_URL",
+		},
+		cli.StringFlag{
+			Name:   "notify-secret",
+			Usage:  "set the secret signing webhook payloads with HMAC-SHA256",
+			EnvVar: "LABD_NOTIFY_SECRET",
+		},
+		cli.StringFlag{
+			Name:   "artifact-store",
+			Usage:  "set the object store keeping benchmark artifacts [file:///path, s3://bucket/prefix], defaults to the state directory",
+			EnvVar: "LABD_ARTIFACT_STORE",
+		},
+		cli.StringSliceFlag{
+			Name:   "auth-token",
+			Usage:  "require requests to carry one of these bearer tokens, health checks are not authenticated",
+			EnvVar: "LABD_AUTH_TOKENS",
+		},
+		cli.StringFlag{
+			Name:   "auth-jwks-url",
+			Usage:  "require requests to carry a JWT signed by a key of the JSON Web Key Set at this URL",
+			EnvVar: "LABD_AUTH_JWKS_URL",
+		},
+		cli.StringFlag{
+			Name:   "auth-jwt-audience",
+			Usage:  "require JWTs to be intended for this audience",
+			EnvVar: "LABD_AUTH_JWT_AUDIENCE",
+		},
+		cli.StringFlag{
+			Name:   "auth-jwt-issuer",
+			Usage:  "require JWTs to be issued by this issuer",
+			EnvVar: "LABD_AUTH_JWT_ISSUER",
+		},
+		cli.StringFlag{
+			Name:   "audit-log",
+			Usage:  "path of the append-only log recording requests that modify resources, defaults to the state directory",
+			EnvVar: "LABD_AUDIT_LOG",
+		},
+		cli.StringFlag{
+			Name:   "rate-limit-read",
+			Usage:  "limit the requests of each client inspecting resources, as requests per interval such as 600/1m",
+			EnvVar: "LABD_RATE_LIMIT_READ",
+		},
+		cli.StringFlag{
+			Name:   "rate-limit-write",
+			Usage:  "limit the requests of each client modifying resources such as submitting benchmarks, as requests per interval such as 30/1m",
+			EnvVar: "LABD_RATE_LIMIT_WRITE",
+		},
+		cli.StringSliceFlag{
+			Name:   "rate-limit-token",
+			Usage:  "override the write limit of clients with a bearer token, as <token>=<limit>",
+			EnvVar: "LABD_RATE_LIMIT_TOKENS",
+		},
 		cli.StringFlag{
 			Name:   "uploader,u",
 			Usage:  "set the uploader to use to distribute p2p app binaries [file, s3]",
@@ -106,10 +216,31 @@ func daemonAction(c *cli.Context) error {
 		return err
 	}
 
-	ctx := cliutil.CommandContext(c)
-	daemon, err := labd.New(root, c.GlobalString("address"), zerolog.Ctx(ctx),
+	// Logs are also retained in memory, so that they can be fetched by labctl
+	// without access to the host.
+	logs := logutil.NewBroadcaster(logBacklog)
+	logger := zerolog.Ctx(cliutil.CommandContext(c)).Output(io.MultiWriter(os.Stderr, logs))
+	ctx := logger.WithContext(cliutil.CommandContext(c))
+
+	daemon, err := labd.New(root, c.GlobalString("address"), &logger,
 		labd.WithLibp2pPort(c.GlobalInt("libp2p-port")),
 		labd.WithProvider(c.GlobalString("provider")),
+		labd.WithProviderSettings(providers.ProviderSettings{
+			Docker: docker.DockerProviderSettings{
+				Image: c.GlobalString("provider.docker.image"),
+			},
+		}),
+		labd.WithMetadataBackend(c.GlobalString("metadata-backend"), c.GlobalString("metadata-dsn")),
+		labd.WithMaxPageSize(c.GlobalInt("max-page-size")),
+		labd.WithIdempotencyWindow(c.GlobalDuration("idempotency-window")),
+		labd.WithNotifier(c.GlobalString("notify-url"), c.GlobalString("notify-secret")),
+		labd.WithGracePeriod(c.GlobalDuration("grace-period")),
+		labd.WithReaper(c.GlobalDuration("reap-interval"), c.GlobalDuration("expiry-warning")),
+		labd.WithArtifactStore(c.GlobalString("artifact-store")),
+		labd.WithAuth(c.GlobalStringSlice("auth-token"), c.GlobalString("auth-jwks-url"), c.GlobalString("auth-jwt-audience"), c.GlobalString("auth-jwt-issuer")),
+		labd.WithRateLimits(c.GlobalString("rate-limit-read"), c.GlobalString("rate-limit-write"), c.GlobalStringSlice("rate-limit-token")),
+		labd.WithAuditLog(c.GlobalString("audit-log")),
+		labd.WithLogs(logs),
 		labd.WithUploader(c.GlobalString("uploader")),
 		labd.WithUploaderSettings(uploaders.UploaderSettings{
 			S3: s3uploader.S3UploaderSettings{
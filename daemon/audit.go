@@ -0,0 +1,152 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/Netflix/p2plab/pkg/httputil"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// AuditOK is the outcome of audited requests that succeeded.
+const AuditOK = "OK"
+
+// AuditSink is an append-only store of audit records.
+type AuditSink interface {
+	io.Closer
+
+	// Record appends a record.
+	Record(ctx context.Context, record metadata.AuditRecord) error
+
+	// Query returns the records since a time in the order they were recorded.
+	Query(ctx context.Context, since time.Time) ([]metadata.AuditRecord, error)
+}
+
+// SetAuditSink records every request to the routes of routers that are not
+// public and that modify resources. Read requests are not audited.
+func (d *Daemon) SetAuditSink(sink AuditSink) {
+	d.audit = sink
+}
+
+// audit wraps handler to record the request and its outcome to the sink once
+// it is handled.
+func audit(sink AuditSink, route Route, handler Handler) Handler {
+	operation := route.Method() + " " + route.Path()
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		record := metadata.AuditRecord{
+			Time:       time.Now().UTC(),
+			Principal:  Principal(ctx),
+			RemoteAddr: r.RemoteAddr,
+			Operation:  operation,
+			Resource:   r.URL.RequestURI(),
+			Outcome:    AuditOK,
+			RequestID:  httputil.RequestID(ctx),
+		}
+
+		err := handler(ctx, w, r, vars)
+		if err != nil {
+			record.Outcome = string(errdefs.Code(err))
+			record.Error = err.Error()
+		}
+
+		// The request has been handled, so failing to audit it is logged rather
+		// than returned.
+		rerr := sink.Record(ctx, record)
+		if rerr != nil {
+			zerolog.Ctx(ctx).Error().Err(rerr).Str("operation", operation).Msg("Failed to record audit")
+		}
+
+		return err
+	}
+}
+
+type fileAuditSink struct {
+	mu   sync.Mutex
+	path string
+	f    *os.File
+}
+
+// NewFileAuditSink returns an audit sink appending records as JSON lines to a
+// file.
+func NewFileAuditSink(path string) (AuditSink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open audit log")
+	}
+
+	return &fileAuditSink{
+		path: path,
+		f:    f,
+	}, nil
+}
+
+func (s *fileAuditSink) Record(ctx context.Context, record metadata.AuditRecord) error {
+	content, err := json.Marshal(&record)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err = s.f.Write(append(content, '\n'))
+	if err != nil {
+		return err
+	}
+	return s.f.Sync()
+}
+
+func (s *fileAuditSink) Query(ctx context.Context, since time.Time) ([]metadata.AuditRecord, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open audit log")
+	}
+	defer f.Close()
+
+	records := []metadata.AuditRecord{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		var record metadata.AuditRecord
+		err = json.Unmarshal(scanner.Bytes(), &record)
+		if err != nil {
+			// A partial line is left by a crash while recording, the records
+			// appended afterwards are still valid.
+			continue
+		}
+
+		if record.Time.Before(since) {
+			continue
+		}
+		records = append(records, record)
+	}
+
+	return records, scanner.Err()
+}
+
+func (s *fileAuditSink) Close() error {
+	return s.f.Close()
+}
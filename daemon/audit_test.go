@@ -0,0 +1,96 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package daemon
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Netflix/p2plab/errdefs"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+type clusterRouter struct{}
+
+func (r *clusterRouter) Routes() []Route {
+	return []Route{
+		NewGetRoute("/clusters/json", func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+			return nil
+		}),
+		NewPostRoute("/clusters/create", func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+			return nil
+		}),
+		NewDeleteRoute("/clusters/delete", func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+			return errors.Wrap(errdefs.ErrNotFound, "cluster")
+		}),
+	}
+}
+
+func TestAudit(t *testing.T) {
+	root, err := ioutil.TempDir("", "p2plab-audit")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	sink, err := NewFileAuditSink(filepath.Join(root, "audit.log"))
+	require.NoError(t, err)
+	defer sink.Close()
+
+	logger := zerolog.Nop()
+	d, err := New("test", "127.0.0.1:0", &logger)
+	require.NoError(t, err)
+	d.tracer = opentracing.NoopTracer{}
+	d.SetAuthenticator(tokenAuthenticator("secret"))
+	d.SetAuditSink(sink)
+	mux := d.createMux(&clusterRouter{})
+
+	start := time.Now().Add(-time.Second)
+	for _, tc := range []struct {
+		method string
+		path   string
+	}{
+		{"GET", "/clusters/json"},
+		{"POST", "/clusters/create?id=c1"},
+		{"DELETE", "/clusters/delete?names=c2"},
+	} {
+		r := httptest.NewRequest(tc.method, tc.path, nil)
+		r.Header.Set("Authorization", "Bearer secret")
+		mux.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	records, err := sink.Query(context.Background(), start)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+
+	require.Equal(t, "tester", records[0].Principal)
+	require.Equal(t, "POST /clusters/create", records[0].Operation)
+	require.Equal(t, "/clusters/create?id=c1", records[0].Resource)
+	require.Equal(t, AuditOK, records[0].Outcome)
+
+	require.Equal(t, "DELETE /clusters/delete", records[1].Operation)
+	require.Equal(t, string(errdefs.CodeNotFound), records[1].Outcome)
+
+	records, err = sink.Query(context.Background(), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Empty(t, records)
+}
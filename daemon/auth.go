@@ -0,0 +1,85 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package daemon
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/pkg/errors"
+)
+
+// Authenticator validates the bearer tokens of requests.
+type Authenticator interface {
+	// Authenticate returns the principal identified by the token, or an error
+	// if the token is not valid.
+	Authenticate(ctx context.Context, token string) (string, error)
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a context of a request made by the principal.
+func WithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// Principal returns the authenticated principal of the request, if any.
+func Principal(ctx context.Context) string {
+	principal, _ := ctx.Value(principalKey{}).(string)
+	return principal
+}
+
+// PublicRouter is implemented by routers whose routes are served without
+// authentication, such as health checks.
+type PublicRouter interface {
+	Router
+
+	// Public returns whether the routes are served without authentication.
+	Public() bool
+}
+
+// SetAuthenticator requires requests to carry a bearer token accepted by
+// auth, except for the routes of public routers.
+func (d *Daemon) SetAuthenticator(auth Authenticator) {
+	d.auth = auth
+}
+
+// authenticate wraps handler to reject requests without a valid bearer token
+// as unauthorized.
+func authenticate(auth Authenticator, handler Handler) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			return errors.Wrap(errdefs.ErrUnauthorized, "missing bearer token")
+		}
+
+		parts := strings.SplitN(header, " ", 2)
+		if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") || parts[1] == "" {
+			return errors.Wrap(errdefs.ErrUnauthorized, "authorization must be a bearer token")
+		}
+
+		principal, err := auth.Authenticate(ctx, parts[1])
+		if err != nil {
+			if errdefs.IsUnauthorized(err) || errdefs.IsUnavailable(err) {
+				return err
+			}
+			return errors.Wrapf(errdefs.ErrUnauthorized, "invalid bearer token: %s", err)
+		}
+
+		return handler(WithPrincipal(ctx, principal), w, r, vars)
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package daemon
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Netflix/p2plab/errdefs"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+type tokenAuthenticator string
+
+func (a tokenAuthenticator) Authenticate(ctx context.Context, token string) (string, error) {
+	if token != string(a) {
+		return "", errdefs.ErrUnauthorized
+	}
+	return "tester", nil
+}
+
+type okRouter struct {
+	path   string
+	public bool
+}
+
+func (r *okRouter) Public() bool {
+	return r.public
+}
+
+func (r *okRouter) Routes() []Route {
+	return []Route{
+		NewGetRoute(r.path, func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+			w.Write([]byte("OK"))
+			return nil
+		}),
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	logger := zerolog.Nop()
+	d, err := New("test", "127.0.0.1:0", &logger)
+	require.NoError(t, err)
+	d.tracer = opentracing.NoopTracer{}
+	d.SetAuthenticator(tokenAuthenticator("secret"))
+	mux := d.createMux(&okRouter{path: "/healthcheck", public: true}, &okRouter{path: "/benchmarks/json"})
+
+	for _, tc := range []struct {
+		path          string
+		authorization string
+		code          int
+	}{
+		{"/healthcheck", "", http.StatusOK},
+		{"/benchmarks/json", "", http.StatusUnauthorized},
+		{"/benchmarks/json", "Basic secret", http.StatusUnauthorized},
+		{"/benchmarks/json", "Bearer wrong", http.StatusUnauthorized},
+		{"/benchmarks/json", "Bearer secret", http.StatusOK},
+	} {
+		r := httptest.NewRequest("GET", tc.path, nil)
+		if tc.authorization != "" {
+			r.Header.Set("Authorization", tc.authorization)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		require.Equal(t, tc.code, w.Code, "%s with %q", tc.path, tc.authorization)
+	}
+}
@@ -19,9 +19,11 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/pkg/httputil"
 	"github.com/Netflix/p2plab/pkg/logutil"
 	"github.com/Netflix/p2plab/pkg/traceutil"
 	"github.com/gorilla/mux"
@@ -30,25 +32,45 @@ import (
 	"github.com/rs/zerolog"
 )
 
+const (
+	// DefaultGracePeriod is how long in-flight requests are given to complete
+	// once the daemon is shutting down.
+	DefaultGracePeriod = 30 * time.Second
+)
+
 type Daemon struct {
-	service string
-	addr    string
-	logger  *zerolog.Logger
-	routers []Router
-	tracer  opentracing.Tracer
-	closers []io.Closer
+	service     string
+	addr        string
+	logger      *zerolog.Logger
+	routers     []Router
+	tracer      opentracing.Tracer
+	closers     []io.Closer
+	gracePeriod time.Duration
+	auth        Authenticator
+	limiter     *RateLimiter
+	audit       AuditSink
+	metrics     *metrics
 }
 
 func New(service, addr string, logger *zerolog.Logger, routers ...Router) (*Daemon, error) {
 	d := &Daemon{
-		service: service,
-		addr:    addr,
-		logger:  logger,
-		routers: routers,
+		service:     service,
+		addr:        addr,
+		logger:      logger,
+		routers:     routers,
+		gracePeriod: DefaultGracePeriod,
+		metrics:     newMetrics(),
 	}
 	return d, nil
 }
 
+// SetGracePeriod sets how long routers and in-flight requests are given to
+// wind down once the serve context is canceled, before the daemon exits
+// regardless.
+func (d *Daemon) SetGracePeriod(gracePeriod time.Duration) {
+	d.gracePeriod = gracePeriod
+}
+
 func (d *Daemon) Close() error {
 	for _, closer := range d.closers {
 		err := closer.Close()
@@ -72,25 +94,90 @@ func (d *Daemon) Serve(ctx context.Context) error {
 		WriteTimeout:      30 * time.Minute,
 	}
 
+	shutdown := make(chan struct{})
 	go func() {
+		defer close(shutdown)
 		<-ctx.Done()
-		err := s.Shutdown(ctx)
+
+		zerolog.Ctx(ctx).Info().Dur("grace", d.gracePeriod).Msg("daemon shutting down")
+
+		// The serve context is canceled, so use a new one that keeps the logger.
+		sctx, cancel := context.WithTimeout(zerolog.Ctx(ctx).WithContext(context.Background()), d.gracePeriod)
+		defer cancel()
+
+		d.shutdownRouters(sctx)
+
+		err := s.Shutdown(sctx)
 		if err != nil {
-			zerolog.Ctx(ctx).Warn().Err(err).Msg("Failed to shutdown daemon")
+			zerolog.Ctx(ctx).Warn().Err(err).Msg("Failed to shutdown daemon gracefully")
+			s.Close()
 		}
 	}()
 
 	zerolog.Ctx(ctx).Info().Str("addr", d.addr).Msg("daemon listening")
-	return s.ListenAndServe()
+	err := s.ListenAndServe()
+	if err != http.ErrServerClosed {
+		return err
+	}
+
+	<-shutdown
+	return nil
+}
+
+// shutdownRouters shuts down the routers that have in-flight work, while the
+// daemon keeps serving requests.
+func (d *Daemon) shutdownRouters(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, router := range d.routers {
+		shutdowner, ok := router.(Shutdowner)
+		if !ok {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := shutdowner.Shutdown(ctx)
+			if err != nil {
+				zerolog.Ctx(ctx).Warn().Err(err).Msg("Failed to shutdown router")
+			}
+		}()
+	}
+	wg.Wait()
 }
 
 func (d *Daemon) createMux(routers ...Router) *mux.Router {
 	root := mux.NewRouter().UseEncodedPath().StrictSlash(true)
+	routers = append(routers, &metricsRouter{d.metrics})
 	for _, router := range routers {
+		if gr, ok := router.(GaugeRouter); ok {
+			d.metrics.addGauges(gr.Gauges()...)
+		}
+
+		public := false
+		if pr, ok := router.(PublicRouter); ok {
+			public = pr.Public()
+		}
+
 		for _, route := range router.Routes() {
+			handler := route.Handler()
+			if d.limiter != nil && !public {
+				handler = rateLimit(d.limiter, d.auth != nil, handler)
+			}
+			// Requests are audited once authenticated, so that records have
+			// the principal of the request.
+			if d.audit != nil && !public && isWrite(route.Method()) {
+				handler = audit(d.audit, route, handler)
+			}
+			if d.auth != nil && !public {
+				handler = authenticate(d.auth, handler)
+			}
+
 			var h http.Handler
-			h = d.createHTTPHandler(route.Handler())
+			h = d.createHTTPHandler(handler)
+			h = httputil.CompressionHandler(h)
 			h = nethttp.Middleware(d.tracer, h)
+			h = d.metrics.instrument(route, h)
 
 			d.logger.Debug().Str("path", route.Path()).Str("method", route.Method()).Msg("Registering route")
 			root.Path(route.Path()).Methods(route.Method()).Handler(h)
@@ -101,8 +188,18 @@ func (d *Daemon) createMux(routers ...Router) *mux.Router {
 
 func (d *Daemon) createHTTPHandler(handler Handler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := d.logger.WithContext(r.Context())
+		// Requests are correlated by the ID of the caller, or a new one if the
+		// caller didn't send any, which is also propagated to the agents.
+		id := r.Header.Get(httputil.RequestIDHeader)
+		if id == "" {
+			id = httputil.NewRequestID(r.Context())
+		}
+		w.Header().Set(httputil.RequestIDHeader, id)
+
+		logger := d.logger.With().Str("request_id", id).Logger()
+		ctx := logger.WithContext(r.Context())
 		ctx = traceutil.WithTracer(ctx, d.tracer)
+		ctx = httputil.WithRequestID(ctx, id)
 		r = r.WithContext(ctx)
 
 		vars := mux.Vars(r)
@@ -112,24 +209,42 @@ func (d *Daemon) createHTTPHandler(handler Handler) http.HandlerFunc {
 
 		err := handler(ctx, w, r, vars)
 		if err != nil {
-			d.logger.Debug().Err(err).Msg("failed request")
-			if errdefs.IsAlreadyExists(err) {
-				http.Error(w, err.Error(), http.StatusConflict)
-			} else if errdefs.IsNotFound(err) {
-				http.Error(w, err.Error(), http.StatusNotFound)
-			} else if errdefs.IsInvalidArgument(err) {
-				http.Error(w, err.Error(), http.StatusNotAcceptable)
-			} else if errdefs.IsUnavailable(err) {
-				http.Error(w, err.Error(), http.StatusServiceUnavailable)
-			} else {
-				// Any error types we don't specifically look out for default to serving a
-				// HTTP 500.
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+			logger.Debug().Err(err).Msg("failed request")
+			code := errdefs.Code(err)
+			if code == errdefs.CodeUnauthorized {
+				w.Header().Set("WWW-Authenticate", "Bearer")
 			}
+			httputil.WriteError(w, httpStatus(code), err)
 		}
 	}
 }
 
+// httpStatus returns the HTTP status of errors with the code.
+func httpStatus(code errdefs.ErrorCode) int {
+	switch code {
+	case errdefs.CodeAlreadyExists:
+		return http.StatusConflict
+	case errdefs.CodeNotFound:
+		return http.StatusNotFound
+	case errdefs.CodeInvalidArgument:
+		return http.StatusNotAcceptable
+	case errdefs.CodeFailedPrecondition:
+		return http.StatusPreconditionFailed
+	case errdefs.CodeUnavailable:
+		return http.StatusServiceUnavailable
+	case errdefs.CodeNotImplemented:
+		return http.StatusNotImplemented
+	case errdefs.CodeUnauthorized:
+		return http.StatusUnauthorized
+	case errdefs.CodeRateLimited:
+		return http.StatusTooManyRequests
+	default:
+		// Any error types we don't specifically look out for default to serving a
+		// HTTP 500.
+		return http.StatusInternalServerError
+	}
+}
+
 func WriteJSON(w http.ResponseWriter, v interface{}) error {
 	content, err := json.MarshalIndent(v, "", "    ")
 	if err != nil {
@@ -0,0 +1,67 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package daemon
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+type shutdownRouter struct {
+	shutdown chan struct{}
+}
+
+func (r *shutdownRouter) Routes() []Route {
+	return nil
+}
+
+func (r *shutdownRouter) Shutdown(ctx context.Context) error {
+	close(r.shutdown)
+	return nil
+}
+
+func TestServeShutdown(t *testing.T) {
+	logger := zerolog.Nop()
+	router := &shutdownRouter{shutdown: make(chan struct{})}
+	d, err := New("test", "127.0.0.1:0", &logger, router)
+	require.NoError(t, err)
+	d.SetGracePeriod(time.Second)
+
+	ctx, cancel := context.WithCancel(logger.WithContext(context.Background()))
+	errs := make(chan error, 1)
+	go func() {
+		errs <- d.Serve(ctx)
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-errs:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("daemon did not shut down")
+	}
+
+	select {
+	case <-router.shutdown:
+	default:
+		t.Fatal("router was not shut down")
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package daemon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteCacheableJSON(t *testing.T) {
+	v := map[string]string{"ID": "a"}
+
+	r := httptest.NewRequest("GET", "/items/a/json", nil)
+	w := httptest.NewRecorder()
+	err := WriteCacheableJSON(w, r, v)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	r.Header.Set("If-None-Match", `"stale", `+etag)
+	w = httptest.NewRecorder()
+	err = WriteCacheableJSON(w, r, v)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotModified, w.Code)
+	require.Empty(t, w.Body.String())
+
+	w = httptest.NewRecorder()
+	err = WriteCacheableJSON(w, r, map[string]string{"ID": "b"})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NotEqual(t, etag, w.Header().Get("ETag"))
+}
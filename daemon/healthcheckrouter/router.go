@@ -16,26 +16,97 @@ package healthcheckrouter
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"strings"
+	"sync"
 
 	"github.com/Netflix/p2plab/daemon"
+	"github.com/Netflix/p2plab/metadata"
 )
 
-type router struct{}
+// HealthCheck reports the status of a component the daemon depends on.
+type HealthCheck func(ctx context.Context) metadata.HealthComponent
 
-func New() daemon.Router {
-	return &router{}
+type router struct {
+	checks []HealthCheck
+	limits []metadata.RateLimit
+}
+
+// New returns a router reporting the liveness and readiness of the daemon,
+// where readiness depends on the status of the components checked.
+func New(checks ...HealthCheck) daemon.Router {
+	return &router{checks: checks}
+}
+
+// NewWithRateLimits returns a router like New that also reports the rate
+// limits of the daemon, so that clients can pace themselves.
+func NewWithRateLimits(limits []metadata.RateLimit, checks ...HealthCheck) daemon.Router {
+	return &router{checks: checks, limits: limits}
+}
+
+// Public serves health checks without authentication, so that load balancers
+// and orchestrators can probe the daemon.
+func (s *router) Public() bool {
+	return true
 }
 
 func (s *router) Routes() []daemon.Route {
 	return []daemon.Route{
 		// GET
 		daemon.NewGetRoute("/healthcheck", s.healthcheck),
+		daemon.NewGetRoute("/healthz", s.healthz),
+		daemon.NewGetRoute("/readyz", s.readyz),
 	}
 }
 
+// healthcheck reports the daemon is live.
 func (s *router) healthcheck(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("OK"))
 	return nil
 }
+
+func (s *router) healthz(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	health := s.check(ctx)
+	return daemon.WriteJSON(w, &health)
+}
+
+// readyz responds with 503 unless the daemon is ready, for load balancers and
+// orchestrators that only look at the status code.
+func (s *router) readyz(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	health := s.check(ctx)
+	if health.Ready {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+		return nil
+	}
+
+	var unavailable []string
+	for _, c := range health.Components {
+		if c.Status == metadata.HealthUnavailable {
+			unavailable = append(unavailable, fmt.Sprintf("%s: %s", c.Name, c.Message))
+		}
+	}
+	http.Error(w, strings.Join(unavailable, "\n"), http.StatusServiceUnavailable)
+	return nil
+}
+
+// check runs the health checks concurrently.
+func (s *router) check(ctx context.Context) metadata.Health {
+	var wg sync.WaitGroup
+	components := make([]metadata.HealthComponent, len(s.checks))
+	for i, check := range s.checks {
+		i, check := i, check
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			components[i] = check(ctx)
+		}()
+	}
+	wg.Wait()
+
+	health := metadata.NewHealth(components)
+	health.RateLimits = s.limits
+	return health
+}
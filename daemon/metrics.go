@@ -0,0 +1,237 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Netflix/p2plab/reports"
+)
+
+// MetricsPath is the path of the metrics of the daemon's HTTP server, which
+// are distinct from the metrics of benchmarks.
+const MetricsPath = "/metrics/daemon"
+
+// latencyBuckets are the upper bounds in seconds of the request latency
+// histograms. Streaming requests may last as long as a benchmark.
+var latencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 300}
+
+// Gauge is a metric sampled whenever the daemon's metrics are scraped.
+type Gauge struct {
+	Name  string
+	Help  string
+	Value func() float64
+}
+
+// GaugeRouter is implemented by routers that expose gauges of their state
+// with the daemon's metrics.
+type GaugeRouter interface {
+	// Gauges returns the gauges of the router.
+	Gauges() []Gauge
+}
+
+// metrics records the rate, errors and duration of the requests to each
+// route of a daemon.
+type metrics struct {
+	inFlight int64
+
+	mu     sync.Mutex
+	routes []*routeMetrics
+	gauges []Gauge
+}
+
+type routeMetrics struct {
+	method  string
+	path    string
+	classes map[string]uint64
+	buckets []uint64
+	sum     float64
+	count   uint64
+}
+
+func newMetrics() *metrics {
+	return &metrics{}
+}
+
+// addGauges adds gauges sampled whenever the metrics are written.
+func (m *metrics) addGauges(gauges ...Gauge) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gauges = append(m.gauges, gauges...)
+}
+
+// instrument wraps the handler of a route to record its requests. Requests
+// are labeled by the path template of the route rather than the path
+// requested, so that the number of series is bounded.
+func (m *metrics) instrument(route Route, h http.Handler) http.Handler {
+	rm := &routeMetrics{
+		method:  route.Method(),
+		path:    route.Path(),
+		classes: make(map[string]uint64),
+		buckets: make([]uint64, len(latencyBuckets)),
+	}
+
+	m.mu.Lock()
+	m.routes = append(m.routes, rm)
+	m.mu.Unlock()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&m.inFlight, 1)
+		defer atomic.AddInt64(&m.inFlight, -1)
+
+		start := time.Now()
+		sw := &statusResponseWriter{ResponseWriter: w}
+		h.ServeHTTP(sw, r)
+		m.observe(rm, sw.Status(), time.Since(start))
+	})
+}
+
+func (m *metrics) observe(rm *routeMetrics, status int, elapsed time.Duration) {
+	seconds := elapsed.Seconds()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rm.classes[statusClass(status)]++
+	for i, le := range latencyBuckets {
+		if seconds <= le {
+			rm.buckets[i]++
+		}
+	}
+	rm.sum += seconds
+	rm.count++
+}
+
+// statusClass returns the class of an HTTP status code, such as "2xx".
+func statusClass(status int) string {
+	return fmt.Sprintf("%dxx", status/100)
+}
+
+// writePrometheus writes the metrics in the Prometheus text exposition format.
+func (m *metrics) writePrometheus(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reports.WritePrometheusHeader(bw, "p2plab_http_requests_total", "counter", "Number of HTTP requests served, by route and status class.")
+	for _, rm := range m.routes {
+		for _, class := range []string{"1xx", "2xx", "3xx", "4xx", "5xx"} {
+			if rm.classes[class] == 0 {
+				continue
+			}
+			reports.WritePrometheusSample(bw, "p2plab_http_requests_total", rm.labels("code", class), float64(rm.classes[class]))
+		}
+	}
+
+	reports.WritePrometheusHeader(bw, "p2plab_http_request_duration_seconds", "histogram", "Time taken to serve HTTP requests, by route.")
+	for _, rm := range m.routes {
+		if rm.count == 0 {
+			continue
+		}
+		for i, le := range latencyBuckets {
+			reports.WritePrometheusSample(bw, "p2plab_http_request_duration_seconds_bucket", rm.labels("le", strconv.FormatFloat(le, 'g', -1, 64)), float64(rm.buckets[i]))
+		}
+		reports.WritePrometheusSample(bw, "p2plab_http_request_duration_seconds_bucket", rm.labels("le", "+Inf"), float64(rm.count))
+		reports.WritePrometheusSample(bw, "p2plab_http_request_duration_seconds_sum", rm.labels(), rm.sum)
+		reports.WritePrometheusSample(bw, "p2plab_http_request_duration_seconds_count", rm.labels(), float64(rm.count))
+	}
+
+	reports.WritePrometheusHeader(bw, "p2plab_http_requests_in_flight", "gauge", "Number of HTTP requests being served.")
+	reports.WritePrometheusSample(bw, "p2plab_http_requests_in_flight", nil, float64(atomic.LoadInt64(&m.inFlight)))
+
+	for _, gauge := range m.gauges {
+		reports.WritePrometheusHeader(bw, gauge.Name, "gauge", gauge.Help)
+		reports.WritePrometheusSample(bw, gauge.Name, nil, gauge.Value())
+	}
+
+	return bw.Flush()
+}
+
+// labels returns the labels of the route's series followed by the extra
+// label pairs.
+func (rm *routeMetrics) labels(pairs ...string) [][2]string {
+	labels := [][2]string{
+		{"method", rm.method},
+		{"route", rm.path},
+	}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		labels = append(labels, [2]string{pairs[i], pairs[i+1]})
+	}
+	return labels
+}
+
+// statusResponseWriter records the status code written to the response.
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusResponseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *statusResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Status returns the status code of the response, which is implicitly OK if
+// none was written.
+func (w *statusResponseWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// metricsRouter serves the metrics of the daemon without authentication, so
+// that they can be scraped like health checks.
+type metricsRouter struct {
+	metrics *metrics
+}
+
+func (s *metricsRouter) Public() bool {
+	return true
+}
+
+func (s *metricsRouter) Routes() []Route {
+	return []Route{
+		NewGetRoute(MetricsPath, s.getMetrics),
+	}
+}
+
+func (s *metricsRouter) getMetrics(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	w.Header().Set("Content-Type", reports.PrometheusContentType)
+	return s.metrics.writePrometheus(w)
+}
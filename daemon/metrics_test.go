@@ -0,0 +1,78 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package daemon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+type gaugeRouter struct {
+	okRouter
+}
+
+func (r *gaugeRouter) Gauges() []Gauge {
+	return []Gauge{
+		{Name: "p2plab_test_depth", Help: "Depth of the test.", Value: func() float64 { return 3 }},
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	logger := zerolog.Nop()
+	d, err := New("test", "127.0.0.1:0", &logger)
+	require.NoError(t, err)
+	d.tracer = opentracing.NoopTracer{}
+	d.SetAuthenticator(tokenAuthenticator("secret"))
+	mux := d.createMux(&okRouter{path: "/healthcheck", public: true}, &gaugeRouter{okRouter{path: "/benchmarks/{id}/json"}})
+
+	for _, tc := range []struct {
+		path  string
+		token string
+		code  int
+	}{
+		{"/healthcheck", "", http.StatusOK},
+		{"/benchmarks/b1/json", "secret", http.StatusOK},
+		{"/benchmarks/b2/json", "secret", http.StatusOK},
+		{"/benchmarks/b1/json", "", http.StatusUnauthorized},
+	} {
+		r := httptest.NewRequest("GET", tc.path, nil)
+		if tc.token != "" {
+			r.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		require.Equal(t, tc.code, w.Code, tc.path)
+	}
+
+	// The metrics are public, so they can be scraped without a token.
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", MetricsPath, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	body := w.Body.String()
+	require.Contains(t, body, "# TYPE p2plab_http_requests_total counter\n")
+	require.Contains(t, body, `p2plab_http_requests_total{method="GET",route="/healthcheck",code="2xx"} 1`+"\n")
+	require.Contains(t, body, `p2plab_http_requests_total{method="GET",route="/benchmarks/{id}/json",code="2xx"} 2`+"\n")
+	require.Contains(t, body, `p2plab_http_requests_total{method="GET",route="/benchmarks/{id}/json",code="4xx"} 1`+"\n")
+	require.Contains(t, body, `p2plab_http_request_duration_seconds_bucket{method="GET",route="/benchmarks/{id}/json",le="+Inf"} 3`+"\n")
+	require.Contains(t, body, `p2plab_http_request_duration_seconds_count{method="GET",route="/benchmarks/{id}/json"} 3`+"\n")
+	require.Contains(t, body, "p2plab_http_requests_in_flight 1\n")
+	require.Contains(t, body, "# TYPE p2plab_test_depth gauge\np2plab_test_depth 3\n")
+}
@@ -0,0 +1,124 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package daemon
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strconv"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/pkg/errors"
+)
+
+const (
+	// NextPageTokenHeader is the response header with the token to request the
+	// next page of a paginated list. It is absent on the last page.
+	NextPageTokenHeader = "Next-Page-Token"
+)
+
+// Page is a window into a list sorted by ID, requested with the limit, offset
+// and page-token form values.
+type Page struct {
+	// Limit is the maximum number of items in the page, zero means no limit.
+	Limit int
+
+	// Offset is the number of items to skip.
+	Offset int
+
+	// After is the ID of the last item of the previous page.
+	After string
+}
+
+// ParsePage parses the page requested by r. If maxLimit is positive, it caps
+// the limit of the page, including requests that didn't specify one.
+func ParsePage(r *http.Request, maxLimit int) (Page, error) {
+	var page Page
+	for key, v := range map[string]*int{"limit": &page.Limit, "offset": &page.Offset} {
+		value := r.FormValue(key)
+		if value == "" {
+			continue
+		}
+
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return page, errors.Wrapf(errdefs.ErrInvalidArgument, "%s must be a non-negative integer", key)
+		}
+		*v = n
+	}
+
+	token := r.FormValue("page-token")
+	if token != "" {
+		after, err := base64.RawURLEncoding.DecodeString(token)
+		if err != nil {
+			return page, errors.Wrap(errdefs.ErrInvalidArgument, "invalid page token")
+		}
+		page.After = string(after)
+	}
+
+	if maxLimit > 0 && (page.Limit == 0 || page.Limit > maxLimit) {
+		page.Limit = maxLimit
+	}
+	return page, nil
+}
+
+// Window returns the bounds [start, end) of the page in a list of n items,
+// and the token for the next page if there are more items. The page starts
+// after the item with the ID of the token, or if it no longer exists, after
+// the IDs sorting before it.
+func (p Page) Window(n int, id func(i int) string) (start, end int, next string) {
+	if p.After != "" {
+		found := false
+		for i := 0; i < n && !found; i++ {
+			if id(i) == p.After {
+				start, found = i+1, true
+			}
+		}
+
+		for !found && start < n && id(start) <= p.After {
+			start++
+		}
+	}
+
+	start += p.Offset
+	if start > n {
+		start = n
+	}
+
+	end = n
+	if p.Limit > 0 && start+p.Limit < n {
+		end = start + p.Limit
+		next = base64.RawURLEncoding.EncodeToString([]byte(id(end - 1)))
+	}
+	return start, end, next
+}
+
+// SetNextPageToken sets the header with the token for the next page, if any.
+func SetNextPageToken(w http.ResponseWriter, next string) {
+	if next != "" {
+		w.Header().Set(NextPageTokenHeader, next)
+	}
+}
+
+// ParseOrder parses the order-by and desc form values of r.
+func ParseOrder(r *http.Request) (field string, desc bool, err error) {
+	if r.FormValue("desc") != "" {
+		desc, err = strconv.ParseBool(r.FormValue("desc"))
+		if err != nil {
+			return "", false, errors.Wrap(errdefs.ErrInvalidArgument, "desc must be a boolean")
+		}
+	}
+	return r.FormValue("order-by"), desc, nil
+}
@@ -0,0 +1,83 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package daemon
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPage(t *testing.T) {
+	ids := []string{"a", "b", "c", "d", "e"}
+	id := func(i int) string { return ids[i] }
+
+	for _, test := range []struct {
+		query    string
+		maxLimit int
+		expected []string
+	}{
+		{"", 0, ids},
+		{"limit=2", 0, []string{"a", "b"}},
+		{"limit=2&offset=2", 0, []string{"c", "d"}},
+		{"offset=10", 0, nil},
+		{"", 3, []string{"a", "b", "c"}},
+		{"limit=10", 3, []string{"a", "b", "c"}},
+	} {
+		page, err := ParsePage(httptest.NewRequest("GET", "/?"+test.query, nil), test.maxLimit)
+		require.NoError(t, err)
+
+		start, end, _ := page.Window(len(ids), id)
+		if test.expected == nil {
+			require.Equal(t, start, end, test.query)
+		} else {
+			require.Equal(t, test.expected, ids[start:end], test.query)
+		}
+	}
+
+	var (
+		token string
+		pages [][]string
+	)
+	for {
+		page, err := ParsePage(httptest.NewRequest("GET", "/?limit=2&page-token="+token, nil), 0)
+		require.NoError(t, err)
+
+		start, end, next := page.Window(len(ids), id)
+		pages = append(pages, ids[start:end])
+		if next == "" {
+			break
+		}
+		token = next
+	}
+	require.Equal(t, [][]string{{"a", "b"}, {"c", "d"}, {"e"}}, pages)
+
+	desc := []string{"e", "d", "c", "b", "a"}
+	page := Page{Limit: 2, After: "d"}
+	start, end, _ := page.Window(len(desc), func(i int) string { return desc[i] })
+	require.Equal(t, []string{"c", "b"}, desc[start:end])
+
+	page = Page{After: "a"}
+	start, end, _ = page.Window(len(desc), func(i int) string { return desc[i] })
+	require.Equal(t, start, end)
+
+	_, err := ParsePage(httptest.NewRequest("GET", "/?limit=-1", nil), 0)
+	require.True(t, errdefs.IsInvalidArgument(err))
+
+	_, err = ParsePage(httptest.NewRequest("GET", "/?page-token=!", nil), 0)
+	require.True(t, errdefs.IsInvalidArgument(err))
+}
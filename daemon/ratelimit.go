@@ -0,0 +1,232 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package daemon
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/pkg/errors"
+)
+
+// maxBuckets is the number of clients tracked before buckets that have
+// refilled are pruned.
+const maxBuckets = 1024
+
+// RateLimiter limits the requests of each client with token buckets, where
+// clients are identified by their bearer token or otherwise by their address.
+// Requests that don't modify resources have a separate limit, so that clients
+// can keep inspecting resources while their submissions are limited.
+type RateLimiter struct {
+	read   metadata.RateLimit
+	write  metadata.RateLimit
+	tokens map[string]metadata.RateLimit
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+// NewRateLimiter returns a rate limiter with read and write limits for each
+// client, where the write limit of clients with one of the bearer tokens is
+// overridden. Zero limits don't restrict any request.
+func NewRateLimiter(read, write metadata.RateLimit, tokens map[string]metadata.RateLimit) *RateLimiter {
+	read.Class = metadata.RateLimitRead
+	write.Class = metadata.RateLimitWrite
+
+	overrides := make(map[string]metadata.RateLimit)
+	for token, limit := range tokens {
+		limit.Class = metadata.RateLimitWrite
+		overrides[token] = limit
+	}
+
+	return &RateLimiter{
+		read:    read,
+		write:   write,
+		tokens:  overrides,
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Limits returns the limits applied to clients without an overridden limit.
+func (l *RateLimiter) Limits() []metadata.RateLimit {
+	var limits []metadata.RateLimit
+	for _, limit := range []metadata.RateLimit{l.read, l.write} {
+		if !limit.Unlimited() {
+			limits = append(limits, limit)
+		}
+	}
+	return limits
+}
+
+// Allow returns whether the request of the method by the client is allowed,
+// and otherwise how long until it would be.
+func (l *RateLimiter) Allow(method, client, token string) (bool, time.Duration) {
+	limit := l.read
+	if isWrite(method) {
+		limit = l.write
+		if override, ok := l.tokens[token]; ok && token != "" {
+			limit = override
+		}
+	}
+	if limit.Unlimited() {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if len(l.buckets) >= maxBuckets {
+		for key, b := range l.buckets {
+			if b.full(now) {
+				delete(l.buckets, key)
+			}
+		}
+	}
+
+	key := limit.Class + "/" + client
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{
+			limit:  limit,
+			tokens: float64(limit.Requests),
+			last:   now,
+		}
+		l.buckets[key] = b
+	}
+
+	return b.take(now)
+}
+
+// SetRateLimiter limits the requests of clients to the routes of routers
+// that are not public.
+func (d *Daemon) SetRateLimiter(limiter *RateLimiter) {
+	d.limiter = limiter
+}
+
+// rateLimit wraps handler to reject requests exceeding the limits of their
+// client as rate limited, with the delay to wait in Retry-After. Clients are
+// only identified by their bearer token if it is authenticated, otherwise
+// they could evade their limits with new tokens.
+func rateLimit(limiter *RateLimiter, authenticated bool, handler Handler) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		var token string
+		parts := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+		if authenticated && len(parts) == 2 && strings.EqualFold(parts[0], "Bearer") {
+			token = parts[1]
+		}
+
+		client := token
+		if client == "" {
+			client = r.RemoteAddr
+			host, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err == nil {
+				client = host
+			}
+		}
+
+		ok, wait := limiter.Allow(r.Method, client, token)
+		if !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			return errors.Wrapf(errdefs.ErrRateLimited, "too many requests, retry in %s", wait.Round(time.Second))
+		}
+
+		return handler(ctx, w, r, vars)
+	}
+}
+
+func isWrite(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	default:
+		return true
+	}
+}
+
+// bucket holds up to limit.Requests tokens, refilled evenly over
+// limit.Interval.
+type bucket struct {
+	limit  metadata.RateLimit
+	tokens float64
+	last   time.Time
+}
+
+func (b *bucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last)
+	if elapsed <= 0 {
+		return
+	}
+	b.last = now
+
+	b.tokens += float64(b.limit.Requests) * float64(elapsed) / float64(b.limit.Interval)
+	if b.tokens > float64(b.limit.Requests) {
+		b.tokens = float64(b.limit.Requests)
+	}
+}
+
+func (b *bucket) full(now time.Time) bool {
+	b.refill(now)
+	return b.tokens >= float64(b.limit.Requests)
+}
+
+func (b *bucket) take(now time.Time) (bool, time.Duration) {
+	b.refill(now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	missing := 1 - b.tokens
+	return false, time.Duration(missing * float64(b.limit.Interval) / float64(b.limit.Requests))
+}
+
+// ParseRateLimit parses a limit of requests per interval such as "30/1m". An
+// empty string is unlimited.
+func ParseRateLimit(s string) (metadata.RateLimit, error) {
+	if s == "" {
+		return metadata.RateLimit{}, nil
+	}
+
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) != 2 {
+		return metadata.RateLimit{}, errors.Wrapf(errdefs.ErrInvalidArgument, "rate limit %q must be requests per interval, e.g. 30/1m", s)
+	}
+
+	requests, err := strconv.Atoi(parts[0])
+	if err != nil || requests < 0 {
+		return metadata.RateLimit{}, errors.Wrapf(errdefs.ErrInvalidArgument, "invalid requests of rate limit %q", s)
+	}
+
+	interval, err := time.ParseDuration(parts[1])
+	if err != nil || interval <= 0 {
+		return metadata.RateLimit{}, errors.Wrapf(errdefs.ErrInvalidArgument, "invalid interval of rate limit %q", s)
+	}
+
+	return metadata.RateLimit{
+		Requests: requests,
+		Interval: interval,
+	}, nil
+}
@@ -0,0 +1,110 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package daemon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Netflix/p2plab/metadata"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter(t *testing.T) {
+	read := metadata.RateLimit{Requests: 4, Interval: time.Minute}
+	write := metadata.RateLimit{Requests: 2, Interval: time.Minute}
+	l := NewRateLimiter(read, write, map[string]metadata.RateLimit{
+		"ci": {Requests: 3, Interval: time.Minute},
+	})
+
+	now := time.Unix(0, 0)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		ok, _ := l.Allow("POST", "alice", "")
+		require.True(t, ok)
+	}
+	ok, wait := l.Allow("POST", "alice", "")
+	require.False(t, ok)
+	require.Equal(t, 30*time.Second, wait)
+
+	// Reads and other clients have their own buckets.
+	ok, _ = l.Allow("GET", "alice", "")
+	require.True(t, ok)
+	ok, _ = l.Allow("POST", "bob", "")
+	require.True(t, ok)
+
+	// Tokens may have a different write limit.
+	for i := 0; i < 3; i++ {
+		ok, _ = l.Allow("POST", "ci", "ci")
+		require.True(t, ok)
+	}
+	ok, _ = l.Allow("POST", "ci", "ci")
+	require.False(t, ok)
+
+	now = now.Add(30 * time.Second)
+	ok, _ = l.Allow("POST", "alice", "")
+	require.True(t, ok)
+
+	require.Equal(t, []metadata.RateLimit{
+		{Class: metadata.RateLimitRead, Requests: 4, Interval: time.Minute},
+		{Class: metadata.RateLimitWrite, Requests: 2, Interval: time.Minute},
+	}, l.Limits())
+}
+
+func TestRateLimit(t *testing.T) {
+	logger := zerolog.Nop()
+	d, err := New("test", "127.0.0.1:0", &logger)
+	require.NoError(t, err)
+	d.tracer = opentracing.NoopTracer{}
+	d.SetRateLimiter(NewRateLimiter(metadata.RateLimit{Requests: 1, Interval: time.Hour}, metadata.RateLimit{}, nil))
+	mux := d.createMux(&okRouter{path: "/healthcheck", public: true}, &okRouter{path: "/benchmarks/json"})
+
+	for _, tc := range []struct {
+		path string
+		code int
+	}{
+		{"/benchmarks/json", http.StatusOK},
+		{"/benchmarks/json", http.StatusTooManyRequests},
+		{"/healthcheck", http.StatusOK},
+		{"/healthcheck", http.StatusOK},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", tc.path, nil))
+		require.Equal(t, tc.code, w.Code, tc.path)
+		if tc.code == http.StatusTooManyRequests {
+			require.Equal(t, "3600", w.Header().Get("Retry-After"))
+		}
+	}
+}
+
+func TestParseRateLimit(t *testing.T) {
+	limit, err := ParseRateLimit("30/1m")
+	require.NoError(t, err)
+	require.Equal(t, metadata.RateLimit{Requests: 30, Interval: time.Minute}, limit)
+
+	limit, err = ParseRateLimit("")
+	require.NoError(t, err)
+	require.True(t, limit.Unlimited())
+
+	for _, s := range []string{"30", "x/1m", "30/soon", "30/0s"} {
+		_, err = ParseRateLimit(s)
+		require.Error(t, err, s)
+	}
+}
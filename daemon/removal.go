@@ -0,0 +1,53 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package daemon
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/pkg/errors"
+)
+
+// Removal is how a deletion treats the resources that depend on the deleted
+// ones, requested with the cascade and force form values. By default, the
+// deletion is refused while there are dependents.
+type Removal struct {
+	// Cascade deletes the dependents along with the resources.
+	Cascade bool
+
+	// Force deletes the resources regardless of their dependents, leaving
+	// them with dangling references.
+	Force bool
+}
+
+// ParseRemoval parses the removal requested by r.
+func ParseRemoval(r *http.Request) (Removal, error) {
+	var removal Removal
+	for key, v := range map[string]*bool{"cascade": &removal.Cascade, "force": &removal.Force} {
+		value := r.FormValue(key)
+		if value == "" {
+			continue
+		}
+
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return removal, errors.Wrapf(errdefs.ErrInvalidArgument, "%s must be a boolean", key)
+		}
+		*v = b
+	}
+	return removal, nil
+}
@@ -23,6 +23,14 @@ type Router interface {
 	Routes() []Route
 }
 
+// Shutdowner is implemented by routers that wind down in-flight work before
+// the daemon exits.
+type Shutdowner interface {
+	// Shutdown returns once the router's work is wound down, or the context is
+	// done.
+	Shutdown(ctx context.Context) error
+}
+
 type Route interface {
 	Method() string
 	Path() string
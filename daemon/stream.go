@@ -0,0 +1,77 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package daemon
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// JSONLinesContentType is the media type of lists streamed with one JSON
+	// value per line.
+	JSONLinesContentType = "application/x-ndjson"
+)
+
+// AcceptsJSONLines returns whether the client asked for lists to be streamed
+// as JSON lines.
+func AcceptsJSONLines(r *http.Request) bool {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err == nil && mediaType == JSONLinesContentType {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteList writes the slice v as JSON lines if the client accepts them, or
+// as a JSON array otherwise.
+func WriteList(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	if !AcceptsJSONLines(r) {
+		return WriteJSON(w, v)
+	}
+	return WriteJSONLines(w, v)
+}
+
+// WriteJSONLines writes every element of the slice v on its own line, flushing
+// after each so that clients can process them as they arrive.
+func WriteJSONLines(w http.ResponseWriter, v interface{}) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return errors.Errorf("cannot stream %T as JSON lines", v)
+	}
+
+	w.Header().Set("Content-Type", JSONLinesContentType)
+	f, _ := w.(http.Flusher)
+
+	enc := json.NewEncoder(w)
+	for i := 0; i < rv.Len(); i++ {
+		err := enc.Encode(rv.Index(i).Interface())
+		if err != nil {
+			return err
+		}
+
+		if f != nil {
+			f.Flush()
+		}
+	}
+	return nil
+}
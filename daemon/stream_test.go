@@ -0,0 +1,46 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package daemon
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteList(t *testing.T) {
+	type item struct {
+		ID string
+	}
+	items := []item{{ID: "a"}, {ID: "b"}}
+
+	r := httptest.NewRequest("GET", "/items/json", nil)
+	w := httptest.NewRecorder()
+	err := WriteList(w, r, &items)
+	require.NoError(t, err)
+	require.JSONEq(t, `[{"ID":"a"},{"ID":"b"}]`, w.Body.String())
+
+	r.Header.Set("Accept", "application/json, "+JSONLinesContentType+"; q=0.9")
+	w = httptest.NewRecorder()
+	err = WriteList(w, r, &items)
+	require.NoError(t, err)
+	require.Equal(t, JSONLinesContentType, w.Header().Get("Content-Type"))
+	require.Equal(t, "{\"ID\":\"a\"}\n{\"ID\":\"b\"}\n", w.Body.String())
+	require.True(t, w.Flushed)
+
+	err = WriteJSONLines(w, item{ID: "c"})
+	require.Error(t, err)
+}
@@ -0,0 +1,40 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package versionrouter
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Netflix/p2plab/daemon"
+	"github.com/Netflix/p2plab/version"
+)
+
+type router struct{}
+
+func New() daemon.Router {
+	return &router{}
+}
+
+func (s *router) Routes() []daemon.Route {
+	return []daemon.Route{
+		// GET
+		daemon.NewGetRoute("/version", s.version),
+	}
+}
+
+func (s *router) version(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	return daemon.WriteJSON(w, version.Get())
+}
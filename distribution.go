@@ -33,6 +33,19 @@ type Uploader interface {
 	Close() error
 }
 
+// ArtifactStore keeps the large files produced by benchmarks outside of the
+// metadata.
+type ArtifactStore interface {
+	// Put stores the content under the key, and returns a link to it.
+	Put(ctx context.Context, key string, r io.Reader) (link string, err error)
+
+	// Get returns the content of a link returned by Put, starting from the
+	// byte at offset.
+	Get(ctx context.Context, link string, offset int64) (io.ReadCloser, error)
+
+	Close() error
+}
+
 type Downloader interface {
 	Download(ctx context.Context, link string) (io.ReadCloser, error)
 }
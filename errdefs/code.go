@@ -0,0 +1,115 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errdefs
+
+import (
+	"context"
+)
+
+// ErrorCode is a machine-readable classification of an error, which is
+// carried over the wire so that clients can branch on the type of failure.
+type ErrorCode string
+
+const (
+	CodeUnknown            ErrorCode = "UNKNOWN"
+	CodeInvalidArgument    ErrorCode = "INVALID_ARGUMENT"
+	CodeNotFound           ErrorCode = "NOT_FOUND"
+	CodeAlreadyExists      ErrorCode = "ALREADY_EXISTS"
+	CodeFailedPrecondition ErrorCode = "FAILED_PRECONDITION"
+	CodeUnavailable        ErrorCode = "UNAVAILABLE"
+	CodeNotImplemented     ErrorCode = "NOT_IMPLEMENTED"
+	CodeUnauthorized       ErrorCode = "UNAUTHORIZED"
+	CodeRateLimited        ErrorCode = "RATE_LIMITED"
+	CodeCancelled          ErrorCode = "CANCELLED"
+	CodeInternal           ErrorCode = "INTERNAL"
+)
+
+// sentinels is a list rather than a map keyed by error, since errors of
+// uncomparable types would panic when hashed.
+var sentinels = []struct {
+	err  error
+	code ErrorCode
+}{
+	{ErrInvalidArgument, CodeInvalidArgument},
+	{ErrNotFound, CodeNotFound},
+	{ErrAlreadyExists, CodeAlreadyExists},
+	{ErrFailedPrecondition, CodeFailedPrecondition},
+	{ErrUnavailable, CodeUnavailable},
+	{ErrNotImplemented, CodeNotImplemented},
+	{ErrUnauthorized, CodeUnauthorized},
+	{ErrRateLimited, CodeRateLimited},
+	{context.Canceled, CodeCancelled},
+	{ErrInternal, CodeInternal},
+}
+
+type coder interface {
+	Code() ErrorCode
+}
+
+type causer interface {
+	Cause() error
+}
+
+type codedError struct {
+	error
+	code ErrorCode
+}
+
+func (e *codedError) Code() ErrorCode {
+	return e.code
+}
+
+func (e *codedError) Cause() error {
+	return e.error
+}
+
+// WithCode annotates err with a code, which takes precedence over the codes
+// of the errors it wraps. Returns nil if err is nil.
+func WithCode(err error, code ErrorCode) error {
+	if err == nil {
+		return nil
+	}
+	return &codedError{err, code}
+}
+
+// Code returns the code of the outermost error in the chain of causes of err
+// that is annotated with a code or is one of the sentinel errors. Returns
+// CodeUnknown if there is none.
+func Code(err error) ErrorCode {
+	for err != nil {
+		if c, ok := err.(coder); ok {
+			return c.Code()
+		}
+
+		for _, sentinel := range sentinels {
+			if err == sentinel.err {
+				return sentinel.code
+			}
+		}
+
+		cause, ok := err.(causer)
+		if !ok {
+			break
+		}
+		err = cause.Cause()
+	}
+
+	return CodeUnknown
+}
+
+// IsCode returns true if err has the code.
+func IsCode(err error, code ErrorCode) bool {
+	return Code(err) == code
+}
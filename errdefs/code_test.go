@@ -0,0 +1,46 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errdefs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCode(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		code ErrorCode
+	}{
+		{nil, CodeUnknown},
+		{errors.New("boom"), CodeUnknown},
+		{ErrNotFound, CodeNotFound},
+		{errors.Wrap(ErrAlreadyExists, "cluster"), CodeAlreadyExists},
+		{errors.Wrap(context.Canceled, "benchmark"), CodeCancelled},
+		{WithCode(errors.New("boom"), CodeInternal), CodeInternal},
+		{errors.Wrap(WithCode(ErrNotFound, CodeFailedPrecondition), "scenario"), CodeFailedPrecondition},
+	} {
+		require.Equal(t, tc.code, Code(tc.err), "%v", tc.err)
+	}
+
+	err := errors.Wrap(WithCode(errors.New("missing"), CodeNotFound), "cluster")
+	require.True(t, IsNotFound(err))
+	require.True(t, IsCode(err, CodeNotFound))
+	require.Equal(t, "cluster: missing", err.Error())
+	require.Nil(t, WithCode(nil, CodeInternal))
+}
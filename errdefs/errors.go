@@ -15,8 +15,6 @@
 package errdefs
 
 import (
-	"context"
-
 	"github.com/pkg/errors"
 )
 
@@ -31,24 +29,61 @@ var (
 	ErrInvalidArgument = errors.New("invalid argument")
 
 	ErrUnavailable = errors.New("unavailable")
+
+	// ErrNotImplemented is returned when a feature is not supported.
+	ErrNotImplemented = errors.New("not implemented")
+
+	// ErrUnauthorized is returned when a request lacks valid credentials.
+	ErrUnauthorized = errors.New("unauthorized")
+
+	// ErrFailedPrecondition is returned when a resource is not in the state
+	// required by the operation.
+	ErrFailedPrecondition = errors.New("failed precondition")
+
+	// ErrInternal is returned when an invariant is broken.
+	ErrInternal = errors.New("internal")
+
+	// ErrRateLimited is returned when a server rejects a request because the
+	// client exceeded its rate limit.
+	ErrRateLimited = errors.New("rate limited")
 )
 
 func IsAlreadyExists(err error) bool {
-	return errors.Cause(err) == ErrAlreadyExists
+	return IsCode(err, CodeAlreadyExists)
 }
 
 func IsNotFound(err error) bool {
-	return errors.Cause(err) == ErrNotFound
+	return IsCode(err, CodeNotFound)
 }
 
 func IsInvalidArgument(err error) bool {
-	return errors.Cause(err) == ErrInvalidArgument
+	return IsCode(err, CodeInvalidArgument)
 }
 
 func IsUnavailable(err error) bool {
-	return errors.Cause(err) == ErrUnavailable
+	return IsCode(err, CodeUnavailable)
+}
+
+func IsNotImplemented(err error) bool {
+	return IsCode(err, CodeNotImplemented)
+}
+
+func IsUnauthorized(err error) bool {
+	return IsCode(err, CodeUnauthorized)
+}
+
+func IsFailedPrecondition(err error) bool {
+	return IsCode(err, CodeFailedPrecondition)
+}
+
+func IsInternal(err error) bool {
+	return IsCode(err, CodeInternal)
+}
+
+func IsRateLimited(err error) bool {
+	return IsCode(err, CodeRateLimited)
 }
 
 func IsCancelled(err error) bool {
-	return errors.Cause(err) == context.Canceled
+	return IsCode(err, CodeCancelled)
 }
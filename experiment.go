@@ -23,6 +23,13 @@ import (
 type ExperimentAPI interface {
 	Create(ctx context.Context, id string, edef metadata.ExperimentDefinition) (Experiment, error)
 
+	// Sweep creates a running experiment grouping the trials of a parameter
+	// sweep.
+	Sweep(ctx context.Context, id string, trials []metadata.ExperimentTrial) (Experiment, error)
+
+	// RecordTrial records the benchmark or error of a trial of a sweep.
+	RecordTrial(ctx context.Context, id string, index int, trial metadata.ExperimentTrial) (Experiment, error)
+
 	Get(ctx context.Context, id string) (Experiment, error)
 
 	Label(ctx context.Context, ids, adds, removes []string) ([]Experiment, error)
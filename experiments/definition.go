@@ -16,14 +16,14 @@ package experiments
 
 import (
 	"encoding/json"
-	"io/ioutil"
 
 	"github.com/Netflix/p2plab/metadata"
+	"github.com/Netflix/p2plab/pkg/cliutil"
 )
 
 func Parse(filename string) (metadata.ExperimentDefinition, error) {
 	var edef metadata.ExperimentDefinition
-	content, err := ioutil.ReadFile(filename)
+	content, err := cliutil.ReadFile(filename)
 	if err != nil {
 		return edef, err
 	}
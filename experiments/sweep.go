@@ -0,0 +1,74 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package experiments
+
+import (
+	"strings"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/pkg/stringutil"
+	"github.com/pkg/errors"
+)
+
+// Variable is a template variable swept over a list of values.
+type Variable struct {
+	Name   string
+	Values []string
+}
+
+// ParseVariable parses a variable in the form "key=v1,v2,...".
+func ParseVariable(s string) (Variable, error) {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return Variable{}, errors.Wrapf(errdefs.ErrInvalidArgument, "variable must be in the form key=v1,v2, got %q", s)
+	}
+
+	values := stringutil.Coalesce(strings.Split(parts[1], ","))
+	if len(values) == 0 {
+		return Variable{}, errors.Wrapf(errdefs.ErrInvalidArgument, "variable %q has no values", parts[0])
+	}
+
+	return Variable{Name: parts[0], Values: values}, nil
+}
+
+// Combinations returns the cartesian product of the values of the variables.
+// The last variable varies the fastest.
+func Combinations(vars []Variable) ([]map[string]string, error) {
+	seen := make(map[string]struct{})
+	for _, v := range vars {
+		if _, ok := seen[v.Name]; ok {
+			return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "variable %q swept more than once", v.Name)
+		}
+		seen[v.Name] = struct{}{}
+	}
+
+	combinations := []map[string]string{{}}
+	for _, v := range vars {
+		var next []map[string]string
+		for _, combination := range combinations {
+			for _, value := range v.Values {
+				params := make(map[string]string, len(combination)+1)
+				for k, val := range combination {
+					params[k] = val
+				}
+				params[v.Name] = value
+				next = append(next, params)
+			}
+		}
+		combinations = next
+	}
+
+	return combinations, nil
+}
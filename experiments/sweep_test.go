@@ -0,0 +1,53 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package experiments
+
+import (
+	"testing"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVariable(t *testing.T) {
+	v, err := ParseVariable("size=1MB,10MB,,100MB")
+	require.NoError(t, err)
+	require.Equal(t, Variable{Name: "size", Values: []string{"1MB", "10MB", "100MB"}}, v)
+
+	for _, s := range []string{"size", "=1MB", "size="} {
+		_, err = ParseVariable(s)
+		require.True(t, errdefs.IsInvalidArgument(err), s)
+	}
+}
+
+func TestCombinations(t *testing.T) {
+	combinations, err := Combinations([]Variable{
+		{Name: "size", Values: []string{"1MB", "10MB"}},
+		{Name: "peers", Values: []string{"5", "10"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []map[string]string{
+		{"size": "1MB", "peers": "5"},
+		{"size": "1MB", "peers": "10"},
+		{"size": "10MB", "peers": "5"},
+		{"size": "10MB", "peers": "10"},
+	}, combinations)
+
+	_, err = Combinations([]Variable{
+		{Name: "size", Values: []string{"1MB"}},
+		{Name: "size", Values: []string{"10MB"}},
+	})
+	require.True(t, errdefs.IsInvalidArgument(err))
+}
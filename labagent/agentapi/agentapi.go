@@ -19,15 +19,26 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"strconv"
 	"time"
 
 	"github.com/Netflix/p2plab"
 	"github.com/Netflix/p2plab/metadata"
 	"github.com/Netflix/p2plab/pkg/httputil"
 	"github.com/Netflix/p2plab/pkg/logutil"
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 )
 
+// ExitCodeTrailer is the HTTP trailer carrying the exit code of an executed
+// command.
+const ExitCodeTrailer = "Exit-Code"
+
+// LogOffsetHeader is the HTTP header carrying the sequence number of the first
+// line of a log stream.
+const LogOffsetHeader = "Log-Offset"
+
 type api struct {
 	addr   string
 	client *httputil.Client
@@ -44,22 +55,52 @@ func (a *api) url(endpoint string, v ...interface{}) string {
 	return fmt.Sprintf("%s%s", a.addr, fmt.Sprintf(endpoint, v...))
 }
 
+// Healthcheck waits for the agent to be reachable and returns whether it is
+// ready.
 func (a *api) Healthcheck(ctx context.Context) bool {
-	req := a.client.NewRequest("GET", a.url("/healthcheck"),
+	health, err := a.health(ctx,
 		httputil.WithRetryWaitMax(5*time.Minute),
 		httputil.WithRetryMax(10),
 	)
-	resp, err := req.Send(ctx)
 	if err != nil {
 		zerolog.Ctx(ctx).Debug().Str("err", err.Error()).Str("addr", a.addr).Msg("unhealthy")
 		return false
 	}
+
+	return health.Ready
+}
+
+func (a *api) Health(ctx context.Context) (metadata.Health, error) {
+	return a.health(ctx, httputil.WithRetryMax(0))
+}
+
+func (a *api) health(ctx context.Context, opts ...httputil.RequestOption) (metadata.Health, error) {
+	var health metadata.Health
+
+	req := a.client.NewRequest("GET", a.url("/healthz"), opts...)
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return health, err
+	}
 	defer resp.Body.Close()
 
-	return true
+	err = json.NewDecoder(resp.Body).Decode(&health)
+	if err != nil {
+		return health, errors.Wrapf(err, "invalid health from %s", a.addr)
+	}
+
+	return health, nil
 }
 
-func (a *api) Update(ctx context.Context, id, link string, pdef metadata.PeerDefinition) error {
+func (a *api) Update(ctx context.Context, id, link string, pdef metadata.PeerDefinition, opts ...p2plab.UpdateOption) error {
+	var settings p2plab.UpdateSettings
+	for _, opt := range opts {
+		err := opt(&settings)
+		if err != nil {
+			return err
+		}
+	}
+
 	content, err := json.MarshalIndent(&pdef, "", "    ")
 	if err != nil {
 		return err
@@ -70,6 +111,10 @@ func (a *api) Update(ctx context.Context, id, link string, pdef metadata.PeerDef
 		Option("id", id).
 		Option("link", link)
 
+	if settings.SHA256 != "" {
+		req.Option("sha256", settings.SHA256)
+	}
+
 	resp, err := req.Send(ctx)
 	if err != nil {
 		return err
@@ -87,6 +132,108 @@ func (a *api) Update(ctx context.Context, id, link string, pdef metadata.PeerDef
 	return nil
 }
 
+func (a *api) ShapeNetwork(ctx context.Context, spec metadata.NetworkSpec) error {
+	content, err := json.MarshalIndent(&spec, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	req := a.client.NewRequest("PUT", a.url("/network")).
+		Body(bytes.NewReader(content))
+
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return nil
+}
+
+func (a *api) Exec(ctx context.Context, args []string, w io.Writer) (int, error) {
+	content, err := json.MarshalIndent(&args, "", "    ")
+	if err != nil {
+		return 0, err
+	}
+
+	req := a.client.NewRequest("PUT", a.url("/exec"), httputil.WithRetryMax(0)).
+		Body(bytes.NewReader(content))
+
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	_, err = io.Copy(w, resp.Body)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to read command output")
+	}
+
+	exitCode, err := strconv.Atoi(resp.Trailer.Get(ExitCodeTrailer))
+	if err != nil {
+		return 0, errors.Errorf("command did not complete on %s", a.addr)
+	}
+
+	return exitCode, nil
+}
+
+func (a *api) Logs(ctx context.Context, opts ...p2plab.LogsOption) (uint64, io.ReadCloser, error) {
+	var settings p2plab.LogsSettings
+	for _, opt := range opts {
+		err := opt(&settings)
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+
+	req := a.client.NewRequest("GET", a.url("/logs"), httputil.WithRetryMax(0)).
+		Option("since", strconv.FormatUint(settings.Since, 10))
+
+	if settings.Follow {
+		req.Option("follow", "true")
+	}
+
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	offset, err := strconv.ParseUint(resp.Header.Get(LogOffsetHeader), 10, 64)
+	if err != nil {
+		resp.Body.Close()
+		return 0, nil, errors.Wrapf(err, "invalid log offset from %s", a.addr)
+	}
+
+	return offset, resp.Body, nil
+}
+
+func (a *api) SampleResources(ctx context.Context, interval time.Duration) (metadata.ResourceUsage, error) {
+	usage := metadata.ResourceUsage{Interval: interval}
+
+	req := a.client.NewRequest("GET", a.url("/resources"), httputil.WithRetryMax(0)).
+		Option("interval", interval.String())
+
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return usage, err
+	}
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var sample metadata.ResourceSample
+		err = dec.Decode(&sample)
+		if err == io.EOF || ctx.Err() != nil {
+			return usage, nil
+		}
+		if err != nil {
+			return usage, errors.Wrapf(err, "failed to read resource samples from %s", a.addr)
+		}
+		usage.Add(sample)
+	}
+}
+
 func (a *api) SSH(ctx context.Context, opts ...p2plab.SSHOption) error {
 	return nil
 }
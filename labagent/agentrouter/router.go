@@ -18,34 +18,50 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"os/exec"
+	"strconv"
+	"syscall"
 	"time"
 
 	"github.com/Netflix/p2plab/daemon"
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/labagent/agentapi"
+	"github.com/Netflix/p2plab/labagent/sampler"
+	"github.com/Netflix/p2plab/labagent/shaper"
 	"github.com/Netflix/p2plab/labagent/supervisor"
 	"github.com/Netflix/p2plab/metadata"
 	"github.com/Netflix/p2plab/pkg/logutil"
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 )
 
 type router struct {
 	addr       string
 	supervisor supervisor.Supervisor
+	shaper     shaper.Shaper
+	logs       *logutil.Broadcaster
 }
 
-func New(addr string, s supervisor.Supervisor) daemon.Router {
-	return &router{addr, s}
+func New(addr string, s supervisor.Supervisor, ns shaper.Shaper, logs *logutil.Broadcaster) daemon.Router {
+	return &router{addr, s, ns, logs}
 }
 
 func (s *router) Routes() []daemon.Route {
 	return []daemon.Route{
+		// GET
+		daemon.NewGetRoute("/logs", s.getLogs),
+		daemon.NewGetRoute("/resources", s.getResources),
 		// PUT
 		daemon.NewPutRoute("/update", s.putUpdate),
+		daemon.NewPutRoute("/network", s.putNetwork),
+		daemon.NewPutRoute("/exec", s.putExec),
 	}
 }
 
 func (s *router) putUpdate(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	id := r.FormValue("id")
 	link := r.FormValue("link")
+	digest := r.FormValue("sha256")
 	ctx, logger := logutil.WithResponseLogger(ctx, w)
 	logger.UpdateContext(func(c zerolog.Context) zerolog.Context {
 		return c.Str("id", id).Str("link", link)
@@ -57,7 +73,7 @@ func (s *router) putUpdate(ctx context.Context, w http.ResponseWriter, r *http.R
 		return err
 	}
 
-	err = s.supervisor.Supervise(ctx, id, link, pdef)
+	err = s.supervisor.Supervise(ctx, id, link, digest, pdef)
 	if err != nil {
 		return err
 	}
@@ -67,3 +83,133 @@ func (s *router) putUpdate(ctx context.Context, w http.ResponseWriter, r *http.R
 
 	return nil
 }
+
+func (s *router) putNetwork(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	var spec metadata.NetworkSpec
+	err := json.NewDecoder(r.Body).Decode(&spec)
+	if err != nil {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid network spec: %s", err)
+	}
+
+	return s.shaper.Shape(ctx, spec)
+}
+
+func (s *router) putExec(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	var args []string
+	err := json.NewDecoder(r.Body).Decode(&args)
+	if err != nil {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid exec arguments: %s", err)
+	}
+	if len(args) == 0 {
+		return errors.Wrap(errdefs.ErrInvalidArgument, "exec requires a command")
+	}
+
+	zerolog.Ctx(ctx).Info().Strs("args", args).Msg("Executing command")
+
+	// Output is streamed as it is written, so the exit code can only be sent
+	// after the body as a trailer.
+	w.Header().Set("Trailer", agentapi.ExitCodeTrailer)
+
+	out := logutil.NewWriteFlusher(w)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	err = cmd.Start()
+	if err != nil {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "failed to start %q: %s", args[0], err)
+	}
+
+	exitCode := 0
+	err = cmd.Wait()
+	if err != nil {
+		exitErr, ok := err.(*exec.ExitError)
+		if !ok {
+			return err
+		}
+		exitCode = exitErr.ExitCode()
+
+		// Commands killed by a signal have no exit code, so report it like a
+		// shell would.
+		status, ok := exitErr.Sys().(syscall.WaitStatus)
+		if ok && status.Signaled() {
+			exitCode = 128 + int(status.Signal())
+		}
+	}
+
+	w.Header().Set(agentapi.ExitCodeTrailer, strconv.Itoa(exitCode))
+	return nil
+}
+
+func (s *router) getLogs(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	var since uint64
+	if r.FormValue("since") != "" {
+		var err error
+		since, err = strconv.ParseUint(r.FormValue("since"), 10, 64)
+		if err != nil {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid since %q", r.FormValue("since"))
+		}
+	}
+
+	follow := false
+	if r.FormValue("follow") != "" {
+		var err error
+		follow, err = strconv.ParseBool(r.FormValue("follow"))
+		if err != nil {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid follow %q", r.FormValue("follow"))
+		}
+	}
+
+	offset, backlog, lines, cancel := s.logs.Subscribe(since)
+	defer cancel()
+
+	w.Header().Set(agentapi.LogOffsetHeader, strconv.FormatUint(offset, 10))
+	w.WriteHeader(http.StatusOK)
+
+	out := logutil.NewWriteFlusher(w)
+	for _, line := range backlog {
+		_, err := out.Write(append(line, '\n'))
+		if err != nil {
+			return nil
+		}
+	}
+
+	if !follow {
+		return nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case line, ok := <-lines:
+			if !ok {
+				// The client fell behind, it is expected to reconnect from the
+				// next sequence number.
+				return nil
+			}
+
+			_, err := out.Write(append(line, '\n'))
+			if err != nil {
+				return nil
+			}
+		}
+	}
+}
+
+func (s *router) getResources(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	interval, err := time.ParseDuration(r.FormValue("interval"))
+	if err != nil || interval <= 0 {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid interval %q", r.FormValue("interval"))
+	}
+
+	pid := s.supervisor.Pid()
+	if pid == 0 {
+		return errors.Wrap(errdefs.ErrUnavailable, "p2p app is not running")
+	}
+
+	out := logutil.NewWriteFlusher(w)
+	enc := json.NewEncoder(out)
+	return sampler.Sample(ctx, pid, interval, func(sample metadata.ResourceSample) error {
+		return enc.Encode(&sample)
+	})
+}
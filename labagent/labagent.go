@@ -16,18 +16,27 @@ package labagent
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"path/filepath"
 
 	"github.com/Netflix/p2plab/daemon"
 	"github.com/Netflix/p2plab/daemon/healthcheckrouter"
+	"github.com/Netflix/p2plab/daemon/versionrouter"
 	"github.com/Netflix/p2plab/downloaders"
 	"github.com/Netflix/p2plab/labagent/agentrouter"
+	"github.com/Netflix/p2plab/labagent/shaper"
 	"github.com/Netflix/p2plab/labagent/supervisor"
+	"github.com/Netflix/p2plab/metadata"
 	"github.com/Netflix/p2plab/pkg/httputil"
+	"github.com/Netflix/p2plab/pkg/logutil"
 	"github.com/rs/zerolog"
 )
 
+// logBacklog is the number of lines of p2p app output retained for clients
+// tailing the agent's logs.
+const logBacklog = 1000
+
 type LabAgent struct {
 	daemon  *daemon.Daemon
 	closers []io.Closer
@@ -50,20 +59,22 @@ func New(root, addr, appRoot, appAddr string, logger *zerolog.Logger, opts ...La
 	settings.DownloaderSettings.Client = client
 	fs := downloaders.New(filepath.Join(root, "downloaders"), settings.DownloaderSettings)
 
-	s, err := supervisor.New(filepath.Join(root, "supervisor"), appRoot, appAddr, client, fs)
+	logs := logutil.NewBroadcaster(logBacklog)
+	s, err := supervisor.New(filepath.Join(root, "supervisor"), appRoot, appAddr, client, fs, logs)
 	if err != nil {
 		return nil, err
 	}
 
 	var closers []io.Closer
 	daemon, err := daemon.New("labagent", addr, logger,
-		healthcheckrouter.New(),
-		agentrouter.New(appAddr, s),
+		healthcheckrouter.New(appHealth(s)),
+		versionrouter.New(),
+		agentrouter.New(appAddr, s, shaper.New(settings.NetworkInterface), logs),
 	)
 	if err != nil {
 		return nil, err
 	}
-	closers = append(closers, daemon)
+	closers = append(closers, daemon, s)
 
 	return &LabAgent{
 		daemon:  daemon,
@@ -71,6 +82,27 @@ func New(root, addr, appRoot, appAddr string, logger *zerolog.Logger, opts ...La
 	}, nil
 }
 
+// appHealth reports whether the p2p app is running. The agent is still ready
+// when it isn't, since the app is only started by updating the agent.
+func appHealth(s supervisor.Supervisor) healthcheckrouter.HealthCheck {
+	return func(ctx context.Context) metadata.HealthComponent {
+		pid := s.Pid()
+		if pid == 0 {
+			return metadata.HealthComponent{
+				Name:    "app",
+				Status:  metadata.HealthDegraded,
+				Message: "p2p app is not running",
+			}
+		}
+
+		return metadata.HealthComponent{
+			Name:    "app",
+			Status:  metadata.HealthOK,
+			Message: fmt.Sprintf("p2p app is running with pid %d", pid),
+		}
+	}
+}
+
 func (a *LabAgent) Close() error {
 	for _, closer := range a.closers {
 		err := closer.Close()
@@ -0,0 +1,68 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sampler
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// clockTicks is the number of clock ticks per second used by procfs, which
+// is fixed to 100 on the architectures we run on.
+const clockTicks = 100
+
+func readProcStat(pid int) (procStat, error) {
+	var stat procStat
+
+	content, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return stat, errors.Wrapf(err, "failed to read stat of process %d", pid)
+	}
+
+	// The command name may contain spaces, so fields are parsed after its
+	// closing parenthesis. The first field after it is the process state.
+	i := strings.LastIndexByte(string(content), ')')
+	if i < 0 {
+		return stat, errors.Errorf("invalid stat of process %d", pid)
+	}
+
+	fields := strings.Fields(string(content[i+1:]))
+	if len(fields) < 22 {
+		return stat, errors.Errorf("invalid stat of process %d", pid)
+	}
+
+	var ticks [2]uint64
+	for j, field := range []string{fields[11], fields[12]} {
+		ticks[j], err = strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return stat, errors.Wrapf(err, "invalid cpu time of process %d", pid)
+		}
+	}
+	stat.cpu = time.Duration(ticks[0]+ticks[1]) * time.Second / clockTicks
+
+	pages, err := strconv.ParseUint(fields[21], 10, 64)
+	if err != nil {
+		return stat, errors.Wrapf(err, "invalid rss of process %d", pid)
+	}
+	stat.rss = pages * uint64(os.Getpagesize())
+
+	return stat, nil
+}
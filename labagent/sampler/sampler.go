@@ -0,0 +1,69 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sampler
+
+import (
+	"context"
+	"time"
+
+	"github.com/Netflix/p2plab/metadata"
+)
+
+type procStat struct {
+	// cpu is the total user and system CPU time of the process.
+	cpu time.Duration
+
+	// rss is the resident set size of the process in bytes.
+	rss uint64
+}
+
+// Sample samples the CPU and memory usage of the process pid every interval,
+// calling fn with each sample until the context is done or fn returns an
+// error. Returns errdefs.ErrNotImplemented if the platform doesn't support
+// sampling.
+func Sample(ctx context.Context, pid int, interval time.Duration, fn func(metadata.ResourceSample) error) error {
+	prev, err := readProcStat(pid)
+	if err != nil {
+		return err
+	}
+	last := time.Now()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			stat, err := readProcStat(pid)
+			if err != nil {
+				return err
+			}
+
+			sample := metadata.ResourceSample{RSS: stat.rss}
+			elapsed := now.Sub(last)
+			if elapsed > 0 {
+				sample.CPU = 100 * float64(stat.cpu-prev.cpu) / float64(elapsed)
+			}
+			prev, last = stat, now
+
+			err = fn(sample)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
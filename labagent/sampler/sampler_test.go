@@ -0,0 +1,44 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sampler
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSample(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	defer cancel()
+
+	var usage metadata.ResourceUsage
+	err := Sample(ctx, os.Getpid(), 50*time.Millisecond, func(sample metadata.ResourceSample) error {
+		usage.Add(sample)
+		return nil
+	})
+	if errdefs.IsNotImplemented(err) {
+		t.Skip(err)
+	}
+	require.NoError(t, err)
+	require.NotEmpty(t, usage.RSS)
+	require.True(t, usage.RSSSummary().Min > 0)
+	require.True(t, usage.CPUSummary().Min >= 0)
+}
@@ -0,0 +1,106 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shaper
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/Netflix/p2plab/pkg/logutil"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// Shaper applies network impairments to the node.
+type Shaper interface {
+	// Shape replaces the impairments of the node with the spec. The zero spec
+	// resets the network to its baseline.
+	Shape(ctx context.Context, spec metadata.NetworkSpec) error
+}
+
+type shaper struct {
+	iface string
+}
+
+// New returns a shaper that impairs the network interface using tc netem. If
+// the interface is empty, or tc is not available, shaping the network returns
+// errdefs.ErrNotImplemented.
+func New(iface string) Shaper {
+	return &shaper{iface}
+}
+
+func (s *shaper) Shape(ctx context.Context, spec metadata.NetworkSpec) error {
+	if s.iface == "" {
+		return errors.Wrap(errdefs.ErrNotImplemented, "network shaping is disabled on this agent")
+	}
+
+	_, err := exec.LookPath("tc")
+	if err != nil {
+		return errors.Wrap(errdefs.ErrNotImplemented, "network shaping requires tc")
+	}
+
+	err = spec.Validate()
+	if err != nil {
+		return err
+	}
+
+	// Deleting a root qdisc that doesn't exist fails, so the error is ignored to
+	// make resets idempotent.
+	if spec.IsZero() {
+		zerolog.Ctx(ctx).Info().Str("iface", s.iface).Msg("Resetting network")
+		s.tc(ctx, "qdisc", "del", "dev", s.iface, "root")
+		return nil
+	}
+
+	zerolog.Ctx(ctx).Info().Str("iface", s.iface).Interface("spec", spec).Msg("Shaping network")
+	err = s.tc(ctx, append([]string{"qdisc", "replace", "dev", s.iface, "root"}, NetemArgs(spec)...)...)
+	if err != nil {
+		return errors.Wrapf(err, "failed to shape network of %q", s.iface)
+	}
+	return nil
+}
+
+// NetemArgs returns the tc netem arguments to apply the spec.
+func NetemArgs(spec metadata.NetworkSpec) []string {
+	args := []string{"netem"}
+	if spec.Latency != "" {
+		args = append(args, "delay", spec.Latency)
+		if spec.Jitter != "" {
+			args = append(args, spec.Jitter)
+		}
+	}
+	if spec.Loss > 0 {
+		args = append(args, "loss", fmt.Sprintf("%s%%", strconv.FormatFloat(spec.Loss, 'f', -1, 64)))
+	}
+	if spec.Bandwidth != "" {
+		args = append(args, "rate", spec.Bandwidth)
+	}
+	return args
+}
+
+func (s *shaper) tc(ctx context.Context, args ...string) error {
+	logger := zerolog.Ctx(ctx).With().Strs("exec", args).Logger()
+	logWriter := logutil.NewWriter(&logger, zerolog.DebugLevel)
+	defer logWriter.Close()
+
+	cmd := exec.CommandContext(ctx, "tc", args...)
+	cmd.Stdout = logWriter
+	cmd.Stderr = logWriter
+	return cmd.Run()
+}
@@ -0,0 +1,35 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shaper
+
+import (
+	"testing"
+
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetemArgs(t *testing.T) {
+	require.Equal(t, []string{"netem"}, NetemArgs(metadata.NetworkSpec{}))
+	require.Equal(t,
+		[]string{"netem", "delay", "100ms", "10ms", "loss", "0.5%", "rate", "10mbit"},
+		NetemArgs(metadata.NetworkSpec{
+			Latency:   "100ms",
+			Jitter:    "10ms",
+			Loss:      0.5,
+			Bandwidth: "10mbit",
+		}),
+	)
+}
@@ -17,7 +17,9 @@ package supervisor
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -26,9 +28,11 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"syscall"
 
 	"github.com/Netflix/p2plab/downloaders"
+	"github.com/Netflix/p2plab/errdefs"
 	"github.com/Netflix/p2plab/metadata"
 	"github.com/Netflix/p2plab/pkg/httputil"
 	"github.com/Netflix/p2plab/pkg/traceutil"
@@ -38,7 +42,16 @@ import (
 )
 
 type Supervisor interface {
-	Supervise(ctx context.Context, id, link string, pdef metadata.PeerDefinition) error
+	// Supervise restarts the p2p app with the given peer definition. If link is
+	// not empty, the binary is replaced first. If digest is not empty, the
+	// downloaded binary must match the hex-encoded SHA-256 digest.
+	Supervise(ctx context.Context, id, link, digest string, pdef metadata.PeerDefinition) error
+
+	// Pid returns the process ID of the p2p app, or zero if it isn't running.
+	Pid() int
+
+	// Close kills the p2p app if it is running.
+	Close() error
 }
 
 type supervisor struct {
@@ -47,11 +60,14 @@ type supervisor struct {
 	appPort string
 	client  *httputil.Client
 	fs      *downloaders.Downloaders
+	logs    io.Writer
 	app     *exec.Cmd
 	cancel  func()
 }
 
-func New(root, appRoot, appAddr string, client *httputil.Client, fs *downloaders.Downloaders) (Supervisor, error) {
+// New returns a supervisor for the p2p app. The app's output is copied to
+// logs in addition to the supervisor's own stdio.
+func New(root, appRoot, appAddr string, client *httputil.Client, fs *downloaders.Downloaders, logs io.Writer) (Supervisor, error) {
 	err := os.MkdirAll(root, 0711)
 	if err != nil {
 		return nil, err
@@ -73,18 +89,21 @@ func New(root, appRoot, appAddr string, client *httputil.Client, fs *downloaders
 		appPort: appPort,
 		client:  client,
 		fs:      fs,
+		logs:    logs,
 	}, nil
 }
 
-func (s *supervisor) Supervise(ctx context.Context, id, link string, pdef metadata.PeerDefinition) error {
-	err := s.kill(ctx)
-	if err != nil {
-		return err
-	}
-
+func (s *supervisor) Supervise(ctx context.Context, id, link, digest string, pdef metadata.PeerDefinition) error {
 	flags := s.peerDefinitionToFlags(id, pdef)
 	if link != "" {
-		err = s.atomicReplaceBinary(ctx, link)
+		// Replace the binary before killing the running app, so a failed
+		// download or checksum mismatch leaves it untouched.
+		err := s.atomicReplaceBinary(ctx, link, digest)
+		if err != nil {
+			return err
+		}
+
+		err = s.kill(ctx)
 		if err != nil {
 			return err
 		}
@@ -96,11 +115,28 @@ func (s *supervisor) Supervise(ctx context.Context, id, link string, pdef metada
 
 		return s.start(ctx, flags)
 	} else {
+		err := s.kill(ctx)
+		if err != nil {
+			return err
+		}
+
 		return s.wait(ctx, flags)
 	}
 
 }
 
+func (s *supervisor) Pid() int {
+	app := s.app
+	if app == nil || app.Process == nil {
+		return 0
+	}
+	return app.Process.Pid
+}
+
+func (s *supervisor) Close() error {
+	return s.kill(context.Background())
+}
+
 func (s *supervisor) peerDefinitionToFlags(id string, pdef metadata.PeerDefinition) []string {
 	flags := []string{
 		fmt.Sprintf("--node-id=%s", id),
@@ -120,6 +156,12 @@ func (s *supervisor) peerDefinitionToFlags(id string, pdef metadata.PeerDefiniti
 	if pdef.Routing != "" {
 		flags = append(flags, fmt.Sprintf("--libp2p-routing=%s", pdef.Routing))
 	}
+	if pdef.RoutingEndpoint != "" {
+		flags = append(flags, fmt.Sprintf("--libp2p-routing-endpoint=%s", pdef.RoutingEndpoint))
+	}
+	if pdef.Datastore != "" {
+		flags = append(flags, fmt.Sprintf("--datastore=%s", pdef.Datastore))
+	}
 
 	return flags
 }
@@ -215,7 +257,7 @@ func (s *supervisor) clear(ctx context.Context) error {
 	return nil
 }
 
-func (s *supervisor) atomicReplaceBinary(ctx context.Context, link string) error {
+func (s *supervisor) atomicReplaceBinary(ctx context.Context, link, digest string) error {
 	span, ctx := traceutil.StartSpanFromContext(ctx, "supervisor.atomicReplaceBinary")
 	defer span.Finish()
 	span.SetTag("link", link)
@@ -244,11 +286,20 @@ func (s *supervisor) atomicReplaceBinary(ctx context.Context, link string) error
 	defer os.Remove(f.Name())
 	defer f.Close()
 
-	_, err = io.Copy(f, rc)
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(f, h), rc)
 	if err != nil {
 		return err
 	}
 
+	if digest != "" {
+		actual := hex.EncodeToString(h.Sum(nil))
+		if !strings.EqualFold(actual, digest) {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "sha256 mismatch for %q: expected %s, got %s", link, digest, actual)
+		}
+		zerolog.Ctx(ctx).Debug().Str("sha256", actual).Msg("Verified binary checksum")
+	}
+
 	err = os.Chmod(f.Name(), 0775)
 	if err != nil {
 		return err
@@ -265,7 +316,7 @@ func (s *supervisor) atomicReplaceBinary(ctx context.Context, link string) error
 }
 
 func (s *supervisor) cmd(ctx context.Context, args ...string) *exec.Cmd {
-	return s.cmdWithStdio(ctx, os.Stdout, os.Stderr, args...)
+	return s.cmdWithStdio(ctx, io.MultiWriter(os.Stdout, s.logs), io.MultiWriter(os.Stderr, s.logs), args...)
 }
 
 func (s *supervisor) cmdWithStdio(ctx context.Context, stdout, stderr io.Writer, args ...string) *exec.Cmd {
@@ -273,5 +324,6 @@ func (s *supervisor) cmdWithStdio(ctx context.Context, stdout, stderr io.Writer,
 	app := exec.CommandContext(ctx, binaryPath, args...)
 	app.Stdout = stdout
 	app.Stderr = stderr
+	setSysProcAttr(app)
 	return app
 }
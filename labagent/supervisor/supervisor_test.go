@@ -0,0 +1,78 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package supervisor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Netflix/p2plab/downloaders"
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSupervisor(t *testing.T) (*supervisor, string) {
+	root, err := ioutil.TempDir("", "p2plab-supervisor")
+	require.NoError(t, err)
+
+	s, err := New(filepath.Join(root, "agent"), filepath.Join(root, "app"), "http://localhost:7003", nil, downloaders.New(root, downloaders.DownloaderSettings{}), ioutil.Discard)
+	require.NoError(t, err)
+
+	return s.(*supervisor), root
+}
+
+func TestSuperviseChecksumMismatch(t *testing.T) {
+	s, root := newTestSupervisor(t)
+	defer os.RemoveAll(root)
+
+	expected := sha256.Sum256([]byte("labapp"))
+
+	payload := filepath.Join(root, "labapp")
+	err := ioutil.WriteFile(payload, []byte("tampered"), 0644)
+	require.NoError(t, err)
+
+	err = s.Supervise(context.Background(), "test", "file://"+payload, hex.EncodeToString(expected[:]), metadata.DefaultPeerDefinition)
+	require.Error(t, err)
+	require.True(t, errdefs.IsInvalidArgument(err))
+	require.Contains(t, err.Error(), "sha256 mismatch")
+
+	_, err = os.Stat(filepath.Join(s.root, "labapp"))
+	require.True(t, os.IsNotExist(err), "binary must not be replaced on mismatch")
+}
+
+func TestAtomicReplaceBinaryChecksum(t *testing.T) {
+	s, root := newTestSupervisor(t)
+	defer os.RemoveAll(root)
+
+	content := []byte("labapp")
+	digest := sha256.Sum256(content)
+
+	payload := filepath.Join(root, "labapp")
+	err := ioutil.WriteFile(payload, content, 0644)
+	require.NoError(t, err)
+
+	err = s.atomicReplaceBinary(context.Background(), "file://"+payload, hex.EncodeToString(digest[:]))
+	require.NoError(t, err)
+
+	actual, err := ioutil.ReadFile(filepath.Join(s.root, "labapp"))
+	require.NoError(t, err)
+	require.Equal(t, content, actual)
+}
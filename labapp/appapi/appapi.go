@@ -19,6 +19,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/Netflix/p2plab"
 	"github.com/Netflix/p2plab/metadata"
@@ -79,6 +81,89 @@ func (a *api) Report(ctx context.Context) (metadata.ReportNode, error) {
 	return report, nil
 }
 
+func (a *api) Stats(ctx context.Context) (metadata.PeerStats, error) {
+	var stats metadata.PeerStats
+
+	req := a.client.NewRequest("GET", a.url("/stats"))
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return stats, err
+	}
+	defer resp.Body.Close()
+
+	err = json.NewDecoder(resp.Body).Decode(&stats)
+	if err != nil {
+		return stats, err
+	}
+
+	return stats, nil
+}
+
+func (a *api) Connect(ctx context.Context, peerInfos []peerstore.PeerInfo) error {
+	return a.Run(ctx, metadata.Task{
+		Type:    metadata.TaskConnect,
+		Subject: strings.Join(p2pAddrs(peerInfos), ","),
+	})
+}
+
+func (a *api) Disconnect(ctx context.Context, peerInfos []peerstore.PeerInfo) error {
+	return a.Run(ctx, metadata.Task{
+		Type:    metadata.TaskDisconnect,
+		Subject: strings.Join(p2pAddrs(peerInfos), ","),
+	})
+}
+
+func (a *api) Ping(ctx context.Context, peerInfo peerstore.PeerInfo, count int) ([]time.Duration, error) {
+	req := a.client.NewRequest("POST", a.url("/ping")).
+		Option("addrs", strings.Join(p2pAddrs([]peerstore.PeerInfo{peerInfo}), ",")).
+		Option("count", count)
+
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var rtts []time.Duration
+	err = json.NewDecoder(resp.Body).Decode(&rtts)
+	if err != nil {
+		return nil, err
+	}
+
+	return rtts, nil
+}
+
+func (a *api) BenchLink(ctx context.Context, peerInfo peerstore.PeerInfo, size int64) (time.Duration, error) {
+	req := a.client.NewRequest("POST", a.url("/benchLink")).
+		Option("addrs", strings.Join(p2pAddrs([]peerstore.PeerInfo{peerInfo}), ",")).
+		Option("size", size)
+
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	var elapsed time.Duration
+	err = json.NewDecoder(resp.Body).Decode(&elapsed)
+	if err != nil {
+		return 0, err
+	}
+
+	return elapsed, nil
+}
+
+// p2pAddrs returns the addresses of the peers suffixed with their peer IDs.
+func p2pAddrs(peerInfos []peerstore.PeerInfo) []string {
+	var addrs []string
+	for _, pi := range peerInfos {
+		for _, ma := range pi.Addrs {
+			addrs = append(addrs, fmt.Sprintf("%s/p2p/%s", ma, pi.ID))
+		}
+	}
+	return addrs
+}
+
 func (a *api) Run(ctx context.Context, task metadata.Task) error {
 	content, err := json.MarshalIndent(&task, "", "    ")
 	if err != nil {
@@ -104,3 +189,46 @@ func (a *api) Run(ctx context.Context, task metadata.Task) error {
 
 	return nil
 }
+
+func (a *api) RunAsync(ctx context.Context, task metadata.Task) (metadata.TaskStatus, error) {
+	var status metadata.TaskStatus
+	content, err := json.MarshalIndent(&task, "", "    ")
+	if err != nil {
+		return status, err
+	}
+
+	req := a.client.NewRequest("POST", a.url("/run")).
+		Option("async", true).
+		Body(bytes.NewReader(content))
+
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return status, err
+	}
+	defer resp.Body.Close()
+
+	err = json.NewDecoder(resp.Body).Decode(&status)
+	if err != nil {
+		return status, err
+	}
+
+	return status, nil
+}
+
+func (a *api) GetTask(ctx context.Context, id string) (metadata.TaskStatus, error) {
+	var status metadata.TaskStatus
+
+	req := a.client.NewRequest("GET", a.url("/tasks/%s", id))
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return status, err
+	}
+	defer resp.Body.Close()
+
+	err = json.NewDecoder(resp.Body).Decode(&status)
+	if err != nil {
+		return status, err
+	}
+
+	return status, nil
+}
@@ -18,6 +18,7 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/Netflix/p2plab/daemon"
@@ -35,11 +36,20 @@ import (
 )
 
 type router struct {
-	peer *peer.Peer
+	peer  *peer.Peer
+	tasks *taskQueue
 }
 
 func New(p *peer.Peer) daemon.Router {
-	return &router{p}
+	s := &router{peer: p}
+	s.tasks = newTaskQueue(s.runTask)
+	return s
+}
+
+// Shutdown stops executing the tasks submitted asynchronously.
+func (s *router) Shutdown(ctx context.Context) error {
+	s.tasks.stop()
+	return nil
 }
 
 func (s *router) Routes() []daemon.Route {
@@ -47,8 +57,12 @@ func (s *router) Routes() []daemon.Route {
 		// GET
 		daemon.NewGetRoute("/peerInfo", s.getPeerInfo),
 		daemon.NewGetRoute("/report", s.getReport),
+		daemon.NewGetRoute("/stats", s.getStats),
+		daemon.NewGetRoute("/tasks/{id}", s.getTask),
 		// POST
 		daemon.NewPostRoute("/run", s.postRunTask),
+		daemon.NewPostRoute("/ping", s.postPing),
+		daemon.NewPostRoute("/benchLink", s.postBenchLink),
 	}
 }
 
@@ -60,6 +74,15 @@ func (s *router) getPeerInfo(ctx context.Context, w http.ResponseWriter, r *http
 	return daemon.WriteJSON(w, &peerInfo)
 }
 
+func (s *router) getStats(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	stats, err := s.peer.Stats(ctx)
+	if err != nil {
+		return err
+	}
+
+	return daemon.WriteJSON(w, &stats)
+}
+
 func (s *router) getReport(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	report, err := s.peer.Report(ctx)
 	if err != nil {
@@ -75,11 +98,42 @@ func (s *router) postRunTask(ctx context.Context, w http.ResponseWriter, r *http
 		return err
 	}
 
+	async := false
+	if r.FormValue("async") != "" {
+		async, err = strconv.ParseBool(r.FormValue("async"))
+		if err != nil {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid async %q", r.FormValue("async"))
+		}
+	}
+
+	if async {
+		logger := zerolog.Ctx(ctx).With().Str("task", string(task.Type)).Str("subject", task.Subject).Logger()
+		status, err := s.tasks.submit(logger.WithContext(ctx), task)
+		if err != nil {
+			return err
+		}
+		return daemon.WriteJSON(w, &status)
+	}
+
 	ctx, logger := logutil.WithResponseLogger(ctx, w)
 	logger.UpdateContext(func(c zerolog.Context) zerolog.Context {
 		return c.Str("task", string(task.Type)).Str("subject", task.Subject)
 	})
 
+	return s.runTask(ctx, task)
+}
+
+func (s *router) getTask(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	status, err := s.tasks.get(vars["id"])
+	if err != nil {
+		return err
+	}
+	return daemon.WriteJSON(w, &status)
+}
+
+// runTask executes the task on the peer.
+func (s *router) runTask(ctx context.Context, task metadata.Task) error {
+	var err error
 	switch task.Type {
 	case metadata.TaskGet:
 		err = s.getFile(ctx, task.Subject)
@@ -92,6 +146,16 @@ func (s *router) postRunTask(ctx context.Context, w http.ResponseWriter, r *http
 	case metadata.TaskDisconnect:
 		addrs := strings.Split(task.Subject, ",")
 		err = s.disconnect(ctx, addrs)
+	case metadata.TaskConnectivity:
+		err = s.connectivity(ctx, task)
+	case metadata.TaskGC:
+		err = s.gc(ctx, task.Subject)
+	case metadata.TaskPin:
+		err = s.pin(ctx, task.Subject)
+	case metadata.TaskUnpin:
+		err = s.unpin(ctx, task.Subject)
+	case metadata.TaskDropCache:
+		s.dropCache(ctx)
 	default:
 		return errors.Wrapf(errdefs.ErrInvalidArgument, "unrecognized task type: %q", task.Type)
 	}
@@ -102,6 +166,64 @@ func (s *router) postRunTask(ctx context.Context, w http.ResponseWriter, r *http
 	return nil
 }
 
+func (s *router) postPing(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	count := 1
+	if r.FormValue("count") != "" {
+		var err error
+		count, err = strconv.Atoi(r.FormValue("count"))
+		if err != nil || count <= 0 {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid count %q", r.FormValue("count"))
+		}
+	}
+
+	infos, err := parseAddrs(strings.Split(r.FormValue("addrs"), ","))
+	if err != nil {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "%s", err)
+	}
+	if len(infos) != 1 {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "addrs must belong to exactly one peer, got %d", len(infos))
+	}
+
+	span, ctx := traceutil.StartSpanFromContext(ctx, "approuter.ping")
+	defer span.Finish()
+	span.SetTag("peer", infos[0].ID.String())
+	span.SetTag("count", count)
+
+	rtts, err := s.peer.Ping(ctx, infos[0], count)
+	if err != nil {
+		return err
+	}
+
+	return daemon.WriteJSON(w, &rtts)
+}
+
+func (s *router) postBenchLink(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	size, err := strconv.ParseInt(r.FormValue("size"), 10, 64)
+	if err != nil || size <= 0 {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid size %q", r.FormValue("size"))
+	}
+
+	infos, err := parseAddrs(strings.Split(r.FormValue("addrs"), ","))
+	if err != nil {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "%s", err)
+	}
+	if len(infos) != 1 {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "addrs must belong to exactly one peer, got %d", len(infos))
+	}
+
+	span, ctx := traceutil.StartSpanFromContext(ctx, "approuter.benchLink")
+	defer span.Finish()
+	span.SetTag("peer", infos[0].ID.String())
+	span.SetTag("size", size)
+
+	elapsed, err := s.peer.BenchLink(ctx, infos[0], size)
+	if err != nil {
+		return err
+	}
+
+	return daemon.WriteJSON(w, &elapsed)
+}
+
 func (s *router) getFile(ctx context.Context, target string) error {
 	span, ctx := traceutil.StartSpanFromContext(ctx, "approuter.getFile")
 	defer span.Finish()
@@ -182,6 +304,114 @@ func (s *router) disconnect(ctx context.Context, addrs []string) error {
 	return nil
 }
 
+func (s *router) connectivity(ctx context.Context, task metadata.Task) error {
+	span, ctx := traceutil.StartSpanFromContext(ctx, "approuter.connectivity")
+	defer span.Finish()
+
+	ctask, err := metadata.ParseConnectivityTask(task)
+	if err != nil {
+		return err
+	}
+	span.SetTag("bootstrap", len(ctask.Bootstrap))
+	span.SetTag("peers", ctask.Peers)
+
+	result, err := s.peer.Connectivity(ctx, ctask)
+	if err != nil {
+		return err
+	}
+
+	zerolog.Ctx(ctx).Debug().Dur("firstPeer", result.FirstPeer).Dur("targetPeers", result.TargetPeers).Msg("Reached target peers")
+	return nil
+}
+
+func (s *router) gc(ctx context.Context, subject string) error {
+	span, ctx := traceutil.StartSpanFromContext(ctx, "approuter.gc")
+	defer span.Finish()
+
+	var roots []cid.Cid
+	for _, v := range strings.Split(subject, ",") {
+		if v == "" {
+			continue
+		}
+
+		c, err := cid.Parse(v)
+		if err != nil {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "%s", err)
+		}
+		roots = append(roots, c)
+	}
+
+	gc, err := s.peer.GC(ctx, roots)
+	if err != nil {
+		return err
+	}
+
+	zerolog.Ctx(ctx).Info().
+		Int("blocksRemoved", gc.BlocksRemoved).
+		Int64("bytesFreed", gc.BytesFreed).
+		Int64("sizeBefore", gc.SizeBefore).
+		Int64("sizeAfter", gc.SizeAfter).
+		Msg("Collected garbage")
+	return nil
+}
+
+func (s *router) pin(ctx context.Context, target string) error {
+	span, ctx := traceutil.StartSpanFromContext(ctx, "approuter.pin")
+	defer span.Finish()
+	span.SetTag("cid", target)
+
+	c, err := cid.Parse(target)
+	if err != nil {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "%s", err)
+	}
+
+	pin, err := s.peer.Pin(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	zerolog.Ctx(ctx).Info().
+		Str("cid", c.String()).
+		Bool("cacheHit", pin.CacheHit).
+		Int("blocks", pin.Blocks).
+		Int64("bytes", pin.Bytes).
+		Dur("duration", pin.Duration).
+		Msg("Pinned content")
+	return nil
+}
+
+func (s *router) unpin(ctx context.Context, target string) error {
+	span, ctx := traceutil.StartSpanFromContext(ctx, "approuter.unpin")
+	defer span.Finish()
+	span.SetTag("cid", target)
+
+	c, err := cid.Parse(target)
+	if err != nil {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "%s", err)
+	}
+
+	gc, err := s.peer.Unpin(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	zerolog.Ctx(ctx).Info().
+		Str("cid", c.String()).
+		Int("blocksRemoved", gc.BlocksRemoved).
+		Int64("bytesFreed", gc.BytesFreed).
+		Msg("Unpinned content")
+	return nil
+}
+
+func (s *router) dropCache(ctx context.Context) {
+	drop := s.peer.DropCache(ctx)
+	if !drop.Dropped {
+		zerolog.Ctx(ctx).Warn().Str("warning", drop.Warning).Msg("Page cache not dropped, caches may be warm")
+		return
+	}
+	zerolog.Ctx(ctx).Info().Msg("Dropped page cache")
+}
+
 func parseAddrs(addrs []string) ([]libp2ppeer.AddrInfo, error) {
 	var mas []multiaddr.Multiaddr
 	for _, addr := range addrs {
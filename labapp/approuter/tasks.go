@@ -0,0 +1,165 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package approuter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/Netflix/p2plab/pkg/logutil"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// maxQueuedTasks is how many tasks can wait to be executed before
+	// submissions are refused.
+	maxQueuedTasks = 1024
+
+	// maxFinishedTasks is how many finished tasks are kept for their status
+	// to be retrieved, before the oldest are forgotten.
+	maxFinishedTasks = 256
+)
+
+// taskQueue executes the tasks submitted asynchronously one at a time, in the
+// order they were submitted.
+type taskQueue struct {
+	run     func(ctx context.Context, task metadata.Task) error
+	ctx     context.Context
+	cancel  context.CancelFunc
+	pending chan queuedTask
+
+	mu       sync.Mutex
+	statuses map[string]*metadata.TaskStatus
+	finished []string
+}
+
+type queuedTask struct {
+	id     string
+	task   metadata.Task
+	logger zerolog.Logger
+}
+
+func newTaskQueue(run func(ctx context.Context, task metadata.Task) error) *taskQueue {
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &taskQueue{
+		run:      run,
+		ctx:      ctx,
+		cancel:   cancel,
+		pending:  make(chan queuedTask, maxQueuedTasks),
+		statuses: make(map[string]*metadata.TaskStatus),
+	}
+	go q.work()
+	return q
+}
+
+// submit queues the task and returns its status. The task is executed with
+// the logger of the context, outliving the context itself.
+func (q *taskQueue) submit(ctx context.Context, task metadata.Task) (metadata.TaskStatus, error) {
+	status := metadata.TaskStatus{
+		ID:        fmt.Sprintf("%s-%d", task.Type, time.Now().UnixNano()),
+		Task:      task,
+		State:     metadata.TaskQueued,
+		CreatedAt: time.Now(),
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	select {
+	case q.pending <- queuedTask{status.ID, task, *zerolog.Ctx(ctx)}:
+	default:
+		return metadata.TaskStatus{}, errors.Wrapf(errdefs.ErrUnavailable, "%d tasks already queued", maxQueuedTasks)
+	}
+	q.statuses[status.ID] = &status
+	return status, nil
+}
+
+// get returns the status of a task submitted asynchronously.
+func (q *taskQueue) get(id string) (metadata.TaskStatus, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	status, ok := q.statuses[id]
+	if !ok {
+		return metadata.TaskStatus{}, errors.Wrapf(errdefs.ErrNotFound, "task %q", id)
+	}
+	return *status, nil
+}
+
+// stop stops executing tasks, and aborts the one executing.
+func (q *taskQueue) stop() {
+	q.cancel()
+}
+
+func (q *taskQueue) work() {
+	for {
+		select {
+		case <-q.ctx.Done():
+			return
+		case qt := <-q.pending:
+			q.execute(qt)
+		}
+	}
+}
+
+func (q *taskQueue) execute(qt queuedTask) {
+	q.update(qt.id, func(status *metadata.TaskStatus) {
+		status.State = metadata.TaskRunning
+		status.StartedAt = time.Now()
+	})
+
+	// The task reports its progress by logging it, like it does to labd when
+	// it is executed synchronously.
+	progress := logutil.NewProgressWriter(func(completed, total int64) {
+		q.update(qt.id, func(status *metadata.TaskStatus) {
+			status.Completed, status.Total = completed, total
+		})
+	})
+	logger := qt.logger.Output(io.MultiWriter(os.Stderr, progress))
+	ctx := logger.WithContext(q.ctx)
+
+	err := q.run(ctx, qt.task)
+	if err != nil {
+		logger.Warn().Err(err).Str("task", qt.id).Msg("Task failed")
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	status := q.statuses[qt.id]
+	status.FinishedAt = time.Now()
+	if err != nil {
+		status.State = metadata.TaskFailed
+		status.Error = err.Error()
+	} else {
+		status.State = metadata.TaskSucceeded
+	}
+
+	q.finished = append(q.finished, qt.id)
+	if len(q.finished) > maxFinishedTasks {
+		delete(q.statuses, q.finished[0])
+		q.finished = q.finished[1:]
+	}
+}
+
+func (q *taskQueue) update(id string, fn func(status *metadata.TaskStatus)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	fn(q.statuses[id])
+}
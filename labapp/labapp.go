@@ -19,6 +19,8 @@ import (
 	"io"
 
 	"github.com/Netflix/p2plab/daemon"
+	"github.com/Netflix/p2plab/daemon/healthcheckrouter"
+	"github.com/Netflix/p2plab/daemon/versionrouter"
 	"github.com/Netflix/p2plab/labapp/approuter"
 	"github.com/Netflix/p2plab/metadata"
 	"github.com/Netflix/p2plab/peer"
@@ -41,6 +43,8 @@ func New(ctx context.Context, root, addr string, port int, logger *zerolog.Logge
 	closers = append(closers, &daemon.CancelCloser{cancel})
 
 	daemon, err := daemon.New("labapp", addr, logger,
+		healthcheckrouter.New(),
+		versionrouter.New(),
 		approuter.New(p),
 	)
 	if err != nil {
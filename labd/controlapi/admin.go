@@ -0,0 +1,125 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controlapi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/Netflix/p2plab/pkg/httputil"
+	"github.com/pkg/errors"
+)
+
+type adminAPI struct {
+	client *httputil.Client
+	url    urlFunc
+}
+
+func (a *adminAPI) Backup(ctx context.Context) (io.ReadCloser, error) {
+	req := a.client.NewRequest("GET", a.url("/admin/backup"), httputil.WithRetryMax(0))
+
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to backup metadata")
+	}
+
+	return resp.Body, nil
+}
+
+func (a *adminAPI) Restore(ctx context.Context, r io.Reader, opts ...p2plab.RestoreOption) error {
+	var settings p2plab.RestoreSettings
+	for _, opt := range opts {
+		err := opt(&settings)
+		if err != nil {
+			return err
+		}
+	}
+
+	req := a.client.NewRequest("PUT", a.url("/admin/restore"), httputil.WithRetryMax(0)).
+		Body(r)
+
+	if settings.Force {
+		req.Option("force", "true")
+	}
+
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to restore metadata")
+	}
+	defer resp.Body.Close()
+
+	return nil
+}
+
+func (a *adminAPI) Logs(ctx context.Context, opts ...p2plab.DaemonLogsOption) (io.ReadCloser, error) {
+	var settings p2plab.DaemonLogsSettings
+	for _, opt := range opts {
+		err := opt(&settings)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	req := a.client.NewRequest("GET", a.url("/admin/logs"), httputil.WithRetryMax(0))
+	if settings.Level != "" {
+		req.Option("level", settings.Level)
+	}
+	if !settings.Since.IsZero() {
+		req.Option("since", settings.Since.Format(time.RFC3339Nano))
+	}
+	if settings.Follow {
+		req.Option("follow", true)
+	}
+
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get daemon logs")
+	}
+
+	return resp.Body, nil
+}
+
+func (a *adminAPI) Audit(ctx context.Context, opts ...p2plab.AuditOption) ([]metadata.AuditRecord, error) {
+	var settings p2plab.AuditSettings
+	for _, opt := range opts {
+		err := opt(&settings)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	req := a.client.NewRequest("GET", a.url("/admin/audit"))
+	if !settings.Since.IsZero() {
+		req.Option("since", settings.Since.Format(time.RFC3339Nano))
+	}
+
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query audit log")
+	}
+	defer resp.Body.Close()
+
+	var records []metadata.AuditRecord
+	err = json.NewDecoder(resp.Body).Decode(&records)
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
@@ -17,13 +17,21 @@ package controlapi
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/daemon"
 	"github.com/Netflix/p2plab/metadata"
 	"github.com/Netflix/p2plab/pkg/httputil"
 	"github.com/Netflix/p2plab/pkg/logutil"
+	digest "github.com/opencontainers/go-digest"
 	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
 )
 
 type benchmarkAPI struct {
@@ -48,12 +56,39 @@ func (a *benchmarkAPI) Create(ctx context.Context, cluster, scenario string, opt
 		req.Option("no-reset", "true")
 	}
 
+	if settings.MaxConcurrency > 0 {
+		req.Option("max-concurrency", strconv.Itoa(settings.MaxConcurrency))
+	}
+
+	if settings.NotifyURL != "" {
+		req.Option("notify-url", settings.NotifyURL)
+	}
+
+	if len(settings.Parameters) > 0 {
+		content, err := json.Marshal(settings.Parameters)
+		if err != nil {
+			return id, err
+		}
+		req.Option("parameters", string(content))
+	}
+
+	if len(settings.Tags) > 0 {
+		req.Option("tags", strings.Join(settings.Tags, ","))
+	}
+
+	if settings.IdempotencyKey != "" {
+		req.Header(IdempotencyKey, settings.IdempotencyKey)
+	}
+
 	resp, err := req.Send(ctx)
 	if err != nil {
 		return id, err
 	}
 	defer resp.Body.Close()
 
+	id = resp.Header.Get(ResourceID)
+	defer a.cancelOnInterrupt(ctx, id)
+
 	logWriter := logutil.LogWriter(ctx)
 	if logWriter != nil {
 		err = logutil.WriteRemoteLogs(ctx, resp.Body, logWriter)
@@ -62,7 +97,28 @@ func (a *benchmarkAPI) Create(ctx context.Context, cluster, scenario string, opt
 		}
 	}
 
-	return resp.Header.Get(ResourceID), nil
+	return id, nil
+}
+
+func (a *benchmarkAPI) DryRun(ctx context.Context, cluster, scenario string) (metadata.DryRun, error) {
+	var dryRun metadata.DryRun
+
+	req := a.client.NewRequest("POST", a.url("/benchmarks/dry-run"), httputil.WithRetryMax(0)).
+		Option("cluster", cluster).
+		Option("scenario", scenario)
+
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return dryRun, err
+	}
+	defer resp.Body.Close()
+
+	err = json.NewDecoder(resp.Body).Decode(&dryRun)
+	if err != nil {
+		return dryRun, err
+	}
+
+	return dryRun, nil
 }
 
 func (a *benchmarkAPI) Get(ctx context.Context, id string) (p2plab.Benchmark, error) {
@@ -130,6 +186,7 @@ func (a *benchmarkAPI) List(ctx context.Context, opts ...p2plab.ListOption) ([]p
 	if settings.Query != "" {
 		req.Option("query", settings.Query)
 	}
+	setListOptions(req, settings)
 
 	resp, err := req.Send(ctx)
 	if err != nil {
@@ -137,6 +194,14 @@ func (a *benchmarkAPI) List(ctx context.Context, opts ...p2plab.ListOption) ([]p
 	}
 	defer resp.Body.Close()
 
+	if settings.NextPageToken != nil {
+		*settings.NextPageToken = resp.Header.Get(daemon.NextPageTokenHeader)
+	}
+
+	if settings.Records != nil {
+		return nil, decodeRecords(resp.Body, settings.Records)
+	}
+
 	var metadatas []metadata.Benchmark
 	err = json.NewDecoder(resp.Body).Decode(&metadatas)
 	if err != nil {
@@ -155,9 +220,13 @@ func (a *benchmarkAPI) List(ctx context.Context, opts ...p2plab.ListOption) ([]p
 	return benchmarks, nil
 }
 
-func (a *benchmarkAPI) Remove(ctx context.Context, ids ...string) error {
+func (a *benchmarkAPI) Remove(ctx context.Context, ids []string, opts ...p2plab.RemoveOption) error {
 	req := a.client.NewRequest("DELETE", a.url("/benchmarks/delete")).
 		Option("ids", strings.Join(ids, ","))
+	err := setRemoveOptions(req, opts)
+	if err != nil {
+		return err
+	}
 
 	resp, err := req.Send(ctx)
 	if err != nil {
@@ -168,6 +237,144 @@ func (a *benchmarkAPI) Remove(ctx context.Context, ids ...string) error {
 	return nil
 }
 
+func (a *benchmarkAPI) Cancel(ctx context.Context, id string) error {
+	req := a.client.NewRequest("PUT", a.url("/benchmarks/%s/cancel", id))
+
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to cancel benchmark")
+	}
+	defer resp.Body.Close()
+
+	return nil
+}
+
+func (a *benchmarkAPI) Retry(ctx context.Context, id string) error {
+	req := a.client.NewRequest("PUT", a.url("/benchmarks/%s/retry", id), httputil.WithRetryMax(0))
+
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to retry benchmark")
+	}
+	defer resp.Body.Close()
+	defer a.cancelOnInterrupt(ctx, id)
+
+	logWriter := logutil.LogWriter(ctx)
+	if logWriter != nil {
+		err = logutil.WriteRemoteLogs(ctx, resp.Body, logWriter)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// cancelTimeout bounds canceling a benchmark after its request was canceled.
+const cancelTimeout = 10 * time.Second
+
+// cancelOnInterrupt cancels the benchmark on the daemon if the request
+// streaming its execution was canceled, so that it isn't left running.
+func (a *benchmarkAPI) cancelOnInterrupt(ctx context.Context, id string) {
+	if ctx.Err() != context.Canceled || id == "" {
+		return
+	}
+
+	// The request context is canceled, so use a new one that keeps the logger.
+	ctx, cancel := context.WithTimeout(zerolog.Ctx(ctx).WithContext(context.Background()), cancelTimeout)
+	defer cancel()
+
+	zerolog.Ctx(ctx).Info().Str("bid", id).Msg("Canceling benchmark")
+	err := a.Cancel(ctx, id)
+	if err != nil {
+		// The daemon may have stopped it already when the request was closed.
+		zerolog.Ctx(ctx).Debug().Err(err).Str("bid", id).Msg("Failed to cancel benchmark")
+	}
+}
+
+func (a *benchmarkAPI) Watch(ctx context.Context, id string, since uint64) (uint64, io.ReadCloser, error) {
+	req := a.client.NewRequest("GET", a.url("/benchmarks/%s/progress", id), httputil.WithRetryMax(0)).
+		Option("since", strconv.FormatUint(since, 10))
+
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "failed to watch benchmark")
+	}
+
+	offset, err := strconv.ParseUint(resp.Header.Get(ProgressOffsetHeader), 10, 64)
+	if err != nil {
+		resp.Body.Close()
+		return 0, nil, errors.Wrapf(err, "invalid progress offset of benchmark %q", id)
+	}
+
+	return offset, resp.Body, nil
+}
+
+func (a *benchmarkAPI) Artifacts(ctx context.Context, id string) ([]metadata.Artifact, error) {
+	req := a.client.NewRequest("GET", a.url("/benchmarks/%s/artifacts/json", id))
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list artifacts")
+	}
+	defer resp.Body.Close()
+
+	var artifacts []metadata.Artifact
+	err = json.NewDecoder(resp.Body).Decode(&artifacts)
+	if err != nil {
+		return nil, err
+	}
+
+	return artifacts, nil
+}
+
+func (a *benchmarkAPI) DownloadArtifact(ctx context.Context, id, name string, offset int64) (int64, io.ReadCloser, error) {
+	req := a.client.NewRequest("GET", a.url("/benchmarks/%s/artifacts/download", id)).
+		Option("name", name)
+
+	if offset > 0 {
+		req.Header("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return 0, nil, errors.Wrapf(err, "failed to download artifact %q", name)
+	}
+
+	// Servers ignoring the range send the whole content.
+	if resp.StatusCode == http.StatusPartialContent {
+		return offset, resp.Body, nil
+	}
+
+	dgst, err := digest.Parse(resp.Header.Get(ArtifactDigestHeader))
+	if err != nil {
+		resp.Body.Close()
+		return 0, nil, errors.Wrapf(err, "invalid digest of artifact %q", name)
+	}
+
+	return 0, &verifiedReader{
+		ReadCloser: resp.Body,
+		name:       name,
+		verifier:   dgst.Verifier(),
+	}, nil
+}
+
+// verifiedReader fails at the end of the stream if the content read doesn't
+// match the expected digest.
+type verifiedReader struct {
+	io.ReadCloser
+	name     string
+	verifier digest.Verifier
+}
+
+func (r *verifiedReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.verifier.Write(p[:n])
+	if err == io.EOF && !r.verifier.Verified() {
+		return n, errors.Errorf("artifact %q doesn't match its digest", r.name)
+	}
+	return n, err
+}
+
 type benchmark struct {
 	client   *httputil.Client
 	metadata metadata.Benchmark
@@ -18,11 +18,12 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"os"
 	"strings"
+	"time"
 
 	"github.com/Netflix/p2plab"
 	"github.com/Netflix/p2plab/metadata"
+	"github.com/Netflix/p2plab/pkg/cliutil"
 	"github.com/Netflix/p2plab/pkg/httputil"
 	"github.com/Netflix/p2plab/pkg/logutil"
 	"github.com/pkg/errors"
@@ -49,31 +50,41 @@ func (a *clusterAPI) Create(ctx context.Context, name string, opts ...p2plab.Cre
 
 	var cdef metadata.ClusterDefinition
 	if settings.Definition != "" {
-		f, err := os.Open(settings.Definition)
+		content, err := cliutil.ReadFile(settings.Definition)
 		if err != nil {
 			return id, err
 		}
-		defer f.Close()
 
-		err = json.NewDecoder(f).Decode(&cdef)
+		err = json.Unmarshal(content, &cdef)
 		if err != nil {
 			return id, err
 		}
-
-		for i, group := range cdef.Groups {
-			if group.Peer == nil {
-				cdef.Groups[i].Peer = &metadata.DefaultPeerDefinition
-			}
-		}
+	} else if len(settings.ClusterDefinition.Groups) > 0 {
+		cdef = settings.ClusterDefinition
 	} else {
 		cdef.Groups = append(cdef.Groups, metadata.ClusterGroup{
 			Size:         settings.Size,
 			InstanceType: settings.InstanceType,
 			Region:       settings.Region,
 			Peer:         &metadata.DefaultPeerDefinition,
+			CPUs:         settings.CPUs,
+			Memory:       settings.Memory,
+			Network:      settings.Network,
 		})
 	}
 
+	if settings.Provider != "" {
+		cdef.Provider = settings.Provider
+	}
+
+	for i, group := range cdef.Groups {
+		if group.Peer == nil {
+			group.Peer = &metadata.DefaultPeerDefinition
+		}
+		pdef := settings.Stack.Apply(*group.Peer)
+		cdef.Groups[i].Peer = &pdef
+	}
+
 	content, err := json.MarshalIndent(&cdef, "", "    ")
 	if err != nil {
 		return id, err
@@ -82,6 +93,15 @@ func (a *clusterAPI) Create(ctx context.Context, name string, opts ...p2plab.Cre
 	req := a.client.NewRequest("POST", a.url("/clusters/create"), httputil.WithRetryMax(0)).
 		Option("name", name).
 		Body(bytes.NewReader(content))
+	if len(settings.Labels) > 0 {
+		req.Option("labels", strings.Join(settings.Labels, ","))
+	}
+	if settings.TTL > 0 {
+		req.Option("ttl", settings.TTL.String())
+	}
+	if settings.NoWait {
+		req.Option("wait", false)
+	}
 
 	resp, err := req.Send(ctx)
 	if err != nil {
@@ -189,12 +209,55 @@ func (a *clusterAPI) List(ctx context.Context, opts ...p2plab.ListOption) ([]p2p
 	return clusters, nil
 }
 
+func (a *clusterAPI) Scale(ctx context.Context, name string, size int) error {
+	req := a.client.NewRequest("PUT", a.url("/clusters/%s/scale", name), httputil.WithRetryMax(0)).
+		Option("size", size)
+
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	logWriter := logutil.LogWriter(ctx)
+	if logWriter != nil {
+		err = logutil.WriteRemoteLogs(ctx, resp.Body, logWriter)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a *clusterAPI) Extend(ctx context.Context, name string, ttl time.Duration) (p2plab.Cluster, error) {
+	req := a.client.NewRequest("PUT", a.url("/clusters/%s/extend", name)).
+		Option("ttl", ttl.String())
+
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	c := cluster{client: a.client, url: a.url}
+	err = json.NewDecoder(resp.Body).Decode(&c.metadata)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
 type Event struct {
 }
 
-func (a *clusterAPI) Remove(ctx context.Context, names ...string) error {
+func (a *clusterAPI) Remove(ctx context.Context, names []string, opts ...p2plab.RemoveOption) error {
 	req := a.client.NewRequest("DELETE", a.url("/clusters/delete")).
 		Option("names", strings.Join(names, ","))
+	err := setRemoveOptions(req, opts)
+	if err != nil {
+		return err
+	}
 
 	resp, err := req.Send(ctx)
 	if err != nil {
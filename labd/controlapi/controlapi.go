@@ -15,14 +15,33 @@
 package controlapi
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"strings"
 
 	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/daemon"
+	"github.com/Netflix/p2plab/metadata"
 	"github.com/Netflix/p2plab/pkg/httputil"
+	"github.com/pkg/errors"
 )
 
 const (
 	ResourceID = "ResourceID"
+
+	// IdempotencyKey identifies retries of the same request, so that they are
+	// not executed more than once.
+	IdempotencyKey = "Idempotency-Key"
+
+	// ProgressOffsetHeader is the HTTP header carrying the sequence number of
+	// the first event of a benchmark progress stream.
+	ProgressOffsetHeader = "Progress-Offset"
+
+	// ArtifactDigestHeader is the HTTP header carrying the digest of a
+	// downloaded artifact.
+	ArtifactDigestHeader = "Artifact-Digest"
 )
 
 type api struct {
@@ -43,6 +62,68 @@ func (a *api) url(endpoint string, v ...interface{}) string {
 	return fmt.Sprintf("%s%s", a.addr, fmt.Sprintf(endpoint, v...))
 }
 
+func setListOptions(req *httputil.Request, settings p2plab.ListSettings) {
+	if settings.Limit > 0 {
+		req.Option("limit", settings.Limit)
+	}
+	if settings.Offset > 0 {
+		req.Option("offset", settings.Offset)
+	}
+	if settings.PageToken != "" {
+		req.Option("page-token", settings.PageToken)
+	}
+	if settings.OrderBy != "" {
+		req.Option("order-by", settings.OrderBy)
+	}
+	if settings.Desc {
+		req.Option("desc", true)
+	}
+	if len(settings.Fields) > 0 {
+		req.Option("fields", strings.Join(settings.Fields, ","))
+	}
+	if settings.Records != nil {
+		req.Header("Accept", daemon.JSONLinesContentType)
+	}
+}
+
+func setRemoveOptions(req *httputil.Request, opts []p2plab.RemoveOption) error {
+	var settings p2plab.RemoveSettings
+	for _, opt := range opts {
+		err := opt(&settings)
+		if err != nil {
+			return err
+		}
+	}
+
+	if settings.Cascade {
+		req.Option("cascade", true)
+	}
+	if settings.Force {
+		req.Option("force", true)
+	}
+	return nil
+}
+
+// decodeRecords calls fn with every value of a list streamed as JSON lines.
+func decodeRecords(r io.Reader, fn func(json.RawMessage) error) error {
+	dec := json.NewDecoder(r)
+	for {
+		var record json.RawMessage
+		err := dec.Decode(&record)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		err = fn(record)
+		if err != nil {
+			return err
+		}
+	}
+}
+
 func (a *api) Cluster() p2plab.ClusterAPI {
 	return &clusterAPI{a.client, a.url}
 }
@@ -62,3 +143,25 @@ func (a *api) Benchmark() p2plab.BenchmarkAPI {
 func (a *api) Experiment() p2plab.ExperimentAPI {
 	return &experimentAPI{a.client, a.url}
 }
+
+func (a *api) Admin() p2plab.AdminAPI {
+	return &adminAPI{a.client, a.url}
+}
+
+func (a *api) Health(ctx context.Context) (metadata.Health, error) {
+	var health metadata.Health
+
+	req := a.client.NewRequest("GET", a.url("/healthz"), httputil.WithRetryMax(0))
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return health, errors.Wrap(err, "failed to get health")
+	}
+	defer resp.Body.Close()
+
+	err = json.NewDecoder(resp.Body).Decode(&health)
+	if err != nil {
+		return health, err
+	}
+
+	return health, nil
+}
@@ -56,6 +56,55 @@ func (a *experimentAPI) Create(ctx context.Context, id string, edef metadata.Exp
 	return &e, nil
 }
 
+func (a *experimentAPI) Sweep(ctx context.Context, id string, trials []metadata.ExperimentTrial) (p2plab.Experiment, error) {
+	content, err := json.Marshal(&trials)
+	if err != nil {
+		return nil, err
+	}
+
+	req := a.client.NewRequest("POST", a.url("/experiments/sweep"), httputil.WithRetryMax(0)).
+		Option("id", id).
+		Body(bytes.NewReader(content))
+
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	e := experiment{client: a.client}
+	err = json.NewDecoder(resp.Body).Decode(&e.metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return &e, nil
+}
+
+func (a *experimentAPI) RecordTrial(ctx context.Context, id string, index int, trial metadata.ExperimentTrial) (p2plab.Experiment, error) {
+	content, err := json.Marshal(&trial)
+	if err != nil {
+		return nil, err
+	}
+
+	req := a.client.NewRequest("PUT", a.url("/experiments/%s/trials/%d", id, index)).
+		Body(bytes.NewReader(content))
+
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	e := experiment{client: a.client}
+	err = json.NewDecoder(resp.Body).Decode(&e.metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return &e, nil
+}
+
 func (a *experimentAPI) Get(ctx context.Context, id string) (p2plab.Experiment, error) {
 	req := a.client.NewRequest("GET", a.url("/experiments/%s/json", id))
 	resp, err := req.Send(ctx)
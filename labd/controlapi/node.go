@@ -21,6 +21,7 @@ import (
 	"strings"
 
 	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/daemon"
 	"github.com/Netflix/p2plab/labagent/agentapi"
 	"github.com/Netflix/p2plab/labapp/appapi"
 	"github.com/Netflix/p2plab/metadata"
@@ -93,6 +94,7 @@ func (a *nodeAPI) List(ctx context.Context, cluster string, opts ...p2plab.ListO
 	if settings.Query != "" {
 		req.Option("query", settings.Query)
 	}
+	setListOptions(req, settings)
 
 	resp, err := req.Send(ctx)
 	if err != nil {
@@ -100,6 +102,14 @@ func (a *nodeAPI) List(ctx context.Context, cluster string, opts ...p2plab.ListO
 	}
 	defer resp.Body.Close()
 
+	if settings.NextPageToken != nil {
+		*settings.NextPageToken = resp.Header.Get(daemon.NextPageTokenHeader)
+	}
+
+	if settings.Records != nil {
+		return nil, decodeRecords(resp.Body, settings.Records)
+	}
+
 	var metadatas []metadata.Node
 	err = json.NewDecoder(resp.Body).Decode(&metadatas)
 	if err != nil {
@@ -114,6 +124,102 @@ func (a *nodeAPI) List(ctx context.Context, cluster string, opts ...p2plab.ListO
 	return ns, nil
 }
 
+func (a *nodeAPI) Connect(ctx context.Context, cluster, source, target string, opts ...p2plab.ConnectOption) ([]metadata.NodeConnection, error) {
+	var settings p2plab.ConnectSettings
+	for _, opt := range opts {
+		err := opt(&settings)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	req := a.client.NewRequest("PUT", a.url("/clusters/%s/nodes/connect", cluster)).
+		Option("query", source).
+		Option("to", target)
+	if settings.Disconnect {
+		req.Option("disconnect", "true")
+	}
+
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var conns []metadata.NodeConnection
+	err = json.NewDecoder(resp.Body).Decode(&conns)
+	if err != nil {
+		return nil, err
+	}
+
+	return conns, nil
+}
+
+func (a *nodeAPI) Ping(ctx context.Context, cluster, q string, opts ...p2plab.PingOption) ([]metadata.NodePing, error) {
+	var settings p2plab.PingSettings
+	for _, opt := range opts {
+		err := opt(&settings)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	req := a.client.NewRequest("PUT", a.url("/clusters/%s/nodes/ping", cluster)).
+		Option("query", q)
+	if settings.Count > 0 {
+		req.Option("count", settings.Count)
+	}
+	if settings.Parallel > 0 {
+		req.Option("parallel", settings.Parallel)
+	}
+
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var pings []metadata.NodePing
+	err = json.NewDecoder(resp.Body).Decode(&pings)
+	if err != nil {
+		return nil, err
+	}
+
+	return pings, nil
+}
+
+func (a *nodeAPI) BenchLink(ctx context.Context, cluster, source, target string, size int64, opts ...p2plab.BenchLinkOption) ([]metadata.NodeLink, error) {
+	var settings p2plab.BenchLinkSettings
+	for _, opt := range opts {
+		err := opt(&settings)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	req := a.client.NewRequest("PUT", a.url("/clusters/%s/nodes/bench-link", cluster)).
+		Option("query", source).
+		Option("to", target).
+		Option("size", size)
+	if settings.Bidirectional {
+		req.Option("bidirectional", "true")
+	}
+
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var links []metadata.NodeLink
+	err = json.NewDecoder(resp.Body).Decode(&links)
+	if err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
 type node struct {
 	p2plab.AgentAPI
 	p2plab.AppAPI
@@ -56,6 +56,30 @@ func (a *scenarioAPI) Create(ctx context.Context, name string, sdef metadata.Sce
 	return &s, nil
 }
 
+func (a *scenarioAPI) Update(ctx context.Context, name string, sdef metadata.ScenarioDefinition) (p2plab.Scenario, error) {
+	content, err := json.MarshalIndent(&sdef, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+
+	req := a.client.NewRequest("PUT", a.url("/scenarios/%s", name)).
+		Body(bytes.NewReader(content))
+
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	s := scenario{client: a.client}
+	err = json.NewDecoder(resp.Body).Decode(&s.metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
 func (a *scenarioAPI) Get(ctx context.Context, name string) (p2plab.Scenario, error) {
 	req := a.client.NewRequest("GET", a.url("/scenarios/%s/json", name))
 	resp, err := req.Send(ctx)
@@ -144,9 +168,13 @@ func (a *scenarioAPI) List(ctx context.Context, opts ...p2plab.ListOption) ([]p2
 	return scenarios, nil
 }
 
-func (a *scenarioAPI) Remove(ctx context.Context, names ...string) error {
+func (a *scenarioAPI) Remove(ctx context.Context, names []string, opts ...p2plab.RemoveOption) error {
 	req := a.client.NewRequest("DELETE", a.url("/scenarios/delete")).
 		Option("names", strings.Join(names, ","))
+	err := setRemoveOptions(req, opts)
+	if err != nil {
+		return err
+	}
 
 	resp, err := req.Send(ctx)
 	if err != nil {
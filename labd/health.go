@@ -0,0 +1,121 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package labd
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Netflix/p2plab/daemon/healthcheckrouter"
+	"github.com/Netflix/p2plab/labd/controlapi"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/Netflix/p2plab/pkg/httputil"
+	"github.com/Netflix/p2plab/providers"
+)
+
+// agentHealthTimeout bounds how long an agent has to respond to be counted as
+// live.
+const agentHealthTimeout = 5 * time.Second
+
+// metadataHealth reports whether the metadata store is reachable.
+func metadataHealth(db metadata.DB) healthcheckrouter.HealthCheck {
+	return func(ctx context.Context) metadata.HealthComponent {
+		component := metadata.HealthComponent{Name: "metadata", Status: metadata.HealthOK}
+
+		_, err := db.ListClusters(ctx)
+		if err != nil {
+			component.Status = metadata.HealthUnavailable
+			component.Message = err.Error()
+		}
+		return component
+	}
+}
+
+// providerHealth reports whether the default node provider is reachable.
+func providerHealth(provider *providers.Providers) healthcheckrouter.HealthCheck {
+	return func(ctx context.Context) metadata.HealthComponent {
+		component := metadata.HealthComponent{
+			Name:    "provider",
+			Status:  metadata.HealthOK,
+			Message: provider.Default(),
+		}
+
+		err := provider.Ping(ctx)
+		if err != nil {
+			component.Status = metadata.HealthUnavailable
+			component.Message = fmt.Sprintf("%s: %s", provider.Default(), err)
+		}
+		return component
+	}
+}
+
+// agentsHealth reports how many agents of the nodes in all clusters are live.
+// Benchmarks can still be served on clusters whose agents are all live, so
+// dead agents only degrade labd.
+func agentsHealth(db metadata.DB, client *httputil.Client) healthcheckrouter.HealthCheck {
+	return func(ctx context.Context) metadata.HealthComponent {
+		component := metadata.HealthComponent{Name: "agents", Status: metadata.HealthOK}
+
+		clusters, err := db.ListClusters(ctx)
+		if err != nil {
+			component.Status = metadata.HealthUnavailable
+			component.Message = err.Error()
+			return component
+		}
+
+		var ns []metadata.Node
+		for _, cluster := range clusters {
+			cns, err := db.ListNodes(ctx, cluster.ID)
+			if err != nil {
+				component.Status = metadata.HealthUnavailable
+				component.Message = err.Error()
+				return component
+			}
+			ns = append(ns, cns...)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, agentHealthTimeout)
+		defer cancel()
+
+		var (
+			wg   sync.WaitGroup
+			mu   sync.Mutex
+			live int
+		)
+		for _, n := range ns {
+			n := n
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				health, err := controlapi.NewNode(client, n).Health(ctx)
+				if err == nil && health.Live {
+					mu.Lock()
+					live++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		component.Message = fmt.Sprintf("%d of %d agents live", live, len(ns))
+		if live < len(ns) {
+			component.Status = metadata.HealthDegraded
+		}
+		return component
+	}
+}
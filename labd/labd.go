@@ -18,11 +18,16 @@ import (
 	"context"
 	"io"
 	"path/filepath"
+	"time"
 
 	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/artifacts"
 	"github.com/Netflix/p2plab/builder"
 	"github.com/Netflix/p2plab/daemon"
 	"github.com/Netflix/p2plab/daemon/healthcheckrouter"
+	"github.com/Netflix/p2plab/daemon/versionrouter"
+	"github.com/Netflix/p2plab/labd/notifier"
+	"github.com/Netflix/p2plab/labd/routers/adminrouter"
 	"github.com/Netflix/p2plab/labd/routers/benchmarkrouter"
 	"github.com/Netflix/p2plab/labd/routers/clusterrouter"
 	"github.com/Netflix/p2plab/labd/routers/experimentrouter"
@@ -30,7 +35,9 @@ import (
 	"github.com/Netflix/p2plab/labd/routers/scenariorouter"
 	"github.com/Netflix/p2plab/metadata"
 	"github.com/Netflix/p2plab/peer"
+	"github.com/Netflix/p2plab/pkg/authutil"
 	"github.com/Netflix/p2plab/pkg/httputil"
+	"github.com/Netflix/p2plab/pkg/logutil"
 	"github.com/Netflix/p2plab/providers"
 	"github.com/Netflix/p2plab/transformers"
 	"github.com/Netflix/p2plab/uploaders"
@@ -38,15 +45,31 @@ import (
 	"github.com/rs/zerolog"
 )
 
+// The client to the agents keeps connections to every node of large clusters
+// idle between the stages of a benchmark, rather than dialing the nodes for
+// each request. This needs a file descriptor limit of labd above
+// agentMaxIdleConns.
+const (
+	agentMaxIdleConns        = 1024
+	agentMaxIdleConnsPerHost = 4
+	agentIdleConnTimeout     = 2 * time.Minute
+)
+
 type Labd struct {
 	daemon  *daemon.Daemon
 	seeder  *peer.Peer
 	builder p2plab.Builder
+	reaper  *clusterrouter.Reaper
 	closers []io.Closer
 }
 
 func New(root, addr string, logger *zerolog.Logger, opts ...LabdOption) (*Labd, error) {
-	var settings LabdSettings
+	settings := LabdSettings{
+		IdempotencyWindow: 24 * time.Hour,
+		GracePeriod:       daemon.DefaultGracePeriod,
+		ReapInterval:      time.Minute,
+		ExpiryWarning:     15 * time.Minute,
+	}
 	for _, opt := range opts {
 		err := opt(&settings)
 		if err != nil {
@@ -54,21 +77,38 @@ func New(root, addr string, logger *zerolog.Logger, opts ...LabdOption) (*Labd,
 		}
 	}
 
+	if settings.MetadataBackend == "" {
+		settings.MetadataBackend = "bolt"
+	}
+
+	if settings.Logs == nil {
+		settings.Logs = logutil.NewBroadcaster(0)
+	}
+
 	var closers []io.Closer
-	db, err := metadata.NewDB(root)
+	db, err := metadata.GetDB(context.Background(), root, settings.MetadataBackend, settings.MetadataDSN)
 	if err != nil {
 		return nil, err
 	}
 	closers = append(closers, db)
 
-	client, err := httputil.NewClient(httputil.NewHTTPClient(), httputil.WithLogger(logger))
+	_, err = db.Migrate(logger.WithContext(context.Background()))
+	if err != nil {
+		db.Close()
+		return nil, errors.Wrap(err, "failed to migrate metadata")
+	}
+
+	client, err := httputil.NewClient(httputil.NewHTTPClient(),
+		httputil.WithLogger(logger),
+		httputil.WithTransportTuning(agentMaxIdleConns, agentMaxIdleConnsPerHost, agentIdleConnTimeout),
+	)
 	if err != nil {
 		return nil, err
 	}
 
 	settings.ProviderSettings.DB = db
 	settings.ProviderSettings.Logger = logger
-	provider, err := providers.GetNodeProvider(filepath.Join(root, "providers"), settings.Provider, settings.ProviderSettings)
+	provider, err := providers.New(filepath.Join(root, "providers"), settings.Provider, settings.ProviderSettings)
 	if err != nil {
 		return nil, err
 	}
@@ -101,17 +141,60 @@ func New(root, addr string, logger *zerolog.Logger, opts ...LabdOption) (*Labd,
 	ts := transformers.New(filepath.Join(root, "transformers"), client.HTTPClient)
 	closers = append(closers, ts)
 
+	store, err := artifacts.New(root, settings.ArtifactStore, client.HTTPClient)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create artifact store")
+	}
+	closers = append(closers, store)
+
+	auditLog := settings.AuditLog
+	if auditLog == "" {
+		auditLog = filepath.Join(root, "audit.log")
+	}
+
+	audit, err := daemon.NewFileAuditSink(auditLog)
+	if err != nil {
+		return nil, err
+	}
+	closers = append(closers, audit)
+
+	notifier := notifier.New(client, settings.NotifyURL, settings.NotifySecret)
+
+	destroying := clusterrouter.NewDestroying()
+
+	limiter := daemon.NewRateLimiter(settings.RateLimitRead, settings.RateLimitWrite, settings.RateLimitTokens)
+
 	daemon, err := daemon.New("labd", addr, logger,
-		healthcheckrouter.New(),
-		clusterrouter.New(db, provider, client),
-		noderouter.New(db, client),
+		healthcheckrouter.NewWithRateLimits(limiter.Limits(),
+			metadataHealth(db),
+			providerHealth(provider),
+			agentsHealth(db, client),
+		),
+		versionrouter.New(),
+		clusterrouter.New(db, provider, client, destroying),
+		noderouter.New(db, client, settings.MaxPageSize),
 		scenariorouter.New(db),
-		benchmarkrouter.New(db, client, ts, seeder, builder),
+		benchmarkrouter.New(db, client, ts, seeder, builder, notifier, store, settings.MaxPageSize, settings.IdempotencyWindow),
 		experimentrouter.New(db, provider, client, ts, seeder, builder),
+		adminrouter.New(db, settings.Logs, audit),
 	)
 	if err != nil {
 		return nil, err
 	}
+	daemon.SetGracePeriod(settings.GracePeriod)
+	daemon.SetRateLimiter(limiter)
+	daemon.SetAuditSink(audit)
+
+	var auths []authutil.Authenticator
+	if len(settings.AuthTokens) > 0 {
+		auths = append(auths, authutil.NewStatic(settings.AuthTokens...))
+	}
+	if settings.AuthJWKSURL != "" {
+		auths = append(auths, authutil.NewJWKS(client.HTTPClient, settings.AuthJWKSURL, settings.AuthJWTAudience, settings.AuthJWTIssuer))
+	}
+	if len(auths) > 0 {
+		daemon.SetAuthenticator(authutil.Any(auths...))
+	}
 	closers = append(closers, daemon)
 
 	d := &Labd{
@@ -120,6 +203,9 @@ func New(root, addr string, logger *zerolog.Logger, opts ...LabdOption) (*Labd,
 		builder: builder,
 		closers: closers,
 	}
+	if settings.ReapInterval > 0 {
+		d.reaper = clusterrouter.NewReaper(db, provider, notifier, destroying, settings.ReapInterval, settings.ExpiryWarning)
+	}
 
 	return d, nil
 }
@@ -147,5 +233,9 @@ func (d *Labd) Serve(ctx context.Context) error {
 	}
 	zerolog.Ctx(ctx).Info().Strs("addrs", addrs).Msg("IPFS listening")
 
+	if d.reaper != nil {
+		go d.reaper.Run(ctx)
+	}
+
 	return d.daemon.Serve(ctx)
 }
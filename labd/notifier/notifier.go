@@ -0,0 +1,158 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/Netflix/p2plab/pkg/httputil"
+	"github.com/pkg/errors"
+)
+
+const (
+	// SignatureHeader is the header carrying the HMAC-SHA256 of the payload,
+	// keyed by the secret of the notifier.
+	SignatureHeader = "X-P2plab-Signature"
+)
+
+// Notification is the payload posted to webhooks when a benchmark finishes.
+type Notification struct {
+	ID       string                   `json:"id"`
+	Status   metadata.BenchmarkStatus `json:"status"`
+	Cluster  string                   `json:"cluster"`
+	Scenario string                   `json:"scenario"`
+	Failed   []string                 `json:"failed,omitempty"`
+	Summary  *metadata.ReportSummary  `json:"summary,omitempty"`
+	Totals   *metadata.ReportNode     `json:"totals,omitempty"`
+}
+
+// NewNotification creates a notification for a benchmark, the report is nil
+// if the benchmark didn't produce one.
+func NewNotification(benchmark metadata.Benchmark, report *metadata.Report) Notification {
+	n := Notification{
+		ID:       benchmark.ID,
+		Status:   benchmark.Status,
+		Cluster:  benchmark.Cluster.ID,
+		Scenario: benchmark.Scenario.ID,
+	}
+
+	for id, result := range benchmark.Nodes {
+		if result.Status != metadata.BenchmarkNodeDone {
+			n.Failed = append(n.Failed, id)
+		}
+	}
+	sort.Strings(n.Failed)
+
+	if report != nil {
+		n.Summary = &report.Summary
+		n.Totals = &report.Aggregates.Totals
+	}
+
+	return n
+}
+
+// ClusterExpiring is the event of an ExpiryNotification.
+const ClusterExpiring = "cluster.expiring"
+
+// ExpiryNotification is the payload posted to webhooks when a cluster is about
+// to be destroyed because its TTL runs out.
+type ExpiryNotification struct {
+	Event     string    `json:"event"`
+	Cluster   string    `json:"cluster"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// NewExpiryNotification creates a notification warning that a cluster expires.
+func NewExpiryNotification(cluster metadata.Cluster) ExpiryNotification {
+	return ExpiryNotification{
+		Event:     ClusterExpiring,
+		Cluster:   cluster.ID,
+		ExpiresAt: cluster.ExpiresAt,
+	}
+}
+
+type Notifier struct {
+	client *httputil.Client
+	url    string
+	secret []byte
+
+	retryMax     int
+	retryWaitMin time.Duration
+	retryWaitMax time.Duration
+}
+
+// New returns a notifier posting to url by default. If secret is not empty,
+// payloads are signed with it.
+func New(client *httputil.Client, url, secret string) *Notifier {
+	return &Notifier{
+		client:       client,
+		url:          url,
+		secret:       []byte(secret),
+		retryMax:     5,
+		retryWaitMin: time.Second,
+		retryWaitMax: 30 * time.Second,
+	}
+}
+
+// Notify posts the notification, a Notification or ExpiryNotification, to
+// url, or the default url of the notifier if empty. Delivery is retried with
+// exponential backoff. It is a no-op if there is no url to post to.
+func (n *Notifier) Notify(ctx context.Context, url string, notification interface{}) error {
+	if url == "" {
+		url = n.url
+	}
+	if url == "" {
+		return nil
+	}
+
+	content, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	req := n.client.NewRequest("POST", url,
+		httputil.WithRetryMax(n.retryMax),
+		httputil.WithRetryWaitMin(n.retryWaitMin),
+		httputil.WithRetryWaitMax(n.retryWaitMax),
+	).
+		Header("Content-Type", "application/json").
+		Body(content)
+
+	if len(n.secret) > 0 {
+		req.Header(SignatureHeader, Sign(n.secret, content))
+	}
+
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "failed to notify %q", url)
+	}
+	defer resp.Body.Close()
+
+	return nil
+}
+
+// Sign returns the signature of content in the form "sha256=<hex>".
+func Sign(secret, content []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(content)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
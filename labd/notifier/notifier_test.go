@@ -0,0 +1,79 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/Netflix/p2plab/pkg/httputil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotify(t *testing.T) {
+	var (
+		attempts     int
+		notification Notification
+		signature    string
+		expected     string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		content, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(content, &notification))
+		signature = r.Header.Get(SignatureHeader)
+		expected = Sign([]byte("secret"), content)
+	}))
+	defer srv.Close()
+
+	client, err := httputil.NewClient(httputil.NewHTTPClient())
+	require.NoError(t, err)
+
+	n := New(client, srv.URL, "secret")
+	n.retryWaitMin = time.Millisecond
+	n.retryWaitMax = time.Millisecond
+
+	err = n.Notify(context.Background(), "", NewNotification(metadata.Benchmark{
+		ID:     "b",
+		Status: metadata.BenchmarkError,
+		Nodes: map[string]metadata.BenchmarkNode{
+			"n1": {Status: metadata.BenchmarkNodeDone},
+			"n2": {Status: metadata.BenchmarkNodeTimeout},
+		},
+	}, nil))
+	require.NoError(t, err)
+	require.Equal(t, 2, attempts)
+	require.Equal(t, "b", notification.ID)
+	require.Equal(t, metadata.BenchmarkError, notification.Status)
+	require.Equal(t, []string{"n2"}, notification.Failed)
+	require.Equal(t, expected, signature)
+}
+
+func TestNotifyWithoutURL(t *testing.T) {
+	n := New(nil, "", "")
+	require.NoError(t, n.Notify(context.Background(), "", Notification{}))
+}
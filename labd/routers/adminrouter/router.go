@@ -0,0 +1,168 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adminrouter
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Netflix/p2plab/daemon"
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/Netflix/p2plab/pkg/logutil"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+type router struct {
+	db    metadata.DB
+	logs  *logutil.Broadcaster
+	audit daemon.AuditSink
+}
+
+func New(db metadata.DB, logs *logutil.Broadcaster, audit daemon.AuditSink) daemon.Router {
+	return &router{db, logs, audit}
+}
+
+func (s *router) Routes() []daemon.Route {
+	return []daemon.Route{
+		// GET
+		daemon.NewGetRoute("/admin/backup", s.getBackup),
+		daemon.NewGetRoute("/admin/logs", s.getLogs),
+		daemon.NewGetRoute("/admin/audit", s.getAudit),
+		// PUT
+		daemon.NewPutRoute("/admin/restore", s.putRestore),
+	}
+}
+
+func (s *router) getBackup(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	w.Header().Set("Content-Type", "application/octet-stream")
+	return s.db.Backup(ctx, w)
+}
+
+func (s *router) putRestore(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	force := false
+	if r.FormValue("force") != "" {
+		var err error
+		force, err = strconv.ParseBool(r.FormValue("force"))
+		if err != nil {
+			return err
+		}
+	}
+
+	err := s.db.Restore(ctx, r.Body, force)
+	if err != nil {
+		return err
+	}
+
+	zerolog.Ctx(ctx).Info().Bool("force", force).Msg("Restored metadata from backup")
+	return nil
+}
+
+func (s *router) getLogs(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	filter := logutil.Filter{Level: zerolog.DebugLevel}
+	if r.FormValue("level") != "" {
+		var err error
+		filter.Level, err = zerolog.ParseLevel(r.FormValue("level"))
+		if err != nil {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid level %q", r.FormValue("level"))
+		}
+	}
+
+	if r.FormValue("since") != "" {
+		var err error
+		filter.Since, err = time.Parse(time.RFC3339Nano, r.FormValue("since"))
+		if err != nil {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid since %q", r.FormValue("since"))
+		}
+	}
+
+	follow := false
+	if r.FormValue("follow") != "" {
+		var err error
+		follow, err = strconv.ParseBool(r.FormValue("follow"))
+		if err != nil {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid follow %q", r.FormValue("follow"))
+		}
+	}
+
+	_, backlog, lines, cancel := s.logs.Subscribe(0)
+	defer cancel()
+
+	w.Header().Set("Content-Type", daemon.JSONLinesContentType)
+	w.WriteHeader(http.StatusOK)
+
+	out := logutil.NewWriteFlusher(w)
+	for _, line := range backlog {
+		if !filter.Match(line) {
+			continue
+		}
+
+		_, err := out.Write(append(line, '\n'))
+		if err != nil {
+			return nil
+		}
+	}
+
+	if !follow {
+		return nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case line, ok := <-lines:
+			if !ok {
+				// The client fell behind, the logs it missed are still retained
+				// if it reconnects.
+				return nil
+			}
+
+			if !filter.Match(line) {
+				continue
+			}
+
+			_, err := out.Write(append(line, '\n'))
+			if err != nil {
+				return nil
+			}
+		}
+	}
+}
+
+func (s *router) getAudit(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if s.audit == nil {
+		return errors.Wrap(errdefs.ErrNotImplemented, "audit log is not enabled")
+	}
+
+	var since time.Time
+	if r.FormValue("since") != "" {
+		var err error
+		since, err = time.Parse(time.RFC3339Nano, r.FormValue("since"))
+		if err != nil {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid since %q", r.FormValue("since"))
+		}
+	}
+
+	records, err := s.audit.Query(ctx, since)
+	if err != nil {
+		return err
+	}
+
+	return daemon.WriteList(w, r, records)
+}
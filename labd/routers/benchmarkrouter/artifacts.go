@@ -0,0 +1,84 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package benchmarkrouter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/artifacts"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/rs/zerolog"
+)
+
+// saveArtifacts uploads the report and the logs of every node to the
+// artifact store, and references them in the benchmark. Failing to store an
+// artifact doesn't fail the benchmark.
+func (s *router) saveArtifacts(ctx context.Context, benchmark *metadata.Benchmark, report metadata.Report, ns []p2plab.Node) {
+	zerolog.Ctx(ctx).Info().Msg("Storing benchmark artifacts")
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	save := func(name string, fn func() ([]byte, error)) {
+		defer wg.Done()
+		content, err := fn()
+		if err != nil {
+			zerolog.Ctx(ctx).Warn().Err(err).Str("artifact", name).Msg("Failed to collect artifact")
+			return
+		}
+
+		artifact, err := artifacts.Upload(ctx, s.artifacts, benchmark.ID, name, bytes.NewReader(content))
+		if err != nil {
+			zerolog.Ctx(ctx).Warn().Err(err).Str("artifact", name).Msg("Failed to store artifact")
+			return
+		}
+
+		mu.Lock()
+		benchmark.Artifacts = metadata.SetArtifact(benchmark.Artifacts, artifact)
+		mu.Unlock()
+	}
+
+	wg.Add(1)
+	go save("report.json", func() ([]byte, error) {
+		return json.MarshalIndent(&report, "", "    ")
+	})
+	for _, n := range ns {
+		n := n
+		wg.Add(1)
+		go save(fmt.Sprintf("logs/%s.log", n.ID()), func() ([]byte, error) {
+			return nodeLogs(ctx, n)
+		})
+	}
+	wg.Wait()
+}
+
+// nodeLogs returns the logs retained by the node's agent.
+func nodeLogs(ctx context.Context, n p2plab.Node) ([]byte, error) {
+	_, rc, err := n.Logs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var buf bytes.Buffer
+	_, err = buf.ReadFrom(rc)
+	return buf.Bytes(), err
+}
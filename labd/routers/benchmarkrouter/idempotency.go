@@ -0,0 +1,108 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package benchmarkrouter
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/Netflix/p2plab/daemon"
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/metadata"
+)
+
+// idempotencyKeys remembers which benchmark was created for an idempotency key
+// until the key expires. Keys are kept in the metadata store, so that retries
+// still find the benchmark after labd restarts.
+type idempotencyKeys struct {
+	db     metadata.DB
+	window time.Duration
+}
+
+func newIdempotencyKeys(db metadata.DB, window time.Duration) *idempotencyKeys {
+	return &idempotencyKeys{
+		db:     db,
+		window: window,
+	}
+}
+
+// idempotencyKeyID scopes an idempotency key by the authenticated principal of
+// the request, or else its remote address, so that clients reusing the same
+// key neither collide nor see each other's benchmarks.
+func idempotencyKeyID(ctx context.Context, r *http.Request, key string) string {
+	client := daemon.Principal(ctx)
+	if client == "" {
+		client = r.RemoteAddr
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err == nil {
+			client = host
+		}
+	}
+	return fmt.Sprintf("%s/%s", client, key)
+}
+
+// claim records bid for the key with id and returns bid, unless the key is
+// already recorded in which case the existing benchmark ID is returned with
+// false. Expired keys are deleted first.
+func (k *idempotencyKeys) claim(ctx context.Context, id, bid string, now time.Time) (string, bool, error) {
+	var (
+		existing string
+		claimed  bool
+	)
+	err := k.db.Update(ctx, func(tctx context.Context) error {
+		err := k.db.DeleteExpiredIdempotencyKeys(tctx, now)
+		if err != nil {
+			return err
+		}
+
+		_, err = k.db.CreateIdempotencyKey(tctx, metadata.IdempotencyKey{
+			ID:        id,
+			Benchmark: bid,
+			ExpiresAt: now.Add(k.window),
+		})
+		if err == nil {
+			existing, claimed = bid, true
+			return nil
+		}
+		if !errdefs.IsAlreadyExists(err) {
+			return err
+		}
+
+		key, err := k.db.GetIdempotencyKey(tctx, id)
+		if err != nil {
+			return err
+		}
+		existing = key.Benchmark
+		return nil
+	})
+	if err != nil {
+		return "", false, err
+	}
+
+	return existing, claimed, nil
+}
+
+// release forgets the key with id so that the request can be retried, if the
+// benchmark was never created.
+func (k *idempotencyKeys) release(ctx context.Context, id string) error {
+	err := k.db.DeleteIdempotencyKey(ctx, id)
+	if err != nil && !errdefs.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
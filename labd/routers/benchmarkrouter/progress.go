@@ -0,0 +1,95 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package benchmarkrouter
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/Netflix/p2plab/pkg/logutil"
+)
+
+// progressBacklog is how many events of a benchmark are retained for watchers
+// to resume from, enough for every transition of large clusters.
+const progressBacklog = 8192
+
+// progress broadcasts the events of an executing benchmark to its watchers as
+// newline-delimited JSON.
+type progress struct {
+	events *logutil.Broadcaster
+	done   chan struct{}
+
+	mu        sync.Mutex
+	status    metadata.BenchmarkStatus
+	completed int
+	total     int
+}
+
+func newProgress() *progress {
+	return &progress{
+		events: logutil.NewBroadcaster(progressBacklog),
+		done:   make(chan struct{}),
+		status: metadata.BenchmarkPlanning,
+	}
+}
+
+// start reports that the benchmark is executing total tasks.
+func (p *progress) start(total int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.status = metadata.BenchmarkRunning
+	p.total = total
+	p.write(metadata.BenchmarkEvent{})
+}
+
+// report records the transition of a node's task, and returns how many of the
+// tasks are finished.
+func (p *progress) report(evt metadata.BenchmarkEvent) (completed, total int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if evt.NodeStatus != metadata.BenchmarkNodeRunning {
+		p.completed++
+	}
+	p.write(evt)
+	return p.completed, p.total
+}
+
+// finish reports the terminal status of the benchmark and ends the stream of
+// its watchers.
+func (p *progress) finish(status metadata.BenchmarkStatus) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.status = status
+	p.write(metadata.BenchmarkEvent{})
+	close(p.done)
+}
+
+func (p *progress) write(evt metadata.BenchmarkEvent) {
+	evt.Time = time.Now()
+	evt.Status = p.status
+	evt.Completed = p.completed
+	evt.Total = p.total
+
+	content, err := json.Marshal(&evt)
+	if err != nil {
+		return
+	}
+	p.events.Write(append(content, '\n'))
+}
@@ -16,16 +16,22 @@ package benchmarkrouter
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Netflix/p2plab"
 	"github.com/Netflix/p2plab/daemon"
+	"github.com/Netflix/p2plab/errdefs"
 	"github.com/Netflix/p2plab/labd/controlapi"
+	"github.com/Netflix/p2plab/labd/notifier"
 	"github.com/Netflix/p2plab/metadata"
 	"github.com/Netflix/p2plab/nodes"
 	"github.com/Netflix/p2plab/peer"
@@ -39,19 +45,115 @@ import (
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	jaeger "github.com/uber/jaeger-client-go"
-	bolt "go.etcd.io/bbolt"
 )
 
 type router struct {
-	db      metadata.DB
-	client  *httputil.Client
-	ts      *transformers.Transformers
-	seeder  *peer.Peer
-	builder p2plab.Builder
+	db        metadata.DB
+	client    *httputil.Client
+	ts        *transformers.Transformers
+	seeder    *peer.Peer
+	builder   p2plab.Builder
+	notifier  *notifier.Notifier
+	artifacts p2plab.ArtifactStore
+
+	maxPageSize int
+	idempotency *idempotencyKeys
+
+	mu           sync.Mutex
+	cancels      map[string]context.CancelFunc
+	progress     map[string]*progress
+	interrupted  map[string]struct{}
+	shuttingDown bool
 }
 
-func New(db metadata.DB, client *httputil.Client, ts *transformers.Transformers, seeder *peer.Peer, builder p2plab.Builder) daemon.Router {
-	return &router{db, client, ts, seeder, builder}
+func New(db metadata.DB, client *httputil.Client, ts *transformers.Transformers, seeder *peer.Peer, builder p2plab.Builder, notifier *notifier.Notifier, artifacts p2plab.ArtifactStore, maxPageSize int, idempotencyWindow time.Duration) daemon.Router {
+	return &router{
+		db:          db,
+		client:      client,
+		ts:          ts,
+		seeder:      seeder,
+		builder:     builder,
+		notifier:    notifier,
+		artifacts:   artifacts,
+		maxPageSize: maxPageSize,
+		idempotency: newIdempotencyKeys(db, idempotencyWindow),
+		cancels:     make(map[string]context.CancelFunc),
+		progress:    make(map[string]*progress),
+		interrupted: make(map[string]struct{}),
+	}
+}
+
+// shutdownPollInterval is how often the executing benchmarks are checked
+// while waiting for them to be interrupted.
+const shutdownPollInterval = 100 * time.Millisecond
+
+// Shutdown stops accepting benchmarks and interrupts the executing ones. Their
+// contexts are canceled, which aborts the tasks running on the nodes, and
+// their partial results are saved before they are untracked.
+func (s *router) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.shuttingDown = true
+	for bid, cancel := range s.cancels {
+		zerolog.Ctx(ctx).Info().Str("bid", bid).Msg("Interrupting benchmark")
+		s.interrupted[bid] = struct{}{}
+		cancel()
+	}
+	s.mu.Unlock()
+
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.mu.Lock()
+		executing := len(s.cancels)
+		s.mu.Unlock()
+		if executing == 0 {
+			return nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return errors.Wrapf(ctx.Err(), "%d benchmarks still executing", executing)
+		}
+	}
+}
+
+// accepting returns an error if the router is shutting down.
+func (s *router) accepting() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.shuttingDown {
+		return errors.Wrap(errdefs.ErrUnavailable, "labd is shutting down")
+	}
+	return nil
+}
+
+// Gauges exposes the number of benchmarks accepted that haven't finished
+// executing with the daemon's metrics.
+func (s *router) Gauges() []daemon.Gauge {
+	return []daemon.Gauge{
+		{
+			Name: "p2plab_benchmark_queue_depth",
+			Help: "Number of benchmarks accepted that haven't finished executing.",
+			Value: func() float64 {
+				s.mu.Lock()
+				defer s.mu.Unlock()
+				return float64(len(s.cancels))
+			},
+		},
+	}
+}
+
+// canceledStatus returns the status of a benchmark whose context was
+// canceled, either by a user or by the daemon shutting down.
+func (s *router) canceledStatus(bid string) metadata.BenchmarkStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.interrupted[bid]; ok {
+		return metadata.BenchmarkInterrupted
+	}
+	return metadata.BenchmarkCanceled
 }
 
 func (s *router) Routes() []daemon.Route {
@@ -60,22 +162,63 @@ func (s *router) Routes() []daemon.Route {
 		daemon.NewGetRoute("/benchmarks/json", s.getBenchmarks),
 		daemon.NewGetRoute("/benchmarks/{id}/json", s.getBenchmarkById),
 		daemon.NewGetRoute("/benchmarks/{id}/report/json", s.getBenchmarkReportById),
+		daemon.NewGetRoute("/benchmarks/{id}/metrics", s.getBenchmarkMetricsById),
+		daemon.NewGetRoute("/benchmarks/{id}/progress", s.getBenchmarkProgressById),
+		daemon.NewGetRoute("/benchmarks/{id}/artifacts/json", s.getBenchmarkArtifactsById),
+		daemon.NewGetRoute("/benchmarks/{id}/artifacts/download", s.getBenchmarkArtifactDownload),
+		daemon.NewGetRoute("/metrics", s.getMetrics),
 		// POST
 		daemon.NewPostRoute("/benchmarks/create", s.postBenchmarksCreate),
+		daemon.NewPostRoute("/benchmarks/dry-run", s.postBenchmarksDryRun),
 		// PUT
 		daemon.NewPutRoute("/benchmarks/label", s.putBenchmarksLabel),
+		daemon.NewPutRoute("/benchmarks/{id}/cancel", s.putBenchmarkCancel),
+		daemon.NewPutRoute("/benchmarks/{id}/retry", s.putBenchmarkRetry),
 		// DELETE
 		daemon.NewDeleteRoute("/benchmarks/delete", s.deleteBenchmarks),
 	}
 }
 
 func (s *router) getBenchmarks(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
-	benchmarks, err := s.db.ListBenchmarks(ctx)
+	page, err := daemon.ParsePage(r, s.maxPageSize)
 	if err != nil {
 		return err
 	}
 
-	return daemon.WriteJSON(w, &benchmarks)
+	field, desc, err := daemon.ParseOrder(r)
+	if err != nil {
+		return err
+	}
+
+	fields := stringutil.Coalesce(strings.Split(r.FormValue("fields"), ","))
+	_, err = metadata.LookupFields(metadata.Benchmark{}, fields)
+	if err != nil {
+		return err
+	}
+
+	benchmarks, err := s.db.ListBenchmarks(ctx, metadata.WithOrderBy(field, desc))
+	if err != nil {
+		return err
+	}
+
+	start, end, next := page.Window(len(benchmarks), func(i int) string {
+		return benchmarks[i].ID
+	})
+	benchmarks = benchmarks[start:end]
+
+	daemon.SetNextPageToken(w, next)
+	if len(fields) == 0 {
+		return daemon.WriteList(w, r, &benchmarks)
+	}
+
+	projections := make([]map[string]json.RawMessage, len(benchmarks))
+	for i, b := range benchmarks {
+		projections[i], err = metadata.ProjectJSON(b, fields)
+		if err != nil {
+			return err
+		}
+	}
+	return daemon.WriteList(w, r, &projections)
 }
 
 func (s *router) getBenchmarkById(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
@@ -85,7 +228,7 @@ func (s *router) getBenchmarkById(ctx context.Context, w http.ResponseWriter, r
 		return err
 	}
 
-	return daemon.WriteJSON(w, &benchmark)
+	return daemon.WriteCacheableJSON(w, r, &benchmark)
 }
 
 func (s *router) getBenchmarkReportById(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
@@ -95,19 +238,245 @@ func (s *router) getBenchmarkReportById(ctx context.Context, w http.ResponseWrit
 		return err
 	}
 
-	return daemon.WriteJSON(w, &report)
+	return daemon.WriteCacheableJSON(w, r, &report)
+}
+
+func (s *router) getBenchmarkMetricsById(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	id := vars["id"]
+	benchmark, err := s.db.GetBenchmark(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	report, err := s.db.GetReport(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", reports.PrometheusContentType)
+	return reports.WritePrometheus(w, []metadata.Benchmark{benchmark}, map[string]metadata.Report{id: report})
+}
+
+// getBenchmarkProgressById streams the events of an executing benchmark as
+// newline-delimited JSON until it finishes, starting from the sequence number
+// since. Benchmarks that are no longer executing only have their final event.
+func (s *router) getBenchmarkProgressById(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	id := vars["id"]
+
+	var since uint64
+	if r.FormValue("since") != "" {
+		var err error
+		since, err = strconv.ParseUint(r.FormValue("since"), 10, 64)
+		if err != nil {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid since %q", r.FormValue("since"))
+		}
+	}
+
+	s.mu.Lock()
+	p, ok := s.progress[id]
+	s.mu.Unlock()
+
+	if !ok {
+		benchmark, err := s.db.GetBenchmark(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if !benchmark.Status.Terminal() {
+			// For example when the daemon restarted in the middle of a benchmark.
+			return errors.Wrapf(errdefs.ErrUnavailable, "benchmark %q is %s but not executing on this daemon", id, benchmark.Status)
+		}
+
+		w.Header().Set(controlapi.ProgressOffsetHeader, strconv.FormatUint(since, 10))
+		return json.NewEncoder(w).Encode(&metadata.BenchmarkEvent{
+			Time:      benchmark.UpdatedAt,
+			Status:    benchmark.Status,
+			Completed: len(benchmark.Nodes),
+			Total:     len(benchmark.Nodes),
+		})
+	}
+
+	offset, backlog, lines, cancel := p.events.Subscribe(since)
+	defer cancel()
+
+	w.Header().Set(controlapi.ProgressOffsetHeader, strconv.FormatUint(offset, 10))
+	w.WriteHeader(http.StatusOK)
+
+	out := logutil.NewWriteFlusher(w)
+	for _, line := range backlog {
+		_, err := out.Write(append(line, '\n'))
+		if err != nil {
+			return nil
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-p.done:
+			// The final event is already buffered, drain it before ending.
+			for {
+				select {
+				case line, ok := <-lines:
+					if !ok {
+						return nil
+					}
+					_, err := out.Write(append(line, '\n'))
+					if err != nil {
+						return nil
+					}
+				default:
+					return nil
+				}
+			}
+		case line, ok := <-lines:
+			if !ok {
+				// The watcher fell behind, it is expected to reconnect from the
+				// next sequence number.
+				return nil
+			}
+
+			_, err := out.Write(append(line, '\n'))
+			if err != nil {
+				return nil
+			}
+		}
+	}
+}
+
+// getBenchmarkArtifactsById returns the artifacts saved with a benchmark.
+func (s *router) getBenchmarkArtifactsById(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	benchmark, err := s.db.GetBenchmark(ctx, vars["id"])
+	if err != nil {
+		return err
+	}
+
+	return daemon.WriteJSON(w, &benchmark.Artifacts)
+}
+
+// getBenchmarkArtifactDownload streams the named artifact of a benchmark.
+func (s *router) getBenchmarkArtifactDownload(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	id, name := vars["id"], r.FormValue("name")
+	benchmark, err := s.db.GetBenchmark(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	for _, artifact := range benchmark.Artifacts {
+		if artifact.Name != name {
+			continue
+		}
+
+		w.Header().Set("Accept-Ranges", "bytes")
+		w.Header().Set(controlapi.ArtifactDigestHeader, artifact.Digest.String())
+
+		// Honor ranges so that interrupted downloads can be resumed.
+		start, end, status := int64(0), artifact.Size-1, http.StatusOK
+		if rng := r.Header.Get("Range"); rng != "" {
+			start, end, err = httputil.ParseRange(rng, artifact.Size)
+			if err != nil {
+				w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", artifact.Size))
+				http.Error(w, err.Error(), http.StatusRequestedRangeNotSatisfiable)
+				return nil
+			}
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, artifact.Size))
+			status = http.StatusPartialContent
+		}
+
+		rc, err := s.artifacts.Get(ctx, artifact.Link, start)
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+
+		w.Header().Set("Content-Length", strconv.FormatInt(end-start+1, 10))
+		w.WriteHeader(status)
+		_, err = io.Copy(w, io.LimitReader(rc, end-start+1))
+		return err
+	}
+
+	return errors.Wrapf(errdefs.ErrNotFound, "benchmark %q has no artifact %q", id, name)
+}
+
+// getMetrics exports the most recent benchmark with a report for each pair
+// of cluster and scenario.
+func (s *router) getMetrics(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	bs, err := s.db.ListBenchmarks(ctx)
+	if err != nil {
+		return err
+	}
+
+	sort.SliceStable(bs, func(i, j int) bool {
+		return bs[i].CreatedAt.After(bs[j].CreatedAt)
+	})
+
+	var (
+		latest     []metadata.Benchmark
+		reportByID = make(map[string]metadata.Report)
+		seen       = make(map[[2]string]struct{})
+	)
+	for _, b := range bs {
+		key := [2]string{b.Cluster.ID, b.Scenario.ID}
+		if _, ok := seen[key]; ok {
+			continue
+		}
+
+		report, err := s.db.GetReport(ctx, b.ID)
+		if err != nil {
+			if errdefs.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+
+		seen[key] = struct{}{}
+		latest = append(latest, b)
+		reportByID[b.ID] = report
+	}
+
+	w.Header().Set("Content-Type", reports.PrometheusContentType)
+	return reports.WritePrometheus(w, latest, reportByID)
 }
 
 func (s *router) postBenchmarksCreate(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	err := s.accepting()
+	if err != nil {
+		return err
+	}
+
 	noReset := false
 	if r.FormValue("no-reset") != "" {
-		var err error
 		noReset, err = strconv.ParseBool(r.FormValue("no-reset"))
 		if err != nil {
 			return err
 		}
 	}
 
+	maxConcurrency := 0
+	if r.FormValue("max-concurrency") != "" {
+		maxConcurrency, err = strconv.Atoi(r.FormValue("max-concurrency"))
+		if err != nil || maxConcurrency < 0 {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid max concurrency %q", r.FormValue("max-concurrency"))
+		}
+	}
+
+	var params map[string]string
+	if r.FormValue("parameters") != "" {
+		err = json.Unmarshal([]byte(r.FormValue("parameters")), &params)
+		if err != nil {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid parameters %q", r.FormValue("parameters"))
+		}
+	}
+
+	tags := stringutil.Coalesce(strings.Split(r.FormValue("tags"), ","))
+	for _, tag := range tags {
+		_, _, err = metadata.ParseKeyValueLabel(tag)
+		if err != nil {
+			return err
+		}
+	}
+
 	sid := r.FormValue("scenario")
 	scenario, err := s.db.GetScenario(ctx, sid)
 	if err != nil {
@@ -121,6 +490,35 @@ func (s *router) postBenchmarksCreate(ctx context.Context, w http.ResponseWriter
 	}
 
 	bid := fmt.Sprintf("%s-%s-%d", cid, sid, time.Now().UnixNano())
+
+	created := false
+	key := r.Header.Get(controlapi.IdempotencyKey)
+	if key != "" {
+		id := idempotencyKeyID(ctx, r, key)
+		existing, ok, err := s.idempotency.claim(ctx, id, bid, time.Now())
+		if err != nil {
+			return errors.Wrap(err, "failed to claim idempotency key")
+		}
+		if !ok {
+			w.Header().Add(controlapi.ResourceID, existing)
+			_, logger := logutil.WithResponseLogger(ctx, w)
+			logger.Info().Str("bid", existing).Msg("Benchmark already created with the same idempotency key")
+			return nil
+		}
+
+		defer func() {
+			if created {
+				return
+			}
+			// The request may be canceled by now, but the key must still be
+			// released for the request to be retried.
+			logger := zerolog.Ctx(ctx)
+			err := s.idempotency.release(logger.WithContext(context.Background()), id)
+			if err != nil {
+				logger.Warn().Err(err).Msg("Failed to release idempotency key")
+			}
+		}()
+	}
 	w.Header().Add(controlapi.ResourceID, bid)
 
 	ctx, logger := logutil.WithResponseLogger(ctx, w)
@@ -128,12 +526,51 @@ func (s *router) postBenchmarksCreate(ctx context.Context, w http.ResponseWriter
 		return c.Str("bid", bid)
 	})
 
+	ctx, progress, untrack := s.track(ctx, bid)
+	defer untrack()
+
 	zerolog.Ctx(ctx).Info().Msg("Retrieving nodes in cluster")
 	mns, err := s.db.ListNodes(ctx, cid)
 	if err != nil {
 		return err
 	}
 
+	stack := scenario.Definition.Stack
+	if (stack != nil || len(scenario.Definition.Routing) > 0 || scenario.Definition.Datastore != "") && noReset {
+		return errors.Wrap(errdefs.ErrInvalidArgument, "scenario stack, routing and datastore cannot be applied without resetting nodes")
+	}
+
+	labeled := query.NewLabeledSet()
+	for _, n := range mns {
+		labeled.Add(query.NewLabeled(n.ID, n.Labels))
+	}
+
+	routing, err := scenarios.Routing(ctx, scenario.Definition, labeled)
+	if err != nil {
+		return err
+	}
+
+	// The scenario stack, routing and datastore only apply for the benchmark,
+	// the nodes keep their own peer definition in the metadata.
+	peers := make(map[string]metadata.PeerDefinition)
+	for i, n := range mns {
+		if stack != nil {
+			mns[i].Peer = stack.Apply(mns[i].Peer)
+		}
+		if rdef, ok := routing[n.ID]; ok {
+			mns[i].Peer = rdef.Apply(mns[i].Peer)
+		}
+		if scenario.Definition.Datastore != "" {
+			mns[i].Peer.Datastore = scenario.Definition.Datastore
+		}
+
+		err = mns[i].Peer.Validate()
+		if err != nil {
+			return errors.Wrapf(err, "invalid libp2p stack for node %q", n.ID)
+		}
+		peers[n.ID] = mns[i].Peer
+	}
+
 	var ns []p2plab.Node
 	lset := query.NewLabeledSet()
 	for _, n := range mns {
@@ -152,6 +589,8 @@ func (s *router) postBenchmarksCreate(ctx context.Context, w http.ResponseWriter
 		if err != nil {
 			return errors.Wrap(err, "failed to connect cluster")
 		}
+	} else if scenario.Definition.Seeding != nil {
+		zerolog.Ctx(ctx).Warn().Msg("Nodes are not reset, leechers may hold content from earlier benchmarks")
 	}
 
 	zerolog.Ctx(ctx).Info().Msg("Creating scenario plan")
@@ -160,17 +599,23 @@ func (s *router) postBenchmarksCreate(ctx context.Context, w http.ResponseWriter
 		return errors.Wrap(err, "failed to create scenario plan")
 	}
 
+	if maxConcurrency > 0 {
+		plan.MaxConcurrency = maxConcurrency
+	}
+
 	benchmark := metadata.Benchmark{
-		ID:       bid,
-		Status:   metadata.BenchmarkRunning,
-		Cluster:  cluster,
-		Scenario: scenario,
-		Plan:     plan,
-		Labels: []string{
+		ID:         bid,
+		Status:     metadata.BenchmarkRunning,
+		Cluster:    cluster,
+		Scenario:   scenario,
+		Plan:       plan,
+		Peers:      peers,
+		Parameters: params,
+		Labels: append([]string{
 			bid,
 			cid,
 			sid,
-		},
+		}, tags...),
 	}
 
 	zerolog.Ctx(ctx).Info().Msg("Creating benchmark metadata")
@@ -178,26 +623,28 @@ func (s *router) postBenchmarksCreate(ctx context.Context, w http.ResponseWriter
 	if err != nil {
 		return err
 	}
-
-	var seederAddrs []string
-	for _, addr := range s.seeder.Host().Addrs() {
-		seederAddrs = append(seederAddrs, fmt.Sprintf("%s/p2p/%s", addr, s.seeder.Host().ID()))
-	}
+	created = true
+	defer s.notify(ctx, r.FormValue("notify-url"), bid)
 
 	zerolog.Ctx(ctx).Info().Msg("Executing scenario plan")
-	execution, err := scenarios.Run(ctx, lset, plan, seederAddrs)
-	if err != nil {
+	progress.start(len(plan.Seed) + len(plan.Benchmark) + plan.StepTasks())
+	start := time.Now()
+	execution, err := scenarios.Run(ctx, lset, plan, s.seederAddrs())
+	if ctx.Err() == context.Canceled {
+		return s.cancelBenchmark(ctx, benchmark, ns, queries, start)
+	}
+	if execution == nil {
+		s.failBenchmark(ctx, benchmark)
 		return errors.Wrap(err, "failed to run scenario plan")
 	}
+	runErr := err
 
 	report := metadata.Report{
 		Summary: metadata.ReportSummary{
 			TotalTime: execution.End.Sub(execution.Start),
 		},
-		Nodes:   execution.Report,
 		Queries: queries,
 	}
-	report.Aggregates = reports.ComputeAggregates(report.Nodes)
 
 	jaegerUI := os.Getenv("JAEGER_UI")
 	if jaegerUI != "" {
@@ -207,16 +654,319 @@ func (s *router) postBenchmarksCreate(ctx context.Context, w http.ResponseWriter
 		}
 	}
 
+	err = s.saveExecution(ctx, benchmark, report, execution, ns)
+	if err != nil {
+		return err
+	}
+
+	if runErr != nil {
+		return errors.Wrap(runErr, "failed to run scenario plan")
+	}
+	return nil
+}
+
+func (s *router) putBenchmarkRetry(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	err := s.accepting()
+	if err != nil {
+		return err
+	}
+
+	bid := vars["id"]
+	benchmark, err := s.db.GetBenchmark(ctx, bid)
+	if err != nil {
+		return err
+	}
+
+	if !benchmark.Status.Terminal() {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "benchmark %q is still %s", bid, benchmark.Status)
+	}
+
+	// Steps depend on each other across nodes, so failed nodes can't be
+	// retried on their own.
+	if len(benchmark.Plan.Steps) > 0 {
+		return errors.Wrapf(errdefs.ErrFailedPrecondition, "benchmark %q has steps, which cannot be retried", bid)
+	}
+
+	var failed []string
+	for id, result := range benchmark.Nodes {
+		if result.Status.Retryable() {
+			failed = append(failed, id)
+		}
+	}
+	if len(failed) == 0 {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "benchmark %q has no failed nodes to retry", bid)
+	}
+
+	report, err := s.db.GetReport(ctx, bid)
+	if err != nil && !errdefs.IsNotFound(err) {
+		return err
+	}
+
+	ctx, logger := logutil.WithResponseLogger(ctx, w)
+	logger.UpdateContext(func(c zerolog.Context) zerolog.Context {
+		return c.Str("bid", bid)
+	})
+
+	ctx, progress, untrack := s.track(ctx, bid)
+	defer untrack()
+
+	zerolog.Ctx(ctx).Info().Msg("Retrieving nodes in cluster")
+	mns, err := s.db.ListNodes(ctx, benchmark.Cluster.ID)
+	if err != nil {
+		return err
+	}
+
+	nodeByID := make(map[string]metadata.Node)
+	for _, n := range mns {
+		nodeByID[n.ID] = n
+	}
+
+	var ns []p2plab.Node
+	lset := query.NewLabeledSet()
+	plan := metadata.ScenarioPlan{
+		Objects:   benchmark.Plan.Objects,
+		Seed:      make(metadata.ScenarioStage),
+		Benchmark: make(metadata.ScenarioStage),
+
+		MaxConcurrency: benchmark.Plan.MaxConcurrency,
+	}
+	for _, id := range failed {
+		n, ok := nodeByID[id]
+		if !ok {
+			zerolog.Ctx(ctx).Warn().Str("node", id).Msg("Node no longer exists in cluster, skipping")
+			benchmark.Nodes[id] = metadata.BenchmarkNode{
+				Status: metadata.BenchmarkNodeMissing,
+				Error:  "node no longer exists in cluster",
+			}
+			continue
+		}
+
+		node := controlapi.NewNode(s.client, n)
+		ns = append(ns, node)
+		lset.Add(node)
+		if task, ok := benchmark.Plan.Seed[id]; ok {
+			plan.Seed[id] = task
+		}
+		if task, ok := benchmark.Plan.Benchmark[id]; ok {
+			plan.Benchmark[id] = task
+		}
+	}
+
+	defer s.notify(ctx, "", bid)
+
+	benchmark.Generation++
+	if len(lset.Slice()) == 0 {
+		return s.saveExecution(ctx, benchmark, report, &scenarios.Execution{}, nil)
+	}
+
+	benchmark.Status = metadata.BenchmarkRunning
+	benchmark, err = s.db.UpdateBenchmark(ctx, benchmark)
+	if err != nil {
+		return err
+	}
+
+	zerolog.Ctx(ctx).Info().Int("generation", benchmark.Generation).Strs("nodes", failed).Msg("Retrying failed nodes")
+	progress.start(len(plan.Seed) + len(plan.Benchmark))
+	execution, err := scenarios.Run(ctx, lset, plan, s.seederAddrs())
+	if ctx.Err() == context.Canceled {
+		benchmark.Status = s.canceledStatus(bid)
+		_, uerr := s.db.UpdateBenchmark(zerolog.Ctx(ctx).WithContext(context.Background()), benchmark)
+		if uerr != nil {
+			return uerr
+		}
+		return errors.Wrapf(context.Canceled, "benchmark %q %s", bid, benchmark.Status)
+	}
+	if execution == nil {
+		s.failBenchmark(ctx, benchmark)
+		return errors.Wrap(err, "failed to retry scenario plan")
+	}
+	runErr := err
+
+	err = s.saveExecution(ctx, benchmark, report, execution, ns)
+	if err != nil {
+		return err
+	}
+
+	if runErr != nil {
+		return errors.Wrap(runErr, "failed to retry scenario plan")
+	}
+	return nil
+}
+
+// saveExecution merges the execution's reports and node results into the
+// benchmark, and updates the benchmark status accordingly.
+func (s *router) saveExecution(ctx context.Context, benchmark metadata.Benchmark, report metadata.Report, execution *scenarios.Execution, ns []p2plab.Node) error {
+	if report.Nodes == nil {
+		report.Nodes = make(map[string]metadata.ReportNode)
+	}
+	for id, node := range execution.Report {
+		report.Nodes[id] = node
+	}
+	report.Aggregates = reports.ComputeAggregates(report.Nodes)
+
+	if benchmark.Nodes == nil {
+		benchmark.Nodes = make(map[string]metadata.BenchmarkNode)
+	}
+	for id, result := range execution.Nodes {
+		benchmark.Nodes[id] = result
+	}
+	report.Summary.TimeToFirstBlock = reports.ComputeTimeToFirstBlock(benchmark.Nodes)
+	report.Summary.BlockLatency = reports.ComputeBlockLatency(benchmark.Nodes)
+	report.Summary.TaskDuration = reports.ComputeTaskDuration(benchmark.Nodes)
+
+	window := execution.Window()
+	benchmark.Window = &window
+	if len(execution.Steps) > 0 {
+		benchmark.Steps = execution.Steps
+	}
+
+	benchmark.Status = metadata.BenchmarkDone
+	for _, result := range benchmark.Nodes {
+		if result.Status != metadata.BenchmarkNodeDone {
+			benchmark.Status = metadata.BenchmarkError
+			break
+		}
+	}
+
+	s.saveArtifacts(ctx, &benchmark, report, ns)
+
 	zerolog.Ctx(ctx).Info().Msg("Updating benchmark metadata")
-	err = s.db.Update(ctx, func(tx *bolt.Tx) error {
-		tctx := metadata.WithTransactionContext(ctx, tx)
+	return s.db.Update(ctx, func(tctx context.Context) error {
+		err := s.db.CreateReport(tctx, benchmark.ID, report)
+		if err != nil {
+			return errors.Wrap(err, "failed to create report")
+		}
+
+		_, err = s.db.UpdateBenchmark(tctx, benchmark)
+		if err != nil {
+			return errors.Wrap(err, "failed to update benchmark")
+		}
+
+		return nil
+	})
+}
+
+// notify posts the final state of the benchmark to the webhook in the
+// background. Failing to deliver the notification doesn't fail the benchmark.
+func (s *router) notify(ctx context.Context, url, bid string) {
+	// The response is done by then, so only log to the daemon.
+	logger := zerolog.Ctx(ctx).Output(os.Stderr)
+	ctx = logger.WithContext(context.Background())
+
+	go func() {
+		benchmark, err := s.db.GetBenchmark(ctx, bid)
+		if err != nil {
+			zerolog.Ctx(ctx).Warn().Err(err).Msg("Failed to get benchmark to notify")
+			return
+		}
+
+		var report *metadata.Report
+		r, err := s.db.GetReport(ctx, bid)
+		if err == nil {
+			report = &r
+		}
+
+		err = s.notifier.Notify(ctx, url, notifier.NewNotification(benchmark, report))
+		if err != nil {
+			zerolog.Ctx(ctx).Warn().Err(err).Msg("Failed to deliver benchmark notification")
+		}
+	}()
+}
+
+func (s *router) failBenchmark(ctx context.Context, benchmark metadata.Benchmark) {
+	benchmark.Status = metadata.BenchmarkError
+	_, err := s.db.UpdateBenchmark(ctx, benchmark)
+	if err != nil {
+		zerolog.Ctx(ctx).Warn().Err(err).Msg("failed to update benchmark status to error")
+	}
+}
+
+// track registers the benchmark as executing so that it can be canceled and
+// watched. The transitions of the nodes' tasks executed with the returned
+// context are reported to the progress.
+func (s *router) track(ctx context.Context, bid string) (context.Context, *progress, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	p := newProgress()
+
+	s.mu.Lock()
+	s.cancels[bid] = cancel
+	s.progress[bid] = p
+	if s.shuttingDown {
+		// The daemon started shutting down after the request was accepted.
+		s.interrupted[bid] = struct{}{}
+		cancel()
+	}
+	s.mu.Unlock()
 
+	report := func(evt metadata.BenchmarkEvent) {
+		completed, total := p.report(evt)
+		if evt.NodeStatus != metadata.BenchmarkNodeRunning {
+			logutil.Progress(ctx, completed, total, "Task finished")
+		}
+	}
+
+	return scenarios.WithProgress(ctx, report), p, func() {
+		s.mu.Lock()
+		delete(s.cancels, bid)
+		delete(s.progress, bid)
+		delete(s.interrupted, bid)
+		s.mu.Unlock()
+		cancel()
+
+		p.finish(s.finalStatus(ctx, bid))
+	}
+}
+
+// finalStatus returns the status of a benchmark that is no longer executing.
+// Benchmarks that never got created or saved are reported as errors.
+func (s *router) finalStatus(ctx context.Context, bid string) metadata.BenchmarkStatus {
+	benchmark, err := s.db.GetBenchmark(zerolog.Ctx(ctx).WithContext(context.Background()), bid)
+	if err != nil || !benchmark.Status.Terminal() {
+		return metadata.BenchmarkError
+	}
+	return benchmark.Status
+}
+
+func (s *router) seederAddrs() []string {
+	var addrs []string
+	for _, addr := range s.seeder.Host().Addrs() {
+		addrs = append(addrs, fmt.Sprintf("%s/p2p/%s", addr, s.seeder.Host().ID()))
+	}
+	return addrs
+}
+
+// cancelBenchmark collects the reports of what the nodes did before the
+// benchmark was canceled, and marks the benchmark as canceled or interrupted.
+func (s *router) cancelBenchmark(ctx context.Context, benchmark metadata.Benchmark, ns []p2plab.Node, queries map[string][]string, start time.Time) error {
+	status := s.canceledStatus(benchmark.ID)
+	zerolog.Ctx(ctx).Info().Str("status", string(status)).Msg("Benchmark canceled, collecting partial reports")
+
+	// The benchmark context is canceled, so use a new one that keeps the logger.
+	ctx = zerolog.Ctx(ctx).WithContext(context.Background())
+
+	report := metadata.Report{
+		Summary: metadata.ReportSummary{
+			TotalTime: time.Since(start),
+		},
+		Queries: queries,
+	}
+
+	var err error
+	report.Nodes, err = nodes.CollectReports(ctx, ns)
+	if err != nil {
+		zerolog.Ctx(ctx).Warn().Err(err).Msg("failed to collect partial reports")
+	}
+	report.Aggregates = reports.ComputeAggregates(report.Nodes)
+
+	s.saveArtifacts(ctx, &benchmark, report, ns)
+
+	err = s.db.Update(ctx, func(tctx context.Context) error {
 		err := s.db.CreateReport(tctx, benchmark.ID, report)
 		if err != nil {
 			return errors.Wrap(err, "failed to create report")
 		}
 
-		benchmark.Status = metadata.BenchmarkDone
+		benchmark.Status = status
 		_, err = s.db.UpdateBenchmark(tctx, benchmark)
 		if err != nil {
 			return errors.Wrap(err, "failed to update benchmark")
@@ -228,7 +978,104 @@ func (s *router) postBenchmarksCreate(ctx context.Context, w http.ResponseWriter
 		return err
 	}
 
-	return nil
+	return errors.Wrapf(context.Canceled, "benchmark %q %s", benchmark.ID, status)
+}
+
+func (s *router) putBenchmarkCancel(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	id := vars["id"]
+
+	s.mu.Lock()
+	cancel, ok := s.cancels[id]
+	s.mu.Unlock()
+	if ok {
+		zerolog.Ctx(ctx).Info().Str("bid", id).Msg("Canceling benchmark")
+		cancel()
+		return nil
+	}
+
+	benchmark, err := s.db.GetBenchmark(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if benchmark.Status.Terminal() {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "benchmark %q is already %s", id, benchmark.Status)
+	}
+
+	// The benchmark isn't executing in this daemon, for example when the daemon
+	// restarted in the middle of a benchmark.
+	benchmark.Status = metadata.BenchmarkCanceled
+	_, err = s.db.UpdateBenchmark(ctx, benchmark)
+	return err
+}
+
+func (s *router) postBenchmarksDryRun(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	sid := r.FormValue("scenario")
+	scenario, err := s.db.GetScenario(ctx, sid)
+	if err != nil {
+		return err
+	}
+
+	err = scenario.Definition.Validate()
+	if err != nil {
+		return err
+	}
+
+	cid := r.FormValue("cluster")
+	_, err = s.db.GetCluster(ctx, cid)
+	if err != nil {
+		return err
+	}
+
+	mns, err := s.db.ListNodes(ctx, cid)
+	if err != nil {
+		return err
+	}
+
+	lset := query.NewLabeledSet()
+	for _, n := range mns {
+		lset.Add(controlapi.NewNode(s.client, n))
+	}
+
+	plan, queries, err := scenarios.Plan(ctx, scenario.Definition, s.ts, s.seeder, lset)
+	if err != nil {
+		return errors.Wrap(err, "failed to create scenario plan")
+	}
+
+	for q, ids := range queries {
+		if len(ids) == 0 {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "query %q matches no nodes in cluster %q", q, cid)
+		}
+	}
+
+	dryRun := metadata.DryRun{
+		Cluster:   cid,
+		Scenario:  sid,
+		Nodes:     len(mns),
+		Queries:   queries,
+		Objects:   make(map[string]metadata.DryRunObject),
+		Seed:      plan.Seed,
+		Benchmark: plan.Benchmark,
+		Steps:     plan.Steps,
+		Seeders:   plan.Seeders,
+		Leechers:  plan.Leechers,
+	}
+
+	for name, c := range plan.Objects {
+		nd, err := s.seeder.DAGService().Get(ctx, c)
+		if err != nil {
+			return errors.Wrapf(err, "failed to resolve object %q", name)
+		}
+
+		size, err := nd.Size()
+		if err != nil {
+			return errors.Wrapf(err, "failed to size object %q", name)
+		}
+
+		dryRun.Objects[name] = metadata.DryRunObject{CID: c.String(), Size: size}
+	}
+
+	return daemon.WriteJSON(w, &dryRun)
 }
 
 func (s *router) putBenchmarksLabel(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
@@ -251,12 +1098,29 @@ func (s *router) putBenchmarksLabel(ctx context.Context, w http.ResponseWriter,
 func (s *router) deleteBenchmarks(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	ids := strings.Split(r.FormValue("ids"), ",")
 
-	err := s.db.DeleteBenchmarks(ctx, ids...)
+	removal, err := daemon.ParseRemoval(r)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	switch {
+	case removal.Force:
+		return s.db.DeleteBenchmarks(ctx, ids...)
+	case removal.Cascade:
+		return metadata.DeleteBenchmarksCascade(ctx, s.db, ids...)
+	}
+
+	dependents, err := metadata.BenchmarkDependents(ctx, s.db, ids...)
+	if err != nil {
+		return err
+	}
+
+	err = dependents.Err("benchmark", "experiments")
+	if err != nil {
+		return err
+	}
+
+	return s.db.DeleteBenchmarks(ctx, ids...)
 }
 
 func (s *router) matchBenchmarks(ctx context.Context, q string) ([]metadata.Benchmark, error) {
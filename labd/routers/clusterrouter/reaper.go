@@ -0,0 +1,265 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterrouter
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/labd/notifier"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/Netflix/p2plab/providers"
+	"github.com/rs/zerolog"
+)
+
+// Reaper destroys clusters whose TTL ran out, warning the default webhook of
+// its notifier some time before they expire.
+//
+// Expired clusters with benchmarks that are still planning or running are
+// postponed until they finish. Benchmarks that finished on a cluster are kept
+// when it is reaped, like removing it with --force, since their reports
+// outlive the cluster.
+type Reaper struct {
+	db         metadata.DB
+	providers  *providers.Providers
+	notifier   *notifier.Notifier
+	destroying *Destroying
+	interval   time.Duration
+	warning    time.Duration
+
+	// warned is the expiry each cluster was last warned about, so that a
+	// cluster is only warned again once its TTL is extended.
+	warned map[string]time.Time
+
+	// started is whether the reaper already reaped once, after which clusters
+	// marked destroying are no longer presumed abandoned.
+	started bool
+}
+
+func NewReaper(db metadata.DB, providers *providers.Providers, notifier *notifier.Notifier, destroying *Destroying, interval, warning time.Duration) *Reaper {
+	return &Reaper{
+		db:         db,
+		providers:  providers,
+		notifier:   notifier,
+		destroying: destroying,
+		interval:   interval,
+		warning:    warning,
+		warned:     make(map[string]time.Time),
+	}
+}
+
+// Run reaps clusters every interval until the context is canceled. Clusters
+// are reaped one at a time, and only after the reaper marked them as
+// destroying, so a cluster is never destroyed twice. Clusters that failed to
+// be destroyed are marked as errored and retried on the next interval.
+//
+// Expired clusters left destroying by a previous labd, such as when it
+// restarted while destroying them, are only claimed again by the first reap
+// when the reaper starts.
+func (r *Reaper) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		err := r.Reap(ctx, time.Now())
+		if err != nil {
+			zerolog.Ctx(ctx).Warn().Err(err).Msg("Failed to reap expired clusters")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reap warns about the clusters expiring within the warning period and
+// destroys the clusters that expired by now.
+func (r *Reaper) Reap(ctx context.Context, now time.Time) error {
+	clusters, err := r.db.ListClusters(ctx)
+	if err != nil {
+		return err
+	}
+
+	abandoned := !r.started
+	r.started = true
+
+	live := make(map[string]struct{})
+	for _, cluster := range clusters {
+		live[cluster.ID] = struct{}{}
+		if cluster.ExpiresAt.IsZero() || (cluster.Status == metadata.ClusterDestroying && !abandoned) {
+			continue
+		}
+
+		logger := zerolog.Ctx(ctx).With().Str("name", cluster.ID).Time("expiresAt", cluster.ExpiresAt).Logger()
+		cctx := logger.WithContext(ctx)
+
+		if !cluster.Expired(now) {
+			if cluster.ExpiresAt.Sub(now) <= r.warning && !r.warned[cluster.ID].Equal(cluster.ExpiresAt) {
+				logger.Info().Msg("Warning cluster is about to expire")
+				err = r.notifier.Notify(cctx, "", notifier.NewExpiryNotification(cluster))
+				if err != nil {
+					logger.Warn().Err(err).Msg("Failed to warn cluster is about to expire")
+				}
+				r.warned[cluster.ID] = cluster.ExpiresAt
+			}
+			continue
+		}
+
+		if !r.destroying.start(cluster.ID) {
+			continue
+		}
+		r.destroy(cctx, cluster, now, abandoned)
+		r.destroying.done(cluster.ID)
+	}
+
+	for id := range r.warned {
+		if _, ok := live[id]; !ok {
+			delete(r.warned, id)
+		}
+	}
+
+	return nil
+}
+
+// destroy destroys an expired cluster once it is claimed, marking it as
+// errored if it failed to be destroyed.
+func (r *Reaper) destroy(ctx context.Context, cluster metadata.Cluster, now time.Time, abandoned bool) {
+	logger := zerolog.Ctx(ctx)
+
+	claimed, err := r.claim(ctx, cluster.ID, now, abandoned)
+	if err != nil {
+		logger.Warn().Err(err).Msg("Failed to mark expired cluster as destroying")
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	logger.Info().Msg("Destroying expired cluster")
+	err = destroyCluster(ctx, r.db, r.providers, cluster)
+	if err != nil {
+		logger.Warn().Err(err).Msg("Failed to destroy expired cluster")
+		err = r.release(ctx, cluster.ID)
+		if err != nil {
+			logger.Warn().Err(err).Msg("Failed to mark expired cluster as errored")
+		}
+		return
+	}
+	logger.Info().Msg("Destroyed expired cluster")
+}
+
+// claim marks a cluster as destroying if it is still expired, not already
+// being destroyed and not running benchmarks, returning whether the reaper
+// should destroy it. Clusters marked destroying are only claimed if they are
+// presumed abandoned.
+func (r *Reaper) claim(ctx context.Context, id string, now time.Time, abandoned bool) (bool, error) {
+	var claimed bool
+	err := r.db.Update(ctx, func(tctx context.Context) error {
+		cluster, err := r.db.GetCluster(tctx, id)
+		if err != nil {
+			if errdefs.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+
+		if !cluster.Expired(now) || (cluster.Status == metadata.ClusterDestroying && !abandoned) {
+			return nil
+		}
+
+		benchmarks, err := r.db.ListBenchmarks(tctx)
+		if err != nil {
+			return err
+		}
+
+		var running []string
+		for _, benchmark := range benchmarks {
+			if benchmark.Cluster.ID == id && !benchmark.Status.Terminal() {
+				running = append(running, benchmark.ID)
+			}
+		}
+		if len(running) > 0 {
+			zerolog.Ctx(ctx).Info().Strs("benchmarks", running).Msg("Postponing expired cluster with running benchmarks")
+			return nil
+		}
+
+		cluster.Status = metadata.ClusterDestroying
+		_, err = r.db.UpdateCluster(tctx, cluster)
+		if err != nil {
+			return err
+		}
+
+		claimed = true
+		return nil
+	})
+	return claimed, err
+}
+
+// release marks a cluster that failed to be destroyed as errored, so that it
+// is claimed again on the next interval.
+func (r *Reaper) release(ctx context.Context, id string) error {
+	return r.db.Update(ctx, func(tctx context.Context) error {
+		cluster, err := r.db.GetCluster(tctx, id)
+		if err != nil {
+			if errdefs.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+
+		cluster.Status = metadata.ClusterError
+		_, err = r.db.UpdateCluster(tctx, cluster)
+		return err
+	})
+}
+
+// Destroying is the set of clusters being destroyed by this process, shared
+// by the cluster router and the reaper so that a cluster removed while the
+// reaper destroys it, or the other way around, is not destroyed twice.
+type Destroying struct {
+	mu  sync.Mutex
+	ids map[string]struct{}
+}
+
+func NewDestroying() *Destroying {
+	return &Destroying{
+		ids: make(map[string]struct{}),
+	}
+}
+
+// start adds a cluster to the set, returning false if it is already being
+// destroyed.
+func (d *Destroying) start(id string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.ids[id]; ok {
+		return false
+	}
+	d.ids[id] = struct{}{}
+	return true
+}
+
+// done removes a cluster from the set.
+func (d *Destroying) done(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.ids, id)
+}
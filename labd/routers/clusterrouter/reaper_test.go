@@ -0,0 +1,117 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterrouter
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReaperClaim(t *testing.T) {
+	root, err := ioutil.TempDir("", "p2plab-reaper")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	db, err := metadata.NewDB(root)
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	_, err = db.Migrate(ctx)
+	require.NoError(t, err)
+
+	now := time.Now()
+	for id, expiresAt := range map[string]time.Time{
+		"expired": now.Add(-time.Minute),
+		"live":    now.Add(time.Hour),
+		"forever": {},
+	} {
+		_, err = db.CreateCluster(ctx, metadata.Cluster{ID: id, Status: metadata.ClusterCreated, ExpiresAt: expiresAt})
+		require.NoError(t, err)
+	}
+
+	_, err = db.CreateCluster(ctx, metadata.Cluster{ID: "busy", Status: metadata.ClusterCreated, ExpiresAt: now.Add(-time.Minute)})
+	require.NoError(t, err)
+	_, err = db.CreateBenchmark(ctx, metadata.Benchmark{
+		ID:      "benchmark",
+		Status:  metadata.BenchmarkRunning,
+		Cluster: metadata.Cluster{ID: "busy"},
+	})
+	require.NoError(t, err)
+
+	r := NewReaper(db, nil, nil, NewDestroying(), time.Minute, time.Minute)
+	for id, expected := range map[string]bool{
+		"expired": true,
+		"live":    false,
+		"forever": false,
+		"missing": false,
+		"busy":    false,
+	} {
+		claimed, err := r.claim(ctx, id, now, false)
+		require.NoError(t, err)
+		require.Equal(t, expected, claimed, id)
+	}
+
+	// A cluster is claimed once its benchmarks finished.
+	benchmark, err := db.GetBenchmark(ctx, "benchmark")
+	require.NoError(t, err)
+	benchmark.Status = metadata.BenchmarkDone
+	_, err = db.UpdateBenchmark(ctx, benchmark)
+	require.NoError(t, err)
+
+	claimed, err := r.claim(ctx, "busy", now, false)
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	// A cluster being destroyed is never claimed twice, however long it takes.
+	claimed, err = r.claim(ctx, "expired", now.Add(time.Hour), false)
+	require.NoError(t, err)
+	require.False(t, claimed)
+
+	cluster, err := db.GetCluster(ctx, "expired")
+	require.NoError(t, err)
+	require.Equal(t, metadata.ClusterDestroying, cluster.Status)
+
+	// Unless it was abandoned by a previous labd.
+	claimed, err = r.claim(ctx, "expired", now, true)
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	// A cluster that failed to be destroyed is claimed again.
+	require.NoError(t, r.release(ctx, "expired"))
+	cluster, err = db.GetCluster(ctx, "expired")
+	require.NoError(t, err)
+	require.Equal(t, metadata.ClusterError, cluster.Status)
+
+	claimed, err = r.claim(ctx, "expired", now, false)
+	require.NoError(t, err)
+	require.True(t, claimed)
+}
+
+func TestDestroying(t *testing.T) {
+	d := NewDestroying()
+	require.True(t, d.start("a"))
+	require.False(t, d.start("a"))
+	require.True(t, d.start("b"))
+
+	d.done("a")
+	require.True(t, d.start("a"))
+}
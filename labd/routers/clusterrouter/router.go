@@ -18,30 +18,35 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/Netflix/p2plab"
 	"github.com/Netflix/p2plab/daemon"
+	"github.com/Netflix/p2plab/errdefs"
 	"github.com/Netflix/p2plab/labd/controlapi"
 	"github.com/Netflix/p2plab/metadata"
-	"github.com/Netflix/p2plab/nodes"
 	"github.com/Netflix/p2plab/pkg/httputil"
 	"github.com/Netflix/p2plab/pkg/logutil"
 	"github.com/Netflix/p2plab/pkg/stringutil"
+	"github.com/Netflix/p2plab/providers"
 	"github.com/Netflix/p2plab/query"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
-	bolt "go.etcd.io/bbolt"
 )
 
 type router struct {
-	db       metadata.DB
-	provider p2plab.NodeProvider
-	client   *httputil.Client
+	db         metadata.DB
+	providers  *providers.Providers
+	client     *httputil.Client
+	destroying *Destroying
 }
 
-func New(db metadata.DB, provider p2plab.NodeProvider, client *httputil.Client) daemon.Router {
-	return &router{db, provider, client}
+func New(db metadata.DB, providers *providers.Providers, client *httputil.Client, destroying *Destroying) daemon.Router {
+	return &router{db, providers, client, destroying}
 }
 
 func (s *router) Routes() []daemon.Route {
@@ -53,6 +58,8 @@ func (s *router) Routes() []daemon.Route {
 		daemon.NewPostRoute("/clusters/create", s.postClustersCreate),
 		// PUT
 		daemon.NewPutRoute("/clusters/label", s.putClustersLabel),
+		daemon.NewPutRoute("/clusters/{name}/scale", s.putClusterScale),
+		daemon.NewPutRoute("/clusters/{name}/extend", s.putClusterExtend),
 		// DELETE
 		daemon.NewDeleteRoute("/clusters/delete", s.deleteClusters),
 	}
@@ -64,7 +71,7 @@ func (s *router) getClusters(ctx context.Context, w http.ResponseWriter, r *http
 		return err
 	}
 
-	return daemon.WriteJSON(w, &matchedClusters)
+	return daemon.WriteCacheableJSON(w, r, &matchedClusters)
 }
 
 func (s *router) getCluster(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
@@ -74,7 +81,7 @@ func (s *router) getCluster(ctx context.Context, w http.ResponseWriter, r *http.
 		return err
 	}
 
-	return daemon.WriteJSON(w, &cluster)
+	return daemon.WriteCacheableJSON(w, r, &cluster)
 }
 
 func (s *router) postClustersCreate(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
@@ -84,19 +91,72 @@ func (s *router) postClustersCreate(ctx context.Context, w http.ResponseWriter,
 		return err
 	}
 
+	for i, group := range cdef.Groups {
+		err = metadata.ValidateUserLabels(group.Labels)
+		if err != nil {
+			return errors.Wrapf(err, "invalid labels for group %d", i)
+		}
+
+		if group.Peer == nil {
+			continue
+		}
+
+		err = group.Peer.Validate()
+		if err != nil {
+			return errors.Wrapf(err, "invalid libp2p stack for group %d", i)
+		}
+	}
+
+	if cdef.Provider == "" {
+		cdef.Provider = s.providers.Default()
+	}
+	provider, err := s.providers.Get(cdef.Provider)
+	if err != nil {
+		return err
+	}
+
+	labels := stringutil.Coalesce(strings.Split(r.FormValue("labels"), ","))
+	for _, label := range labels {
+		_, _, err = metadata.ParseKeyValueLabel(label)
+		if err != nil {
+			return err
+		}
+	}
+	err = metadata.ValidateUserLabels(labels)
+	if err != nil {
+		return err
+	}
+
+	ttl, err := parseTTL(r.FormValue("ttl"))
+	if err != nil {
+		return err
+	}
+
+	wait := true
+	if r.FormValue("wait") != "" {
+		wait, err = strconv.ParseBool(r.FormValue("wait"))
+		if err != nil {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid wait %q", r.FormValue("wait"))
+		}
+	}
+
 	name := r.FormValue("name")
+	daemonLogger := zerolog.Ctx(ctx).With().Str("name", name).Str("provider", cdef.Provider).Logger()
 	ctx, logger := logutil.WithResponseLogger(ctx, w)
 	logger.UpdateContext(func(c zerolog.Context) zerolog.Context {
-		return c.Str("name", name)
+		return c.Str("name", name).Str("provider", cdef.Provider)
 	})
 
 	cluster := metadata.Cluster{
 		ID:         name,
 		Status:     metadata.ClusterCreating,
 		Definition: cdef,
-		Labels: append([]string{
+		Labels: append(append([]string{
 			name,
-		}, cdef.GenerateLabels()...),
+		}, cdef.GenerateLabels()...), labels...),
+	}
+	if ttl > 0 {
+		cluster.ExpiresAt = time.Now().UTC().Add(ttl)
 	}
 
 	cluster, err = s.db.CreateCluster(ctx, cluster)
@@ -105,8 +165,47 @@ func (s *router) postClustersCreate(ctx context.Context, w http.ResponseWriter,
 	}
 	w.Header().Add(controlapi.ResourceID, name)
 
+	if !wait {
+		// The cluster outlives the request, so its progress is polled from the
+		// states of its nodes instead of streamed back.
+		go func() {
+			err := s.provision(daemonLogger.WithContext(context.Background()), provider, cluster)
+			if err != nil {
+				daemonLogger.Error().Err(err).Msg("Failed to provision cluster")
+			}
+		}()
+		return nil
+	}
+
+	return s.provision(ctx, provider, cluster)
+}
+
+// provision creates the nodes of a cluster and waits for them to be ready,
+// marking the cluster as errored if it fails.
+func (s *router) provision(ctx context.Context, provider p2plab.NodeProvider, cluster metadata.Cluster) error {
+	err := s.createNodes(ctx, provider, &cluster)
+	if err != nil {
+		cluster.Status = metadata.ClusterError
+		_, uerr := s.db.UpdateCluster(ctx, cluster)
+		if uerr != nil {
+			zerolog.Ctx(ctx).Warn().Err(uerr).Msg("Failed to update cluster status to error")
+		}
+		return err
+	}
+
+	zerolog.Ctx(ctx).Info().Msg("Updating cluster metadata")
+	cluster.Status = metadata.ClusterCreated
+	_, err = s.db.UpdateCluster(ctx, cluster)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *router) createNodes(ctx context.Context, provider p2plab.NodeProvider, cluster *metadata.Cluster) error {
 	zerolog.Ctx(ctx).Info().Msg("Creating node group")
-	ng, err := s.provider.CreateNodeGroup(ctx, name, cdef)
+	ng, err := provider.CreateNodeGroup(ctx, cluster.ID, cluster.Definition)
 	if err != nil {
 		return err
 	}
@@ -114,15 +213,14 @@ func (s *router) postClustersCreate(ctx context.Context, w http.ResponseWriter,
 	zerolog.Ctx(ctx).Info().Msg("Updating metadata with new nodes")
 	var mns []metadata.Node
 	cluster.Status = metadata.ClusterConnecting
-	err = s.db.Update(ctx, func(tx *bolt.Tx) error {
+	err = s.db.Update(ctx, func(tctx context.Context) error {
 		var err error
-		tctx := metadata.WithTransactionContext(ctx, tx)
-		cluster, err = s.db.UpdateCluster(tctx, cluster)
+		*cluster, err = s.db.UpdateCluster(tctx, *cluster)
 		if err != nil {
 			return err
 		}
 
-		mns, err = s.db.CreateNodes(tctx, cluster.ID, ng.Nodes)
+		mns, err = s.db.CreateNodes(tctx, cluster.ID, provisioning(ng.Nodes))
 		if err != nil {
 			return err
 		}
@@ -133,21 +231,66 @@ func (s *router) postClustersCreate(ctx context.Context, w http.ResponseWriter,
 		return err
 	}
 
-	var ns []p2plab.Node
-	for _, n := range mns {
-		ns = append(ns, controlapi.NewNode(s.client, n))
+	return s.waitReady(ctx, cluster.ID, mns)
+}
+
+// provisioning marks nodes created by a provider as provisioning.
+func provisioning(ns []metadata.Node) []metadata.Node {
+	for i := range ns {
+		ns[i].Status = metadata.NodeProvisioning
 	}
+	return ns
+}
 
-	err = nodes.WaitHealthy(ctx, ns)
-	if err != nil {
-		return err
+// waitReady waits for the agents of nodes to be healthy, recording whether
+// each node is ready or failed in its metadata. Unlike nodes.WaitHealthy, it
+// waits for every node so that each of them has a final state.
+func (s *router) waitReady(ctx context.Context, cluster string, mns []metadata.Node) error {
+	zerolog.Ctx(ctx).Info().Msg("Waiting for healthy nodes")
+	wctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go logutil.Elapsed(wctx, 20*time.Second, "Waiting for healthy nodes")
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		ready  int
+		failed []string
+	)
+	for _, n := range mns {
+		n := n
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			n.Status, n.Reason = metadata.NodeReady, ""
+			if !controlapi.NewNode(s.client, n).Healthcheck(ctx) {
+				n.Status, n.Reason = metadata.NodeFailed, "agent did not become healthy"
+				if ctx.Err() != nil {
+					n.Reason = ctx.Err().Error()
+				}
+			}
+
+			_, err := s.db.UpdateNode(ctx, cluster, n)
+			if err != nil {
+				zerolog.Ctx(ctx).Warn().Err(err).Str("node", n.ID).Msg("Failed to update node status")
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if n.Status == metadata.NodeFailed {
+				failed = append(failed, n.ID)
+				return
+			}
+			ready++
+			logutil.Progress(ctx, ready, len(mns), "Node is healthy")
+		}()
 	}
+	wg.Wait()
 
-	zerolog.Ctx(ctx).Info().Msg("Updating cluster metadata")
-	cluster.Status = metadata.ClusterCreated
-	_, err = s.db.UpdateCluster(ctx, cluster)
-	if err != nil {
-		return err
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return errors.Wrapf(errdefs.ErrUnavailable, "nodes %s failed to provision", strings.Join(failed, ","))
 	}
 
 	return nil
@@ -170,49 +313,246 @@ func (s *router) putClustersLabel(ctx context.Context, w http.ResponseWriter, r
 	return daemon.WriteJSON(w, &clusters)
 }
 
-func (s *router) deleteClusters(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
-	names := strings.Split(r.FormValue("names"), ",")
+func (s *router) putClusterScale(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	name := vars["name"]
+	size, err := strconv.Atoi(r.FormValue("size"))
+	if err != nil || size < 1 {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid cluster size %q", r.FormValue("size"))
+	}
 
 	ctx, logger := logutil.WithResponseLogger(ctx, w)
+	logger.UpdateContext(func(c zerolog.Context) zerolog.Context {
+		return c.Str("name", name).Int("size", size)
+	})
 
-	// TODO: parallelize with different color loggers?
-	for _, name := range names {
-		logger := logger.With().Str("name", name).Logger()
-		ctx = logger.WithContext(ctx)
+	cluster, err := s.db.GetCluster(ctx, name)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get cluster %q", name)
+	}
+
+	if len(cluster.Definition.Groups) == 0 {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "cluster %q has no cluster groups to scale", name)
+	}
+
+	ns, err := s.db.ListNodes(ctx, cluster.ID)
+	if err != nil {
+		return errors.Wrap(err, "failed to list nodes")
+	}
+
+	ng := &p2plab.NodeGroup{
+		ID:    cluster.ID,
+		Nodes: ns,
+	}
+
+	delta := size - len(ns)
+	switch {
+	case delta > 0:
+		return s.addNodes(ctx, cluster, ng, delta)
+	case delta < 0:
+		return s.removeNodes(ctx, cluster, ng, -delta)
+	default:
+		zerolog.Ctx(ctx).Info().Msg("Cluster is already at the requested size")
+		return nil
+	}
+}
 
-		cluster, err := s.db.GetCluster(ctx, name)
+func (s *router) putClusterExtend(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	name := vars["name"]
+	ttl, err := parseTTL(r.FormValue("ttl"))
+	if err != nil {
+		return err
+	}
+	if ttl == 0 {
+		return errors.Wrap(errdefs.ErrInvalidArgument, "ttl required to extend a cluster")
+	}
+
+	var cluster metadata.Cluster
+	err = s.db.Update(ctx, func(tctx context.Context) error {
+		var err error
+		cluster, err = s.db.GetCluster(tctx, name)
 		if err != nil {
-			return errors.Wrapf(err, "failed to get cluster %q", name)
+			return err
 		}
 
-		if cluster.Status != metadata.ClusterDestroying {
-			cluster.Status = metadata.ClusterDestroying
-			cluster, err = s.db.UpdateCluster(ctx, cluster)
-			if err != nil {
-				return errors.Wrap(err, "failed to update cluster status to destroying")
-			}
+		if cluster.Status == metadata.ClusterDestroying {
+			return errors.Wrapf(errdefs.ErrFailedPrecondition, "cluster %q is being destroyed", name)
+		}
+
+		// Extend from the current expiry, unless there is none or it already
+		// passed, so that extending twice adds up.
+		expiresAt := time.Now().UTC()
+		if cluster.ExpiresAt.After(expiresAt) {
+			expiresAt = cluster.ExpiresAt
+		}
+		cluster.ExpiresAt = expiresAt.Add(ttl)
+
+		cluster, err = s.db.UpdateCluster(tctx, cluster)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return daemon.WriteJSON(w, &cluster)
+}
+
+func (s *router) addNodes(ctx context.Context, cluster metadata.Cluster, ng *p2plab.NodeGroup, n int) error {
+	// New nodes are added to the last cluster group.
+	cluster.Definition.Groups[len(cluster.Definition.Groups)-1].Size += n
+
+	provider, err := s.providers.Get(cluster.Definition.Provider)
+	if err != nil {
+		return err
+	}
+
+	zerolog.Ctx(ctx).Info().Int("nodes", n).Msg("Adding nodes")
+	added, err := provider.AddNodes(ctx, ng, cluster.Definition)
+	if err != nil {
+		return errors.Wrap(err, "failed to add nodes")
+	}
+
+	zerolog.Ctx(ctx).Info().Msg("Updating metadata with new nodes")
+	var mns []metadata.Node
+	err = s.db.Update(ctx, func(tctx context.Context) error {
+		var err error
+		_, err = s.db.UpdateCluster(tctx, cluster)
+		if err != nil {
+			return err
+		}
+
+		mns, err = s.db.CreateNodes(tctx, cluster.ID, provisioning(added))
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.waitReady(ctx, cluster.ID, mns)
+}
+
+func (s *router) removeNodes(ctx context.Context, cluster metadata.Cluster, ng *p2plab.NodeGroup, n int) error {
+	busy, err := s.busyNodes(ctx, cluster.ID)
+	if err != nil {
+		return err
+	}
+
+	// Prefer draining idle nodes first, then the most recently created ones.
+	candidates := make([]metadata.Node, len(ng.Nodes))
+	copy(candidates, ng.Nodes)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		_, ibusy := busy[candidates[i].ID]
+		_, jbusy := busy[candidates[j].ID]
+		if ibusy != jbusy {
+			return !ibusy
 		}
+		return candidates[i].CreatedAt.After(candidates[j].CreatedAt)
+	})
+
+	var (
+		removed []metadata.Node
+		ids     []string
+	)
+	for _, node := range candidates[:n] {
+		i := cluster.Definition.NodeGroupIndex(node)
+		if i < 0 {
+			return errors.Wrapf(errdefs.ErrNotFound, "no cluster group for node %q", node.ID)
+		}
+		cluster.Definition.Groups[i].Size--
 
-		ns, err := s.db.ListNodes(ctx, cluster.ID)
+		if _, ok := busy[node.ID]; ok {
+			zerolog.Ctx(ctx).Warn().Str("node", node.ID).Msg("Removing node used by an active benchmark")
+		}
+		removed = append(removed, node)
+		ids = append(ids, node.ID)
+	}
+
+	provider, err := s.providers.Get(cluster.Definition.Provider)
+	if err != nil {
+		return err
+	}
+
+	zerolog.Ctx(ctx).Info().Strs("nodes", ids).Msg("Removing nodes")
+	err = provider.RemoveNodes(ctx, ng, cluster.Definition, removed)
+	if err != nil {
+		return errors.Wrap(err, "failed to remove nodes")
+	}
+
+	zerolog.Ctx(ctx).Info().Msg("Updating metadata with removed nodes")
+	return s.db.Update(ctx, func(tctx context.Context) error {
+		_, err := s.db.UpdateCluster(tctx, cluster)
 		if err != nil {
-			return errors.Wrap(err, "failed to list nodes")
+			return err
+		}
+
+		return s.db.DeleteNodes(tctx, cluster.ID, ids...)
+	})
+}
+
+// busyNodes returns the IDs of nodes that have tasks in a benchmark that is
+// still planning or running on the cluster.
+func (s *router) busyNodes(ctx context.Context, cluster string) (map[string]struct{}, error) {
+	bs, err := s.db.ListBenchmarks(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list benchmarks")
+	}
+
+	busy := make(map[string]struct{})
+	for _, b := range bs {
+		if b.Cluster.ID != cluster {
+			continue
+		}
+		if b.Status != metadata.BenchmarkPlanning && b.Status != metadata.BenchmarkRunning {
+			continue
+		}
+
+		for _, stage := range []metadata.ScenarioStage{b.Plan.Seed, b.Plan.Benchmark} {
+			for id := range stage {
+				busy[id] = struct{}{}
+			}
 		}
+	}
+
+	return busy, nil
+}
 
-		ng := &p2plab.NodeGroup{
-			ID:    cluster.ID,
-			Nodes: ns,
+func (s *router) deleteClusters(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	names := strings.Split(r.FormValue("names"), ",")
+
+	removal, err := daemon.ParseRemoval(r)
+	if err != nil {
+		return err
+	}
+
+	if !removal.Force {
+		dependents, err := metadata.ClusterDependents(ctx, s.db, names...)
+		if err != nil {
+			return err
 		}
 
-		logger.Info().Msg("Destroying node group")
-		err = s.provider.DestroyNodeGroup(ctx, ng)
+		if removal.Cascade {
+			err = metadata.DeleteBenchmarksCascade(ctx, s.db, dependents.IDs()...)
+		} else {
+			err = dependents.Err("cluster", "benchmarks")
+		}
 		if err != nil {
-			return errors.Wrap(err, "failed to destroy node group")
+			return err
 		}
+	}
+
+	ctx, logger := logutil.WithResponseLogger(ctx, w)
+
+	// TODO: parallelize with different color loggers?
+	for _, name := range names {
+		logger := logger.With().Str("name", name).Logger()
+		ctx = logger.WithContext(ctx)
 
-		logger.Info().Msg("Deleting cluster metadata")
-		err = s.db.DeleteCluster(ctx, cluster.ID)
+		err := s.deleteCluster(ctx, name)
 		if err != nil {
-			return errors.Wrap(err, "failed to delete cluster metadata")
+			return err
 		}
 
 		logger.Info().Msg("Destroyed cluster")
@@ -221,6 +561,76 @@ func (s *router) deleteClusters(ctx context.Context, w http.ResponseWriter, r *h
 	return nil
 }
 
+// deleteCluster destroys a cluster, unless it is already being destroyed by
+// labd. Clusters left destroying by a previous labd are destroyed again.
+func (s *router) deleteCluster(ctx context.Context, name string) error {
+	if !s.destroying.start(name) {
+		return errors.Wrapf(errdefs.ErrFailedPrecondition, "cluster %q is being destroyed", name)
+	}
+	defer s.destroying.done(name)
+
+	cluster, err := s.db.GetCluster(ctx, name)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get cluster %q", name)
+	}
+
+	if cluster.Status != metadata.ClusterDestroying {
+		cluster.Status = metadata.ClusterDestroying
+		cluster, err = s.db.UpdateCluster(ctx, cluster)
+		if err != nil {
+			return errors.Wrap(err, "failed to update cluster status to destroying")
+		}
+	}
+
+	return destroyCluster(ctx, s.db, s.providers, cluster)
+}
+
+// parseTTL parses the TTL of a cluster, which is zero if s is empty.
+func parseTTL(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	ttl, err := time.ParseDuration(s)
+	if err != nil || ttl <= 0 {
+		return 0, errors.Wrapf(errdefs.ErrInvalidArgument, "invalid ttl %q", s)
+	}
+	return ttl, nil
+}
+
+// destroyCluster destroys the node group of a cluster being destroyed and
+// deletes its metadata.
+func destroyCluster(ctx context.Context, db metadata.DB, ps *providers.Providers, cluster metadata.Cluster) error {
+	ns, err := db.ListNodes(ctx, cluster.ID)
+	if err != nil {
+		return errors.Wrap(err, "failed to list nodes")
+	}
+
+	ng := &p2plab.NodeGroup{
+		ID:    cluster.ID,
+		Nodes: ns,
+	}
+
+	provider, err := ps.Get(cluster.Definition.Provider)
+	if err != nil {
+		return err
+	}
+
+	zerolog.Ctx(ctx).Info().Msg("Destroying node group")
+	err = provider.DestroyNodeGroup(ctx, ng)
+	if err != nil {
+		return errors.Wrap(err, "failed to destroy node group")
+	}
+
+	zerolog.Ctx(ctx).Info().Msg("Deleting cluster metadata")
+	err = db.DeleteCluster(ctx, cluster.ID)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete cluster metadata")
+	}
+
+	return nil
+}
+
 func (s *router) matchClusters(ctx context.Context, q string) ([]metadata.Cluster, error) {
 	cs, err := s.db.ListClusters(ctx)
 	if err != nil {
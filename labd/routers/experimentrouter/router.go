@@ -16,32 +16,35 @@ package experimentrouter
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/Netflix/p2plab"
 	"github.com/Netflix/p2plab/daemon"
+	"github.com/Netflix/p2plab/errdefs"
 	"github.com/Netflix/p2plab/metadata"
 	"github.com/Netflix/p2plab/peer"
 	"github.com/Netflix/p2plab/pkg/httputil"
 	"github.com/Netflix/p2plab/pkg/stringutil"
+	"github.com/Netflix/p2plab/providers"
 	"github.com/Netflix/p2plab/query"
 	"github.com/Netflix/p2plab/transformers"
-	"github.com/containerd/containerd/errdefs"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 )
 
 type router struct {
 	db       metadata.DB
-	provider p2plab.NodeProvider
+	provider *providers.Providers
 	client   *httputil.Client
 	ts       *transformers.Transformers
 	seeder   *peer.Peer
 	builder  p2plab.Builder
 }
 
-func New(db metadata.DB, provider p2plab.NodeProvider, client *httputil.Client, ts *transformers.Transformers, seeder *peer.Peer, builder p2plab.Builder) daemon.Router {
+func New(db metadata.DB, provider *providers.Providers, client *httputil.Client, ts *transformers.Transformers, seeder *peer.Peer, builder p2plab.Builder) daemon.Router {
 	return &router{db, provider, client, ts, seeder, builder}
 }
 
@@ -52,8 +55,10 @@ func (s *router) Routes() []daemon.Route {
 		daemon.NewGetRoute("/experiments/{id}/json", s.getExperimentByName),
 		// POST
 		daemon.NewPostRoute("/experiments/create", s.postExperimentsCreate),
+		daemon.NewPostRoute("/experiments/sweep", s.postExperimentsSweep),
 		// PUT
 		daemon.NewPutRoute("/experiments/label", s.putExperimentsLabel),
+		daemon.NewPutRoute("/experiments/{id}/trials/{index}", s.putExperimentTrial),
 		// DELETE
 		daemon.NewDeleteRoute("/experiments/delete", s.deleteExperiments),
 	}
@@ -69,7 +74,7 @@ func (s *router) getExperiments(ctx context.Context, w http.ResponseWriter, r *h
 }
 
 func (s *router) getExperimentByName(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
-	id := vars["name"]
+	id := vars["id"]
 	experiment, err := s.db.GetExperiment(ctx, id)
 	if err != nil {
 		return err
@@ -82,6 +87,114 @@ func (s *router) postExperimentsCreate(ctx context.Context, w http.ResponseWrite
 	return errors.New("unimplemented")
 }
 
+// postExperimentsSweep creates a running experiment with the trials of a
+// parameter sweep. The trials are run by the client, which records each one as
+// it finishes.
+func (s *router) postExperimentsSweep(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	id := r.FormValue("id")
+	if id == "" {
+		return errors.Wrap(errdefs.ErrInvalidArgument, "experiment id required")
+	}
+
+	var trials []metadata.ExperimentTrial
+	err := json.NewDecoder(r.Body).Decode(&trials)
+	if err != nil {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid trials: %s", err)
+	}
+
+	if len(trials) == 0 {
+		return errors.Wrap(errdefs.ErrInvalidArgument, "sweep must have at least one trial")
+	}
+
+	for i, trial := range trials {
+		if len(trial.Parameters) == 0 {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "trial %d has no parameters", i)
+		}
+		if trial.Done() {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "trial %d is already done", i)
+		}
+	}
+
+	experiment, err := s.db.CreateExperiment(ctx, metadata.Experiment{
+		ID:     id,
+		Status: metadata.ExperimentRunning,
+		Trials: trials,
+		Labels: []string{
+			id,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return daemon.WriteJSON(w, &experiment)
+}
+
+// putExperimentTrial records the outcome of a trial of a sweep. Once every
+// trial is done, the experiment is done, or errored if any trial failed.
+func (s *router) putExperimentTrial(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	id := vars["id"]
+	index, err := strconv.Atoi(vars["index"])
+	if err != nil {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid trial index %q", vars["index"])
+	}
+
+	var trial metadata.ExperimentTrial
+	err = json.NewDecoder(r.Body).Decode(&trial)
+	if err != nil {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid trial: %s", err)
+	}
+
+	if !trial.Done() {
+		return errors.Wrap(errdefs.ErrInvalidArgument, "trial must have a benchmark or an error")
+	}
+
+	var experiment metadata.Experiment
+	err = s.db.Update(ctx, func(tctx context.Context) error {
+		experiment, err = s.db.GetExperiment(tctx, id)
+		if err != nil {
+			return err
+		}
+
+		if experiment.Status != metadata.ExperimentRunning {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "experiment status %q", experiment.Status)
+		}
+
+		if index < 0 || index >= len(experiment.Trials) {
+			return errors.Wrapf(errdefs.ErrNotFound, "trial %d of experiment %q", index, id)
+		}
+
+		trial.Parameters = experiment.Trials[index].Parameters
+		experiment.Trials[index] = trial
+
+		done, failed := 0, 0
+		for _, t := range experiment.Trials {
+			if t.Done() {
+				done++
+			}
+			if t.Error != "" {
+				failed++
+			}
+		}
+
+		if done == len(experiment.Trials) {
+			experiment.Status = metadata.ExperimentDone
+			if failed > 0 {
+				experiment.Status = metadata.ExperimentError
+			}
+		}
+
+		experiment, err = s.db.UpdateExperiment(tctx, experiment)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	zerolog.Ctx(ctx).Info().Str("experiment", id).Int("trial", index).Str("status", string(experiment.Status)).Msg("Recorded trial")
+	return daemon.WriteJSON(w, &experiment)
+}
+
 func (s *router) putExperimentsLabel(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	ids := strings.Split(r.FormValue("ids"), ",")
 	addLabels := stringutil.Coalesce(strings.Split(r.FormValue("adds"), ","))
@@ -113,7 +226,7 @@ func (s *router) deleteExperiments(ctx context.Context, w http.ResponseWriter, r
 		switch experiment.Status {
 		case metadata.ExperimentDone, metadata.ExperimentError:
 		default:
-			return errors.Wrapf(errdefs.ErrFailedPrecondition, "experiment status %q", experiment.Status)
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "experiment status %q", experiment.Status)
 		}
 
 		logger.Info().Msg("Deleting experiment")
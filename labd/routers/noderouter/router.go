@@ -18,24 +18,35 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/Netflix/p2plab"
 	"github.com/Netflix/p2plab/daemon"
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/labd/controlapi"
 	"github.com/Netflix/p2plab/metadata"
 	"github.com/Netflix/p2plab/pkg/httputil"
 	"github.com/Netflix/p2plab/pkg/stringutil"
 	"github.com/Netflix/p2plab/query"
-	bolt "go.etcd.io/bbolt"
+	peerstore "github.com/libp2p/go-libp2p-peerstore"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
 )
 
+// connectConcurrency is the maximum number of requests made to the nodes at
+// once when connecting them.
+const connectConcurrency = 32
+
 type router struct {
-	db     metadata.DB
-	client *httputil.Client
+	db          metadata.DB
+	client      *httputil.Client
+	maxPageSize int
 }
 
-func New(db metadata.DB, client *httputil.Client) daemon.Router {
-	return &router{db, client}
+func New(db metadata.DB, client *httputil.Client, maxPageSize int) daemon.Router {
+	return &router{db, client, maxPageSize}
 }
 
 func (s *router) Routes() []daemon.Route {
@@ -46,17 +57,53 @@ func (s *router) Routes() []daemon.Route {
 		// PUT
 		daemon.NewPutRoute("/clusters/{name}/nodes/label", s.putNodesLabel),
 		daemon.NewPutRoute("/clusters/{name}/nodes/update", s.putNodesUpdate),
+		daemon.NewPutRoute("/clusters/{name}/nodes/connect", s.putNodesConnect),
+		daemon.NewPutRoute("/clusters/{name}/nodes/ping", s.putNodesPing),
+		daemon.NewPutRoute("/clusters/{name}/nodes/bench-link", s.putNodesBenchLink),
 	}
 }
 
 func (s *router) getNodes(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	page, err := daemon.ParsePage(r, s.maxPageSize)
+	if err != nil {
+		return err
+	}
+
+	field, desc, err := daemon.ParseOrder(r)
+	if err != nil {
+		return err
+	}
+
+	fields := stringutil.Coalesce(strings.Split(r.FormValue("fields"), ","))
+	_, err = metadata.LookupFields(metadata.Node{}, fields)
+	if err != nil {
+		return err
+	}
+
 	clusterId := vars["name"]
-	matchedNodes, err := s.matchNodes(ctx, clusterId, r.FormValue("query"))
+	matchedNodes, err := s.matchNodes(ctx, clusterId, r.FormValue("query"), metadata.WithOrderBy(field, desc))
 	if err != nil {
 		return err
 	}
 
-	return daemon.WriteJSON(w, &matchedNodes)
+	start, end, next := page.Window(len(matchedNodes), func(i int) string {
+		return matchedNodes[i].ID
+	})
+	matchedNodes = matchedNodes[start:end]
+
+	daemon.SetNextPageToken(w, next)
+	if len(fields) == 0 {
+		return daemon.WriteList(w, r, &matchedNodes)
+	}
+
+	projections := make([]map[string]json.RawMessage, len(matchedNodes))
+	for i, n := range matchedNodes {
+		projections[i], err = metadata.ProjectJSON(n, fields)
+		if err != nil {
+			return err
+		}
+	}
+	return daemon.WriteList(w, r, &projections)
 }
 
 func (s *router) getNodeById(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
@@ -73,10 +120,13 @@ func (s *router) putNodesLabel(ctx context.Context, w http.ResponseWriter, r *ht
 	ids := strings.Split(r.FormValue("ids"), ",")
 	addLabels := stringutil.Coalesce(strings.Split(r.FormValue("adds"), ","))
 	removeLabels := stringutil.Coalesce(strings.Split(r.FormValue("removes"), ","))
+	err := metadata.ValidateUserLabels(addLabels)
+	if err != nil {
+		return err
+	}
 
 	var nodes []metadata.Node
 	if len(addLabels) > 0 || len(removeLabels) > 0 {
-		var err error
 		clusterId := vars["name"]
 		nodes, err = s.db.LabelNodes(ctx, clusterId, ids, addLabels, removeLabels)
 		if err != nil {
@@ -101,8 +151,7 @@ func (s *router) putNodesUpdate(ctx context.Context, w http.ResponseWriter, r *h
 	}
 
 	var ns []metadata.Node
-	err = s.db.Update(ctx, func(tx *bolt.Tx) error {
-		tctx := metadata.WithTransactionContext(ctx, tx)
+	err = s.db.Update(ctx, func(tctx context.Context) error {
 
 		for _, n := range matchedNodes {
 			if pdef.GitReference != "" {
@@ -119,9 +168,17 @@ func (s *router) putNodesUpdate(ctx context.Context, w http.ResponseWriter, r *h
 			}
 			if pdef.Routing != "" {
 				n.Peer.Routing = pdef.Routing
+				n.Peer.RoutingEndpoint = pdef.RoutingEndpoint
+			}
+			if pdef.Datastore != "" {
+				n.Peer.Datastore = pdef.Datastore
+			}
+
+			err := n.Peer.Validate()
+			if err != nil {
+				return errors.Wrapf(err, "invalid libp2p stack for node %q", n.ID)
 			}
 
-			var err error
 			n, err = s.db.UpdateNode(tctx, clusterId, n)
 			if err != nil {
 				return err
@@ -138,8 +195,257 @@ func (s *router) putNodesUpdate(ctx context.Context, w http.ResponseWriter, r *h
 	return daemon.WriteJSON(w, &ns)
 }
 
-func (s *router) matchNodes(ctx context.Context, clusterId, q string) ([]metadata.Node, error) {
-	ns, err := s.db.ListNodes(ctx, clusterId)
+func (s *router) putNodesConnect(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	disconnect := false
+	if r.FormValue("disconnect") != "" {
+		var err error
+		disconnect, err = strconv.ParseBool(r.FormValue("disconnect"))
+		if err != nil {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid disconnect %q", r.FormValue("disconnect"))
+		}
+	}
+
+	clusterId := vars["name"]
+	sources, err := s.matchNodes(ctx, clusterId, r.FormValue("query"))
+	if err != nil {
+		return err
+	}
+
+	targets, err := s.matchNodes(ctx, clusterId, r.FormValue("to"))
+	if err != nil {
+		return err
+	}
+
+	peerInfos, peerErrs := s.peerInfos(ctx, targets, connectConcurrency)
+
+	var conns []metadata.NodeConnection
+	for _, source := range sources {
+		for _, target := range targets {
+			if source.ID == target.ID {
+				continue
+			}
+			conns = append(conns, metadata.NodeConnection{
+				Source: source.ID,
+				Target: target.ID,
+			})
+		}
+	}
+
+	targetIndex := make(map[string]int)
+	for i, target := range targets {
+		targetIndex[target.ID] = i
+	}
+
+	sourceNodes := make(map[string]p2plab.Node)
+	for _, source := range sources {
+		sourceNodes[source.ID] = controlapi.NewNode(s.client, source)
+	}
+
+	s.fanOut(len(conns), connectConcurrency, func(i int) {
+		conn := &conns[i]
+		t := targetIndex[conn.Target]
+		if peerErrs[t] != nil {
+			conn.Error = peerErrs[t].Error()
+			return
+		}
+
+		n := sourceNodes[conn.Source]
+		pis := []peerstore.PeerInfo{peerInfos[t]}
+
+		var err error
+		if disconnect {
+			err = n.Disconnect(ctx, pis)
+		} else {
+			err = n.Connect(ctx, pis)
+		}
+		if err != nil {
+			zerolog.Ctx(ctx).Warn().Err(err).Str("source", conn.Source).Str("target", conn.Target).Msg("Failed to connect nodes")
+			conn.Error = err.Error()
+		}
+	})
+
+	return daemon.WriteJSON(w, &conns)
+}
+
+func (s *router) putNodesPing(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	count := 1
+	if r.FormValue("count") != "" {
+		var err error
+		count, err = strconv.Atoi(r.FormValue("count"))
+		if err != nil || count <= 0 {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid count %q", r.FormValue("count"))
+		}
+	}
+
+	parallel := connectConcurrency
+	if r.FormValue("parallel") != "" {
+		var err error
+		parallel, err = strconv.Atoi(r.FormValue("parallel"))
+		if err != nil || parallel <= 0 {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid parallel %q", r.FormValue("parallel"))
+		}
+	}
+
+	clusterId := vars["name"]
+	ns, err := s.matchNodes(ctx, clusterId, r.FormValue("query"))
+	if err != nil {
+		return err
+	}
+
+	peerInfos, peerErrs := s.peerInfos(ctx, ns, parallel)
+
+	var pings []metadata.NodePing
+	var pairs [][2]int
+	for i, source := range ns {
+		for j, target := range ns {
+			if i == j {
+				continue
+			}
+			pings = append(pings, metadata.NodePing{
+				Source: source.ID,
+				Target: target.ID,
+			})
+			pairs = append(pairs, [2]int{i, j})
+		}
+	}
+
+	s.fanOut(len(pings), parallel, func(k int) {
+		ping := &pings[k]
+		i, j := pairs[k][0], pairs[k][1]
+		if peerErrs[j] != nil {
+			ping.Error = peerErrs[j].Error()
+			return
+		}
+
+		n := controlapi.NewNode(s.client, ns[i])
+		rtts, err := n.Ping(ctx, peerInfos[j], count)
+		if err != nil {
+			zerolog.Ctx(ctx).Warn().Err(err).Str("source", ping.Source).Str("target", ping.Target).Msg("Failed to ping node")
+			ping.Error = err.Error()
+			return
+		}
+		ping.RTTs = rtts
+	})
+
+	return daemon.WriteJSON(w, &pings)
+}
+
+func (s *router) putNodesBenchLink(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	size, err := strconv.ParseInt(r.FormValue("size"), 10, 64)
+	if err != nil || size <= 0 {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid size %q", r.FormValue("size"))
+	}
+
+	bidirectional := false
+	if r.FormValue("bidirectional") != "" {
+		bidirectional, err = strconv.ParseBool(r.FormValue("bidirectional"))
+		if err != nil {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid bidirectional %q", r.FormValue("bidirectional"))
+		}
+	}
+
+	clusterId := vars["name"]
+	sources, err := s.matchNodes(ctx, clusterId, r.FormValue("query"))
+	if err != nil {
+		return err
+	}
+
+	targets, err := s.matchNodes(ctx, clusterId, r.FormValue("to"))
+	if err != nil {
+		return err
+	}
+
+	var (
+		ns    []metadata.Node
+		index = make(map[string]int)
+	)
+	for _, n := range append(sources, targets...) {
+		if _, ok := index[n.ID]; !ok {
+			index[n.ID] = len(ns)
+			ns = append(ns, n)
+		}
+	}
+
+	var links []metadata.NodeLink
+	seen := make(map[[2]string]struct{})
+	addLink := func(source, target string) {
+		pair := [2]string{source, target}
+		if _, ok := seen[pair]; ok || source == target {
+			return
+		}
+		seen[pair] = struct{}{}
+		links = append(links, metadata.NodeLink{Source: source, Target: target, Bytes: size})
+	}
+	for _, source := range sources {
+		for _, target := range targets {
+			addLink(source.ID, target.ID)
+			if bidirectional {
+				addLink(target.ID, source.ID)
+			}
+		}
+	}
+
+	peerInfos, peerErrs := s.peerInfos(ctx, ns, connectConcurrency)
+
+	// Links are measured one at a time, so that transfers don't compete for
+	// the bandwidth of nodes they share.
+	for i := range links {
+		link := &links[i]
+		t := index[link.Target]
+		if peerErrs[t] != nil {
+			link.Error = peerErrs[t].Error()
+			continue
+		}
+
+		n := controlapi.NewNode(s.client, ns[index[link.Source]])
+		elapsed, err := n.BenchLink(ctx, peerInfos[t], size)
+		if err != nil {
+			zerolog.Ctx(ctx).Warn().Err(err).Str("source", link.Source).Str("target", link.Target).Msg("Failed to benchmark link")
+			link.Error = err.Error()
+			continue
+		}
+		link.Duration = elapsed
+	}
+
+	return daemon.WriteJSON(w, &links)
+}
+
+// peerInfos retrieves the peer info of every node once, and remembers the
+// failures so that every pair involving the node can report it.
+func (s *router) peerInfos(ctx context.Context, ns []metadata.Node, concurrency int) ([]peerstore.PeerInfo, []error) {
+	peerInfos := make([]peerstore.PeerInfo, len(ns))
+	peerErrs := make([]error, len(ns))
+	s.fanOut(len(ns), concurrency, func(i int) {
+		n := controlapi.NewNode(s.client, ns[i])
+		peerInfos[i], peerErrs[i] = n.PeerInfo(ctx)
+		if peerErrs[i] == nil && len(peerInfos[i].Addrs) == 0 {
+			peerErrs[i] = errors.Errorf("peer %q has zero addresses", ns[i].Address)
+		}
+	})
+	return peerInfos, peerErrs
+}
+
+// fanOut calls fn for every index in [0, n) with at most concurrency calls in
+// flight.
+func (s *router) fanOut(n, concurrency int, fn func(i int)) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+func (s *router) matchNodes(ctx context.Context, clusterId, q string, opts ...metadata.ListOption) ([]metadata.Node, error) {
+	ns, err := s.db.ListNodes(ctx, clusterId, opts...)
 	if err != nil {
 		return nil, err
 	}
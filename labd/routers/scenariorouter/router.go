@@ -16,7 +16,7 @@ package scenariorouter
 
 import (
 	"context"
-	"encoding/json"
+	"io/ioutil"
 	"net/http"
 	"strings"
 
@@ -45,6 +45,7 @@ func (s *router) Routes() []daemon.Route {
 		daemon.NewPostRoute("/scenarios/create", s.postScenariosCreate),
 		// PUT
 		daemon.NewPutRoute("/scenarios/label", s.putScenariosLabel),
+		daemon.NewPutRoute("/scenarios/{name}", s.putScenario),
 		// DELETE
 		daemon.NewDeleteRoute("/scenarios/delete", s.deleteScenarios),
 	}
@@ -56,7 +57,7 @@ func (s *router) getScenarios(ctx context.Context, w http.ResponseWriter, r *htt
 		return err
 	}
 
-	return daemon.WriteJSON(w, &scenarios)
+	return daemon.WriteCacheableJSON(w, r, &scenarios)
 }
 
 func (s *router) getScenarioByName(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
@@ -66,12 +67,16 @@ func (s *router) getScenarioByName(ctx context.Context, w http.ResponseWriter, r
 		return err
 	}
 
-	return daemon.WriteJSON(w, &scenario)
+	return daemon.WriteCacheableJSON(w, r, &scenario)
 }
 
 func (s *router) postScenariosCreate(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
-	var sdef metadata.ScenarioDefinition
-	err := json.NewDecoder(r.Body).Decode(&sdef)
+	content, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+
+	sdef, err := metadata.ParseScenarioDefinition(content)
 	if err != nil {
 		return err
 	}
@@ -94,6 +99,40 @@ func (s *router) postScenariosCreate(ctx context.Context, w http.ResponseWriter,
 	return daemon.WriteJSON(w, &scenario)
 }
 
+// putScenario replaces the definition of an existing scenario, keeping its
+// labels. Benchmarks already run keep the definition they ran with.
+func (s *router) putScenario(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	content, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+
+	sdef, err := metadata.ParseScenarioDefinition(content)
+	if err != nil {
+		return err
+	}
+
+	name := vars["name"]
+	var scenario metadata.Scenario
+	err = s.db.Update(ctx, func(tctx context.Context) error {
+		var err error
+		scenario, err = s.db.GetScenario(tctx, name)
+		if err != nil {
+			return err
+		}
+
+		zerolog.Ctx(ctx).Info().Str("scenario", name).Msg("Updating scenario")
+		scenario.Definition = sdef
+		scenario, err = s.db.UpdateScenario(tctx, scenario)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return daemon.WriteJSON(w, &scenario)
+}
+
 func (s *router) putScenariosLabel(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	names := strings.Split(r.FormValue("names"), ",")
 	addLabels := stringutil.Coalesce(strings.Split(r.FormValue("adds"), ","))
@@ -114,8 +153,29 @@ func (s *router) putScenariosLabel(ctx context.Context, w http.ResponseWriter, r
 func (s *router) deleteScenarios(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	ids := strings.Split(r.FormValue("names"), ",")
 
+	removal, err := daemon.ParseRemoval(r)
+	if err != nil {
+		return err
+	}
+
+	if !removal.Force {
+		dependents, err := metadata.ScenarioDependents(ctx, s.db, ids...)
+		if err != nil {
+			return err
+		}
+
+		if removal.Cascade {
+			err = metadata.DeleteBenchmarksCascade(ctx, s.db, dependents.IDs()...)
+		} else {
+			err = dependents.Err("scenario", "benchmarks")
+		}
+		if err != nil {
+			return err
+		}
+	}
+
 	zerolog.Ctx(ctx).Info().Strs("scenarios", ids).Msg("Deleting scenarios")
-	err := s.db.DeleteScenarios(ctx, ids...)
+	err = s.db.DeleteScenarios(ctx, ids...)
 	if err != nil {
 		return err
 	}
@@ -15,8 +15,16 @@
 package labd
 
 import (
+	"strings"
+	"time"
+
+	"github.com/Netflix/p2plab/daemon"
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/Netflix/p2plab/pkg/logutil"
 	"github.com/Netflix/p2plab/providers"
 	"github.com/Netflix/p2plab/uploaders"
+	"github.com/pkg/errors"
 )
 
 type LabdOption func(*LabdSettings) error
@@ -27,6 +35,55 @@ type LabdSettings struct {
 	ProviderSettings providers.ProviderSettings
 	Uploader         string
 	UploaderSettings uploaders.UploaderSettings
+	MetadataBackend  string
+	MetadataDSN      string
+	MaxPageSize      int
+
+	// IdempotencyWindow is how long idempotency keys of benchmark requests are
+	// remembered.
+	IdempotencyWindow time.Duration
+
+	// NotifyURL is the default webhook notified when benchmarks finish, signed
+	// with NotifySecret if set.
+	NotifyURL    string
+	NotifySecret string
+
+	// GracePeriod is how long executing benchmarks are given to save their
+	// partial results when labd shuts down.
+	GracePeriod time.Duration
+
+	// ReapInterval is how often clusters are checked for an expired TTL, and
+	// ExpiryWarning how long before expiry the webhook is warned.
+	ReapInterval  time.Duration
+	ExpiryWarning time.Duration
+
+	// ArtifactStore is the URI of the object store keeping benchmark
+	// artifacts, defaulting to the state directory.
+	ArtifactStore string
+
+	// AuthTokens and AuthJWKSURL are the static bearer tokens and the JSON Web
+	// Key Set validating requests. Requests are not authenticated if neither
+	// is set. JWTs must be issued by AuthJWTIssuer for AuthJWTAudience, unless
+	// they are empty.
+	AuthTokens      []string
+	AuthJWKSURL     string
+	AuthJWTAudience string
+	AuthJWTIssuer   string
+
+	// RateLimitRead and RateLimitWrite limit the requests of each client that
+	// inspect and modify resources respectively, where RateLimitTokens
+	// override the write limit of clients with the bearer tokens.
+	RateLimitRead   metadata.RateLimit
+	RateLimitWrite  metadata.RateLimit
+	RateLimitTokens map[string]metadata.RateLimit
+
+	// AuditLog is the path of the file recording requests that modify
+	// resources, defaulting to the state directory.
+	AuditLog string
+
+	// Logs retains the recent logs of labd served to clients. No logs are
+	// served if it is nil.
+	Logs *logutil.Broadcaster
 }
 
 func WithLibp2pPort(port int) LabdOption {
@@ -50,6 +107,133 @@ func WithProviderSettings(settings providers.ProviderSettings) LabdOption {
 	}
 }
 
+// WithMetadataBackend sets the backend of the metadata store, connecting to
+// dsn for SQL backends.
+func WithMetadataBackend(backend, dsn string) LabdOption {
+	return func(s *LabdSettings) error {
+		s.MetadataBackend = backend
+		s.MetadataDSN = dsn
+		return nil
+	}
+}
+
+// WithMaxPageSize caps the number of results of paginated lists, zero means
+// no cap.
+func WithMaxPageSize(size int) LabdOption {
+	return func(s *LabdSettings) error {
+		s.MaxPageSize = size
+		return nil
+	}
+}
+
+// WithIdempotencyWindow sets how long idempotency keys of benchmark requests
+// are remembered.
+func WithIdempotencyWindow(window time.Duration) LabdOption {
+	return func(s *LabdSettings) error {
+		s.IdempotencyWindow = window
+		return nil
+	}
+}
+
+// WithGracePeriod sets how long executing benchmarks are given to save their
+// partial results when labd shuts down.
+func WithGracePeriod(gracePeriod time.Duration) LabdOption {
+	return func(s *LabdSettings) error {
+		s.GracePeriod = gracePeriod
+		return nil
+	}
+}
+
+// WithReaper sets how often clusters are checked for an expired TTL, and how
+// long before expiry the default webhook is warned.
+func WithReaper(interval, warning time.Duration) LabdOption {
+	return func(s *LabdSettings) error {
+		s.ReapInterval = interval
+		s.ExpiryWarning = warning
+		return nil
+	}
+}
+
+// WithArtifactStore sets the URI of the object store keeping benchmark
+// artifacts, such as "s3://bucket/prefix".
+func WithArtifactStore(uri string) LabdOption {
+	return func(s *LabdSettings) error {
+		s.ArtifactStore = uri
+		return nil
+	}
+}
+
+// WithAuth requires requests to carry a bearer token, either one of the static
+// tokens or a JWT signed by a key of the JSON Web Key Set at jwksURL. Unless
+// empty, the JWT must also be issued by issuer for audience.
+func WithAuth(tokens []string, jwksURL, audience, issuer string) LabdOption {
+	return func(s *LabdSettings) error {
+		s.AuthTokens = tokens
+		s.AuthJWKSURL = jwksURL
+		s.AuthJWTAudience = audience
+		s.AuthJWTIssuer = issuer
+		return nil
+	}
+}
+
+// WithRateLimits limits the read and write requests of each client, such as
+// "600/1m", and overrides the write limit of tokens given as
+// "<token>=<limit>". Empty limits are unlimited.
+func WithRateLimits(read, write string, tokens []string) LabdOption {
+	return func(s *LabdSettings) error {
+		var err error
+		s.RateLimitRead, err = daemon.ParseRateLimit(read)
+		if err != nil {
+			return err
+		}
+
+		s.RateLimitWrite, err = daemon.ParseRateLimit(write)
+		if err != nil {
+			return err
+		}
+
+		s.RateLimitTokens = make(map[string]metadata.RateLimit)
+		for _, token := range tokens {
+			parts := strings.SplitN(token, "=", 2)
+			if len(parts) != 2 || parts[0] == "" {
+				return errors.Wrap(errdefs.ErrInvalidArgument, "token rate limits must be <token>=<limit>")
+			}
+
+			s.RateLimitTokens[parts[0]], err = daemon.ParseRateLimit(parts[1])
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// WithAuditLog records requests that modify resources to a file.
+func WithAuditLog(path string) LabdOption {
+	return func(s *LabdSettings) error {
+		s.AuditLog = path
+		return nil
+	}
+}
+
+// WithLogs serves the logs written to the broadcaster to clients.
+func WithLogs(logs *logutil.Broadcaster) LabdOption {
+	return func(s *LabdSettings) error {
+		s.Logs = logs
+		return nil
+	}
+}
+
+// WithNotifier sets the default webhook notified when benchmarks finish, and
+// the secret signing its payloads.
+func WithNotifier(url, secret string) LabdOption {
+	return func(s *LabdSettings) error {
+		s.NotifyURL = url
+		s.NotifySecret = secret
+		return nil
+	}
+}
+
 func WithUploader(uploader string) LabdOption {
 	return func(s *LabdSettings) error {
 		s.Uploader = uploader
@@ -0,0 +1,64 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"path"
+	"strings"
+	"time"
+
+	"github.com/Netflix/p2plab/errdefs"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+)
+
+// Artifact is a file produced by a benchmark, such as the logs of a node.
+// Only its reference is kept in the metadata, its content is in the artifact
+// store.
+type Artifact struct {
+	// Name is the path of the artifact relative to its benchmark, such as
+	// "logs/<node>.log".
+	Name string
+
+	// Link is where the artifact store keeps the content.
+	Link string
+
+	Size int64
+
+	Digest digest.Digest
+
+	CreatedAt time.Time
+}
+
+// ValidateArtifactName returns an error if the name is not a relative path
+// contained in its benchmark.
+func ValidateArtifactName(name string) error {
+	if name == "" || path.IsAbs(name) || path.Clean(name) != name || name == ".." || strings.HasPrefix(name, "../") {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "artifact name must be a clean relative path, got %q", name)
+	}
+	return nil
+}
+
+// SetArtifact adds the artifact to the list, replacing the artifact with the
+// same name.
+func SetArtifact(artifacts []Artifact, artifact Artifact) []Artifact {
+	for i, a := range artifacts {
+		if a.Name == artifact.Name {
+			artifacts[i] = artifact
+			return artifacts
+		}
+	}
+	return append(artifacts, artifact)
+}
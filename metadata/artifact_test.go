@@ -0,0 +1,41 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"testing"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateArtifactName(t *testing.T) {
+	require.NoError(t, ValidateArtifactName("report.json"))
+	require.NoError(t, ValidateArtifactName("logs/n1.log"))
+
+	for _, name := range []string{"", "/etc/passwd", "..", "../report.json", "logs/../../report.json", "logs/"} {
+		require.True(t, errdefs.IsInvalidArgument(ValidateArtifactName(name)), "%q", name)
+	}
+}
+
+func TestSetArtifact(t *testing.T) {
+	artifacts := SetArtifact(nil, Artifact{Name: "report.json", Size: 1})
+	artifacts = SetArtifact(artifacts, Artifact{Name: "logs/n1.log", Size: 2})
+	artifacts = SetArtifact(artifacts, Artifact{Name: "report.json", Size: 3})
+	require.Equal(t, []Artifact{
+		{Name: "report.json", Size: 3},
+		{Name: "logs/n1.log", Size: 2},
+	}, artifacts)
+}
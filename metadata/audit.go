@@ -0,0 +1,44 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import "time"
+
+// AuditRecord is a record of a request to a daemon that modified resources.
+type AuditRecord struct {
+	Time time.Time
+
+	// Principal is the client authenticated by its bearer token, which is
+	// empty if the daemon doesn't authenticate requests.
+	Principal string `json:",omitempty"`
+
+	// RemoteAddr is the network address of the client.
+	RemoteAddr string
+
+	// Operation is the method and path template of the request, such as
+	// "POST /clusters/create".
+	Operation string
+
+	// Resource is the path and query of the request identifying the
+	// resources operated on.
+	Resource string
+
+	// Outcome is "OK" if the request succeeded, or the error code otherwise.
+	Outcome string
+
+	Error string `json:",omitempty"`
+
+	RequestID string `json:",omitempty"`
+}
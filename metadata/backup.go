@@ -0,0 +1,347 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+var errStoreNotEmpty = errors.Wrap(errdefs.ErrAlreadyExists, "metadata store is not empty, force is required to overwrite it")
+
+// resourceBucketKeys are the buckets holding the resources of a bolt store.
+var resourceBucketKeys = [][]byte{
+	bucketKeyClusters,
+	bucketKeyScenarios,
+	bucketKeyBuilds,
+	bucketKeyBenchmarks,
+	bucketKeyExperiments,
+}
+
+func (m *db) Backup(ctx context.Context, w io.Writer) error {
+	return m.view(ctx, func(tx *bolt.Tx) error {
+		_, err := tx.WriteTo(w)
+		return err
+	})
+}
+
+func (m *db) Restore(ctx context.Context, r io.Reader, force bool) error {
+	f, err := ioutil.TempFile("", "p2plab-restore")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = io.Copy(f, r)
+	f.Close()
+	if err != nil {
+		return errors.Wrap(err, "failed to receive backup")
+	}
+
+	backup, err := bolt.Open(f.Name(), 0600, &bolt.Options{ReadOnly: true, Timeout: openTimeout})
+	if err != nil {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid bolt backup: %s", err)
+	}
+	defer backup.Close()
+
+	return backup.View(func(btx *bolt.Tx) error {
+		version, err := getBoltSchemaVersion(btx)
+		if err != nil {
+			return err
+		}
+
+		_, err = pendingMigrations(version)
+		if err != nil {
+			return err
+		}
+
+		return m.update(ctx, func(tx *bolt.Tx) error {
+			if !force && !isBoltEmpty(tx) {
+				return errStoreNotEmpty
+			}
+
+			err := tx.DeleteBucket(bucketKeyVersion)
+			if err != nil && err != bolt.ErrBucketNotFound {
+				return err
+			}
+
+			src := btx.Bucket(bucketKeyVersion)
+			if src != nil {
+				dst, err := tx.CreateBucket(bucketKeyVersion)
+				if err != nil {
+					return err
+				}
+
+				err = copyBucket(dst, src)
+				if err != nil {
+					return errors.Wrap(err, "failed to copy backup")
+				}
+			}
+
+			// Bring backups of older versions up to date.
+			_, err = migrateBolt(ctx, tx)
+			return err
+		})
+	})
+}
+
+func isBoltEmpty(tx *bolt.Tx) bool {
+	for _, key := range resourceBucketKeys {
+		bkt := getBucket(tx, bucketKeyVersion, key)
+		if bkt == nil {
+			continue
+		}
+
+		k, _ := bkt.Cursor().First()
+		if k != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func copyBucket(dst, src *bolt.Bucket) error {
+	err := dst.SetSequence(src.Sequence())
+	if err != nil {
+		return err
+	}
+
+	return src.ForEach(func(k, v []byte) error {
+		// Values are only valid for the life of the backup's transaction.
+		k = append([]byte(nil), k...)
+		if v != nil {
+			return dst.Put(k, append([]byte(nil), v...))
+		}
+
+		child, err := dst.CreateBucket(k)
+		if err != nil {
+			return err
+		}
+		return copyBucket(child, src.Bucket(k))
+	})
+}
+
+// postgresTables are the tables dumped by a postgres backup, in an order that
+// satisfies their foreign keys.
+var postgresTables = []struct {
+	name string
+	keys []string
+}{
+	{"clusters", []string{"id"}},
+	{"nodes", []string{"cluster_id", "id"}},
+	{"scenarios", []string{"id"}},
+	{"builds", []string{"id"}},
+	{"benchmarks", []string{"id"}},
+	{"reports", []string{"benchmark_id"}},
+	{"experiments", []string{"id"}},
+	{"idempotency_keys", []string{"id"}},
+}
+
+// postgresBackupHeader is the first line of a postgres backup, followed by a
+// line for every row.
+type postgresBackupHeader struct {
+	Backend       string `json:"backend"`
+	SchemaVersion int    `json:"schemaVersion"`
+}
+
+type postgresBackupRow struct {
+	Table string          `json:"table"`
+	Keys  []string        `json:"keys"`
+	Doc   json.RawMessage `json:"doc"`
+}
+
+func (m *pgdb) Backup(ctx context.Context, w io.Writer) error {
+	// A repeatable read transaction dumps every table from the same snapshot.
+	sqltx, err := m.sqldb.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer sqltx.Rollback()
+
+	tx := &postgresTx{sqltx, false}
+	version, err := getPostgresSchemaVersion(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	err = enc.Encode(postgresBackupHeader{Backend: "postgres", SchemaVersion: version})
+	if err != nil {
+		return err
+	}
+
+	for _, table := range postgresTables {
+		query := fmt.Sprintf(`SELECT %s, doc FROM %s ORDER BY %s`, strings.Join(table.keys, ", "), table.name, strings.Join(table.keys, ", "))
+		rows, err := tx.QueryContext(ctx, query)
+		if err != nil {
+			return errors.Wrapf(err, "failed to dump %s", table.name)
+		}
+
+		for rows.Next() {
+			row := postgresBackupRow{Table: table.name, Keys: make([]string, len(table.keys))}
+			dest := make([]interface{}, len(table.keys)+1)
+			for i := range row.Keys {
+				dest[i] = &row.Keys[i]
+			}
+			dest[len(dest)-1] = (*[]byte)(&row.Doc)
+
+			err = rows.Scan(dest...)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+
+			err = enc.Encode(&row)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		err = rows.Close()
+		if err != nil {
+			return err
+		}
+		if rows.Err() != nil {
+			return rows.Err()
+		}
+	}
+
+	return nil
+}
+
+func (m *pgdb) Restore(ctx context.Context, r io.Reader, force bool) error {
+	dec := json.NewDecoder(bufio.NewReader(r))
+
+	var header postgresBackupHeader
+	err := dec.Decode(&header)
+	if err != nil || header.Backend != "postgres" {
+		return errors.Wrap(errdefs.ErrInvalidArgument, "invalid postgres backup")
+	}
+
+	_, err = pendingMigrations(header.SchemaVersion)
+	if err != nil {
+		return err
+	}
+
+	return m.update(ctx, func(tx *postgresTx) error {
+		err := lockPostgresSchemaVersion(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		if !force {
+			empty, err := isPostgresEmpty(ctx, tx)
+			if err != nil {
+				return err
+			}
+			if !empty {
+				return errStoreNotEmpty
+			}
+		}
+
+		for i := len(postgresTables) - 1; i >= 0; i-- {
+			_, err = tx.ExecContext(ctx, fmt.Sprintf(`DROP TABLE IF EXISTS %s`, postgresTables[i].name))
+			if err != nil {
+				return err
+			}
+		}
+
+		// Recreate the schema of the backup, so that its rows are restored into
+		// the tables they were dumped from, and then migrate them forward.
+		err = applyPostgresMigrations(ctx, tx, migrations[:header.SchemaVersion])
+		if err != nil {
+			return err
+		}
+
+		for {
+			var row postgresBackupRow
+			err = dec.Decode(&row)
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid postgres backup row: %s", err)
+			}
+
+			err = restorePostgresRow(ctx, tx, row)
+			if err != nil {
+				return err
+			}
+		}
+
+		return applyPostgresMigrations(ctx, tx, migrations[header.SchemaVersion:])
+	})
+}
+
+func restorePostgresRow(ctx context.Context, tx *postgresTx, row postgresBackupRow) error {
+	for _, table := range postgresTables {
+		if table.name != row.Table {
+			continue
+		}
+
+		if len(row.Keys) != len(table.keys) {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid keys for %s row in backup", row.Table)
+		}
+
+		var (
+			placeholders []string
+			args         []interface{}
+		)
+		for i, key := range row.Keys {
+			placeholders = append(placeholders, fmt.Sprintf("$%d", i+1))
+			args = append(args, key)
+		}
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)+1))
+		args = append(args, string(row.Doc))
+
+		query := fmt.Sprintf(`INSERT INTO %s (%s, doc) VALUES (%s)`, table.name, strings.Join(table.keys, ", "), strings.Join(placeholders, ", "))
+		_, err := tx.ExecContext(ctx, query, args...)
+		return errors.Wrapf(err, "failed to restore %s row", row.Table)
+	}
+
+	return errors.Wrapf(errdefs.ErrInvalidArgument, "unknown table %q in backup", row.Table)
+}
+
+func isPostgresEmpty(ctx context.Context, tx *postgresTx) (bool, error) {
+	for _, table := range postgresTables {
+		var exists bool
+		err := tx.QueryRowContext(ctx, fmt.Sprintf(`SELECT to_regclass('%s') IS NOT NULL`, table.name)).Scan(&exists)
+		if err != nil {
+			return false, err
+		}
+		if !exists {
+			continue
+		}
+
+		err = tx.QueryRowContext(ctx, fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s)`, table.name)).Scan(&exists)
+		if err != nil {
+			return false, err
+		}
+		if exists {
+			return false, nil
+		}
+	}
+	return true, nil
+}
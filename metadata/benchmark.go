@@ -16,6 +16,8 @@ package metadata
 
 import (
 	"context"
+	"encoding/json"
+	"strconv"
 	"time"
 
 	"github.com/Netflix/p2plab/errdefs"
@@ -35,11 +37,106 @@ type Benchmark struct {
 
 	Plan ScenarioPlan
 
+	// Nodes is the result of the benchmark on each node.
+	Nodes map[string]BenchmarkNode
+
+	// Generation is incremented every time failed nodes are retried.
+	Generation int
+
+	// Window delimits the measured window of the benchmark stage from its
+	// warmup and cooldown, once the benchmark has run.
+	Window *BenchmarkWindow `json:",omitempty"`
+
+	// Peers is the peer definition each node ran the benchmark with, so that
+	// results are attributable to a libp2p stack.
+	Peers map[string]PeerDefinition `json:",omitempty"`
+
+	// Artifacts are the files the benchmark produced, kept in the artifact
+	// store.
+	Artifacts []Artifact `json:",omitempty"`
+
+	// Steps is the result of each step of the benchmark, if the scenario has
+	// steps.
+	Steps []BenchmarkStep `json:",omitempty"`
+
+	// Parameters are the template variables the scenario was expanded with
+	// when the benchmark is a trial of a parameter sweep.
+	Parameters map[string]string `json:",omitempty"`
+
 	Labels []string
 
 	CreatedAt, UpdatedAt time.Time
 }
 
+// BenchmarkWindow delimits the phases of the benchmark stage. The warmup runs
+// from WarmupStart to Start, metrics are measured from Start to End, and the
+// cooldown runs from End to CooldownEnd.
+type BenchmarkWindow struct {
+	WarmupStart time.Time
+	Start       time.Time
+	End         time.Time
+	CooldownEnd time.Time
+}
+
+// BenchmarkNode is the result of a benchmark on a single node.
+type BenchmarkNode struct {
+	Status BenchmarkNodeStatus
+
+	Error string `json:",omitempty"`
+
+	// Duration is the time the node took to execute its task.
+	Duration time.Duration `json:",omitempty"`
+
+	// Bitswap is the bitswap activity of the node during the benchmark, the
+	// difference of its stats from the start to the end of the benchmark.
+	Bitswap *ReportBitswap `json:",omitempty"`
+
+	// Resources is the CPU and memory usage of the node during the benchmark,
+	// if the scenario has a sample interval.
+	Resources *ResourceUsage `json:",omitempty"`
+
+	// Connectivity is the result of the node's connectivity task, if the
+	// scenario has a connectivity objective.
+	Connectivity *Connectivity `json:",omitempty"`
+
+	// Retrieval is the timing of the content retrieved by the node, if its
+	// task is to get content.
+	Retrieval *Retrieval `json:",omitempty"`
+
+	// CacheDrop is the last drop of the node's page cache before the
+	// benchmark, such as during the seed, which tells whether the node's
+	// caches were cold.
+	CacheDrop *CacheDrop `json:",omitempty"`
+}
+
+// BenchmarkNodeStatus is the status of a benchmark on a single node.
+type BenchmarkNodeStatus string
+
+var (
+	BenchmarkNodeDone BenchmarkNodeStatus = "done"
+
+	BenchmarkNodeError BenchmarkNodeStatus = "error"
+
+	BenchmarkNodeTimeout BenchmarkNodeStatus = "timeout"
+
+	// BenchmarkNodeMissing indicates the node was removed from the cluster
+	// before it could be retried.
+	BenchmarkNodeMissing BenchmarkNodeStatus = "missing"
+
+	// BenchmarkNodeRunning and BenchmarkNodeSkipped are reported in the
+	// progress of an executing benchmark, a node is skipped when it failed to
+	// seed before its benchmark task. Nodes of a step depending on a failed step
+	// are also skipped, and recorded as such.
+	BenchmarkNodeRunning BenchmarkNodeStatus = "running"
+
+	BenchmarkNodeSkipped BenchmarkNodeStatus = "skipped"
+)
+
+// Retryable returns true if the node failed in a way that can be retried.
+func (s BenchmarkNodeStatus) Retryable() bool {
+	return s == BenchmarkNodeError || s == BenchmarkNodeTimeout
+}
+
 // BenchmarkStatus is the current status of a benchmark.
 type BenchmarkStatus string
 
@@ -51,14 +148,74 @@ var (
 	BenchmarkDone BenchmarkStatus = "done"
 
 	BenchmarkError BenchmarkStatus = "error"
+
+	BenchmarkCanceled BenchmarkStatus = "canceled"
+
+	// BenchmarkInterrupted indicates the benchmark was canceled because labd
+	// shut down while it was executing.
+	BenchmarkInterrupted BenchmarkStatus = "interrupted"
 )
 
+// Terminal returns true if a benchmark with this status will not change
+// status anymore.
+func (s BenchmarkStatus) Terminal() bool {
+	switch s {
+	case BenchmarkDone, BenchmarkError, BenchmarkCanceled, BenchmarkInterrupted:
+		return true
+	default:
+		return false
+	}
+}
+
 type ScenarioPlan struct {
 	Objects map[string]cid.Cid
 
 	Seed ScenarioStage
 
 	Benchmark ScenarioStage
+
+	// Steps replace the seed and benchmark stages, ordered so that every step
+	// follows the steps it depends on.
+	Steps []ScenarioStep `json:",omitempty"`
+
+	// Teardown is executed once the benchmark finishes.
+	Teardown ScenarioStage
+
+	// Network is the impairment applied to nodes during the benchmark stage.
+	Network *NetworkSpec
+
+	// SampleInterval is how often the resource usage of nodes is sampled during
+	// the benchmark stage, sampling is disabled if zero.
+	SampleInterval time.Duration
+
+	// Warmup and Cooldown are waited before and after the benchmark stage is
+	// measured.
+	Warmup, Cooldown time.Duration
+
+	// Objective is what the benchmark stage measures, if not the retrieval of
+	// objects.
+	Objective *ObjectiveDefinition
+
+	// Seeders and Leechers are the IDs of the nodes that seeded and the nodes
+	// that were benchmarked, if the scenario seeds a subset of the cluster.
+	Seeders, Leechers []string
+
+	// MaxConcurrency is how many nodes executed a stage in parallel, unbounded
+	// if zero.
+	MaxConcurrency int
+
+	// GC is the garbage collection of the nodes' blockstores once the
+	// benchmark is torn down, if any.
+	GC *GCDefinition
+}
+
+// StepTasks returns the number of tasks across the steps of the plan.
+func (p ScenarioPlan) StepTasks() int {
+	n := 0
+	for _, step := range p.Steps {
+		n += len(step.Tasks)
+	}
+	return n
 }
 
 type ScenarioStage map[string]Task
@@ -77,12 +234,31 @@ var (
 	TaskConnect    TaskType = "connect"
 	TaskConnectOne TaskType = "connect-one"
 	TaskDisconnect TaskType = "disconnect"
+
+	// TaskConnectivity bootstraps the node and measures how long it takes to
+	// connect to its peers. The subject is a ConnectivityTask.
+	TaskConnectivity TaskType = "connectivity"
+
+	// TaskGC garbage collects the blockstore of the node. The subject is a
+	// comma-separated list of CIDs whose DAGs are kept, if any.
+	TaskGC TaskType = "gc"
+
+	// TaskPin fetches the DAG of the CID of the subject unless the node
+	// already holds it, and keeps it from garbage collection.
+	TaskPin TaskType = "pin"
+
+	// TaskUnpin unpins the CID of the subject and evicts its DAG.
+	TaskUnpin TaskType = "unpin"
+
+	// TaskDropCache drops the OS page cache of the node, the subject is
+	// ignored. Nodes that can't drop it only warn.
+	TaskDropCache TaskType = "drop-cache"
 )
 
 func (m *db) GetBenchmark(ctx context.Context, id string) (Benchmark, error) {
 	var benchmark Benchmark
 
-	err := m.View(ctx, func(tx *bolt.Tx) error {
+	err := m.view(ctx, func(tx *bolt.Tx) error {
 		bkt := getBenchmarksBucket(tx)
 		if bkt == nil {
 			return errors.Wrapf(errdefs.ErrNotFound, "benchmark %q", id)
@@ -108,9 +284,14 @@ func (m *db) GetBenchmark(ctx context.Context, id string) (Benchmark, error) {
 	return benchmark, nil
 }
 
-func (m *db) ListBenchmarks(ctx context.Context) ([]Benchmark, error) {
+func (m *db) ListBenchmarks(ctx context.Context, opts ...ListOption) ([]Benchmark, error) {
+	settings, err := newListSettings(opts, OrderByCreated, OrderByUpdated, OrderByDuration)
+	if err != nil {
+		return nil, err
+	}
+
 	var benchmarks []Benchmark
-	err := m.View(ctx, func(tx *bolt.Tx) error {
+	err = m.view(ctx, func(tx *bolt.Tx) error {
 		bkt := getBenchmarksBucket(tx)
 		if bkt == nil {
 			return nil
@@ -137,11 +318,16 @@ func (m *db) ListBenchmarks(ctx context.Context) ([]Benchmark, error) {
 		return nil, err
 	}
 
+	order(settings, len(benchmarks), func(i, j int) {
+		benchmarks[i], benchmarks[j] = benchmarks[j], benchmarks[i]
+	}, func(i int) int64 {
+		return timeOrderKey(settings.OrderBy, benchmarks[i].CreatedAt, benchmarks[i].UpdatedAt)
+	})
 	return benchmarks, nil
 }
 
 func (m *db) CreateBenchmark(ctx context.Context, benchmark Benchmark) (Benchmark, error) {
-	err := m.Update(ctx, func(tx *bolt.Tx) error {
+	err := m.update(ctx, func(tx *bolt.Tx) error {
 		bkt, err := createBenchmarksBucket(tx)
 		if err != nil {
 			return err
@@ -171,7 +357,7 @@ func (m *db) UpdateBenchmark(ctx context.Context, benchmark Benchmark) (Benchmar
 		return Benchmark{}, errors.Wrapf(errdefs.ErrInvalidArgument, "benchmark id required for update")
 	}
 
-	err := m.Update(ctx, func(tx *bolt.Tx) error {
+	err := m.update(ctx, func(tx *bolt.Tx) error {
 		bkt, err := createBenchmarksBucket(tx)
 		if err != nil {
 			return err
@@ -194,7 +380,7 @@ func (m *db) UpdateBenchmark(ctx context.Context, benchmark Benchmark) (Benchmar
 
 func (m *db) LabelBenchmarks(ctx context.Context, ids, adds, removes []string) ([]Benchmark, error) {
 	var benchmarks []Benchmark
-	err := m.Update(ctx, func(tx *bolt.Tx) error {
+	err := m.update(ctx, func(tx *bolt.Tx) error {
 		bkt, err := createBenchmarksBucket(tx)
 		if err != nil {
 			return err
@@ -232,7 +418,7 @@ func (m *db) LabelBenchmarks(ctx context.Context, ids, adds, removes []string) (
 }
 
 func (m *db) DeleteBenchmarks(ctx context.Context, ids ...string) error {
-	return m.Update(ctx, func(tx *bolt.Tx) error {
+	return m.update(ctx, func(tx *bolt.Tx) error {
 		bkt := getBenchmarksBucket(tx)
 		if bkt == nil {
 			return nil
@@ -287,6 +473,11 @@ func readBenchmark(bkt *bolt.Bucket, benchmark *Benchmark) error {
 		return err
 	}
 
+	benchmark.Nodes, err = readBenchmarkNodes(bkt)
+	if err != nil {
+		return err
+	}
+
 	return bkt.ForEach(func(k, v []byte) error {
 		if v == nil {
 			return nil
@@ -297,12 +488,80 @@ func readBenchmark(bkt *bolt.Bucket, benchmark *Benchmark) error {
 			benchmark.ID = string(v)
 		case string(bucketKeyStatus):
 			benchmark.Status = BenchmarkStatus(v)
+		case string(bucketKeyGeneration):
+			generation, err := strconv.Atoi(string(v))
+			if err != nil {
+				return err
+			}
+			benchmark.Generation = generation
+		case string(bucketKeyWindow):
+			benchmark.Window = new(BenchmarkWindow)
+			return json.Unmarshal(v, benchmark.Window)
+		case string(bucketKeyPeers):
+			return json.Unmarshal(v, &benchmark.Peers)
+		case string(bucketKeyArtifacts):
+			return json.Unmarshal(v, &benchmark.Artifacts)
+		case string(bucketKeyParameters):
+			return json.Unmarshal(v, &benchmark.Parameters)
+		case string(bucketKeySteps):
+			return json.Unmarshal(v, &benchmark.Steps)
 		}
 
 		return nil
 	})
 }
 
+func readBenchmarkNodes(bkt *bolt.Bucket) (map[string]BenchmarkNode, error) {
+	nbkt := bkt.Bucket(bucketKeyNodes)
+	if nbkt == nil {
+		return nil, nil
+	}
+
+	results := make(map[string]BenchmarkNode)
+	err := nbkt.ForEach(func(id, v []byte) error {
+		ibkt := nbkt.Bucket(id)
+		if ibkt == nil {
+			return nil
+		}
+
+		var result BenchmarkNode
+		err := ibkt.ForEach(func(k, v []byte) error {
+			switch string(k) {
+			case string(bucketKeyStatus):
+				result.Status = BenchmarkNodeStatus(v)
+			case string(bucketKeyError):
+				result.Error = string(v)
+			case string(bucketKeyDuration):
+				result.Duration, _ = time.ParseDuration(string(v))
+			case string(bucketKeyBitswap):
+				result.Bitswap = new(ReportBitswap)
+				return json.Unmarshal(v, result.Bitswap)
+			case string(bucketKeyResources):
+				result.Resources = new(ResourceUsage)
+				return json.Unmarshal(v, result.Resources)
+			case string(bucketKeyConnectivity):
+				result.Connectivity = new(Connectivity)
+				return json.Unmarshal(v, result.Connectivity)
+			case string(bucketKeyRetrieval):
+				result.Retrieval = new(Retrieval)
+				return json.Unmarshal(v, result.Retrieval)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		results[string(id)] = result
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
 func readPlan(bkt *bolt.Bucket, plan *ScenarioPlan) error {
 	m, err := readMap(bkt, bucketKeyObjects)
 	if err != nil {
@@ -330,6 +589,63 @@ func readPlan(bkt *bolt.Bucket, plan *ScenarioPlan) error {
 		return nil
 	}
 
+	plan.Teardown, err = readTaskMap(bkt, bucketKeyTeardown)
+	if err != nil {
+		return err
+	}
+
+	if v := bkt.Get(bucketKeySteps); v != nil {
+		err = json.Unmarshal(v, &plan.Steps)
+		if err != nil {
+			return err
+		}
+	}
+
+	plan.Network, err = readNetworkSpec(bkt)
+	if err != nil {
+		return err
+	}
+
+	plan.GC, err = readGC(bkt)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range []struct {
+		key   []byte
+		value *time.Duration
+	}{
+		{bucketKeySampleInterval, &plan.SampleInterval},
+		{bucketKeyWarmup, &plan.Warmup},
+		{bucketKeyCooldown, &plan.Cooldown},
+	} {
+		if v := bkt.Get(d.key); v != nil {
+			*d.value, _ = time.ParseDuration(string(v))
+		}
+	}
+
+	plan.Objective, err = readObjective(bkt)
+	if err != nil {
+		return err
+	}
+
+	plan.Seeders, err = readIDs(bkt, bucketKeySeeders)
+	if err != nil {
+		return err
+	}
+
+	plan.Leechers, err = readIDs(bkt, bucketKeyLeechers)
+	if err != nil {
+		return err
+	}
+
+	if v := bkt.Get(bucketKeyMaxConcurrency); v != nil {
+		plan.MaxConcurrency, err = strconv.Atoi(string(v))
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -435,9 +751,75 @@ func writeBenchmark(bkt *bolt.Bucket, benchmark *Benchmark) error {
 		return err
 	}
 
+	err = writeBenchmarkNodes(bkt, benchmark.Nodes)
+	if err != nil {
+		return err
+	}
+
+	if benchmark.Window != nil {
+		content, err := json.Marshal(benchmark.Window)
+		if err != nil {
+			return err
+		}
+
+		err = bkt.Put(bucketKeyWindow, content)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(benchmark.Peers) > 0 {
+		content, err := json.Marshal(benchmark.Peers)
+		if err != nil {
+			return err
+		}
+
+		err = bkt.Put(bucketKeyPeers, content)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(benchmark.Artifacts) > 0 {
+		content, err := json.Marshal(benchmark.Artifacts)
+		if err != nil {
+			return err
+		}
+
+		err = bkt.Put(bucketKeyArtifacts, content)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(benchmark.Parameters) > 0 {
+		content, err := json.Marshal(benchmark.Parameters)
+		if err != nil {
+			return err
+		}
+
+		err = bkt.Put(bucketKeyParameters, content)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(benchmark.Steps) > 0 {
+		content, err := json.Marshal(benchmark.Steps)
+		if err != nil {
+			return err
+		}
+
+		err = bkt.Put(bucketKeySteps, content)
+		if err != nil {
+			return err
+		}
+	}
+
 	for _, f := range []field{
 		{bucketKeyID, []byte(benchmark.ID)},
 		{bucketKeyStatus, []byte(benchmark.Status)},
+		{bucketKeyGeneration, []byte(strconv.Itoa(benchmark.Generation))},
 	} {
 		err = bkt.Put(f.key, f.value)
 		if err != nil {
@@ -448,6 +830,85 @@ func writeBenchmark(bkt *bolt.Bucket, benchmark *Benchmark) error {
 	return nil
 }
 
+func writeBenchmarkNodes(bkt *bolt.Bucket, results map[string]BenchmarkNode) error {
+	if len(results) == 0 {
+		return nil
+	}
+
+	nbkt, err := RecreateBucket(bkt, bucketKeyNodes)
+	if err != nil {
+		return err
+	}
+
+	for id, result := range results {
+		ibkt, err := nbkt.CreateBucket([]byte(id))
+		if err != nil {
+			return err
+		}
+
+		for _, f := range []field{
+			{bucketKeyStatus, []byte(result.Status)},
+			{bucketKeyError, []byte(result.Error)},
+			{bucketKeyDuration, []byte(result.Duration.String())},
+		} {
+			err = ibkt.Put(f.key, f.value)
+			if err != nil {
+				return err
+			}
+		}
+
+		if result.Bitswap != nil {
+			content, err := json.Marshal(result.Bitswap)
+			if err != nil {
+				return err
+			}
+
+			err = ibkt.Put(bucketKeyBitswap, content)
+			if err != nil {
+				return err
+			}
+		}
+
+		if result.Resources != nil {
+			content, err := json.Marshal(result.Resources)
+			if err != nil {
+				return err
+			}
+
+			err = ibkt.Put(bucketKeyResources, content)
+			if err != nil {
+				return err
+			}
+		}
+
+		if result.Connectivity != nil {
+			content, err := json.Marshal(result.Connectivity)
+			if err != nil {
+				return err
+			}
+
+			err = ibkt.Put(bucketKeyConnectivity, content)
+			if err != nil {
+				return err
+			}
+		}
+
+		if result.Retrieval != nil {
+			content, err := json.Marshal(result.Retrieval)
+			if err != nil {
+				return err
+			}
+
+			err = ibkt.Put(bucketKeyRetrieval, content)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
 func writePlan(bkt *bolt.Bucket, plan *ScenarioPlan) error {
 	obkt := bkt.Bucket(bucketKeyObjects)
 	if obkt != nil {
@@ -483,6 +944,73 @@ func writePlan(bkt *bolt.Bucket, plan *ScenarioPlan) error {
 		return err
 	}
 
+	err = writeTaskMap(bkt, bucketKeyTeardown, plan.Teardown)
+	if err != nil {
+		return err
+	}
+
+	if len(plan.Steps) > 0 {
+		content, err := json.Marshal(plan.Steps)
+		if err != nil {
+			return err
+		}
+
+		err = bkt.Put(bucketKeySteps, content)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = writeNetworkSpec(bkt, plan.Network)
+	if err != nil {
+		return err
+	}
+
+	err = writeGC(bkt, plan.GC)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range []struct {
+		key   []byte
+		value time.Duration
+	}{
+		{bucketKeySampleInterval, plan.SampleInterval},
+		{bucketKeyWarmup, plan.Warmup},
+		{bucketKeyCooldown, plan.Cooldown},
+	} {
+		if d.value == 0 {
+			continue
+		}
+
+		err = bkt.Put(d.key, []byte(d.value.String()))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = writeObjective(bkt, plan.Objective)
+	if err != nil {
+		return err
+	}
+
+	err = writeIDs(bkt, bucketKeySeeders, plan.Seeders)
+	if err != nil {
+		return err
+	}
+
+	err = writeIDs(bkt, bucketKeyLeechers, plan.Leechers)
+	if err != nil {
+		return err
+	}
+
+	if plan.MaxConcurrency > 0 {
+		err = bkt.Put(bucketKeyMaxConcurrency, []byte(strconv.Itoa(plan.MaxConcurrency)))
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
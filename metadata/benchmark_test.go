@@ -0,0 +1,100 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	metrics "github.com/libp2p/go-libp2p-core/metrics"
+	protocol "github.com/libp2p/go-libp2p-protocol"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBenchmarkNodes(t *testing.T) {
+	root, err := ioutil.TempDir("", "p2plab-metadata")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	db, err := NewDB(root)
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	benchmark, err := db.CreateBenchmark(ctx, Benchmark{
+		ID:     "benchmark",
+		Status: BenchmarkRunning,
+	})
+	require.NoError(t, err)
+
+	benchmark.Status = BenchmarkError
+	benchmark.Generation = 1
+	benchmark.Nodes = map[string]BenchmarkNode{
+		"a": {Status: BenchmarkNodeDone, Duration: 1500 * time.Millisecond, Bitswap: &ReportBitswap{BlocksReceived: 4, DupBlksReceived: 1}, Retrieval: &Retrieval{FirstBlock: 200 * time.Millisecond, Total: 1400 * time.Millisecond}},
+		"b": {Status: BenchmarkNodeTimeout, Error: "context deadline exceeded"},
+		"c": {Status: BenchmarkNodeDone, Resources: &ResourceUsage{Interval: time.Second, CPU: []float64{12.5, 50}, RSS: []uint64{1 << 20, 1 << 21}}},
+		"d": {Status: BenchmarkNodeDone, Connectivity: &Connectivity{
+			FirstPeer:   time.Second,
+			TargetPeers: 3 * time.Second,
+			Timeline:    []ConnectivitySample{{Elapsed: time.Second, Peers: 1, RoutingTable: 1}, {Elapsed: 3 * time.Second, Peers: 4, RoutingTable: 4}},
+		}},
+	}
+	_, err = db.UpdateBenchmark(ctx, benchmark)
+	require.NoError(t, err)
+
+	actual, err := db.GetBenchmark(ctx, benchmark.ID)
+	require.NoError(t, err)
+	require.Equal(t, BenchmarkError, actual.Status)
+	require.Equal(t, 1, actual.Generation)
+	require.Equal(t, benchmark.Nodes, actual.Nodes)
+	require.False(t, actual.Nodes["a"].Status.Retryable())
+	require.True(t, actual.Nodes["b"].Status.Retryable())
+}
+
+func TestReportBitswapSub(t *testing.T) {
+	start := ReportBitswap{BlocksReceived: 2, DataReceived: 512, MessagesReceived: 10}
+	end := ReportBitswap{BlocksReceived: 5, DataReceived: 2048, MessagesReceived: 4}
+
+	diff := end.Sub(start)
+	require.Equal(t, uint64(3), diff.BlocksReceived)
+	require.Equal(t, uint64(1536), diff.DataReceived)
+	require.Equal(t, uint64(4), diff.MessagesReceived)
+}
+
+func TestResourceUsageSummary(t *testing.T) {
+	var usage ResourceUsage
+	require.Equal(t, ResourceSummary{}, usage.CPUSummary())
+
+	for _, sample := range []ResourceSample{{CPU: 20, RSS: 300}, {CPU: 10, RSS: 100}, {CPU: 60, RSS: 200}} {
+		usage.Add(sample)
+	}
+	require.Equal(t, ResourceSummary{Min: 10, Avg: 30, Max: 60}, usage.CPUSummary())
+	require.Equal(t, ResourceSummary{Min: 100, Avg: 200, Max: 300}, usage.RSSSummary())
+}
+
+func TestReportBandwidthSub(t *testing.T) {
+	start := ReportBandwidth{Totals: metrics.Stats{TotalIn: 100, TotalOut: 50}}
+	end := ReportBandwidth{
+		Totals:    metrics.Stats{TotalIn: 300, TotalOut: 80, RateIn: 10},
+		Protocols: map[protocol.ID]metrics.Stats{"/ipfs/bitswap": {TotalIn: 200}},
+	}
+
+	diff := end.Sub(start)
+	require.Equal(t, metrics.Stats{TotalIn: 200, TotalOut: 30, RateIn: 10}, diff.Totals)
+	require.Equal(t, metrics.Stats{TotalIn: 200}, diff.Protocols["/ipfs/bitswap"])
+}
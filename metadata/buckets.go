@@ -18,30 +18,57 @@ import bolt "go.etcd.io/bbolt"
 
 var (
 	// API Resources.
-	bucketKeyVersion     = []byte(schemaVersion)
-	bucketKeyClusters    = []byte("clusters")
-	bucketKeyNodes       = []byte("nodes")
-	bucketKeyScenarios   = []byte("scenarios")
-	bucketKeyBuilds      = []byte("builds")
-	bucketKeyBenchmarks  = []byte("benchmarks")
-	bucketKeyExperiments = []byte("experiments")
+	bucketKeyVersion       = []byte(schemaVersion)
+	bucketKeySchemaVersion = []byte("schemaVersion")
+	bucketKeyClusters      = []byte("clusters")
+	bucketKeyNodes         = []byte("nodes")
+	bucketKeyScenarios     = []byte("scenarios")
+	bucketKeyBuilds        = []byte("builds")
+	bucketKeyBenchmarks    = []byte("benchmarks")
+	bucketKeyExperiments   = []byte("experiments")
+
+	// Idempotency keys of requests.
+	bucketKeyIdempotencyKeys = []byte("idempotencyKeys")
 
 	// Cluster buckets.
 	bucketKeySize         = []byte("size")
 	bucketKeyInstanceType = []byte("instanceType")
 	bucketKeyRegion       = []byte("region")
+	bucketKeyProvider     = []byte("provider")
+	bucketKeyCPUs         = []byte("cpus")
+	bucketKeyMemory       = []byte("memory")
+	bucketKeyNetwork      = []byte("network")
 
 	// Scenario buckets.
-	bucketKeyObjects   = []byte("objects")
-	bucketKeySeed      = []byte("seed")
-	bucketKeyBenchmark = []byte("benchmark")
-	bucketKeyType      = []byte("type")
-	bucketKeySource    = []byte("source")
-	bucketKeyLayout    = []byte("layout")
-	bucketKeyChunker   = []byte("chunker")
-	bucketKeyRawLeaves = []byte("rawLeaves")
-	bucketKeyHashFunc  = []byte("hashFunc")
-	bucketKeyMaxLinks  = []byte("maxLinks")
+	bucketKeyObjects        = []byte("objects")
+	bucketKeySeed           = []byte("seed")
+	bucketKeyBenchmark      = []byte("benchmark")
+	bucketKeyType           = []byte("type")
+	bucketKeySource         = []byte("source")
+	bucketKeyLayout         = []byte("layout")
+	bucketKeyChunker        = []byte("chunker")
+	bucketKeyRawLeaves      = []byte("rawLeaves")
+	bucketKeyHashFunc       = []byte("hashFunc")
+	bucketKeyMaxLinks       = []byte("maxLinks")
+	bucketKeyPlatform       = []byte("platform")
+	bucketKeyExclude        = []byte("exclude")
+	bucketKeyLatency        = []byte("latency")
+	bucketKeyJitter         = []byte("jitter")
+	bucketKeyBandwidth      = []byte("bandwidth")
+	bucketKeyLoss           = []byte("loss")
+	bucketKeySampleInterval = []byte("sampleInterval")
+	bucketKeyObjective      = []byte("objective")
+	bucketKeySeeding        = []byte("seeding")
+	bucketKeySeeders        = []byte("seeders")
+	bucketKeyLeechers       = []byte("leechers")
+	bucketKeyWarmup         = []byte("warmup")
+	bucketKeyCooldown       = []byte("cooldown")
+	bucketKeyMaxConcurrency = []byte("maxConcurrency")
+	bucketKeyStack          = []byte("stack")
+	bucketKeyGC             = []byte("gc")
+	bucketKeyTeardown       = []byte("teardown")
+	bucketKeyDatastore      = []byte("datastore")
+	bucketKeySteps          = []byte("steps")
 
 	// Node buckets.
 	bucketKeyAddress            = []byte("address")
@@ -52,16 +79,31 @@ var (
 	bucketKeyMuxers             = []byte("muxers")
 	bucketKeySecurityTransports = []byte("securityTransports")
 	bucketKeyRouting            = []byte("routing")
+	bucketKeyRoutingEndpoint    = []byte("routingEndpoint")
 
 	// Build buckets
 	bucketKeyLink = []byte("link")
 
 	// Benchmark buckets.
-	bucketKeyCluster  = []byte("cluster")
-	bucketKeyScenario = []byte("scenario")
-	bucketKeyPlan     = []byte("plan")
-	bucketKeySubject  = []byte("subject")
-	bucketKeyReport   = []byte("report")
+	bucketKeyCluster      = []byte("cluster")
+	bucketKeyScenario     = []byte("scenario")
+	bucketKeyPlan         = []byte("plan")
+	bucketKeySubject      = []byte("subject")
+	bucketKeyReport       = []byte("report")
+	bucketKeyGeneration   = []byte("generation")
+	bucketKeyError        = []byte("error")
+	bucketKeyDuration     = []byte("duration")
+	bucketKeyBitswap      = []byte("bitswap")
+	bucketKeyResources    = []byte("resources")
+	bucketKeyConnectivity = []byte("connectivity")
+	bucketKeyRetrieval    = []byte("retrieval")
+	bucketKeyWindow       = []byte("window")
+	bucketKeyPeers        = []byte("peers")
+	bucketKeyArtifacts    = []byte("artifacts")
+	bucketKeyParameters   = []byte("parameters")
+
+	// Experiment buckets.
+	bucketKeyTrials = []byte("trials")
 
 	// Common buckets.
 	bucketKeyID           = []byte("id")
@@ -69,6 +111,7 @@ var (
 	bucketKeyLabels       = []byte("labels")
 	bucketKeyCreatedAt    = []byte("createdAt")
 	bucketKeyUpdatedAt    = []byte("updatedAt")
+	bucketKeyExpiresAt    = []byte("expiresAt")
 	bucketKeyDefinition   = []byte("definition")
 	bucketKeyGitReference = []byte("gitReference")
 )
@@ -173,3 +216,15 @@ func getExperimentBucket(tx *bolt.Tx, name string) *bolt.Bucket {
 func createExperimentsBucket(tx *bolt.Tx) (*bolt.Bucket, error) {
 	return createBucketIfNotExists(tx, bucketKeyVersion, bucketKeyExperiments)
 }
+
+func getIdempotencyKeysBucket(tx *bolt.Tx) *bolt.Bucket {
+	return getBucket(tx, bucketKeyVersion, bucketKeyIdempotencyKeys)
+}
+
+func getIdempotencyKeyBucket(tx *bolt.Tx, id string) *bolt.Bucket {
+	return getBucket(tx, bucketKeyVersion, bucketKeyIdempotencyKeys, []byte(id))
+}
+
+func createIdempotencyKeysBucket(tx *bolt.Tx) (*bolt.Bucket, error) {
+	return createBucketIfNotExists(tx, bucketKeyVersion, bucketKeyIdempotencyKeys)
+}
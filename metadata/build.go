@@ -34,7 +34,7 @@ type Build struct {
 func (m *db) GetBuild(ctx context.Context, id string) (Build, error) {
 	var build Build
 
-	err := m.View(ctx, func(tx *bolt.Tx) error {
+	err := m.view(ctx, func(tx *bolt.Tx) error {
 		bkt := getBuildsBucket(tx)
 		if bkt == nil {
 			return errors.Wrapf(errdefs.ErrNotFound, "build %q", id)
@@ -62,7 +62,7 @@ func (m *db) GetBuild(ctx context.Context, id string) (Build, error) {
 
 func (m *db) ListBuilds(ctx context.Context) ([]Build, error) {
 	var builds []Build
-	err := m.View(ctx, func(tx *bolt.Tx) error {
+	err := m.view(ctx, func(tx *bolt.Tx) error {
 		bkt := getBuildsBucket(tx)
 		if bkt == nil {
 			return nil
@@ -93,7 +93,7 @@ func (m *db) ListBuilds(ctx context.Context) ([]Build, error) {
 }
 
 func (m *db) CreateBuild(ctx context.Context, build Build) (Build, error) {
-	err := m.Update(ctx, func(tx *bolt.Tx) error {
+	err := m.update(ctx, func(tx *bolt.Tx) error {
 		bkt, err := createBuildsBucket(tx)
 		if err != nil {
 			return err
@@ -119,7 +119,7 @@ func (m *db) CreateBuild(ctx context.Context, build Build) (Build, error) {
 }
 
 func (m *db) DeleteBuild(ctx context.Context, id string) error {
-	return m.Update(ctx, func(tx *bolt.Tx) error {
+	return m.update(ctx, func(tx *bolt.Tx) error {
 		bkt := getBuildsBucket(tx)
 		if bkt == nil {
 			return nil
@@ -0,0 +1,31 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"time"
+)
+
+// CacheDrop is the result of dropping the OS page cache of a node, so that
+// content is read from disk rather than memory.
+type CacheDrop struct {
+	Time time.Time
+
+	// Dropped is whether the page cache was dropped, otherwise Warning is why
+	// it wasn't.
+	Dropped bool
+
+	Warning string `json:",omitempty"`
+}
@@ -0,0 +1,56 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffDefinitions(t *testing.T) {
+	changes, err := DiffDefinitions(ClusterDefinition{
+		Groups: []ClusterGroup{{Size: 2, InstanceType: "t2.micro", Region: "us-west-2"}},
+	}, ClusterDefinition{
+		Groups: []ClusterGroup{{Size: 2, InstanceType: "t2.micro", Region: "us-west-2"}},
+	})
+	require.NoError(t, err)
+	require.Empty(t, changes)
+
+	changes, err = DiffDefinitions(ClusterDefinition{
+		Provider: "inmemory",
+		Groups:   []ClusterGroup{{Size: 2, Region: "us-west-2"}},
+	}, ClusterDefinition{
+		Groups: []ClusterGroup{{Size: 3, Region: "us-west-2"}, {Size: 1, Region: "us-east-1"}},
+	})
+	require.NoError(t, err)
+	require.Len(t, changes, 3)
+	require.Equal(t, Change{Path: "Groups[0].Size", Old: float64(2), New: float64(3)}, changes[0])
+	require.Equal(t, "Groups[1]", changes[1].Path)
+	require.Nil(t, changes[1].Old)
+	require.Equal(t, "us-east-1", changes[1].New.(map[string]interface{})["Region"])
+	require.Equal(t, Change{Path: "provider", Old: "inmemory"}, changes[2])
+
+	changes, err = DiffDefinitions(ScenarioDefinition{
+		Benchmark: map[string]string{"neighbors": "fetch"},
+	}, ScenarioDefinition{
+		Benchmark: map[string]string{"(not neighbors)": "fetch"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []Change{
+		{Path: `benchmark["(not neighbors)"]`, New: "fetch"},
+		{Path: "benchmark.neighbors", Old: "fetch"},
+	}, changes)
+}
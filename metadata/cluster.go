@@ -37,9 +37,18 @@ type Cluster struct {
 
 	Labels []string
 
+	// ExpiresAt is when the cluster is destroyed by the reaper of labd, or zero
+	// if the cluster never expires.
+	ExpiresAt time.Time
+
 	CreatedAt, UpdatedAt time.Time
 }
 
+// Expired returns whether the cluster has a TTL that ran out by now.
+func (c Cluster) Expired(now time.Time) bool {
+	return !c.ExpiresAt.IsZero() && !now.Before(c.ExpiresAt)
+}
+
 func (c Cluster) Validate() error {
 	err := ValidateClusterID(c.ID)
 	if err != nil {
@@ -60,6 +69,10 @@ var (
 )
 
 type ClusterDefinition struct {
+	// Provider is the node provider that creates the cluster's nodes. Defaults
+	// to the provider labd was started with.
+	Provider string `json:"provider,omitempty"`
+
 	Groups []ClusterGroup
 }
 
@@ -90,18 +103,41 @@ func (d ClusterDefinition) GenerateLabels() (labels []string) {
 	return labels
 }
 
+// NodeGroupIndex returns the index of the first group whose region and
+// instance type are labels of the node, or -1 if no group matches.
+func (d ClusterDefinition) NodeGroupIndex(n Node) int {
+	for i, g := range d.Groups {
+		var region, instanceType bool
+		for _, l := range n.Labels {
+			region = region || l == g.Region
+			instanceType = instanceType || l == g.InstanceType
+		}
+		if region && instanceType {
+			return i
+		}
+	}
+	return -1
+}
+
 type ClusterGroup struct {
 	Size         int
 	InstanceType string
 	Region       string
 	Peer         *PeerDefinition `json:"peer,omitempty"`
 	Labels       []string
+
+	// CPUs, Memory and Network constrain the nodes of providers that run them
+	// as containers, in the format of docker run's --cpus, --memory and
+	// --network flags.
+	CPUs    string `json:"cpus,omitempty"`
+	Memory  string `json:"memory,omitempty"`
+	Network string `json:"network,omitempty"`
 }
 
 func (m *db) GetCluster(ctx context.Context, id string) (Cluster, error) {
 	var cluster Cluster
 
-	err := m.View(ctx, func(tx *bolt.Tx) error {
+	err := m.view(ctx, func(tx *bolt.Tx) error {
 		bkt := getClustersBucket(tx)
 		if bkt == nil {
 			return errors.Wrapf(errdefs.ErrNotFound, "cluster %q", id)
@@ -129,7 +165,7 @@ func (m *db) GetCluster(ctx context.Context, id string) (Cluster, error) {
 
 func (m *db) ListClusters(ctx context.Context) ([]Cluster, error) {
 	var clusters []Cluster
-	err := m.View(ctx, func(tx *bolt.Tx) error {
+	err := m.view(ctx, func(tx *bolt.Tx) error {
 		bkt := getClustersBucket(tx)
 		if bkt == nil {
 			return nil
@@ -165,7 +201,7 @@ func (m *db) CreateCluster(ctx context.Context, cluster Cluster) (Cluster, error
 		return Cluster{}, err
 	}
 
-	err = m.Update(ctx, func(tx *bolt.Tx) error {
+	err = m.update(ctx, func(tx *bolt.Tx) error {
 		bkt, err := createClustersBucket(tx)
 		if err != nil {
 			return err
@@ -195,7 +231,7 @@ func (m *db) UpdateCluster(ctx context.Context, cluster Cluster) (Cluster, error
 		return Cluster{}, errors.Wrapf(errdefs.ErrInvalidArgument, "cluster id required for update")
 	}
 
-	err := m.Update(ctx, func(tx *bolt.Tx) error {
+	err := m.update(ctx, func(tx *bolt.Tx) error {
 		bkt, err := createClustersBucket(tx)
 		if err != nil {
 			return err
@@ -218,7 +254,7 @@ func (m *db) UpdateCluster(ctx context.Context, cluster Cluster) (Cluster, error
 
 func (m *db) LabelClusters(ctx context.Context, ids, adds, removes []string) ([]Cluster, error) {
 	var clusters []Cluster
-	err := m.Update(ctx, func(tx *bolt.Tx) error {
+	err := m.update(ctx, func(tx *bolt.Tx) error {
 		bkt, err := createClustersBucket(tx)
 		if err != nil {
 			return err
@@ -256,7 +292,7 @@ func (m *db) LabelClusters(ctx context.Context, ids, adds, removes []string) ([]
 }
 
 func (m *db) DeleteCluster(ctx context.Context, id string) error {
-	return m.Update(ctx, func(tx *bolt.Tx) error {
+	return m.update(ctx, func(tx *bolt.Tx) error {
 		bkt := getClustersBucket(tx)
 		if bkt == nil {
 			return nil
@@ -300,6 +336,8 @@ func readCluster(bkt *bolt.Bucket, cluster *Cluster) error {
 			cluster.ID = string(v)
 		case string(bucketKeyStatus):
 			cluster.Status = ClusterStatus(v)
+		case string(bucketKeyExpiresAt):
+			return cluster.ExpiresAt.UnmarshalBinary(v)
 		}
 
 		return nil
@@ -313,6 +351,7 @@ func readClusterDefinition(bkt *bolt.Bucket) (ClusterDefinition, error) {
 	if dbkt == nil {
 		return cdef, nil
 	}
+	cdef.Provider = string(dbkt.Get(bucketKeyProvider))
 
 	i := 0
 	gbkt := dbkt.Bucket([]byte(strconv.Itoa(i)))
@@ -347,6 +386,12 @@ func readClusterDefinition(bkt *bolt.Bucket) (ClusterDefinition, error) {
 				group.InstanceType = string(v)
 			case string(bucketKeyRegion):
 				group.Region = string(v)
+			case string(bucketKeyCPUs):
+				group.CPUs = string(v)
+			case string(bucketKeyMemory):
+				group.Memory = string(v)
+			case string(bucketKeyNetwork):
+				group.Network = string(v)
 			}
 			return nil
 		})
@@ -379,6 +424,19 @@ func writeCluster(bkt *bolt.Bucket, cluster *Cluster) error {
 		return err
 	}
 
+	if cluster.ExpiresAt.IsZero() {
+		err = bkt.Delete(bucketKeyExpiresAt)
+	} else {
+		var expiresAt []byte
+		expiresAt, err = cluster.ExpiresAt.MarshalBinary()
+		if err == nil {
+			err = bkt.Put(bucketKeyExpiresAt, expiresAt)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
 	for _, f := range []field{
 		{bucketKeyID, []byte(cluster.ID)},
 		{bucketKeyStatus, []byte(cluster.Status)},
@@ -398,6 +456,13 @@ func writeClusterDefinition(bkt *bolt.Bucket, cdef ClusterDefinition) error {
 		return err
 	}
 
+	if cdef.Provider != "" {
+		err = dbkt.Put(bucketKeyProvider, []byte(cdef.Provider))
+		if err != nil {
+			return err
+		}
+	}
+
 	for i, group := range cdef.Groups {
 		gbkt, err := dbkt.CreateBucket([]byte(strconv.Itoa(i)))
 		if err != nil {
@@ -420,6 +485,9 @@ func writeClusterDefinition(bkt *bolt.Bucket, cdef ClusterDefinition) error {
 			{bucketKeySize, []byte(strconv.Itoa(group.Size))},
 			{bucketKeyInstanceType, []byte(group.InstanceType)},
 			{bucketKeyRegion, []byte(group.Region)},
+			{bucketKeyCPUs, []byte(group.CPUs)},
+			{bucketKeyMemory, []byte(group.Memory)},
+			{bucketKeyNetwork, []byte(group.Network)},
 		} {
 			err = gbkt.Put(f.key, f.value)
 			if err != nil {
@@ -0,0 +1,660 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Netflix/p2plab/errdefs"
+	cid "github.com/ipfs/go-cid"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// postgresTestDSN is the environment variable with the DSN of a scratch
+// postgres database to run the conformance tests against. Its tables are
+// dropped before every test.
+const postgresTestDSN = "P2PLAB_TEST_POSTGRES_DSN"
+
+type newTestDB func(t *testing.T) (DB, func())
+
+func TestConformance(t *testing.T) {
+	backends := map[string]newTestDB{
+		"bolt": newBoltTestDB,
+	}
+	if dsn := os.Getenv(postgresTestDSN); dsn != "" {
+		backends["postgres"] = func(t *testing.T) (DB, func()) {
+			return newPostgresTestDB(t, dsn)
+		}
+	}
+
+	for name, newDB := range backends {
+		newDB := newDB
+		t.Run(name, func(t *testing.T) {
+			for _, test := range []struct {
+				name string
+				fn   func(t *testing.T, db DB)
+			}{
+				{"Clusters", testClusters},
+				{"Nodes", testNodes},
+				{"Scenarios", testScenarios},
+				{"Builds", testBuilds},
+				{"IdempotencyKeys", testIdempotencyKeys},
+				{"Benchmarks", testBenchmarks},
+				{"Experiments", testExperiments},
+				{"Transactions", testTransactions},
+				{"BackupRestore", testBackupRestore},
+				{"Ordering", testOrdering},
+			} {
+				test := test
+				t.Run(test.name, func(t *testing.T) {
+					db, cleanup := newDB(t)
+					defer cleanup()
+					test.fn(t, db)
+				})
+			}
+		})
+	}
+}
+
+func newBoltTestDB(t *testing.T) (DB, func()) {
+	root, err := ioutil.TempDir("", "p2plab-metadata")
+	require.NoError(t, err)
+
+	db, err := NewDB(root)
+	require.NoError(t, err)
+
+	_, err = db.Migrate(context.Background())
+	require.NoError(t, err)
+
+	return db, func() {
+		db.Close()
+		os.RemoveAll(root)
+	}
+}
+
+func newPostgresTestDB(t *testing.T, dsn string) (DB, func()) {
+	sqldb, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	defer sqldb.Close()
+
+	for _, table := range []string{"reports", "benchmarks", "experiments", "builds", "scenarios", "nodes", "clusters", "idempotency_keys", "schema_version"} {
+		_, err = sqldb.Exec(`DROP TABLE IF EXISTS ` + table)
+		require.NoError(t, err)
+	}
+
+	db, err := NewPostgresDB(context.Background(), dsn)
+	require.NoError(t, err)
+
+	_, err = db.Migrate(context.Background())
+	require.NoError(t, err)
+
+	return db, func() {
+		db.Close()
+	}
+}
+
+func testClusters(t *testing.T, db DB) {
+	ctx := context.Background()
+	def := ClusterDefinition{
+		Provider: "inmemory",
+		Groups:   []ClusterGroup{{Size: 2, InstanceType: "t3.micro", Region: "us-west-2"}},
+	}
+
+	_, err := db.CreateCluster(ctx, Cluster{ID: " bad"})
+	require.True(t, errdefs.IsInvalidArgument(err), "expected invalid argument, got %v", err)
+
+	for _, id := range []string{"b", "a"} {
+		cluster, err := db.CreateCluster(ctx, Cluster{ID: id, Status: ClusterCreating, Definition: def, Labels: []string{"x"}})
+		require.NoError(t, err)
+		require.False(t, cluster.CreatedAt.IsZero())
+	}
+
+	_, err = db.CreateCluster(ctx, Cluster{ID: "a"})
+	require.True(t, errdefs.IsAlreadyExists(err), "expected already exists, got %v", err)
+
+	cluster, err := db.GetCluster(ctx, "a")
+	require.NoError(t, err)
+	require.Equal(t, "a", cluster.ID)
+	require.Equal(t, ClusterCreating, cluster.Status)
+	require.Equal(t, def, cluster.Definition)
+
+	_, err = db.GetCluster(ctx, "missing")
+	require.True(t, errdefs.IsNotFound(err), "expected not found, got %v", err)
+
+	expiresAt := time.Now().Add(time.Hour).UTC()
+	cluster.ExpiresAt = expiresAt
+	_, err = db.UpdateCluster(ctx, cluster)
+	require.NoError(t, err)
+
+	cluster, err = db.GetCluster(ctx, "a")
+	require.NoError(t, err)
+	require.True(t, expiresAt.Equal(cluster.ExpiresAt), "expected expiry %s, got %s", expiresAt, cluster.ExpiresAt)
+	require.False(t, cluster.Expired(time.Now()))
+	require.True(t, cluster.Expired(expiresAt))
+
+	cluster.ExpiresAt = time.Time{}
+	cluster.Status = ClusterCreated
+	_, err = db.UpdateCluster(ctx, cluster)
+	require.NoError(t, err)
+
+	cluster, err = db.GetCluster(ctx, "a")
+	require.NoError(t, err)
+	require.True(t, cluster.ExpiresAt.IsZero())
+
+	_, err = db.UpdateCluster(ctx, Cluster{ID: "missing"})
+	require.True(t, errdefs.IsNotFound(err), "expected not found, got %v", err)
+
+	clusters, err := db.ListClusters(ctx)
+	require.NoError(t, err)
+	require.Len(t, clusters, 2)
+	require.Equal(t, "a", clusters[0].ID)
+	require.Equal(t, ClusterCreated, clusters[0].Status)
+	require.Equal(t, "b", clusters[1].ID)
+
+	clusters, err = db.LabelClusters(ctx, []string{"a", "b"}, []string{"x", "y"}, []string{"z"})
+	require.NoError(t, err)
+	require.Len(t, clusters, 2)
+	for _, cluster := range clusters {
+		require.Equal(t, []string{"x", "y"}, cluster.Labels)
+	}
+
+	clusters, err = db.LabelClusters(ctx, []string{"a"}, nil, []string{"x"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"y"}, clusters[0].Labels)
+
+	_, err = db.LabelClusters(ctx, []string{"missing"}, []string{"x"}, nil)
+	require.True(t, errdefs.IsNotFound(err), "expected not found, got %v", err)
+
+	_, err = db.CreateNode(ctx, "a", Node{ID: "n"})
+	require.NoError(t, err)
+
+	err = db.DeleteCluster(ctx, "a")
+	require.NoError(t, err)
+
+	_, err = db.GetCluster(ctx, "a")
+	require.True(t, errdefs.IsNotFound(err), "expected not found, got %v", err)
+
+	nodes, err := db.ListNodes(ctx, "a")
+	require.NoError(t, err)
+	require.Empty(t, nodes)
+
+	err = db.DeleteCluster(ctx, "a")
+	require.True(t, errdefs.IsNotFound(err), "expected not found, got %v", err)
+}
+
+func testNodes(t *testing.T, db DB) {
+	ctx := context.Background()
+	for _, cluster := range []string{"c1", "c2"} {
+		_, err := db.CreateCluster(ctx, Cluster{ID: cluster})
+		require.NoError(t, err)
+	}
+
+	nodes, err := db.CreateNodes(ctx, "c1", []Node{
+		{ID: "n2", Address: "10.0.0.2", AgentPort: 7002, AppPort: 7003, Peer: DefaultPeerDefinition},
+		{ID: "n1", Address: "10.0.0.1", AgentPort: 7002, AppPort: 7003, Peer: DefaultPeerDefinition},
+	})
+	require.NoError(t, err)
+	require.Len(t, nodes, 2)
+	require.False(t, nodes[0].CreatedAt.IsZero())
+
+	_, err = db.CreateNode(ctx, "c2", Node{ID: "n1"})
+	require.NoError(t, err, "node IDs are scoped to their cluster")
+
+	_, err = db.CreateNode(ctx, "c1", Node{ID: "n1"})
+	require.True(t, errdefs.IsAlreadyExists(err), "expected already exists, got %v", err)
+
+	node, err := db.GetNode(ctx, "c1", "n1")
+	require.NoError(t, err)
+	require.Equal(t, "10.0.0.1", node.Address)
+	require.Equal(t, 7002, node.AgentPort)
+	require.Equal(t, 7003, node.AppPort)
+	require.Equal(t, DefaultPeerDefinition, node.Peer)
+
+	_, err = db.GetNode(ctx, "c2", "n2")
+	require.True(t, errdefs.IsNotFound(err), "expected not found, got %v", err)
+
+	node.Address = "10.0.1.1"
+	node.Status = NodeFailed
+	node.Reason = "agent is not healthy"
+	_, err = db.UpdateNode(ctx, "c1", node)
+	require.NoError(t, err)
+
+	nodes, err = db.ListNodes(ctx, "c1")
+	require.NoError(t, err)
+	require.Len(t, nodes, 2)
+	require.Equal(t, "n1", nodes[0].ID)
+	require.Equal(t, "10.0.1.1", nodes[0].Address)
+	require.Equal(t, NodeFailed, nodes[0].Status)
+	require.Equal(t, "agent is not healthy", nodes[0].Reason)
+	require.Equal(t, "n2", nodes[1].ID)
+
+	nodes, err = db.LabelNodes(ctx, "c1", []string{"n1", "n2"}, []string{"seeder"}, nil)
+	require.NoError(t, err)
+	require.Len(t, nodes, 2)
+	require.Equal(t, []string{"seeder"}, nodes[1].Labels)
+
+	err = db.DeleteNodes(ctx, "c1", "n1", "n2")
+	require.NoError(t, err)
+
+	err = db.DeleteNodes(ctx, "c1", "n1")
+	require.True(t, errdefs.IsNotFound(err), "expected not found, got %v", err)
+
+	nodes, err = db.ListNodes(ctx, "c2")
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+}
+
+func testScenarios(t *testing.T, db DB) {
+	ctx := context.Background()
+	def := ScenarioDefinition{
+		Objects: map[string]ObjectDefinition{
+			"image": {Type: "oci", Source: "docker.io/library/golang:latest", Layout: "balanced", Chunker: "size-262144", HashFunc: "sha2-256", MaxLinks: 174},
+		},
+		Seed:      map[string]string{"(neighbors)": "image"},
+		Benchmark: map[string]string{"*": "image"},
+		Teardown:  map[string]string{"(neighbors)": "unpin image"},
+		Network:   &NetworkSpec{Latency: "50ms", Jitter: "5ms", Bandwidth: "10mbit", Loss: 0.5},
+		GC:        &GCDefinition{Keep: []string{"image"}},
+		Datastore: "memory",
+	}
+
+	_, err := db.CreateScenario(ctx, Scenario{ID: "s", Definition: def})
+	require.NoError(t, err)
+
+	_, err = db.CreateScenario(ctx, Scenario{ID: "s"})
+	require.True(t, errdefs.IsAlreadyExists(err), "expected already exists, got %v", err)
+
+	scenario, err := db.GetScenario(ctx, "s")
+	require.NoError(t, err)
+	require.Equal(t, def, scenario.Definition)
+
+	scenario.Definition.Benchmark = map[string]string{"*": "other"}
+	_, err = db.UpdateScenario(ctx, scenario)
+	require.NoError(t, err)
+
+	scenarios, err := db.LabelScenarios(ctx, []string{"s"}, []string{"nightly"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"nightly"}, scenarios[0].Labels)
+
+	scenarios, err = db.ListScenarios(ctx)
+	require.NoError(t, err)
+	require.Len(t, scenarios, 1)
+	require.Equal(t, map[string]string{"*": "other"}, scenarios[0].Definition.Benchmark)
+	require.Equal(t, []string{"nightly"}, scenarios[0].Labels)
+
+	err = db.DeleteScenarios(ctx, "s")
+	require.NoError(t, err)
+
+	err = db.DeleteScenarios(ctx, "s")
+	require.True(t, errdefs.IsNotFound(err), "expected not found, got %v", err)
+}
+
+func testBuilds(t *testing.T, db DB) {
+	ctx := context.Background()
+	_, err := db.CreateBuild(ctx, Build{ID: "abc", Link: "file:///builds/abc"})
+	require.NoError(t, err)
+
+	_, err = db.CreateBuild(ctx, Build{ID: "abc"})
+	require.True(t, errdefs.IsAlreadyExists(err), "expected already exists, got %v", err)
+
+	build, err := db.GetBuild(ctx, "abc")
+	require.NoError(t, err)
+	require.Equal(t, "file:///builds/abc", build.Link)
+
+	builds, err := db.ListBuilds(ctx)
+	require.NoError(t, err)
+	require.Len(t, builds, 1)
+
+	err = db.DeleteBuild(ctx, "abc")
+	require.NoError(t, err)
+
+	_, err = db.GetBuild(ctx, "abc")
+	require.True(t, errdefs.IsNotFound(err), "expected not found, got %v", err)
+}
+
+func testIdempotencyKeys(t *testing.T, db DB) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	for id, expiresAt := range map[string]time.Time{
+		"alice/key": now.Add(time.Hour),
+		"bob/key":   now.Add(-time.Minute),
+	} {
+		_, err := db.CreateIdempotencyKey(ctx, IdempotencyKey{ID: id, Benchmark: "benchmark", ExpiresAt: expiresAt})
+		require.NoError(t, err)
+	}
+
+	_, err := db.CreateIdempotencyKey(ctx, IdempotencyKey{ID: "alice/key"})
+	require.True(t, errdefs.IsAlreadyExists(err), "expected already exists, got %v", err)
+
+	key, err := db.GetIdempotencyKey(ctx, "alice/key")
+	require.NoError(t, err)
+	require.Equal(t, "benchmark", key.Benchmark)
+	require.True(t, key.ExpiresAt.Equal(now.Add(time.Hour)))
+	require.False(t, key.Expired(now))
+
+	err = db.DeleteExpiredIdempotencyKeys(ctx, now)
+	require.NoError(t, err)
+
+	_, err = db.GetIdempotencyKey(ctx, "bob/key")
+	require.True(t, errdefs.IsNotFound(err), "expected not found, got %v", err)
+
+	err = db.DeleteIdempotencyKey(ctx, "alice/key")
+	require.NoError(t, err)
+
+	err = db.DeleteIdempotencyKey(ctx, "alice/key")
+	require.True(t, errdefs.IsNotFound(err), "expected not found, got %v", err)
+}
+
+func testBenchmarks(t *testing.T, db DB) {
+	ctx := context.Background()
+	c, err := cid.Decode("QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbdG")
+	require.NoError(t, err)
+
+	plan := ScenarioPlan{
+		Objects:   map[string]cid.Cid{"image": c},
+		Seed:      ScenarioStage{"n1": {Type: TaskGet, Subject: c.String()}},
+		Benchmark: ScenarioStage{"n2": {Type: TaskGet, Subject: c.String()}},
+		Steps: []ScenarioStep{
+			{Name: "connect", Connect: true, Tasks: ScenarioStage{"n1": {Type: TaskConnect}, "n2": {Type: TaskConnect}}},
+			{Name: "get", DependsOn: []string{"connect"}, Tasks: ScenarioStage{"n2": {Type: TaskGet, Subject: c.String()}}},
+		},
+
+		SampleInterval: time.Second,
+		Warmup:         10 * time.Second,
+		Cooldown:       5 * time.Second,
+		Seeders:        []string{"n1"},
+		Leechers:       []string{"n2"},
+		MaxConcurrency: 4,
+	}
+	_, err = db.CreateBenchmark(ctx, Benchmark{
+		ID:       "b",
+		Status:   BenchmarkRunning,
+		Cluster:  Cluster{ID: "c"},
+		Scenario: Scenario{ID: "s"},
+		Plan:     plan,
+	})
+	require.NoError(t, err)
+
+	_, err = db.CreateBenchmark(ctx, Benchmark{ID: "b"})
+	require.True(t, errdefs.IsAlreadyExists(err), "expected already exists, got %v", err)
+
+	_, err = db.GetReport(ctx, "b")
+	require.True(t, errdefs.IsNotFound(err), "expected not found, got %v", err)
+
+	err = db.CreateReport(ctx, "missing", Report{})
+	require.True(t, errdefs.IsNotFound(err), "expected not found, got %v", err)
+
+	var report Report
+	report.Summary.TotalTime = time.Minute
+	report.Nodes = map[string]ReportNode{"n2": {Bitswap: ReportBitswap{BlocksReceived: 10}}}
+	err = db.CreateReport(ctx, "b", report)
+	require.NoError(t, err)
+
+	benchmark, err := db.GetBenchmark(ctx, "b")
+	require.NoError(t, err)
+	require.Equal(t, BenchmarkRunning, benchmark.Status)
+	require.Equal(t, "c", benchmark.Cluster.ID)
+	require.Equal(t, "s", benchmark.Scenario.ID)
+	require.Equal(t, plan, benchmark.Plan)
+
+	benchmark.Status = BenchmarkError
+	benchmark.Generation = 1
+	benchmark.Nodes = map[string]BenchmarkNode{
+		"n2": {Status: BenchmarkNodeTimeout, Error: "context deadline exceeded", Duration: time.Second},
+	}
+	warmupStart := time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC)
+	benchmark.Window = &BenchmarkWindow{
+		WarmupStart: warmupStart,
+		Start:       warmupStart.Add(10 * time.Second),
+		End:         warmupStart.Add(time.Minute),
+		CooldownEnd: warmupStart.Add(time.Minute + 5*time.Second),
+	}
+	benchmark.Artifacts = []Artifact{{
+		Name:      "report.json",
+		Link:      "file:///tmp/artifacts/b/report.json",
+		Size:      2,
+		Digest:    digest.FromString("{}"),
+		CreatedAt: warmupStart,
+	}}
+	benchmark.Parameters = map[string]string{"size": "1MB"}
+	benchmark.Steps = []BenchmarkStep{{
+		Name:  "get",
+		Start: warmupStart.Add(10 * time.Second),
+		End:   warmupStart.Add(time.Minute),
+		Nodes: benchmark.Nodes,
+	}}
+	_, err = db.UpdateBenchmark(ctx, benchmark)
+	require.NoError(t, err)
+
+	actual, err := db.GetReport(ctx, "b")
+	require.NoError(t, err)
+	require.Equal(t, report.Summary.TotalTime, actual.Summary.TotalTime)
+	require.Equal(t, report.Nodes, actual.Nodes)
+
+	benchmarks, err := db.LabelBenchmarks(ctx, []string{"b"}, []string{"baseline"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"baseline"}, benchmarks[0].Labels)
+
+	benchmarks, err = db.ListBenchmarks(ctx)
+	require.NoError(t, err)
+	require.Len(t, benchmarks, 1)
+	require.Equal(t, BenchmarkError, benchmarks[0].Status)
+	require.Equal(t, 1, benchmarks[0].Generation)
+	require.Equal(t, benchmark.Nodes, benchmarks[0].Nodes)
+	require.Equal(t, benchmark.Window, benchmarks[0].Window)
+	require.Equal(t, benchmark.Steps, benchmarks[0].Steps)
+	require.Equal(t, benchmark.Artifacts, benchmarks[0].Artifacts)
+	require.Equal(t, benchmark.Parameters, benchmarks[0].Parameters)
+	require.Equal(t, []string{"baseline"}, benchmarks[0].Labels)
+
+	err = db.DeleteBenchmarks(ctx, "b")
+	require.NoError(t, err)
+
+	_, err = db.GetReport(ctx, "b")
+	require.True(t, errdefs.IsNotFound(err), "expected not found, got %v", err)
+
+	err = db.DeleteBenchmarks(ctx, "b")
+	require.True(t, errdefs.IsNotFound(err), "expected not found, got %v", err)
+}
+
+func testExperiments(t *testing.T, db DB) {
+	ctx := context.Background()
+	_, err := db.CreateExperiment(ctx, Experiment{ID: "e", Status: ExperimentRunning})
+	require.NoError(t, err)
+
+	_, err = db.CreateExperiment(ctx, Experiment{ID: "e"})
+	require.True(t, errdefs.IsAlreadyExists(err), "expected already exists, got %v", err)
+
+	experiment, err := db.GetExperiment(ctx, "e")
+	require.NoError(t, err)
+	require.Equal(t, ExperimentRunning, experiment.Status)
+
+	experiment.Status = ExperimentDone
+	experiment.Trials = []ExperimentTrial{
+		{Parameters: map[string]string{"size": "1MB"}, Benchmark: "b"},
+		{Parameters: map[string]string{"size": "10MB"}, Error: "timed out"},
+	}
+	_, err = db.UpdateExperiment(ctx, experiment)
+	require.NoError(t, err)
+
+	experiments, err := db.LabelExperiments(ctx, []string{"e"}, []string{"v1"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"v1"}, experiments[0].Labels)
+
+	experiments, err = db.ListExperiments(ctx)
+	require.NoError(t, err)
+	require.Len(t, experiments, 1)
+	require.Equal(t, ExperimentDone, experiments[0].Status)
+	require.Equal(t, experiment.Trials, experiments[0].Trials)
+
+	err = db.DeleteExperiment(ctx, "e")
+	require.NoError(t, err)
+
+	err = db.DeleteExperiment(ctx, "e")
+	require.True(t, errdefs.IsNotFound(err), "expected not found, got %v", err)
+}
+
+func testTransactions(t *testing.T, db DB) {
+	ctx := context.Background()
+	_, err := db.CreateBenchmark(ctx, Benchmark{ID: "b", Status: BenchmarkRunning})
+	require.NoError(t, err)
+
+	errAbort := errors.New("abort")
+	err = db.Update(ctx, func(tctx context.Context) error {
+		err := db.CreateReport(tctx, "b", Report{})
+		if err != nil {
+			return err
+		}
+
+		_, err = db.UpdateBenchmark(tctx, Benchmark{ID: "b", Status: BenchmarkDone})
+		if err != nil {
+			return err
+		}
+
+		return errAbort
+	})
+	require.Equal(t, errAbort, err)
+
+	_, err = db.GetReport(ctx, "b")
+	require.True(t, errdefs.IsNotFound(err), "expected rolled back report, got %v", err)
+
+	benchmark, err := db.GetBenchmark(ctx, "b")
+	require.NoError(t, err)
+	require.Equal(t, BenchmarkRunning, benchmark.Status)
+
+	err = db.Update(ctx, func(tctx context.Context) error {
+		err := db.CreateReport(tctx, "b", Report{})
+		if err != nil {
+			return err
+		}
+
+		_, err = db.UpdateBenchmark(tctx, Benchmark{ID: "b", Status: BenchmarkDone})
+		return err
+	})
+	require.NoError(t, err)
+
+	_, err = db.GetReport(ctx, "b")
+	require.NoError(t, err)
+
+	err = db.View(ctx, func(tctx context.Context) error {
+		benchmark, err := db.GetBenchmark(tctx, "b")
+		require.NoError(t, err)
+		require.Equal(t, BenchmarkDone, benchmark.Status)
+
+		_, err = db.CreateBenchmark(tctx, Benchmark{ID: "c"})
+		return err
+	})
+	require.Error(t, err, "writes must fail in a read-only transaction")
+}
+
+func testBackupRestore(t *testing.T, db DB) {
+	ctx := context.Background()
+	_, err := db.CreateCluster(ctx, Cluster{ID: "c", Labels: []string{"x"}})
+	require.NoError(t, err)
+
+	_, err = db.CreateNode(ctx, "c", Node{ID: "n", Address: "10.0.0.1"})
+	require.NoError(t, err)
+
+	_, err = db.CreateBenchmark(ctx, Benchmark{ID: "b", Status: BenchmarkDone})
+	require.NoError(t, err)
+
+	var report Report
+	report.Summary.TotalTime = time.Minute
+	err = db.CreateReport(ctx, "b", report)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	err = db.Backup(ctx, &buf)
+	require.NoError(t, err)
+
+	_, err = db.CreateCluster(ctx, Cluster{ID: "after"})
+	require.NoError(t, err)
+
+	err = db.Restore(ctx, bytes.NewReader(buf.Bytes()), false)
+	require.True(t, errdefs.IsAlreadyExists(err), "expected restore into non-empty store to be refused, got %v", err)
+
+	err = db.Restore(ctx, bytes.NewReader([]byte("not a backup")), true)
+	require.True(t, errdefs.IsInvalidArgument(err), "expected invalid argument, got %v", err)
+
+	_, err = db.GetCluster(ctx, "after")
+	require.NoError(t, err, "failed restore must not modify the store")
+
+	err = db.Restore(ctx, bytes.NewReader(buf.Bytes()), true)
+	require.NoError(t, err)
+
+	_, err = db.GetCluster(ctx, "after")
+	require.True(t, errdefs.IsNotFound(err), "expected not found, got %v", err)
+
+	cluster, err := db.GetCluster(ctx, "c")
+	require.NoError(t, err)
+	require.Equal(t, []string{"x"}, cluster.Labels)
+
+	node, err := db.GetNode(ctx, "c", "n")
+	require.NoError(t, err)
+	require.Equal(t, "10.0.0.1", node.Address)
+
+	actual, err := db.GetReport(ctx, "b")
+	require.NoError(t, err)
+	require.Equal(t, time.Minute, actual.Summary.TotalTime)
+
+	pending, err := db.PendingMigrations(ctx)
+	require.NoError(t, err)
+	require.Empty(t, pending)
+}
+
+func testOrdering(t *testing.T, db DB) {
+	ctx := context.Background()
+	for _, id := range []string{"c", "a", "b"} {
+		_, err := db.CreateBenchmark(ctx, Benchmark{ID: id})
+		require.NoError(t, err)
+		time.Sleep(time.Millisecond)
+	}
+
+	benchmark, err := db.GetBenchmark(ctx, "c")
+	require.NoError(t, err)
+	_, err = db.UpdateBenchmark(ctx, benchmark)
+	require.NoError(t, err)
+
+	ids := func(opts ...ListOption) []string {
+		benchmarks, err := db.ListBenchmarks(ctx, opts...)
+		require.NoError(t, err)
+
+		var ids []string
+		for _, benchmark := range benchmarks {
+			ids = append(ids, benchmark.ID)
+		}
+		return ids
+	}
+
+	require.Equal(t, []string{"a", "b", "c"}, ids())
+	require.Equal(t, []string{"c", "b", "a"}, ids(WithOrderBy(OrderByName, true)))
+	require.Equal(t, []string{"c", "a", "b"}, ids(WithOrderBy(OrderByCreated, false)))
+	require.Equal(t, []string{"b", "a", "c"}, ids(WithOrderBy(OrderByCreated, true)))
+	require.Equal(t, []string{"c"}, ids(WithOrderBy(OrderByDuration, true))[:1])
+
+	_, err = db.ListBenchmarks(ctx, WithOrderBy("size", false))
+	require.True(t, errdefs.IsInvalidArgument(err), "expected invalid argument, got %v", err)
+
+	_, err = db.ListNodes(ctx, "cluster", WithOrderBy(OrderByDuration, false))
+	require.True(t, errdefs.IsInvalidArgument(err), "expected invalid argument, got %v", err)
+}
@@ -16,19 +16,26 @@ package metadata
 
 import (
 	"context"
+	"io"
 	"path/filepath"
+	"time"
 
+	"github.com/Netflix/p2plab/errdefs"
 	"github.com/pkg/errors"
 	bolt "go.etcd.io/bbolt"
 )
 
 const (
 	schemaVersion = "v1"
+
+	// openTimeout is how long to wait for the lock of a bolt store held by
+	// another process.
+	openTimeout = 5 * time.Second
 )
 
 type transactionKey struct{}
 
-func WithTransactionContext(ctx context.Context, tx *bolt.Tx) context.Context {
+func withTransactionContext(ctx context.Context, tx *bolt.Tx) context.Context {
 	return context.WithValue(ctx, transactionKey{}, tx)
 }
 
@@ -40,10 +47,32 @@ type DB interface {
 	ReportStore
 	BenchmarkStore
 	ExperimentStore
+	IdempotencyKeyStore
+
+	// View calls fn within a read-only transaction. Store methods called with
+	// the context passed to fn are part of the transaction.
+	View(ctx context.Context, fn func(context.Context) error) error
+
+	// Update calls fn within a read-write transaction, which is committed if fn
+	// returns nil and rolled back otherwise. Store methods called with the
+	// context passed to fn are part of the transaction.
+	Update(ctx context.Context, fn func(context.Context) error) error
+
+	// PendingMigrations returns the schema migrations that have not been
+	// applied to the store yet.
+	PendingMigrations(ctx context.Context) ([]Migration, error)
+
+	// Migrate applies pending schema migrations within a single transaction
+	// and returns them.
+	Migrate(ctx context.Context) ([]Migration, error)
 
-	View(ctx context.Context, fn func(*bolt.Tx) error) error
+	// Backup writes a consistent snapshot of the store to w.
+	Backup(ctx context.Context, w io.Writer) error
 
-	Update(ctx context.Context, fn func(*bolt.Tx) error) error
+	// Restore replaces the contents of the store with a backup, migrating it
+	// to the latest schema version. Unless force is true, it refuses to
+	// overwrite a store that is not empty.
+	Restore(ctx context.Context, r io.Reader, force bool) error
 
 	Close() error
 }
@@ -65,7 +94,7 @@ type ClusterStore interface {
 type NodeStore interface {
 	GetNode(ctx context.Context, cluster, id string) (Node, error)
 
-	ListNodes(ctx context.Context, cluster string) ([]Node, error)
+	ListNodes(ctx context.Context, cluster string, opts ...ListOption) ([]Node, error)
 
 	CreateNode(ctx context.Context, cluster string, node Node) (Node, error)
 
@@ -74,6 +103,8 @@ type NodeStore interface {
 	UpdateNode(ctx context.Context, cluster string, node Node) (Node, error)
 
 	LabelNodes(ctx context.Context, cluster string, ids, adds, removes []string) ([]Node, error)
+
+	DeleteNodes(ctx context.Context, cluster string, ids ...string) error
 }
 
 type ScenarioStore interface {
@@ -109,7 +140,7 @@ type ReportStore interface {
 type BenchmarkStore interface {
 	GetBenchmark(ctx context.Context, id string) (Benchmark, error)
 
-	ListBenchmarks(ctx context.Context) ([]Benchmark, error)
+	ListBenchmarks(ctx context.Context, opts ...ListOption) ([]Benchmark, error)
 
 	CreateBenchmark(ctx context.Context, benchmark Benchmark) (Benchmark, error)
 
@@ -134,14 +165,43 @@ type ExperimentStore interface {
 	DeleteExperiment(ctx context.Context, id string) error
 }
 
+type IdempotencyKeyStore interface {
+	GetIdempotencyKey(ctx context.Context, id string) (IdempotencyKey, error)
+
+	CreateIdempotencyKey(ctx context.Context, key IdempotencyKey) (IdempotencyKey, error)
+
+	DeleteIdempotencyKey(ctx context.Context, id string) error
+
+	// DeleteExpiredIdempotencyKeys deletes the keys that expired by now.
+	DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) error
+}
+
 type db struct {
 	boltdb *bolt.DB
 }
 
+// GetDB returns the metadata store for a backend [bolt, postgres]. Bolt
+// stores are created in root, while postgres stores connect to dsn.
+func GetDB(ctx context.Context, root, backend, dsn string) (DB, error) {
+	switch backend {
+	case "bolt":
+		return NewDB(root)
+	case "postgres":
+		if dsn == "" {
+			return nil, errors.Wrap(errdefs.ErrInvalidArgument, "postgres metadata backend requires a dsn")
+		}
+		return NewPostgresDB(ctx, dsn)
+	default:
+		return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "unrecognized metadata backend %q", backend)
+	}
+}
+
 func NewDB(root string) (DB, error) {
 	path := filepath.Join(root, "meta.db")
-	boltdb, err := bolt.Open(path, 0644, nil)
-	if err != nil {
+	boltdb, err := bolt.Open(path, 0644, &bolt.Options{Timeout: openTimeout})
+	if err == bolt.ErrTimeout {
+		return nil, errors.Wrapf(errdefs.ErrUnavailable, "%s is locked by another process", path)
+	} else if err != nil {
 		return nil, err
 	}
 
@@ -152,7 +212,19 @@ func (m *db) Close() error {
 	return m.boltdb.Close()
 }
 
-func (m *db) View(ctx context.Context, fn func(*bolt.Tx) error) error {
+func (m *db) View(ctx context.Context, fn func(context.Context) error) error {
+	return m.view(ctx, func(tx *bolt.Tx) error {
+		return fn(withTransactionContext(ctx, tx))
+	})
+}
+
+func (m *db) Update(ctx context.Context, fn func(context.Context) error) error {
+	return m.update(ctx, func(tx *bolt.Tx) error {
+		return fn(withTransactionContext(ctx, tx))
+	})
+}
+
+func (m *db) view(ctx context.Context, fn func(*bolt.Tx) error) error {
 	tx, ok := ctx.Value(transactionKey{}).(*bolt.Tx)
 	if !ok {
 		return m.boltdb.View(fn)
@@ -160,7 +232,7 @@ func (m *db) View(ctx context.Context, fn func(*bolt.Tx) error) error {
 	return fn(tx)
 }
 
-func (m *db) Update(ctx context.Context, fn func(*bolt.Tx) error) error {
+func (m *db) update(ctx context.Context, fn func(*bolt.Tx) error) error {
 	tx, ok := ctx.Value(transactionKey{}).(*bolt.Tx)
 	if !ok {
 		return m.boltdb.Update(fn)
@@ -0,0 +1,48 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+// DryRun describes what a benchmark of a scenario on a cluster would execute,
+// without executing it.
+type DryRun struct {
+	Cluster string
+
+	Scenario string
+
+	// Nodes is the number of nodes in the cluster.
+	Nodes int
+
+	// Queries maps each benchmark query to the IDs of the nodes it matches.
+	Queries map[string][]string
+
+	// Objects are the objects of the scenario transformed into IPLD DAGs.
+	Objects map[string]DryRunObject
+
+	// Seed and Benchmark are the tasks each node would execute in each stage.
+	Seed, Benchmark ScenarioStage
+
+	// Steps are the steps that would replace the stages, if any.
+	Steps []ScenarioStep `json:",omitempty"`
+
+	// Seeders and Leechers are set if the scenario seeds a subset of nodes.
+	Seeders, Leechers []string `json:",omitempty"`
+}
+
+type DryRunObject struct {
+	CID string
+
+	// Size is the cumulative size of the object's DAG in bytes.
+	Size uint64
+}
@@ -16,6 +16,7 @@ package metadata
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
 	"github.com/Netflix/p2plab/errdefs"
@@ -30,6 +31,10 @@ type Experiment struct {
 
 	Definition ExperimentDefinition
 
+	// Trials are the parameter combinations of a sweep, in the order they
+	// were expanded, and the benchmark each one ran as.
+	Trials []ExperimentTrial `json:",omitempty"`
+
 	Labels []string
 
 	CreatedAt, UpdatedAt time.Time
@@ -54,10 +59,28 @@ type ExperimentDefinition struct {
 
 type IndependentVariable map[string]interface{}
 
+// ExperimentTrial is a single combination of a parameter sweep. A trial that
+// is neither finished nor failed is still pending.
+type ExperimentTrial struct {
+	Parameters map[string]string
+
+	// Benchmark is the ID of the benchmark the trial ran as.
+	Benchmark string `json:",omitempty"`
+
+	// Error is set when the trial failed to run.
+	Error string `json:",omitempty"`
+}
+
+// Done returns whether the trial has finished, either with a benchmark or an
+// error.
+func (t ExperimentTrial) Done() bool {
+	return t.Benchmark != "" || t.Error != ""
+}
+
 func (m *db) GetExperiment(ctx context.Context, id string) (Experiment, error) {
 	var experiment Experiment
 
-	err := m.View(ctx, func(tx *bolt.Tx) error {
+	err := m.view(ctx, func(tx *bolt.Tx) error {
 		bkt := getExperimentsBucket(tx)
 		if bkt == nil {
 			return errors.Wrapf(errdefs.ErrNotFound, "experiment %q", id)
@@ -85,7 +108,7 @@ func (m *db) GetExperiment(ctx context.Context, id string) (Experiment, error) {
 
 func (m *db) ListExperiments(ctx context.Context) ([]Experiment, error) {
 	var experiments []Experiment
-	err := m.View(ctx, func(tx *bolt.Tx) error {
+	err := m.view(ctx, func(tx *bolt.Tx) error {
 		bkt := getExperimentsBucket(tx)
 		if bkt == nil {
 			return nil
@@ -116,7 +139,7 @@ func (m *db) ListExperiments(ctx context.Context) ([]Experiment, error) {
 }
 
 func (m *db) CreateExperiment(ctx context.Context, experiment Experiment) (Experiment, error) {
-	err := m.Update(ctx, func(tx *bolt.Tx) error {
+	err := m.update(ctx, func(tx *bolt.Tx) error {
 		bkt, err := createExperimentsBucket(tx)
 		if err != nil {
 			return err
@@ -146,7 +169,7 @@ func (m *db) UpdateExperiment(ctx context.Context, experiment Experiment) (Exper
 		return Experiment{}, errors.Wrapf(errdefs.ErrInvalidArgument, "experiment id required for update")
 	}
 
-	err := m.Update(ctx, func(tx *bolt.Tx) error {
+	err := m.update(ctx, func(tx *bolt.Tx) error {
 		bkt, err := createExperimentsBucket(tx)
 		if err != nil {
 			return err
@@ -169,7 +192,7 @@ func (m *db) UpdateExperiment(ctx context.Context, experiment Experiment) (Exper
 
 func (m *db) LabelExperiments(ctx context.Context, ids, adds, removes []string) ([]Experiment, error) {
 	var experiments []Experiment
-	err := m.Update(ctx, func(tx *bolt.Tx) error {
+	err := m.update(ctx, func(tx *bolt.Tx) error {
 		bkt, err := createExperimentsBucket(tx)
 		if err != nil {
 			return err
@@ -207,7 +230,7 @@ func (m *db) LabelExperiments(ctx context.Context, ids, adds, removes []string)
 }
 
 func (m *db) DeleteExperiment(ctx context.Context, id string) error {
-	return m.Update(ctx, func(tx *bolt.Tx) error {
+	return m.update(ctx, func(tx *bolt.Tx) error {
 		bkt := getExperimentsBucket(tx)
 		if bkt == nil {
 			return nil
@@ -251,6 +274,8 @@ func readExperiment(bkt *bolt.Bucket, experiment *Experiment) error {
 			experiment.ID = string(v)
 		case string(bucketKeyStatus):
 			experiment.Status = ExperimentStatus(v)
+		case string(bucketKeyTrials):
+			return json.Unmarshal(v, &experiment.Trials)
 		}
 
 		return nil
@@ -302,6 +327,18 @@ func writeExperiment(bkt *bolt.Bucket, experiment *Experiment) error {
 		return err
 	}
 
+	if len(experiment.Trials) > 0 {
+		content, err := json.Marshal(experiment.Trials)
+		if err != nil {
+			return err
+		}
+
+		err = bkt.Put(bucketKeyTrials, content)
+		if err != nil {
+			return err
+		}
+	}
+
 	for _, f := range []field{
 		{bucketKeyID, []byte(experiment.ID)},
 		{bucketKeyStatus, []byte(experiment.Status)},
@@ -0,0 +1,125 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/pkg/errors"
+)
+
+// Field is a field of a resource that lists can be projected to.
+type Field struct {
+	Name string
+
+	// Keys are the top-level JSON keys of the resource the field is derived
+	// from.
+	Keys []string
+
+	Value func(v interface{}) interface{}
+}
+
+var nodeFields = []Field{
+	{"name", []string{"ID"}, func(v interface{}) interface{} { return v.(Node).ID }},
+	{"address", []string{"Address"}, func(v interface{}) interface{} { return v.(Node).Address }},
+	{"agentPort", []string{"AgentPort"}, func(v interface{}) interface{} { return v.(Node).AgentPort }},
+	{"appPort", []string{"AppPort"}, func(v interface{}) interface{} { return v.(Node).AppPort }},
+	{"gitReference", []string{"Peer"}, func(v interface{}) interface{} { return v.(Node).Peer.GitReference }},
+	{"transports", []string{"Peer"}, func(v interface{}) interface{} { return v.(Node).Peer.Transports }},
+	{"routing", []string{"Peer"}, func(v interface{}) interface{} { return v.(Node).Peer.Routing }},
+	{"datastore", []string{"Peer"}, func(v interface{}) interface{} { return v.(Node).Peer.Datastore }},
+	{"labels", []string{"Labels"}, func(v interface{}) interface{} { return v.(Node).Labels }},
+	{"createdAt", []string{"CreatedAt"}, func(v interface{}) interface{} { return v.(Node).CreatedAt }},
+	{"updatedAt", []string{"UpdatedAt"}, func(v interface{}) interface{} { return v.(Node).UpdatedAt }},
+}
+
+var benchmarkFields = []Field{
+	{"name", []string{"ID"}, func(v interface{}) interface{} { return v.(Benchmark).ID }},
+	{"status", []string{"Status"}, func(v interface{}) interface{} { return v.(Benchmark).Status }},
+	{"cluster", []string{"Cluster"}, func(v interface{}) interface{} { return v.(Benchmark).Cluster.ID }},
+	{"scenario", []string{"Scenario"}, func(v interface{}) interface{} { return v.(Benchmark).Scenario.ID }},
+	{"generation", []string{"Generation"}, func(v interface{}) interface{} { return v.(Benchmark).Generation }},
+	{"labels", []string{"Labels"}, func(v interface{}) interface{} { return v.(Benchmark).Labels }},
+	{"createdAt", []string{"CreatedAt"}, func(v interface{}) interface{} { return v.(Benchmark).CreatedAt }},
+	{"updatedAt", []string{"UpdatedAt"}, func(v interface{}) interface{} { return v.(Benchmark).UpdatedAt }},
+	{"duration", []string{"CreatedAt", "UpdatedAt"}, func(v interface{}) interface{} {
+		b := v.(Benchmark)
+		return b.UpdatedAt.Sub(b.CreatedAt)
+	}},
+}
+
+// LookupFields returns the fields of a resource with the given names.
+func LookupFields(v interface{}, names []string) ([]Field, error) {
+	var fields []Field
+	switch v.(type) {
+	case Node:
+		fields = nodeFields
+	case Benchmark:
+		fields = benchmarkFields
+	default:
+		return nil, errors.Wrapf(errdefs.ErrNotImplemented, "%T does not support fields", v)
+	}
+
+	var valid []string
+	for _, field := range fields {
+		valid = append(valid, field.Name)
+	}
+
+	var lookup []Field
+	for _, name := range names {
+		found := false
+		for _, field := range fields {
+			if field.Name == name {
+				lookup = append(lookup, field)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "unknown field %q, must be one of [%s]", name, strings.Join(valid, ", "))
+		}
+	}
+	return lookup, nil
+}
+
+// ProjectJSON returns the JSON object of a resource with only the keys needed
+// by the fields with the given names.
+func ProjectJSON(v interface{}, names []string) (map[string]json.RawMessage, error) {
+	fields, err := LookupFields(v, names)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var object map[string]json.RawMessage
+	err = json.Unmarshal(content, &object)
+	if err != nil {
+		return nil, err
+	}
+
+	projection := make(map[string]json.RawMessage)
+	for _, field := range fields {
+		for _, key := range field.Keys {
+			projection[key] = object[key]
+		}
+	}
+	return projection, nil
+}
@@ -0,0 +1,44 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProjectJSON(t *testing.T) {
+	node := Node{ID: "n", Address: "10.0.0.1", AgentPort: 7002, Peer: PeerDefinition{GitReference: "abc"}}
+	projection, err := ProjectJSON(node, []string{"name", "gitReference"})
+	require.NoError(t, err)
+	require.Len(t, projection, 2)
+	require.Contains(t, projection, "ID")
+	require.Contains(t, projection, "Peer")
+
+	content, err := json.Marshal(projection)
+	require.NoError(t, err)
+
+	var actual Node
+	err = json.Unmarshal(content, &actual)
+	require.NoError(t, err)
+	require.Equal(t, Node{ID: "n", Peer: PeerDefinition{GitReference: "abc"}}, actual)
+
+	_, err = ProjectJSON(node, []string{"name", "size"})
+	require.True(t, errdefs.IsInvalidArgument(err), "expected invalid argument, got %v", err)
+	require.Contains(t, err.Error(), "address")
+}
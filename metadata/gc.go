@@ -0,0 +1,81 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"encoding/json"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+// GCDefinition garbage collects the blockstore of every node once the
+// benchmark finishes, so that content doesn't accumulate and skew the
+// benchmarks that follow.
+type GCDefinition struct {
+	// Keep are the names of objects of the scenario whose blocks are kept in
+	// the blockstores, so that the next benchmark starts with a warm cache.
+	Keep []string `json:"keep,omitempty"`
+}
+
+// Validate returns an error if an object kept is not defined by the scenario.
+func (d GCDefinition) Validate(objects map[string]ObjectDefinition) error {
+	for _, name := range d.Keep {
+		if _, ok := objects[name]; !ok {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "gc keeps undefined object %q", name)
+		}
+	}
+	return nil
+}
+
+// GarbageCollection is the result of garbage collecting the blockstore of a
+// node.
+type GarbageCollection struct {
+	BlocksRemoved int
+
+	BytesFreed int64
+
+	// SizeBefore and SizeAfter are the bytes of the blocks in the blockstore
+	// before and after the collection.
+	SizeBefore int64
+	SizeAfter  int64
+}
+
+func readGC(bkt *bolt.Bucket) (*GCDefinition, error) {
+	v := bkt.Get(bucketKeyGC)
+	if v == nil {
+		return nil, nil
+	}
+
+	var gc GCDefinition
+	err := json.Unmarshal(v, &gc)
+	if err != nil {
+		return nil, err
+	}
+	return &gc, nil
+}
+
+func writeGC(bkt *bolt.Bucket, gc *GCDefinition) error {
+	if gc == nil {
+		return nil
+	}
+
+	content, err := json.Marshal(gc)
+	if err != nil {
+		return err
+	}
+	return bkt.Put(bucketKeyGC, content)
+}
@@ -0,0 +1,31 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"testing"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGCDefinitionValidate(t *testing.T) {
+	objects := map[string]ObjectDefinition{"image": {Type: "oci"}}
+	require.NoError(t, GCDefinition{}.Validate(objects))
+	require.NoError(t, GCDefinition{Keep: []string{"image"}}.Validate(objects))
+
+	err := GCDefinition{Keep: []string{"image", "other"}}.Validate(objects)
+	require.True(t, errdefs.IsInvalidArgument(err), "expected invalid argument, got %v", err)
+}
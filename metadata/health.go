@@ -0,0 +1,107 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"fmt"
+	"time"
+)
+
+// Health is the status of a daemon and of the components it depends on.
+type Health struct {
+	// Live is true if the daemon process is up, which is always the case when
+	// it responds.
+	Live bool
+
+	// Ready is true if no component is unavailable, so that the daemon can
+	// serve requests such as benchmarks.
+	Ready bool
+
+	Components []HealthComponent
+
+	// RateLimits are the limits on the requests of each client, so that
+	// clients can pace themselves.
+	RateLimits []RateLimit `json:",omitempty"`
+}
+
+type HealthComponent struct {
+	Name string
+
+	Status HealthStatus
+
+	Message string `json:",omitempty"`
+}
+
+// HealthStatus is the status of a component of a daemon.
+type HealthStatus string
+
+var (
+	HealthOK HealthStatus = "ok"
+
+	// HealthDegraded indicates a problem with the component that doesn't
+	// prevent the daemon from serving requests.
+	HealthDegraded HealthStatus = "degraded"
+
+	HealthUnavailable HealthStatus = "unavailable"
+)
+
+// NewHealth returns the health of a live daemon with the given components,
+// which is ready unless a component is unavailable.
+func NewHealth(components []HealthComponent) Health {
+	health := Health{
+		Live:       true,
+		Ready:      true,
+		Components: components,
+	}
+	for _, c := range components {
+		if c.Status == HealthUnavailable {
+			health.Ready = false
+		}
+	}
+	return health
+}
+
+// RateLimit is a limit on a class of requests of a client, allowing Requests
+// per Interval which may all be made at once.
+type RateLimit struct {
+	// Class is the class of requests limited, either RateLimitRead or
+	// RateLimitWrite.
+	Class string
+
+	Requests int
+
+	Interval time.Duration
+}
+
+const (
+	// RateLimitRead is the class of requests that don't modify resources.
+	RateLimitRead = "read"
+
+	// RateLimitWrite is the class of requests that create or modify resources,
+	// such as benchmark submissions.
+	RateLimitWrite = "write"
+)
+
+// Unlimited returns true if the limit doesn't restrict any request.
+func (l RateLimit) Unlimited() bool {
+	return l.Requests <= 0 || l.Interval <= 0
+}
+
+func (l RateLimit) String() string {
+	if l.Unlimited() {
+		return "unlimited"
+	}
+	return fmt.Sprintf("%d/%s", l.Requests, l.Interval)
+}
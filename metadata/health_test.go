@@ -0,0 +1,40 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewHealth(t *testing.T) {
+	health := NewHealth(nil)
+	require.True(t, health.Live)
+	require.True(t, health.Ready)
+
+	health = NewHealth([]HealthComponent{
+		{Name: "metadata", Status: HealthOK},
+		{Name: "agents", Status: HealthDegraded},
+	})
+	require.True(t, health.Ready)
+
+	health = NewHealth([]HealthComponent{
+		{Name: "metadata", Status: HealthUnavailable},
+		{Name: "agents", Status: HealthOK},
+	})
+	require.True(t, health.Live)
+	require.False(t, health.Ready)
+}
@@ -126,50 +126,18 @@ func writeMap(bkt *bolt.Bucket, name []byte, m map[string]string) error {
 type labelCallback func(bkt *bolt.Bucket, id string, labels []string) error
 
 func batchUpdateLabels(bkt *bolt.Bucket, ids, adds, removes []string, cb labelCallback) error {
-	if len(ids) == 0 {
-		return nil
-	}
-
-	addSet := make(map[string]struct{})
-	for _, l := range adds {
-		addSet[l] = struct{}{}
-	}
-
-	removeSet := make(map[string]struct{})
-	for _, l := range removes {
-		removeSet[l] = struct{}{}
-	}
-
 	for _, id := range ids {
 		ibkt := bkt.Bucket([]byte(id))
 		if ibkt == nil {
 			return errors.Wrapf(errdefs.ErrNotFound, "%q", id)
 		}
 
-		var labels []string
-		lbkt := ibkt.Bucket(bucketKeyLabels)
-		if lbkt != nil {
-			err := lbkt.ForEach(func(k, v []byte) error {
-				if _, ok := removeSet[string(k)]; ok {
-					return nil
-				}
-
-				labels = append(labels, string(k))
-				delete(addSet, string(k))
-				return nil
-			})
-			if err != nil {
-				return err
-			}
-
-		}
-
-		for l, _ := range addSet {
-			labels = append(labels, l)
+		labels, err := readLabels(ibkt)
+		if err != nil {
+			return err
 		}
-		sort.Strings(labels)
 
-		err := cb(ibkt, id, labels)
+		err = cb(ibkt, id, mergeLabels(labels, adds, removes))
 		if err != nil {
 			return err
 		}
@@ -178,6 +146,32 @@ func batchUpdateLabels(bkt *bolt.Bucket, ids, adds, removes []string, cb labelCa
 	return nil
 }
 
+// mergeLabels returns the sorted set of labels with removes removed and then
+// adds added.
+func mergeLabels(labels, adds, removes []string) []string {
+	removeSet := make(map[string]struct{})
+	for _, l := range removes {
+		removeSet[l] = struct{}{}
+	}
+
+	labelSet := make(map[string]struct{})
+	for _, l := range labels {
+		if _, ok := removeSet[l]; !ok {
+			labelSet[l] = struct{}{}
+		}
+	}
+	for _, l := range adds {
+		labelSet[l] = struct{}{}
+	}
+
+	var merged []string
+	for l := range labelSet {
+		merged = append(merged, l)
+	}
+	sort.Strings(merged)
+	return merged
+}
+
 func readLabels(bkt *bolt.Bucket) ([]string, error) {
 	var labels []string
 	lbkt := bkt.Bucket(bucketKeyLabels)
@@ -0,0 +1,149 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"math"
+	"math/bits"
+	"sort"
+	"time"
+)
+
+// histogramPrecision is the number of bits of precision of the buckets of a
+// Histogram, so that bucket widths are within 1/2^histogramPrecision of their
+// values.
+const histogramPrecision = 6
+
+// Histogram is a streaming digest of latencies. Samples are counted in
+// log-linear buckets, similar to an HDR histogram, so percentiles are
+// estimated within 2% in memory bounded by the range of the samples
+// rather than their count. Histograms of different nodes or benchmarks merge
+// by adding their counts.
+type Histogram struct {
+	// Counts is the number of samples in each non-empty bucket.
+	Counts map[int]uint64 `json:",omitempty"`
+
+	Count uint64
+
+	Min, Max time.Duration
+}
+
+// Record adds a sample to the histogram. Negative samples are recorded as
+// zero.
+func (h *Histogram) Record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+
+	if h.Counts == nil {
+		h.Counts = make(map[int]uint64)
+	}
+	h.Counts[histogramBucket(uint64(d))]++
+
+	if h.Count == 0 || d < h.Min {
+		h.Min = d
+	}
+	if d > h.Max {
+		h.Max = d
+	}
+	h.Count++
+}
+
+// Merge adds the samples of o to the histogram.
+func (h *Histogram) Merge(o Histogram) {
+	if o.Count == 0 {
+		return
+	}
+
+	if h.Counts == nil {
+		h.Counts = make(map[int]uint64, len(o.Counts))
+	}
+	for i, n := range o.Counts {
+		h.Counts[i] += n
+	}
+
+	if h.Count == 0 || o.Min < h.Min {
+		h.Min = o.Min
+	}
+	if o.Max > h.Max {
+		h.Max = o.Max
+	}
+	h.Count += o.Count
+}
+
+// Percentile returns the nearest-rank p-th percentile of the samples, as the
+// highest value of its bucket bounded by the min and max samples.
+func (h Histogram) Percentile(p float64) time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+
+	// The lowest and highest samples are known exactly.
+	rank := uint64(math.Ceil(p / 100 * float64(h.Count)))
+	if rank <= 1 {
+		return h.Min
+	}
+	if rank >= h.Count {
+		return h.Max
+	}
+
+	var buckets []int
+	for i := range h.Counts {
+		buckets = append(buckets, i)
+	}
+	sort.Ints(buckets)
+
+	var seen uint64
+	for _, i := range buckets {
+		seen += h.Counts[i]
+		if seen >= rank {
+			v := time.Duration(histogramBucketMax(i))
+			if v < h.Min {
+				return h.Min
+			}
+			if v > h.Max {
+				return h.Max
+			}
+			return v
+		}
+	}
+	return h.Max
+}
+
+// histogramBucket returns the bucket of v. Values below 2^histogramPrecision
+// have a bucket of their own, the others share buckets with the values of the
+// same magnitude and the same leading histogramPrecision+1 bits.
+func histogramBucket(v uint64) int {
+	const sub = 1 << histogramPrecision
+	if v < sub {
+		return int(v)
+	}
+
+	shift := uint(bits.Len64(v) - 1 - histogramPrecision)
+	mantissa := v >> shift
+	return sub + int(shift)*sub + int(mantissa-sub)
+}
+
+// histogramBucketMax returns the highest value of bucket i.
+func histogramBucketMax(i int) uint64 {
+	const sub = 1 << histogramPrecision
+	if i < sub {
+		return uint64(i)
+	}
+
+	shift := uint((i - sub) / sub)
+	mantissa := uint64(sub + (i-sub)%sub)
+	return (mantissa+1)<<shift - 1
+}
@@ -0,0 +1,79 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistogramPercentile(t *testing.T) {
+	var h Histogram
+	require.Equal(t, time.Duration(0), h.Percentile(50))
+
+	for i := 1; i <= 10000; i++ {
+		h.Record(time.Duration(i) * time.Microsecond)
+	}
+	require.Equal(t, uint64(10000), h.Count)
+	require.Equal(t, time.Microsecond, h.Min)
+	require.Equal(t, 10*time.Millisecond, h.Max)
+	require.Equal(t, h.Min, h.Percentile(0))
+	require.Equal(t, h.Max, h.Percentile(100))
+
+	for p, expected := range map[float64]time.Duration{
+		50:   5 * time.Millisecond,
+		90:   9 * time.Millisecond,
+		99:   9900 * time.Microsecond,
+		99.9: 9990 * time.Microsecond,
+	} {
+		actual := h.Percentile(p)
+		require.True(t, actual >= expected, "p%v: %s < %s", p, actual, expected)
+		require.InEpsilon(t, float64(expected), float64(actual), 0.02, "p%v", p)
+	}
+
+	// Samples of the same magnitude share buckets, so memory is bounded by
+	// the range of the samples.
+	require.True(t, len(h.Counts) < 1000, "%d buckets", len(h.Counts))
+}
+
+func TestHistogramMerge(t *testing.T) {
+	var a, b, all Histogram
+	for i := 1; i <= 1000; i++ {
+		d := time.Duration(i*i) * time.Microsecond
+		all.Record(d)
+		if i%2 == 0 {
+			a.Record(d)
+		} else {
+			b.Record(d)
+		}
+	}
+
+	var merged Histogram
+	merged.Merge(a)
+	merged.Merge(Histogram{})
+	merged.Merge(b)
+	require.Equal(t, all, merged)
+
+	content, err := json.Marshal(&merged)
+	require.NoError(t, err)
+
+	var decoded Histogram
+	err = json.Unmarshal(content, &decoded)
+	require.NoError(t, err)
+	require.Equal(t, all.Percentile(99), decoded.Percentile(99))
+}
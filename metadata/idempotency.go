@@ -0,0 +1,196 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"time"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+// IdempotencyKey records the benchmark created by a request with an
+// idempotency key, so that retries of the request return the same benchmark
+// until the key expires.
+type IdempotencyKey struct {
+	// ID is the idempotency key scoped by the client that sent it.
+	ID string
+
+	Benchmark string
+
+	ExpiresAt time.Time
+
+	CreatedAt, UpdatedAt time.Time
+}
+
+// Expired returns whether the key ran out by now.
+func (k IdempotencyKey) Expired(now time.Time) bool {
+	return !now.Before(k.ExpiresAt)
+}
+
+func (m *db) GetIdempotencyKey(ctx context.Context, id string) (IdempotencyKey, error) {
+	var key IdempotencyKey
+
+	err := m.view(ctx, func(tx *bolt.Tx) error {
+		bkt := getIdempotencyKeyBucket(tx, id)
+		if bkt == nil {
+			return errors.Wrapf(errdefs.ErrNotFound, "idempotency key %q", id)
+		}
+
+		key.ID = id
+		err := readIdempotencyKey(bkt, &key)
+		if err != nil {
+			return errors.Wrapf(err, "idempotency key %q", id)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return IdempotencyKey{}, err
+	}
+
+	return key, nil
+}
+
+func (m *db) CreateIdempotencyKey(ctx context.Context, key IdempotencyKey) (IdempotencyKey, error) {
+	err := m.update(ctx, func(tx *bolt.Tx) error {
+		bkt, err := createIdempotencyKeysBucket(tx)
+		if err != nil {
+			return err
+		}
+
+		kbkt, err := bkt.CreateBucket([]byte(key.ID))
+		if err != nil {
+			if err != bolt.ErrBucketExists {
+				return err
+			}
+
+			return errors.Wrapf(errdefs.ErrAlreadyExists, "idempotency key %q", key.ID)
+		}
+
+		key.CreatedAt = time.Now().UTC()
+		key.UpdatedAt = key.CreatedAt
+		return writeIdempotencyKey(kbkt, &key)
+	})
+	if err != nil {
+		return IdempotencyKey{}, err
+	}
+	return key, nil
+}
+
+func (m *db) DeleteIdempotencyKey(ctx context.Context, id string) error {
+	return m.update(ctx, func(tx *bolt.Tx) error {
+		bkt := getIdempotencyKeysBucket(tx)
+		if bkt == nil {
+			return errors.Wrapf(errdefs.ErrNotFound, "idempotency key %q", id)
+		}
+
+		err := bkt.DeleteBucket([]byte(id))
+		if err != nil {
+			if err == bolt.ErrBucketNotFound {
+				return errors.Wrapf(errdefs.ErrNotFound, "idempotency key %q", id)
+			}
+			return err
+		}
+
+		return nil
+	})
+}
+
+func (m *db) DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) error {
+	return m.update(ctx, func(tx *bolt.Tx) error {
+		bkt := getIdempotencyKeysBucket(tx)
+		if bkt == nil {
+			return nil
+		}
+
+		var expired [][]byte
+		err := bkt.ForEach(func(k, v []byte) error {
+			var key IdempotencyKey
+			err := readIdempotencyKey(bkt.Bucket(k), &key)
+			if err != nil {
+				return err
+			}
+
+			if key.Expired(now) {
+				expired = append(expired, k)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, k := range expired {
+			err = bkt.DeleteBucket(k)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func readIdempotencyKey(bkt *bolt.Bucket, key *IdempotencyKey) error {
+	err := ReadTimestamps(bkt, &key.CreatedAt, &key.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	return bkt.ForEach(func(k, v []byte) error {
+		if v == nil {
+			return nil
+		}
+
+		switch string(k) {
+		case string(bucketKeyID):
+			key.ID = string(v)
+		case string(bucketKeyBenchmark):
+			key.Benchmark = string(v)
+		case string(bucketKeyExpiresAt):
+			return key.ExpiresAt.UnmarshalBinary(v)
+		}
+
+		return nil
+	})
+}
+
+func writeIdempotencyKey(bkt *bolt.Bucket, key *IdempotencyKey) error {
+	err := WriteTimestamps(bkt, key.CreatedAt, key.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	expiresAt, err := key.ExpiresAt.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	for _, f := range []field{
+		{bucketKeyID, []byte(key.ID)},
+		{bucketKeyBenchmark, []byte(key.Benchmark)},
+		{bucketKeyExpiresAt, expiresAt},
+	} {
+		err = bkt.Put(f.key, f.value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
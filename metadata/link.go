@@ -0,0 +1,44 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"time"
+)
+
+// NodeLink is the result of a node sending a synthetic payload to a target
+// node over libp2p to measure the throughput of the link between them.
+type NodeLink struct {
+	Source string
+
+	Target string
+
+	// Bytes is the size of the payload.
+	Bytes int64
+
+	// Duration is how long it took for the target to receive the payload.
+	Duration time.Duration `json:",omitempty"`
+
+	Error string `json:",omitempty"`
+}
+
+// Mbps returns the measured throughput in megabits per second, or zero if the
+// transfer failed.
+func (l NodeLink) Mbps() float64 {
+	if l.Error != "" || l.Duration <= 0 {
+		return 0
+	}
+	return float64(l.Bytes*8) / l.Duration.Seconds() / 1e6
+}
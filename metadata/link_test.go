@@ -0,0 +1,32 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNodeLinkMbps(t *testing.T) {
+	link := NodeLink{Bytes: 100 * 1000 * 1000, Duration: 8 * time.Second}
+	require.Equal(t, 100.0, link.Mbps())
+
+	link.Error = "failed to open stream"
+	require.Zero(t, link.Mbps())
+
+	require.Zero(t, NodeLink{Bytes: 1}.Mbps())
+}
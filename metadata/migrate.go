@@ -0,0 +1,281 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Migration is a versioned change to the schema of the metadata store.
+type Migration struct {
+	Version     int
+	Description string
+}
+
+type migration struct {
+	Migration
+	bolt     func(tx *bolt.Tx) error
+	postgres func(ctx context.Context, tx *postgresTx) error
+}
+
+// migrations is the ordered registry of schema migrations. Versions start at 1
+// and must be contiguous, a store at version N has applied the first N
+// migrations. Append new migrations to the end, never modify released ones.
+var migrations = []migration{
+	{
+		Migration: Migration{Version: 1, Description: "create initial schema"},
+		bolt: func(tx *bolt.Tx) error {
+			for _, key := range [][]byte{bucketKeyClusters, bucketKeyScenarios, bucketKeyBuilds, bucketKeyBenchmarks, bucketKeyExperiments} {
+				_, err := createBucketIfNotExists(tx, bucketKeyVersion, key)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		postgres: func(ctx context.Context, tx *postgresTx) error {
+			for _, stmt := range postgresSchema {
+				_, err := tx.ExecContext(ctx, stmt)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Migration: Migration{Version: 2, Description: "create idempotency keys"},
+		bolt: func(tx *bolt.Tx) error {
+			_, err := createIdempotencyKeysBucket(tx)
+			return err
+		},
+		postgres: func(ctx context.Context, tx *postgresTx) error {
+			_, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS idempotency_keys (id TEXT PRIMARY KEY, doc JSONB NOT NULL)`)
+			return err
+		},
+	},
+}
+
+// LatestSchemaVersion is the schema version of a fully migrated store.
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// pendingMigrations returns the migrations to apply to a store at version.
+func pendingMigrations(version int) ([]migration, error) {
+	latest := LatestSchemaVersion()
+	if version > latest {
+		return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "metadata schema version %d is newer than the latest supported version %d, refusing to downgrade", version, latest)
+	}
+
+	return migrations[version:], nil
+}
+
+func migrationsOf(pending []migration) []Migration {
+	var ms []Migration
+	for _, m := range pending {
+		ms = append(ms, m.Migration)
+	}
+	return ms
+}
+
+func logMigration(ctx context.Context, m migration) {
+	zerolog.Ctx(ctx).Info().Int("version", m.Version).Str("description", m.Description).Msg("Applying metadata migration")
+}
+
+func (m *db) PendingMigrations(ctx context.Context) ([]Migration, error) {
+	var pending []migration
+	err := m.view(ctx, func(tx *bolt.Tx) error {
+		version, err := getBoltSchemaVersion(tx)
+		if err != nil {
+			return err
+		}
+
+		pending, err = pendingMigrations(version)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return migrationsOf(pending), nil
+}
+
+func (m *db) Migrate(ctx context.Context) ([]Migration, error) {
+	var applied []migration
+	err := m.update(ctx, func(tx *bolt.Tx) error {
+		var err error
+		applied, err = migrateBolt(ctx, tx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return migrationsOf(applied), nil
+}
+
+func migrateBolt(ctx context.Context, tx *bolt.Tx) ([]migration, error) {
+	version, err := getBoltSchemaVersion(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := pendingMigrations(version)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, migration := range pending {
+		logMigration(ctx, migration)
+		err = migration.bolt(tx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to apply metadata migration %d", migration.Version)
+		}
+	}
+
+	if len(pending) == 0 {
+		return nil, nil
+	}
+
+	bkt, err := createBucketIfNotExists(tx, bucketKeyVersion)
+	if err != nil {
+		return nil, err
+	}
+	return pending, bkt.Put(bucketKeySchemaVersion, []byte(strconv.Itoa(LatestSchemaVersion())))
+}
+
+func getBoltSchemaVersion(tx *bolt.Tx) (int, error) {
+	bkt := getBucket(tx, bucketKeyVersion)
+	if bkt == nil {
+		return 0, nil
+	}
+
+	v := bkt.Get(bucketKeySchemaVersion)
+	if v == nil {
+		return 0, nil
+	}
+
+	version, err := strconv.Atoi(string(v))
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid metadata schema version %q", v)
+	}
+	return version, nil
+}
+
+func (m *pgdb) PendingMigrations(ctx context.Context) ([]Migration, error) {
+	var pending []migration
+	err := m.view(ctx, func(tx *postgresTx) error {
+		version, err := getPostgresSchemaVersion(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		pending, err = pendingMigrations(version)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return migrationsOf(pending), nil
+}
+
+func (m *pgdb) Migrate(ctx context.Context) ([]Migration, error) {
+	var applied []migration
+	err := m.update(ctx, func(tx *postgresTx) error {
+		err := lockPostgresSchemaVersion(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		version, err := getPostgresSchemaVersion(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		applied, err = pendingMigrations(version)
+		if err != nil {
+			return err
+		}
+
+		return applyPostgresMigrations(ctx, tx, applied)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return migrationsOf(applied), nil
+}
+
+// lockPostgresSchemaVersion creates the schema version table if necessary and
+// locks it to serialize schema changes from multiple daemons.
+func lockPostgresSchemaVersion(ctx context.Context, tx *postgresTx) error {
+	_, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_version (version INTEGER NOT NULL)`)
+	if err != nil {
+		return errors.Wrap(err, "failed to create schema version table")
+	}
+
+	_, err = tx.ExecContext(ctx, `LOCK TABLE schema_version IN EXCLUSIVE MODE`)
+	return err
+}
+
+func applyPostgresMigrations(ctx context.Context, tx *postgresTx, ms []migration) error {
+	if len(ms) == 0 {
+		return nil
+	}
+
+	for _, migration := range ms {
+		logMigration(ctx, migration)
+		err := migration.postgres(ctx, tx)
+		if err != nil {
+			return errors.Wrapf(err, "failed to apply metadata migration %d", migration.Version)
+		}
+	}
+
+	_, err := tx.ExecContext(ctx, `DELETE FROM schema_version`)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO schema_version (version) VALUES ($1)`, ms[len(ms)-1].Version)
+	return err
+}
+
+func getPostgresSchemaVersion(ctx context.Context, tx *postgresTx) (int, error) {
+	var exists bool
+	err := tx.QueryRowContext(ctx, `SELECT to_regclass('schema_version') IS NOT NULL`).Scan(&exists)
+	if err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, nil
+	}
+
+	var version int
+	err = tx.QueryRowContext(ctx, `SELECT version FROM schema_version`).Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	return version, nil
+}
@@ -0,0 +1,78 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestMigrationsOrdered(t *testing.T) {
+	for i, m := range migrations {
+		require.Equal(t, i+1, m.Version, "migration versions must be contiguous")
+		require.NotEmpty(t, m.Description)
+		require.NotNil(t, m.bolt, "migration %d missing bolt implementation", m.Version)
+		require.NotNil(t, m.postgres, "migration %d missing postgres implementation", m.Version)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	root, err := ioutil.TempDir("", "p2plab-metadata")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	store, err := NewDB(root)
+	require.NoError(t, err)
+	defer store.Close()
+
+	ctx := context.Background()
+	pending, err := store.PendingMigrations(ctx)
+	require.NoError(t, err)
+	require.Len(t, pending, len(migrations))
+
+	applied, err := store.Migrate(ctx)
+	require.NoError(t, err)
+	require.Equal(t, pending, applied)
+
+	applied, err = store.Migrate(ctx)
+	require.NoError(t, err)
+	require.Empty(t, applied)
+
+	err = store.(*db).boltdb.Update(func(tx *bolt.Tx) error {
+		return getBucket(tx, bucketKeyVersion).Put(bucketKeySchemaVersion, []byte(strconv.Itoa(LatestSchemaVersion()+1)))
+	})
+	require.NoError(t, err)
+
+	_, err = store.Migrate(ctx)
+	require.True(t, errdefs.IsInvalidArgument(err), "expected downgrade to be refused, got %v", err)
+
+	var buf bytes.Buffer
+	err = store.Backup(ctx, &buf)
+	require.NoError(t, err)
+
+	fresh, cleanup := newBoltTestDB(t)
+	defer cleanup()
+
+	err = fresh.Restore(ctx, &buf, false)
+	require.True(t, errdefs.IsInvalidArgument(err), "expected restoring a newer backup to be refused, got %v", err)
+}
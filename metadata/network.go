@@ -0,0 +1,130 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+// NetworkSpec describes impairments applied to the network of every node
+// while a benchmark runs. The zero value is the unimpaired baseline.
+type NetworkSpec struct {
+	// Latency is the delay added to outgoing packets, such as "100ms".
+	Latency string `json:"latency,omitempty"`
+
+	// Jitter is the random variation of the added latency, such as "10ms".
+	Jitter string `json:"jitter,omitempty"`
+
+	// Bandwidth caps the outgoing rate, in tc units such as "10mbit".
+	Bandwidth string `json:"bandwidth,omitempty"`
+
+	// Loss is the percentage of outgoing packets dropped.
+	Loss float64 `json:"loss,omitempty"`
+}
+
+var bandwidthRegexp = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?(bit|kbit|mbit|gbit|tbit|bps|kbps|mbps|gbps|tbps)$`)
+
+// IsZero returns true if the spec doesn't impair the network.
+func (s NetworkSpec) IsZero() bool {
+	return s == NetworkSpec{}
+}
+
+func (s NetworkSpec) Validate() error {
+	for field, value := range map[string]string{
+		"latency": s.Latency,
+		"jitter":  s.Jitter,
+	} {
+		if value == "" {
+			continue
+		}
+
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "network %s must be a positive duration, got %q", field, value)
+		}
+	}
+
+	if s.Jitter != "" && s.Latency == "" {
+		return errors.Wrap(errdefs.ErrInvalidArgument, "network jitter requires latency")
+	}
+
+	if s.Bandwidth != "" && !bandwidthRegexp.MatchString(s.Bandwidth) {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "network bandwidth must be a rate such as \"10mbit\", got %q", s.Bandwidth)
+	}
+
+	if s.Loss < 0 || s.Loss > 100 {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "network loss must be a percentage, got %v", s.Loss)
+	}
+
+	return nil
+}
+
+func readNetworkSpec(bkt *bolt.Bucket) (*NetworkSpec, error) {
+	nbkt := bkt.Bucket(bucketKeyNetwork)
+	if nbkt == nil {
+		return nil, nil
+	}
+
+	var spec NetworkSpec
+	err := nbkt.ForEach(func(k, v []byte) error {
+		switch string(k) {
+		case string(bucketKeyLatency):
+			spec.Latency = string(v)
+		case string(bucketKeyJitter):
+			spec.Jitter = string(v)
+		case string(bucketKeyBandwidth):
+			spec.Bandwidth = string(v)
+		case string(bucketKeyLoss):
+			spec.Loss, _ = strconv.ParseFloat(string(v), 64)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &spec, nil
+}
+
+func writeNetworkSpec(bkt *bolt.Bucket, spec *NetworkSpec) error {
+	if spec == nil {
+		return nil
+	}
+
+	nbkt, err := RecreateBucket(bkt, bucketKeyNetwork)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range []field{
+		{bucketKeyLatency, []byte(spec.Latency)},
+		{bucketKeyJitter, []byte(spec.Jitter)},
+		{bucketKeyBandwidth, []byte(spec.Bandwidth)},
+		{bucketKeyLoss, []byte(strconv.FormatFloat(spec.Loss, 'f', -1, 64))},
+	} {
+		err = nbkt.Put(f.key, f.value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
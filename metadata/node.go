@@ -16,6 +16,7 @@ package metadata
 
 import (
 	"context"
+	"fmt"
 	"strconv"
 	"strings"
 	"time"
@@ -35,6 +36,17 @@ var (
 	}
 )
 
+// NodeLabelPrefix is reserved for the key=value labels derived from provider
+// metadata when a node is registered. User labels may not use it, so that
+// queries such as 'node.zone=us-west-2a' always reflect where a node runs.
+const NodeLabelPrefix = "node."
+
+const (
+	NodeLabelRegion       = NodeLabelPrefix + "region"
+	NodeLabelZone         = NodeLabelPrefix + "zone"
+	NodeLabelInstanceType = NodeLabelPrefix + "instance-type"
+)
+
 type Node struct {
 	ID string
 
@@ -48,9 +60,62 @@ type Node struct {
 
 	Labels []string
 
+	// Status is the provisioning state of the node, and Reason why the node
+	// failed to provision if it did.
+	Status NodeStatus `json:",omitempty"`
+	Reason string     `json:",omitempty"`
+
 	CreatedAt, UpdatedAt time.Time
 }
 
+// NodeStatus is the provisioning state of a node.
+type NodeStatus string
+
+var (
+	// NodePending is the state of nodes requested from the provider that are
+	// not created yet.
+	NodePending NodeStatus = "pending"
+
+	// NodeProvisioning is the state of nodes whose agent is not healthy yet.
+	NodeProvisioning NodeStatus = "provisioning"
+
+	NodeReady  NodeStatus = "ready"
+	NodeFailed NodeStatus = "failed"
+)
+
+// NodeConnection is the result of a node dialing, or hanging up on, a target
+// node.
+type NodeConnection struct {
+	Source string
+
+	Target string
+
+	Error string `json:",omitempty"`
+}
+
+// NodeLabels returns the provider labels of a node in the given region, zone
+// and instance type, followed by the user labels. Empty values are omitted.
+func NodeLabels(region, zone, instanceType string, labels ...string) []string {
+	var ls []string
+	for _, kv := range [][2]string{
+		{NodeLabelRegion, region},
+		{NodeLabelZone, zone},
+		{NodeLabelInstanceType, instanceType},
+	} {
+		if kv[1] != "" {
+			ls = append(ls, kv[0]+"="+kv[1])
+		}
+	}
+	return append(ls, labels...)
+}
+
+// SyntheticZone returns a zone in the region for the i-th node of a provider
+// without availability zones, spreading nodes across three zones so that
+// zone-aware scenarios can be exercised locally.
+func SyntheticZone(region string, i int) string {
+	return fmt.Sprintf("%s%c", region, 'a'+i%3)
+}
+
 type PeerDefinition struct {
 	GitReference string
 
@@ -61,12 +126,19 @@ type PeerDefinition struct {
 	SecurityTransports []string
 
 	Routing string
+
+	// RoutingEndpoint is the endpoint of the "delegated" routing.
+	RoutingEndpoint string `json:",omitempty"`
+
+	// Datastore is the datastore backing the blockstore of labapp, one of
+	// Datastores. Defaults to "badger".
+	Datastore string `json:",omitempty"`
 }
 
 func (m *db) GetNode(ctx context.Context, cluster, id string) (Node, error) {
 	var node Node
 
-	err := m.View(ctx, func(tx *bolt.Tx) error {
+	err := m.view(ctx, func(tx *bolt.Tx) error {
 		bkt := getNodesBucket(tx, cluster)
 		if bkt == nil {
 			return errors.Wrapf(errdefs.ErrNotFound, "node %q", id)
@@ -92,9 +164,14 @@ func (m *db) GetNode(ctx context.Context, cluster, id string) (Node, error) {
 	return node, nil
 }
 
-func (m *db) ListNodes(ctx context.Context, cluster string) ([]Node, error) {
+func (m *db) ListNodes(ctx context.Context, cluster string, opts ...ListOption) ([]Node, error) {
+	settings, err := newListSettings(opts, OrderByCreated, OrderByUpdated)
+	if err != nil {
+		return nil, err
+	}
+
 	var nodes []Node
-	err := m.View(ctx, func(tx *bolt.Tx) error {
+	err = m.view(ctx, func(tx *bolt.Tx) error {
 		bkt := getNodesBucket(tx, cluster)
 		if bkt == nil {
 			return nil
@@ -121,6 +198,11 @@ func (m *db) ListNodes(ctx context.Context, cluster string) ([]Node, error) {
 		return nil, err
 	}
 
+	order(settings, len(nodes), func(i, j int) {
+		nodes[i], nodes[j] = nodes[j], nodes[i]
+	}, func(i int) int64 {
+		return timeOrderKey(settings.OrderBy, nodes[i].CreatedAt, nodes[i].UpdatedAt)
+	})
 	return nodes, nil
 }
 
@@ -138,7 +220,7 @@ func (m *db) CreateNode(ctx context.Context, cluster string, node Node) (Node, e
 }
 
 func (m *db) CreateNodes(ctx context.Context, cluster string, nodes []Node) ([]Node, error) {
-	err := m.Update(ctx, func(tx *bolt.Tx) error {
+	err := m.update(ctx, func(tx *bolt.Tx) error {
 		bkt, err := createNodesBucket(tx, cluster)
 		if err != nil {
 			return err
@@ -178,7 +260,7 @@ func (m *db) UpdateNode(ctx context.Context, cluster string, node Node) (Node, e
 		return Node{}, errors.Wrapf(errdefs.ErrInvalidArgument, "node id required for update")
 	}
 
-	err := m.Update(ctx, func(tx *bolt.Tx) error {
+	err := m.update(ctx, func(tx *bolt.Tx) error {
 		bkt, err := createNodesBucket(tx, cluster)
 		if err != nil {
 			return err
@@ -201,7 +283,7 @@ func (m *db) UpdateNode(ctx context.Context, cluster string, node Node) (Node, e
 
 func (m *db) LabelNodes(ctx context.Context, cluster string, ids, adds, removes []string) ([]Node, error) {
 	var nodes []Node
-	err := m.Update(ctx, func(tx *bolt.Tx) error {
+	err := m.update(ctx, func(tx *bolt.Tx) error {
 		bkt, err := createNodesBucket(tx, cluster)
 		if err != nil {
 			return err
@@ -239,7 +321,7 @@ func (m *db) LabelNodes(ctx context.Context, cluster string, ids, adds, removes
 }
 
 func (m *db) DeleteNodes(ctx context.Context, cluster string, ids ...string) error {
-	return m.Update(ctx, func(tx *bolt.Tx) error {
+	return m.update(ctx, func(tx *bolt.Tx) error {
 		bkt := getNodesBucket(tx, cluster)
 		if bkt == nil {
 			return nil
@@ -290,6 +372,10 @@ func readNode(bkt *bolt.Bucket, node *Node) error {
 			node.AgentPort, _ = strconv.Atoi(string(v))
 		case string(bucketKeyAppPort):
 			node.AppPort, _ = strconv.Atoi(string(v))
+		case string(bucketKeyStatus):
+			node.Status = NodeStatus(v)
+		case string(bucketKeyError):
+			node.Reason = string(v)
 		}
 
 		return nil
@@ -326,6 +412,10 @@ func readPeerDefinition(bkt *bolt.Bucket) (PeerDefinition, error) {
 			}
 		case string(bucketKeyRouting):
 			pdef.Routing = string(v)
+		case string(bucketKeyRoutingEndpoint):
+			pdef.RoutingEndpoint = string(v)
+		case string(bucketKeyDatastore):
+			pdef.Datastore = string(v)
 		}
 
 		return nil
@@ -354,6 +444,8 @@ func writeNode(bkt *bolt.Bucket, node *Node) error {
 		{bucketKeyAddress, []byte(node.Address)},
 		{bucketKeyAgentPort, []byte(strconv.Itoa(node.AgentPort))},
 		{bucketKeyAppPort, []byte(strconv.Itoa(node.AppPort))},
+		{bucketKeyStatus, []byte(node.Status)},
+		{bucketKeyError, []byte(node.Reason)},
 	} {
 		err = bkt.Put(f.key, f.value)
 		if err != nil {
@@ -384,6 +476,8 @@ func writePeerDefinition(bkt *bolt.Bucket, pdef PeerDefinition) error {
 		{bucketKeyMuxers, []byte(strings.Join(pdef.Muxers, ","))},
 		{bucketKeySecurityTransports, []byte(strings.Join(pdef.SecurityTransports, ","))},
 		{bucketKeyRouting, []byte(pdef.Routing)},
+		{bucketKeyRoutingEndpoint, []byte(pdef.RoutingEndpoint)},
+		{bucketKeyDatastore, []byte(pdef.Datastore)},
 	} {
 		err = dbkt.Put(f.key, f.value)
 		if err != nil {
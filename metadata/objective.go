@@ -0,0 +1,196 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+// ObjectiveDefinition changes what the benchmark stage measures. Without an
+// objective, nodes retrieve the objects of their benchmark actions.
+type ObjectiveDefinition struct {
+	// Type is the kind of objective, one of ["connectivity"].
+	Type ObjectiveType `json:"type"`
+
+	// Peers is the number of peers a node must connect to for the connectivity
+	// objective to complete. Defaults to every other benchmarked node.
+	Peers int `json:"peers,omitempty"`
+
+	// Timeout bounds how long nodes take to reach their target peer count, such
+	// as "1m". Defaults to "1m".
+	Timeout string `json:"timeout,omitempty"`
+
+	// Interval is how often the peer count and DHT routing table size of nodes
+	// are recorded, such as "1s". Defaults to "1s".
+	Interval string `json:"interval,omitempty"`
+}
+
+type ObjectiveType string
+
+var (
+	// ObjectiveConnectivity measures how long nodes take to bootstrap the DHT
+	// and find each other. The benchmark queries select the nodes, their
+	// actions are ignored.
+	ObjectiveConnectivity ObjectiveType = "connectivity"
+)
+
+var (
+	defaultObjectiveTimeout  = time.Minute
+	defaultObjectiveInterval = time.Second
+)
+
+// Validate returns an error if the objective is invalid.
+func (d ObjectiveDefinition) Validate() error {
+	if d.Type != ObjectiveConnectivity {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "objective type must be one of [%s], got %q", ObjectiveConnectivity, d.Type)
+	}
+
+	if d.Peers < 0 {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "objective peers must not be negative, got %d", d.Peers)
+	}
+
+	_, err := d.ConnectivityTask(0)
+	return err
+}
+
+// ConnectivityTask returns the task of a connectivity objective for a node
+// benchmarked along with n other nodes.
+func (d ObjectiveDefinition) ConnectivityTask(n int) (ConnectivityTask, error) {
+	task := ConnectivityTask{
+		Peers:    d.Peers,
+		Timeout:  defaultObjectiveTimeout,
+		Interval: defaultObjectiveInterval,
+	}
+	if task.Peers == 0 {
+		task.Peers = n
+	}
+
+	for _, f := range []struct {
+		name  string
+		value string
+		d     *time.Duration
+	}{
+		{"timeout", d.Timeout, &task.Timeout},
+		{"interval", d.Interval, &task.Interval},
+	} {
+		if f.value == "" {
+			continue
+		}
+
+		v, err := time.ParseDuration(f.value)
+		if err != nil || v <= 0 {
+			return task, errors.Wrapf(errdefs.ErrInvalidArgument, "objective %s must be a positive duration, got %q", f.name, f.value)
+		}
+		*f.d = v
+	}
+
+	return task, nil
+}
+
+// ConnectivityTask is the subject of a connectivity task, encoded as JSON.
+type ConnectivityTask struct {
+	// Bootstrap are the addresses of the peers the node bootstraps from. Nodes
+	// without bootstrap peers wait for other nodes to connect to them.
+	Bootstrap []string `json:",omitempty"`
+
+	// Peers is the number of peers the node must connect to.
+	Peers int
+
+	// Timeout bounds how long the node takes to connect to its peers.
+	Timeout time.Duration
+
+	// Interval is how often the timeline is sampled.
+	Interval time.Duration
+}
+
+// Task returns the connectivity task as a task.
+func (t ConnectivityTask) Task() (Task, error) {
+	content, err := json.Marshal(&t)
+	if err != nil {
+		return Task{}, err
+	}
+	return Task{Type: TaskConnectivity, Subject: string(content)}, nil
+}
+
+// ParseConnectivityTask decodes the subject of a connectivity task.
+func ParseConnectivityTask(task Task) (ConnectivityTask, error) {
+	var t ConnectivityTask
+	if task.Type != TaskConnectivity {
+		return t, errors.Wrapf(errdefs.ErrInvalidArgument, "expected %q task, got %q", TaskConnectivity, task.Type)
+	}
+
+	err := json.Unmarshal([]byte(task.Subject), &t)
+	if err != nil {
+		return t, errors.Wrapf(errdefs.ErrInvalidArgument, "invalid connectivity task: %s", err)
+	}
+	return t, nil
+}
+
+// Connectivity is the result of a connectivity task on a node.
+type Connectivity struct {
+	// FirstPeer is the time the node took to connect to its first peer, or zero
+	// if it never did.
+	FirstPeer time.Duration `json:",omitempty"`
+
+	// TargetPeers is the time the node took to connect to its target number of
+	// peers, or zero if it never did.
+	TargetPeers time.Duration `json:",omitempty"`
+
+	// Timeline is the peer count and DHT routing table size of the node over
+	// the task.
+	Timeline []ConnectivitySample `json:",omitempty"`
+}
+
+type ConnectivitySample struct {
+	// Elapsed is the time since the start of the task.
+	Elapsed time.Duration
+
+	// Peers is the number of peers connected to the node.
+	Peers int
+
+	// RoutingTable is the number of peers in the node's DHT routing table.
+	RoutingTable int
+}
+
+func readObjective(bkt *bolt.Bucket) (*ObjectiveDefinition, error) {
+	v := bkt.Get(bucketKeyObjective)
+	if v == nil {
+		return nil, nil
+	}
+
+	var objective ObjectiveDefinition
+	err := json.Unmarshal(v, &objective)
+	if err != nil {
+		return nil, err
+	}
+	return &objective, nil
+}
+
+func writeObjective(bkt *bolt.Bucket, objective *ObjectiveDefinition) error {
+	if objective == nil {
+		return nil
+	}
+
+	content, err := json.Marshal(objective)
+	if err != nil {
+		return err
+	}
+	return bkt.Put(bucketKeyObjective, content)
+}
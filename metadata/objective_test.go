@@ -0,0 +1,40 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectivityTask(t *testing.T) {
+	ctask, err := ObjectiveDefinition{Type: ObjectiveConnectivity, Interval: "500ms"}.ConnectivityTask(4)
+	require.NoError(t, err)
+	require.Equal(t, ConnectivityTask{Peers: 4, Timeout: time.Minute, Interval: 500 * time.Millisecond}, ctask)
+
+	ctask.Bootstrap = []string{"/ip4/10.0.0.1/tcp/4001/p2p/QmaCpDMGvV2BGHeYERUEnRQAwe3N8SzbUtfsmvsqQLuvuJ"}
+	task, err := ctask.Task()
+	require.NoError(t, err)
+	require.Equal(t, TaskConnectivity, task.Type)
+
+	actual, err := ParseConnectivityTask(task)
+	require.NoError(t, err)
+	require.Equal(t, ctask, actual)
+
+	_, err = ParseConnectivityTask(Task{Type: TaskGet})
+	require.Error(t, err)
+}
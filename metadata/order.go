@@ -0,0 +1,129 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/pkg/errors"
+)
+
+var (
+	// OrderByName orders by ID. It is the key of records in every backend, so
+	// lists are read in order without being sorted.
+	OrderByName = "name"
+
+	// OrderByCreated orders by creation time.
+	OrderByCreated = "created"
+
+	// OrderByUpdated orders by last update time.
+	OrderByUpdated = "updated"
+
+	// OrderByDuration orders by the time between creation and the last update,
+	// which is the run time of finished benchmarks.
+	OrderByDuration = "duration"
+)
+
+type ListOption func(*ListSettings) error
+
+type ListSettings struct {
+	OrderBy string
+	Desc    bool
+}
+
+// WithOrderBy orders a list by a field, in descending order if desc is true.
+//
+// Ordering by a field other than name reads every record of the list and sorts
+// them in memory for the bolt backend, which takes O(n log n) time and O(n)
+// memory in the number of records regardless of pagination.
+func WithOrderBy(field string, desc bool) ListOption {
+	return func(s *ListSettings) error {
+		if field != "" {
+			s.OrderBy = field
+		}
+		s.Desc = desc
+		return nil
+	}
+}
+
+// newListSettings applies opts, validating that the order-by field is either
+// name or one of fields.
+func newListSettings(opts []ListOption, fields ...string) (ListSettings, error) {
+	settings := ListSettings{OrderBy: OrderByName}
+	for _, opt := range opts {
+		err := opt(&settings)
+		if err != nil {
+			return settings, err
+		}
+	}
+
+	fields = append([]string{OrderByName}, fields...)
+	for _, field := range fields {
+		if settings.OrderBy == field {
+			return settings, nil
+		}
+	}
+	return settings, errors.Wrapf(errdefs.ErrInvalidArgument, "cannot order by %q, must be one of [%s]", settings.OrderBy, strings.Join(fields, ", "))
+}
+
+// timeOrderKey returns the sort key of a record for a time-based field.
+func timeOrderKey(field string, createdAt, updatedAt time.Time) int64 {
+	switch field {
+	case OrderByCreated:
+		return createdAt.UnixNano()
+	case OrderByUpdated:
+		return updatedAt.UnixNano()
+	case OrderByDuration:
+		return int64(updatedAt.Sub(createdAt))
+	default:
+		return 0
+	}
+}
+
+// order sorts a list of n records read in ascending name order, breaking ties
+// by name.
+func order(settings ListSettings, n int, swap func(i, j int), key func(i int) int64) {
+	if settings.OrderBy == OrderByName {
+		if settings.Desc {
+			for i := 0; i < n/2; i++ {
+				swap(i, n-1-i)
+			}
+		}
+		return
+	}
+
+	sort.Stable(&sorter{n, swap, key, settings.Desc})
+}
+
+type sorter struct {
+	n    int
+	swap func(i, j int)
+	key  func(i int) int64
+	desc bool
+}
+
+func (s *sorter) Len() int { return s.n }
+
+func (s *sorter) Swap(i, j int) { s.swap(i, j) }
+
+func (s *sorter) Less(i, j int) bool {
+	if s.desc {
+		return s.key(i) > s.key(j)
+	}
+	return s.key(i) < s.key(j)
+}
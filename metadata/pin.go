@@ -0,0 +1,33 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"time"
+)
+
+// Pin is the result of pinning the DAG of a CID on a node.
+type Pin struct {
+	// CacheHit is whether the node already held every block of the DAG,
+	// otherwise the missing blocks were fetched.
+	CacheHit bool
+
+	Blocks int
+
+	Bytes int64
+
+	// Duration is how long it took to fetch and verify the DAG.
+	Duration time.Duration
+}
@@ -0,0 +1,74 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"time"
+)
+
+// Unreachable marks a pair of nodes in a latency matrix where the source
+// failed to ping the target.
+const Unreachable = "unreachable"
+
+// NodePing is the result of a node pinging a target node over libp2p.
+type NodePing struct {
+	Source string
+
+	Target string
+
+	// RTTs are the round trip times of every ping.
+	RTTs []time.Duration `json:",omitempty"`
+
+	Error string `json:",omitempty"`
+}
+
+// Latency returns the mean round trip time, or zero if the target was
+// unreachable.
+func (p NodePing) Latency() time.Duration {
+	if p.Error != "" || len(p.RTTs) == 0 {
+		return 0
+	}
+
+	var total time.Duration
+	for _, rtt := range p.RTTs {
+		total += rtt
+	}
+	return total / time.Duration(len(p.RTTs))
+}
+
+// LatencyMatrix maps source node IDs to target node IDs to the latency between
+// them, or Unreachable.
+type LatencyMatrix map[string]map[string]string
+
+// NewLatencyMatrix returns the latency matrix of the pings. Pairs that were not
+// pinged, such as a node and itself, are omitted.
+func NewLatencyMatrix(pings []NodePing) LatencyMatrix {
+	m := make(LatencyMatrix)
+	for _, p := range pings {
+		row, ok := m[p.Source]
+		if !ok {
+			row = make(map[string]string)
+			m[p.Source] = row
+		}
+
+		latency := p.Latency()
+		if latency == 0 {
+			row[p.Target] = Unreachable
+			continue
+		}
+		row[p.Target] = latency.Round(time.Microsecond).String()
+	}
+	return m
+}
@@ -0,0 +1,35 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatencyMatrix(t *testing.T) {
+	m := NewLatencyMatrix([]NodePing{
+		{Source: "a", Target: "b", RTTs: []time.Duration{time.Millisecond, 3 * time.Millisecond}},
+		{Source: "a", Target: "c", Error: "failed to dial"},
+		{Source: "b", Target: "a"},
+		{Source: "b", Target: "c", RTTs: []time.Duration{1500 * time.Nanosecond}},
+	})
+	require.Equal(t, LatencyMatrix{
+		"a": {"b": "2ms", "c": Unreachable},
+		"b": {"a": Unreachable, "c": "2µs"},
+	}, m)
+}
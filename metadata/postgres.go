@@ -0,0 +1,898 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/pkg/errors"
+
+	// Registers the postgres driver for database/sql.
+	_ "github.com/lib/pq"
+)
+
+// postgresSchema creates the tables of the postgres metadata store. Objects
+// are stored as JSON documents keyed by their ID.
+var postgresSchema = []string{
+	`CREATE TABLE IF NOT EXISTS clusters (id TEXT PRIMARY KEY, doc JSONB NOT NULL)`,
+	`CREATE TABLE IF NOT EXISTS nodes (cluster_id TEXT NOT NULL, id TEXT NOT NULL, doc JSONB NOT NULL, PRIMARY KEY (cluster_id, id))`,
+	`CREATE TABLE IF NOT EXISTS scenarios (id TEXT PRIMARY KEY, doc JSONB NOT NULL)`,
+	`CREATE TABLE IF NOT EXISTS builds (id TEXT PRIMARY KEY, doc JSONB NOT NULL)`,
+	`CREATE TABLE IF NOT EXISTS benchmarks (id TEXT PRIMARY KEY, doc JSONB NOT NULL)`,
+	`CREATE TABLE IF NOT EXISTS reports (benchmark_id TEXT PRIMARY KEY REFERENCES benchmarks (id) ON DELETE CASCADE, doc JSONB NOT NULL)`,
+	`CREATE TABLE IF NOT EXISTS experiments (id TEXT PRIMARY KEY, doc JSONB NOT NULL)`,
+}
+
+type postgresTransactionKey struct{}
+
+type postgresTx struct {
+	*sql.Tx
+	writable bool
+}
+
+type pgdb struct {
+	sqldb *sql.DB
+}
+
+// NewPostgresDB returns a metadata store backed by the PostgreSQL database at
+// dsn. Call Migrate to create or upgrade its schema before use.
+func NewPostgresDB(ctx context.Context, dsn string) (DB, error) {
+	sqldb, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	err = sqldb.PingContext(ctx)
+	if err != nil {
+		sqldb.Close()
+		return nil, errors.Wrap(err, "failed to connect to postgres")
+	}
+
+	return &pgdb{sqldb}, nil
+}
+
+func (m *pgdb) Close() error {
+	return m.sqldb.Close()
+}
+
+func (m *pgdb) View(ctx context.Context, fn func(context.Context) error) error {
+	return m.view(ctx, func(tx *postgresTx) error {
+		return fn(context.WithValue(ctx, postgresTransactionKey{}, tx))
+	})
+}
+
+func (m *pgdb) Update(ctx context.Context, fn func(context.Context) error) error {
+	return m.update(ctx, func(tx *postgresTx) error {
+		return fn(context.WithValue(ctx, postgresTransactionKey{}, tx))
+	})
+}
+
+func (m *pgdb) view(ctx context.Context, fn func(*postgresTx) error) error {
+	tx, ok := ctx.Value(postgresTransactionKey{}).(*postgresTx)
+	if ok {
+		return fn(tx)
+	}
+	return m.transact(ctx, false, fn)
+}
+
+func (m *pgdb) update(ctx context.Context, fn func(*postgresTx) error) error {
+	tx, ok := ctx.Value(postgresTransactionKey{}).(*postgresTx)
+	if !ok {
+		return m.transact(ctx, true, fn)
+	} else if !tx.writable {
+		return errors.New("unable to use read-only transaction from context")
+	}
+	return fn(tx)
+}
+
+func (m *pgdb) transact(ctx context.Context, writable bool, fn func(*postgresTx) error) error {
+	sqltx, err := m.sqldb.BeginTx(ctx, &sql.TxOptions{ReadOnly: !writable})
+	if err != nil {
+		return err
+	}
+
+	err = fn(&postgresTx{sqltx, writable})
+	if err != nil {
+		sqltx.Rollback()
+		return err
+	}
+
+	return sqltx.Commit()
+}
+
+func (m *pgdb) GetCluster(ctx context.Context, id string) (Cluster, error) {
+	var cluster Cluster
+	err := m.view(ctx, func(tx *postgresTx) error {
+		return getDocument(ctx, tx, `SELECT doc FROM clusters WHERE id = $1`, &cluster, "cluster %q", id)
+	})
+	if err != nil {
+		return Cluster{}, err
+	}
+
+	return cluster, nil
+}
+
+func (m *pgdb) ListClusters(ctx context.Context) ([]Cluster, error) {
+	var clusters []Cluster
+	err := m.view(ctx, func(tx *postgresTx) error {
+		return listDocuments(ctx, tx, `SELECT doc FROM clusters ORDER BY id COLLATE "C"`, func(content []byte) error {
+			var cluster Cluster
+			err := json.Unmarshal(content, &cluster)
+			if err != nil {
+				return err
+			}
+			clusters = append(clusters, cluster)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return clusters, nil
+}
+
+func (m *pgdb) CreateCluster(ctx context.Context, cluster Cluster) (Cluster, error) {
+	err := cluster.Validate()
+	if err != nil {
+		return Cluster{}, err
+	}
+
+	err = m.update(ctx, func(tx *postgresTx) error {
+		cluster.CreatedAt = time.Now().UTC()
+		cluster.UpdatedAt = cluster.CreatedAt
+		return insertDocument(ctx, tx, `INSERT INTO clusters (id, doc) VALUES ($1, $2) ON CONFLICT DO NOTHING`, &cluster, "cluster %q", cluster.ID)
+	})
+	if err != nil {
+		return Cluster{}, err
+	}
+	return cluster, nil
+}
+
+func (m *pgdb) UpdateCluster(ctx context.Context, cluster Cluster) (Cluster, error) {
+	if cluster.ID == "" {
+		return Cluster{}, errors.Wrapf(errdefs.ErrInvalidArgument, "cluster id required for update")
+	}
+
+	err := m.update(ctx, func(tx *postgresTx) error {
+		cluster.UpdatedAt = time.Now().UTC()
+		return updateDocument(ctx, tx, `UPDATE clusters SET doc = $2 WHERE id = $1`, &cluster, "cluster %q", cluster.ID)
+	})
+	if err != nil {
+		return Cluster{}, err
+	}
+
+	return cluster, nil
+}
+
+func (m *pgdb) LabelClusters(ctx context.Context, ids, adds, removes []string) ([]Cluster, error) {
+	var clusters []Cluster
+	err := m.update(ctx, func(tx *postgresTx) error {
+		for _, id := range ids {
+			var cluster Cluster
+			err := getDocument(ctx, tx, `SELECT doc FROM clusters WHERE id = $1 FOR UPDATE`, &cluster, "%q", id)
+			if err != nil {
+				return err
+			}
+
+			cluster.Labels = mergeLabels(cluster.Labels, adds, removes)
+			cluster.UpdatedAt = time.Now().UTC()
+
+			err = updateDocument(ctx, tx, `UPDATE clusters SET doc = $2 WHERE id = $1`, &cluster, "%q", id)
+			if err != nil {
+				return err
+			}
+			clusters = append(clusters, cluster)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return clusters, nil
+}
+
+func (m *pgdb) DeleteCluster(ctx context.Context, id string) error {
+	return m.update(ctx, func(tx *postgresTx) error {
+		_, err := tx.ExecContext(ctx, `DELETE FROM nodes WHERE cluster_id = $1`, id)
+		if err != nil {
+			return err
+		}
+
+		return deleteDocument(ctx, tx, `DELETE FROM clusters WHERE id = $1`, "cluster %q", id)
+	})
+}
+
+func (m *pgdb) GetNode(ctx context.Context, cluster, id string) (Node, error) {
+	var node Node
+	err := m.view(ctx, func(tx *postgresTx) error {
+		return getDocument(ctx, tx, `SELECT doc FROM nodes WHERE id = $1 AND cluster_id = $2`, &node, "node %q", id, cluster)
+	})
+	if err != nil {
+		return Node{}, err
+	}
+
+	return node, nil
+}
+
+func (m *pgdb) ListNodes(ctx context.Context, cluster string, opts ...ListOption) ([]Node, error) {
+	settings, err := newListSettings(opts, OrderByCreated, OrderByUpdated)
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []Node
+	err = m.view(ctx, func(tx *postgresTx) error {
+		return listDocuments(ctx, tx, `SELECT doc FROM nodes WHERE cluster_id = $1 ORDER BY `+postgresOrderBy(settings), func(content []byte) error {
+			var node Node
+			err := json.Unmarshal(content, &node)
+			if err != nil {
+				return err
+			}
+			nodes = append(nodes, node)
+			return nil
+		}, cluster)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return nodes, nil
+}
+
+func (m *pgdb) CreateNode(ctx context.Context, cluster string, node Node) (Node, error) {
+	nodes, err := m.CreateNodes(ctx, cluster, []Node{node})
+	if err != nil {
+		return Node{}, err
+	}
+
+	if len(nodes) != 1 {
+		return Node{}, errors.New("failed to retrieve created node")
+	}
+
+	return nodes[0], nil
+}
+
+func (m *pgdb) CreateNodes(ctx context.Context, cluster string, nodes []Node) ([]Node, error) {
+	err := m.update(ctx, func(tx *postgresTx) error {
+		for i, node := range nodes {
+			node.CreatedAt = time.Now().UTC()
+			node.UpdatedAt = node.CreatedAt
+			err := insertDocument(ctx, tx, `INSERT INTO nodes (id, doc, cluster_id) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`, &node, "node %q", node.ID, cluster)
+			if err != nil {
+				return err
+			}
+			nodes[i] = node
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return nodes, nil
+}
+
+func (m *pgdb) UpdateNode(ctx context.Context, cluster string, node Node) (Node, error) {
+	if node.ID == "" {
+		return Node{}, errors.Wrapf(errdefs.ErrInvalidArgument, "node id required for update")
+	}
+
+	err := m.update(ctx, func(tx *postgresTx) error {
+		node.UpdatedAt = time.Now().UTC()
+		return updateDocument(ctx, tx, `UPDATE nodes SET doc = $2 WHERE id = $1 AND cluster_id = $3`, &node, "node %q", node.ID, cluster)
+	})
+	if err != nil {
+		return Node{}, err
+	}
+
+	return node, nil
+}
+
+func (m *pgdb) LabelNodes(ctx context.Context, cluster string, ids, adds, removes []string) ([]Node, error) {
+	var nodes []Node
+	err := m.update(ctx, func(tx *postgresTx) error {
+		for _, id := range ids {
+			var node Node
+			err := getDocument(ctx, tx, `SELECT doc FROM nodes WHERE id = $1 AND cluster_id = $2 FOR UPDATE`, &node, "%q", id, cluster)
+			if err != nil {
+				return err
+			}
+
+			node.Labels = mergeLabels(node.Labels, adds, removes)
+			node.UpdatedAt = time.Now().UTC()
+
+			err = updateDocument(ctx, tx, `UPDATE nodes SET doc = $2 WHERE id = $1 AND cluster_id = $3`, &node, "%q", id, cluster)
+			if err != nil {
+				return err
+			}
+			nodes = append(nodes, node)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return nodes, nil
+}
+
+func (m *pgdb) DeleteNodes(ctx context.Context, cluster string, ids ...string) error {
+	return m.update(ctx, func(tx *postgresTx) error {
+		for _, id := range ids {
+			err := deleteDocument(ctx, tx, `DELETE FROM nodes WHERE id = $1 AND cluster_id = $2`, "node %q", id, cluster)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (m *pgdb) GetScenario(ctx context.Context, id string) (Scenario, error) {
+	var scenario Scenario
+	err := m.view(ctx, func(tx *postgresTx) error {
+		return getDocument(ctx, tx, `SELECT doc FROM scenarios WHERE id = $1`, &scenario, "scenario %q", id)
+	})
+	if err != nil {
+		return Scenario{}, err
+	}
+
+	return scenario, nil
+}
+
+func (m *pgdb) ListScenarios(ctx context.Context) ([]Scenario, error) {
+	var scenarios []Scenario
+	err := m.view(ctx, func(tx *postgresTx) error {
+		return listDocuments(ctx, tx, `SELECT doc FROM scenarios ORDER BY id COLLATE "C"`, func(content []byte) error {
+			var scenario Scenario
+			err := json.Unmarshal(content, &scenario)
+			if err != nil {
+				return err
+			}
+			scenarios = append(scenarios, scenario)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return scenarios, nil
+}
+
+func (m *pgdb) CreateScenario(ctx context.Context, scenario Scenario) (Scenario, error) {
+	err := m.update(ctx, func(tx *postgresTx) error {
+		scenario.CreatedAt = time.Now().UTC()
+		scenario.UpdatedAt = scenario.CreatedAt
+		return insertDocument(ctx, tx, `INSERT INTO scenarios (id, doc) VALUES ($1, $2) ON CONFLICT DO NOTHING`, &scenario, "scenario %q", scenario.ID)
+	})
+	if err != nil {
+		return Scenario{}, err
+	}
+	return scenario, nil
+}
+
+func (m *pgdb) UpdateScenario(ctx context.Context, scenario Scenario) (Scenario, error) {
+	if scenario.ID == "" {
+		return Scenario{}, errors.Wrapf(errdefs.ErrInvalidArgument, "scenario id required for update")
+	}
+
+	err := m.update(ctx, func(tx *postgresTx) error {
+		scenario.UpdatedAt = time.Now().UTC()
+		return updateDocument(ctx, tx, `UPDATE scenarios SET doc = $2 WHERE id = $1`, &scenario, "scenario %q", scenario.ID)
+	})
+	if err != nil {
+		return Scenario{}, err
+	}
+
+	return scenario, nil
+}
+
+func (m *pgdb) LabelScenarios(ctx context.Context, ids, adds, removes []string) ([]Scenario, error) {
+	var scenarios []Scenario
+	err := m.update(ctx, func(tx *postgresTx) error {
+		for _, id := range ids {
+			var scenario Scenario
+			err := getDocument(ctx, tx, `SELECT doc FROM scenarios WHERE id = $1 FOR UPDATE`, &scenario, "%q", id)
+			if err != nil {
+				return err
+			}
+
+			scenario.Labels = mergeLabels(scenario.Labels, adds, removes)
+			scenario.UpdatedAt = time.Now().UTC()
+
+			err = updateDocument(ctx, tx, `UPDATE scenarios SET doc = $2 WHERE id = $1`, &scenario, "%q", id)
+			if err != nil {
+				return err
+			}
+			scenarios = append(scenarios, scenario)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return scenarios, nil
+}
+
+func (m *pgdb) DeleteScenarios(ctx context.Context, ids ...string) error {
+	return m.update(ctx, func(tx *postgresTx) error {
+		for _, id := range ids {
+			err := deleteDocument(ctx, tx, `DELETE FROM scenarios WHERE id = $1`, "scenario %q", id)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (m *pgdb) GetBuild(ctx context.Context, id string) (Build, error) {
+	var build Build
+	err := m.view(ctx, func(tx *postgresTx) error {
+		return getDocument(ctx, tx, `SELECT doc FROM builds WHERE id = $1`, &build, "build %q", id)
+	})
+	if err != nil {
+		return Build{}, err
+	}
+
+	return build, nil
+}
+
+func (m *pgdb) ListBuilds(ctx context.Context) ([]Build, error) {
+	var builds []Build
+	err := m.view(ctx, func(tx *postgresTx) error {
+		return listDocuments(ctx, tx, `SELECT doc FROM builds ORDER BY id COLLATE "C"`, func(content []byte) error {
+			var build Build
+			err := json.Unmarshal(content, &build)
+			if err != nil {
+				return err
+			}
+			builds = append(builds, build)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return builds, nil
+}
+
+func (m *pgdb) CreateBuild(ctx context.Context, build Build) (Build, error) {
+	err := m.update(ctx, func(tx *postgresTx) error {
+		build.CreatedAt = time.Now().UTC()
+		build.UpdatedAt = build.CreatedAt
+		return insertDocument(ctx, tx, `INSERT INTO builds (id, doc) VALUES ($1, $2) ON CONFLICT DO NOTHING`, &build, "build %q", build.ID)
+	})
+	if err != nil {
+		return Build{}, err
+	}
+	return build, nil
+}
+
+func (m *pgdb) DeleteBuild(ctx context.Context, id string) error {
+	return m.update(ctx, func(tx *postgresTx) error {
+		return deleteDocument(ctx, tx, `DELETE FROM builds WHERE id = $1`, "build %q", id)
+	})
+}
+
+func (m *pgdb) GetIdempotencyKey(ctx context.Context, id string) (IdempotencyKey, error) {
+	var key IdempotencyKey
+	err := m.view(ctx, func(tx *postgresTx) error {
+		return getDocument(ctx, tx, `SELECT doc FROM idempotency_keys WHERE id = $1`, &key, "idempotency key %q", id)
+	})
+	if err != nil {
+		return IdempotencyKey{}, err
+	}
+
+	return key, nil
+}
+
+func (m *pgdb) CreateIdempotencyKey(ctx context.Context, key IdempotencyKey) (IdempotencyKey, error) {
+	err := m.update(ctx, func(tx *postgresTx) error {
+		key.CreatedAt = time.Now().UTC()
+		key.UpdatedAt = key.CreatedAt
+		return insertDocument(ctx, tx, `INSERT INTO idempotency_keys (id, doc) VALUES ($1, $2) ON CONFLICT DO NOTHING`, &key, "idempotency key %q", key.ID)
+	})
+	if err != nil {
+		return IdempotencyKey{}, err
+	}
+	return key, nil
+}
+
+func (m *pgdb) DeleteIdempotencyKey(ctx context.Context, id string) error {
+	return m.update(ctx, func(tx *postgresTx) error {
+		return deleteDocument(ctx, tx, `DELETE FROM idempotency_keys WHERE id = $1`, "idempotency key %q", id)
+	})
+}
+
+func (m *pgdb) DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) error {
+	return m.update(ctx, func(tx *postgresTx) error {
+		_, err := tx.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE (doc->>'ExpiresAt')::timestamptz <= $1`, now)
+		return err
+	})
+}
+
+func (m *pgdb) GetReport(ctx context.Context, id string) (Report, error) {
+	var report Report
+	err := m.view(ctx, func(tx *postgresTx) error {
+		err := requireBenchmark(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		err = getDocument(ctx, tx, `SELECT doc FROM reports WHERE benchmark_id = $1`, &report, "no report available for benchmark %q", id)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+func (m *pgdb) CreateReport(ctx context.Context, id string, report Report) error {
+	return m.update(ctx, func(tx *postgresTx) error {
+		err := requireBenchmark(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		return insertDocument(ctx, tx, `INSERT INTO reports (benchmark_id, doc) VALUES ($1, $2) ON CONFLICT (benchmark_id) DO UPDATE SET doc = excluded.doc`, &report, "report %q", id)
+	})
+}
+
+func (m *pgdb) GetBenchmark(ctx context.Context, id string) (Benchmark, error) {
+	var benchmark Benchmark
+	err := m.view(ctx, func(tx *postgresTx) error {
+		return getDocument(ctx, tx, `SELECT doc FROM benchmarks WHERE id = $1`, &benchmark, "benchmark %q", id)
+	})
+	if err != nil {
+		return Benchmark{}, err
+	}
+
+	return benchmark, nil
+}
+
+func (m *pgdb) ListBenchmarks(ctx context.Context, opts ...ListOption) ([]Benchmark, error) {
+	settings, err := newListSettings(opts, OrderByCreated, OrderByUpdated, OrderByDuration)
+	if err != nil {
+		return nil, err
+	}
+
+	var benchmarks []Benchmark
+	err = m.view(ctx, func(tx *postgresTx) error {
+		return listDocuments(ctx, tx, `SELECT doc FROM benchmarks ORDER BY `+postgresOrderBy(settings), func(content []byte) error {
+			var benchmark Benchmark
+			err := json.Unmarshal(content, &benchmark)
+			if err != nil {
+				return err
+			}
+			benchmarks = append(benchmarks, benchmark)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return benchmarks, nil
+}
+
+func (m *pgdb) CreateBenchmark(ctx context.Context, benchmark Benchmark) (Benchmark, error) {
+	err := m.update(ctx, func(tx *postgresTx) error {
+		benchmark.CreatedAt = time.Now().UTC()
+		benchmark.UpdatedAt = benchmark.CreatedAt
+		return insertDocument(ctx, tx, `INSERT INTO benchmarks (id, doc) VALUES ($1, $2) ON CONFLICT DO NOTHING`, &benchmark, "benchmark %q", benchmark.ID)
+	})
+	if err != nil {
+		return Benchmark{}, err
+	}
+	return benchmark, nil
+}
+
+func (m *pgdb) UpdateBenchmark(ctx context.Context, benchmark Benchmark) (Benchmark, error) {
+	if benchmark.ID == "" {
+		return Benchmark{}, errors.Wrapf(errdefs.ErrInvalidArgument, "benchmark id required for update")
+	}
+
+	err := m.update(ctx, func(tx *postgresTx) error {
+		benchmark.UpdatedAt = time.Now().UTC()
+		return updateDocument(ctx, tx, `UPDATE benchmarks SET doc = $2 WHERE id = $1`, &benchmark, "benchmark %q", benchmark.ID)
+	})
+	if err != nil {
+		return Benchmark{}, err
+	}
+
+	return benchmark, nil
+}
+
+func (m *pgdb) LabelBenchmarks(ctx context.Context, ids, adds, removes []string) ([]Benchmark, error) {
+	var benchmarks []Benchmark
+	err := m.update(ctx, func(tx *postgresTx) error {
+		for _, id := range ids {
+			var benchmark Benchmark
+			err := getDocument(ctx, tx, `SELECT doc FROM benchmarks WHERE id = $1 FOR UPDATE`, &benchmark, "%q", id)
+			if err != nil {
+				return err
+			}
+
+			benchmark.Labels = mergeLabels(benchmark.Labels, adds, removes)
+			benchmark.UpdatedAt = time.Now().UTC()
+
+			err = updateDocument(ctx, tx, `UPDATE benchmarks SET doc = $2 WHERE id = $1`, &benchmark, "%q", id)
+			if err != nil {
+				return err
+			}
+			benchmarks = append(benchmarks, benchmark)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return benchmarks, nil
+}
+
+func (m *pgdb) DeleteBenchmarks(ctx context.Context, ids ...string) error {
+	return m.update(ctx, func(tx *postgresTx) error {
+		for _, id := range ids {
+			err := deleteDocument(ctx, tx, `DELETE FROM benchmarks WHERE id = $1`, "benchmark %q", id)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (m *pgdb) GetExperiment(ctx context.Context, id string) (Experiment, error) {
+	var experiment Experiment
+	err := m.view(ctx, func(tx *postgresTx) error {
+		return getDocument(ctx, tx, `SELECT doc FROM experiments WHERE id = $1`, &experiment, "experiment %q", id)
+	})
+	if err != nil {
+		return Experiment{}, err
+	}
+
+	return experiment, nil
+}
+
+func (m *pgdb) ListExperiments(ctx context.Context) ([]Experiment, error) {
+	var experiments []Experiment
+	err := m.view(ctx, func(tx *postgresTx) error {
+		return listDocuments(ctx, tx, `SELECT doc FROM experiments ORDER BY id COLLATE "C"`, func(content []byte) error {
+			var experiment Experiment
+			err := json.Unmarshal(content, &experiment)
+			if err != nil {
+				return err
+			}
+			experiments = append(experiments, experiment)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return experiments, nil
+}
+
+func (m *pgdb) CreateExperiment(ctx context.Context, experiment Experiment) (Experiment, error) {
+	err := m.update(ctx, func(tx *postgresTx) error {
+		experiment.CreatedAt = time.Now().UTC()
+		experiment.UpdatedAt = experiment.CreatedAt
+		return insertDocument(ctx, tx, `INSERT INTO experiments (id, doc) VALUES ($1, $2) ON CONFLICT DO NOTHING`, &experiment, "experiment %q", experiment.ID)
+	})
+	if err != nil {
+		return Experiment{}, err
+	}
+	return experiment, nil
+}
+
+func (m *pgdb) UpdateExperiment(ctx context.Context, experiment Experiment) (Experiment, error) {
+	if experiment.ID == "" {
+		return Experiment{}, errors.Wrapf(errdefs.ErrInvalidArgument, "experiment id required for update")
+	}
+
+	err := m.update(ctx, func(tx *postgresTx) error {
+		experiment.UpdatedAt = time.Now().UTC()
+		return updateDocument(ctx, tx, `UPDATE experiments SET doc = $2 WHERE id = $1`, &experiment, "experiment %q", experiment.ID)
+	})
+	if err != nil {
+		return Experiment{}, err
+	}
+
+	return experiment, nil
+}
+
+func (m *pgdb) LabelExperiments(ctx context.Context, ids, adds, removes []string) ([]Experiment, error) {
+	var experiments []Experiment
+	err := m.update(ctx, func(tx *postgresTx) error {
+		for _, id := range ids {
+			var experiment Experiment
+			err := getDocument(ctx, tx, `SELECT doc FROM experiments WHERE id = $1 FOR UPDATE`, &experiment, "%q", id)
+			if err != nil {
+				return err
+			}
+
+			experiment.Labels = mergeLabels(experiment.Labels, adds, removes)
+			experiment.UpdatedAt = time.Now().UTC()
+
+			err = updateDocument(ctx, tx, `UPDATE experiments SET doc = $2 WHERE id = $1`, &experiment, "%q", id)
+			if err != nil {
+				return err
+			}
+			experiments = append(experiments, experiment)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return experiments, nil
+}
+
+func (m *pgdb) DeleteExperiment(ctx context.Context, id string) error {
+	return m.update(ctx, func(tx *postgresTx) error {
+		return deleteDocument(ctx, tx, `DELETE FROM experiments WHERE id = $1`, "experiment %q", id)
+	})
+}
+
+// getDocument decodes the document selected by query with the ID and any
+// remaining args.
+func getDocument(ctx context.Context, tx *postgresTx, query string, v interface{}, format, id string, args ...interface{}) error {
+	var content []byte
+	err := tx.QueryRowContext(ctx, query, append([]interface{}{id}, args...)...).Scan(&content)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errors.Wrapf(errdefs.ErrNotFound, format, id)
+		}
+		return err
+	}
+
+	return json.Unmarshal(content, v)
+}
+
+func requireBenchmark(ctx context.Context, tx *postgresTx, id string) error {
+	var exists int
+	err := tx.QueryRowContext(ctx, `SELECT 1 FROM benchmarks WHERE id = $1`, id).Scan(&exists)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errors.Wrapf(errdefs.ErrNotFound, "benchmark %q", id)
+		}
+		return err
+	}
+	return nil
+}
+
+func listDocuments(ctx context.Context, tx *postgresTx, query string, fn func([]byte) error, args ...interface{}) error {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var content []byte
+		err = rows.Scan(&content)
+		if err != nil {
+			return err
+		}
+
+		err = fn(content)
+		if err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// insertDocument executes query with the ID, the encoded document and any
+// remaining args. The query is expected to insert nothing if the ID is taken.
+func insertDocument(ctx context.Context, tx *postgresTx, query string, v interface{}, format, id string, args ...interface{}) error {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, query, append([]interface{}{id, string(content)}, args...)...)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.Wrapf(errdefs.ErrAlreadyExists, format, id)
+	}
+
+	return nil
+}
+
+// updateDocument executes query with the ID, the encoded document and any
+// remaining args.
+func updateDocument(ctx context.Context, tx *postgresTx, query string, v interface{}, format, id string, args ...interface{}) error {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, query, append([]interface{}{id, string(content)}, args...)...)
+	if err != nil {
+		return err
+	}
+
+	return requireRowsAffected(result, format, id)
+}
+
+func deleteDocument(ctx context.Context, tx *postgresTx, query, format, id string, args ...interface{}) error {
+	result, err := tx.ExecContext(ctx, query, append([]interface{}{id}, args...)...)
+	if err != nil {
+		return err
+	}
+
+	return requireRowsAffected(result, format, id)
+}
+
+func requireRowsAffected(result sql.Result, format, id string) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.Wrapf(errdefs.ErrNotFound, format, id)
+	}
+	return nil
+}
+
+// postgresOrderBy returns the ORDER BY clause for a list, which matches the
+// ordering of the bolt backend.
+func postgresOrderBy(settings ListSettings) string {
+	dir := "ASC"
+	if settings.Desc {
+		dir = "DESC"
+	}
+
+	var key string
+	switch settings.OrderBy {
+	case OrderByCreated:
+		key = `(doc->>'CreatedAt')::timestamptz`
+	case OrderByUpdated:
+		key = `(doc->>'UpdatedAt')::timestamptz`
+	case OrderByDuration:
+		key = `((doc->>'UpdatedAt')::timestamptz - (doc->>'CreatedAt')::timestamptz)`
+	default:
+		return `id COLLATE "C" ` + dir
+	}
+	return key + " " + dir + `, id COLLATE "C" ASC`
+}
@@ -0,0 +1,93 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import "time"
+
+var (
+	BenchmarkStageSeed = "seed"
+
+	BenchmarkStageBenchmark = "benchmark"
+)
+
+// BenchmarkEvent is a transition in the progress of an executing benchmark.
+type BenchmarkEvent struct {
+	Time time.Time
+
+	// Stage, Node and NodeStatus are the transition of a node's task. They are
+	// empty for transitions of the benchmark itself.
+	Stage      string              `json:",omitempty"`
+	Node       string              `json:",omitempty"`
+	NodeStatus BenchmarkNodeStatus `json:",omitempty"`
+	Error      string              `json:",omitempty"`
+
+	Status BenchmarkStatus
+
+	// Completed is how many of the Total tasks of the benchmark are finished.
+	Completed int
+	Total     int
+}
+
+// BenchmarkProgress is the state of an executing benchmark, folded from its
+// events.
+type BenchmarkProgress struct {
+	ID string
+
+	Status BenchmarkStatus
+
+	Completed int
+	Total     int
+
+	// Nodes is the state of the latest task of each node.
+	Nodes map[string]BenchmarkNodeProgress
+}
+
+type BenchmarkNodeProgress struct {
+	Stage string
+
+	Status BenchmarkNodeStatus
+
+	Error string `json:",omitempty"`
+}
+
+// Apply updates the progress with an event.
+func (p *BenchmarkProgress) Apply(evt BenchmarkEvent) {
+	p.Status = evt.Status
+	p.Completed = evt.Completed
+	p.Total = evt.Total
+
+	if evt.Node == "" {
+		return
+	}
+	if p.Nodes == nil {
+		p.Nodes = make(map[string]BenchmarkNodeProgress)
+	}
+	p.Nodes[evt.Node] = BenchmarkNodeProgress{
+		Stage:  evt.Stage,
+		Status: evt.NodeStatus,
+		Error:  evt.Error,
+	}
+}
+
+// Percent returns the percentage of tasks completed.
+func (p BenchmarkProgress) Percent() float64 {
+	if p.Total == 0 {
+		if p.Status.Terminal() {
+			return 100
+		}
+		return 0
+	}
+	return 100 * float64(p.Completed) / float64(p.Total)
+}
@@ -0,0 +1,42 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBenchmarkProgress(t *testing.T) {
+	p := BenchmarkProgress{ID: "b"}
+	require.Equal(t, float64(0), p.Percent())
+
+	p.Apply(BenchmarkEvent{Status: BenchmarkRunning, Total: 4})
+	p.Apply(BenchmarkEvent{Stage: BenchmarkStageSeed, Node: "n1", NodeStatus: BenchmarkNodeRunning, Status: BenchmarkRunning, Total: 4})
+	p.Apply(BenchmarkEvent{Stage: BenchmarkStageSeed, Node: "n1", NodeStatus: BenchmarkNodeDone, Status: BenchmarkRunning, Completed: 1, Total: 4})
+	p.Apply(BenchmarkEvent{Stage: BenchmarkStageBenchmark, Node: "n2", NodeStatus: BenchmarkNodeError, Error: "boom", Status: BenchmarkRunning, Completed: 2, Total: 4})
+	require.Equal(t, float64(50), p.Percent())
+	require.Equal(t, map[string]BenchmarkNodeProgress{
+		"n1": {Stage: BenchmarkStageSeed, Status: BenchmarkNodeDone},
+		"n2": {Stage: BenchmarkStageBenchmark, Status: BenchmarkNodeError, Error: "boom"},
+	}, p.Nodes)
+
+	p.Apply(BenchmarkEvent{Status: BenchmarkCanceled, Completed: 2, Total: 4})
+	require.Equal(t, BenchmarkCanceled, p.Status)
+	require.Len(t, p.Nodes, 2)
+
+	require.Equal(t, float64(100), BenchmarkProgress{Status: BenchmarkDone}.Percent())
+}
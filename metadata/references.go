@@ -0,0 +1,177 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/pkg/errors"
+)
+
+// Dependents maps the ID of a resource to the IDs of the resources that
+// reference it, which block its deletion. Resources without dependents are
+// omitted.
+type Dependents map[string][]string
+
+// IDs returns the IDs of every dependent, sorted.
+func (d Dependents) IDs() []string {
+	set := make(map[string]struct{})
+	for _, ids := range d {
+		for _, id := range ids {
+			set[id] = struct{}{}
+		}
+	}
+
+	var ids []string
+	for id := range set {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Err returns an ErrFailedPrecondition listing the dependents blocking the
+// deletion of resources of the given kind, or nil if there are none.
+func (d Dependents) Err(kind, dependentKind string) error {
+	if len(d) == 0 {
+		return nil
+	}
+
+	var ids []string
+	for id := range d {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var blockers []string
+	for _, id := range ids {
+		blockers = append(blockers, fmt.Sprintf("%s %q is referenced by %s %s", kind, id, dependentKind, strings.Join(d[id], ", ")))
+	}
+	return errors.Wrapf(errdefs.ErrFailedPrecondition, "%s, remove them first or delete with cascade", strings.Join(blockers, "; "))
+}
+
+// ClusterDependents returns the benchmarks that ran on each of the clusters.
+func ClusterDependents(ctx context.Context, db DB, ids ...string) (Dependents, error) {
+	return benchmarkDependents(ctx, db, ids, func(b Benchmark) string {
+		return b.Cluster.ID
+	})
+}
+
+// ScenarioDependents returns the benchmarks that ran each of the scenarios.
+func ScenarioDependents(ctx context.Context, db DB, ids ...string) (Dependents, error) {
+	return benchmarkDependents(ctx, db, ids, func(b Benchmark) string {
+		return b.Scenario.ID
+	})
+}
+
+func benchmarkDependents(ctx context.Context, db DB, ids []string, reference func(b Benchmark) string) (Dependents, error) {
+	set := make(map[string]struct{})
+	for _, id := range ids {
+		set[id] = struct{}{}
+	}
+
+	benchmarks, err := db.ListBenchmarks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dependents := make(Dependents)
+	for _, b := range benchmarks {
+		id := reference(b)
+		if _, ok := set[id]; ok {
+			dependents[id] = append(dependents[id], b.ID)
+		}
+	}
+	for id := range dependents {
+		sort.Strings(dependents[id])
+	}
+	return dependents, nil
+}
+
+// BenchmarkDependents returns the experiments whose trials ran each of the
+// benchmarks.
+func BenchmarkDependents(ctx context.Context, db DB, ids ...string) (Dependents, error) {
+	set := make(map[string]struct{})
+	for _, id := range ids {
+		set[id] = struct{}{}
+	}
+
+	experiments, err := db.ListExperiments(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dependents := make(Dependents)
+	for _, e := range experiments {
+		for _, trial := range e.Trials {
+			if _, ok := set[trial.Benchmark]; ok {
+				dependents[trial.Benchmark] = append(dependents[trial.Benchmark], e.ID)
+			}
+		}
+	}
+	for id := range dependents {
+		sort.Strings(dependents[id])
+	}
+	return dependents, nil
+}
+
+// DeleteBenchmarksCascade deletes the benchmarks and the experiments whose
+// trials ran them. Running benchmarks and experiments are never deleted, since
+// they are still writing their results.
+func DeleteBenchmarksCascade(ctx context.Context, db DB, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	for _, id := range ids {
+		benchmark, err := db.GetBenchmark(ctx, id)
+		if err != nil {
+			return err
+		}
+		if benchmark.Status == BenchmarkRunning {
+			return errors.Wrapf(errdefs.ErrFailedPrecondition, "benchmark %q is running", id)
+		}
+	}
+
+	dependents, err := BenchmarkDependents(ctx, db, ids...)
+	if err != nil {
+		return err
+	}
+
+	experiments := dependents.IDs()
+	for _, id := range experiments {
+		experiment, err := db.GetExperiment(ctx, id)
+		if err != nil {
+			return err
+		}
+		if experiment.Status == ExperimentRunning {
+			return errors.Wrapf(errdefs.ErrFailedPrecondition, "experiment %q is running", id)
+		}
+	}
+
+	return db.Update(ctx, func(tctx context.Context) error {
+		for _, id := range experiments {
+			err := db.DeleteExperiment(tctx, id)
+			if err != nil {
+				return err
+			}
+		}
+		return db.DeleteBenchmarks(tctx, ids...)
+	})
+}
@@ -0,0 +1,75 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReferences(t *testing.T) {
+	db, cleanup := newBoltTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for _, b := range []Benchmark{
+		{ID: "b1", Status: BenchmarkDone, Cluster: Cluster{ID: "c"}, Scenario: Scenario{ID: "s"}},
+		{ID: "b2", Status: BenchmarkDone, Cluster: Cluster{ID: "c"}, Scenario: Scenario{ID: "t"}},
+		{ID: "b3", Status: BenchmarkRunning, Cluster: Cluster{ID: "d"}, Scenario: Scenario{ID: "t"}},
+	} {
+		_, err := db.CreateBenchmark(ctx, b)
+		require.NoError(t, err)
+	}
+
+	_, err := db.CreateExperiment(ctx, Experiment{
+		ID:     "e",
+		Status: ExperimentDone,
+		Trials: []ExperimentTrial{{Benchmark: "b1"}},
+	})
+	require.NoError(t, err)
+
+	dependents, err := ClusterDependents(ctx, db, "c", "unused")
+	require.NoError(t, err)
+	require.Equal(t, Dependents{"c": {"b1", "b2"}}, dependents)
+
+	err = dependents.Err("cluster", "benchmarks")
+	require.True(t, errdefs.IsFailedPrecondition(err), "expected failed precondition, got %v", err)
+	require.Contains(t, err.Error(), `cluster "c" is referenced by benchmarks b1, b2`)
+
+	dependents, err = ScenarioDependents(ctx, db, "t")
+	require.NoError(t, err)
+	require.Equal(t, []string{"b2", "b3"}, dependents.IDs())
+
+	dependents, err = BenchmarkDependents(ctx, db, "b1", "b2")
+	require.NoError(t, err)
+	require.Equal(t, Dependents{"b1": {"e"}}, dependents)
+	require.NoError(t, Dependents{}.Err("benchmark", "experiments"))
+
+	err = DeleteBenchmarksCascade(ctx, db, "b3")
+	require.True(t, errdefs.IsFailedPrecondition(err), "expected failed precondition, got %v", err)
+
+	err = DeleteBenchmarksCascade(ctx, db, "b1", "b2")
+	require.NoError(t, err)
+
+	_, err = db.GetExperiment(ctx, "e")
+	require.True(t, errdefs.IsNotFound(err), "expected not found, got %v", err)
+
+	dependents, err = ClusterDependents(ctx, db, "c")
+	require.NoError(t, err)
+	require.Empty(t, dependents)
+}
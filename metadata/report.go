@@ -40,6 +40,18 @@ type Report struct {
 type ReportSummary struct {
 	TotalTime time.Duration
 
+	// TimeToFirstBlock is the distribution of the time nodes took to receive
+	// the first block of the content they retrieved, if any did.
+	TimeToFirstBlock *ReportLatency `json:",omitempty"`
+
+	// BlockLatency is the distribution of the time blocks took to be received
+	// once requested, across the retrievals of all nodes.
+	BlockLatency *ReportLatency `json:",omitempty"`
+
+	// TaskDuration is the distribution of the time nodes took to execute their
+	// task.
+	TaskDuration *ReportLatency `json:",omitempty"`
+
 	Trace string
 
 	Metrics string
@@ -53,6 +65,65 @@ type ReportNode struct {
 	Bitswap ReportBitswap
 
 	Bandwidth ReportBandwidth
+
+	// Connectivity is the result of the last connectivity task of the node.
+	Connectivity *Connectivity `json:",omitempty"`
+
+	// Retrieval is the result of the last retrieval of the node.
+	Retrieval *Retrieval `json:",omitempty"`
+
+	// GC is the result of the last garbage collection of the node.
+	GC *GarbageCollection `json:",omitempty"`
+
+	// Pins is the result of pinning each CID pinned on the node, keyed by CID.
+	Pins map[string]Pin `json:",omitempty"`
+
+	// CacheDrop is the last drop of the page cache of the node.
+	CacheDrop *CacheDrop `json:",omitempty"`
+}
+
+// ReportLatency summarizes the distribution of a latency across nodes.
+type ReportLatency struct {
+	P50  time.Duration
+	P90  time.Duration
+	P95  time.Duration
+	P99  time.Duration
+	P999 time.Duration
+
+	// Histogram is the digest the percentiles are estimated from, kept so that
+	// latencies of different benchmarks can be merged.
+	Histogram Histogram
+}
+
+// NewReportLatency returns the percentiles of the samples of h, or nil if it
+// has none.
+func NewReportLatency(h Histogram) *ReportLatency {
+	if h.Count == 0 {
+		return nil
+	}
+
+	return &ReportLatency{
+		P50:       h.Percentile(50),
+		P90:       h.Percentile(90),
+		P95:       h.Percentile(95),
+		P99:       h.Percentile(99),
+		P999:      h.Percentile(99.9),
+		Histogram: h,
+	}
+}
+
+// Retrieval is the timing of a node retrieving content.
+type Retrieval struct {
+	// FirstBlock is the time from the start of the retrieval until the first
+	// block was received, or zero if none was.
+	FirstBlock time.Duration `json:",omitempty"`
+
+	// Total is the time the retrieval took to complete.
+	Total time.Duration
+
+	// Blocks is the digest of the time each block took to be received once
+	// requested.
+	Blocks *Histogram `json:",omitempty"`
 }
 
 type ReportBitswap struct {
@@ -65,6 +136,27 @@ type ReportBitswap struct {
 	MessagesReceived uint64
 }
 
+// Sub returns the difference of the stats from an earlier snapshot o. Counters
+// lower than in o, such as after the peer restarted, are returned as is.
+func (b ReportBitswap) Sub(o ReportBitswap) ReportBitswap {
+	sub := func(a, b uint64) uint64 {
+		if a < b {
+			return a
+		}
+		return a - b
+	}
+
+	return ReportBitswap{
+		BlocksReceived:   sub(b.BlocksReceived, o.BlocksReceived),
+		DataReceived:     sub(b.DataReceived, o.DataReceived),
+		BlocksSent:       sub(b.BlocksSent, o.BlocksSent),
+		DataSent:         sub(b.DataSent, o.DataSent),
+		DupBlksReceived:  sub(b.DupBlksReceived, o.DupBlksReceived),
+		DupDataReceived:  sub(b.DupDataReceived, o.DupDataReceived),
+		MessagesReceived: sub(b.MessagesReceived, o.MessagesReceived),
+	}
+}
+
 type ReportBandwidth struct {
 	Totals metrics.Stats
 
@@ -74,10 +166,37 @@ type ReportBandwidth struct {
 	Protocols map[protocol.ID]metrics.Stats
 }
 
+// Sub returns the bandwidth transferred since an earlier snapshot o. Rates are
+// kept as is, since they are not cumulative.
+func (b ReportBandwidth) Sub(o ReportBandwidth) ReportBandwidth {
+	sub := func(s, o metrics.Stats) metrics.Stats {
+		s.TotalIn -= o.TotalIn
+		s.TotalOut -= o.TotalOut
+		if s.TotalIn < 0 || s.TotalOut < 0 {
+			s.TotalIn += o.TotalIn
+			s.TotalOut += o.TotalOut
+		}
+		return s
+	}
+
+	diff := ReportBandwidth{
+		Totals:    sub(b.Totals, o.Totals),
+		Peers:     make(map[peer.ID]metrics.Stats, len(b.Peers)),
+		Protocols: make(map[protocol.ID]metrics.Stats, len(b.Protocols)),
+	}
+	for id, stats := range b.Peers {
+		diff.Peers[id] = sub(stats, o.Peers[id])
+	}
+	for id, stats := range b.Protocols {
+		diff.Protocols[id] = sub(stats, o.Protocols[id])
+	}
+	return diff
+}
+
 func (m *db) GetReport(ctx context.Context, id string) (Report, error) {
 	var report Report
 
-	err := m.View(ctx, func(tx *bolt.Tx) error {
+	err := m.view(ctx, func(tx *bolt.Tx) error {
 		bkt := getBenchmarksBucket(tx)
 		if bkt == nil {
 			return errors.Wrapf(errdefs.ErrNotFound, "benchmark %q", id)
@@ -103,7 +222,7 @@ func (m *db) GetReport(ctx context.Context, id string) (Report, error) {
 }
 
 func (m *db) CreateReport(ctx context.Context, id string, report Report) error {
-	err := m.Update(ctx, func(tx *bolt.Tx) error {
+	err := m.update(ctx, func(tx *bolt.Tx) error {
 		bkt, err := createBenchmarksBucket(tx)
 		if err != nil {
 			return err
@@ -150,3 +269,37 @@ func writeReport(bkt *bolt.Bucket, report Report) error {
 
 	return nil
 }
+
+// ReportDiff compares the results of two benchmarks.
+type ReportDiff struct {
+	A, B string
+
+	Metrics []ReportMetricDiff
+
+	// Warnings describe differences between the benchmarks that may make the
+	// comparison misleading.
+	Warnings []string `json:",omitempty"`
+}
+
+// ReportMetricDiff compares a metric between two benchmarks, where lower
+// values are better.
+type ReportMetricDiff struct {
+	Name string
+
+	Unit ReportMetricUnit
+
+	A, B float64
+
+	// Delta is the change from A to B in percent, omitted if A is zero.
+	Delta *float64 `json:",omitempty"`
+
+	// Regression is true if B is worse than A beyond the diff threshold.
+	Regression bool
+}
+
+type ReportMetricUnit string
+
+var (
+	ReportMetricDuration ReportMetricUnit = "duration"
+	ReportMetricBytes    ReportMetricUnit = "bytes"
+)
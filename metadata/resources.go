@@ -0,0 +1,99 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"time"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/pkg/errors"
+)
+
+// ResourceUsage is the resource usage of a node's p2p app, sampled at a fixed
+// interval while the benchmark runs. Samples are kept as series of values to
+// keep benchmarks compact.
+type ResourceUsage struct {
+	Interval time.Duration
+
+	// CPU is the CPU usage of each sample, in percent of a single core.
+	CPU []float64
+
+	// RSS is the resident set size of each sample, in bytes.
+	RSS []uint64
+}
+
+// ResourceSample is a single sample of the resource usage of a process.
+type ResourceSample struct {
+	CPU float64
+	RSS uint64
+}
+
+// ResourceSummary summarizes a series of samples.
+type ResourceSummary struct {
+	Min, Avg, Max float64
+}
+
+// Add appends a sample to the series.
+func (u *ResourceUsage) Add(sample ResourceSample) {
+	u.CPU = append(u.CPU, sample.CPU)
+	u.RSS = append(u.RSS, sample.RSS)
+}
+
+// CPUSummary returns the min, average and max CPU usage.
+func (u ResourceUsage) CPUSummary() ResourceSummary {
+	return summarize(len(u.CPU), func(i int) float64 { return u.CPU[i] })
+}
+
+// RSSSummary returns the min, average and max resident set size.
+func (u ResourceUsage) RSSSummary() ResourceSummary {
+	return summarize(len(u.RSS), func(i int) float64 { return float64(u.RSS[i]) })
+}
+
+func summarize(n int, value func(i int) float64) ResourceSummary {
+	var summary ResourceSummary
+	if n == 0 {
+		return summary
+	}
+
+	summary.Min, summary.Max = value(0), value(0)
+	var sum float64
+	for i := 0; i < n; i++ {
+		v := value(i)
+		if v < summary.Min {
+			summary.Min = v
+		}
+		if v > summary.Max {
+			summary.Max = v
+		}
+		sum += v
+	}
+	summary.Avg = sum / float64(n)
+	return summary
+}
+
+// ParseScenarioDuration parses a duration field of a scenario definition,
+// such as its sample interval. An empty value parses to zero.
+func ParseScenarioDuration(name, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, errors.Wrapf(errdefs.ErrInvalidArgument, "%s must be a positive duration, got %q", name, value)
+	}
+
+	return d, nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"encoding/json"
+	"net/url"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// RoutingModes are the content routings labapp can use. "nil" never finds
+	// providers, so content is only exchanged between connected peers.
+	RoutingModes = []string{"nil", "kaddht", "delegated", "mdns"}
+)
+
+// RoutingDefinition selects the content routing of nodes.
+type RoutingDefinition struct {
+	// Mode must be one of the following: ["nil", "kaddht", "delegated",
+	// "mdns"].
+	Mode string `json:"mode"`
+
+	// Endpoint is the URL of the IPFS HTTP API that providing and finding
+	// providers is delegated to, required by the "delegated" mode.
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// Validate returns an error if the mode is unknown or its endpoint is
+// missing.
+func (d RoutingDefinition) Validate() error {
+	if !contains(RoutingModes, d.Mode) {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "unknown routing mode %q, must be one of %q", d.Mode, RoutingModes)
+	}
+
+	if d.Mode != "delegated" {
+		if d.Endpoint != "" {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "routing mode %q does not take an endpoint", d.Mode)
+		}
+		return nil
+	}
+
+	u, err := url.Parse(d.Endpoint)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "routing mode \"delegated\" requires an http endpoint, got %q", d.Endpoint)
+	}
+
+	return nil
+}
+
+// Apply returns the peer definition with the routing replacing its own.
+func (d RoutingDefinition) Apply(pdef PeerDefinition) PeerDefinition {
+	pdef.Routing = d.Mode
+	pdef.RoutingEndpoint = d.Endpoint
+	return pdef
+}
+
+func readRouting(bkt *bolt.Bucket) (map[string]RoutingDefinition, error) {
+	v := bkt.Get(bucketKeyRouting)
+	if v == nil {
+		return nil, nil
+	}
+
+	var routing map[string]RoutingDefinition
+	err := json.Unmarshal(v, &routing)
+	if err != nil {
+		return nil, err
+	}
+	return routing, nil
+}
+
+func writeRouting(bkt *bolt.Bucket, routing map[string]RoutingDefinition) error {
+	if len(routing) == 0 {
+		return nil
+	}
+
+	content, err := json.Marshal(routing)
+	if err != nil {
+		return err
+	}
+	return bkt.Put(bucketKeyRouting, content)
+}
@@ -0,0 +1,47 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"testing"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoutingDefinitionValidate(t *testing.T) {
+	require.NoError(t, RoutingDefinition{Mode: "kaddht"}.Validate())
+	require.NoError(t, RoutingDefinition{Mode: "delegated", Endpoint: "http://127.0.0.1:5001"}.Validate())
+
+	for _, rdef := range []RoutingDefinition{
+		{},
+		{Mode: "gossip"},
+		{Mode: "delegated"},
+		{Mode: "delegated", Endpoint: "127.0.0.1:5001"},
+		{Mode: "mdns", Endpoint: "http://127.0.0.1:5001"},
+	} {
+		require.True(t, errdefs.IsInvalidArgument(rdef.Validate()), "%+v", rdef)
+	}
+}
+
+func TestRoutingDefinitionApply(t *testing.T) {
+	pdef := RoutingDefinition{Mode: "delegated", Endpoint: "http://127.0.0.1:5001"}.Apply(DefaultPeerDefinition)
+	require.Equal(t, "delegated", pdef.Routing)
+	require.Equal(t, "http://127.0.0.1:5001", pdef.RoutingEndpoint)
+	require.NoError(t, pdef.Validate())
+
+	pdef = RoutingDefinition{Mode: "mdns"}.Apply(pdef)
+	require.Empty(t, pdef.RoutingEndpoint)
+}
@@ -15,8 +15,14 @@
 package metadata
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Netflix/p2plab/errdefs"
@@ -42,9 +48,66 @@ type ScenarioDefinition struct {
 	// a cluster with initial data before running the benchmark.
 	Seed map[string]string `json:"seed,omitempty"`
 
+	// Seeding seeds only a subset of the cluster and benchmarks retrieval on
+	// the other nodes. It replaces the seed stage.
+	Seeding *SeedingDefinition `json:"seeding,omitempty"`
+
 	// Benchmark maps a query to an action. Queries are executed in parallel
 	// during the benchmark and metrics are collected during this stage.
 	Benchmark map[string]string `json:"benchmark,omitempty"`
+
+	// Steps replace the seed and benchmark stages with ordered steps, executed
+	// as a graph of dependencies during the benchmark. The timing of every
+	// step is recorded separately.
+	Steps []StepDefinition `json:"steps,omitempty"`
+
+	// Teardown maps a query to an action executed once the benchmark finishes,
+	// such as unpinning content pinned during the seed. Queries are executed in
+	// parallel, and failures don't fail the benchmark.
+	Teardown map[string]string `json:"teardown,omitempty"`
+
+	// Network impairs the network of every node during the benchmark stage. It
+	// is reset once the benchmark finishes or is canceled.
+	Network *NetworkSpec `json:"network,omitempty"`
+
+	// SampleInterval is how often the CPU and memory usage of every node is
+	// sampled during the benchmark stage, such as "1s". Sampling is disabled
+	// if empty.
+	SampleInterval string `json:"sampleInterval,omitempty"`
+
+	// Warmup is how long nodes stay connected before the benchmark stage is
+	// measured, such as "10s". Bandwidth and bitswap activity during the
+	// warmup are discarded from the report.
+	Warmup string `json:"warmup,omitempty"`
+
+	// Cooldown is how long in-flight transfers are left to settle after the
+	// benchmark stage is measured, before the nodes are torn down.
+	Cooldown string `json:"cooldown,omitempty"`
+
+	// Objective changes what the benchmark stage measures.
+	Objective *ObjectiveDefinition `json:"objective,omitempty"`
+
+	// MaxConcurrency bounds how many nodes execute a stage in parallel, nodes
+	// beyond the limit are queued until a slot frees. Steps running at the same
+	// time share the limit. Unbounded if zero.
+	MaxConcurrency int `json:"maxConcurrency,omitempty"`
+
+	// Stack overrides the libp2p transports, muxers and security transports
+	// of every node for the benchmark.
+	Stack *StackDefinition `json:"stack,omitempty"`
+
+	// Routing maps a query to the content routing of the matched nodes for the
+	// benchmark, so nodes with different routings can be benchmarked together.
+	// Nodes not matched keep their own routing.
+	Routing map[string]RoutingDefinition `json:"routing,omitempty"`
+
+	// Datastore overrides the datastore of every node for the benchmark, one of
+	// Datastores. "memory" isolates the network from disk.
+	Datastore string `json:"datastore,omitempty"`
+
+	// GC garbage collects the blockstore of every node as the benchmark is
+	// torn down.
+	GC *GCDefinition `json:"gc,omitempty"`
 }
 
 // ObjectDefinition define a type of data that will be distributed during the
@@ -69,6 +132,22 @@ type ObjectDefinition struct {
 	HashFunc string `json:"hashFunc"`
 
 	MaxLinks int `json:"maxLinks"`
+
+	// Platform pins the platform selected from a multi-arch image index, in the
+	// form "os/arch[/variant]". Defaults to "linux/amd64".
+	Platform string `json:"platform,omitempty"`
+
+	// Exclude is a list of gitignore-style patterns of files to skip when the
+	// source is a directory tree.
+	Exclude []string `json:"exclude,omitempty"`
+
+	// Size is the size of generated content such as "1GB", required for objects
+	// of type "random".
+	Size string `json:"size,omitempty"`
+
+	// Seed is the seed for generated content. The same size and seed always
+	// produces the same content.
+	Seed int64 `json:"seed,omitempty"`
 }
 
 // ObjectType is the type of data retrieved.
@@ -79,10 +158,226 @@ var (
 	ObjectContainerImage ObjectType = "oci-image"
 )
 
+// ParseScenarioDefinition decodes a scenario definition, rejecting unknown
+// fields, and validates it.
+func ParseScenarioDefinition(content []byte) (ScenarioDefinition, error) {
+	var sdef ScenarioDefinition
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(&sdef)
+	if err != nil {
+		path, ok := unknownScenarioField(content)
+		if ok {
+			return sdef, errors.Wrapf(errdefs.ErrInvalidArgument, "scenario definition has unknown field %q", path)
+		}
+		return sdef, errors.Wrapf(errdefs.ErrInvalidArgument, "invalid scenario definition: %s", err)
+	}
+
+	return sdef, sdef.Validate()
+}
+
+// Validate returns an error if a required field is missing.
+func (d ScenarioDefinition) Validate() error {
+	errs := d.Problems()
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// Problems returns every error of the definition instead of only the first,
+// so that they can all be fixed at once.
+func (d ScenarioDefinition) Problems() []error {
+	var errs []error
+	if len(d.Steps) > 0 {
+		for _, field := range []struct {
+			name string
+			set  bool
+		}{
+			{"seed", len(d.Seed) > 0},
+			{"seeding", d.Seeding != nil},
+			{"benchmark", len(d.Benchmark) > 0},
+			{"objective", d.Objective != nil && d.Objective.Type == ObjectiveConnectivity},
+		} {
+			if field.set {
+				errs = append(errs, errors.Wrapf(errdefs.ErrInvalidArgument, "scenario definition cannot have both \"steps\" and %q", field.name))
+			}
+		}
+
+		err := ValidateSteps(d.Steps)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	} else if len(d.Benchmark) == 0 {
+		errs = append(errs, errors.Wrap(errdefs.ErrInvalidArgument, "scenario definition is missing required field \"benchmark\""))
+	}
+
+	if d.Network != nil {
+		err := d.Network.Validate()
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, field := range []struct {
+		name  string
+		value string
+	}{
+		{"sample interval", d.SampleInterval},
+		{"warmup", d.Warmup},
+		{"cooldown", d.Cooldown},
+	} {
+		_, err := ParseScenarioDuration(field.name, field.value)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if d.MaxConcurrency < 0 {
+		errs = append(errs, errors.Wrapf(errdefs.ErrInvalidArgument, "max concurrency must not be negative, got %d", d.MaxConcurrency))
+	}
+
+	if d.Objective != nil {
+		err := d.Objective.Validate()
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if d.Stack != nil {
+		err := d.Stack.Validate()
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, q := range sortedKeys(d.Routing) {
+		err := d.Routing[q].Validate()
+		if err != nil {
+			errs = append(errs, errors.Wrapf(err, "routing for query %q", q))
+		}
+	}
+
+	if d.Datastore != "" {
+		if !contains(Datastores, d.Datastore) {
+			errs = append(errs, errors.Wrapf(errdefs.ErrInvalidArgument, "unknown datastore %q, must be one of %q", d.Datastore, Datastores))
+		} else if d.Datastore == "memory" {
+			// Nothing is read from disk, so there is no page cache to drop.
+			for _, stage := range []map[string]string{d.Seed, d.Benchmark, d.Teardown} {
+				for _, q := range sortedKeys(stage) {
+					if strings.TrimSpace(stage[q]) == string(TaskDropCache) {
+						errs = append(errs, errors.Wrapf(errdefs.ErrInvalidArgument, "action %q for query %q cannot be used with datastore \"memory\"", stage[q], q))
+					}
+				}
+			}
+			for _, step := range d.Steps {
+				if strings.TrimSpace(step.Action) == string(TaskDropCache) {
+					errs = append(errs, errors.Wrapf(errdefs.ErrInvalidArgument, "action %q of step %q cannot be used with datastore \"memory\"", step.Action, step.Name))
+				}
+			}
+		}
+	}
+
+	if d.GC != nil {
+		err := d.GC.Validate(d.Objects)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if d.Seeding != nil {
+		if len(d.Seed) > 0 {
+			errs = append(errs, errors.Wrap(errdefs.ErrInvalidArgument, "scenario definition cannot have both \"seed\" and \"seeding\""))
+		}
+
+		err := d.Seeding.Validate()
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, name := range sortedKeys(d.Objects) {
+		odef := d.Objects[name]
+		required := []string{"type", "source"}
+		values := map[string]string{
+			"type":   odef.Type,
+			"source": odef.Source,
+			"size":   odef.Size,
+		}
+		if odef.Type == "random" {
+			required = []string{"type", "size"}
+		}
+
+		for _, field := range required {
+			if values[field] == "" {
+				errs = append(errs, errors.Wrapf(errdefs.ErrInvalidArgument, "scenario definition is missing required field \"objects.%s.%s\"", name, field))
+			}
+		}
+	}
+
+	return errs
+}
+
+// sortedKeys returns the keys of a map with string keys in order.
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	for _, k := range reflect.ValueOf(m).MapKeys() {
+		keys = append(keys, k.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// unknownScenarioField returns the path of the first field in content that
+// doesn't belong to a scenario definition.
+func unknownScenarioField(content []byte) (string, bool) {
+	var sdef map[string]json.RawMessage
+	err := json.Unmarshal(content, &sdef)
+	if err != nil {
+		return "", false
+	}
+
+	for key := range sdef {
+		if !hasJSONField(reflect.TypeOf(ScenarioDefinition{}), key) {
+			return key, true
+		}
+	}
+
+	var objects map[string]map[string]json.RawMessage
+	err = json.Unmarshal(sdef["objects"], &objects)
+	if err != nil {
+		return "", false
+	}
+
+	for name, odef := range objects {
+		for key := range odef {
+			if !hasJSONField(reflect.TypeOf(ObjectDefinition{}), key) {
+				return fmt.Sprintf("objects.%s.%s", name, key), true
+			}
+		}
+	}
+
+	return "", false
+}
+
+func hasJSONField(t reflect.Type, key string) bool {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" {
+			name = field.Name
+		}
+		if strings.EqualFold(name, key) {
+			return true
+		}
+	}
+	return false
+}
+
 func (m *db) GetScenario(ctx context.Context, id string) (Scenario, error) {
 	var scenario Scenario
 
-	err := m.View(ctx, func(tx *bolt.Tx) error {
+	err := m.view(ctx, func(tx *bolt.Tx) error {
 		bkt := getScenariosBucket(tx)
 		if bkt == nil {
 			return errors.Wrapf(errdefs.ErrNotFound, "scenario %q", id)
@@ -110,7 +405,7 @@ func (m *db) GetScenario(ctx context.Context, id string) (Scenario, error) {
 
 func (m *db) ListScenarios(ctx context.Context) ([]Scenario, error) {
 	var scenarios []Scenario
-	err := m.View(ctx, func(tx *bolt.Tx) error {
+	err := m.view(ctx, func(tx *bolt.Tx) error {
 		bkt := getScenariosBucket(tx)
 		if bkt == nil {
 			return nil
@@ -141,7 +436,7 @@ func (m *db) ListScenarios(ctx context.Context) ([]Scenario, error) {
 }
 
 func (m *db) CreateScenario(ctx context.Context, scenario Scenario) (Scenario, error) {
-	err := m.Update(ctx, func(tx *bolt.Tx) error {
+	err := m.update(ctx, func(tx *bolt.Tx) error {
 		bkt, err := createScenariosBucket(tx)
 		if err != nil {
 			return err
@@ -171,7 +466,7 @@ func (m *db) UpdateScenario(ctx context.Context, scenario Scenario) (Scenario, e
 		return Scenario{}, errors.Wrapf(errdefs.ErrInvalidArgument, "scenario id required for update")
 	}
 
-	err := m.Update(ctx, func(tx *bolt.Tx) error {
+	err := m.update(ctx, func(tx *bolt.Tx) error {
 		bkt, err := createScenariosBucket(tx)
 		if err != nil {
 			return err
@@ -194,7 +489,7 @@ func (m *db) UpdateScenario(ctx context.Context, scenario Scenario) (Scenario, e
 
 func (m *db) LabelScenarios(ctx context.Context, ids, adds, removes []string) ([]Scenario, error) {
 	var scenarios []Scenario
-	err := m.Update(ctx, func(tx *bolt.Tx) error {
+	err := m.update(ctx, func(tx *bolt.Tx) error {
 		bkt, err := createScenariosBucket(tx)
 		if err != nil {
 			return err
@@ -232,7 +527,7 @@ func (m *db) LabelScenarios(ctx context.Context, ids, adds, removes []string) ([
 }
 
 func (m *db) DeleteScenarios(ctx context.Context, ids ...string) error {
-	return m.Update(ctx, func(tx *bolt.Tx) error {
+	return m.update(ctx, func(tx *bolt.Tx) error {
 		bkt := getScenariosBucket(tx)
 		if bkt == nil {
 			return nil
@@ -306,6 +601,58 @@ func readScenarioDefinition(bkt *bolt.Bucket) (ScenarioDefinition, error) {
 		return sdef, err
 	}
 
+	sdef.Teardown, err = readMap(dbkt, bucketKeyTeardown)
+	if err != nil {
+		return sdef, err
+	}
+
+	sdef.Network, err = readNetworkSpec(dbkt)
+	if err != nil {
+		return sdef, err
+	}
+
+	sdef.SampleInterval = string(dbkt.Get(bucketKeySampleInterval))
+	sdef.Warmup = string(dbkt.Get(bucketKeyWarmup))
+	sdef.Cooldown = string(dbkt.Get(bucketKeyCooldown))
+	sdef.Datastore = string(dbkt.Get(bucketKeyDatastore))
+
+	if v := dbkt.Get(bucketKeyMaxConcurrency); v != nil {
+		sdef.MaxConcurrency, err = strconv.Atoi(string(v))
+		if err != nil {
+			return sdef, err
+		}
+	}
+
+	sdef.Objective, err = readObjective(dbkt)
+	if err != nil {
+		return sdef, err
+	}
+
+	sdef.Seeding, err = readSeeding(dbkt)
+	if err != nil {
+		return sdef, err
+	}
+
+	sdef.Stack, err = readStack(dbkt)
+	if err != nil {
+		return sdef, err
+	}
+
+	sdef.Routing, err = readRouting(dbkt)
+	if err != nil {
+		return sdef, err
+	}
+
+	sdef.GC, err = readGC(dbkt)
+	if err != nil {
+		return sdef, err
+	}
+
+	sdef.Steps, err = readSteps(dbkt)
+	if err != nil {
+		return sdef, err
+	}
+
 	return sdef, nil
 }
 
@@ -366,6 +713,67 @@ func writeScenarioDefinition(bkt *bolt.Bucket, sdef ScenarioDefinition) error {
 		return err
 	}
 
+	err = writeMap(dbkt, bucketKeyTeardown, sdef.Teardown)
+	if err != nil {
+		return err
+	}
+
+	err = writeNetworkSpec(dbkt, sdef.Network)
+	if err != nil {
+		return err
+	}
+
+	fields := []field{
+		{bucketKeySampleInterval, []byte(sdef.SampleInterval)},
+		{bucketKeyWarmup, []byte(sdef.Warmup)},
+		{bucketKeyCooldown, []byte(sdef.Cooldown)},
+		{bucketKeyDatastore, []byte(sdef.Datastore)},
+	}
+	if sdef.MaxConcurrency > 0 {
+		fields = append(fields, field{bucketKeyMaxConcurrency, []byte(strconv.Itoa(sdef.MaxConcurrency))})
+	}
+
+	for _, f := range fields {
+		if len(f.value) == 0 {
+			continue
+		}
+
+		err = dbkt.Put(f.key, f.value)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = writeObjective(dbkt, sdef.Objective)
+	if err != nil {
+		return err
+	}
+
+	err = writeSeeding(dbkt, sdef.Seeding)
+	if err != nil {
+		return err
+	}
+
+	err = writeStack(dbkt, sdef.Stack)
+	if err != nil {
+		return err
+	}
+
+	err = writeRouting(dbkt, sdef.Routing)
+	if err != nil {
+		return err
+	}
+
+	err = writeGC(dbkt, sdef.GC)
+	if err != nil {
+		return err
+	}
+
+	err = writeSteps(dbkt, sdef.Steps)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -399,6 +807,16 @@ func readObjects(bkt *bolt.Bucket) (map[string]ObjectDefinition, error) {
 				object.HashFunc = string(v)
 			case string(bucketKeyMaxLinks):
 				object.MaxLinks, _ = strconv.Atoi(string(v))
+			case string(bucketKeyPlatform):
+				object.Platform = string(v)
+			case string(bucketKeyExclude):
+				if len(v) > 0 {
+					object.Exclude = strings.Split(string(v), "\n")
+				}
+			case string(bucketKeySize):
+				object.Size = string(v)
+			case string(bucketKeySeed):
+				object.Seed, _ = strconv.ParseInt(string(v), 10, 64)
 			}
 			return nil
 		})
@@ -436,6 +854,10 @@ func writeObjects(bkt *bolt.Bucket, objects map[string]ObjectDefinition) error {
 			{bucketKeyRawLeaves, []byte(strconv.FormatBool(object.RawLeaves))},
 			{bucketKeyHashFunc, []byte(object.HashFunc)},
 			{bucketKeyMaxLinks, []byte(strconv.Itoa(object.MaxLinks))},
+			{bucketKeyPlatform, []byte(object.Platform)},
+			{bucketKeyExclude, []byte(strings.Join(object.Exclude, "\n"))},
+			{bucketKeySize, []byte(object.Size)},
+			{bucketKeySeed, []byte(strconv.FormatInt(object.Seed, 10))},
 		} {
 			err = nbkt.Put(f.key, f.value)
 			if err != nil {
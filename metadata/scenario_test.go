@@ -0,0 +1,254 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseScenarioDefinition(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{
+			"valid",
+			`{"objects": {"golang": {"type": "oci", "source": "docker.io/library/golang:latest"}}, "benchmark": {"*": "golang"}}`,
+			"",
+		},
+		{
+			"unknown top-level field",
+			`{"benchmarks": {"*": "golang"}}`,
+			`"benchmarks"`,
+		},
+		{
+			"unknown object field",
+			`{"objects": {"golang": {"type": "oci", "sources": "golang"}}, "benchmark": {"*": "golang"}}`,
+			`"objects.golang.sources"`,
+		},
+		{
+			"missing benchmark",
+			`{"objects": {"golang": {"type": "oci", "source": "golang"}}}`,
+			`"benchmark"`,
+		},
+		{
+			"missing object source",
+			`{"objects": {"golang": {"type": "oci"}}, "benchmark": {"*": "golang"}}`,
+			`"objects.golang.source"`,
+		},
+		{
+			"random object without source",
+			`{"objects": {"noise": {"type": "random", "size": "1MB", "seed": 42}}, "benchmark": {"*": "noise"}}`,
+			"",
+		},
+		{
+			"missing random object size",
+			`{"objects": {"noise": {"type": "random"}}, "benchmark": {"*": "noise"}}`,
+			`"objects.noise.size"`,
+		},
+		{
+			"network impairment",
+			`{"benchmark": {"*": "golang"}, "network": {"latency": "100ms", "jitter": "10ms", "bandwidth": "10mbit", "loss": 0.5}}`,
+			"",
+		},
+		{
+			"invalid network bandwidth",
+			`{"benchmark": {"*": "golang"}, "network": {"bandwidth": "fast"}}`,
+			"network bandwidth",
+		},
+		{
+			"network loss out of range",
+			`{"benchmark": {"*": "golang"}, "network": {"loss": 150}}`,
+			"network loss",
+		},
+		{
+			"invalid sample interval",
+			`{"benchmark": {"*": "golang"}, "sampleInterval": "-1s"}`,
+			"sample interval",
+		},
+		{
+			"connectivity objective",
+			`{"benchmark": {"*": ""}, "objective": {"type": "connectivity", "peers": 3, "timeout": "30s"}}`,
+			"",
+		},
+		{
+			"unknown objective type",
+			`{"benchmark": {"*": ""}, "objective": {"type": "latency"}}`,
+			"objective type",
+		},
+		{
+			"invalid objective interval",
+			`{"benchmark": {"*": ""}, "objective": {"type": "connectivity", "interval": "often"}}`,
+			"objective interval",
+		},
+		{
+			"seeding by percent",
+			`{"objects": {"noise": {"type": "random", "size": "1MB"}}, "seeding": {"percent": 25, "action": "noise"}, "benchmark": {"*": "noise"}}`,
+			"",
+		},
+		{
+			"seeding by query and percent",
+			`{"seeding": {"query": "'seeder'", "percent": 25, "action": "noise"}, "benchmark": {"*": "noise"}}`,
+			"exactly one of query or percent",
+		},
+		{
+			"seeding with seed",
+			`{"seed": {"*": "noise"}, "seeding": {"percent": 25, "action": "noise"}, "benchmark": {"*": "noise"}}`,
+			`"seeding"`,
+		},
+		{
+			"seeding every node",
+			`{"seeding": {"percent": 100, "action": "noise"}, "benchmark": {"*": "noise"}}`,
+			"seeding percent",
+		},
+		{
+			"warmup and cooldown",
+			`{"benchmark": {"*": "golang"}, "warmup": "10s", "cooldown": "5s"}`,
+			"",
+		},
+		{
+			"negative max concurrency",
+			`{"benchmark": {"*": "golang"}, "maxConcurrency": -1}`,
+			"max concurrency",
+		},
+		{
+			"invalid cooldown",
+			`{"benchmark": {"*": "golang"}, "cooldown": "0s"}`,
+			"cooldown",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseScenarioDefinition([]byte(test.content))
+			if test.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			require.True(t, errdefs.IsInvalidArgument(err))
+			require.Contains(t, err.Error(), test.err)
+		})
+	}
+}
+
+func TestScenarioDefinitionProblems(t *testing.T) {
+	sdef := ScenarioDefinition{
+		Objects: map[string]ObjectDefinition{
+			"golang": {Type: "oci"},
+			"noise":  {Type: "random"},
+		},
+		Warmup:         "soon",
+		MaxConcurrency: -1,
+	}
+
+	errs := sdef.Problems()
+	require.Len(t, errs, 5)
+	for _, err := range errs {
+		require.True(t, errdefs.IsInvalidArgument(err), "%v", err)
+	}
+	require.Contains(t, errs[0].Error(), `"benchmark"`)
+	require.Contains(t, errs[3].Error(), `"objects.golang.source"`)
+	require.Contains(t, errs[4].Error(), `"objects.noise.size"`)
+	require.EqualError(t, sdef.Validate(), errs[0].Error())
+}
+
+func TestScenarioObjects(t *testing.T) {
+	root, err := ioutil.TempDir("", "p2plab-metadata")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	db, err := NewDB(root)
+	require.NoError(t, err)
+	defer db.Close()
+
+	objects := map[string]ObjectDefinition{
+		"image": {Type: "oci", Source: "docker.io/library/golang:latest", Platform: "linux/arm64"},
+		"site":  {Type: "dir", Source: "/srv/site", Exclude: []string{"*.log", "!keep.log"}},
+		"noise": {Type: "random", Size: "1GB", Seed: 42},
+	}
+
+	ctx := context.Background()
+	_, err = db.CreateScenario(ctx, Scenario{
+		ID: "scenario",
+		Definition: ScenarioDefinition{
+			Objects:   objects,
+			Benchmark: map[string]string{"*": "image"},
+			Network:   &NetworkSpec{Latency: "50ms", Loss: 1.5},
+
+			SampleInterval: "500ms",
+			Warmup:         "10s",
+			Objective:      &ObjectiveDefinition{Type: ObjectiveConnectivity, Peers: 2},
+			Seeding:        &SeedingDefinition{Query: "'seeder'", Action: "image"},
+			MaxConcurrency: 8,
+		},
+	})
+	require.NoError(t, err)
+
+	actual, err := db.GetScenario(ctx, "scenario")
+	require.NoError(t, err)
+	require.Equal(t, objects, actual.Definition.Objects)
+	require.Equal(t, &NetworkSpec{Latency: "50ms", Loss: 1.5}, actual.Definition.Network)
+	require.Equal(t, "500ms", actual.Definition.SampleInterval)
+	require.Equal(t, "10s", actual.Definition.Warmup)
+	require.Equal(t, &ObjectiveDefinition{Type: ObjectiveConnectivity, Peers: 2}, actual.Definition.Objective)
+	require.Equal(t, &SeedingDefinition{Query: "'seeder'", Action: "image"}, actual.Definition.Seeding)
+	require.Equal(t, 8, actual.Definition.MaxConcurrency)
+}
+
+func TestScenarioDefinitionDatastore(t *testing.T) {
+	sdef := ScenarioDefinition{
+		Objects:   map[string]ObjectDefinition{"image": {Type: "oci", Source: "alpine"}},
+		Seed:      map[string]string{"(not 'seeder')": "drop-cache"},
+		Benchmark: map[string]string{"*": "image"},
+		Datastore: "flatfs",
+	}
+	require.Empty(t, sdef.Problems())
+
+	sdef.Datastore = "memory"
+	errs := sdef.Problems()
+	require.Len(t, errs, 1)
+	require.Contains(t, errs[0].Error(), `"drop-cache" for query "(not 'seeder')" cannot be used with datastore "memory"`)
+
+	sdef.Datastore = "leveldb"
+	errs = sdef.Problems()
+	require.Len(t, errs, 1)
+	require.True(t, errdefs.IsInvalidArgument(errs[0]), "%v", errs[0])
+}
+
+func TestScenarioDefinitionSteps(t *testing.T) {
+	sdef := ScenarioDefinition{
+		Objects: map[string]ObjectDefinition{"image": {Type: "oci", Source: "alpine"}},
+		Steps: []StepDefinition{
+			{Name: "pin", Nodes: "'seeder'", Action: "pin image"},
+			{Name: "connect", Nodes: "*", Action: "connect", DependsOn: []string{"pin"}},
+			{Name: "get", Nodes: "(not 'seeder')", Action: "image", DependsOn: []string{"connect"}},
+			{Name: "gc", Nodes: "*", Action: "gc", DependsOn: []string{"get"}},
+		},
+	}
+	require.Empty(t, sdef.Problems())
+
+	sdef.Benchmark = map[string]string{"*": "image"}
+	sdef.Steps[0].DependsOn = []string{"gc"}
+	errs := sdef.Problems()
+	require.Len(t, errs, 2)
+	require.Contains(t, errs[0].Error(), `cannot have both "steps" and "benchmark"`)
+	require.Contains(t, errs[1].Error(), `depend on each other in a cycle`)
+}
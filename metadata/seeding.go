@@ -0,0 +1,143 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+// SeedingDefinition seeds a subset of the cluster, the seeders, and benchmarks
+// retrieval on the nodes that didn't seed, the leechers. Seeders are selected
+// either by query or by percentage.
+type SeedingDefinition struct {
+	// Query selects the seeders.
+	Query string `json:"query,omitempty"`
+
+	// Percent selects this percentage of the cluster as seeders, rounded up,
+	// taking nodes in order of their IDs.
+	Percent float64 `json:"percent,omitempty"`
+
+	// Action is what seeders do to seed, such as retrieving an object.
+	Action string `json:"action"`
+}
+
+// Validate returns an error if the seeding is invalid.
+func (d SeedingDefinition) Validate() error {
+	if (d.Query == "") == (d.Percent == 0) {
+		return errors.Wrap(errdefs.ErrInvalidArgument, "seeding must select seeders by exactly one of query or percent")
+	}
+
+	if d.Percent < 0 || d.Percent >= 100 {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "seeding percent must be within (0, 100), got %v", d.Percent)
+	}
+
+	if d.Action == "" {
+		return errors.Wrap(errdefs.ErrInvalidArgument, "scenario definition is missing required field \"seeding.action\"")
+	}
+
+	return nil
+}
+
+// SelectPercent returns the seeders among ids selected by percent.
+func (d SeedingDefinition) SelectPercent(ids []string) []string {
+	sorted := append([]string(nil), ids...)
+	sort.Strings(sorted)
+
+	n := int(math.Ceil(float64(len(sorted)) * d.Percent / 100))
+	return sorted[:n]
+}
+
+// ValidateSeeding returns an error if the seeders and leechers of a plan
+// overlap, or if there is nothing to benchmark.
+func ValidateSeeding(seeders, leechers []string) error {
+	if len(seeders) == 0 {
+		return errors.Wrap(errdefs.ErrInvalidArgument, "seeding selects no seeders")
+	}
+	if len(leechers) == 0 {
+		return errors.Wrap(errdefs.ErrInvalidArgument, "seeding leaves no leechers to benchmark")
+	}
+
+	seen := make(map[string]struct{})
+	for _, id := range seeders {
+		seen[id] = struct{}{}
+	}
+
+	var overlap []string
+	for _, id := range leechers {
+		if _, ok := seen[id]; ok {
+			overlap = append(overlap, id)
+		}
+	}
+	if len(overlap) > 0 {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "nodes %v are both seeders and leechers", overlap)
+	}
+
+	return nil
+}
+
+func readSeeding(bkt *bolt.Bucket) (*SeedingDefinition, error) {
+	v := bkt.Get(bucketKeySeeding)
+	if v == nil {
+		return nil, nil
+	}
+
+	var seeding SeedingDefinition
+	err := json.Unmarshal(v, &seeding)
+	if err != nil {
+		return nil, err
+	}
+	return &seeding, nil
+}
+
+func writeSeeding(bkt *bolt.Bucket, seeding *SeedingDefinition) error {
+	if seeding == nil {
+		return nil
+	}
+
+	content, err := json.Marshal(seeding)
+	if err != nil {
+		return err
+	}
+	return bkt.Put(bucketKeySeeding, content)
+}
+
+func readIDs(bkt *bolt.Bucket, key []byte) ([]string, error) {
+	v := bkt.Get(key)
+	if v == nil {
+		return nil, nil
+	}
+
+	var ids []string
+	err := json.Unmarshal(v, &ids)
+	return ids, err
+}
+
+func writeIDs(bkt *bolt.Bucket, key []byte, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	content, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+	return bkt.Put(key, content)
+}
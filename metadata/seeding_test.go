@@ -0,0 +1,40 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"testing"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectPercent(t *testing.T) {
+	ids := []string{"d", "a", "c", "b", "e"}
+	require.Equal(t, []string{"a"}, SeedingDefinition{Percent: 10}.SelectPercent(ids))
+	require.Equal(t, []string{"a", "b", "c"}, SeedingDefinition{Percent: 50}.SelectPercent(ids))
+	require.Equal(t, []string{"d", "a", "c", "b", "e"}, ids)
+}
+
+func TestValidateSeeding(t *testing.T) {
+	require.NoError(t, ValidateSeeding([]string{"a"}, []string{"b", "c"}))
+
+	err := ValidateSeeding([]string{"a", "b"}, []string{"b", "c"})
+	require.True(t, errdefs.IsInvalidArgument(err))
+	require.Contains(t, err.Error(), "[b]")
+
+	err = ValidateSeeding([]string{"a"}, nil)
+	require.True(t, errdefs.IsInvalidArgument(err))
+}
@@ -0,0 +1,168 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"encoding/json"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// StackTransports, StackMuxers and StackSecurityTransports are the libp2p
+	// components labapp can enable.
+	StackTransports         = []string{"tcp", "ws", "quic"}
+	StackMuxers             = []string{"mplex", "yamux"}
+	StackSecurityTransports = []string{"secio", "tls"}
+)
+
+// StackDefinition selects the libp2p transports, muxers and security
+// transports nodes enable at startup. Empty fields keep the node's own.
+type StackDefinition struct {
+	Transports []string `json:"transports,omitempty"`
+
+	Muxers []string `json:"muxers,omitempty"`
+
+	SecurityTransports []string `json:"securityTransports,omitempty"`
+}
+
+// Validate returns an error if the stack selects an unknown component.
+func (d StackDefinition) Validate() error {
+	for _, c := range []struct {
+		kind  string
+		names []string
+		known []string
+	}{
+		{"transport", d.Transports, StackTransports},
+		{"muxer", d.Muxers, StackMuxers},
+		{"security transport", d.SecurityTransports, StackSecurityTransports},
+	} {
+		seen := make(map[string]struct{})
+		for _, name := range c.names {
+			if name == "noise" {
+				return errors.Wrap(errdefs.ErrInvalidArgument, "security transport \"noise\" is not supported by the libp2p version of labapp")
+			}
+
+			if !contains(c.known, name) {
+				return errors.Wrapf(errdefs.ErrInvalidArgument, "unknown %s %q, must be one of %q", c.kind, name, c.known)
+			}
+
+			if _, ok := seen[name]; ok {
+				return errors.Wrapf(errdefs.ErrInvalidArgument, "%s %q is listed twice", c.kind, name)
+			}
+			seen[name] = struct{}{}
+		}
+	}
+
+	return nil
+}
+
+// Apply returns the peer definition with the non-empty fields of the stack
+// replacing its own.
+func (d StackDefinition) Apply(pdef PeerDefinition) PeerDefinition {
+	if len(d.Transports) > 0 {
+		pdef.Transports = d.Transports
+	}
+	if len(d.Muxers) > 0 {
+		pdef.Muxers = d.Muxers
+	}
+	if len(d.SecurityTransports) > 0 {
+		pdef.SecurityTransports = d.SecurityTransports
+	}
+	return pdef
+}
+
+// Validate returns an error if the peer's libp2p stack is incomplete,
+// combines components that cannot work together, or has an invalid routing or
+// datastore.
+func (pdef PeerDefinition) Validate() error {
+	stack := StackDefinition{
+		Transports:         pdef.Transports,
+		Muxers:             pdef.Muxers,
+		SecurityTransports: pdef.SecurityTransports,
+	}
+	err := stack.Validate()
+	if err != nil {
+		return err
+	}
+
+	if len(pdef.Transports) == 0 {
+		return errors.Wrap(errdefs.ErrInvalidArgument, "peer must enable at least one transport")
+	}
+
+	// QUIC secures and multiplexes connections itself, so the other
+	// components are only negotiated over the other transports.
+	quicOnly := len(pdef.Transports) == 1 && pdef.Transports[0] == "quic"
+	if quicOnly {
+		if len(pdef.SecurityTransports) > 0 && !contains(pdef.SecurityTransports, "tls") {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "transport \"quic\" always secures connections with \"tls\", cannot use %q", pdef.SecurityTransports)
+		}
+	} else {
+		if len(pdef.Muxers) == 0 {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "transports %q require at least one muxer", pdef.Transports)
+		}
+
+		if len(pdef.SecurityTransports) == 0 {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "transports %q require at least one security transport", pdef.Transports)
+		}
+	}
+
+	if pdef.Datastore != "" && !contains(Datastores, pdef.Datastore) {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "unknown datastore %q, must be one of %q", pdef.Datastore, Datastores)
+	}
+
+	if pdef.Routing != "" {
+		return RoutingDefinition{Mode: pdef.Routing, Endpoint: pdef.RoutingEndpoint}.Validate()
+	}
+
+	return nil
+}
+
+func contains(l []string, s string) bool {
+	for _, e := range l {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+func readStack(bkt *bolt.Bucket) (*StackDefinition, error) {
+	v := bkt.Get(bucketKeyStack)
+	if v == nil {
+		return nil, nil
+	}
+
+	var stack StackDefinition
+	err := json.Unmarshal(v, &stack)
+	if err != nil {
+		return nil, err
+	}
+	return &stack, nil
+}
+
+func writeStack(bkt *bolt.Bucket, stack *StackDefinition) error {
+	if stack == nil {
+		return nil
+	}
+
+	content, err := json.Marshal(stack)
+	if err != nil {
+		return err
+	}
+	return bkt.Put(bucketKeyStack, content)
+}
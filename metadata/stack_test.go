@@ -0,0 +1,63 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"testing"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackDefinitionValidate(t *testing.T) {
+	require.NoError(t, StackDefinition{}.Validate())
+	require.NoError(t, StackDefinition{Transports: []string{"quic"}, SecurityTransports: []string{"tls"}}.Validate())
+
+	for _, stack := range []StackDefinition{
+		{Transports: []string{"udp"}},
+		{Muxers: []string{"yamux", "yamux"}},
+		{SecurityTransports: []string{"noise"}},
+	} {
+		require.True(t, errdefs.IsInvalidArgument(stack.Validate()), "%+v", stack)
+	}
+}
+
+func TestStackDefinitionApply(t *testing.T) {
+	pdef := StackDefinition{Transports: []string{"quic"}}.Apply(DefaultPeerDefinition)
+	require.Equal(t, []string{"quic"}, pdef.Transports)
+	require.Equal(t, DefaultPeerDefinition.Muxers, pdef.Muxers)
+	require.Equal(t, DefaultPeerDefinition.GitReference, pdef.GitReference)
+}
+
+func TestPeerDefinitionValidate(t *testing.T) {
+	require.NoError(t, DefaultPeerDefinition.Validate())
+	require.NoError(t, PeerDefinition{Transports: []string{"quic"}}.Validate())
+	require.NoError(t, PeerDefinition{
+		Transports:         []string{"tcp", "quic"},
+		Muxers:             []string{"yamux"},
+		SecurityTransports: []string{"secio"},
+	}.Validate())
+	require.NoError(t, PeerDefinition{Transports: []string{"quic"}, Datastore: "flatfs"}.Validate())
+
+	for _, pdef := range []PeerDefinition{
+		{},
+		{Transports: []string{"quic"}, Datastore: "leveldb"},
+		{Transports: []string{"quic"}, SecurityTransports: []string{"secio"}},
+		{Transports: []string{"tcp"}, SecurityTransports: []string{"tls"}},
+		{Transports: []string{"ws"}, Muxers: []string{"mplex"}},
+	} {
+		require.True(t, errdefs.IsInvalidArgument(pdef.Validate()), "%+v", pdef)
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"time"
+
+	metrics "github.com/libp2p/go-libp2p-core/metrics"
+)
+
+// PeerStats is a snapshot of the repo and network of a peer, cheap enough to
+// be polled frequently.
+type PeerStats struct {
+	Time time.Time
+
+	// RepoSize is the size of the datastore on disk in bytes.
+	RepoSize uint64
+
+	// Pinned is the number of CIDs pinned.
+	Pinned int
+
+	// Peers is the number of connected peers.
+	Peers int
+
+	// RoutingTableSize is the number of peers in the DHT routing table, or
+	// zero if the peer doesn't route with a DHT.
+	RoutingTableSize int
+
+	Bandwidth metrics.Stats
+}
@@ -0,0 +1,178 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+// StepActionConnect is the action of a step that connects the nodes of the
+// step to each other, such as to set up a topology before retrieving content.
+const StepActionConnect = "connect"
+
+// StepDefinition is a step of a scenario. Steps start as soon as the steps they
+// depend on are done, so steps that don't depend on each other run in
+// parallel.
+type StepDefinition struct {
+	// Name identifies the step to the steps that depend on it.
+	Name string `json:"name"`
+
+	// Nodes is a query selecting the nodes executing the step.
+	Nodes string `json:"nodes"`
+
+	// Action is executed by every node selected, such as "pin image", or
+	// "connect" to connect the nodes selected to each other.
+	Action string `json:"action"`
+
+	// DependsOn are the names of the steps that must be done before the step
+	// starts.
+	DependsOn []string `json:"dependsOn,omitempty"`
+}
+
+// ValidateSteps returns an error if a step is missing a field, is named twice,
+// depends on an undefined step or depends on itself through its dependencies.
+func ValidateSteps(steps []StepDefinition) error {
+	names := make(map[string]struct{})
+	for i, step := range steps {
+		if step.Name == "" {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "scenario definition is missing required field \"steps.%d.name\"", i)
+		}
+		if _, ok := names[step.Name]; ok {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "step %q is defined twice", step.Name)
+		}
+		names[step.Name] = struct{}{}
+
+		for _, field := range []struct {
+			name  string
+			value string
+		}{
+			{"nodes", step.Nodes},
+			{"action", step.Action},
+		} {
+			if field.value == "" {
+				return errors.Wrapf(errdefs.ErrInvalidArgument, "scenario definition is missing required field \"steps.%d.%s\"", i, field.name)
+			}
+		}
+	}
+
+	for _, step := range steps {
+		for _, dep := range step.DependsOn {
+			if _, ok := names[dep]; !ok {
+				return errors.Wrapf(errdefs.ErrInvalidArgument, "step %q depends on undefined step %q", step.Name, dep)
+			}
+		}
+	}
+
+	_, err := SortSteps(steps)
+	return err
+}
+
+// SortSteps returns the steps ordered so that every step follows the steps it
+// depends on, otherwise in the order they are defined. Returns an error naming
+// the steps left if their dependencies form a cycle.
+func SortSteps(steps []StepDefinition) ([]StepDefinition, error) {
+	var (
+		sorted  []StepDefinition
+		pending = steps
+		done    = make(map[string]struct{})
+	)
+	for len(pending) > 0 {
+		var left []StepDefinition
+		for _, step := range pending {
+			ready := true
+			for _, dep := range step.DependsOn {
+				if _, ok := done[dep]; !ok {
+					ready = false
+					break
+				}
+			}
+
+			if ready {
+				sorted = append(sorted, step)
+				done[step.Name] = struct{}{}
+			} else {
+				left = append(left, step)
+			}
+		}
+
+		if len(left) == len(pending) {
+			var names []string
+			for _, step := range left {
+				names = append(names, step.Name)
+			}
+			sort.Strings(names)
+			return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "steps %q depend on each other in a cycle", names)
+		}
+		pending = left
+	}
+	return sorted, nil
+}
+
+// ScenarioStep is a planned step, with the task of each node selected.
+type ScenarioStep struct {
+	Name string
+
+	DependsOn []string `json:",omitempty"`
+
+	// Connect is whether the nodes of the step connect to each other, in which
+	// case their tasks are only connect tasks without a subject.
+	Connect bool `json:",omitempty"`
+
+	Tasks ScenarioStage
+}
+
+// BenchmarkStep is the result of a step of the benchmark.
+type BenchmarkStep struct {
+	Name string
+
+	// Start is when the steps the step depends on were done, and End is when
+	// the last of its nodes finished.
+	Start, End time.Time
+
+	// Nodes is the result of the step on each node.
+	Nodes map[string]BenchmarkNode
+}
+
+func readSteps(bkt *bolt.Bucket) ([]StepDefinition, error) {
+	v := bkt.Get(bucketKeySteps)
+	if v == nil {
+		return nil, nil
+	}
+
+	var steps []StepDefinition
+	err := json.Unmarshal(v, &steps)
+	if err != nil {
+		return nil, err
+	}
+	return steps, nil
+}
+
+func writeSteps(bkt *bolt.Bucket, steps []StepDefinition) error {
+	if len(steps) == 0 {
+		return nil
+	}
+
+	content, err := json.Marshal(steps)
+	if err != nil {
+		return err
+	}
+	return bkt.Put(bucketKeySteps, content)
+}
@@ -0,0 +1,60 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"testing"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSortSteps(t *testing.T) {
+	steps, err := SortSteps([]StepDefinition{
+		{Name: "get", DependsOn: []string{"pin", "connect"}},
+		{Name: "gc", DependsOn: []string{"get"}},
+		{Name: "pin"},
+		{Name: "connect", DependsOn: []string{"pin"}},
+	})
+	require.NoError(t, err)
+
+	var names []string
+	for _, step := range steps {
+		names = append(names, step.Name)
+	}
+	require.Equal(t, []string{"pin", "connect", "get", "gc"}, names)
+}
+
+func TestValidateSteps(t *testing.T) {
+	step := func(name string, deps ...string) StepDefinition {
+		return StepDefinition{Name: name, Nodes: "*", Action: "connect", DependsOn: deps}
+	}
+	require.NoError(t, ValidateSteps([]StepDefinition{step("a"), step("b", "a"), step("c", "a")}))
+
+	for _, tc := range []struct {
+		steps []StepDefinition
+		err   string
+	}{
+		{[]StepDefinition{step("a"), {Name: "b", Nodes: "*"}}, `"steps.1.action"`},
+		{[]StepDefinition{step("a"), step("a")}, `step "a" is defined twice`},
+		{[]StepDefinition{step("a", "b")}, `step "a" depends on undefined step "b"`},
+		{[]StepDefinition{step("a", "a")}, `steps ["a"] depend on each other in a cycle`},
+		{[]StepDefinition{step("a"), step("b", "c"), step("c", "b")}, `steps ["b" "c"] depend on each other in a cycle`},
+	} {
+		err := ValidateSteps(tc.steps)
+		require.True(t, errdefs.IsInvalidArgument(err), "%v", err)
+		require.Contains(t, err.Error(), tc.err)
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"time"
+)
+
+// TaskState is the state of a task submitted asynchronously to a node.
+type TaskState string
+
+var (
+	TaskQueued    TaskState = "queued"
+	TaskRunning   TaskState = "running"
+	TaskSucceeded TaskState = "succeeded"
+	TaskFailed    TaskState = "failed"
+)
+
+// TaskStatus is the status of a task submitted asynchronously to a node,
+// which executes its tasks one at a time in the order they were submitted.
+type TaskStatus struct {
+	ID string
+
+	Task Task
+
+	State TaskState
+
+	// Completed is how many of the Total units of the task are done, as last
+	// reported by the task. Total is zero until the task reports it.
+	Completed int64
+	Total     int64
+
+	Error string `json:",omitempty"`
+
+	CreatedAt  time.Time
+	StartedAt  time.Time `json:",omitempty"`
+	FinishedAt time.Time `json:",omitempty"`
+}
+
+// Finished returns whether the task succeeded or failed.
+func (s TaskStatus) Finished() bool {
+	return s.State == TaskSucceeded || s.State == TaskFailed
+}
@@ -16,6 +16,7 @@ package metadata
 
 import (
 	"regexp"
+	"strings"
 
 	"github.com/Netflix/p2plab/errdefs"
 	"github.com/pkg/errors"
@@ -23,6 +24,8 @@ import (
 
 var (
 	ClusterIDPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,32}`)
+
+	unsafeNameChars = regexp.MustCompile(`[^a-z0-9]+`)
 )
 
 func ValidateClusterID(id string) error {
@@ -32,3 +35,35 @@ func ValidateClusterID(id string) error {
 	}
 	return nil
 }
+
+// SanitizeName derives a valid resource name from s, such as the base name of
+// a definition file, by lowercasing it and replacing every run of characters
+// other than letters and digits with a single "-".
+func SanitizeName(s string) (string, error) {
+	name := strings.Trim(unsafeNameChars.ReplaceAllString(strings.ToLower(s), "-"), "-")
+	if name == "" {
+		return "", errors.Wrapf(errdefs.ErrInvalidArgument, "cannot derive a name from %q, set one explicitly", s)
+	}
+	return name, nil
+}
+
+// ParseKeyValueLabel splits a label of the form "key=value", so that queries
+// such as 'env=staging' or "env =~ 'stag.*'" select resources by key.
+func ParseKeyValueLabel(label string) (key, value string, err error) {
+	parts := strings.SplitN(label, "=", 2)
+	if len(parts) != 2 || parts[0] == "" || strings.ContainsAny(label, ", ") {
+		return "", "", errors.Wrapf(errdefs.ErrInvalidArgument, "label %q must be key=value", label)
+	}
+	return parts[0], parts[1], nil
+}
+
+// ValidateUserLabels returns an error if a label uses the NodeLabelPrefix,
+// which is reserved for labels derived from provider metadata.
+func ValidateUserLabels(labels []string) error {
+	for _, label := range labels {
+		if strings.HasPrefix(label, NodeLabelPrefix) {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "label %q uses reserved prefix %q", label, NodeLabelPrefix)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"testing"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseKeyValueLabel(t *testing.T) {
+	key, value, err := ParseKeyValueLabel("env=staging")
+	require.NoError(t, err)
+	require.Equal(t, "env", key)
+	require.Equal(t, "staging", value)
+
+	key, value, err = ParseKeyValueLabel("owner=")
+	require.NoError(t, err)
+	require.Equal(t, "owner", key)
+	require.Equal(t, "", value)
+
+	for _, label := range []string{"staging", "=staging", "env=a,b", "env=a b"} {
+		_, _, err = ParseKeyValueLabel(label)
+		require.True(t, errdefs.IsInvalidArgument(err), label)
+	}
+}
+
+func TestValidateUserLabels(t *testing.T) {
+	require.NoError(t, ValidateUserLabels([]string{"env=staging", "nodes=2"}))
+
+	err := ValidateUserLabels([]string{"env=staging", "node.zone=us-west-2a"})
+	require.True(t, errdefs.IsInvalidArgument(err))
+}
+
+func TestNodeLabels(t *testing.T) {
+	require.Equal(t, []string{
+		"node.region=us-west-2",
+		"node.zone=us-west-2a",
+		"node.instance-type=t2.micro",
+		"env=staging",
+	}, NodeLabels("us-west-2", "us-west-2a", "t2.micro", "env=staging"))
+
+	require.Equal(t, []string{"node.region=us-west-2"}, NodeLabels("us-west-2", "", ""))
+}
+
+func TestSanitizeName(t *testing.T) {
+	for _, test := range []struct {
+		in, name string
+	}{
+		{"my scenario!", "my-scenario"},
+		{"Neighbors", "neighbors"},
+		{"100-nodes", "100-nodes"},
+		{"--a__b..c--", "a-b-c"},
+		{"large  file   (v2)", "large-file-v2"},
+		{"café", "caf"},
+	} {
+		name, err := SanitizeName(test.in)
+		require.NoError(t, err, test.in)
+		require.Equal(t, test.name, name, test.in)
+	}
+
+	for _, in := range []string{"", "!!!", "---", "日本"} {
+		_, err := SanitizeName(in)
+		require.True(t, errdefs.IsInvalidArgument(err), in)
+	}
+}
@@ -28,6 +28,75 @@ type NodeAPI interface {
 	Label(ctx context.Context, cluster string, ids, adds, removes []string) ([]Node, error)
 
 	List(ctx context.Context, cluster string, opts ...ListOption) ([]Node, error)
+
+	// Connect makes every node matching the source query dial every node
+	// matching the target query, and returns the result of each pair.
+	Connect(ctx context.Context, cluster, source, target string, opts ...ConnectOption) ([]metadata.NodeConnection, error)
+
+	// Ping makes every node matching the query ping every other matching node
+	// over libp2p, and returns the result of each pair.
+	Ping(ctx context.Context, cluster, q string, opts ...PingOption) ([]metadata.NodePing, error)
+
+	// BenchLink makes every node matching the source query send a synthetic
+	// payload of the given size to every node matching the target query, and
+	// returns the throughput of each pair.
+	BenchLink(ctx context.Context, cluster, source, target string, size int64, opts ...BenchLinkOption) ([]metadata.NodeLink, error)
+}
+
+// ConnectOption is an option to modify connect settings.
+type ConnectOption func(*ConnectSettings) error
+
+type ConnectSettings struct {
+	// Disconnect hangs up on the target nodes instead of dialing them.
+	Disconnect bool
+}
+
+func WithDisconnect() ConnectOption {
+	return func(s *ConnectSettings) error {
+		s.Disconnect = true
+		return nil
+	}
+}
+
+// PingOption is an option to modify ping settings.
+type PingOption func(*PingSettings) error
+
+type PingSettings struct {
+	// Count is the number of pings between each pair of nodes.
+	Count int
+
+	// Parallel is the maximum number of pairs pinging at once.
+	Parallel int
+}
+
+func WithPingCount(count int) PingOption {
+	return func(s *PingSettings) error {
+		s.Count = count
+		return nil
+	}
+}
+
+func WithPingParallel(parallel int) PingOption {
+	return func(s *PingSettings) error {
+		s.Parallel = parallel
+		return nil
+	}
+}
+
+// BenchLinkOption is an option to modify link benchmark settings.
+type BenchLinkOption func(*BenchLinkSettings) error
+
+type BenchLinkSettings struct {
+	// Bidirectional also sends the payload from the target nodes to the
+	// source nodes.
+	Bidirectional bool
+}
+
+func WithBidirectional() BenchLinkOption {
+	return func(s *BenchLinkSettings) error {
+		s.Bidirectional = true
+		return nil
+	}
 }
 
 // Node is an instance running the P2P application to be benchmarked.
@@ -45,6 +114,20 @@ type NodeProvider interface {
 	CreateNodeGroup(ctx context.Context, id string, cdef metadata.ClusterDefinition) (*NodeGroup, error)
 
 	DestroyNodeGroup(ctx context.Context, ng *NodeGroup) error
+
+	// AddNodes provisions nodes for a node group so that it matches the cluster
+	// definition, and returns only the newly provisioned nodes.
+	AddNodes(ctx context.Context, ng *NodeGroup, cdef metadata.ClusterDefinition) ([]metadata.Node, error)
+
+	// RemoveNodes destroys the given nodes of a node group, where the cluster
+	// definition is already updated to not include them.
+	RemoveNodes(ctx context.Context, ng *NodeGroup, cdef metadata.ClusterDefinition, ns []metadata.Node) error
+}
+
+// Pinger is implemented by node providers that depend on an external service,
+// to check that the service is reachable.
+type Pinger interface {
+	Ping(ctx context.Context) error
 }
 
 type NodeGroup struct {
@@ -58,3 +141,47 @@ type SSHOption func(SSHSettings) error
 // SSHSetttings specify ssh settings when connecting to a node.
 type SSHSettings struct {
 }
+
+// UpdateOption is an option to modify update settings.
+type UpdateOption func(*UpdateSettings) error
+
+// UpdateSettings specify how a node updates its p2p app.
+type UpdateSettings struct {
+	// SHA256 is the expected hex-encoded digest of the binary at the update
+	// link. The agent refuses to apply the update if it doesn't match.
+	SHA256 string
+}
+
+func WithUpdateSHA256(digest string) UpdateOption {
+	return func(s *UpdateSettings) error {
+		s.SHA256 = digest
+		return nil
+	}
+}
+
+// LogsOption is an option to modify logs settings.
+type LogsOption func(*LogsSettings) error
+
+// LogsSettings specify which output of a node's p2p app is streamed.
+type LogsSettings struct {
+	// Since is the sequence number of the first line to stream. Lines that are
+	// no longer retained by the agent are skipped.
+	Since uint64
+
+	// Follow keeps the stream open for new lines.
+	Follow bool
+}
+
+func WithLogsSince(seq uint64) LogsOption {
+	return func(s *LogsSettings) error {
+		s.Since = seq
+		return nil
+	}
+}
+
+func WithLogsFollow() LogsOption {
+	return func(s *LogsSettings) error {
+		s.Follow = true
+		return nil
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodes
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/Netflix/p2plab/pkg/traceutil"
+	cid "github.com/ipfs/go-cid"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+)
+
+// CollectGarbage garbage collects the blockstore of every node, keeping the
+// DAGs of the roots.
+func CollectGarbage(ctx context.Context, ns []p2plab.Node, roots []cid.Cid) error {
+	span, ctx := traceutil.StartSpanFromContext(ctx, "nodes.CollectGarbage")
+	defer span.Finish()
+	span.SetTag("nodes", len(ns))
+	span.SetTag("roots", len(roots))
+
+	var keep []string
+	for _, c := range roots {
+		keep = append(keep, c.String())
+	}
+
+	task := metadata.Task{
+		Type:    metadata.TaskGC,
+		Subject: strings.Join(keep, ","),
+	}
+
+	collectPeers, gctx := errgroup.WithContext(ctx)
+	for _, n := range ns {
+		n := n
+		collectPeers.Go(func() error {
+			err := n.Run(gctx, task)
+			if err != nil {
+				return errors.Wrapf(err, "failed to collect garbage of %q", n.ID())
+			}
+			return nil
+		})
+	}
+
+	return collectPeers.Wait()
+}
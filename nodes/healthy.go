@@ -16,6 +16,7 @@ package nodes
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/Netflix/p2plab"
@@ -36,6 +37,11 @@ func WaitHealthy(ctx context.Context, ns []p2plab.Node) error {
 
 	zerolog.Ctx(ctx).Info().Msg("Waiting for healthy nodes")
 	go logutil.Elapsed(gctx, 20*time.Second, "Waiting for healthy nodes")
+
+	var (
+		mu      sync.Mutex
+		healthy int
+	)
 	for _, n := range ns {
 		n := n
 		healthchecks.Go(func() error {
@@ -43,6 +49,11 @@ func WaitHealthy(ctx context.Context, ns []p2plab.Node) error {
 			if !ok {
 				return errors.Wrapf(errdefs.ErrUnavailable, "node %q", n.ID())
 			}
+
+			mu.Lock()
+			healthy++
+			logutil.Progress(ctx, healthy, len(ns), "Node is healthy")
+			mu.Unlock()
 			return nil
 		})
 	}
@@ -0,0 +1,73 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodes
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/rs/zerolog"
+)
+
+// SampleResources starts sampling the resource usage of every node at the
+// interval. Sampling stops when the returned function is called, which waits
+// for the samplers to finish and returns the usage of each node. Nodes that
+// fail to sample are skipped with a warning.
+func SampleResources(ctx context.Context, ns []p2plab.Node, interval time.Duration) func() map[string]metadata.ResourceUsage {
+	sctx, cancel := context.WithCancel(ctx)
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		usage = make(map[string]metadata.ResourceUsage)
+	)
+
+	zerolog.Ctx(ctx).Info().Str("interval", interval.String()).Msg("Sampling resource usage")
+	for _, n := range ns {
+		n := n
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			u, err := n.SampleResources(sctx, interval)
+			if err != nil {
+				logger := zerolog.Ctx(ctx).Warn().Str("node", n.ID()).Err(err)
+				if errdefs.IsNotImplemented(err) {
+					logger.Msg("Node does not support resource sampling, skipping")
+				} else {
+					logger.Msg("Failed to sample resource usage")
+				}
+			}
+
+			if len(u.CPU) == 0 {
+				return
+			}
+
+			mu.Lock()
+			usage[n.ID()] = u
+			mu.Unlock()
+		}()
+	}
+
+	return func() map[string]metadata.ResourceUsage {
+		cancel()
+		wg.Wait()
+		return usage
+	}
+}
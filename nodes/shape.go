@@ -0,0 +1,53 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodes
+
+import (
+	"context"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/Netflix/p2plab/pkg/traceutil"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"golang.org/x/sync/errgroup"
+)
+
+// ShapeNetwork applies the network spec to every node. Nodes that don't
+// support network shaping are skipped with a warning.
+func ShapeNetwork(ctx context.Context, ns []p2plab.Node, spec metadata.NetworkSpec) error {
+	span, ctx := traceutil.StartSpanFromContext(ctx, "nodes.ShapeNetwork")
+	defer span.Finish()
+	span.SetTag("nodes", len(ns))
+
+	shapePeers, gctx := errgroup.WithContext(ctx)
+	for _, n := range ns {
+		n := n
+		shapePeers.Go(func() error {
+			err := n.ShapeNetwork(gctx, spec)
+			if errdefs.IsNotImplemented(err) {
+				zerolog.Ctx(ctx).Warn().Str("node", n.ID()).Err(err).Msg("Node does not support network shaping, skipping")
+				return nil
+			}
+			if err != nil {
+				return errors.Wrapf(err, "failed to shape network of %q", n.ID())
+			}
+			return nil
+		})
+	}
+
+	return shapePeers.Wait()
+}
@@ -18,12 +18,15 @@ import (
 	"context"
 	"io"
 
+	"github.com/Netflix/p2plab/errdefs"
 	"github.com/Netflix/p2plab/metadata"
+	humanize "github.com/dustin/go-humanize"
 	cid "github.com/ipfs/go-cid"
 	files "github.com/ipfs/go-ipfs-files"
 	ipld "github.com/ipfs/go-ipld-format"
 	host "github.com/libp2p/go-libp2p-core/host"
 	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/pkg/errors"
 )
 
 type Peer interface {
@@ -55,6 +58,10 @@ type AddSettings struct {
 	NoCopy    bool
 	HashFunc  string
 	MaxLinks  int
+	Platform  string
+	Exclude   []string
+	Size      int64
+	Seed      int64
 }
 
 func WithLayout(layout string) AddOption {
@@ -91,3 +98,41 @@ func WithMaxLinks(maxLinks int) AddOption {
 		return nil
 	}
 }
+
+// WithPlatform pins the platform (e.g. "linux/arm64") used to select a
+// manifest when the source is a multi-arch image index.
+func WithPlatform(platform string) AddOption {
+	return func(s *AddSettings) error {
+		s.Platform = platform
+		return nil
+	}
+}
+
+// WithExclude skips files matching any of the gitignore-style patterns when
+// transforming a directory tree.
+func WithExclude(patterns ...string) AddOption {
+	return func(s *AddSettings) error {
+		s.Exclude = append(s.Exclude, patterns...)
+		return nil
+	}
+}
+
+// WithSize sets the size of generated content, such as "512MB" or "2GiB".
+func WithSize(size string) AddOption {
+	return func(s *AddSettings) error {
+		n, err := humanize.ParseBytes(size)
+		if err != nil {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid size %q: %s", size, err)
+		}
+		s.Size = int64(n)
+		return nil
+	}
+}
+
+// WithSeed sets the seed used to generate deterministic content.
+func WithSeed(seed int64) AddOption {
+	return func(s *AddSettings) error {
+		s.Seed = seed
+		return nil
+	}
+}
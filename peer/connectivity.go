@@ -0,0 +1,132 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package peer
+
+import (
+	"context"
+	"time"
+
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/libp2p/go-libp2p-core/network"
+	libp2ppeer "github.com/libp2p/go-libp2p-core/peer"
+	kaddht "github.com/libp2p/go-libp2p-kad-dht"
+	multiaddr "github.com/multiformats/go-multiaddr"
+	"github.com/pkg/errors"
+)
+
+// Connectivity bootstraps the peer from the bootstrap peers and records how
+// long it takes to connect to the target number of peers, along with a
+// timeline of its peer count and DHT routing table size. The result is
+// reported until the next connectivity task.
+func (p *Peer) Connectivity(ctx context.Context, task metadata.ConnectivityTask) (metadata.Connectivity, error) {
+	var result metadata.Connectivity
+	start := time.Now()
+	defer func() {
+		p.mu.Lock()
+		p.connectivity = &result
+		p.mu.Unlock()
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, task.Timeout)
+	defer cancel()
+
+	connected := make(chan struct{}, 1)
+	notifee := &network.NotifyBundle{
+		ConnectedF: func(network.Network, network.Conn) {
+			select {
+			case connected <- struct{}{}:
+			default:
+			}
+		},
+	}
+	p.host.Network().Notify(notifee)
+	defer p.host.Network().StopNotify(notifee)
+
+	var mas []multiaddr.Multiaddr
+	for _, addr := range task.Bootstrap {
+		ma, err := multiaddr.NewMultiaddr(addr)
+		if err != nil {
+			return result, err
+		}
+		mas = append(mas, ma)
+	}
+
+	infos, err := libp2ppeer.AddrInfosFromP2pAddrs(mas...)
+	if err != nil {
+		return result, err
+	}
+
+	err = p.Connect(ctx, infos)
+	if err != nil {
+		return result, errors.Wrap(err, "failed to connect to bootstrap peers")
+	}
+
+	dht, _ := p.r.(*kaddht.IpfsDHT)
+	if dht != nil && len(infos) > 0 {
+		go bootstrapDHT(ctx, dht)
+	}
+
+	sample := func() bool {
+		elapsed := time.Since(start)
+		peers := len(p.host.Network().Peers())
+		if peers > 0 && result.FirstPeer == 0 {
+			result.FirstPeer = elapsed
+		}
+		if peers >= task.Peers && result.TargetPeers == 0 {
+			result.TargetPeers = elapsed
+		}
+
+		s := metadata.ConnectivitySample{Elapsed: elapsed, Peers: peers}
+		if dht != nil {
+			s.RoutingTable = dht.RoutingTable().Size()
+		}
+		result.Timeline = append(result.Timeline, s)
+		return result.TargetPeers > 0
+	}
+
+	ticker := time.NewTicker(task.Interval)
+	defer ticker.Stop()
+
+	for !sample() {
+		select {
+		case <-ctx.Done():
+			return result, errors.Wrapf(ctx.Err(), "connected to %d of %d peers", len(p.host.Network().Peers()), task.Peers)
+		case <-connected:
+		case <-ticker.C:
+		}
+	}
+
+	return result, nil
+}
+
+// bootstrapDHT runs bootstrap rounds back to back until the context is done,
+// so that the peer keeps discovering peers through the DHT.
+func bootstrapDHT(ctx context.Context, dht *kaddht.IpfsDHT) {
+	for ctx.Err() == nil {
+		err := dht.BootstrapOnce(ctx, kaddht.DefaultBootstrapConfig)
+		if err != nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+		}
+	}
+}
+
+func (p *Peer) lastConnectivity() *metadata.Connectivity {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.connectivity
+}
@@ -0,0 +1,149 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package peer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	cid "github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/routing"
+	multiaddr "github.com/multiformats/go-multiaddr"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// delegatedRouting is a content routing that delegates providing and finding
+// providers to the HTTP API of an IPFS node.
+type delegatedRouting struct {
+	client   *http.Client
+	endpoint string
+}
+
+func newDelegatedRouting(endpoint string) *delegatedRouting {
+	return &delegatedRouting{
+		client:   http.DefaultClient,
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+	}
+}
+
+func (r *delegatedRouting) Provide(ctx context.Context, c cid.Cid, announce bool) error {
+	if !announce {
+		return nil
+	}
+
+	body, err := r.call(ctx, "dht/provide", url.Values{"arg": {c.String()}})
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	_, err = io.Copy(ioutil.Discard, body)
+	return err
+}
+
+func (r *delegatedRouting) FindProvidersAsync(ctx context.Context, c cid.Cid, count int) <-chan peer.AddrInfo {
+	ch := make(chan peer.AddrInfo)
+	go func() {
+		defer close(ch)
+
+		body, err := r.call(ctx, "dht/findprovs", url.Values{
+			"arg":           {c.String()},
+			"num-providers": {fmt.Sprintf("%d", count)},
+		})
+		if err != nil {
+			log.Warn().Err(err).Str("cid", c.String()).Msg("Failed to find providers")
+			return
+		}
+		defer body.Close()
+
+		found := 0
+		dec := json.NewDecoder(body)
+		for {
+			var evt struct {
+				Type      routing.QueryEventType
+				Responses []struct {
+					ID    string
+					Addrs []string
+				}
+			}
+			err := dec.Decode(&evt)
+			if err != nil {
+				if err != io.EOF {
+					log.Warn().Err(err).Str("cid", c.String()).Msg("Failed to decode providers")
+				}
+				return
+			}
+
+			if evt.Type != routing.Provider {
+				continue
+			}
+
+			for _, resp := range evt.Responses {
+				id, err := peer.IDB58Decode(resp.ID)
+				if err != nil {
+					continue
+				}
+
+				pi := peer.AddrInfo{ID: id}
+				for _, addr := range resp.Addrs {
+					ma, err := multiaddr.NewMultiaddr(addr)
+					if err != nil {
+						continue
+					}
+					pi.Addrs = append(pi.Addrs, ma)
+				}
+
+				select {
+				case ch <- pi:
+				case <-ctx.Done():
+					return
+				}
+
+				found++
+				if count > 0 && found >= count {
+					return
+				}
+			}
+		}
+	}()
+	return ch
+}
+
+func (r *delegatedRouting) call(ctx context.Context, command string, params url.Values) (io.ReadCloser, error) {
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/api/v0/%s?%s", r.endpoint, command, params.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, errors.Errorf("delegated routing %q returned %s", command, resp.Status)
+	}
+
+	return resp.Body, nil
+}
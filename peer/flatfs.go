@@ -0,0 +1,253 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package peer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Netflix/p2plab/errdefs"
+	datastore "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	"github.com/pkg/errors"
+)
+
+const (
+	// flatfsShardFunc is the sharding of go-ds-flatfs's default layout, where
+	// blocks are stored in directories named after the next-to-last two
+	// characters of their keys.
+	flatfsShardFunc = "/repo/flatfs/shard/v1/next-to-last/2"
+
+	flatfsShardFile = "SHARDING"
+	flatfsExtension = ".data"
+)
+
+// flatfs is a datastore with the on-disk layout of go-ds-flatfs: a file per
+// key, sharded by the next-to-last two characters of the key, written to a
+// temporary file and synced before it is renamed into place. Keys must be a
+// single path segment, such as the keys of blocks.
+type flatfs struct {
+	path string
+}
+
+// openFlatfs opens the flatfs datastore at path, creating it if it doesn't
+// exist.
+func openFlatfs(path string) (*flatfs, error) {
+	err := os.MkdirAll(path, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	shardFile := filepath.Join(path, flatfsShardFile)
+	content, err := ioutil.ReadFile(shardFile)
+	switch {
+	case os.IsNotExist(err):
+		err = ioutil.WriteFile(shardFile, []byte(flatfsShardFunc+"\n"), 0644)
+		if err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	case strings.TrimSpace(string(content)) != flatfsShardFunc:
+		return nil, errors.Wrapf(errdefs.ErrFailedPrecondition, "flatfs at %q is sharded by %q, expected %q", path, strings.TrimSpace(string(content)), flatfsShardFunc)
+	}
+
+	return &flatfs{path: path}, nil
+}
+
+// shard returns the next-to-last two characters of name, padded with
+// underscores if it is too short.
+func shard(name string) string {
+	name = "__" + name
+	return name[len(name)-3 : len(name)-1]
+}
+
+func (fs *flatfs) filename(key datastore.Key) (string, error) {
+	name := strings.TrimPrefix(key.String(), "/")
+	if name == "" || strings.ContainsAny(name, "/.") {
+		return "", errors.Wrapf(errdefs.ErrInvalidArgument, "flatfs key %q must be a single path segment", key)
+	}
+	return filepath.Join(fs.path, shard(name), name+flatfsExtension), nil
+}
+
+func (fs *flatfs) Put(key datastore.Key, value []byte) error {
+	filename, err := fs.filename(key)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(filename)
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(dir, "put-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(value)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmp.Name(), filename)
+	if err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+func (fs *flatfs) Get(key datastore.Key) ([]byte, error) {
+	filename, err := fs.filename(key)
+	if err != nil {
+		return nil, datastore.ErrNotFound
+	}
+
+	value, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil, datastore.ErrNotFound
+	}
+	return value, err
+}
+
+func (fs *flatfs) Has(key datastore.Key) (bool, error) {
+	_, err := fs.GetSize(key)
+	if err == datastore.ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (fs *flatfs) GetSize(key datastore.Key) (int, error) {
+	filename, err := fs.filename(key)
+	if err != nil {
+		return -1, datastore.ErrNotFound
+	}
+
+	fi, err := os.Stat(filename)
+	if os.IsNotExist(err) {
+		return -1, datastore.ErrNotFound
+	}
+	if err != nil {
+		return -1, err
+	}
+	return int(fi.Size()), nil
+}
+
+func (fs *flatfs) Delete(key datastore.Key) error {
+	filename, err := fs.filename(key)
+	if err != nil {
+		return nil
+	}
+
+	err = os.Remove(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (fs *flatfs) Query(q query.Query) (query.Results, error) {
+	var entries []query.Entry
+	err := fs.walk(func(name, filename string, size int64) error {
+		entry := query.Entry{Key: "/" + name}
+		if !q.KeysOnly {
+			value, err := ioutil.ReadFile(filename)
+			if err != nil {
+				return err
+			}
+			entry.Value = value
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r := query.ResultsWithEntries(q, entries)
+	return query.NaiveQueryApply(q, r), nil
+}
+
+func (fs *flatfs) Batch() (datastore.Batch, error) {
+	return datastore.NewBasicBatch(fs), nil
+}
+
+func (fs *flatfs) DiskUsage() (uint64, error) {
+	var usage uint64
+	err := fs.walk(func(name, filename string, size int64) error {
+		usage += uint64(size)
+		return nil
+	})
+	return usage, err
+}
+
+func (fs *flatfs) Close() error {
+	return nil
+}
+
+// walk calls fn with the name, filename and size of every key in the
+// datastore.
+func (fs *flatfs) walk(fn func(name, filename string, size int64) error) error {
+	shards, err := ioutil.ReadDir(fs.path)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range shards {
+		if !s.IsDir() {
+			continue
+		}
+
+		dir := filepath.Join(fs.path, s.Name())
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+
+		for _, f := range files {
+			if f.IsDir() || !strings.HasSuffix(f.Name(), flatfsExtension) {
+				continue
+			}
+
+			err = fn(strings.TrimSuffix(f.Name(), flatfsExtension), filepath.Join(dir, f.Name()), f.Size())
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// syncDir syncs a directory so that the files renamed into it are durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,125 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package peer
+
+import (
+	"context"
+
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/Netflix/p2plab/pkg/traceutil"
+	cid "github.com/ipfs/go-cid"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	ipld "github.com/ipfs/go-ipld-format"
+	"github.com/pkg/errors"
+)
+
+// GC removes the blocks of the blockstore that are not reachable from the
+// roots or the pinned content, recording what was collected for the report.
+// The DAGs of the roots are only walked through the blocks held locally.
+func (p *Peer) GC(ctx context.Context, roots []cid.Cid) (metadata.GarbageCollection, error) {
+	span, ctx := traceutil.StartSpanFromContext(ctx, "peer.GC")
+	defer span.Finish()
+
+	var gc metadata.GarbageCollection
+	keep, err := p.walkLocal(append(roots, p.pinned()...))
+	if err != nil {
+		return gc, err
+	}
+
+	keys, err := p.bs.AllKeysChan(ctx)
+	if err != nil {
+		return gc, errors.Wrap(err, "failed to list blocks")
+	}
+
+	for c := range keys {
+		size, err := p.bs.GetSize(c)
+		if err != nil {
+			return gc, errors.Wrapf(err, "failed to get size of block %q", c)
+		}
+		gc.SizeBefore += int64(size)
+
+		if keep.blocks.Has(c) {
+			continue
+		}
+
+		err = p.bs.DeleteBlock(c)
+		if err != nil {
+			return gc, errors.Wrapf(err, "failed to delete block %q", c)
+		}
+		gc.BlocksRemoved++
+		gc.BytesFreed += int64(size)
+	}
+	if ctx.Err() != nil {
+		return gc, ctx.Err()
+	}
+	gc.SizeAfter = gc.SizeBefore - gc.BytesFreed
+
+	span.SetTag("blocksRemoved", gc.BlocksRemoved)
+	span.SetTag("bytesFreed", gc.BytesFreed)
+
+	p.mu.Lock()
+	p.gc = &gc
+	p.mu.Unlock()
+	return gc, nil
+}
+
+func (p *Peer) lastGC() *metadata.GarbageCollection {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.gc
+}
+
+// localDAG is the part of DAGs held locally.
+type localDAG struct {
+	blocks *cid.Set
+	size   int64
+
+	// missing is how many blocks linked from the DAGs are not held locally.
+	missing int
+}
+
+// walkLocal walks the DAGs of the roots through the blocks held locally.
+func (p *Peer) walkLocal(roots []cid.Cid) (localDAG, error) {
+	dag := localDAG{blocks: cid.NewSet()}
+	visited := cid.NewSet()
+	queue := append([]cid.Cid{}, roots...)
+	for len(queue) > 0 {
+		c := queue[0]
+		queue = queue[1:]
+		if !visited.Visit(c) {
+			continue
+		}
+
+		blk, err := p.bs.Get(c)
+		if err == blockstore.ErrNotFound {
+			dag.missing++
+			continue
+		}
+		if err != nil {
+			return dag, errors.Wrapf(err, "failed to get block %q", c)
+		}
+		dag.blocks.Add(c)
+		dag.size += int64(len(blk.RawData()))
+
+		nd, err := ipld.Decode(blk)
+		if err != nil {
+			return dag, errors.Wrapf(err, "failed to decode block %q", c)
+		}
+		for _, link := range nd.Links() {
+			queue = append(queue, link.Cid)
+		}
+	}
+	return dag, nil
+}
@@ -68,7 +68,7 @@ func NewLibp2pPeer(ctx context.Context, port int, pdef metadata.PeerDefinition,
 		securityOptions = append(securityOptions, option)
 	}
 
-	routingOption, r, err := NewRoutingOption(ctx, pdef.Routing)
+	routingOption, getRouting, err := NewRoutingOption(ctx, pdef.Routing, pdef.RoutingEndpoint)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "failed to create routing option")
 	}
@@ -86,7 +86,14 @@ func NewLibp2pPeer(ctx context.Context, port int, pdef metadata.PeerDefinition,
 		return nil, nil, errors.Wrap(err, "failed to create libp2p host")
 	}
 
-	return host, r, nil
+	if pdef.Routing == "mdns" {
+		err = startMDNS(ctx, host)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to start mdns")
+		}
+	}
+
+	return host, getRouting(), nil
 }
 
 func NewTransportOption(transportType string, port int) (libp2p.Option, string, error) {
@@ -124,14 +131,19 @@ func NewSecurityOption(securityType string) (libp2p.Option, error) {
 	}
 }
 
-func NewRoutingOption(ctx context.Context, routingType string) (libp2p.Option, routing.ContentRouting, error) {
+// NewRoutingOption returns the libp2p option for the routing type, and a
+// function returning the content routing once the host is constructed. The
+// endpoint is only used by the "delegated" routing type.
+func NewRoutingOption(ctx context.Context, routingType, endpoint string) (libp2p.Option, func() routing.ContentRouting, error) {
 	switch routingType {
-	case "nil":
-		routing, err := nilrouting.ConstructNilRouting(nil, nil, nil, nil)
+	case "nil", "mdns":
+		// mDNS only discovers peers on the local network, content is found
+		// through the wantlists of connected peers.
+		r, err := nilrouting.ConstructNilRouting(nil, nil, nil, nil)
 		if err != nil {
 			return nil, nil, err
 		}
-		return libp2p.Routing(nil), routing, nil
+		return libp2p.Routing(nil), func() routing.ContentRouting { return r }, nil
 	case "kaddht":
 		var dht *kaddht.IpfsDHT
 		newDHT := func(h host.Host) (routing.PeerRouting, error) {
@@ -139,7 +151,13 @@ func NewRoutingOption(ctx context.Context, routingType string) (libp2p.Option, r
 			dht, err = kaddht.New(ctx, h)
 			return dht, err
 		}
-		return libp2p.Routing(newDHT), dht, nil
+		return libp2p.Routing(newDHT), func() routing.ContentRouting { return dht }, nil
+	case "delegated":
+		if endpoint == "" {
+			return nil, nil, errors.Wrap(errdefs.ErrInvalidArgument, "routing \"delegated\" requires an endpoint")
+		}
+		r := newDelegatedRouting(endpoint)
+		return libp2p.Routing(nil), func() routing.ContentRouting { return r }, nil
 	default:
 		return nil, nil, errors.Wrapf(errdefs.ErrInvalidArgument, "routing %q", routingType)
 	}
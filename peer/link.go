@@ -0,0 +1,102 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package peer
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/helpers"
+	"github.com/libp2p/go-libp2p-core/network"
+	libp2ppeer "github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/protocol"
+	"github.com/pkg/errors"
+)
+
+// LinkBenchProtocol is the libp2p protocol that receives a synthetic payload
+// to measure the throughput of the link between two peers.
+const LinkBenchProtocol protocol.ID = "/p2plab/bench-link/1.0.0"
+
+// handleLinkBench discards the payload of a link benchmark and acknowledges it
+// with the number of bytes received once the sender closes the stream.
+func handleLinkBench(s network.Stream) {
+	n, err := io.Copy(ioutil.Discard, s)
+	if err != nil {
+		s.Reset()
+		return
+	}
+
+	err = binary.Write(s, binary.BigEndian, n)
+	if err != nil {
+		s.Reset()
+		return
+	}
+	helpers.FullClose(s)
+}
+
+// BenchLink sends a synthetic payload of the given size to a peer, and returns
+// how long it took until the peer acknowledged receiving all of it.
+func (p *Peer) BenchLink(ctx context.Context, info libp2ppeer.AddrInfo, size int64) (time.Duration, error) {
+	err := p.Connect(ctx, []libp2ppeer.AddrInfo{info})
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to connect to peer")
+	}
+
+	s, err := p.host.NewStream(ctx, info.ID, LinkBenchProtocol)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to open stream")
+	}
+	defer s.Reset()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		s.SetDeadline(deadline)
+	}
+
+	start := time.Now()
+	_, err = io.CopyN(s, zeroReader{}, size)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to send payload")
+	}
+
+	err = s.Close()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to close stream")
+	}
+
+	var received int64
+	err = binary.Read(s, binary.BigEndian, &received)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to read acknowledgement")
+	}
+	elapsed := time.Since(start)
+
+	if received != size {
+		return 0, errors.Errorf("peer received %d of %d bytes", received, size)
+	}
+	return elapsed, nil
+}
+
+// zeroReader is an endless source of zero bytes.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
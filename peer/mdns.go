@@ -0,0 +1,68 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package peer
+
+import (
+	"context"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p/p2p/discovery"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// MDNSInterval is how often peers on the local network are queried when
+	// routing with mDNS.
+	MDNSInterval = 10 * time.Second
+)
+
+// mdnsNotifee connects to the peers discovered over mDNS.
+type mdnsNotifee struct {
+	ctx  context.Context
+	host host.Host
+}
+
+func (n *mdnsNotifee) HandlePeerFound(pi peer.AddrInfo) {
+	if pi.ID == n.host.ID() {
+		return
+	}
+
+	err := n.host.Connect(n.ctx, pi)
+	if err != nil {
+		log.Debug().Err(err).Str("peer", pi.ID.Pretty()).Msg("Failed to connect to mDNS peer")
+	}
+}
+
+// startMDNS discovers and connects to peers on the local network until the
+// context is canceled.
+func startMDNS(ctx context.Context, h host.Host) error {
+	service, err := discovery.NewMdnsService(ctx, h, MDNSInterval, discovery.ServiceTag)
+	if err != nil {
+		return err
+	}
+	service.RegisterNotifee(&mdnsNotifee{ctx, h})
+
+	go func() {
+		<-ctx.Done()
+		err := service.Close()
+		if err != nil {
+			log.Warn().Msgf("failed to close mdns service: %q", err)
+		}
+	}()
+
+	return nil
+}
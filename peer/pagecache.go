@@ -0,0 +1,51 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package peer
+
+import (
+	"context"
+	"time"
+
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/Netflix/p2plab/pkg/traceutil"
+)
+
+// DropCache drops the OS page cache so that the blockstore is read from disk
+// by the retrievals that follow. It is best-effort, when the platform is not
+// supported or the peer lacks privilege, the result has a warning instead.
+func (p *Peer) DropCache(ctx context.Context) metadata.CacheDrop {
+	span, _ := traceutil.StartSpanFromContext(ctx, "peer.DropCache")
+	defer span.Finish()
+
+	drop := metadata.CacheDrop{Time: time.Now()}
+	err := dropPageCache()
+	if err != nil {
+		drop.Warning = err.Error()
+	} else {
+		drop.Dropped = true
+	}
+	span.SetTag("dropped", drop.Dropped)
+
+	p.mu.Lock()
+	p.cacheDrop = &drop
+	p.mu.Unlock()
+	return drop
+}
+
+func (p *Peer) lastCacheDrop() *metadata.CacheDrop {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.cacheDrop
+}
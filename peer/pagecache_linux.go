@@ -0,0 +1,39 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package peer
+
+import (
+	"io/ioutil"
+	"os"
+	"syscall"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/pkg/errors"
+)
+
+// dropPageCache writes dirty pages to disk, then drops the clean page cache,
+// dentries and inodes, which requires root.
+func dropPageCache() error {
+	syscall.Sync()
+
+	err := ioutil.WriteFile("/proc/sys/vm/drop_caches", []byte("3"), 0)
+	if os.IsPermission(err) {
+		return errors.Wrap(errdefs.ErrFailedPrecondition, "dropping the page cache requires root")
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to drop page cache")
+	}
+	return nil
+}
@@ -19,17 +19,21 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Netflix/p2plab"
-	"github.com/Netflix/p2plab/dag"
+	"github.com/Netflix/p2plab/errdefs"
 	"github.com/Netflix/p2plab/metadata"
 	bitswap "github.com/ipfs/go-bitswap"
 	"github.com/ipfs/go-bitswap/network"
 	blockservice "github.com/ipfs/go-blockservice"
 	cid "github.com/ipfs/go-cid"
 	datastore "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/mount"
+	dssync "github.com/ipfs/go-datastore/sync"
 	badger "github.com/ipfs/go-ds-badger"
 	blockstore "github.com/ipfs/go-ipfs-blockstore"
 	chunker "github.com/ipfs/go-ipfs-chunker"
@@ -78,10 +82,17 @@ type Peer struct {
 	ds       datastore.Batching
 	swarm    *swarm.Swarm
 	reporter metrics.Reporter
+
+	mu           sync.Mutex
+	connectivity *metadata.Connectivity
+	retrieval    *metadata.Retrieval
+	gc           *metadata.GarbageCollection
+	pins         map[cid.Cid]metadata.Pin
+	cacheDrop    *metadata.CacheDrop
 }
 
 func New(ctx context.Context, root string, port int, pdef metadata.PeerDefinition) (*Peer, error) {
-	ds, err := NewDatastore(root)
+	ds, err := NewDatastore(root, pdef.Datastore)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create datastore")
 	}
@@ -92,6 +103,8 @@ func New(ctx context.Context, root string, port int, pdef metadata.PeerDefinitio
 		return nil, errors.Wrap(err, "failed to create libp2p peer")
 	}
 
+	h.SetStreamHandler(LinkBenchProtocol, handleLinkBench)
+
 	swarm, ok := h.Network().(*swarm.Swarm)
 	if !ok {
 		return nil, errors.New("expected to be able to cast host network to swarm")
@@ -147,6 +160,7 @@ func New(ctx context.Context, root string, port int, pdef metadata.PeerDefinitio
 		ds:       ds,
 		swarm:    swarm,
 		reporter: reporter,
+		pins:     make(map[cid.Cid]metadata.Pin),
 	}, nil
 }
 
@@ -266,11 +280,6 @@ func (p *Peer) Add(ctx context.Context, r io.Reader, opts ...p2plab.AddOption) (
 	return nd, err
 }
 
-func (p *Peer) FetchGraph(ctx context.Context, c cid.Cid) error {
-	ng := merkledag.NewSession(ctx, p.dserv)
-	return dag.Walk(ctx, c, ng)
-}
-
 func (p *Peer) Get(ctx context.Context, c cid.Cid) (files.Node, error) {
 	nd, err := p.dserv.Get(ctx, c)
 	if err != nil {
@@ -287,7 +296,7 @@ func (p *Peer) Report(ctx context.Context) (metadata.ReportNode, error) {
 	}
 
 	return metadata.ReportNode{
-		metadata.ReportBitswap{
+		Bitswap: metadata.ReportBitswap{
 			BlocksReceived:   stat.BlocksReceived,
 			DataReceived:     stat.DataReceived,
 			BlocksSent:       stat.BlocksSent,
@@ -296,16 +305,46 @@ func (p *Peer) Report(ctx context.Context) (metadata.ReportNode, error) {
 			DupDataReceived:  stat.DupDataReceived,
 			MessagesReceived: stat.MessagesReceived,
 		},
-		metadata.ReportBandwidth{
+		Bandwidth: metadata.ReportBandwidth{
 			Totals:    p.reporter.GetBandwidthTotals(),
 			Peers:     p.reporter.GetBandwidthByPeer(),
 			Protocols: p.reporter.GetBandwidthByProtocol(),
 		},
+		Connectivity: p.lastConnectivity(),
+		Retrieval:    p.lastRetrieval(),
+		GC:           p.lastGC(),
+		Pins:         p.lastPins(),
+		CacheDrop:    p.lastCacheDrop(),
 	}, nil
 }
 
-func NewDatastore(path string) (datastore.Batching, error) {
-	return badger.NewDatastore(path, &badger.DefaultOptions)
+// NewDatastore returns the datastore of the given type rooted at root, which
+// defaults to badger. Like the flatfs profile of go-ipfs, the flatfs datastore
+// only stores blocks, and keeps other keys in badger.
+func NewDatastore(root, datastoreType string) (datastore.Batching, error) {
+	switch datastoreType {
+	case "", "badger":
+		return badger.NewDatastore(root, &badger.DefaultOptions)
+	case "flatfs":
+		blocks, err := openFlatfs(filepath.Join(root, "flatfs"))
+		if err != nil {
+			return nil, err
+		}
+
+		ds, err := badger.NewDatastore(root, &badger.DefaultOptions)
+		if err != nil {
+			return nil, err
+		}
+
+		return mount.New([]mount.Mount{
+			{Prefix: blockstore.BlockPrefix, Datastore: blocks},
+			{Prefix: datastore.NewKey("/"), Datastore: ds},
+		}), nil
+	case "memory":
+		return dssync.MutexWrap(datastore.NewMapDatastore()), nil
+	default:
+		return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "datastore %q", datastoreType)
+	}
 }
 
 func NewBlockstore(ctx context.Context, ds datastore.Batching) (blockstore.Blockstore, error) {
@@ -0,0 +1,141 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package peer
+
+import (
+	"context"
+	"time"
+
+	"github.com/Netflix/p2plab/dag"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/Netflix/p2plab/pkg/traceutil"
+	cid "github.com/ipfs/go-cid"
+	merkledag "github.com/ipfs/go-merkledag"
+	"github.com/pkg/errors"
+)
+
+// Pin fetches the DAG of c unless every block is already held locally,
+// verifies that it is complete, and pins it so that garbage collections keep
+// it. Pins are not persisted, so they are lost when the peer restarts.
+func (p *Peer) Pin(ctx context.Context, c cid.Cid) (metadata.Pin, error) {
+	span, ctx := traceutil.StartSpanFromContext(ctx, "peer.Pin")
+	defer span.Finish()
+	span.SetTag("cid", c.String())
+
+	start := time.Now()
+	local, err := p.walkLocal([]cid.Cid{c})
+	if err != nil {
+		return metadata.Pin{}, err
+	}
+
+	pin := metadata.Pin{
+		CacheHit: local.missing == 0,
+	}
+	span.SetTag("cacheHit", pin.CacheHit)
+
+	if !pin.CacheHit {
+		err = dag.Walk(ctx, c, merkledag.NewSession(ctx, p.dserv))
+		if err != nil {
+			return pin, errors.Wrapf(err, "failed to fetch %q", c)
+		}
+
+		local, err = p.walkLocal([]cid.Cid{c})
+		if err != nil {
+			return pin, err
+		}
+		if local.missing > 0 {
+			return pin, errors.Errorf("%d blocks of %q are missing once fetched", local.missing, c)
+		}
+	}
+
+	pin.Blocks = local.blocks.Len()
+	pin.Bytes = local.size
+	pin.Duration = time.Since(start)
+
+	p.mu.Lock()
+	p.pins[c] = pin
+	p.mu.Unlock()
+	return pin, nil
+}
+
+// Unpin unpins c and evicts the blocks of its DAG that are not part of the
+// DAGs of other pins. Only the blocks removed and bytes freed of the returned
+// collection are set.
+func (p *Peer) Unpin(ctx context.Context, c cid.Cid) (metadata.GarbageCollection, error) {
+	span, ctx := traceutil.StartSpanFromContext(ctx, "peer.Unpin")
+	defer span.Finish()
+	span.SetTag("cid", c.String())
+
+	p.mu.Lock()
+	delete(p.pins, c)
+	p.mu.Unlock()
+
+	var gc metadata.GarbageCollection
+	keep, err := p.walkLocal(p.pinned())
+	if err != nil {
+		return gc, err
+	}
+
+	evict, err := p.walkLocal([]cid.Cid{c})
+	if err != nil {
+		return gc, err
+	}
+
+	for _, b := range evict.blocks.Keys() {
+		if keep.blocks.Has(b) {
+			continue
+		}
+
+		size, err := p.bs.GetSize(b)
+		if err != nil {
+			return gc, errors.Wrapf(err, "failed to get size of block %q", b)
+		}
+
+		err = p.bs.DeleteBlock(b)
+		if err != nil {
+			return gc, errors.Wrapf(err, "failed to delete block %q", b)
+		}
+		gc.BlocksRemoved++
+		gc.BytesFreed += int64(size)
+	}
+	return gc, nil
+}
+
+// pinned returns the CIDs pinned.
+func (p *Peer) pinned() []cid.Cid {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var cids []cid.Cid
+	for c := range p.pins {
+		cids = append(cids, c)
+	}
+	return cids
+}
+
+// lastPins returns the result of pinning each CID pinned, keyed by CID.
+func (p *Peer) lastPins() map[string]metadata.Pin {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.pins) == 0 {
+		return nil
+	}
+
+	pins := make(map[string]metadata.Pin)
+	for c, pin := range p.pins {
+		pins[c.String()] = pin
+	}
+	return pins
+}
@@ -0,0 +1,54 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package peer
+
+import (
+	"context"
+	"time"
+
+	libp2ppeer "github.com/libp2p/go-libp2p-core/peer"
+	ping "github.com/libp2p/go-libp2p/p2p/protocol/ping"
+	"github.com/pkg/errors"
+)
+
+// Ping connects to a peer and returns the round trip times of count pings
+// over the libp2p ping protocol.
+func (p *Peer) Ping(ctx context.Context, info libp2ppeer.AddrInfo, count int) ([]time.Duration, error) {
+	err := p.Connect(ctx, []libp2ppeer.AddrInfo{info})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect to peer")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var rtts []time.Duration
+	results := ping.Ping(ctx, p.host, info.ID)
+	for len(rtts) < count {
+		select {
+		case <-ctx.Done():
+			return rtts, ctx.Err()
+		case result, ok := <-results:
+			if !ok {
+				return rtts, errors.New("ping stream closed")
+			}
+			if result.Error != nil {
+				return rtts, errors.Wrap(result.Error, "failed to ping peer")
+			}
+			rtts = append(rtts, result.RTT)
+		}
+	}
+	return rtts, nil
+}
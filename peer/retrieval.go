@@ -0,0 +1,108 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package peer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Netflix/p2plab/dag"
+	"github.com/Netflix/p2plab/metadata"
+	cid "github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
+	merkledag "github.com/ipfs/go-merkledag"
+)
+
+// FetchGraph retrieves every block of the DAG rooted at c, recording the time
+// to the first block, the latency of each block and the total time of the
+// retrieval for the report.
+func (p *Peer) FetchGraph(ctx context.Context, c cid.Cid) error {
+	start := time.Now()
+	ng := &timingGetter{
+		NodeGetter: merkledag.NewSession(ctx, p.dserv),
+		start:      start,
+	}
+
+	err := dag.Walk(ctx, c, ng)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.retrieval = &metadata.Retrieval{
+		FirstBlock: ng.first,
+		Total:      time.Since(start),
+		Blocks:     &ng.blocks,
+	}
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *Peer) lastRetrieval() *metadata.Retrieval {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.retrieval
+}
+
+// timingGetter records when the first block of a retrieval is received, and
+// how long each block took to be received once requested. dag.Walk gets the
+// root of the DAG before its links, so the first block is always received
+// through Get.
+type timingGetter struct {
+	ipld.NodeGetter
+	start time.Time
+
+	once  sync.Once
+	first time.Duration
+
+	mu     sync.Mutex
+	blocks metadata.Histogram
+}
+
+func (g *timingGetter) Get(ctx context.Context, c cid.Cid) (ipld.Node, error) {
+	requested := time.Now()
+	nd, err := g.NodeGetter.Get(ctx, c)
+	if err == nil {
+		g.once.Do(func() {
+			g.first = time.Since(g.start)
+		})
+		g.record(time.Since(requested))
+	}
+	return nd, err
+}
+
+func (g *timingGetter) GetMany(ctx context.Context, cids []cid.Cid) <-chan *ipld.NodeOption {
+	requested := time.Now()
+	in := g.NodeGetter.GetMany(ctx, cids)
+
+	out := make(chan *ipld.NodeOption, len(cids))
+	go func() {
+		defer close(out)
+		for ndOpt := range in {
+			if ndOpt.Err == nil {
+				g.record(time.Since(requested))
+			}
+			out <- ndOpt
+		}
+	}()
+	return out
+}
+
+func (g *timingGetter) record(d time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.blocks.Record(d)
+}
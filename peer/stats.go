@@ -0,0 +1,50 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package peer
+
+import (
+	"context"
+	"time"
+
+	"github.com/Netflix/p2plab/metadata"
+	datastore "github.com/ipfs/go-datastore"
+	kaddht "github.com/libp2p/go-libp2p-kad-dht"
+	"github.com/pkg/errors"
+)
+
+// Stats returns a snapshot of the repo and network of the peer. Unlike Report,
+// it doesn't walk bitswap or per-peer bandwidth, so it can be polled.
+func (p *Peer) Stats(ctx context.Context) (metadata.PeerStats, error) {
+	size, err := datastore.DiskUsage(p.ds)
+	if err != nil {
+		return metadata.PeerStats{}, errors.Wrap(err, "failed to get repo size")
+	}
+
+	p.mu.Lock()
+	pinned := len(p.pins)
+	p.mu.Unlock()
+
+	stats := metadata.PeerStats{
+		Time:      time.Now(),
+		RepoSize:  size,
+		Pinned:    pinned,
+		Peers:     len(p.host.Network().Peers()),
+		Bandwidth: p.reporter.GetBandwidthTotals(),
+	}
+	if dht, ok := p.r.(*kaddht.IpfsDHT); ok {
+		stats.RoutingTableSize = dht.RoutingTable().Size()
+	}
+	return stats, nil
+}
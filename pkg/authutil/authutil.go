@@ -0,0 +1,88 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package authutil validates the bearer tokens of requests to the daemons.
+package authutil
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/pkg/errors"
+)
+
+// Authenticator validates bearer tokens.
+type Authenticator interface {
+	// Authenticate returns the principal identified by the token, or an error
+	// if the token is not valid.
+	Authenticate(ctx context.Context, token string) (string, error)
+}
+
+type staticAuthenticator struct {
+	tokens [][]byte
+}
+
+// NewStatic returns an authenticator accepting a static list of tokens.
+func NewStatic(tokens ...string) Authenticator {
+	a := &staticAuthenticator{}
+	for _, token := range tokens {
+		a.tokens = append(a.tokens, []byte(token))
+	}
+	return a
+}
+
+func (a *staticAuthenticator) Authenticate(ctx context.Context, token string) (string, error) {
+	// Compare every token in constant time so that timing does not reveal how
+	// much of a token matched.
+	match := 0
+	for _, t := range a.tokens {
+		match |= subtle.ConstantTimeCompare(t, []byte(token))
+	}
+	if match != 1 {
+		return "", errors.Wrap(errdefs.ErrUnauthorized, "unknown bearer token")
+	}
+	return TokenPrincipal(token), nil
+}
+
+// TokenPrincipal returns the principal of a static token, which identifies it
+// without revealing it.
+func TokenPrincipal(token string) string {
+	digest := sha256.Sum256([]byte(token))
+	return "token:" + hex.EncodeToString(digest[:])[:12]
+}
+
+type anyAuthenticator []Authenticator
+
+// Any returns an authenticator accepting tokens accepted by any of auths.
+func Any(auths ...Authenticator) Authenticator {
+	if len(auths) == 1 {
+		return auths[0]
+	}
+	return anyAuthenticator(auths)
+}
+
+func (auths anyAuthenticator) Authenticate(ctx context.Context, token string) (string, error) {
+	err := errors.Wrap(errdefs.ErrUnauthorized, "no authenticator configured")
+	for _, auth := range auths {
+		var principal string
+		principal, err = auth.Authenticate(ctx, token)
+		if err == nil {
+			return principal, nil
+		}
+	}
+	return "", err
+}
@@ -0,0 +1,115 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authutil
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatic(t *testing.T) {
+	ctx := context.Background()
+	auth := NewStatic("a", "b")
+	principal, err := auth.Authenticate(ctx, "b")
+	require.NoError(t, err)
+	require.Equal(t, TokenPrincipal("b"), principal)
+
+	_, err = auth.Authenticate(ctx, "c")
+	require.True(t, errdefs.IsUnauthorized(err))
+}
+
+func signJWT(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	encode := func(v interface{}) string {
+		content, err := json.Marshal(v)
+		require.NoError(t, err)
+		return base64.RawURLEncoding.EncodeToString(content)
+	}
+
+	signed := encode(map[string]string{"alg": "RS256", "typ": "JWT", "kid": kid}) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestJWKS(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	fetches := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "k1",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	auth := NewJWKS(srv.Client(), srv.URL, "p2plab", "https://issuer")
+	exp := time.Now().Add(time.Hour).Unix()
+
+	principal, err := auth.Authenticate(ctx, signJWT(t, key, "k1", map[string]interface{}{"sub": "ci", "exp": exp, "aud": "p2plab", "iss": "https://issuer"}))
+	require.NoError(t, err)
+	require.Equal(t, "jwt:ci", principal)
+
+	_, err = auth.Authenticate(ctx, signJWT(t, key, "k1", map[string]interface{}{"exp": exp, "aud": []string{"other", "p2plab"}, "iss": "https://issuer"}))
+	require.NoError(t, err)
+
+	for _, claims := range []map[string]interface{}{
+		{"aud": "p2plab", "iss": "https://issuer"},
+		{"exp": exp, "aud": "other", "iss": "https://issuer"},
+		{"exp": exp, "iss": "https://issuer"},
+		{"exp": exp, "aud": "p2plab", "iss": "https://other"},
+	} {
+		_, err = auth.Authenticate(ctx, signJWT(t, key, "k1", claims))
+		require.True(t, errdefs.IsUnauthorized(err), "expected unauthorized for %v, got %v", claims, err)
+	}
+
+	_, err = auth.Authenticate(ctx, signJWT(t, key, "k1", map[string]interface{}{"exp": time.Now().Add(-time.Hour).Unix()}))
+	require.True(t, errdefs.IsUnauthorized(err), "expected unauthorized, got %v", err)
+
+	_, err = auth.Authenticate(ctx, signJWT(t, other, "k1", map[string]interface{}{"exp": exp}))
+	require.True(t, errdefs.IsUnauthorized(err), "expected unauthorized, got %v", err)
+
+	// Unknown keys are not refetched more often than the refresh interval.
+	_, err = auth.Authenticate(ctx, signJWT(t, key, "k2", map[string]interface{}{"exp": exp}))
+	require.True(t, errdefs.IsUnauthorized(err), "expected unauthorized, got %v", err)
+	require.Equal(t, 1, fetches)
+
+	_, err = auth.Authenticate(ctx, "not-a-jwt")
+	require.True(t, errdefs.IsUnauthorized(err), "expected unauthorized, got %v", err)
+}
@@ -0,0 +1,360 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authutil
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha256" // registers SHA-256 for RS256 and ES256
+	_ "crypto/sha512" // registers SHA-384 and SHA-512
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/pkg/errors"
+)
+
+var (
+	// JWKSRefreshInterval is the minimum time between fetches of the key set,
+	// which is refetched when a token is signed by an unknown key.
+	JWKSRefreshInterval = time.Minute
+
+	// ClockSkew is the leeway given when checking the expiry and not-before
+	// times of tokens.
+	ClockSkew = 30 * time.Second
+)
+
+type jwksAuthenticator struct {
+	client   *http.Client
+	url      string
+	audience string
+	issuer   string
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+// NewJWKS returns an authenticator accepting JSON Web Tokens signed by a key
+// of the JSON Web Key Set served at url. RS256, RS384, RS512, ES256, ES384 and
+// ES512 signatures are supported.
+//
+// Tokens must expire, and unless empty, their audience must include audience
+// and their issuer must be issuer.
+func NewJWKS(client *http.Client, url, audience, issuer string) Authenticator {
+	return &jwksAuthenticator{
+		client:   client,
+		url:      url,
+		audience: audience,
+		issuer:   issuer,
+	}
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jwtClaims struct {
+	Subject   string      `json:"sub"`
+	Issuer    string      `json:"iss"`
+	Audience  jwtAudience `json:"aud"`
+	ExpiresAt *int64      `json:"exp"`
+	NotBefore *int64      `json:"nbf"`
+}
+
+// jwtAudience is the audience of a JWT, which is either a single string or an
+// array of strings.
+type jwtAudience []string
+
+func (a *jwtAudience) UnmarshalJSON(data []byte) error {
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		*a = jwtAudience{s}
+		return nil
+	}
+
+	var ss []string
+	err := json.Unmarshal(data, &ss)
+	if err != nil {
+		return errors.New("aud must be a string or an array of strings")
+	}
+	*a = ss
+	return nil
+}
+
+func (a jwtAudience) contains(audience string) bool {
+	for _, aud := range a {
+		if aud == audience {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *jwksAuthenticator) Authenticate(ctx context.Context, token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", errors.Wrap(errdefs.ErrUnauthorized, "bearer token is not a JWT")
+	}
+
+	var header jwtHeader
+	err := decodeSegment(parts[0], &header)
+	if err != nil {
+		return "", errors.Wrapf(errdefs.ErrUnauthorized, "invalid JWT header: %s", err)
+	}
+
+	var claims jwtClaims
+	err = decodeSegment(parts[1], &claims)
+	if err != nil {
+		return "", errors.Wrapf(errdefs.ErrUnauthorized, "invalid JWT claims: %s", err)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", errors.Wrapf(errdefs.ErrUnauthorized, "invalid JWT signature: %s", err)
+	}
+
+	key, err := a.key(ctx, header.Kid)
+	if err != nil {
+		return "", err
+	}
+
+	err = verifySignature(header.Alg, key, []byte(parts[0]+"."+parts[1]), sig)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	if claims.ExpiresAt == nil {
+		return "", errors.Wrap(errdefs.ErrUnauthorized, "JWT has no expiry")
+	}
+	if now.After(time.Unix(*claims.ExpiresAt, 0).Add(ClockSkew)) {
+		return "", errors.Wrap(errdefs.ErrUnauthorized, "JWT is expired")
+	}
+	if claims.NotBefore != nil && now.Before(time.Unix(*claims.NotBefore, 0).Add(-ClockSkew)) {
+		return "", errors.Wrap(errdefs.ErrUnauthorized, "JWT is not valid yet")
+	}
+	if a.issuer != "" && claims.Issuer != a.issuer {
+		return "", errors.Wrapf(errdefs.ErrUnauthorized, "JWT issuer %q is not trusted", claims.Issuer)
+	}
+	if a.audience != "" && !claims.Audience.contains(a.audience) {
+		return "", errors.Wrapf(errdefs.ErrUnauthorized, "JWT is not intended for audience %q", a.audience)
+	}
+
+	if claims.Subject == "" {
+		return "jwt", nil
+	}
+	return "jwt:" + claims.Subject, nil
+}
+
+// key returns the key with the given ID, refetching the key set if the key is
+// unknown and it was not fetched recently. An empty ID matches the only key of
+// a set.
+func (a *jwksAuthenticator) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key, ok := a.lookup(kid)
+	if ok {
+		return key, nil
+	}
+
+	if time.Since(a.fetchedAt) < JWKSRefreshInterval {
+		return nil, errors.Wrapf(errdefs.ErrUnauthorized, "unknown signing key %q", kid)
+	}
+
+	keys, err := a.fetch(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(errdefs.ErrUnavailable, "failed to fetch JWKS: %s", err)
+	}
+	a.keys, a.fetchedAt = keys, time.Now()
+
+	key, ok = a.lookup(kid)
+	if !ok {
+		return nil, errors.Wrapf(errdefs.ErrUnauthorized, "unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+func (a *jwksAuthenticator) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(a.keys) == 1 {
+		for _, key := range a.keys {
+			return key, true
+		}
+	}
+
+	key, ok := a.keys[kid]
+	return key, ok
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (a *jwksAuthenticator) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	req, err := http.NewRequest("GET", a.url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unexpected status %q", resp.Status)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&set)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		key, err := k.publicKey()
+		if err != nil {
+			// Skip keys of unsupported types, so that they don't prevent
+			// validating tokens signed by other keys.
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, errors.Errorf("unsupported curve %q", k.Crv)
+		}
+
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, errors.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func verifySignature(alg string, key crypto.PublicKey, signed, sig []byte) error {
+	var hash crypto.Hash
+	switch alg {
+	case "RS256", "ES256":
+		hash = crypto.SHA256
+	case "RS384", "ES384":
+		hash = crypto.SHA384
+	case "RS512", "ES512":
+		hash = crypto.SHA512
+	default:
+		return errors.Wrapf(errdefs.ErrUnauthorized, "unsupported JWT algorithm %q", alg)
+	}
+
+	h := hash.New()
+	h.Write(signed)
+	digest := h.Sum(nil)
+
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(alg, "RS") {
+			break
+		}
+		err := rsa.VerifyPKCS1v15(pub, hash, digest, sig)
+		if err != nil {
+			return errors.Wrap(errdefs.ErrUnauthorized, "invalid JWT signature")
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		if !strings.HasPrefix(alg, "ES") {
+			break
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return errors.Wrap(errdefs.ErrUnauthorized, "invalid JWT signature")
+		}
+		r, s := new(big.Int).SetBytes(sig[:size]), new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return errors.Wrap(errdefs.ErrUnauthorized, "invalid JWT signature")
+		}
+		return nil
+	}
+	return errors.Wrapf(errdefs.ErrUnauthorized, "JWT algorithm %q does not match the signing key", alg)
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	content, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(content, v)
+}
+
+func decodeInt(s string) (*big.Int, error) {
+	content, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(content), nil
+}
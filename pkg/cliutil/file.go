@@ -0,0 +1,32 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cliutil
+
+import (
+	"io/ioutil"
+	"os"
+)
+
+// Stdin is the filename that reads a file from the standard input instead,
+// such as a definition generated by another command.
+const Stdin = "-"
+
+// ReadFile reads the whole file, or the standard input if filename is Stdin.
+func ReadFile(filename string) ([]byte, error) {
+	if filename == Stdin {
+		return ioutil.ReadAll(os.Stdin)
+	}
+	return ioutil.ReadFile(filename)
+}
@@ -17,7 +17,6 @@ package cliutil
 import (
 	"context"
 	"fmt"
-	"io"
 	"os"
 	"os/signal"
 	"sync"
@@ -29,13 +28,17 @@ import (
 type InterruptHandler struct {
 	sig chan os.Signal
 	wg  sync.WaitGroup
+
+	mu     sync.Mutex
+	onExit []func()
 }
 
 type handlerFunc func(ih *InterruptHandler, sig os.Signal)
 
 // NewInterruptHandler returns a new interrupt handler that will invoke cancel
-// if any of the signals provided are received.
-func NewInterruptHandler(cancel context.CancelFunc, sigs ...os.Signal) io.Closer {
+// if any of the signals provided are received. Another signal terminates the
+// process.
+func NewInterruptHandler(cancel context.CancelFunc, sigs ...os.Signal) *InterruptHandler {
 	intrh := &InterruptHandler{
 		sig: make(chan os.Signal, 1),
 	}
@@ -45,10 +48,11 @@ func NewInterruptHandler(cancel context.CancelFunc, sigs ...os.Signal) io.Closer
 		count++
 		switch count {
 		case 1:
-			// Prevent un-terminated ^C character in terminal.
-			fmt.Println()
+			// Prevent un-terminated ^C character in terminal. Messages go to stderr
+			// so that they don't corrupt printed output.
+			fmt.Fprintln(os.Stderr)
 
-			fmt.Println("Gracefully cancelling request...")
+			fmt.Fprintln(os.Stderr, "Gracefully cancelling request...")
 
 			ih.wg.Add(1)
 			go func() {
@@ -57,7 +61,8 @@ func NewInterruptHandler(cancel context.CancelFunc, sigs ...os.Signal) io.Closer
 			}()
 
 		default:
-			fmt.Println("Received another interrupt before graceful shutdown, terminating...")
+			fmt.Fprintln(os.Stderr, "Received another interrupt before graceful shutdown, terminating...")
+			ih.exit()
 
 			syscallSig, ok := sig.(syscall.Signal)
 			if !ok {
@@ -73,6 +78,22 @@ func NewInterruptHandler(cancel context.CancelFunc, sigs ...os.Signal) io.Closer
 	return intrh
 }
 
+// OnExit registers fn to be called before the process is terminated by
+// another signal, such as to flush traces.
+func (ih *InterruptHandler) OnExit(fn func()) {
+	ih.mu.Lock()
+	defer ih.mu.Unlock()
+	ih.onExit = append(ih.onExit, fn)
+}
+
+func (ih *InterruptHandler) exit() {
+	ih.mu.Lock()
+	defer ih.mu.Unlock()
+	for _, fn := range ih.onExit {
+		fn()
+	}
+}
+
 // Close closes its signal receiver and waits for its handlers to exit cleanly.
 func (ih *InterruptHandler) Close() error {
 	close(ih.sig)
@@ -0,0 +1,135 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httputil
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// WithCache caches responses that carry an ETag in dir. Cached responses are
+// revalidated with If-None-Match, and served from the cache when the server
+// responds with 304 Not Modified. Entries older than ttl are discarded.
+func WithCache(dir string, ttl time.Duration) ClientOption {
+	return func(c *Client) error {
+		err := os.MkdirAll(dir, 0700)
+		if err != nil {
+			return err
+		}
+
+		c.cache = &cache{dir: dir, ttl: ttl}
+		return nil
+	}
+}
+
+// cache is an on-disk cache of GET responses, keyed by their URL.
+type cache struct {
+	dir string
+	ttl time.Duration
+}
+
+type cacheEntry struct {
+	ETag   string
+	Header http.Header
+	Body   []byte
+}
+
+func (c *cache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:]))
+}
+
+// get returns the entry cached for key, if any and not expired.
+func (c *cache) get(key string) (*cacheEntry, bool) {
+	path := c.path(key)
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, false
+	}
+
+	if c.ttl > 0 && time.Since(fi.ModTime()) > c.ttl {
+		os.Remove(path)
+		return nil, false
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	var entry cacheEntry
+	err = json.Unmarshal(content, &entry)
+	if err != nil {
+		os.Remove(path)
+		return nil, false
+	}
+
+	return &entry, true
+}
+
+// put caches the body of a response for key under its ETag.
+func (c *cache) put(key string, header http.Header, body []byte) error {
+	content, err := json.Marshal(&cacheEntry{
+		ETag:   header.Get("ETag"),
+		Header: header,
+		Body:   body,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so that concurrent readers never see a
+	// partial entry.
+	path := c.path(key)
+	tmp, err := ioutil.TempFile(c.dir, filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(content)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// response returns a response served from the entry, in place of the 304 Not
+// Modified resp.
+func (e *cacheEntry) response(resp *http.Response) *http.Response {
+	resp.Body.Close()
+
+	cached := *resp
+	cached.Status = http.StatusText(http.StatusOK)
+	cached.StatusCode = http.StatusOK
+	cached.Header = e.Header
+	cached.Body = ioutil.NopCloser(bytes.NewReader(e.Body))
+	cached.ContentLength = int64(len(e.Body))
+	return &cached
+}
@@ -0,0 +1,78 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httputil
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	var (
+		body        = "v1"
+		notModified int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := `"` + body + `"`
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "httputil-cache")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	client, err := NewClient(NewHTTPClient(), WithCache(dir, time.Hour))
+	require.NoError(t, err)
+
+	get := func() string {
+		resp, err := client.NewRequest("GET", srv.URL).Send(context.Background())
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		content, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(content)
+	}
+
+	require.Equal(t, "v1", get())
+	require.Equal(t, 0, notModified)
+
+	require.Equal(t, "v1", get())
+	require.Equal(t, 1, notModified)
+
+	body = "v2"
+	require.Equal(t, "v2", get())
+	require.Equal(t, "v2", get())
+	require.Equal(t, 2, notModified)
+
+	client, err = NewClient(NewHTTPClient(), WithCache(dir, time.Nanosecond))
+	require.NoError(t, err)
+	require.Equal(t, "v2", get())
+	require.Equal(t, 2, notModified)
+}
@@ -18,12 +18,17 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/Netflix/p2plab/errdefs"
 	cleanhttp "github.com/hashicorp/go-cleanhttp"
 	retryablehttp "github.com/hashicorp/go-retryablehttp"
 	"github.com/opentracing-contrib/go-stdlib/nethttp"
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 )
 
+// defaultRetryWaitMax is the longest time waited between retries of a request.
+const defaultRetryWaitMax = 30 * time.Second
+
 func NewHTTPClient() *http.Client {
 	return &http.Client{
 		Transport: &nethttp.Transport{
@@ -33,8 +38,12 @@ func NewHTTPClient() *http.Client {
 }
 
 type Client struct {
-	HTTPClient *http.Client
-	logger *zerolog.Logger
+	HTTPClient  *http.Client
+	logger      *zerolog.Logger
+	compression bool
+	cache       *cache
+	token       string
+	retry       bool
 }
 
 func NewClient(hclient *http.Client, opts ...ClientOption) (*Client, error) {
@@ -55,7 +64,7 @@ func NewClient(hclient *http.Client, opts ...ClientOption) (*Client, error) {
 func (c *Client) NewRequest(method, url string, opts ...RequestOption) *Request {
 	settings := RequestSettings{
 		RetryWaitMin: 1 * time.Second,
-		RetryWaitMax: 30 * time.Second,
+		RetryWaitMax: defaultRetryWaitMax,
 		RetryMax:     4,
 		CheckRetry:   retryablehttp.DefaultRetryPolicy,
 		Backoff:      retryablehttp.DefaultBackoff,
@@ -64,6 +73,12 @@ func (c *Client) NewRequest(method, url string, opts ...RequestOption) *Request
 		opt(&settings)
 	}
 
+	// Requests are already retried by the transport of WithRetry, which knows
+	// which methods are safe to retry.
+	if c.retry {
+		settings.RetryMax = 0
+	}
+
 	client := &retryablehttp.Client{
 		HTTPClient:   c.HTTPClient,
 		RetryWaitMin: settings.RetryWaitMin,
@@ -77,12 +92,19 @@ func (c *Client) NewRequest(method, url string, opts ...RequestOption) *Request
 		client.Logger = c.logger
 	}
 
-	return &Request{
-		Method:  method,
-		Url:     url,
-		Options: make(map[string]string),
-		client:  client,
+	req := &Request{
+		Method:      method,
+		Url:         url,
+		Options:     make(map[string]string),
+		Headers:     make(map[string]string),
+		client:      client,
+		compression: c.compression,
+		cache:       c.cache,
 	}
+	if c.token != "" {
+		req.Header("Authorization", "Bearer "+c.token)
+	}
+	return req
 }
 
 type ClientOption func(*Client) error
@@ -94,6 +116,47 @@ func WithLogger(logger *zerolog.Logger) ClientOption {
 	}
 }
 
+// WithTimeout sets a time limit for each HTTP request made by the client,
+// including reading the response body. A context deadline passed to
+// Request.Send still applies, so whichever expires first cancels the request.
+func WithTimeout(d time.Duration) ClientOption {
+	return func(c *Client) error {
+		c.HTTPClient.Timeout = d
+		return nil
+	}
+}
+
+// WithBearerToken authenticates every request of the client with a bearer
+// token in the Authorization header.
+func WithBearerToken(token string) ClientOption {
+	return func(c *Client) error {
+		c.token = token
+		return nil
+	}
+}
+
+// WithTransportTuning sizes the pool of idle keep-alive connections of the
+// client's transport, which cleanhttp limits to a few per host. Clients that
+// fan out to many hosts should keep more connections idle to reuse them rather
+// than dialing each time.
+//
+// Every open connection holds a file descriptor, so maxIdleConns should stay
+// well below the process's file descriptor limit (see `ulimit -n`), leaving
+// room for the connections that are in use and for the daemon's own files.
+func WithTransportTuning(maxIdleConns, maxIdleConnsPerHost int, idleTimeout time.Duration) ClientOption {
+	return func(c *Client) error {
+		transport := baseTransport(c.HTTPClient.Transport)
+		if transport == nil {
+			return errors.Wrap(errdefs.ErrInvalidArgument, "client transport does not support tuning")
+		}
+
+		transport.MaxIdleConns = maxIdleConns
+		transport.MaxIdleConnsPerHost = maxIdleConnsPerHost
+		transport.IdleConnTimeout = idleTimeout
+		return nil
+	}
+}
+
 type RequestOption func(*RequestSettings)
 
 type RequestSettings struct {
@@ -0,0 +1,170 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httputil
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/opentracing-contrib/go-stdlib/nethttp"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	jaeger "github.com/uber/jaeger-client-go"
+	"github.com/uber/jaeger-client-go/zipkin"
+)
+
+func newSlowServer(delay time.Duration) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+		}
+	}))
+}
+
+func TestClientTimeout(t *testing.T) {
+	srv := newSlowServer(5 * time.Second)
+	defer srv.Close()
+
+	client, err := NewClient(NewHTTPClient(), WithTimeout(100*time.Millisecond))
+	require.NoError(t, err)
+
+	start := time.Now()
+	_, err = client.NewRequest("GET", srv.URL, WithRetryMax(0)).Send(context.Background())
+	require.Error(t, err)
+	require.True(t, time.Since(start) < 2*time.Second)
+}
+
+func TestContextDeadlineWins(t *testing.T) {
+	srv := newSlowServer(5 * time.Second)
+	defer srv.Close()
+
+	client, err := NewClient(NewHTTPClient(), WithTimeout(10*time.Second))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = client.NewRequest("GET", srv.URL).Send(ctx)
+	require.Error(t, err)
+	require.Equal(t, context.DeadlineExceeded, ctx.Err())
+	require.True(t, time.Since(start) < 2*time.Second)
+}
+
+func TestBearerToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "invalid bearer token", http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(NewHTTPClient(), WithBearerToken("secret"))
+	require.NoError(t, err)
+
+	resp, err := client.NewRequest("GET", srv.URL).Send(context.Background())
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	client, err = NewClient(NewHTTPClient(), WithBearerToken("wrong"))
+	require.NoError(t, err)
+
+	_, err = client.NewRequest("GET", srv.URL, WithRetryMax(0)).Send(context.Background())
+	require.True(t, errdefs.IsUnauthorized(err), "expected unauthorized, got %v", err)
+}
+
+func TestRequestID(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get(RequestIDHeader)))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(NewHTTPClient())
+	require.NoError(t, err)
+
+	ctx := WithRequestID(context.Background(), "abc")
+	resp, err := client.NewRequest("GET", srv.URL).Send(ctx)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	content, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "abc", string(content))
+}
+
+func TestTracePropagation(t *testing.T) {
+	newTracer := func() opentracing.Tracer {
+		propagator := zipkin.NewZipkinB3HTTPHeaderPropagator()
+		tracer, _ := jaeger.NewTracer("test", jaeger.NewConstSampler(true), jaeger.NewInMemoryReporter(),
+			jaeger.TracerOptions.Injector(opentracing.HTTPHeaders, propagator),
+			jaeger.TracerOptions.Extractor(opentracing.HTTPHeaders, propagator),
+		)
+		return tracer
+	}
+
+	traceIDs := make(chan string, 1)
+	srv := httptest.NewServer(nethttp.Middleware(newTracer(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NotEmpty(t, r.Header.Get("X-B3-TraceId"))
+		span := opentracing.SpanFromContext(r.Context())
+		traceIDs <- span.Context().(jaeger.SpanContext).TraceID().String()
+	})))
+	defer srv.Close()
+
+	client, err := NewClient(NewHTTPClient())
+	require.NoError(t, err)
+
+	span := newTracer().StartSpan("command")
+	ctx := opentracing.ContextWithSpan(context.Background(), span)
+	resp, err := client.NewRequest("GET", srv.URL).Send(ctx)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	require.Equal(t, span.Context().(jaeger.SpanContext).TraceID().String(), <-traceIDs)
+}
+
+func TestErrorCode(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		WriteError(w, http.StatusPreconditionFailed, errors.Wrap(errdefs.ErrFailedPrecondition, "benchmark is running"))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(NewHTTPClient())
+	require.NoError(t, err)
+
+	_, err = client.NewRequest("GET", srv.URL).Send(context.Background())
+	require.True(t, errdefs.IsFailedPrecondition(err), "expected failed precondition, got %v", err)
+	require.Contains(t, err.Error(), "benchmark is running")
+}
+
+func TestTransportTuning(t *testing.T) {
+	client, err := NewClient(NewHTTPClient(), WithTransportTuning(512, 8, time.Minute))
+	require.NoError(t, err)
+
+	transport := baseTransport(client.HTTPClient.Transport)
+	require.NotNil(t, transport)
+	require.Equal(t, 512, transport.MaxIdleConns)
+	require.Equal(t, 8, transport.MaxIdleConnsPerHost)
+	require.Equal(t, time.Minute, transport.IdleConnTimeout)
+
+	_, err = NewClient(&http.Client{Transport: http.NewFileTransport(http.Dir("."))}, WithTransportTuning(512, 8, time.Minute))
+	require.True(t, errdefs.IsInvalidArgument(err))
+}
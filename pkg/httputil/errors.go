@@ -0,0 +1,79 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httputil
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/pkg/errors"
+)
+
+// ErrorResponse is the body of the error responses of p2plab daemons.
+type ErrorResponse struct {
+	Error     string            `json:"error"`
+	Code      errdefs.ErrorCode `json:"code"`
+	RequestID string            `json:"requestId,omitempty"`
+}
+
+// WriteError replies to the request with the error and its code, so that
+// the client can reconstruct a coded error.
+func WriteError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+
+	json.NewEncoder(w).Encode(&ErrorResponse{
+		Error:     err.Error(),
+		Code:      errdefs.Code(err),
+		RequestID: w.Header().Get(RequestIDHeader),
+	})
+}
+
+// responseError returns the error of a rejected response. The code of the
+// error is taken from the body if it is an ErrorResponse, or from its status
+// otherwise.
+func responseError(resp *http.Response, body []byte) error {
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		var er ErrorResponse
+		err := json.Unmarshal(body, &er)
+		if err == nil && er.Code != "" {
+			err = errdefs.WithCode(errors.Errorf("server rejected request [%d]: %s", resp.StatusCode, er.Error), er.Code)
+			if resp.StatusCode == http.StatusTooManyRequests {
+				d, _ := retryAfter(resp)
+				err = &RateLimitError{err: err, RetryAfter: d}
+			}
+			return err
+		}
+	}
+
+	err := errors.Errorf("server rejected request [%d]: %s", resp.StatusCode, body)
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		d, _ := retryAfter(resp)
+		return &RateLimitError{
+			err:        errdefs.WithCode(err, errdefs.CodeRateLimited),
+			RetryAfter: d,
+		}
+	case http.StatusNotImplemented:
+		return errdefs.WithCode(err, errdefs.CodeNotImplemented)
+	case http.StatusUnauthorized:
+		return errdefs.WithCode(err, errdefs.CodeUnauthorized)
+	default:
+		return err
+	}
+}
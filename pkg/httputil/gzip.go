@@ -0,0 +1,173 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httputil
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+)
+
+var (
+	// CompressionThreshold is the minimum size of a request body in bytes
+	// before it is compressed by clients with compression enabled.
+	CompressionThreshold = 1024
+)
+
+// WithCompression enables gzip compression for the client. Responses are
+// requested with gzip encoding and transparently decompressed, and request
+// bodies above CompressionThreshold are compressed.
+func WithCompression() ClientOption {
+	return func(c *Client) error {
+		c.compression = true
+		return nil
+	}
+}
+
+// CompressionHandler decompresses gzip request bodies and compresses responses
+// for clients that accept gzip encoding. Flushes are passed through so that
+// streaming responses are not buffered. Responses that set Accept-Ranges or
+// Content-Range are sent uncompressed, since their ranges and lengths refer to
+// the uncompressed content.
+func CompressionHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			defer zr.Close()
+
+			r.Body = zr
+			r.ContentLength = -1
+			r.Header.Del("Content-Encoding")
+			r.Header.Del("Content-Length")
+		}
+
+		if !acceptsGzip(r) {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		gw := &gzipResponseWriter{
+			ResponseWriter: w,
+			code:           http.StatusOK,
+		}
+		defer gw.Close()
+
+		h.ServeHTTP(gw, r)
+	})
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, encoding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		encoding = strings.TrimSpace(strings.Split(encoding, ";")[0])
+		if encoding == "gzip" {
+			return true
+		}
+	}
+	return false
+}
+
+// gzipResponseWriter defers writing the header until the first write, so that
+// responses without a body are sent uncompressed.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	zw      *gzip.Writer
+	code    int
+	started bool
+}
+
+// ranged returns whether the response serves ranges of its content.
+func (w *gzipResponseWriter) ranged() bool {
+	h := w.Header()
+	return h.Get("Accept-Ranges") != "" || h.Get("Content-Range") != ""
+}
+
+func (w *gzipResponseWriter) WriteHeader(code int) {
+	w.code = code
+}
+
+func (w *gzipResponseWriter) Write(p []byte) (int, error) {
+	w.start()
+	if w.zw == nil {
+		return w.ResponseWriter.Write(p)
+	}
+	return w.zw.Write(p)
+}
+
+func (w *gzipResponseWriter) Flush() {
+	w.start()
+	if w.zw != nil {
+		w.zw.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *gzipResponseWriter) Close() error {
+	if !w.started {
+		w.ResponseWriter.WriteHeader(w.code)
+		return nil
+	}
+	if w.zw == nil {
+		return nil
+	}
+	return w.zw.Close()
+}
+
+func (w *gzipResponseWriter) start() {
+	if w.started {
+		return
+	}
+	w.started = true
+
+	if w.ranged() {
+		w.ResponseWriter.WriteHeader(w.code)
+		return
+	}
+
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Encoding", "gzip")
+	h.Add("Vary", "Accept-Encoding")
+	w.ResponseWriter.WriteHeader(w.code)
+	w.zw = gzip.NewWriter(w.ResponseWriter)
+}
+
+type gzipReadCloser struct {
+	zr *gzip.Reader
+	rc io.ReadCloser
+}
+
+func newGzipReadCloser(rc io.ReadCloser) (io.ReadCloser, error) {
+	zr, err := gzip.NewReader(rc)
+	if err != nil {
+		return nil, err
+	}
+	return &gzipReadCloser{zr, rc}, nil
+}
+
+func (r *gzipReadCloser) Read(p []byte) (int, error) {
+	return r.zr.Read(p)
+}
+
+func (r *gzipReadCloser) Close() error {
+	r.zr.Close()
+	return r.rc.Close()
+}
@@ -0,0 +1,77 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httputil
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/pkg/errors"
+)
+
+// ParseRange parses the value of a Range header with a single byte range
+// against content of the given size, returning the first and last byte of the
+// range inclusively. Returns errdefs.ErrInvalidArgument if the range is
+// malformed or not satisfiable.
+func ParseRange(header string, size int64) (start, end int64, err error) {
+	spec := strings.TrimPrefix(header, "bytes=")
+	if spec == header || strings.Contains(spec, ",") {
+		return 0, 0, errors.Wrapf(errdefs.ErrInvalidArgument, "unsupported range %q", header)
+	}
+
+	parts := strings.SplitN(spec, "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, errors.Wrapf(errdefs.ErrInvalidArgument, "invalid range %q", header)
+	}
+	first, last := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+
+	switch {
+	case first == "" && last == "":
+		return 0, 0, errors.Wrapf(errdefs.ErrInvalidArgument, "invalid range %q", header)
+	case first == "":
+		// A suffix range requests the last bytes of the content.
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n <= 0 {
+			return 0, 0, errors.Wrapf(errdefs.ErrInvalidArgument, "invalid range %q", header)
+		}
+		if n > size {
+			n = size
+		}
+		start, end = size-n, size-1
+	default:
+		start, err = strconv.ParseInt(first, 10, 64)
+		if err != nil || start < 0 {
+			return 0, 0, errors.Wrapf(errdefs.ErrInvalidArgument, "invalid range %q", header)
+		}
+
+		end = size - 1
+		if last != "" {
+			end, err = strconv.ParseInt(last, 10, 64)
+			if err != nil || end < start {
+				return 0, 0, errors.Wrapf(errdefs.ErrInvalidArgument, "invalid range %q", header)
+			}
+			if end >= size {
+				end = size - 1
+			}
+		}
+	}
+
+	if start >= size {
+		return 0, 0, errors.Wrapf(errdefs.ErrInvalidArgument, "range %q not satisfiable for %d bytes", header, size)
+	}
+
+	return start, end, nil
+}
@@ -0,0 +1,51 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httputil
+
+import (
+	"testing"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRange(t *testing.T) {
+	for header, expected := range map[string][2]int64{
+		"bytes=0-":     {0, 99},
+		"bytes=10-":    {10, 99},
+		"bytes=10-19":  {10, 19},
+		"bytes=90-200": {90, 99},
+		"bytes=-10":    {90, 99},
+		"bytes=-200":   {0, 99},
+	} {
+		start, end, err := ParseRange(header, 100)
+		require.NoError(t, err, header)
+		require.Equal(t, expected, [2]int64{start, end}, header)
+	}
+
+	for _, header := range []string{
+		"",
+		"items=0-10",
+		"bytes=-",
+		"bytes=a-",
+		"bytes=20-10",
+		"bytes=-0",
+		"bytes=0-10,20-30",
+		"bytes=100-",
+	} {
+		_, _, err := ParseRange(header, 100)
+		require.True(t, errdefs.IsInvalidArgument(err), "%q", header)
+	}
+}
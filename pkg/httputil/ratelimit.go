@@ -0,0 +1,103 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httputil
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// RateLimitError is returned when a server responds with 429 Too Many
+// Requests, carrying the delay the server suggested in Retry-After.
+type RateLimitError struct {
+	err error
+
+	// RetryAfter is the delay before the request may be retried, or zero if
+	// the server didn't suggest one.
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return errors.Wrapf(e.err, "retry after %s", e.RetryAfter).Error()
+	}
+	return e.err.Error()
+}
+
+func (e *RateLimitError) Cause() error {
+	return e.err
+}
+
+// RetryAfter returns the delay suggested by the server that rate limited the
+// request that failed with err.
+func RetryAfter(err error) (time.Duration, bool) {
+	for err != nil {
+		if rerr, ok := err.(*RateLimitError); ok {
+			return rerr.RetryAfter, rerr.RetryAfter > 0
+		}
+
+		cause, ok := err.(interface{ Cause() error })
+		if !ok {
+			break
+		}
+		err = cause.Cause()
+	}
+	return 0, false
+}
+
+// parseRetryAfter parses a Retry-After header, either in seconds or as a HTTP
+// date.
+func parseRetryAfter(header string, now time.Time) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+
+	seconds, err := strconv.Atoi(header)
+	if err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	t, err := http.ParseTime(header)
+	if err != nil {
+		return 0, false
+	}
+
+	d := t.Sub(now)
+	if d < 0 {
+		d = 0
+	}
+	return d, true
+}
+
+// retryAfter returns the delay suggested by a rate limited response.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil || resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	return parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+}
+
+// exceedsDeadline returns true if the context expires before the delay.
+func exceedsDeadline(ctx context.Context, d time.Duration) bool {
+	deadline, ok := ctx.Deadline()
+	return ok && time.Now().Add(d).After(deadline)
+}
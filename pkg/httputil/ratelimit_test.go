@@ -0,0 +1,122 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httputil
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/stretchr/testify/require"
+)
+
+// newRateLimitedServer returns a server that rate limits the first request
+// and serves the following ones.
+func newRateLimitedServer(retryAfter string) (*httptest.Server, *int32) {
+	var count int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&count, 1) == 1 {
+			w.Header().Set("Retry-After", retryAfter)
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+			return
+		}
+	}))
+	return srv, &count
+}
+
+func TestRetryAfterCapped(t *testing.T) {
+	srv, count := newRateLimitedServer("120")
+	defer srv.Close()
+
+	client, err := NewClient(NewHTTPClient(), WithRetry(1, time.Millisecond))
+	require.NoError(t, err)
+	client.HTTPClient.Transport.(*retryTransport).maxWait = 100 * time.Millisecond
+
+	start := time.Now()
+	resp, err := client.NewRequest("POST", srv.URL).Send(context.Background())
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	require.True(t, time.Since(start) < time.Second, "expected the delay in Retry-After to be capped")
+	require.Equal(t, int32(2), atomic.LoadInt32(count))
+}
+
+func TestRetryAfterTransport(t *testing.T) {
+	srv, count := newRateLimitedServer("1")
+	defer srv.Close()
+
+	client, err := NewClient(NewHTTPClient(), WithRetry(1, time.Millisecond))
+	require.NoError(t, err)
+
+	start := time.Now()
+	resp, err := client.NewRequest("GET", srv.URL, WithRetryMax(0)).Send(context.Background())
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	require.True(t, time.Since(start) >= time.Second, "expected to wait for the delay in Retry-After")
+	require.Equal(t, int32(2), atomic.LoadInt32(count))
+}
+
+func TestRateLimitedWithoutRetries(t *testing.T) {
+	srv, count := newRateLimitedServer("120")
+	defer srv.Close()
+
+	client, err := NewClient(NewHTTPClient())
+	require.NoError(t, err)
+
+	_, err = client.NewRequest("GET", srv.URL).Send(context.Background())
+	require.True(t, errdefs.IsRateLimited(err), "expected rate limited, got %v", err)
+
+	d, ok := RetryAfter(err)
+	require.True(t, ok)
+	require.Equal(t, 2*time.Minute, d)
+	require.Equal(t, int32(1), atomic.LoadInt32(count))
+}
+
+func TestRateLimitedPastDeadline(t *testing.T) {
+	srv, count := newRateLimitedServer("120")
+	defer srv.Close()
+
+	client, err := NewClient(NewHTTPClient(), WithRetry(1, time.Millisecond))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	_, err = client.NewRequest("GET", srv.URL).Send(ctx)
+	require.True(t, errdefs.IsRateLimited(err), "expected rate limited, got %v", err)
+	require.True(t, time.Since(start) < time.Second, "expected to fail without waiting past the deadline")
+	require.Equal(t, int32(1), atomic.LoadInt32(count))
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	d, ok := parseRetryAfter("30", now)
+	require.True(t, ok)
+	require.Equal(t, 30*time.Second, d)
+
+	d, ok = parseRetryAfter(now.Add(time.Minute).Format(http.TimeFormat), now)
+	require.True(t, ok)
+	require.Equal(t, time.Minute, d)
+
+	_, ok = parseRetryAfter("soon", now)
+	require.False(t, ok)
+}
@@ -16,6 +16,7 @@ package httputil
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"fmt"
 	"io"
@@ -36,10 +37,13 @@ type Request struct {
 	Method  string
 	Url     string
 	Options map[string]string
+	Headers map[string]string
 	body    io.Reader
 
-	client    *retryablehttp.Client
-	rawClient *http.Client
+	client      *retryablehttp.Client
+	rawClient   *http.Client
+	compression bool
+	cache       *cache
 }
 
 func (r *Request) Option(key string, value interface{}) *Request {
@@ -59,6 +63,11 @@ func (r *Request) Option(key string, value interface{}) *Request {
 	return r
 }
 
+func (r *Request) Header(key, value string) *Request {
+	r.Headers[key] = value
+	return r
+}
+
 func (r *Request) Body(value interface{}) *Request {
 	var reader io.Reader
 	switch v := value.(type) {
@@ -75,13 +84,50 @@ func (r *Request) Body(value interface{}) *Request {
 }
 
 func (r *Request) Send(ctx context.Context) (*http.Response, error) {
+	body, compressed, err := r.compressBody()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to compress request body")
+	}
+
 	u := r.url()
-	req, err := http.NewRequest(r.Method, u, r.body)
+	req, err := http.NewRequest(r.Method, u, body)
 	if err != nil {
 		return nil, errors.Wrap(errdefs.ErrInvalidArgument, "failed to create new http request")
 	}
 	req = req.WithContext(ctx)
 
+	for key, value := range r.Headers {
+		req.Header.Set(key, value)
+	}
+
+	if id := RequestID(ctx); id != "" && req.Header.Get(RequestIDHeader) == "" {
+		req.Header.Set(RequestIDHeader, id)
+	}
+
+	if r.compression {
+		// Setting Accept-Encoding explicitly disables the transport's transparent
+		// decompression, so gzip responses are decompressed below.
+		req.Header.Set("Accept-Encoding", "gzip")
+	}
+	if compressed {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	// Only plain GET requests are cached, streamed and partial responses are
+	// always fetched.
+	var (
+		cacheKey string
+		cached   *cacheEntry
+	)
+	if r.cache != nil && r.Method == "GET" && req.Header.Get("Range") == "" {
+		cacheKey = fmt.Sprintf("%s\n%s", u, req.Header.Get("Accept"))
+		var ok bool
+		cached, ok = r.cache.get(cacheKey)
+		if ok {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+	}
+
 	span := opentracing.SpanFromContext(ctx)
 	if span != nil {
 		var ht *nethttp.Tracer
@@ -99,6 +145,32 @@ func (r *Request) Send(ctx context.Context) (*http.Response, error) {
 		return resp, errors.Wrap(err, "failed to do http request")
 	}
 
+	if resp.Header.Get("Content-Encoding") == "gzip" && !resp.Uncompressed {
+		resp.Body, err = newGzipReadCloser(resp.Body)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to decompress response body")
+		}
+		resp.Header.Del("Content-Encoding")
+	}
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		return cached.response(resp), nil
+	}
+
+	if cacheKey != "" && resp.StatusCode == http.StatusOK && resp.Header.Get("ETag") != "" {
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read response body")
+		}
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		err = r.cache.put(cacheKey, resp.Header, body)
+		if err != nil {
+			log.Warn().Err(err).Msg("failed to cache response")
+		}
+	}
+
 	if (resp.StatusCode >= 400 && resp.StatusCode <= 499) ||
 		resp.StatusCode < 200 || resp.StatusCode > 299 {
 		body, err := ioutil.ReadAll(resp.Body)
@@ -107,12 +179,41 @@ func (r *Request) Send(ctx context.Context) (*http.Response, error) {
 		}
 		defer resp.Body.Close()
 
-		return nil, errors.Errorf("server rejected request [%d]: %s", resp.StatusCode, body)
+		return nil, responseError(resp, body)
 	}
 
 	return resp, nil
 }
 
+func (r *Request) compressBody() (body io.Reader, compressed bool, err error) {
+	if !r.compression || r.body == nil {
+		return r.body, false, nil
+	}
+
+	content, err := ioutil.ReadAll(r.body)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(content) < CompressionThreshold {
+		return bytes.NewReader(content), false, nil
+	}
+
+	buf := new(bytes.Buffer)
+	zw := gzip.NewWriter(buf)
+	_, err = zw.Write(content)
+	if err != nil {
+		return nil, false, err
+	}
+
+	err = zw.Close()
+	if err != nil {
+		return nil, false, err
+	}
+
+	return buf, true, nil
+}
+
 func (r *Request) url() string {
 	values := make(url.Values)
 	for k, v := range r.Options {
@@ -0,0 +1,51 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httputil
+
+import (
+	"context"
+
+	"github.com/Netflix/p2plab/pkg/traceutil"
+	"github.com/rs/xid"
+)
+
+const (
+	// RequestIDHeader is the HTTP header correlating a request across labctl,
+	// labd and the agents it calls.
+	RequestIDHeader = "X-Request-ID"
+)
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context whose requests are sent with the request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID of the context, if any.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// NewRequestID returns the trace ID of the span in ctx so that request IDs
+// match traces, or a new unique ID if the context is not traced.
+func NewRequestID(ctx context.Context) string {
+	id := traceutil.TraceID(ctx)
+	if id == "" {
+		id = xid.New().String()
+	}
+	return id
+}
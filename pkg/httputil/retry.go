@@ -0,0 +1,145 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httputil
+
+import (
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+)
+
+// WithRetry wraps the client's transport to retry requests up to attempts
+// times with jittered exponential backoff. Idempotent requests are retried on
+// network errors and 5xx responses, other requests are only retried when the
+// connection could not be established or were rate limited, in which case the
+// delay in Retry-After is waited instead. Waits are capped at 30 seconds, and
+// rate limited requests are not retried without this option. The transport
+// replaces the retries of requests made by the client, so that each request is
+// attempted at most attempts+1 times.
+func WithRetry(attempts int, backoff time.Duration) ClientOption {
+	return func(c *Client) error {
+		next := c.HTTPClient.Transport
+		if next == nil {
+			next = http.DefaultTransport
+		}
+
+		c.HTTPClient.Transport = &retryTransport{
+			next:     next,
+			attempts: attempts,
+			backoff:  backoff,
+			maxWait:  defaultRetryWaitMax,
+		}
+		c.retry = true
+		return nil
+	}
+}
+
+type retryTransport struct {
+	next     http.RoundTripper
+	attempts int
+	backoff  time.Duration
+	maxWait  time.Duration
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := t.next.RoundTrip(req)
+		if attempt >= t.attempts || !t.shouldRetry(req, resp, err) {
+			return resp, err
+		}
+
+		// Requests with a body can only be retried if it can be replayed.
+		if req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return resp, err
+			}
+
+			body, berr := req.GetBody()
+			if berr != nil {
+				return resp, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		wait := t.wait(attempt)
+		if d, ok := retryAfter(resp); ok {
+			wait = t.cap(d)
+		}
+
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (t *retryTransport) shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+
+	if err != nil {
+		return isIdempotent(req.Method) || isDialError(err)
+	}
+
+	// Rate limited requests were not processed, so they are retried after
+	// the delay suggested by the server if the context allows it.
+	if resp.StatusCode == http.StatusTooManyRequests {
+		d, _ := retryAfter(resp)
+		return !exceedsDeadline(req.Context(), t.cap(d))
+	}
+
+	// Not implemented is a permanent failure, retrying won't change it.
+	return isIdempotent(req.Method) && resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented
+}
+
+func (t *retryTransport) wait(attempt int) time.Duration {
+	wait := t.cap(t.backoff * time.Duration(1<<uint(attempt)))
+	if wait <= 0 {
+		return 0
+	}
+
+	// Jitter by up to half the wait to avoid synchronized retries.
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
+
+// cap limits a wait to the maximum wait between retries.
+func (t *retryTransport) cap(wait time.Duration) time.Duration {
+	if wait > t.maxWait {
+		return t.maxWait
+	}
+	return wait
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+func isDialError(err error) bool {
+	operr, ok := err.(*net.OpError)
+	return ok && operr.Op == "dial"
+}
@@ -0,0 +1,51 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httputil
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetry(t *testing.T) {
+	var count int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		http.Error(w, "unavailable", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(NewHTTPClient(), WithRetry(2, time.Millisecond))
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		method   string
+		expected int32
+	}{
+		{"GET", 3},
+		{"POST", 1},
+	} {
+		atomic.StoreInt32(&count, 0)
+		_, err = client.NewRequest(tc.method, srv.URL, WithRetryWaitMin(time.Millisecond)).Send(context.Background())
+		require.Error(t, err)
+		require.Equal(t, tc.expected, atomic.LoadInt32(&count), "unexpected attempts for %s", tc.method)
+	}
+}
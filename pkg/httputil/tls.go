@@ -0,0 +1,87 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httputil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/opentracing-contrib/go-stdlib/nethttp"
+	"github.com/pkg/errors"
+)
+
+// WithTLSConfig sets the TLS configuration of the client's transport.
+func WithTLSConfig(cfg *tls.Config) ClientOption {
+	return func(c *Client) error {
+		transport := baseTransport(c.HTTPClient.Transport)
+		if transport == nil {
+			return errors.Wrap(errdefs.ErrInvalidArgument, "client transport does not support tls config")
+		}
+
+		transport.TLSClientConfig = cfg
+		return nil
+	}
+}
+
+// WithClientCert configures the client's transport with a client certificate
+// and key for mutual TLS. When caFile is provided, it replaces the system pool
+// for verifying servers.
+func WithClientCert(certFile, keyFile, caFile string) ClientOption {
+	return func(c *Client) error {
+		cfg := &tls.Config{}
+		if certFile != "" || keyFile != "" {
+			cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+			if err != nil {
+				return errors.Wrap(err, "failed to load client cert")
+			}
+			cfg.Certificates = []tls.Certificate{cert}
+		}
+
+		if caFile != "" {
+			content, err := ioutil.ReadFile(caFile)
+			if err != nil {
+				return errors.Wrap(err, "failed to read ca file")
+			}
+
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(content) {
+				return errors.Wrapf(errdefs.ErrInvalidArgument, "no certificates found in %q", caFile)
+			}
+			cfg.RootCAs = pool
+		}
+
+		return WithTLSConfig(cfg)(c)
+	}
+}
+
+// baseTransport unwraps round trippers set up by this package to find the
+// underlying transport.
+func baseTransport(rt http.RoundTripper) *http.Transport {
+	for {
+		switch t := rt.(type) {
+		case *http.Transport:
+			return t
+		case *nethttp.Transport:
+			rt = t.RoundTripper
+		case *retryTransport:
+			rt = t.next
+		default:
+			return nil
+		}
+	}
+}
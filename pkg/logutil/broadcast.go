@@ -0,0 +1,117 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logutil
+
+import (
+	"bytes"
+	"sync"
+)
+
+const subscriberBuffer = 256
+
+// Broadcaster is a writer that retains the most recent lines written to it
+// and fans them out to subscribers. Every line is assigned a sequence number
+// so subscribers can resume from where they left off.
+type Broadcaster struct {
+	mu      sync.Mutex
+	lines   [][]byte
+	size    int
+	next    uint64
+	partial bytes.Buffer
+	subs    map[*subscriber]struct{}
+}
+
+type subscriber struct {
+	ch chan []byte
+}
+
+func NewBroadcaster(size int) *Broadcaster {
+	return &Broadcaster{
+		size: size,
+		subs: make(map[*subscriber]struct{}),
+	}
+}
+
+func (b *Broadcaster) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.partial.Write(p)
+	for {
+		i := bytes.IndexByte(b.partial.Bytes(), '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+
+		line := make([]byte, i)
+		copy(line, b.partial.Next(i + 1)[:i])
+		b.append(line)
+	}
+}
+
+func (b *Broadcaster) append(line []byte) {
+	b.lines = append(b.lines, line)
+	if len(b.lines) > b.size {
+		b.lines = b.lines[len(b.lines)-b.size:]
+	}
+	b.next++
+
+	for sub := range b.subs {
+		select {
+		case sub.ch <- line:
+		default:
+			// Disconnect subscribers that fall behind rather than blocking the
+			// writer, they can resume from the retained lines.
+			close(sub.ch)
+			delete(b.subs, sub)
+		}
+	}
+}
+
+// Subscribe returns the retained lines starting at sequence number since and
+// a channel of lines written afterwards. The returned offset is the sequence
+// number of the first line returned, which is greater than since if lines
+// have been discarded in the meantime. If since is ahead of the broadcaster,
+// it is assumed to have been reset and all retained lines are returned.
+//
+// The channel is closed if the subscriber falls behind or cancel is called.
+func (b *Broadcaster) Subscribe(since uint64) (uint64, [][]byte, <-chan []byte, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	oldest := b.next - uint64(len(b.lines))
+	if since < oldest || since > b.next {
+		since = oldest
+	}
+
+	backlog := make([][]byte, b.next-since)
+	copy(backlog, b.lines[since-oldest:])
+
+	sub := &subscriber{ch: make(chan []byte, subscriberBuffer)}
+	b.subs[sub] = struct{}{}
+
+	cancel := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		_, ok := b.subs[sub]
+		if ok {
+			close(sub.ch)
+			delete(b.subs, sub)
+		}
+	}
+
+	return since, backlog, sub.ch, cancel
+}
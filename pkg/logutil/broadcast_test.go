@@ -0,0 +1,74 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBroadcaster(t *testing.T) {
+	b := NewBroadcaster(3)
+
+	_, err := b.Write([]byte("a\nb\nc"))
+	require.NoError(t, err)
+
+	offset, backlog, lines, cancel := b.Subscribe(0)
+	require.Equal(t, uint64(0), offset)
+	require.Equal(t, [][]byte{[]byte("a"), []byte("b")}, backlog)
+
+	_, err = b.Write([]byte("\nd\n"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("c"), <-lines)
+	require.Equal(t, []byte("d"), <-lines)
+
+	cancel()
+	_, ok := <-lines
+	require.False(t, ok)
+
+	// Lines older than the retained window are skipped.
+	offset, backlog, _, cancel = b.Subscribe(0)
+	defer cancel()
+	require.Equal(t, uint64(1), offset)
+	require.Equal(t, [][]byte{[]byte("b"), []byte("c"), []byte("d")}, backlog)
+
+	offset, backlog, _, cancel = b.Subscribe(3)
+	defer cancel()
+	require.Equal(t, uint64(3), offset)
+	require.Equal(t, [][]byte{[]byte("d")}, backlog)
+
+	// A resumed subscriber ahead of the broadcaster gets everything retained.
+	offset, _, _, cancel = b.Subscribe(100)
+	defer cancel()
+	require.Equal(t, uint64(1), offset)
+}
+
+func TestBroadcasterSlowSubscriber(t *testing.T) {
+	b := NewBroadcaster(1)
+	_, _, lines, cancel := b.Subscribe(0)
+	defer cancel()
+
+	for i := 0; i <= subscriberBuffer; i++ {
+		_, err := b.Write([]byte("line\n"))
+		require.NoError(t, err)
+	}
+
+	n := 0
+	for range lines {
+		n++
+	}
+	require.Equal(t, subscriberBuffer, n)
+}
@@ -0,0 +1,88 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Filter selects structured log lines by level and time.
+type Filter struct {
+	// Level is the minimum level of the lines selected.
+	Level zerolog.Level
+
+	// Since excludes lines logged before it, unless it is the zero time.
+	Since time.Time
+}
+
+// Match returns true if the zerolog event in line is selected by the filter.
+// Lines without a level or a time are not filtered on them, and lines that
+// aren't events are always selected.
+func (f Filter) Match(line []byte) bool {
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.UseNumber()
+
+	var evt map[string]interface{}
+	err := decoder.Decode(&evt)
+	if err != nil {
+		return true
+	}
+
+	levelStr, ok := evt[zerolog.LevelFieldName].(string)
+	if ok {
+		level, err := zerolog.ParseLevel(levelStr)
+		if err == nil && level < f.Level {
+			return false
+		}
+	}
+
+	if !f.Since.IsZero() {
+		t, ok := eventTime(evt[zerolog.TimestampFieldName])
+		if ok && t.Before(f.Since) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// eventTime parses the time field of an event written with the configured
+// zerolog.TimeFieldFormat.
+func eventTime(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case json.Number:
+		n, err := t.Int64()
+		if err != nil {
+			return time.Time{}, false
+		}
+
+		if zerolog.TimeFieldFormat == zerolog.TimeFormatUnixMs {
+			return time.Unix(0, n*int64(time.Millisecond)), true
+		}
+		return time.Unix(n, 0), true
+	case string:
+		parsed, err := time.Parse(zerolog.TimeFieldFormat, t)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return parsed, true
+	default:
+		return time.Time{}, false
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter(t *testing.T) {
+	format := zerolog.TimeFieldFormat
+	zerolog.TimeFieldFormat = zerolog.TimeFormatUnixMs
+	defer func() {
+		zerolog.TimeFieldFormat = format
+	}()
+
+	f := Filter{
+		Level: zerolog.WarnLevel,
+		Since: time.Unix(1000, 0),
+	}
+
+	for _, tc := range []struct {
+		line  string
+		match bool
+	}{
+		{`{"level":"warn","time":1000000}`, true},
+		{`{"level":"error","time":1500000}`, true},
+		{`{"level":"info","time":1500000}`, false},
+		{`{"level":"error","time":999999}`, false},
+		{`{"level":"error"}`, true},
+		{`{"time":1500000}`, true},
+		{`not an event`, true},
+	} {
+		require.Equal(t, tc.match, f.Match([]byte(tc.line)), tc.line)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logutil
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// LineMux interleaves the output of multiple sources line by line, prefixing
+// each line with the name of its source.
+type LineMux struct {
+	w  io.Writer
+	mu sync.Mutex
+}
+
+func NewLineMux(w io.Writer) *LineMux {
+	return &LineMux{w: w}
+}
+
+// Writer returns a writer for a source. Partial lines are buffered until they
+// are terminated or the writer is closed.
+func (m *LineMux) Writer(prefix string) io.WriteCloser {
+	return &lineWriter{mux: m, prefix: prefix}
+}
+
+func (m *LineMux) writeLine(prefix string, line []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := fmt.Fprintf(m.w, "%s | %s\n", prefix, line)
+	return err
+}
+
+type lineWriter struct {
+	mux    *LineMux
+	prefix string
+	buf    bytes.Buffer
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	for {
+		i := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+
+		err := w.mux.writeLine(w.prefix, w.buf.Next(i + 1)[:i])
+		if err != nil {
+			return len(p), err
+		}
+	}
+}
+
+func (w *lineWriter) Close() error {
+	if w.buf.Len() == 0 {
+		return nil
+	}
+
+	line := w.buf.Bytes()
+	w.buf.Reset()
+	return w.mux.writeLine(w.prefix, line)
+}
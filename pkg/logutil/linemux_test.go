@@ -0,0 +1,41 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logutil
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLineMux(t *testing.T) {
+	var buf bytes.Buffer
+	mux := NewLineMux(&buf)
+
+	a := mux.Writer("a")
+	b := mux.Writer("b")
+
+	_, err := a.Write([]byte("hello "))
+	require.NoError(t, err)
+	_, err = b.Write([]byte("first\nsecond\n"))
+	require.NoError(t, err)
+	_, err = a.Write([]byte("world\npartial"))
+	require.NoError(t, err)
+	require.NoError(t, a.Close())
+	require.NoError(t, b.Close())
+
+	require.Equal(t, "b | first\nb | second\na | hello world\na | partial\n", buf.String())
+}
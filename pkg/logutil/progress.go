@@ -0,0 +1,102 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// CompletedFieldName and TotalFieldName are the fields of log events
+	// reporting the progress of a long operation.
+	CompletedFieldName = "completed"
+	TotalFieldName     = "total"
+)
+
+type progressKey struct{}
+
+// ProgressFunc is called with the progress reported by remote log events.
+type ProgressFunc func(completed, total int64)
+
+// WithProgress returns a context where remote logs written with
+// WriteRemoteLogs report their progress to fn. A nil fn stops reporting.
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// Progress logs that completed out of total units of an operation are done.
+func Progress(ctx context.Context, completed, total int, msg string) {
+	zerolog.Ctx(ctx).Info().
+		Int(CompletedFieldName, completed).
+		Int(TotalFieldName, total).
+		Msg(msg)
+}
+
+// NewProgressWriter returns a writer of log events that reports the progress
+// of the events logged with Progress to fn, so that the progress of local
+// operations can be tracked like remote ones.
+func NewProgressWriter(fn ProgressFunc) io.Writer {
+	return progressWriter(fn)
+}
+
+type progressWriter ProgressFunc
+
+func (w progressWriter) Write(p []byte) (int, error) {
+	decoder := json.NewDecoder(bytes.NewReader(p))
+	decoder.UseNumber()
+
+	var evt map[string]interface{}
+	err := decoder.Decode(&evt)
+	if err == nil {
+		eventProgress(ProgressFunc(w), evt)
+	}
+	return len(p), nil
+}
+
+// reportProgress reports the progress of a remote log event, if any.
+func reportProgress(ctx context.Context, evt map[string]interface{}) {
+	fn, ok := ctx.Value(progressKey{}).(ProgressFunc)
+	if !ok || fn == nil {
+		return
+	}
+	eventProgress(fn, evt)
+}
+
+// eventProgress reports the progress of a log event to fn, if any.
+func eventProgress(fn ProgressFunc, evt map[string]interface{}) {
+	completed, ok := evt[CompletedFieldName].(json.Number)
+	if !ok {
+		return
+	}
+	total, ok := evt[TotalFieldName].(json.Number)
+	if !ok {
+		return
+	}
+
+	c, err := completed.Int64()
+	if err != nil {
+		return
+	}
+	t, err := total.Int64()
+	if err != nil {
+		return
+	}
+	fn(c, t)
+}
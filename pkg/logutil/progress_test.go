@@ -0,0 +1,59 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logutil
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteRemoteLogsProgress(t *testing.T) {
+	var remote bytes.Buffer
+	logger := zerolog.New(&remote)
+	ctx := logger.WithContext(context.Background())
+	Progress(ctx, 1, 3, "Node is healthy")
+	logger.Info().Msg("Not progress")
+	Progress(ctx, 2, 3, "Node is healthy")
+
+	var reported [][2]int64
+	local := zerolog.New(nil)
+	ctx = WithProgress(local.WithContext(context.Background()), func(completed, total int64) {
+		reported = append(reported, [2]int64{completed, total})
+	})
+
+	var out bytes.Buffer
+	err := WriteRemoteLogs(ctx, &remote, &out)
+	require.NoError(t, err)
+	require.Equal(t, [][2]int64{{1, 3}, {2, 3}}, reported)
+	require.Equal(t, 3, strings.Count(out.String(), "\n"))
+}
+
+func TestProgressWriter(t *testing.T) {
+	var reported [][2]int64
+	logger := zerolog.New(NewProgressWriter(func(completed, total int64) {
+		reported = append(reported, [2]int64{completed, total})
+	}))
+	ctx := logger.WithContext(context.Background())
+	Progress(ctx, 1, 2, "Block fetched")
+	logger.Info().Msg("Not progress")
+	Progress(ctx, 2, 2, "Block fetched")
+
+	require.Equal(t, [][2]int64{{1, 2}, {2, 2}}, reported)
+}
@@ -40,6 +40,7 @@ func WriteRemoteLogs(ctx context.Context, remote io.Reader, writer io.Writer) er
 			}
 			return errors.New("unexpected non-json response")
 		}
+		reportProgress(ctx, evt)
 
 		levelRaw, ok := evt[zerolog.LevelFieldName]
 		if ok {
@@ -0,0 +1,490 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package traceutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	jaeger "github.com/uber/jaeger-client-go"
+	j "github.com/uber/jaeger-client-go/thrift-gen/jaeger"
+)
+
+// OTLP protocols, where http/protobuf is the default.
+const (
+	otlpProtocolGRPC     = "grpc"
+	otlpProtocolProtobuf = "http/protobuf"
+	otlpProtocolJSON     = "http/json"
+)
+
+const (
+	// otlpBatchSize is the number of spans buffered before they are exported.
+	otlpBatchSize = 100
+
+	otlpDefaultTimeout = 10 * time.Second
+)
+
+// otlpConfig is the configuration of an OTLP exporter from the standard
+// OTEL_EXPORTER_OTLP_* environment variables, where the traces specific ones
+// take precedence.
+type otlpConfig struct {
+	Endpoint string
+	Headers  map[string]string
+	Protocol string
+	Insecure bool
+	Timeout  time.Duration
+}
+
+// otlpConfigFromEnv returns the OTLP exporter configuration, or false if
+// neither OTEL_EXPORTER_OTLP_TRACES_ENDPOINT nor OTEL_EXPORTER_OTLP_ENDPOINT
+// are set.
+func otlpConfigFromEnv(getenv func(string) string) (otlpConfig, bool, error) {
+	var cfg otlpConfig
+	if getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" && getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" {
+		return cfg, false, nil
+	}
+
+	cfg.Protocol = otlpEnv(getenv, "PROTOCOL")
+	switch cfg.Protocol {
+	case "":
+		cfg.Protocol = otlpProtocolProtobuf
+	case otlpProtocolGRPC, otlpProtocolProtobuf, otlpProtocolJSON:
+	default:
+		return cfg, true, errors.Errorf("OTLP protocol %q is not supported, must be one of [%s, %s, %s]", cfg.Protocol, otlpProtocolGRPC, otlpProtocolProtobuf, otlpProtocolJSON)
+	}
+
+	// The generic endpoint is the base URL of the signals over HTTP, but the
+	// address of the collector over gRPC.
+	cfg.Endpoint = getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+		if cfg.Protocol != otlpProtocolGRPC {
+			cfg.Endpoint = strings.TrimSuffix(cfg.Endpoint, "/") + "/v1/traces"
+		}
+	}
+	cfg.Insecure = otlpEnv(getenv, "INSECURE") == "true"
+
+	cfg.Headers = make(map[string]string)
+	for _, name := range []string{"OTEL_EXPORTER_OTLP_HEADERS", "OTEL_EXPORTER_OTLP_TRACES_HEADERS"} {
+		err := parseOTLPHeaders(getenv(name), cfg.Headers)
+		if err != nil {
+			return cfg, true, errors.Wrapf(err, "invalid %s", name)
+		}
+	}
+
+	cfg.Timeout = otlpDefaultTimeout
+	if timeout := otlpEnv(getenv, "TIMEOUT"); timeout != "" {
+		ms, err := strconv.Atoi(timeout)
+		if err != nil || ms <= 0 {
+			return cfg, true, errors.Errorf("invalid OTLP timeout %q, must be a positive number of milliseconds", timeout)
+		}
+		cfg.Timeout = time.Duration(ms) * time.Millisecond
+	}
+
+	return cfg, true, nil
+}
+
+// otlpEnv returns the traces specific OTLP exporter variable with the given
+// suffix, falling back to the generic one.
+func otlpEnv(getenv func(string) string, suffix string) string {
+	value := getenv("OTEL_EXPORTER_OTLP_TRACES_" + suffix)
+	if value == "" {
+		value = getenv("OTEL_EXPORTER_OTLP_" + suffix)
+	}
+	return value
+}
+
+// parseOTLPHeaders parses comma separated key=value pairs with URL encoded
+// values into headers.
+func parseOTLPHeaders(s string, headers map[string]string) error {
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return errors.Errorf("header %q must be in the form key=value", pair)
+		}
+
+		value, err := url.PathUnescape(strings.TrimSpace(kv[1]))
+		if err != nil {
+			return errors.Wrapf(err, "header %q", kv[0])
+		}
+		headers[strings.TrimSpace(kv[0])] = value
+	}
+	return nil
+}
+
+// newOTLPTracer returns a tracer that exports spans to an OTLP collector with
+// the configured protocol. Its trace IDs are 128 bits, as required by OTLP, and
+// the service name is overridden by OTEL_SERVICE_NAME.
+func newOTLPTracer(service string, cfg otlpConfig, propagator *httpPropagator, logger jaeger.Logger) (opentracing.Tracer, io.Closer, error) {
+	if name := os.Getenv("OTEL_SERVICE_NAME"); name != "" {
+		service = name
+	}
+
+	reporterOpts := []jaeger.ReporterOption{
+		jaeger.ReporterOptions.BufferFlushInterval(time.Second),
+	}
+	if logger != nil {
+		reporterOpts = append(reporterOpts, jaeger.ReporterOptions.Logger(logger))
+	}
+
+	transport, err := newOTLPTransport(service, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tracer, closer := jaeger.NewTracer(
+		service,
+		jaeger.NewConstSampler(true),
+		jaeger.NewRemoteReporter(transport, reporterOpts...),
+		jaeger.TracerOptions.Gen128Bit(true),
+		jaeger.TracerOptions.Injector(opentracing.HTTPHeaders, propagator),
+		jaeger.TracerOptions.Extractor(opentracing.HTTPHeaders, propagator),
+	)
+	return tracer, closer, nil
+}
+
+// otlpTransport is a jaeger transport that buffers spans and exports them in
+// batches to an OTLP collector.
+type otlpTransport struct {
+	service  string
+	cfg      otlpConfig
+	exporter otlpExporter
+
+	mu    sync.Mutex
+	spans []otlpSpan
+}
+
+// otlpExporter sends batches of spans to an OTLP collector.
+type otlpExporter interface {
+	Export(ctx context.Context, traces *otlpTraces) error
+	Close() error
+}
+
+func newOTLPTransport(service string, cfg otlpConfig) (*otlpTransport, error) {
+	var (
+		exporter otlpExporter
+		err      error
+	)
+	switch cfg.Protocol {
+	case otlpProtocolGRPC:
+		exporter, err = newOTLPGRPCExporter(cfg)
+		if err != nil {
+			return nil, err
+		}
+	case otlpProtocolJSON:
+		exporter = newOTLPHTTPExporter(cfg, "application/json", func(traces *otlpTraces) ([]byte, error) {
+			return json.Marshal(traces)
+		})
+	default:
+		exporter = newOTLPHTTPExporter(cfg, "application/x-protobuf", marshalOTLPProto)
+	}
+
+	return &otlpTransport{
+		service:  service,
+		cfg:      cfg,
+		exporter: exporter,
+	}, nil
+}
+
+func (t *otlpTransport) Append(span *jaeger.Span) (int, error) {
+	t.mu.Lock()
+	t.spans = append(t.spans, newOTLPSpan(jaeger.BuildJaegerThrift(span)))
+	full := len(t.spans) >= otlpBatchSize
+	t.mu.Unlock()
+
+	if full {
+		return t.Flush()
+	}
+	return 0, nil
+}
+
+func (t *otlpTransport) Flush() (int, error) {
+	t.mu.Lock()
+	spans := t.spans
+	t.spans = nil
+	t.mu.Unlock()
+
+	if len(spans) == 0 {
+		return 0, nil
+	}
+
+	err := t.export(spans)
+	if err != nil {
+		return len(spans), err
+	}
+	return len(spans), nil
+}
+
+func (t *otlpTransport) Close() error {
+	_, err := t.Flush()
+	if cerr := t.exporter.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (t *otlpTransport) export(spans []otlpSpan) error {
+	ctx, cancel := context.WithTimeout(context.Background(), t.cfg.Timeout)
+	defer cancel()
+
+	return t.exporter.Export(ctx, &otlpTraces{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: []otlpAttribute{newOTLPAttribute("service.name", t.service)},
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: "github.com/Netflix/p2plab"},
+				Spans: spans,
+			}},
+		}},
+	})
+}
+
+// otlpHTTPExporter posts spans to an OTLP collector over HTTP, encoded by
+// marshal.
+type otlpHTTPExporter struct {
+	cfg         otlpConfig
+	client      *http.Client
+	contentType string
+	marshal     func(*otlpTraces) ([]byte, error)
+}
+
+func newOTLPHTTPExporter(cfg otlpConfig, contentType string, marshal func(*otlpTraces) ([]byte, error)) *otlpHTTPExporter {
+	return &otlpHTTPExporter{
+		cfg:         cfg,
+		client:      &http.Client{Timeout: cfg.Timeout},
+		contentType: contentType,
+		marshal:     marshal,
+	}
+}
+
+func (e *otlpHTTPExporter) Export(ctx context.Context, traces *otlpTraces) error {
+	content, err := e.marshal(traces)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", e.cfg.Endpoint, bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", e.contentType)
+	for key, value := range e.cfg.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to export spans")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("failed to export spans: %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+func (e *otlpHTTPExporter) Close() error {
+	return nil
+}
+
+// The following types are the subset of the OTLP/JSON encoding of traces used
+// by the exporter. IDs are hex encoded and 64 bit integers are strings. They
+// are converted to protobuf by marshalOTLPProto.
+
+type otlpTraces struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Events            []otlpEvent     `json:"events,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano string          `json:"timeUnixNano"`
+	Name         string          `json:"name"`
+	Attributes   []otlpAttribute `json:"attributes,omitempty"`
+}
+
+type otlpStatus struct {
+	Code int `json:"code,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+// OTLP span kinds and status codes.
+const (
+	otlpKindInternal = 1
+	otlpKindServer   = 2
+	otlpKindClient   = 3
+	otlpKindProducer = 4
+	otlpKindConsumer = 5
+
+	otlpStatusError = 2
+)
+
+// newOTLPSpan converts a span from its jaeger representation, which has
+// microsecond timestamps, to OTLP. The span.kind and error tags become the
+// kind and status of the span.
+func newOTLPSpan(js *j.Span) otlpSpan {
+	start := js.StartTime * int64(time.Microsecond)
+	span := otlpSpan{
+		TraceID:           fmt.Sprintf("%016x%016x", uint64(js.TraceIdHigh), uint64(js.TraceIdLow)),
+		SpanID:            otlpSpanID(js.SpanId),
+		Name:              js.OperationName,
+		Kind:              otlpKindInternal,
+		StartTimeUnixNano: strconv.FormatInt(start, 10),
+		EndTimeUnixNano:   strconv.FormatInt(start+js.Duration*int64(time.Microsecond), 10),
+	}
+	if js.ParentSpanId != 0 {
+		span.ParentSpanID = otlpSpanID(js.ParentSpanId)
+	}
+
+	for _, tag := range js.Tags {
+		switch {
+		case tag.Key == "span.kind" && tag.VStr != nil:
+			span.Kind = otlpKind(*tag.VStr)
+		case tag.Key == "error" && tag.VBool != nil:
+			if *tag.VBool {
+				span.Status.Code = otlpStatusError
+			}
+		default:
+			span.Attributes = append(span.Attributes, newOTLPTagAttribute(tag))
+		}
+	}
+
+	for _, log := range js.Logs {
+		event := otlpEvent{
+			TimeUnixNano: strconv.FormatInt(log.Timestamp*int64(time.Microsecond), 10),
+			Name:         "log",
+		}
+		for _, field := range log.Fields {
+			if field.Key == "event" && field.VStr != nil {
+				event.Name = *field.VStr
+				continue
+			}
+			event.Attributes = append(event.Attributes, newOTLPTagAttribute(field))
+		}
+		span.Events = append(span.Events, event)
+	}
+
+	return span
+}
+
+func otlpSpanID(id int64) string {
+	b := make([]byte, 8)
+	for i := 0; i < 8; i++ {
+		b[7-i] = byte(uint64(id) >> (8 * uint(i)))
+	}
+	return hex.EncodeToString(b)
+}
+
+func otlpKind(kind string) int {
+	switch kind {
+	case "server":
+		return otlpKindServer
+	case "client":
+		return otlpKindClient
+	case "producer":
+		return otlpKindProducer
+	case "consumer":
+		return otlpKindConsumer
+	default:
+		return otlpKindInternal
+	}
+}
+
+func newOTLPAttribute(key, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpValue{StringValue: &value}}
+}
+
+func newOTLPTagAttribute(tag *j.Tag) otlpAttribute {
+	attr := otlpAttribute{Key: tag.Key}
+	switch tag.VType {
+	case j.TagType_BOOL:
+		attr.Value.BoolValue = tag.VBool
+	case j.TagType_LONG:
+		if tag.VLong != nil {
+			s := strconv.FormatInt(*tag.VLong, 10)
+			attr.Value.IntValue = &s
+		}
+	case j.TagType_DOUBLE:
+		attr.Value.DoubleValue = tag.VDouble
+	case j.TagType_BINARY:
+		s := hex.EncodeToString(tag.VBinary)
+		attr.Value.StringValue = &s
+	default:
+		attr.Value.StringValue = tag.VStr
+	}
+	return attr
+}
@@ -0,0 +1,279 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package traceutil
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestOTLPConfigFromEnv(t *testing.T) {
+	env := func(vars map[string]string) func(string) string {
+		return func(key string) string {
+			return vars[key]
+		}
+	}
+
+	_, ok, err := otlpConfigFromEnv(env(nil))
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	cfg, ok, err := otlpConfigFromEnv(env(map[string]string{
+		"OTEL_EXPORTER_OTLP_ENDPOINT":        "http://collector:4318/",
+		"OTEL_EXPORTER_OTLP_HEADERS":         "api-key=secret,tenant=a%20b",
+		"OTEL_EXPORTER_OTLP_TRACES_HEADERS":  "tenant=c",
+		"OTEL_EXPORTER_OTLP_TRACES_PROTOCOL": "http/json",
+		"OTEL_EXPORTER_OTLP_TIMEOUT":         "500",
+	}))
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, otlpConfig{
+		Endpoint: "http://collector:4318/v1/traces",
+		Headers:  map[string]string{"api-key": "secret", "tenant": "c"},
+		Protocol: "http/json",
+		Timeout:  500 * time.Millisecond,
+	}, cfg)
+
+	cfg, _, err = otlpConfigFromEnv(env(map[string]string{
+		"OTEL_EXPORTER_OTLP_ENDPOINT":        "http://collector:4318",
+		"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT": "http://traces:4318/custom",
+	}))
+	require.NoError(t, err)
+	require.Equal(t, "http://traces:4318/custom", cfg.Endpoint)
+	require.Equal(t, otlpProtocolProtobuf, cfg.Protocol)
+	require.Equal(t, otlpDefaultTimeout, cfg.Timeout)
+
+	cfg, _, err = otlpConfigFromEnv(env(map[string]string{
+		"OTEL_EXPORTER_OTLP_ENDPOINT": "http://collector:4317",
+		"OTEL_EXPORTER_OTLP_PROTOCOL": "grpc",
+		"OTEL_EXPORTER_OTLP_INSECURE": "true",
+	}))
+	require.NoError(t, err)
+	require.Equal(t, "http://collector:4317", cfg.Endpoint)
+	require.Equal(t, otlpProtocolGRPC, cfg.Protocol)
+	require.True(t, cfg.Insecure)
+
+	for _, vars := range []map[string]string{
+		{"OTEL_EXPORTER_OTLP_PROTOCOL": "thrift"},
+		{"OTEL_EXPORTER_OTLP_HEADERS": "novalue"},
+		{"OTEL_EXPORTER_OTLP_TIMEOUT": "10s"},
+	} {
+		vars["OTEL_EXPORTER_OTLP_ENDPOINT"] = "http://collector:4318"
+		_, _, err = otlpConfigFromEnv(env(vars))
+		require.Error(t, err, "expected error for %v", vars)
+	}
+}
+
+func TestOTLPTracer(t *testing.T) {
+	received := make(chan otlpTraces, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/traces", r.URL.Path)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.Equal(t, "secret", r.Header.Get("api-key"))
+
+		var traces otlpTraces
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&traces))
+		received <- traces
+	}))
+	defer srv.Close()
+
+	tracer, closer, err := newOTLPTracer("labd", otlpConfig{
+		Endpoint: srv.URL + "/v1/traces",
+		Headers:  map[string]string{"api-key": "secret"},
+		Protocol: otlpProtocolJSON,
+		Timeout:  time.Second,
+	}, &httpPropagator{injector: w3cPropagator{}}, nil)
+	require.NoError(t, err)
+
+	parent := tracer.StartSpan("parent")
+	child := tracer.StartSpan("child", opentracing.ChildOf(parent.Context()))
+	child.SetTag("span.kind", "client")
+	child.SetTag("error", true)
+	child.SetTag("nodes", 3)
+	child.LogKV("event", "retry", "attempt", "2")
+	child.Finish()
+	parent.Finish()
+	require.NoError(t, closer.Close())
+
+	var traces otlpTraces
+	select {
+	case traces = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for spans")
+	}
+
+	require.Len(t, traces.ResourceSpans, 1)
+	rs := traces.ResourceSpans[0]
+	require.Equal(t, "service.name", rs.Resource.Attributes[0].Key)
+	require.Equal(t, "labd", *rs.Resource.Attributes[0].Value.StringValue)
+
+	spans := rs.ScopeSpans[0].Spans
+	require.Len(t, spans, 2)
+	c, p := spans[0], spans[1]
+	require.Equal(t, "child", c.Name)
+	require.Len(t, c.TraceID, 32)
+	require.Equal(t, p.TraceID, c.TraceID)
+	require.Equal(t, p.SpanID, c.ParentSpanID)
+	require.Empty(t, p.ParentSpanID)
+	require.Equal(t, otlpKindClient, c.Kind)
+	require.Equal(t, otlpStatusError, c.Status.Code)
+	require.Equal(t, otlpKindInternal, p.Kind)
+
+	var nodes *string
+	for _, attr := range c.Attributes {
+		if attr.Key == "nodes" {
+			nodes = attr.Value.IntValue
+		}
+	}
+	require.NotNil(t, nodes)
+	require.Equal(t, "3", *nodes)
+
+	require.Len(t, c.Events, 1)
+	require.Equal(t, "retry", c.Events[0].Name)
+}
+
+func TestOTLPProtobuf(t *testing.T) {
+	content, err := marshalOTLPProto(&otlpTraces{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: []otlpAttribute{newOTLPAttribute("k", "v")},
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: "s"},
+				Spans: []otlpSpan{{
+					TraceID:           "0102",
+					SpanID:            "03",
+					Name:              "n",
+					Kind:              otlpKindClient,
+					StartTimeUnixNano: "1",
+					EndTimeUnixNano:   "0",
+					Status:            otlpStatus{Code: otlpStatusError},
+				}},
+			}},
+		}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []byte{
+		0x0a, 0x2e, // resource_spans
+		0x0a, 0x0a, // resource
+		0x0a, 0x08, 0x0a, 0x01, 'k', 0x12, 0x03, 0x0a, 0x01, 'v', // attributes
+		0x12, 0x20, // scope_spans
+		0x0a, 0x03, 0x0a, 0x01, 's', // scope
+		0x12, 0x19, // spans
+		0x0a, 0x02, 0x01, 0x02, // trace_id
+		0x12, 0x01, 0x03, // span_id
+		0x2a, 0x01, 'n', // name
+		0x30, 0x03, // kind
+		0x39, 0x01, 0, 0, 0, 0, 0, 0, 0, // start_time_unix_nano
+		0x7a, 0x02, 0x18, 0x02, // status
+	}, content)
+
+	_, err = marshalOTLPProto(&otlpTraces{
+		ResourceSpans: []otlpResourceSpans{{
+			ScopeSpans: []otlpScopeSpans{{
+				Spans: []otlpSpan{{TraceID: "xyz"}},
+			}},
+		}},
+	})
+	require.Error(t, err)
+}
+
+func TestOTLPExporters(t *testing.T) {
+	received := make(chan []byte, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		content, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		received <- content
+	}))
+	defer srv.Close()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	gsrv := grpc.NewServer(grpc.CustomCodec(rawServerCodec{}), grpc.UnknownServiceHandler(func(srv interface{}, stream grpc.ServerStream) error {
+		method, _ := grpc.MethodFromServerStream(stream)
+		require.Equal(t, otlpGRPCMethod, method)
+		md, _ := metadata.FromIncomingContext(stream.Context())
+		require.Equal(t, []string{"secret"}, md.Get("api-key"))
+
+		var content []byte
+		err := stream.RecvMsg(&content)
+		if err != nil {
+			return err
+		}
+		received <- content
+		return stream.SendMsg([]byte{})
+	}))
+	go gsrv.Serve(lis)
+	defer gsrv.Stop()
+
+	for _, cfg := range []otlpConfig{
+		{Endpoint: srv.URL + "/v1/traces", Protocol: otlpProtocolProtobuf},
+		{Endpoint: "http://" + lis.Addr().String(), Protocol: otlpProtocolGRPC},
+	} {
+		cfg.Headers = map[string]string{"api-key": "secret"}
+		cfg.Timeout = 5 * time.Second
+
+		tracer, closer, err := newOTLPTracer("labd", cfg, &httpPropagator{injector: w3cPropagator{}}, nil)
+		require.NoError(t, err)
+
+		tracer.StartSpan("span").Finish()
+		require.NoError(t, closer.Close())
+
+		select {
+		case content := <-received:
+			require.Contains(t, string(content), "labd")
+			require.Contains(t, string(content), "span")
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for spans over %s", cfg.Protocol)
+		}
+	}
+}
+
+// rawServerCodec lets the test server receive the encoded request.
+type rawServerCodec struct {
+	rawCodec
+}
+
+func (rawServerCodec) String() string {
+	return "proto"
+}
+
+func TestNewUnsupportedOTLPProtocol(t *testing.T) {
+	for key, value := range map[string]string{
+		"OTEL_EXPORTER_OTLP_ENDPOINT": "http://collector:4317",
+		"OTEL_EXPORTER_OTLP_PROTOCOL": "thrift",
+	} {
+		require.NoError(t, os.Setenv(key, value))
+		defer os.Unsetenv(key)
+	}
+
+	_, tracer, closer := New(context.Background(), "labd", nil)
+	defer closer.Close()
+	require.IsType(t, opentracing.NoopTracer{}, tracer)
+}
@@ -0,0 +1,335 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package traceutil
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"net/url"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+// otlpGRPCMethod is the full name of the method of the OTLP trace service.
+const otlpGRPCMethod = "/opentelemetry.proto.collector.trace.v1.TraceService/Export"
+
+// Protobuf wire types.
+const (
+	protoVarint  = 0
+	protoFixed64 = 1
+	protoBytes   = 2
+)
+
+// otlpGRPCExporter sends spans to an OTLP collector over gRPC.
+type otlpGRPCExporter struct {
+	cfg  otlpConfig
+	conn *grpc.ClientConn
+}
+
+func newOTLPGRPCExporter(cfg otlpConfig) (*otlpGRPCExporter, error) {
+	// The endpoint may be a URL, where the scheme decides whether the
+	// connection is secure, or a bare host and port.
+	target, insecure := cfg.Endpoint, cfg.Insecure
+	u, err := url.Parse(cfg.Endpoint)
+	if err == nil && u.Host != "" {
+		target = u.Host
+		insecure = insecure || u.Scheme == "http"
+	}
+
+	opt := grpc.WithInsecure()
+	if !insecure {
+		opt = grpc.WithTransportCredentials(credentials.NewTLS(nil))
+	}
+
+	conn, err := grpc.Dial(target, opt)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to dial OTLP collector %q", target)
+	}
+
+	return &otlpGRPCExporter{
+		cfg:  cfg,
+		conn: conn,
+	}, nil
+}
+
+func (e *otlpGRPCExporter) Export(ctx context.Context, traces *otlpTraces) error {
+	content, err := marshalOTLPProto(traces)
+	if err != nil {
+		return err
+	}
+
+	ctx = metadata.NewOutgoingContext(ctx, metadata.New(e.cfg.Headers))
+
+	var resp []byte
+	err = e.conn.Invoke(ctx, otlpGRPCMethod, content, &resp, grpc.ForceCodec(rawCodec{}))
+	if err != nil {
+		return errors.Wrap(err, "failed to export spans")
+	}
+	return nil
+}
+
+func (e *otlpGRPCExporter) Close() error {
+	return e.conn.Close()
+}
+
+// rawCodec passes through messages that are already encoded as protobuf.
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	b, ok := v.([]byte)
+	if !ok {
+		return nil, errors.Errorf("unexpected message type %T", v)
+	}
+	return b, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return errors.Errorf("unexpected message type %T", v)
+	}
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+func (rawCodec) Name() string {
+	return "proto"
+}
+
+// marshalOTLPProto encodes traces as an OTLP ExportTraceServiceRequest.
+func marshalOTLPProto(traces *otlpTraces) ([]byte, error) {
+	var p protoBuffer
+	for _, rs := range traces.ResourceSpans {
+		err := p.message(1, func(p *protoBuffer) error {
+			err := p.message(1, func(p *protoBuffer) error {
+				return p.attributes(1, rs.Resource.Attributes)
+			})
+			if err != nil {
+				return err
+			}
+
+			for _, ss := range rs.ScopeSpans {
+				err = p.message(2, func(p *protoBuffer) error {
+					err := p.message(1, func(p *protoBuffer) error {
+						p.string(1, ss.Scope.Name)
+						return nil
+					})
+					if err != nil {
+						return err
+					}
+
+					for _, span := range ss.Spans {
+						err = p.message(2, func(p *protoBuffer) error {
+							return p.span(span)
+						})
+						if err != nil {
+							return err
+						}
+					}
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return p.b, nil
+}
+
+// protoBuffer is a minimal protobuf encoder. Fields with zero values are
+// omitted, as proto3 does.
+type protoBuffer struct {
+	b []byte
+}
+
+func (p *protoBuffer) tag(field, wireType int) {
+	p.varint(uint64(field)<<3 | uint64(wireType))
+}
+
+func (p *protoBuffer) varint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	p.b = append(p.b, b[:n]...)
+}
+
+func (p *protoBuffer) fixed64(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	p.tag(field, protoFixed64)
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	p.b = append(p.b, b[:]...)
+}
+
+func (p *protoBuffer) uint(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	p.tag(field, protoVarint)
+	p.varint(v)
+}
+
+func (p *protoBuffer) bytes(field int, b []byte) {
+	if len(b) == 0 {
+		return
+	}
+	p.tag(field, protoBytes)
+	p.varint(uint64(len(b)))
+	p.b = append(p.b, b...)
+}
+
+func (p *protoBuffer) string(field int, s string) {
+	p.bytes(field, []byte(s))
+}
+
+// message encodes the fields written by fn as an embedded message.
+func (p *protoBuffer) message(field int, fn func(p *protoBuffer) error) error {
+	var m protoBuffer
+	err := fn(&m)
+	if err != nil {
+		return err
+	}
+	p.tag(field, protoBytes)
+	p.varint(uint64(len(m.b)))
+	p.b = append(p.b, m.b...)
+	return nil
+}
+
+func (p *protoBuffer) hex(field int, s string) error {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return errors.Wrapf(err, "invalid id %q", s)
+	}
+	p.bytes(field, b)
+	return nil
+}
+
+func (p *protoBuffer) nanos(field int, s string) error {
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return errors.Wrapf(err, "invalid timestamp %q", s)
+	}
+	p.fixed64(field, v)
+	return nil
+}
+
+func (p *protoBuffer) span(span otlpSpan) error {
+	err := p.hex(1, span.TraceID)
+	if err != nil {
+		return err
+	}
+	err = p.hex(2, span.SpanID)
+	if err != nil {
+		return err
+	}
+	err = p.hex(4, span.ParentSpanID)
+	if err != nil {
+		return err
+	}
+	p.string(5, span.Name)
+	p.uint(6, uint64(span.Kind))
+	err = p.nanos(7, span.StartTimeUnixNano)
+	if err != nil {
+		return err
+	}
+	err = p.nanos(8, span.EndTimeUnixNano)
+	if err != nil {
+		return err
+	}
+	err = p.attributes(9, span.Attributes)
+	if err != nil {
+		return err
+	}
+
+	for _, event := range span.Events {
+		err = p.message(11, func(p *protoBuffer) error {
+			err := p.nanos(1, event.TimeUnixNano)
+			if err != nil {
+				return err
+			}
+			p.string(2, event.Name)
+			return p.attributes(3, event.Attributes)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if span.Status.Code != 0 {
+		return p.message(15, func(p *protoBuffer) error {
+			p.uint(3, uint64(span.Status.Code))
+			return nil
+		})
+	}
+	return nil
+}
+
+func (p *protoBuffer) attributes(field int, attrs []otlpAttribute) error {
+	for _, attr := range attrs {
+		err := p.message(field, func(p *protoBuffer) error {
+			p.string(1, attr.Key)
+			return p.message(2, func(p *protoBuffer) error {
+				return p.value(attr.Value)
+			})
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// value encodes the set field of an AnyValue, which is written even when it
+// holds a zero value since it is a member of a oneof.
+func (p *protoBuffer) value(v otlpValue) error {
+	switch {
+	case v.StringValue != nil:
+		p.tag(1, protoBytes)
+		p.varint(uint64(len(*v.StringValue)))
+		p.b = append(p.b, *v.StringValue...)
+	case v.BoolValue != nil:
+		p.tag(2, protoVarint)
+		if *v.BoolValue {
+			p.varint(1)
+		} else {
+			p.varint(0)
+		}
+	case v.IntValue != nil:
+		i, err := strconv.ParseInt(*v.IntValue, 10, 64)
+		if err != nil {
+			return errors.Wrapf(err, "invalid int value %q", *v.IntValue)
+		}
+		p.tag(3, protoVarint)
+		p.varint(uint64(i))
+	case v.DoubleValue != nil:
+		p.tag(4, protoFixed64)
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], math.Float64bits(*v.DoubleValue))
+		p.b = append(p.b, b[:]...)
+	}
+	return nil
+}
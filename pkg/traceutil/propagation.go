@@ -0,0 +1,172 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package traceutil
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	jaeger "github.com/uber/jaeger-client-go"
+	"github.com/uber/jaeger-client-go/zipkin"
+)
+
+// Formats of span contexts propagated in HTTP headers, selected with the
+// TRACE_PROPAGATION environment variable.
+const (
+	// PropagationJaeger is the uber-trace-id header of Jaeger.
+	PropagationJaeger = "jaeger"
+
+	// PropagationB3 is the X-B3-* headers of Zipkin.
+	PropagationB3 = "b3"
+
+	// PropagationW3C is the traceparent header of W3C Trace Context, used by
+	// OpenTelemetry.
+	PropagationW3C = "w3c"
+)
+
+const traceparentHeader = "traceparent"
+
+// httpPropagator injects span contexts into HTTP headers in one format, and
+// extracts them from any of the supported formats, trying that one first. This
+// lets a trace continue across processes whose tracers were configured with
+// different formats.
+type httpPropagator struct {
+	injector   jaeger.Injector
+	extractors []jaeger.Extractor
+}
+
+// newHTTPPropagator returns a propagator for the format in TRACE_PROPAGATION,
+// or the given default format if it is unset.
+func newHTTPPropagator(getenv func(string) string, defaultFormat string) (*httpPropagator, error) {
+	format := getenv("TRACE_PROPAGATION")
+	if format == "" {
+		format = defaultFormat
+	}
+
+	propagators := map[string]interface {
+		jaeger.Injector
+		jaeger.Extractor
+	}{
+		PropagationJaeger: jaeger.NewHTTPHeaderPropagator((&jaeger.HeadersConfig{}).ApplyDefaults(), *jaeger.NewNullMetrics()),
+		PropagationB3:     zipkin.NewZipkinB3HTTPHeaderPropagator(),
+		PropagationW3C:    w3cPropagator{},
+	}
+
+	propagator, ok := propagators[strings.ToLower(format)]
+	if !ok {
+		return nil, errors.Errorf("unsupported TRACE_PROPAGATION %q, must be one of %s, %s or %s", format, PropagationJaeger, PropagationB3, PropagationW3C)
+	}
+
+	p := &httpPropagator{
+		injector:   propagator,
+		extractors: []jaeger.Extractor{propagator},
+	}
+	for _, name := range []string{PropagationW3C, PropagationB3, PropagationJaeger} {
+		if name != strings.ToLower(format) {
+			p.extractors = append(p.extractors, propagators[name])
+		}
+	}
+	return p, nil
+}
+
+func (p *httpPropagator) Inject(sc jaeger.SpanContext, carrier interface{}) error {
+	return p.injector.Inject(sc, carrier)
+}
+
+func (p *httpPropagator) Extract(carrier interface{}) (jaeger.SpanContext, error) {
+	for _, extractor := range p.extractors {
+		sc, err := extractor.Extract(carrier)
+		if err != opentracing.ErrSpanContextNotFound {
+			return sc, err
+		}
+	}
+	return jaeger.SpanContext{}, opentracing.ErrSpanContextNotFound
+}
+
+// w3cPropagator propagates span contexts in the traceparent header of W3C
+// Trace Context. Baggage is not propagated.
+type w3cPropagator struct{}
+
+func (w3cPropagator) Inject(sc jaeger.SpanContext, carrier interface{}) error {
+	writer, ok := carrier.(opentracing.TextMapWriter)
+	if !ok {
+		return opentracing.ErrInvalidCarrier
+	}
+
+	flags := 0
+	if sc.IsSampled() {
+		flags = 1
+	}
+	traceID := sc.TraceID()
+	writer.Set(traceparentHeader, fmt.Sprintf("00-%016x%016x-%016x-%02x", traceID.High, traceID.Low, uint64(sc.SpanID()), flags))
+	return nil
+}
+
+func (w3cPropagator) Extract(carrier interface{}) (jaeger.SpanContext, error) {
+	reader, ok := carrier.(opentracing.TextMapReader)
+	if !ok {
+		return jaeger.SpanContext{}, opentracing.ErrInvalidCarrier
+	}
+
+	var traceparent string
+	err := reader.ForeachKey(func(key, value string) error {
+		if strings.ToLower(key) == traceparentHeader {
+			traceparent = value
+		}
+		return nil
+	})
+	if err != nil {
+		return jaeger.SpanContext{}, err
+	}
+	if traceparent == "" {
+		return jaeger.SpanContext{}, opentracing.ErrSpanContextNotFound
+	}
+
+	return parseTraceparent(traceparent)
+}
+
+// parseTraceparent parses a traceparent header of the form
+// "<version>-<trace-id>-<parent-id>-<flags>".
+func parseTraceparent(s string) (jaeger.SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return jaeger.SpanContext{}, opentracing.ErrSpanContextCorrupted
+	}
+	// Version 00 has exactly four parts, later versions may append more.
+	if parts[0] == "00" && len(parts) != 4 {
+		return jaeger.SpanContext{}, opentracing.ErrSpanContextCorrupted
+	}
+
+	var values [4]uint64
+	for i, hex := range []string{parts[1][:16], parts[1][16:], parts[2], parts[3]} {
+		v, err := strconv.ParseUint(hex, 16, 64)
+		if err != nil {
+			return jaeger.SpanContext{}, opentracing.ErrSpanContextCorrupted
+		}
+		values[i] = v
+	}
+
+	traceID := jaeger.TraceID{High: values[0], Low: values[1]}
+	spanID := jaeger.SpanID(values[2])
+	if !traceID.IsValid() || spanID == 0 {
+		return jaeger.SpanContext{}, opentracing.ErrSpanContextCorrupted
+	}
+
+	sampled := values[3]&1 == 1
+	return jaeger.NewSpanContext(traceID, spanID, 0, sampled, nil), nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package traceutil
+
+import (
+	"net/http"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/require"
+	jaeger "github.com/uber/jaeger-client-go"
+)
+
+func TestHTTPPropagator(t *testing.T) {
+	env := func(format string) func(string) string {
+		return func(key string) string {
+			if key == "TRACE_PROPAGATION" {
+				return format
+			}
+			return ""
+		}
+	}
+
+	traceID := jaeger.TraceID{High: 0x0af7651916cd43dd, Low: 0x8448eb211c80319c}
+	sc := jaeger.NewSpanContext(traceID, jaeger.SpanID(0xb7ad6b7169203331), 0, true, nil)
+
+	for format, header := range map[string]string{
+		PropagationW3C:    "Traceparent",
+		PropagationB3:     "X-B3-Traceid",
+		PropagationJaeger: "Uber-Trace-Id",
+	} {
+		p, err := newHTTPPropagator(env(format), PropagationJaeger)
+		require.NoError(t, err)
+
+		h := make(http.Header)
+		require.NoError(t, p.Inject(sc, opentracing.HTTPHeadersCarrier(h)))
+		require.NotEmpty(t, h.Get(header), "expected %s header for %s", header, format)
+
+		// Any propagator extracts span contexts of every format.
+		other, err := newHTTPPropagator(env(""), PropagationJaeger)
+		require.NoError(t, err)
+
+		extracted, err := other.Extract(opentracing.HTTPHeadersCarrier(h))
+		require.NoError(t, err)
+		require.Equal(t, traceID, extracted.TraceID())
+		require.Equal(t, sc.SpanID(), extracted.SpanID())
+		require.True(t, extracted.IsSampled())
+	}
+
+	p, err := newHTTPPropagator(env(""), PropagationW3C)
+	require.NoError(t, err)
+
+	h := make(http.Header)
+	require.NoError(t, p.Inject(sc, opentracing.HTTPHeadersCarrier(h)))
+	require.Equal(t, "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01", h.Get("traceparent"))
+
+	_, err = p.Extract(opentracing.HTTPHeadersCarrier(make(http.Header)))
+	require.Equal(t, opentracing.ErrSpanContextNotFound, err)
+
+	_, err = newHTTPPropagator(env("grpc"), PropagationW3C)
+	require.Error(t, err)
+}
+
+func TestParseTraceparent(t *testing.T) {
+	sc, err := parseTraceparent("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-00")
+	require.NoError(t, err)
+	require.Equal(t, "af7651916cd43dd8448eb211c80319c", sc.TraceID().String())
+	require.False(t, sc.IsSampled())
+
+	for _, s := range []string{
+		"",
+		"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331",
+		"00-00000000000000000000000000000000-b7ad6b7169203331-01",
+		"00-0af7651916cd43dd8448eb211c80319c-0000000000000000-01",
+		"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01-extra",
+		"ff-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+		"00-0af7651916cd43dd8448eb211c80319z-b7ad6b7169203331-01",
+	} {
+		_, err := parseTraceparent(s)
+		require.Equal(t, opentracing.ErrSpanContextCorrupted, err, "expected %q to be corrupted", s)
+	}
+
+	_, err = parseTraceparent("01-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01-future")
+	require.NoError(t, err)
+}
@@ -18,12 +18,15 @@ import (
 	"context"
 	"io"
 	"log"
+	"math"
 	"os"
+	"strconv"
 	"time"
 
 	opentracing "github.com/opentracing/opentracing-go"
 	jaeger "github.com/uber/jaeger-client-go"
 	"github.com/uber/jaeger-client-go/config"
+	zipkintransport "github.com/uber/jaeger-client-go/transport/zipkin"
 )
 
 type tracerKey struct{}
@@ -44,32 +47,177 @@ func StartSpanFromContext(ctx context.Context, operationName string, opts ...ope
 	return opentracing.StartSpanFromContextWithTracer(ctx, Tracer(ctx), operationName, opts...)
 }
 
+// TraceID returns the ID of the trace of the span in ctx, or an empty string
+// if there is no span or it is not traced by jaeger.
+func TraceID(ctx context.Context) string {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return ""
+	}
+
+	sc, ok := span.Context().(jaeger.SpanContext)
+	if !ok || !sc.IsValid() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
+
+// New returns a context with a tracer for the service, picked in order of
+// precedence from the environment:
+//
+//  1. OTLP, if OTEL_EXPORTER_OTLP_TRACES_ENDPOINT or OTEL_EXPORTER_OTLP_ENDPOINT
+//     is set. Spans are exported over gRPC, or over HTTP as protobuf or JSON,
+//     selected by OTEL_EXPORTER_OTLP_PROTOCOL and configured by the standard
+//     OTEL_EXPORTER_OTLP_* variables for headers, insecure and timeout. If
+//     they are invalid, such as an unknown protocol, a warning is logged and
+//     the OTLP exporter is disabled.
+//  2. Jaeger, if JAEGER_TRACE is set to the address of a Jaeger agent.
+//  3. Zipkin, if ZIPKIN_TRACE is set to the URL of a Zipkin collector.
+//  4. A noop tracer otherwise.
+//
+// Span contexts are propagated over HTTP in the format of TRACE_PROPAGATION,
+// one of jaeger, b3 or w3c, defaulting to the native format of the tracer: w3c
+// for OTLP, jaeger for Jaeger and b3 for Zipkin. Incoming span contexts are
+// accepted in any of these formats.
 func New(ctx context.Context, service string, logger jaeger.Logger) (context.Context, opentracing.Tracer, io.Closer) {
-	tracerAddr := os.Getenv("JAEGER_TRACE")
-	if tracerAddr != "" {
-		cfg := config.Configuration{
-			Sampler: &config.SamplerConfig{
-				Type:  "const",
-				Param: 1,
-			},
-			Reporter: &config.ReporterConfig{
-				BufferFlushInterval: time.Second,
-				LocalAgentHostPort:  tracerAddr,
-			},
+	var (
+		tracer opentracing.Tracer
+		closer io.Closer
+		err    error
+	)
+
+	otlpCfg, otlp, err := otlpConfigFromEnv(os.Getenv)
+	if err != nil {
+		warnf(logger, "Disabling OTLP exporter: %s", err)
+		otlp = false
+	}
+
+	jaegerAddr := os.Getenv("JAEGER_TRACE")
+	zipkinAddr := os.Getenv("ZIPKIN_TRACE")
+
+	var format string
+	switch {
+	case otlp:
+		format = PropagationW3C
+	case jaegerAddr != "":
+		format = PropagationJaeger
+	case zipkinAddr != "":
+		format = PropagationB3
+	default:
+		return ctx, opentracing.NoopTracer{}, &nopCloser{}
+	}
+
+	propagator, err := newHTTPPropagator(os.Getenv, format)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch {
+	case otlp:
+		if jaegerAddr != "" || zipkinAddr != "" {
+			warnf(logger, "OTLP exporter endpoint is set, ignoring JAEGER_TRACE and ZIPKIN_TRACE")
 		}
-		tracer, closer, err := cfg.New(
-			service,
-			config.Logger(logger),
-		)
-		if err != nil {
-			log.Fatal(err)
+
+		tracer, closer, err = newOTLPTracer(service, otlpCfg, propagator, logger)
+	case jaegerAddr != "":
+		if zipkinAddr != "" {
+			warnf(logger, "Both JAEGER_TRACE and ZIPKIN_TRACE are set, ignoring ZIPKIN_TRACE")
 		}
 
-		ctx = WithTracer(ctx, tracer)
-		return ctx, tracer, closer
+		tracer, closer, err = newJaegerTracer(service, jaegerAddr, os.Getenv("JAEGER_SAMPLE_RATE"), propagator, logger)
+	default:
+		tracer, closer, err = newZipkinTracer(service, zipkinAddr, propagator, logger)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx = WithTracer(ctx, tracer)
+	return ctx, tracer, closer
+}
+
+func newJaegerTracer(service, addr, sampleRate string, propagator *httpPropagator, logger jaeger.Logger) (opentracing.Tracer, io.Closer, error) {
+	cfg := config.Configuration{
+		Sampler: newSamplerConfig(sampleRate, logger),
+		Reporter: &config.ReporterConfig{
+			BufferFlushInterval: time.Second,
+			LocalAgentHostPort:  addr,
+		},
 	}
+	return cfg.New(
+		service,
+		config.Logger(logger),
+		config.Injector(opentracing.HTTPHeaders, propagator),
+		config.Extractor(opentracing.HTTPHeaders, propagator),
+	)
+}
+
+// newSamplerConfig returns a probabilistic sampler if sampleRate is a valid
+// float, otherwise it falls back to sampling every trace.
+func newSamplerConfig(sampleRate string, logger jaeger.Logger) *config.SamplerConfig {
+	constSampler := &config.SamplerConfig{
+		Type:  jaeger.SamplerTypeConst,
+		Param: 1,
+	}
+	if sampleRate == "" {
+		return constSampler
+	}
+
+	rate, err := strconv.ParseFloat(sampleRate, 64)
+	if err != nil {
+		warnf(logger, "Invalid JAEGER_SAMPLE_RATE %q, sampling every trace", sampleRate)
+		return constSampler
+	}
+
+	if rate < 0 || rate > 1 {
+		clamped := math.Max(0, math.Min(1, rate))
+		warnf(logger, "JAEGER_SAMPLE_RATE %v is outside [0, 1], clamping to %v", rate, clamped)
+		rate = clamped
+	}
+
+	return &config.SamplerConfig{
+		Type:  jaeger.SamplerTypeProbabilistic,
+		Param: rate,
+	}
+}
 
-	return ctx, opentracing.NoopTracer{}, &nopCloser{}
+// newZipkinTracer returns a tracer that reports spans to a Zipkin collector
+// over HTTP, e.g. http://localhost:9411/api/v1/spans.
+func newZipkinTracer(service, addr string, propagator *httpPropagator, logger jaeger.Logger) (opentracing.Tracer, io.Closer, error) {
+	var opts []zipkintransport.HTTPOption
+	if logger != nil {
+		opts = append(opts, zipkintransport.HTTPLogger(logger))
+	}
+
+	transport, err := zipkintransport.NewHTTPTransport(addr, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reporterOpts := []jaeger.ReporterOption{
+		jaeger.ReporterOptions.BufferFlushInterval(time.Second),
+	}
+	if logger != nil {
+		reporterOpts = append(reporterOpts, jaeger.ReporterOptions.Logger(logger))
+	}
+
+	tracer, closer := jaeger.NewTracer(
+		service,
+		jaeger.NewConstSampler(true),
+		jaeger.NewRemoteReporter(transport, reporterOpts...),
+		jaeger.TracerOptions.Injector(opentracing.HTTPHeaders, propagator),
+		jaeger.TracerOptions.Extractor(opentracing.HTTPHeaders, propagator),
+		jaeger.TracerOptions.ZipkinSharedRPCSpan(true),
+	)
+	return tracer, closer, nil
+}
+
+func warnf(logger jaeger.Logger, format string, args ...interface{}) {
+	if logger == nil {
+		log.Printf(format, args...)
+		return
+	}
+	logger.Infof(format, args...)
 }
 
 type nopCloser struct{}
@@ -0,0 +1,151 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package printer
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Netflix/p2plab/metadata"
+)
+
+// columns returns the column names for the tabular printers.
+func columns(v interface{}) []string {
+	switch t := v.(type) {
+	case Projection:
+		return t.columns()
+	case metadata.Cluster:
+		return []string{"ID", "STATUS", "SIZE", "EXPIRES", "LABELS", "CREATEDAT", "UPDATEDAT"}
+	case metadata.Node:
+		return []string{"ID", "ADDRESS", "GITREFERENCE", "LABELS", "CREATEDAT", "UPDATEDAT"}
+	case metadata.Scenario:
+		return []string{"ID", "LABELS", "CREATEDAT", "UPDATEDAT"}
+	case metadata.Benchmark:
+		return []string{"ID", "STATUS", "CLUSTER", "SCENARIO", "LABELS", "CREATEDAT", "UPDATEDAT"}
+	case metadata.Experiment:
+		return []string{"ID", "STATUS", "LABELS", "CREATEDAT", "UPDATEDAT"}
+	case metadata.HealthComponent:
+		return []string{"COMPONENT", "STATUS", "MESSAGE"}
+	case metadata.Artifact:
+		return []string{"NAME", "SIZE", "DIGEST", "CREATEDAT"}
+	case metadata.AuditRecord:
+		return []string{"TIME", "PRINCIPAL", "OPERATION", "RESOURCE", "OUTCOME"}
+	case metadata.PeerStats:
+		return []string{"REPOSIZE", "PINNED", "PEERS", "ROUTINGTABLE", "TOTALIN", "TOTALOUT", "RATEIN", "RATEOUT"}
+	default:
+		return nil
+	}
+}
+
+// values returns the column values for the tabular printers, formatting
+// timestamps with formatTime.
+func values(v interface{}, formatTime func(time.Time) string) []string {
+	switch t := v.(type) {
+	case Projection:
+		return t.values(formatTime)
+	case metadata.Cluster:
+		return []string{
+			t.ID,
+			string(t.Status),
+			strconv.Itoa(t.Definition.Size()),
+			formatExpiry(t.ExpiresAt, formatTime),
+			strings.Join(t.Labels, ","),
+			formatTime(t.CreatedAt),
+			formatTime(t.UpdatedAt),
+		}
+	case metadata.Node:
+		return []string{
+			t.ID,
+			t.Address,
+			t.Peer.GitReference,
+			strings.Join(t.Labels, ","),
+			formatTime(t.CreatedAt),
+			formatTime(t.UpdatedAt),
+		}
+	case metadata.Scenario:
+		return []string{
+			t.ID,
+			strings.Join(t.Labels, ","),
+			formatTime(t.CreatedAt),
+			formatTime(t.UpdatedAt),
+		}
+	case metadata.Benchmark:
+		return []string{
+			t.ID,
+			string(t.Status),
+			t.Cluster.ID,
+			t.Scenario.ID,
+			strings.Join(t.Labels, ","),
+			formatTime(t.CreatedAt),
+			formatTime(t.UpdatedAt),
+		}
+	case metadata.Experiment:
+		return []string{
+			t.ID,
+			string(t.Status),
+			strings.Join(t.Labels, ","),
+			formatTime(t.CreatedAt),
+			formatTime(t.UpdatedAt),
+		}
+	case metadata.HealthComponent:
+		return []string{
+			t.Name,
+			string(t.Status),
+			t.Message,
+		}
+	case metadata.Artifact:
+		return []string{
+			t.Name,
+			strconv.FormatInt(t.Size, 10),
+			t.Digest.String(),
+			formatTime(t.CreatedAt),
+		}
+	case metadata.AuditRecord:
+		principal := t.Principal
+		if principal == "" {
+			principal = t.RemoteAddr
+		}
+		return []string{
+			formatTime(t.Time),
+			principal,
+			t.Operation,
+			t.Resource,
+			t.Outcome,
+		}
+	case metadata.PeerStats:
+		return []string{
+			strconv.FormatUint(t.RepoSize, 10),
+			strconv.Itoa(t.Pinned),
+			strconv.Itoa(t.Peers),
+			strconv.Itoa(t.RoutingTableSize),
+			strconv.FormatInt(t.Bandwidth.TotalIn, 10),
+			strconv.FormatInt(t.Bandwidth.TotalOut, 10),
+			strconv.FormatFloat(t.Bandwidth.RateIn, 'f', 0, 64),
+			strconv.FormatFloat(t.Bandwidth.RateOut, 'f', 0, 64),
+		}
+	default:
+		return nil
+	}
+}
+
+// formatExpiry formats the expiry of a resource, which is empty if the
+// resource never expires.
+func formatExpiry(t time.Time, formatTime func(time.Time) string) string {
+	if t.IsZero() {
+		return ""
+	}
+	return formatTime(t)
+}
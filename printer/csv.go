@@ -0,0 +1,187 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package printer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Netflix/p2plab/metadata"
+)
+
+type csvPrinter struct{}
+
+func NewCSVPrinter() Printer {
+	return &csvPrinter{}
+}
+
+func (p *csvPrinter) Print(v interface{}) error {
+	w := csv.NewWriter(os.Stdout)
+
+	var err error
+	switch t := v.(type) {
+	case []interface{}:
+		if len(t) == 0 {
+			return nil
+		}
+		err = p.write(w, t)
+	case Page:
+		return p.Print(t.Items)
+	case metadata.Report:
+		err = p.writeReport(w, t)
+	default:
+		err = p.write(w, []interface{}{t})
+	}
+	if err != nil {
+		return err
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+func (p *csvPrinter) write(w *csv.Writer, l []interface{}) error {
+	header := columns(l[0])
+	if header == nil {
+		return p.writeFlattened(w, l)
+	}
+
+	err := w.Write(header)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range l {
+		err = w.Write(values(e, formatRFC3339))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFlattened writes records without known columns by flattening their
+// JSON representation into dotted column names.
+func (p *csvPrinter) writeFlattened(w *csv.Writer, l []interface{}) error {
+	var (
+		rows   []map[string]string
+		keySet = make(map[string]struct{})
+	)
+	for _, e := range l {
+		content, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+
+		var obj interface{}
+		err = json.Unmarshal(content, &obj)
+		if err != nil {
+			return err
+		}
+
+		row := make(map[string]string)
+		flatten(row, "", obj)
+		for k := range row {
+			keySet[k] = struct{}{}
+		}
+		rows = append(rows, row)
+	}
+
+	var header []string
+	for k := range keySet {
+		header = append(header, k)
+	}
+	sort.Strings(header)
+
+	err := w.Write(header)
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		record := make([]string, len(header))
+		for i, k := range header {
+			record[i] = row[k]
+		}
+
+		err = w.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *csvPrinter) writeReport(w *csv.Writer, report metadata.Report) error {
+	err := w.Write([]string{"QUERY", "NODE", "TOTALIN", "TOTALOUT", "RATEIN", "RATEOUT", "BLOCKSRECV", "BLOCKSSENT", "DUPBLOCKS", "DATARECV", "DATASENT", "DUPDATA"})
+	if err != nil {
+		return err
+	}
+
+	qryBuckets, nodeIdsByQryBucket := sortQueryBuckets(report)
+	for _, qryBucket := range qryBuckets {
+		for _, nodeId := range nodeIdsByQryBucket[qryBucket] {
+			totals := report.Nodes[nodeId].Bandwidth.Totals
+			bswap := report.Nodes[nodeId].Bitswap
+			err = w.Write([]string{
+				qryBucket,
+				nodeId,
+				strconv.FormatInt(totals.TotalIn, 10),
+				strconv.FormatInt(totals.TotalOut, 10),
+				strconv.FormatFloat(totals.RateIn, 'f', -1, 64),
+				strconv.FormatFloat(totals.RateOut, 'f', -1, 64),
+				strconv.FormatUint(bswap.BlocksReceived, 10),
+				strconv.FormatUint(bswap.BlocksSent, 10),
+				strconv.FormatUint(bswap.DupBlksReceived, 10),
+				strconv.FormatUint(bswap.DataReceived, 10),
+				strconv.FormatUint(bswap.DataSent, 10),
+				strconv.FormatUint(bswap.DupDataReceived, 10),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func flatten(row map[string]string, prefix string, v interface{}) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, e := range t {
+			key := k
+			if prefix != "" {
+				key = fmt.Sprintf("%s.%s", prefix, k)
+			}
+			flatten(row, key, e)
+		}
+	case []interface{}:
+		content, _ := json.Marshal(t)
+		row[prefix] = string(content)
+	case nil:
+		row[prefix] = ""
+	default:
+		row[prefix] = fmt.Sprint(t)
+	}
+}
+
+func formatRFC3339(t time.Time) string {
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,64 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package printer
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Netflix/p2plab/metadata"
+	humanize "github.com/dustin/go-humanize"
+	"github.com/olekukonko/tablewriter"
+)
+
+func printReportDiff(diff metadata.ReportDiff) error {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAutoFormatHeaders(false)
+	table.SetHeader([]string{"METRIC", diff.A, diff.B, "DELTA", ""})
+
+	for _, m := range diff.Metrics {
+		delta := "-"
+		if m.Delta != nil {
+			delta = fmt.Sprintf("%+.1f%%", *m.Delta)
+		}
+
+		var regression string
+		if m.Regression {
+			regression = "REGRESSION"
+		}
+
+		table.Append([]string{
+			m.Name,
+			formatMetric(m.Unit, m.A),
+			formatMetric(m.Unit, m.B),
+			delta,
+			regression,
+		})
+	}
+	table.Render()
+	return nil
+}
+
+func formatMetric(unit metadata.ReportMetricUnit, v float64) string {
+	switch unit {
+	case metadata.ReportMetricDuration:
+		return time.Duration(v).Round(time.Millisecond).String()
+	case metadata.ReportMetricBytes:
+		return humanize.Bytes(uint64(v))
+	default:
+		return humanize.Ftoa(v)
+	}
+}
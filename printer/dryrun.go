@@ -0,0 +1,125 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package printer
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/alecthomas/template"
+	humanize "github.com/dustin/go-humanize"
+	"github.com/olekukonko/tablewriter"
+)
+
+var (
+	DryRunTemplate = template.Must(template.New("dryrun").Parse(`Dry run, nothing executed.
+
+Benchmarking scenario {{.Scenario}} on cluster {{.Cluster}} ({{.Nodes}} nodes) would:
+
+# Transform objects
+{{.ObjectsTable}}
+# Match queries
+{{.QueriesTable}}
+# Execute tasks
+{{if .Steps}}{{range .Steps}}Step {{.}}
+{{end}}{{else}}Seed stage: {{.Seed}} nodes
+Benchmark stage: {{.Benchmark}} nodes
+{{end}}{{if .Seeders}}Seeders: {{.Seeders}}
+Leechers: {{.Leechers}}
+{{end}}`))
+)
+
+type DryRunData struct {
+	Cluster      string
+	Scenario     string
+	Nodes        int
+	ObjectsTable string
+	QueriesTable string
+	Seed         int
+	Benchmark    int
+	Steps        []string
+	Seeders      string
+	Leechers     string
+}
+
+func printDryRun(dryRun metadata.DryRun) error {
+	data := DryRunData{
+		Cluster:      dryRun.Cluster,
+		Scenario:     dryRun.Scenario,
+		Nodes:        dryRun.Nodes,
+		ObjectsTable: printDryRunObjects(dryRun),
+		QueriesTable: printDryRunQueries(dryRun),
+		Seed:         len(dryRun.Seed),
+		Benchmark:    len(dryRun.Benchmark),
+		Seeders:      strings.Join(dryRun.Seeders, ","),
+		Leechers:     strings.Join(dryRun.Leechers, ","),
+	}
+
+	for _, step := range dryRun.Steps {
+		line := fmt.Sprintf("%s: %d nodes", step.Name, len(step.Tasks))
+		if len(step.DependsOn) > 0 {
+			line += fmt.Sprintf(", after %s", strings.Join(step.DependsOn, ", "))
+		}
+		data.Steps = append(data.Steps, line)
+	}
+
+	return DryRunTemplate.Execute(os.Stdout, &data)
+}
+
+func printDryRunObjects(dryRun metadata.DryRun) string {
+	buf := new(bytes.Buffer)
+	table := tablewriter.NewWriter(buf)
+	table.SetAutoFormatHeaders(false)
+	table.SetHeader([]string{"OBJECT", "CID", "SIZE"})
+
+	var names []string
+	for name := range dryRun.Objects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		object := dryRun.Objects[name]
+		table.Append([]string{name, object.CID, humanize.Bytes(object.Size)})
+	}
+
+	table.Render()
+	return buf.String()
+}
+
+func printDryRunQueries(dryRun metadata.DryRun) string {
+	buf := new(bytes.Buffer)
+	table := tablewriter.NewWriter(buf)
+	table.SetAutoFormatHeaders(false)
+	table.SetHeader([]string{"QUERY", "NODES"})
+
+	var queries []string
+	for q := range dryRun.Queries {
+		queries = append(queries, q)
+	}
+	sort.Strings(queries)
+
+	for _, q := range queries {
+		table.Append([]string{q, strconv.Itoa(len(dryRun.Queries[q]))})
+	}
+
+	table.Render()
+	return buf.String()
+}
@@ -35,6 +35,12 @@ func (p *idPrinter) Print(v interface{}) error {
 				return err
 			}
 		}
+	case Page:
+		return p.Print(t.Items)
+	case Projection:
+		if len(t.Values) > 0 {
+			fmt.Println(t.Values[0])
+		}
 	case metadata.Cluster:
 		fmt.Printf("%s\n", t.ID)
 	case metadata.Node:
@@ -0,0 +1,62 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package printer
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// jsonlPrinter prints one compact JSON object per line, so that output can be
+// processed before it is complete.
+type jsonlPrinter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func NewJSONLPrinter() Printer {
+	return &jsonlPrinter{
+		enc: json.NewEncoder(os.Stdout),
+	}
+}
+
+func (p *jsonlPrinter) Print(v interface{}) error {
+	switch t := v.(type) {
+	case []interface{}:
+		for _, e := range t {
+			err := p.PrintRecord(e)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case Page:
+		err := p.Print(t.Items)
+		if err != nil {
+			return err
+		}
+		printPageFooter(os.Stderr, t)
+		return nil
+	default:
+		return p.PrintRecord(t)
+	}
+}
+
+func (p *jsonlPrinter) PrintRecord(v interface{}) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.enc.Encode(v)
+}
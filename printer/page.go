@@ -0,0 +1,34 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package printer
+
+import (
+	"fmt"
+	"io"
+)
+
+// Page is a page of results from a paginated list. Structured printers print
+// the page as is, while the others print its items followed by a footer when
+// more results are available.
+type Page struct {
+	Items         []interface{} `json:"items"`
+	NextPageToken string        `json:"nextPageToken,omitempty"`
+}
+
+func printPageFooter(w io.Writer, page Page) {
+	if page.NextPageToken != "" {
+		fmt.Fprintf(w, "More results available, continue with --page-token %s\n", page.NextPageToken)
+	}
+}
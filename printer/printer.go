@@ -23,6 +23,15 @@ type Printer interface {
 	Print(v interface{}) error
 }
 
+// StreamPrinter is a printer that prints records one at a time as they
+// arrive, instead of buffering a whole list.
+type StreamPrinter interface {
+	Printer
+
+	// PrintRecord prints a single record of a stream.
+	PrintRecord(v interface{}) error
+}
+
 type OutputType string
 
 var (
@@ -31,16 +40,43 @@ var (
 	OutputID    OutputType = "id"
 	OutputUnix  OutputType = "unix"
 	OutputJSON  OutputType = "json"
+	OutputJSONL OutputType = "jsonl"
+	OutputYAML  OutputType = "yaml"
+	OutputCSV   OutputType = "csv"
+
+	OutputTemplate OutputType = "go-template"
 )
 
-func GetPrinter(output, auto OutputType) (Printer, error) {
+type PrinterOption func(*PrinterSettings) error
+
+type PrinterSettings struct {
+	Format string
+}
+
+// WithFormat sets the template used by the go-template printer.
+func WithFormat(format string) PrinterOption {
+	return func(s *PrinterSettings) error {
+		s.Format = format
+		return nil
+	}
+}
+
+func GetPrinter(output, auto OutputType, opts ...PrinterOption) (Printer, error) {
+	var settings PrinterSettings
+	for _, opt := range opts {
+		err := opt(&settings)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	var p Printer
 	switch output {
 	case OutputAuto:
 		if auto == OutputAuto {
 			return nil, errors.Wrap(errdefs.ErrInvalidArgument, "auto printer cannot be auto")
 		}
-		return GetPrinter(auto, "")
+		return GetPrinter(auto, "", opts...)
 	case OutputTable:
 		p = NewTablePrinter()
 	case OutputID:
@@ -49,6 +85,17 @@ func GetPrinter(output, auto OutputType) (Printer, error) {
 		p = NewUnixPrinter()
 	case OutputJSON:
 		p = NewJSONPrinter()
+	case OutputJSONL:
+		p = NewJSONLPrinter()
+	case OutputYAML:
+		p = NewYAMLPrinter()
+	case OutputCSV:
+		p = NewCSVPrinter()
+	case OutputTemplate:
+		if settings.Format == "" {
+			return nil, errors.Wrap(errdefs.ErrInvalidArgument, "go-template printer requires a format")
+		}
+		return NewTemplatePrinter(settings.Format)
 	default:
 		return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "output %q is not valid", output)
 	}
@@ -0,0 +1,64 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package printer
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/olekukonko/tablewriter"
+	"golang.org/x/term"
+)
+
+// printProgress prints the progress of a benchmark. When stdout is a
+// terminal, the previous progress of lines lines is redrawn in place. It
+// returns the number of lines printed.
+func printProgress(progress metadata.BenchmarkProgress, lines int) (int, error) {
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "Benchmark %s is %s: %d/%d tasks (%.0f%%)\n", progress.ID, progress.Status, progress.Completed, progress.Total, progress.Percent())
+
+	if len(progress.Nodes) > 0 {
+		table := tablewriter.NewWriter(buf)
+		table.SetAutoFormatHeaders(false)
+		table.SetHeader([]string{"NODE", "STAGE", "STATUS", "ERROR"})
+
+		var ids []string
+		for id := range progress.Nodes {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		for _, id := range ids {
+			node := progress.Nodes[id]
+			table.Append([]string{id, node.Stage, string(node.Status), node.Error})
+		}
+		table.Render()
+	}
+
+	if lines > 0 && term.IsTerminal(int(os.Stdout.Fd())) {
+		// Move the cursor up to the previous progress and clear it.
+		fmt.Printf("\033[%dA\033[J", lines)
+	}
+
+	_, err := os.Stdout.Write(buf.Bytes())
+	if err != nil {
+		return 0, err
+	}
+
+	return bytes.Count(buf.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package printer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Netflix/p2plab/metadata"
+)
+
+// Projection is a resource projected to a subset of its fields. It marshals
+// to a JSON object with the fields in order.
+type Projection struct {
+	Fields []string
+	Values []interface{}
+}
+
+// Project projects every resource in l to the fields with the given names.
+func Project(l []interface{}, names []string) ([]interface{}, error) {
+	projections := make([]interface{}, len(l))
+	for i, v := range l {
+		fields, err := metadata.LookupFields(v, names)
+		if err != nil {
+			return nil, err
+		}
+
+		p := Projection{Fields: names}
+		for _, field := range fields {
+			p.Values = append(p.Values, field.Value(v))
+		}
+		projections[i] = p
+	}
+	return projections, nil
+}
+
+func (p Projection) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range p.Fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		key, err := json.Marshal(field)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := json.Marshal(p.Values[i])
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Map returns the projection as a map from field names to values.
+func (p Projection) Map() map[string]interface{} {
+	m := make(map[string]interface{})
+	for i, field := range p.Fields {
+		m[field] = p.Values[i]
+	}
+	return m
+}
+
+func (p Projection) columns() []string {
+	var header []string
+	for _, field := range p.Fields {
+		header = append(header, strings.ToUpper(field))
+	}
+	return header
+}
+
+func (p Projection) values(formatTime func(time.Time) string) []string {
+	var row []string
+	for _, value := range p.Values {
+		switch t := value.(type) {
+		case time.Time:
+			row = append(row, formatTime(t))
+		case []string:
+			row = append(row, strings.Join(t, ","))
+		default:
+			row = append(row, fmt.Sprint(t))
+		}
+	}
+	return row
+}
@@ -31,7 +31,10 @@ import (
 var (
 	ReportTemplate = template.Must(template.New("report").Parse(`# Summary
 Total time: {{.TotalTime}}
-Trace: {{.Trace}}
+{{if .TimeToFirstBlock}}Time to first block: {{.TimeToFirstBlock}}
+{{end}}{{if .BlockLatency}}Block latency: {{.BlockLatency}}
+{{end}}{{if .TaskDuration}}Task duration: {{.TaskDuration}}
+{{end}}Trace: {{.Trace}}
 
 # Bandwidth
 {{.BandwidthTable}}
@@ -40,10 +43,13 @@ Trace: {{.Trace}}
 )
 
 type ReportData struct {
-	TotalTime      string
-	Trace          string
-	BandwidthTable string
-	BitswapTable   string
+	TotalTime        string
+	TimeToFirstBlock string
+	BlockLatency     string
+	TaskDuration     string
+	Trace            string
+	BandwidthTable   string
+	BitswapTable     string
 }
 
 func printReport(report metadata.Report) error {
@@ -56,6 +62,9 @@ func printReport(report metadata.Report) error {
 		BandwidthTable: bwTable,
 		BitswapTable:   bswapTable,
 	}
+	data.TimeToFirstBlock = formatLatency(report.Summary.TimeToFirstBlock)
+	data.BlockLatency = formatLatency(report.Summary.BlockLatency)
+	data.TaskDuration = formatLatency(report.Summary.TaskDuration)
 
 	err := ReportTemplate.Execute(os.Stdout, &data)
 	if err != nil {
@@ -65,6 +74,15 @@ func printReport(report metadata.Report) error {
 	return nil
 }
 
+// formatLatency formats the percentiles of a latency, or returns an empty
+// string if it was not measured.
+func formatLatency(latency *metadata.ReportLatency) string {
+	if latency == nil {
+		return ""
+	}
+	return fmt.Sprintf("p50 %s, p90 %s, p95 %s, p99 %s, p99.9 %s", latency.P50, latency.P90, latency.P95, latency.P99, latency.P999)
+}
+
 func printReportBandwidth(report metadata.Report) string {
 	buf := new(bytes.Buffer)
 	table := tablewriter.NewWriter(buf)
@@ -17,8 +17,6 @@ package printer
 import (
 	"fmt"
 	"os"
-	"strconv"
-	"strings"
 
 	"github.com/Netflix/p2plab/metadata"
 	humanize "github.com/dustin/go-humanize"
@@ -46,8 +44,19 @@ func (p *tablePrinter) Print(v interface{}) error {
 		for _, e := range t {
 			p.addRow(table, e)
 		}
+	case Page:
+		err := p.Print(t.Items)
+		if err != nil {
+			return err
+		}
+		printPageFooter(os.Stdout, t)
+		return nil
 	case metadata.Report:
 		return printReport(t)
+	case metadata.ReportDiff:
+		return printReportDiff(t)
+	case metadata.DryRun:
+		return printDryRun(t)
 	default:
 		p.addHeader(table, t)
 		p.addRow(table, t)
@@ -58,64 +67,15 @@ func (p *tablePrinter) Print(v interface{}) error {
 }
 
 func (p *tablePrinter) addHeader(table *tablewriter.Table, v interface{}) {
-	switch v.(type) {
-	case metadata.Cluster:
-		table.SetHeader([]string{"ID", "STATUS", "SIZE", "LABELS", "CREATEDAT", "UPDATEDAT"})
-	case metadata.Node:
-		table.SetHeader([]string{"ID", "ADDRESS", "GITREFERENCE", "LABELS", "CREATEDAT", "UPDATEDAT"})
-	case metadata.Scenario:
-		table.SetHeader([]string{"ID", "LABELS", "CREATEDAT", "UPDATEDAT"})
-	case metadata.Benchmark:
-		table.SetHeader([]string{"ID", "STATUS", "CLUSTER", "SCENARIO", "LABELS", "CREATEDAT", "UPDATEDAT"})
-	case metadata.Experiment:
-		table.SetHeader([]string{"ID", "STATUS", "LABELS", "CREATEDAT", "UPDATEDAT"})
+	header := columns(v)
+	if header != nil {
+		table.SetHeader(header)
 	}
 }
 
 func (p *tablePrinter) addRow(table *tablewriter.Table, v interface{}) {
-	switch t := v.(type) {
-	case metadata.Cluster:
-		table.Append([]string{
-			t.ID,
-			string(t.Status),
-			strconv.Itoa(t.Definition.Size()),
-			strings.Join(t.Labels, ","),
-			humanize.Time(t.CreatedAt),
-			humanize.Time(t.UpdatedAt),
-		})
-	case metadata.Node:
-		table.Append([]string{
-			t.ID,
-			t.Address,
-			t.Peer.GitReference,
-			strings.Join(t.Labels, ","),
-			humanize.Time(t.CreatedAt),
-			humanize.Time(t.UpdatedAt),
-		})
-	case metadata.Scenario:
-		table.Append([]string{
-			t.ID,
-			strings.Join(t.Labels, ","),
-			humanize.Time(t.CreatedAt),
-			humanize.Time(t.UpdatedAt),
-		})
-	case metadata.Benchmark:
-		table.Append([]string{
-			t.ID,
-			string(t.Status),
-			t.Cluster.ID,
-			t.Scenario.ID,
-			strings.Join(t.Labels, ","),
-			humanize.Time(t.CreatedAt),
-			humanize.Time(t.UpdatedAt),
-		})
-	case metadata.Experiment:
-		table.Append([]string{
-			t.ID,
-			string(t.Status),
-			strings.Join(t.Labels, ","),
-			humanize.Time(t.CreatedAt),
-			humanize.Time(t.UpdatedAt),
-		})
+	row := values(v, humanize.Time)
+	if row != nil {
+		table.Append(row)
 	}
 }
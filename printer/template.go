@@ -0,0 +1,67 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package printer
+
+import (
+	"os"
+	"text/template"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/pkg/errors"
+)
+
+type templatePrinter struct {
+	tmpl *template.Template
+}
+
+// NewTemplatePrinter returns a printer that executes a Go template for each
+// record.
+func NewTemplatePrinter(tmpl string) (Printer, error) {
+	t, err := template.New("format").Parse(tmpl)
+	if err != nil {
+		return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "failed to parse template: %s", err)
+	}
+
+	return &templatePrinter{t}, nil
+}
+
+func (p *templatePrinter) Print(v interface{}) error {
+	if page, ok := v.(Page); ok {
+		v = page.Items
+	}
+
+	l, ok := v.([]interface{})
+	if !ok {
+		l = []interface{}{v}
+	}
+
+	for _, e := range l {
+		if projection, ok := e.(Projection); ok {
+			e = projection.Map()
+		}
+
+		err := p.tmpl.Execute(os.Stdout, e)
+		if err != nil {
+			return err
+		}
+
+		_, err = os.Stdout.Write([]byte("\n"))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -16,11 +16,17 @@ package printer
 
 import (
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/Netflix/p2plab/metadata"
 )
 
-type unixPrinter struct{}
+type unixPrinter struct {
+	// progressLines is the number of lines of the last progress printed, so
+	// that the next one is redrawn in place.
+	progressLines int
+}
 
 func NewUnixPrinter() Printer {
 	return &unixPrinter{}
@@ -35,6 +41,15 @@ func (p *unixPrinter) Print(v interface{}) error {
 				return err
 			}
 		}
+	case Page:
+		err := p.Print(t.Items)
+		if err != nil {
+			return err
+		}
+		// Keep stdout limited to IDs so it can be piped to other commands.
+		printPageFooter(os.Stderr, t)
+	case Projection:
+		fmt.Println(strings.Join(t.values(formatRFC3339), "\t"))
 	case metadata.Cluster:
 		fmt.Printf("%s\n", t.ID)
 	case metadata.Node:
@@ -45,6 +60,16 @@ func (p *unixPrinter) Print(v interface{}) error {
 		fmt.Printf("%s\n", t.ID)
 	case metadata.Experiment:
 		fmt.Printf("%s\n", t.ID)
+	case metadata.HealthComponent:
+		fmt.Printf("%s\t%s\n", t.Name, t.Status)
+	case metadata.Artifact:
+		fmt.Printf("%s\n", t.Name)
+	case metadata.BenchmarkProgress:
+		lines, err := printProgress(t, p.progressLines)
+		if err != nil {
+			return err
+		}
+		p.progressLines = lines
 	}
 
 	return nil
@@ -0,0 +1,44 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package printer
+
+import (
+	"fmt"
+
+	"sigs.k8s.io/yaml"
+)
+
+type yamlPrinter struct{}
+
+func NewYAMLPrinter() Printer {
+	return &yamlPrinter{}
+}
+
+func (p *yamlPrinter) Print(v interface{}) error {
+	// Empty result sets are printed as an empty sequence rather than null.
+	if l, ok := v.([]interface{}); ok && l == nil {
+		v = []interface{}{}
+	}
+
+	// Marshal through JSON struct tags so objects have the same shape as the
+	// JSON printer.
+	content, err := yaml.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(string(content))
+	return nil
+}
@@ -0,0 +1,321 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/Netflix/p2plab/pkg/logutil"
+	"github.com/Netflix/p2plab/pkg/traceutil"
+	"github.com/Netflix/p2plab/providers/terraform"
+	"github.com/pkg/errors"
+	"github.com/rs/xid"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// DefaultImage is the labagent image nodes are created from.
+	DefaultImage = "netflix/p2plab-labagent:latest"
+
+	labelCluster = "p2plab.cluster"
+	labelNode    = "p2plab.node"
+)
+
+type DockerProviderSettings struct {
+	// Image is the labagent image nodes are created from.
+	Image string
+}
+
+type provider struct {
+	image string
+}
+
+// New returns a provider that runs every node as a labagent container using
+// the docker CLI. Containers and networks are labeled with their cluster so
+// they can be found again after labd restarts.
+func New(settings DockerProviderSettings) (p2plab.NodeProvider, error) {
+	_, err := exec.LookPath("docker")
+	if err != nil {
+		return nil, errors.Wrap(err, "docker provider requires the docker CLI")
+	}
+
+	image := settings.Image
+	if image == "" {
+		image = DefaultImage
+	}
+
+	return &provider{
+		image: image,
+	}, nil
+}
+
+func (p *provider) CreateNodeGroup(ctx context.Context, id string, cdef metadata.ClusterDefinition) (*p2plab.NodeGroup, error) {
+	span, ctx := traceutil.StartSpanFromContext(ctx, "docker.CreateNodeGroup")
+	defer span.Finish()
+	span.SetTag("cluster", id)
+
+	var ns []metadata.Node
+	for _, group := range cdef.Groups {
+		gns, err := p.createNodes(ctx, id, group, group.Size)
+		if err != nil {
+			destroyErr := p.destroy(ctx, id)
+			if destroyErr != nil {
+				zerolog.Ctx(ctx).Error().Err(destroyErr).Msg("failed to clean up containers")
+			}
+			return nil, err
+		}
+		ns = append(ns, gns...)
+	}
+
+	return &p2plab.NodeGroup{
+		ID:    id,
+		Nodes: ns,
+	}, nil
+}
+
+func (p *provider) AddNodes(ctx context.Context, ng *p2plab.NodeGroup, cdef metadata.ClusterDefinition) ([]metadata.Node, error) {
+	sizes := make([]int, len(cdef.Groups))
+	for _, n := range ng.Nodes {
+		i := cdef.NodeGroupIndex(n)
+		if i >= 0 {
+			sizes[i]++
+		}
+	}
+
+	var ns []metadata.Node
+	for i, group := range cdef.Groups {
+		gns, err := p.createNodes(ctx, ng.ID, group, group.Size-sizes[i])
+		if err != nil {
+			return nil, err
+		}
+		ns = append(ns, gns...)
+	}
+
+	return ns, nil
+}
+
+func (p *provider) RemoveNodes(ctx context.Context, ng *p2plab.NodeGroup, cdef metadata.ClusterDefinition, ns []metadata.Node) error {
+	if len(ns) == 0 {
+		return nil
+	}
+
+	args := []string{"rm", "--force"}
+	for _, n := range ns {
+		args = append(args, containerName(ng.ID, n.ID))
+	}
+
+	_, err := p.docker(ctx, args...)
+	return err
+}
+
+func (p *provider) DestroyNodeGroup(ctx context.Context, ng *p2plab.NodeGroup) error {
+	span, ctx := traceutil.StartSpanFromContext(ctx, "docker.DestroyNodeGroup")
+	defer span.Finish()
+	span.SetTag("cluster", ng.ID)
+
+	return p.destroy(ctx, ng.ID)
+}
+
+// Ping checks that the docker daemon is reachable.
+func (p *provider) Ping(ctx context.Context) error {
+	_, err := p.docker(ctx, "version", "--format", "{{.Server.Version}}")
+	if err != nil {
+		return errors.Wrap(err, "docker daemon is unreachable")
+	}
+	return nil
+}
+
+// destroy removes all the containers of a cluster and then the networks that
+// were created for it.
+func (p *provider) destroy(ctx context.Context, cluster string) error {
+	filter := fmt.Sprintf("label=%s=%s", labelCluster, cluster)
+
+	containers, err := p.docker(ctx, "ps", "--all", "--quiet", "--filter", filter)
+	if err != nil {
+		return errors.Wrap(err, "failed to list containers")
+	}
+
+	if len(containers) > 0 {
+		zerolog.Ctx(ctx).Info().Int("containers", len(containers)).Msg("Removing containers")
+		_, err = p.docker(ctx, append([]string{"rm", "--force"}, containers...)...)
+		if err != nil {
+			return errors.Wrap(err, "failed to remove containers")
+		}
+	}
+
+	networks, err := p.docker(ctx, "network", "ls", "--quiet", "--filter", filter)
+	if err != nil {
+		return errors.Wrap(err, "failed to list networks")
+	}
+
+	if len(networks) > 0 {
+		zerolog.Ctx(ctx).Info().Int("networks", len(networks)).Msg("Removing networks")
+		_, err = p.docker(ctx, append([]string{"network", "rm"}, networks...)...)
+		if err != nil {
+			return errors.Wrap(err, "failed to remove networks")
+		}
+	}
+
+	return nil
+}
+
+func (p *provider) createNodes(ctx context.Context, cluster string, group metadata.ClusterGroup, size int) ([]metadata.Node, error) {
+	if size <= 0 {
+		return nil, nil
+	}
+
+	if group.Network != "" {
+		err := p.ensureNetwork(ctx, cluster, group.Network)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var ns []metadata.Node
+	for i := 0; i < size; i++ {
+		id := xid.New().String()
+		name := containerName(cluster, id)
+
+		args := []string{
+			"run", "--detach",
+			"--name", name,
+			"--hostname", id,
+			"--label", fmt.Sprintf("%s=%s", labelCluster, cluster),
+			"--label", fmt.Sprintf("%s=%s", labelNode, id),
+			"--publish", fmt.Sprintf("127.0.0.1::%d", terraform.DefaultAgentPort),
+			"--publish", fmt.Sprintf("127.0.0.1::%d", terraform.DefaultAppPort),
+			// Allows the agent to shape the container's network with tc.
+			"--cap-add", "NET_ADMIN",
+		}
+		if group.CPUs != "" {
+			args = append(args, "--cpus", group.CPUs)
+		}
+		if group.Memory != "" {
+			args = append(args, "--memory", group.Memory)
+		}
+		if group.Network != "" {
+			args = append(args, "--network", group.Network)
+		}
+		args = append(args, p.image,
+			fmt.Sprintf("--address=:%d", terraform.DefaultAgentPort),
+			fmt.Sprintf("--app-address=http://localhost:%d", terraform.DefaultAppPort),
+		)
+
+		zerolog.Ctx(ctx).Debug().Str("container", name).Msg("Starting labagent container")
+		_, err := p.docker(ctx, args...)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to run container %q", name)
+		}
+
+		agentPort, err := p.publishedPort(ctx, name, terraform.DefaultAgentPort)
+		if err != nil {
+			return nil, err
+		}
+
+		appPort, err := p.publishedPort(ctx, name, terraform.DefaultAppPort)
+		if err != nil {
+			return nil, err
+		}
+
+		n := metadata.Node{
+			ID:        id,
+			Address:   "127.0.0.1",
+			AgentPort: agentPort,
+			AppPort:   appPort,
+			Labels: append([]string{
+				id,
+				group.InstanceType,
+				group.Region,
+			}, metadata.NodeLabels(group.Region, metadata.SyntheticZone(group.Region, i), group.InstanceType, group.Labels...)...),
+		}
+		if group.Peer != nil {
+			n.Peer = *group.Peer
+		}
+		ns = append(ns, n)
+	}
+
+	return ns, nil
+}
+
+// ensureNetwork creates a network owned by the cluster if a network with that
+// name doesn't exist yet. Networks that already exist are left untouched on
+// destroy.
+func (p *provider) ensureNetwork(ctx context.Context, cluster, network string) error {
+	_, err := p.docker(ctx, "network", "inspect", network)
+	if err == nil {
+		return nil
+	}
+
+	zerolog.Ctx(ctx).Info().Str("network", network).Msg("Creating network")
+	_, err = p.docker(ctx, "network", "create", "--label", fmt.Sprintf("%s=%s", labelCluster, cluster), network)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create network %q", network)
+	}
+	return nil
+}
+
+func (p *provider) publishedPort(ctx context.Context, name string, port int) (int, error) {
+	lines, err := p.docker(ctx, "port", name, fmt.Sprintf("%d/tcp", port))
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to get published port %d of %q", port, name)
+	}
+
+	for _, line := range lines {
+		_, hostPort, err := net.SplitHostPort(line)
+		if err != nil {
+			continue
+		}
+		return strconv.Atoi(hostPort)
+	}
+	return 0, errors.Errorf("container %q has no published port for %d", name, port)
+}
+
+// docker runs the docker CLI and returns the non-empty lines of its output.
+func (p *provider) docker(ctx context.Context, args ...string) ([]string, error) {
+	logger := zerolog.Ctx(ctx).With().Strs("exec", args).Logger()
+	logWriter := logutil.NewWriter(&logger, zerolog.DebugLevel)
+	defer logWriter.Close()
+
+	stdout := new(bytes.Buffer)
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stdin = nil
+	cmd.Stdout = stdout
+	cmd.Stderr = logWriter
+	err := cmd.Run()
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+func containerName(cluster, id string) string {
+	return fmt.Sprintf("p2plab-%s-%s", cluster, id)
+}
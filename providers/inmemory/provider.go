@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 
 	"github.com/Netflix/p2plab"
 	"github.com/Netflix/p2plab/labagent"
@@ -30,12 +31,17 @@ import (
 
 type provider struct {
 	root      string
+	mu        sync.Mutex
 	nodes     map[string][]*node
 	logger    *zerolog.Logger
 	agentOpts []labagent.LabagentOption
 }
 
-func New(root string, db metadata.DB, logger *zerolog.Logger, agentOpts ...labagent.LabagentOption) (p2plab.NodeProvider, error) {
+// New returns a provider that runs every node as a labagent in the same
+// process, listening on free ports on localhost. Nodes of existing inmemory
+// clusters are restarted, including clusters without a provider if inmemory
+// is the default provider.
+func New(root string, db metadata.DB, logger *zerolog.Logger, isDefault bool, agentOpts ...labagent.LabagentOption) (p2plab.NodeProvider, error) {
 	err := os.MkdirAll(root, 0711)
 	if err != nil {
 		return nil, err
@@ -55,6 +61,11 @@ func New(root string, db metadata.DB, logger *zerolog.Logger, agentOpts ...labag
 	}
 
 	for _, cluster := range clusters {
+		provider := cluster.Definition.Provider
+		if provider != "inmemory" && (provider != "" || !isDefault) {
+			continue
+		}
+
 		nodes, err := db.ListNodes(ctx, cluster.ID)
 		if err != nil {
 			return nil, err
@@ -65,7 +76,7 @@ func New(root string, db metadata.DB, logger *zerolog.Logger, agentOpts ...labag
 			if err != nil {
 				return nil, err
 			}
-			p.nodes[node.ID] = append(p.nodes[node.ID], n)
+			p.nodes[cluster.ID] = append(p.nodes[cluster.ID], n)
 		}
 	}
 
@@ -75,33 +86,12 @@ func New(root string, db metadata.DB, logger *zerolog.Logger, agentOpts ...labag
 func (p *provider) CreateNodeGroup(ctx context.Context, id string, cdef metadata.ClusterDefinition) (*p2plab.NodeGroup, error) {
 	var ns []metadata.Node
 	for _, group := range cdef.Groups {
-		for i := 0; i < group.Size; i++ {
-			freePorts, err := freeport.GetFreePorts(2)
-			if err != nil {
-				return nil, err
-			}
-			agentPort, appPort := freePorts[0], freePorts[1]
-
-			id := xid.New().String()
-			n, err := p.newNode(id, agentPort, appPort)
-			if err != nil {
-				return nil, err
-			}
-			p.nodes[id] = append(p.nodes[id], n)
-
-			ns = append(ns, metadata.Node{
-				ID:        n.ID,
-				Address:   "127.0.0.1",
-				AgentPort: n.AgentPort,
-				AppPort:   n.AppPort,
-				Peer:      *group.Peer,
-				Labels: append([]string{
-					n.ID,
-					group.InstanceType,
-					group.Region,
-				}, group.Labels...),
-			})
+		gns, err := p.createNodes(id, group, group.Size)
+		if err != nil {
+			p.destroy(id)
+			return nil, err
 		}
+		ns = append(ns, gns...)
 	}
 
 	return &p2plab.NodeGroup{
@@ -110,16 +100,119 @@ func (p *provider) CreateNodeGroup(ctx context.Context, id string, cdef metadata
 	}, nil
 }
 
-func (p *provider) DestroyNodeGroup(ctx context.Context, ng *p2plab.NodeGroup) error {
+func (p *provider) AddNodes(ctx context.Context, ng *p2plab.NodeGroup, cdef metadata.ClusterDefinition) ([]metadata.Node, error) {
+	sizes := make([]int, len(cdef.Groups))
+	for _, n := range ng.Nodes {
+		i := cdef.NodeGroupIndex(n)
+		if i >= 0 {
+			sizes[i]++
+		}
+	}
+
+	var ns []metadata.Node
+	for i, group := range cdef.Groups {
+		gns, err := p.createNodes(ng.ID, group, group.Size-sizes[i])
+		if err != nil {
+			return nil, err
+		}
+		ns = append(ns, gns...)
+	}
+
+	return ns, nil
+}
+
+func (p *provider) RemoveNodes(ctx context.Context, ng *p2plab.NodeGroup, cdef metadata.ClusterDefinition, ns []metadata.Node) error {
+	removed := make(map[string]struct{})
+	for _, n := range ns {
+		removed[n.ID] = struct{}{}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var (
+		kept []*node
+		err  error
+	)
 	for _, n := range p.nodes[ng.ID] {
-		err := n.Close()
+		if _, ok := removed[n.ID]; !ok {
+			kept = append(kept, n)
+			continue
+		}
+
+		closeErr := p.closeNode(n)
+		if closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	p.nodes[ng.ID] = kept
+	return err
+}
+
+func (p *provider) createNodes(cluster string, group metadata.ClusterGroup, size int) ([]metadata.Node, error) {
+	var ns []metadata.Node
+	for i := 0; i < size; i++ {
+		freePorts, err := freeport.GetFreePorts(2)
 		if err != nil {
-			return err
+			return nil, err
+		}
+		agentPort, appPort := freePorts[0], freePorts[1]
+
+		id := xid.New().String()
+		n, err := p.newNode(id, agentPort, appPort)
+		if err != nil {
+			return nil, err
+		}
+
+		p.mu.Lock()
+		p.nodes[cluster] = append(p.nodes[cluster], n)
+		p.mu.Unlock()
+
+		ns = append(ns, metadata.Node{
+			ID:        n.ID,
+			Address:   "127.0.0.1",
+			AgentPort: n.AgentPort,
+			AppPort:   n.AppPort,
+			Peer:      *group.Peer,
+			Labels: append([]string{
+				n.ID,
+				group.InstanceType,
+				group.Region,
+			}, metadata.NodeLabels(group.Region, metadata.SyntheticZone(group.Region, i), group.InstanceType, group.Labels...)...),
+		})
+	}
+	return ns, nil
+}
+
+func (p *provider) DestroyNodeGroup(ctx context.Context, ng *p2plab.NodeGroup) error {
+	return p.destroy(ng.ID)
+}
+
+// destroy stops every node of a cluster, attempting to stop all of them even
+// if some fail to close.
+func (p *provider) destroy(cluster string) error {
+	p.mu.Lock()
+	ns := p.nodes[cluster]
+	delete(p.nodes, cluster)
+	p.mu.Unlock()
+
+	var err error
+	for _, n := range ns {
+		closeErr := p.closeNode(n)
+		if closeErr != nil && err == nil {
+			err = closeErr
 		}
 	}
+	return err
+}
+
+func (p *provider) closeNode(n *node) error {
+	err := n.Close()
+	if err != nil {
+		return err
+	}
 
-	delete(p.nodes, ng.ID)
-	return nil
+	return os.RemoveAll(filepath.Join(p.root, n.ID))
 }
 
 type node struct {
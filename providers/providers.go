@@ -15,7 +15,9 @@
 package providers
 
 import (
+	"context"
 	"path/filepath"
+	"sync"
 
 	"github.com/Netflix/p2plab"
 	"github.com/Netflix/p2plab/downloaders"
@@ -23,6 +25,7 @@ import (
 	"github.com/Netflix/p2plab/errdefs"
 	"github.com/Netflix/p2plab/labagent"
 	"github.com/Netflix/p2plab/metadata"
+	"github.com/Netflix/p2plab/providers/docker"
 	"github.com/Netflix/p2plab/providers/inmemory"
 	"github.com/Netflix/p2plab/providers/terraform"
 	"github.com/pkg/errors"
@@ -32,13 +35,85 @@ import (
 type ProviderSettings struct {
 	DB     metadata.DB
 	Logger *zerolog.Logger
+	Docker docker.DockerProviderSettings
+
+	// Default is the provider of clusters that don't specify one.
+	Default string
+}
+
+// Providers lazily creates node providers by type, so that clusters can be
+// created by different providers on the same labd.
+type Providers struct {
+	root     string
+	settings ProviderSettings
+	mu       sync.Mutex
+	ps       map[string]p2plab.NodeProvider
+}
+
+func New(root, defaultType string, settings ProviderSettings) (*Providers, error) {
+	settings.Default = defaultType
+	p := &Providers{
+		root:     root,
+		settings: settings,
+		ps:       make(map[string]p2plab.NodeProvider),
+	}
+
+	// Create the default provider eagerly to fail early on misconfiguration.
+	_, err := p.Get(defaultType)
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Default returns the provider type of clusters that don't specify one.
+func (p *Providers) Default() string {
+	return p.settings.Default
+}
+
+// Get returns the node provider of a given type, or the default provider if
+// the type is empty.
+func (p *Providers) Get(providerType string) (p2plab.NodeProvider, error) {
+	if providerType == "" {
+		providerType = p.settings.Default
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	provider, ok := p.ps[providerType]
+	if !ok {
+		var err error
+		provider, err = GetNodeProvider(p.root, providerType, p.settings)
+		if err != nil {
+			return nil, err
+		}
+		p.ps[providerType] = provider
+	}
+	return provider, nil
+}
+
+// Ping checks that the default provider can be created and, if it depends on
+// an external service, that the service is reachable.
+func (p *Providers) Ping(ctx context.Context) error {
+	provider, err := p.Get("")
+	if err != nil {
+		return err
+	}
+
+	pinger, ok := provider.(p2plab.Pinger)
+	if !ok {
+		return nil
+	}
+	return pinger.Ping(ctx)
 }
 
 func GetNodeProvider(root, providerType string, settings ProviderSettings) (p2plab.NodeProvider, error) {
 	root = filepath.Join(root, providerType)
 	switch providerType {
 	case "inmemory":
-		return inmemory.New(root, settings.DB, settings.Logger, labagent.WithDownloaderSettings(downloaders.DownloaderSettings{
+		return inmemory.New(root, settings.DB, settings.Logger, settings.Default == providerType, labagent.WithDownloaderSettings(downloaders.DownloaderSettings{
 			S3: s3downloader.S3DownloaderSettings{
 				Region: "us-west-2",
 			},
@@ -46,6 +121,8 @@ func GetNodeProvider(root, providerType string, settings ProviderSettings) (p2pl
 		)
 	case "terraform":
 		return terraform.New(root)
+	case "docker":
+		return docker.New(settings.Docker)
 	default:
 		return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "unrecognized node provider type %q", providerType)
 	}
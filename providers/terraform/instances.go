@@ -27,6 +27,9 @@ type EC2Instance struct {
 	InstanceId   string `json:"InstanceId"`
 	InstanceType string `json:"InstanceType"`
 	PrivateIp    string `json:"PrivateIpAddress"`
+	Placement    struct {
+		AvailabilityZone string `json:"AvailabilityZone"`
+	} `json:"Placement"`
 }
 
 func DiscoverInstances(ctx context.Context, asg, region string) ([]EC2Instance, error) {
@@ -16,10 +16,12 @@ package terraform
 
 import (
 	"context"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"text/template"
 
 	"github.com/Netflix/p2plab"
@@ -122,20 +124,13 @@ func (p *provider) CreateNodeGroup(ctx context.Context, id string, cdef metadata
 }
 
 func (p *provider) DestroyNodeGroup(ctx context.Context, ng *p2plab.NodeGroup) error {
-	t, ok := p.terraformById[ng.ID]
-	if !ok {
-		zerolog.Ctx(ctx).Debug().Msg("Creating terraform handler")
-		var err error
-		clusterDir := filepath.Join(p.root, ng.ID)
-		t, err = NewTerraform(ctx, clusterDir)
-		if err != nil {
-			return errors.Wrap(err, "failed to create terraform handler")
-		}
-		p.terraformById[ng.ID] = t
+	t, err := p.terraform(ctx, ng.ID)
+	if err != nil {
+		return err
 	}
 
 	zerolog.Ctx(ctx).Debug().Msg("Terraform destroying")
-	err := t.Destroy(ctx, ng.ID)
+	err = t.Destroy(ctx, ng.ID)
 	if err != nil {
 		return errors.Wrap(err, "failed to terraform destroy")
 	}
@@ -151,6 +146,105 @@ func (p *provider) DestroyNodeGroup(ctx context.Context, ng *p2plab.NodeGroup) e
 	return nil
 }
 
+func (p *provider) AddNodes(ctx context.Context, ng *p2plab.NodeGroup, cdef metadata.ClusterDefinition) ([]metadata.Node, error) {
+	t, err := p.terraform(ctx, ng.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	zerolog.Ctx(ctx).Debug().Msg("Executing tfvars template")
+	err = p.executeTfvarsTemplate(ng.ID, cdef)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to execute tfvars template")
+	}
+
+	zerolog.Ctx(ctx).Debug().Msg("Terraform applying")
+	ns, err := t.Apply(ctx, ng.ID, cdef)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to terraform apply")
+	}
+
+	existing := make(map[string]struct{})
+	for _, n := range ng.Nodes {
+		existing[n.ID] = struct{}{}
+	}
+
+	var added []metadata.Node
+	for _, n := range ns {
+		if _, ok := existing[n.ID]; !ok {
+			added = append(added, n)
+		}
+	}
+
+	return added, nil
+}
+
+func (p *provider) RemoveNodes(ctx context.Context, ng *p2plab.NodeGroup, cdef metadata.ClusterDefinition, ns []metadata.Node) error {
+	t, err := p.terraform(ctx, ng.ID)
+	if err != nil {
+		return err
+	}
+
+	// Terminate the chosen instances first, otherwise terraform lets the ASG
+	// pick which instances to terminate when its size is reduced.
+	for _, n := range ns {
+		i := cdef.NodeGroupIndex(n)
+		if i < 0 {
+			return errors.Wrapf(errdefs.ErrNotFound, "no cluster group for node %q", n.ID)
+		}
+		cg := cdef.Groups[i]
+		asg := fmt.Sprintf("%s-%d", ng.ID, i)
+
+		zerolog.Ctx(ctx).Debug().Str("asg", asg).Str("node", n.ID).Msg("Terminating instance in ASG")
+		err = awscli(ctx, "autoscaling", "update-auto-scaling-group",
+			"--auto-scaling-group-name", asg,
+			"--min-size", strconv.Itoa(cg.Size),
+			"--region", cg.Region,
+		)
+		if err != nil {
+			return errors.Wrapf(err, "failed to update min size of ASG %q", asg)
+		}
+
+		err = awscli(ctx, "autoscaling", "terminate-instance-in-auto-scaling-group",
+			"--instance-id", n.ID,
+			"--should-decrement-desired-capacity",
+			"--region", cg.Region,
+		)
+		if err != nil {
+			return errors.Wrapf(err, "failed to terminate instance %q", n.ID)
+		}
+	}
+
+	zerolog.Ctx(ctx).Debug().Msg("Executing tfvars template")
+	err = p.executeTfvarsTemplate(ng.ID, cdef)
+	if err != nil {
+		return errors.Wrap(err, "failed to execute tfvars template")
+	}
+
+	zerolog.Ctx(ctx).Debug().Msg("Terraform applying")
+	_, err = t.Apply(ctx, ng.ID, cdef)
+	if err != nil {
+		return errors.Wrap(err, "failed to terraform apply")
+	}
+
+	return nil
+}
+
+func (p *provider) terraform(ctx context.Context, id string) (*Terraform, error) {
+	t, ok := p.terraformById[id]
+	if !ok {
+		zerolog.Ctx(ctx).Debug().Msg("Creating terraform handler")
+		var err error
+		clusterDir := filepath.Join(p.root, id)
+		t, err = NewTerraform(ctx, clusterDir)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create terraform handler")
+		}
+		p.terraformById[id] = t
+	}
+	return t, nil
+}
+
 func (p *provider) prepareClusterDir(id string) (clusterDir string, err error) {
 	clusterDir = filepath.Join(p.root, id)
 	_, err = os.Stat(clusterDir)
@@ -93,7 +93,7 @@ func (t *Terraform) Apply(ctx context.Context, id string, cdef metadata.ClusterD
 					instance.InstanceId,
 					instance.InstanceType,
 					cg.Region,
-				}, cg.Labels...),
+				}, metadata.NodeLabels(cg.Region, instance.Placement.AvailabilityZone, instance.InstanceType, cg.Labels...)...),
 			}
 			if cg.Peer != nil {
 				n.Peer = *cg.Peer
@@ -38,6 +38,7 @@ var executetest = []struct {
 	{"(and 'slowdisk' 'region=us-west-2')", []p2plab.Labeled{ls[0]}},
 	{"(or 'region=us-west-2' 'region=us-east-1')", ls},
 	{"(or (not 'slowdisk') 'banana')", []p2plab.Labeled{ls[1], ls[2]}},
+	{"region=us-east-1", []p2plab.Labeled{ls[2]}},
 }
 
 func TestExecute(t *testing.T) {
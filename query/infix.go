@@ -0,0 +1,323 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/Netflix/p2plab"
+	"github.com/pkg/errors"
+)
+
+type infixToken struct {
+	kind  infixTokenKind
+	value string
+}
+
+type infixTokenKind int
+
+const (
+	tokenLabel infixTokenKind = iota
+	tokenOperator
+)
+
+func (t infixToken) is(op string) bool {
+	return t.kind == tokenOperator && t.value == op
+}
+
+func (t infixToken) String() string {
+	return t.value
+}
+
+// isInfix returns true if the query uses any infix operator.
+func isInfix(q string) bool {
+	tokens, err := lexInfix(q)
+	if err != nil {
+		return false
+	}
+
+	for _, t := range tokens {
+		if t.kind == tokenOperator && t.value != "(" && t.value != ")" {
+			return true
+		}
+	}
+
+	// Parenthesized labels are a grouping rather than a function.
+	if len(tokens) > 1 && tokens[0].is("(") && tokens[1].kind == tokenLabel {
+		switch tokens[1].value {
+		case "not", "and", "or":
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+func lexInfix(q string) ([]infixToken, error) {
+	var tokens []infixToken
+	for i := 0; i < len(q); {
+		c := q[i]
+		switch {
+		case unicode.IsSpace(rune(c)):
+			i++
+		case strings.HasPrefix(q[i:], "=~") || strings.HasPrefix(q[i:], "!~"):
+			tokens = append(tokens, infixToken{tokenOperator, q[i : i+2]})
+			i += 2
+		case c == '(' || c == ')' || c == ',' || c == '!':
+			tokens = append(tokens, infixToken{tokenOperator, string(c)})
+			i++
+		case strings.HasPrefix(q[i:], "&&") || strings.HasPrefix(q[i:], "||"):
+			tokens = append(tokens, infixToken{tokenOperator, q[i : i+2]})
+			i += 2
+		case c == '\'':
+			j := strings.IndexByte(q[i+1:], '\'')
+			if j < 0 {
+				return nil, errors.Errorf("unterminated quote at offset %d", i)
+			}
+			tokens = append(tokens, infixToken{tokenLabel, q[i+1 : i+1+j]})
+			i += j + 2
+		default:
+			j := i
+			for ; j < len(q) && !unicode.IsSpace(rune(q[j])) && !strings.ContainsRune("()',!&|", rune(q[j])); j++ {
+				if strings.HasPrefix(q[j:], "=~") {
+					break
+				}
+			}
+			if j == i {
+				return nil, errors.Errorf("unexpected character %q at offset %d", c, i)
+			}
+
+			word := q[i:j]
+			if word == "in" {
+				tokens = append(tokens, infixToken{tokenOperator, word})
+			} else {
+				tokens = append(tokens, infixToken{tokenLabel, word})
+			}
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+type infixParser struct {
+	tokens []infixToken
+	pos    int
+}
+
+// parseInfix parses a query written with infix operators into the same queries
+// as the s-expression syntax. '!' binds tighter than '&&', which binds tighter
+// than '||':
+//
+//	infix := or
+//	or := and ('||' and)*
+//	and := unary ('&&' unary)*
+//	unary := '!' unary
+//	       | primary
+//	primary := '(' or ')'
+//	         | label
+//	         | label 'in' '(' label (',' label)* ')'
+//	         | label '=~' label
+//	         | label '!~' label
+//	label := quoted_string
+//	       | string
+//
+// A `key in (a, b)` predicate matches labels of the form `key=a` or `key=b`.
+// A `key =~ regex` predicate matches labels of the form `key=value` where the
+// whole value matches the regex, and `key !~ regex` is its negation.
+func parseInfix(q string) (p2plab.Query, error) {
+	tokens, err := lexInfix(q)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &infixParser{tokens: tokens}
+	qry, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.pos < len(p.tokens) {
+		return nil, errors.Errorf("unexpected trailing token %q", p.tokens[p.pos])
+	}
+	return qry, nil
+}
+
+func (p *infixParser) peek() (infixToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return infixToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *infixParser) accept(op string) bool {
+	t, ok := p.peek()
+	if ok && t.is(op) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *infixParser) expect(op string) error {
+	if !p.accept(op) {
+		t, ok := p.peek()
+		if !ok {
+			return errors.Errorf("expected %q but query ended", op)
+		}
+		return errors.Errorf("expected %q but got %q", op, t)
+	}
+	return nil
+}
+
+func (p *infixParser) parseOr() (p2plab.Query, error) {
+	qry, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	queries := []p2plab.Query{qry}
+	for p.accept("||") {
+		qry, err = p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		queries = append(queries, qry)
+	}
+
+	if len(queries) == 1 {
+		return queries[0], nil
+	}
+	return newOrQuery(queries)
+}
+
+func (p *infixParser) parseAnd() (p2plab.Query, error) {
+	qry, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	queries := []p2plab.Query{qry}
+	for p.accept("&&") {
+		qry, err = p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		queries = append(queries, qry)
+	}
+
+	if len(queries) == 1 {
+		return queries[0], nil
+	}
+	return newAndQuery(queries)
+}
+
+func (p *infixParser) parseUnary() (p2plab.Query, error) {
+	if p.accept("!") {
+		qry, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return newNotQuery([]p2plab.Query{qry})
+	}
+	return p.parsePrimary()
+}
+
+func (p *infixParser) parsePrimary() (p2plab.Query, error) {
+	if p.accept("(") {
+		qry, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		err = p.expect(")")
+		if err != nil {
+			return nil, err
+		}
+		return qry, nil
+	}
+
+	key, err := p.parseLabel()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case p.accept("=~"):
+		return p.parseRegex(key)
+	case p.accept("!~"):
+		qry, err := p.parseRegex(key)
+		if err != nil {
+			return nil, err
+		}
+		return newNotQuery([]p2plab.Query{qry})
+	case !p.accept("in"):
+		return newLabelQuery(fmt.Sprintf("'%s'", key))
+	}
+
+	err = p.expect("(")
+	if err != nil {
+		return nil, err
+	}
+
+	var queries []p2plab.Query
+	for {
+		value, err := p.parseLabel()
+		if err != nil {
+			return nil, err
+		}
+
+		qry, err := newLabelQuery(fmt.Sprintf("'%s=%s'", key, value))
+		if err != nil {
+			return nil, err
+		}
+		queries = append(queries, qry)
+
+		if !p.accept(",") {
+			break
+		}
+	}
+
+	err = p.expect(")")
+	if err != nil {
+		return nil, err
+	}
+
+	if len(queries) == 1 {
+		return queries[0], nil
+	}
+	return newOrQuery(queries)
+}
+
+func (p *infixParser) parseRegex(key string) (p2plab.Query, error) {
+	pattern, err := p.parseLabel()
+	if err != nil {
+		return nil, err
+	}
+	return newRegexQuery(key, pattern)
+}
+
+func (p *infixParser) parseLabel() (string, error) {
+	t, ok := p.peek()
+	if !ok {
+		return "", errors.New("expected label but query ended")
+	}
+	if t.kind != tokenLabel {
+		return "", errors.Errorf("expected label but got %q", t)
+	}
+	p.pos++
+	return t.value, nil
+}
@@ -17,6 +17,7 @@ package query
 import (
 	"context"
 	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/Netflix/p2plab"
@@ -34,6 +35,8 @@ import (
 //       | ‘and’
 //       | ‘or’
 // label := quoted_string
+//
+// Queries may also be written with infix operators, see parseInfix.
 func Parse(ctx context.Context, q string) (p2plab.Query, error) {
 	tokens := tokenize(q)
 	if len(tokens) == 0 {
@@ -44,7 +47,9 @@ func Parse(ctx context.Context, q string) (p2plab.Query, error) {
 		qry p2plab.Query
 		err error
 	)
-	if len(tokens) == 1 {
+	if isInfix(q) {
+		qry, err = parseInfix(q)
+	} else if len(tokens) == 1 {
 		label := strings.Trim(tokens[0], "'")
 		qry, err = newLabelQuery(fmt.Sprintf("'%s'", label))
 	} else {
@@ -310,3 +315,43 @@ func (q *labelQuery) Match(ctx context.Context, lset p2plab.LabeledSet) (p2plab.
 
 	return labelSet, nil
 }
+
+type regexQuery struct {
+	key     string
+	pattern string
+	re      *regexp.Regexp
+}
+
+// newRegexQuery compiles the pattern once so that matching it against many
+// labeled sets doesn't recompile it.
+func newRegexQuery(key, pattern string) (p2plab.Query, error) {
+	re, err := regexp.Compile(fmt.Sprintf("^(?:%s)$", pattern))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid regex for %q", key)
+	}
+
+	return &regexQuery{
+		key:     key,
+		pattern: pattern,
+		re:      re,
+	}, nil
+}
+
+func (q *regexQuery) String() string {
+	return fmt.Sprintf("('%s' =~ '%s')", q.key, q.pattern)
+}
+
+func (q *regexQuery) Match(ctx context.Context, lset p2plab.LabeledSet) (p2plab.LabeledSet, error) {
+	regexSet := NewLabeledSet()
+	for _, l := range lset.Slice() {
+		for _, label := range l.Labels() {
+			parts := strings.SplitN(label, "=", 2)
+			if len(parts) == 2 && parts[0] == q.key && q.re.MatchString(parts[1]) {
+				regexSet.Add(l)
+				break
+			}
+		}
+	}
+
+	return regexSet, nil
+}
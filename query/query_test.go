@@ -0,0 +1,111 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/stretchr/testify/require"
+)
+
+var parsetest = []struct {
+	in  string
+	out string
+}{
+	// S-expressions are unchanged.
+	{"apple", "'apple'"},
+	{"(not 'apple')", "(not 'apple')"},
+	{"(and 'slowdisk' (or 'a' 'b'))", "(and 'slowdisk' (or 'a' 'b'))"},
+	// Infix operators.
+	{"'a' && 'b'", "(and 'a' 'b')"},
+	{"'a' || 'b'", "(or 'a' 'b')"},
+	{"!'a'", "(not 'a')"},
+	{"a && b && c", "(and 'a' 'b' 'c')"},
+	// '!' binds tighter than '&&' which binds tighter than '||'.
+	{"'a' || 'b' && 'c'", "(or 'a' (and 'b' 'c'))"},
+	{"'a' && 'b' || 'c'", "(or (and 'a' 'b') 'c')"},
+	{"!'a' && 'b'", "(and (not 'a') 'b')"},
+	{"!!'a'", "(not (not 'a'))"},
+	// Grouping overrides precedence.
+	{"('a' || 'b') && 'c'", "(and (or 'a' 'b') 'c')"},
+	{"!('a' && 'b')", "(not (and 'a' 'b'))"},
+	{"('a')", "'a'"},
+	// Set membership.
+	{"region in (us-west-2, us-east-1)", "(or 'region=us-west-2' 'region=us-east-1')"},
+	{"region in ('us-west-2')", "'region=us-west-2'"},
+	{"!region in (us-west-2) && slowdisk", "(and (not 'region=us-west-2') 'slowdisk')"},
+	// Regex matching.
+	{`region =~ us-.*`, `('region' =~ 'us-.*')`},
+	{`region=~'us-(west|east)-\d'`, `('region' =~ 'us-(west|east)-\d')`},
+	{`region !~ us-.* || slowdisk`, `(or (not ('region' =~ 'us-.*')) 'slowdisk')`},
+	// Quoted labels may contain operators.
+	{"'a&&b' || c", "(or 'a&&b' 'c')"},
+}
+
+func TestParse(t *testing.T) {
+	ctx := context.Background()
+
+	for _, parse := range parsetest {
+		qry, err := Parse(ctx, parse.in)
+		require.NoError(t, err, parse.in)
+		require.Equal(t, parse.out, qry.String(), parse.in)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	ctx := context.Background()
+
+	for _, in := range []string{
+		"'a' &&",
+		"|| 'a'",
+		"('a' || 'b'",
+		"'a' || 'b')",
+		"region in",
+		"region in (a,",
+		"region in (a b)",
+		"'a' && 'b",
+		"!",
+		"region =~",
+		"=~ us-.*",
+		"region =~ 'us-(west'",
+	} {
+		_, err := Parse(ctx, in)
+		require.Error(t, err, in)
+		require.True(t, errdefs.IsInvalidArgument(err), in)
+	}
+}
+
+func TestExecuteInfix(t *testing.T) {
+	ctx := context.Background()
+
+	for _, execute := range []struct {
+		in  string
+		out []p2plab.Labeled
+	}{
+		{"region in (us-west-2) && !slowdisk", []p2plab.Labeled{ls[1]}},
+		{"region in (us-west-2, us-east-1) && !'apple'", []p2plab.Labeled{ls[1], ls[2]}},
+		{"cherry || slowdisk", []p2plab.Labeled{ls[0], ls[2]}},
+		{`region =~ 'us-west-\d'`, []p2plab.Labeled{ls[0], ls[1]}},
+		{`region =~ west`, nil},
+		{`region !~ 'us-west-\d'`, []p2plab.Labeled{ls[2]}},
+	} {
+		labeledSet, err := Execute(ctx, ls, execute.in)
+		require.NoError(t, err, execute.in)
+		require.Equal(t, execute.out, labeledSet.Slice(), execute.in)
+	}
+}
@@ -14,7 +14,9 @@
 
 package reports
 
-import "github.com/Netflix/p2plab/metadata"
+import (
+	"github.com/Netflix/p2plab/metadata"
+)
 
 type uint64Pair struct {
 	single    uint64
@@ -65,3 +67,39 @@ func ComputeAggregates(reportByNodeId map[string]metadata.ReportNode) metadata.R
 	}
 	return aggregates
 }
+
+// ComputeTimeToFirstBlock summarizes the time nodes took to receive the first
+// block of the content they retrieved, or returns nil if no node did.
+func ComputeTimeToFirstBlock(nodes map[string]metadata.BenchmarkNode) *metadata.ReportLatency {
+	var h metadata.Histogram
+	for _, result := range nodes {
+		if result.Status == metadata.BenchmarkNodeDone && result.Retrieval != nil && result.Retrieval.FirstBlock > 0 {
+			h.Record(result.Retrieval.FirstBlock)
+		}
+	}
+	return metadata.NewReportLatency(h)
+}
+
+// ComputeBlockLatency merges the block latencies of the retrievals of every
+// node, or returns nil if no node retrieved content.
+func ComputeBlockLatency(nodes map[string]metadata.BenchmarkNode) *metadata.ReportLatency {
+	var h metadata.Histogram
+	for _, result := range nodes {
+		if result.Status == metadata.BenchmarkNodeDone && result.Retrieval != nil && result.Retrieval.Blocks != nil {
+			h.Merge(*result.Retrieval.Blocks)
+		}
+	}
+	return metadata.NewReportLatency(h)
+}
+
+// ComputeTaskDuration summarizes the time nodes took to execute their task, or
+// returns nil if no node completed one.
+func ComputeTaskDuration(nodes map[string]metadata.BenchmarkNode) *metadata.ReportLatency {
+	var h metadata.Histogram
+	for _, result := range nodes {
+		if result.Status == metadata.BenchmarkNodeDone && result.Duration > 0 {
+			h.Record(result.Duration)
+		}
+	}
+	return metadata.NewReportLatency(h)
+}
@@ -0,0 +1,72 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reports
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeTimeToFirstBlock(t *testing.T) {
+	require.Nil(t, ComputeTimeToFirstBlock(map[string]metadata.BenchmarkNode{
+		"1": {Status: metadata.BenchmarkNodeDone},
+	}))
+
+	nodes := map[string]metadata.BenchmarkNode{
+		"failed": {Status: metadata.BenchmarkNodeError, Retrieval: &metadata.Retrieval{FirstBlock: time.Hour}},
+	}
+	for i := 1; i <= 20; i++ {
+		nodes[strconv.Itoa(i)] = metadata.BenchmarkNode{
+			Status:    metadata.BenchmarkNodeDone,
+			Retrieval: &metadata.Retrieval{FirstBlock: time.Duration(i) * time.Millisecond, Total: time.Second},
+		}
+	}
+
+	ttfb := ComputeTimeToFirstBlock(nodes)
+	require.NotNil(t, ttfb)
+	require.Equal(t, uint64(20), ttfb.Histogram.Count)
+	require.InEpsilon(t, float64(10*time.Millisecond), float64(ttfb.P50), 0.02)
+	require.InEpsilon(t, float64(18*time.Millisecond), float64(ttfb.P90), 0.02)
+	require.InEpsilon(t, float64(19*time.Millisecond), float64(ttfb.P95), 0.02)
+	require.Equal(t, 20*time.Millisecond, ttfb.P99)
+	require.Equal(t, 20*time.Millisecond, ttfb.P999)
+}
+
+func TestComputeBlockLatency(t *testing.T) {
+	require.Nil(t, ComputeBlockLatency(map[string]metadata.BenchmarkNode{
+		"1": {Status: metadata.BenchmarkNodeDone, Retrieval: &metadata.Retrieval{Total: time.Second}},
+	}))
+
+	var a, b metadata.Histogram
+	for i := 1; i <= 100; i++ {
+		a.Record(time.Duration(i) * time.Millisecond)
+		b.Record(time.Duration(100+i) * time.Millisecond)
+	}
+
+	latency := ComputeBlockLatency(map[string]metadata.BenchmarkNode{
+		"a":      {Status: metadata.BenchmarkNodeDone, Retrieval: &metadata.Retrieval{Blocks: &a}},
+		"b":      {Status: metadata.BenchmarkNodeDone, Retrieval: &metadata.Retrieval{Blocks: &b}},
+		"failed": {Status: metadata.BenchmarkNodeError, Retrieval: &metadata.Retrieval{Blocks: &b}},
+	})
+	require.NotNil(t, latency)
+	require.Equal(t, uint64(200), latency.Histogram.Count)
+	require.InEpsilon(t, float64(100*time.Millisecond), float64(latency.P50), 0.02)
+	require.InEpsilon(t, float64(198*time.Millisecond), float64(latency.P99), 0.02)
+	require.Equal(t, 200*time.Millisecond, latency.P999)
+}
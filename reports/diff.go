@@ -0,0 +1,175 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reports
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/pkg/errors"
+)
+
+// DefaultRegressionThreshold is the increase in percent beyond which a metric
+// is considered to have regressed.
+const DefaultRegressionThreshold = 5.0
+
+// HeadlineMetric is the metric of a diff that summarizes a benchmark, the total
+// time it took.
+const HeadlineMetric = "duration"
+
+// Metrics are the names of the metrics compared by Diff.
+var Metrics = []string{
+	HeadlineMetric,
+	"latency_p50",
+	"latency_p95",
+	"latency_p99",
+	"total_bytes",
+	"dup_data_received",
+}
+
+// ValidateMetrics returns an error if a name is not one of Metrics.
+func ValidateMetrics(names []string) error {
+	for _, name := range names {
+		found := false
+		for _, metric := range Metrics {
+			if name == metric {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "unknown metric %q, must be one of [%s]", name, strings.Join(Metrics, ", "))
+		}
+	}
+	return nil
+}
+
+// Diff compares the report of benchmark b against the report of benchmark a.
+// Differences in scenario or nodes are reported as warnings. Metrics that were
+// zero for a have no delta and never regress.
+func Diff(a, b metadata.Benchmark, ra, rb metadata.Report, threshold float64) metadata.ReportDiff {
+	diff := metadata.ReportDiff{
+		A: a.ID,
+		B: b.ID,
+	}
+
+	if a.Scenario.ID != b.Scenario.ID {
+		diff.Warnings = append(diff.Warnings, fmt.Sprintf("scenarios differ: %q and %q", a.Scenario.ID, b.Scenario.ID))
+	}
+	if len(a.Nodes) != len(b.Nodes) {
+		diff.Warnings = append(diff.Warnings, fmt.Sprintf("node counts differ: %d and %d", len(a.Nodes), len(b.Nodes)))
+	}
+	for _, bm := range []metadata.Benchmark{a, b} {
+		failed := 0
+		for _, result := range bm.Nodes {
+			if result.Status != metadata.BenchmarkNodeDone {
+				failed++
+			}
+		}
+		if failed > 0 {
+			diff.Warnings = append(diff.Warnings, fmt.Sprintf("benchmark %q has %d failed nodes", bm.ID, failed))
+		}
+	}
+
+	da, db := nodeDurations(a), nodeDurations(b)
+	for _, m := range []struct {
+		name string
+		unit metadata.ReportMetricUnit
+		a, b float64
+	}{
+		{HeadlineMetric, metadata.ReportMetricDuration, float64(ra.Summary.TotalTime), float64(rb.Summary.TotalTime)},
+		{"latency_p50", metadata.ReportMetricDuration, percentile(da, 50), percentile(db, 50)},
+		{"latency_p95", metadata.ReportMetricDuration, percentile(da, 95), percentile(db, 95)},
+		{"latency_p99", metadata.ReportMetricDuration, percentile(da, 99), percentile(db, 99)},
+		{"total_bytes", metadata.ReportMetricBytes, totalBytes(ra), totalBytes(rb)},
+		{"dup_data_received", metadata.ReportMetricBytes, float64(ra.Aggregates.Totals.Bitswap.DupDataReceived), float64(rb.Aggregates.Totals.Bitswap.DupDataReceived)},
+	} {
+		md := metadata.ReportMetricDiff{
+			Name: m.name,
+			Unit: m.unit,
+			A:    m.a,
+			B:    m.b,
+		}
+		if m.a != 0 {
+			delta := (m.b - m.a) / m.a * 100
+			md.Delta = &delta
+			md.Regression = delta > threshold
+		}
+		diff.Metrics = append(diff.Metrics, md)
+	}
+
+	return diff
+}
+
+// ParseThreshold parses a regression threshold in percent, such as "10%" or
+// "10".
+func ParseThreshold(s string) (float64, error) {
+	threshold, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "%"), 64)
+	if err != nil || threshold < 0 {
+		return 0, errors.Wrapf(errdefs.ErrInvalidArgument, "invalid regression threshold %q", s)
+	}
+	return threshold, nil
+}
+
+// Regressions splits the metrics of a diff into those that regressed beyond
+// its threshold and those that improved.
+func Regressions(diff metadata.ReportDiff) (regressed, improved []metadata.ReportMetricDiff) {
+	for _, m := range diff.Metrics {
+		switch {
+		case m.Regression:
+			regressed = append(regressed, m)
+		case m.B < m.A:
+			improved = append(improved, m)
+		}
+	}
+	return regressed, improved
+}
+
+func nodeDurations(benchmark metadata.Benchmark) []time.Duration {
+	var durations []time.Duration
+	for _, result := range benchmark.Nodes {
+		if result.Status == metadata.BenchmarkNodeDone {
+			durations = append(durations, result.Duration)
+		}
+	}
+	sort.Slice(durations, func(i, j int) bool {
+		return durations[i] < durations[j]
+	})
+	return durations
+}
+
+// percentile returns the nearest-rank percentile of sorted durations.
+func percentile(sorted []time.Duration, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return float64(sorted[rank-1])
+}
+
+func totalBytes(report metadata.Report) float64 {
+	totals := report.Aggregates.Totals.Bandwidth.Totals
+	return float64(totals.TotalIn + totals.TotalOut)
+}
@@ -0,0 +1,121 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reports
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	a := metadata.Benchmark{
+		ID:       "a",
+		Scenario: metadata.Scenario{ID: "s"},
+		Nodes: map[string]metadata.BenchmarkNode{
+			"1": {Status: metadata.BenchmarkNodeDone, Duration: time.Second},
+			"2": {Status: metadata.BenchmarkNodeDone, Duration: 3 * time.Second},
+		},
+	}
+	b := metadata.Benchmark{
+		ID:       "b",
+		Scenario: metadata.Scenario{ID: "s"},
+		Nodes: map[string]metadata.BenchmarkNode{
+			"1": {Status: metadata.BenchmarkNodeDone, Duration: time.Second},
+			"2": {Status: metadata.BenchmarkNodeDone, Duration: 6 * time.Second},
+			"3": {Status: metadata.BenchmarkNodeError},
+		},
+	}
+
+	var ra, rb metadata.Report
+	ra.Summary.TotalTime = 10 * time.Second
+	rb.Summary.TotalTime = 9 * time.Second
+	rb.Aggregates.Totals.Bitswap.DupDataReceived = 1
+
+	diff := Diff(a, b, ra, rb, DefaultRegressionThreshold)
+	require.Equal(t, "a", diff.A)
+	require.Equal(t, "b", diff.B)
+	require.Equal(t, []string{
+		"node counts differ: 2 and 3",
+		`benchmark "b" has 1 failed nodes`,
+	}, diff.Warnings)
+
+	var names []string
+	metrics := make(map[string]metadata.ReportMetricDiff)
+	for _, m := range diff.Metrics {
+		names = append(names, m.Name)
+		metrics[m.Name] = m
+	}
+	require.Equal(t, Metrics, names)
+
+	require.InDelta(t, -10, *metrics["duration"].Delta, 0.001)
+	require.False(t, metrics["duration"].Regression)
+
+	require.Equal(t, float64(time.Second), metrics["latency_p50"].B)
+	require.InDelta(t, 100, *metrics["latency_p99"].Delta, 0.001)
+	require.True(t, metrics["latency_p99"].Regression)
+
+	require.Nil(t, metrics["dup_data_received"].Delta)
+	require.False(t, metrics["dup_data_received"].Regression)
+
+	require.Nil(t, metrics["total_bytes"].Delta)
+	require.False(t, metrics["total_bytes"].Regression)
+}
+
+func TestRegressions(t *testing.T) {
+	delta := func(v float64) *float64 {
+		return &v
+	}
+
+	regressed, improved := Regressions(metadata.ReportDiff{
+		Metrics: []metadata.ReportMetricDiff{
+			{Name: "duration", A: 10, B: 9, Delta: delta(-10)},
+			{Name: "latency_p50", A: 10, B: 10.2, Delta: delta(2)},
+			{Name: "latency_p99", A: 10, B: 20, Delta: delta(100), Regression: true},
+			{Name: "total_bytes"},
+		},
+	})
+	require.Len(t, regressed, 1)
+	require.Equal(t, "latency_p99", regressed[0].Name)
+	require.Len(t, improved, 1)
+	require.Equal(t, "duration", improved[0].Name)
+}
+
+func TestValidateMetrics(t *testing.T) {
+	require.NoError(t, ValidateMetrics([]string{HeadlineMetric, "latency_p99"}))
+
+	err := ValidateMetrics([]string{"latency"})
+	require.True(t, errdefs.IsInvalidArgument(err))
+}
+
+func TestParseThreshold(t *testing.T) {
+	for in, expected := range map[string]float64{
+		"10%": 10,
+		"2.5": 2.5,
+		"0%":  0,
+	} {
+		threshold, err := ParseThreshold(in)
+		require.NoError(t, err)
+		require.Equal(t, expected, threshold)
+	}
+
+	for _, in := range []string{"", "%", "ten", "-5%"} {
+		_, err := ParseThreshold(in)
+		require.True(t, errdefs.IsInvalidArgument(err), in)
+	}
+}
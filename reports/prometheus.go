@@ -0,0 +1,200 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reports
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Netflix/p2plab/metadata"
+)
+
+// PrometheusContentType is the content type of the Prometheus text exposition
+// format.
+const PrometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+type benchmarkMetric struct {
+	name  string
+	help  string
+	value func(report metadata.Report) float64
+}
+
+type nodeMetric struct {
+	name  string
+	help  string
+	value func(report metadata.Report, node metadata.ReportNode) float64
+}
+
+// The names and help text of metrics are used by dashboards, so they must not
+// change across releases. New metrics may be added.
+var (
+	benchmarkMetrics = []benchmarkMetric{
+		{
+			"p2plab_benchmark_duration_seconds",
+			"Time taken to execute the benchmark stage of the scenario.",
+			func(r metadata.Report) float64 { return r.Summary.TotalTime.Seconds() },
+		},
+	}
+
+	nodeMetrics = []nodeMetric{
+		{
+			"p2plab_bitswap_blocks_received",
+			"Number of blocks received over bitswap during the benchmark.",
+			func(r metadata.Report, n metadata.ReportNode) float64 { return float64(n.Bitswap.BlocksReceived) },
+		},
+		{
+			"p2plab_bitswap_data_received_bytes",
+			"Bytes of block data received over bitswap during the benchmark.",
+			func(r metadata.Report, n metadata.ReportNode) float64 { return float64(n.Bitswap.DataReceived) },
+		},
+		{
+			"p2plab_bitswap_blocks_sent",
+			"Number of blocks sent over bitswap during the benchmark.",
+			func(r metadata.Report, n metadata.ReportNode) float64 { return float64(n.Bitswap.BlocksSent) },
+		},
+		{
+			"p2plab_bitswap_data_sent_bytes",
+			"Bytes of block data sent over bitswap during the benchmark.",
+			func(r metadata.Report, n metadata.ReportNode) float64 { return float64(n.Bitswap.DataSent) },
+		},
+		{
+			"p2plab_bitswap_dup_blocks_received",
+			"Number of duplicate blocks received over bitswap during the benchmark.",
+			func(r metadata.Report, n metadata.ReportNode) float64 { return float64(n.Bitswap.DupBlksReceived) },
+		},
+		{
+			"p2plab_bitswap_dup_data_received_bytes",
+			"Bytes of duplicate block data received over bitswap during the benchmark.",
+			func(r metadata.Report, n metadata.ReportNode) float64 { return float64(n.Bitswap.DupDataReceived) },
+		},
+		{
+			"p2plab_bitswap_messages_received",
+			"Number of bitswap messages received during the benchmark.",
+			func(r metadata.Report, n metadata.ReportNode) float64 { return float64(n.Bitswap.MessagesReceived) },
+		},
+		{
+			"p2plab_bitswap_throughput_bytes_per_second",
+			"Bytes of block data received over bitswap per second of the benchmark.",
+			func(r metadata.Report, n metadata.ReportNode) float64 {
+				seconds := r.Summary.TotalTime.Seconds()
+				if seconds == 0 {
+					return 0
+				}
+				return float64(n.Bitswap.DataReceived) / seconds
+			},
+		},
+		{
+			"p2plab_bandwidth_in_bytes",
+			"Bytes received by the libp2p host during the benchmark.",
+			func(r metadata.Report, n metadata.ReportNode) float64 { return float64(n.Bandwidth.Totals.TotalIn) },
+		},
+		{
+			"p2plab_bandwidth_out_bytes",
+			"Bytes sent by the libp2p host during the benchmark.",
+			func(r metadata.Report, n metadata.ReportNode) float64 { return float64(n.Bandwidth.Totals.TotalOut) },
+		},
+		{
+			"p2plab_bandwidth_in_rate_bytes_per_second",
+			"Rate of bytes received by the libp2p host at the end of the benchmark.",
+			func(r metadata.Report, n metadata.ReportNode) float64 { return n.Bandwidth.Totals.RateIn },
+		},
+		{
+			"p2plab_bandwidth_out_rate_bytes_per_second",
+			"Rate of bytes sent by the libp2p host at the end of the benchmark.",
+			func(r metadata.Report, n metadata.ReportNode) float64 { return n.Bandwidth.Totals.RateOut },
+		},
+	}
+)
+
+// WritePrometheus writes the metrics of benchmarks in the Prometheus text
+// exposition format. Benchmarks without a report in reportByID are skipped.
+func WritePrometheus(w io.Writer, benchmarks []metadata.Benchmark, reportByID map[string]metadata.Report) error {
+	bw := bufio.NewWriter(w)
+
+	for _, metric := range benchmarkMetrics {
+		WritePrometheusHeader(bw, metric.name, "gauge", metric.help)
+		for _, b := range benchmarks {
+			report, ok := reportByID[b.ID]
+			if !ok {
+				continue
+			}
+			WritePrometheusSample(bw, metric.name, benchmarkLabels(b), metric.value(report))
+		}
+	}
+
+	for _, metric := range nodeMetrics {
+		WritePrometheusHeader(bw, metric.name, "gauge", metric.help)
+		for _, b := range benchmarks {
+			report, ok := reportByID[b.ID]
+			if !ok {
+				continue
+			}
+
+			var ids []string
+			for id := range report.Nodes {
+				ids = append(ids, id)
+			}
+			sort.Strings(ids)
+
+			for _, id := range ids {
+				labels := append(benchmarkLabels(b), [2]string{"node", id})
+				WritePrometheusSample(bw, metric.name, labels, metric.value(report, report.Nodes[id]))
+			}
+		}
+	}
+
+	return bw.Flush()
+}
+
+func benchmarkLabels(b metadata.Benchmark) [][2]string {
+	return [][2]string{
+		{"benchmark", b.ID},
+		{"cluster", b.Cluster.ID},
+		{"scenario", b.Scenario.ID},
+	}
+}
+
+// WritePrometheusHeader writes the help text and type, such as gauge or
+// counter, of a metric in the Prometheus text exposition format.
+func WritePrometheusHeader(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+}
+
+// WritePrometheusSample writes a sample of a metric labeled by name and value
+// pairs in the Prometheus text exposition format.
+func WritePrometheusSample(w io.Writer, name string, labels [][2]string, value float64) {
+	formatted := strconv.FormatFloat(value, 'g', -1, 64)
+	if len(labels) == 0 {
+		fmt.Fprintf(w, "%s %s\n", name, formatted)
+		return
+	}
+
+	var pairs []string
+	for _, l := range labels {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, l[0], escapeLabelValue(l[1])))
+	}
+	fmt.Fprintf(w, "%s{%s} %s\n", name, strings.Join(pairs, ","), formatted)
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeLabelValue(v string) string {
+	return labelValueEscaper.Replace(v)
+}
@@ -0,0 +1,74 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reports
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWritePrometheus(t *testing.T) {
+	benchmarks := []metadata.Benchmark{
+		{
+			ID:       "b1",
+			Cluster:  metadata.Cluster{ID: "c1"},
+			Scenario: metadata.Scenario{ID: `s"1`},
+		},
+		{
+			ID: "no-report",
+		},
+	}
+
+	var node metadata.ReportNode
+	node.Bitswap.DataReceived = 2048
+	node.Bandwidth.Totals.RateIn = 0.5
+
+	reportByID := map[string]metadata.Report{
+		"b1": {
+			Summary: metadata.ReportSummary{TotalTime: 2 * time.Second},
+			Nodes: map[string]metadata.ReportNode{
+				"n2": {},
+				"n1": node,
+			},
+		},
+	}
+
+	buf := new(bytes.Buffer)
+	err := WritePrometheus(buf, benchmarks, reportByID)
+	require.NoError(t, err)
+
+	lines := strings.Split(buf.String(), "\n")
+	for _, expected := range []string{
+		`# HELP p2plab_benchmark_duration_seconds Time taken to execute the benchmark stage of the scenario.`,
+		`# TYPE p2plab_benchmark_duration_seconds gauge`,
+		`p2plab_benchmark_duration_seconds{benchmark="b1",cluster="c1",scenario="s\"1"} 2`,
+		`p2plab_bitswap_data_received_bytes{benchmark="b1",cluster="c1",scenario="s\"1",node="n1"} 2048`,
+		`p2plab_bitswap_throughput_bytes_per_second{benchmark="b1",cluster="c1",scenario="s\"1",node="n1"} 1024`,
+		`p2plab_bandwidth_in_rate_bytes_per_second{benchmark="b1",cluster="c1",scenario="s\"1",node="n1"} 0.5`,
+	} {
+		require.Contains(t, lines, expected)
+	}
+	require.NotContains(t, buf.String(), "no-report")
+
+	// Nodes are sorted so that the output is stable.
+	n1 := strings.Index(buf.String(), `p2plab_bitswap_blocks_received{benchmark="b1",cluster="c1",scenario="s\"1",node="n1"}`)
+	n2 := strings.Index(buf.String(), `p2plab_bitswap_blocks_received{benchmark="b1",cluster="c1",scenario="s\"1",node="n2"}`)
+	require.True(t, n1 >= 0 && n1 < n2)
+}
@@ -28,12 +28,17 @@ type ScenarioAPI interface {
 	// Get returns a scenario.
 	Get(ctx context.Context, name string) (Scenario, error)
 
+	// Update replaces the definition of an existing scenario.
+	Update(ctx context.Context, name string, sdef metadata.ScenarioDefinition) (Scenario, error)
+
 	Label(ctx context.Context, names, adds, removes []string) ([]Scenario, error)
 
 	// List returns available scenarios.
 	List(ctx context.Context, opts ...ListOption) ([]Scenario, error)
 
-	Remove(ctx context.Context, names ...string) error
+	// Remove deletes scenarios. Scenarios that benchmarks ran are not removed
+	// unless cascaded or forced.
+	Remove(ctx context.Context, names []string, opts ...RemoveOption) error
 }
 
 // Scenario is a schema for benchmarks that describes objects to benchmark, how
@@ -0,0 +1,73 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scenarios
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/pkg/errors"
+)
+
+// bootstrapConnectivity assigns bootstrap peers to the connectivity tasks of
+// the stage. The first node by ID is the bootstrap peer of every other node,
+// so nodes only find each other through it.
+func bootstrapConnectivity(ctx context.Context, lset p2plab.LabeledSet, stage metadata.ScenarioStage) (metadata.ScenarioStage, error) {
+	var ids []string
+	for id := range stage {
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return stage, nil
+	}
+	sort.Strings(ids)
+
+	n, ok := lset.Get(ids[0]).(p2plab.Node)
+	if !ok {
+		return nil, errors.Errorf("could not find bootstrap node %q", ids[0])
+	}
+
+	peerInfo, err := n.PeerInfo(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get peer info of bootstrap node %q", ids[0])
+	}
+
+	var addrs []string
+	for _, ma := range peerInfo.Addrs {
+		addrs = append(addrs, fmt.Sprintf("%s/p2p/%s", ma, peerInfo.ID))
+	}
+
+	bootstrapped := make(metadata.ScenarioStage)
+	for i, id := range ids {
+		ctask, err := metadata.ParseConnectivityTask(stage[id])
+		if err != nil {
+			return nil, err
+		}
+
+		if i > 0 {
+			ctask.Bootstrap = addrs
+		}
+
+		bootstrapped[id], err = ctask.Task()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return bootstrapped, nil
+}
@@ -15,23 +15,112 @@
 package scenarios
 
 import (
-	"encoding/json"
-	"io/ioutil"
+	"bytes"
+	"strings"
 
+	"github.com/Netflix/p2plab/errdefs"
 	"github.com/Netflix/p2plab/metadata"
+	"github.com/Netflix/p2plab/pkg/cliutil"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
 )
 
-func Parse(filename string) (metadata.ScenarioDefinition, error) {
-	var sdef metadata.ScenarioDefinition
-	content, err := ioutil.ReadFile(filename)
+// Parse reads a scenario definition, expanding its template variables with
+// lookup. A filename of cliutil.Stdin reads the definition from the standard
+// input.
+func Parse(filename string, lookup LookupFunc) (metadata.ScenarioDefinition, error) {
+	content, err := cliutil.ReadFile(filename)
 	if err != nil {
-		return sdef, err
+		return metadata.ScenarioDefinition{}, err
 	}
 
-	err = json.Unmarshal(content, &sdef)
+	return ParseTemplate(filename, content, lookup)
+}
+
+// ParseTemplate parses the content of a templated scenario definition read from
+// filename, expanding its template variables with lookup.
+func ParseTemplate(filename string, content []byte, lookup LookupFunc) (metadata.ScenarioDefinition, error) {
+	content, err := Expand(content, lookup)
 	if err != nil {
-		return sdef, err
+		return metadata.ScenarioDefinition{}, errors.Wrapf(err, "failed to expand %q", filename)
+	}
+
+	sdef, err := metadata.ParseScenarioDefinition(content)
+	if err != nil {
+		return sdef, errors.Wrapf(err, "failed to parse %q", filename)
 	}
 
 	return sdef, nil
 }
+
+// ReadTemplate reads a templated scenario definition and expands its
+// variables with lookup.
+func ReadTemplate(filename string, lookup LookupFunc) ([]byte, error) {
+	content, err := cliutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err = Expand(content, lookup)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to expand %q", filename)
+	}
+
+	return content, nil
+}
+
+// SplitDocuments splits the content of a definition file into its documents,
+// separated by "---" lines as in multi-document YAML. Documents are returned as
+// JSON, converted from YAML unless they already are JSON objects, and empty
+// documents are skipped.
+func SplitDocuments(content []byte) ([][]byte, error) {
+	var (
+		docs    [][]byte
+		current bytes.Buffer
+	)
+	flush := func() error {
+		doc := append([]byte(nil), bytes.TrimSpace(current.Bytes())...)
+		current.Reset()
+		if len(doc) == 0 || isComment(doc) {
+			return nil
+		}
+
+		if doc[0] != '{' {
+			var err error
+			doc, err = yaml.YAMLToJSON(doc)
+			if err != nil {
+				return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid document %d: %s", len(docs)+1, err)
+			}
+		}
+		docs = append(docs, doc)
+		return nil
+	}
+
+	for _, line := range strings.SplitAfter(string(content), "\n") {
+		if strings.TrimRight(line, " \t\r\n") == "---" {
+			err := flush()
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		current.WriteString(line)
+	}
+
+	err := flush()
+	if err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// isComment returns whether every line of the document is a YAML comment.
+func isComment(doc []byte) bool {
+	for _, line := range bytes.Split(doc, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 && line[0] != '#' {
+			return false
+		}
+	}
+	return true
+}
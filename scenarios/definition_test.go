@@ -0,0 +1,67 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scenarios
+
+import (
+	"testing"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitDocuments(t *testing.T) {
+	docs, err := SplitDocuments([]byte(`{
+  "benchmark": {"neighbors": "fetch"}
+}`))
+	require.NoError(t, err)
+	require.Equal(t, []string{`{
+  "benchmark": {"neighbors": "fetch"}
+}`}, stringDocs(docs))
+
+	docs, err = SplitDocuments([]byte(`# Related scenarios.
+---
+benchmark:
+  neighbors: fetch
+---
+---
+{"benchmark": {"(not neighbors)": "fetch"}}
+---  
+# Empty.
+---
+objects:
+  image:
+    type: oci
+    source: alpine
+benchmark:
+  all: fetch
+`))
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		`{"benchmark":{"neighbors":"fetch"}}`,
+		`{"benchmark": {"(not neighbors)": "fetch"}}`,
+		`{"benchmark":{"all":"fetch"},"objects":{"image":{"source":"alpine","type":"oci"}}}`,
+	}, stringDocs(docs))
+
+	_, err = SplitDocuments([]byte("benchmark: fetch\n---\nbenchmark: [\n"))
+	require.True(t, errdefs.IsInvalidArgument(err))
+}
+
+func stringDocs(docs [][]byte) []string {
+	var s []string
+	for _, doc := range docs {
+		s = append(s, string(doc))
+	}
+	return s
+}
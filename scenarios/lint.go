@@ -0,0 +1,140 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scenarios
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/Netflix/p2plab/actions"
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/Netflix/p2plab/query"
+	"github.com/Netflix/p2plab/transformers"
+	"github.com/pkg/errors"
+)
+
+// Lint returns every problem of a scenario definition that can be found
+// without a daemon: unknown and missing fields, invalid queries, objects
+// without a transformer and actions on undefined objects.
+func Lint(ctx context.Context, content []byte) []error {
+	_, err := metadata.ParseScenarioDefinition(content)
+	if err == nil {
+		return nil
+	}
+
+	// Decode leniently to report the problems past unknown fields.
+	var (
+		errs []error
+		sdef metadata.ScenarioDefinition
+	)
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.DisallowUnknownFields()
+	if dec.Decode(&sdef) != nil {
+		errs = append(errs, err)
+
+		sdef = metadata.ScenarioDefinition{}
+		err = json.Unmarshal(content, &sdef)
+		if err != nil {
+			return errs
+		}
+	}
+
+	errs = append(errs, sdef.Problems()...)
+
+	for _, name := range sortedKeys(sdef.Objects) {
+		odef := sdef.Objects[name]
+		if odef.Type != "" && !isObjectType(odef.Type) {
+			errs = append(errs, errors.Wrapf(errdefs.ErrInvalidArgument, "object %q has unrecognized type %q, must be one of %s", name, odef.Type, strings.Join(transformers.ObjectTypes, ", ")))
+		}
+	}
+
+	lintQuery := func(field, q string) {
+		_, err := query.Parse(ctx, q)
+		if err != nil {
+			errs = append(errs, errors.Wrapf(err, "invalid query %q in %q", q, field))
+		}
+	}
+	lintAction := func(field, a string) {
+		// Actions may retrieve a file within an object.
+		_, object := actions.Split(a)
+		if object == "" {
+			return
+		}
+		object = strings.SplitN(object, "/", 2)[0]
+		if _, ok := sdef.Objects[object]; !ok {
+			errs = append(errs, errors.Wrapf(errdefs.ErrInvalidArgument, "action %q in %q retrieves undefined object %q", a, field, object))
+		}
+	}
+
+	for _, q := range sortedKeys(sdef.Seed) {
+		lintQuery("seed", q)
+		lintAction("seed", sdef.Seed[q])
+	}
+	for _, q := range sortedKeys(sdef.Benchmark) {
+		lintQuery("benchmark", q)
+		lintAction("benchmark", sdef.Benchmark[q])
+	}
+	for _, q := range sortedKeys(sdef.Teardown) {
+		lintQuery("teardown", q)
+		lintAction("teardown", sdef.Teardown[q])
+	}
+	for i, step := range sdef.Steps {
+		if step.Nodes != "" {
+			lintQuery(fmt.Sprintf("steps.%d.nodes", i), step.Nodes)
+		}
+		if step.Action != "" && step.Action != metadata.StepActionConnect {
+			lintAction(fmt.Sprintf("steps.%d.action", i), step.Action)
+		}
+	}
+	for _, q := range sortedKeys(sdef.Routing) {
+		lintQuery("routing", q)
+	}
+	if sdef.Seeding != nil {
+		if sdef.Seeding.Query != "" {
+			lintQuery("seeding.query", sdef.Seeding.Query)
+		}
+		if sdef.Seeding.Action != "" {
+			lintAction("seeding.action", sdef.Seeding.Action)
+		}
+	}
+
+	return errs
+}
+
+// sortedKeys returns the keys of a map with string keys in order, so that
+// problems are reported deterministically.
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	for _, k := range reflect.ValueOf(m).MapKeys() {
+		keys = append(keys, k.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func isObjectType(objectType string) bool {
+	for _, t := range transformers.ObjectTypes {
+		if t == objectType {
+			return true
+		}
+	}
+	return false
+}
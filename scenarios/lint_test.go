@@ -0,0 +1,56 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scenarios
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLintActions(t *testing.T) {
+	errs := Lint(context.Background(), []byte(`{
+  "objects": {"image": {"type": "oci", "source": "alpine"}},
+  "seed": {"'seeder'": "pin image"},
+  "benchmark": {"(not 'seeder')": "image"},
+  "teardown": {"'seeder'": "unpin image", "(not 'seeder')": "drop-cache"}
+}`))
+	require.Empty(t, errs)
+
+	errs = Lint(context.Background(), []byte(`{
+  "objects": {"image": {"type": "oci", "source": "alpine"}},
+  "benchmark": {"(not 'seeder')": "get image"},
+  "teardown": {"'seeder'": "unpin other", "bogus((": "unpin image"},
+  "unknown": true
+}`))
+	require.Len(t, errs, 3)
+	require.Contains(t, errs[1].Error(), `"unpin other" in "teardown" retrieves undefined object "other"`)
+}
+
+func TestLintSteps(t *testing.T) {
+	errs := Lint(context.Background(), []byte(`{
+  "objects": {"image": {"type": "oci", "source": "alpine"}},
+  "steps": [
+    {"name": "pin", "nodes": "'seeder'", "action": "pin other"},
+    {"name": "connect", "nodes": "bogus((", "action": "connect", "dependsOn": ["pin"]},
+    {"name": "get", "nodes": "*", "action": "image", "dependsOn": ["get"]}
+  ]
+}`))
+	require.Len(t, errs, 3)
+	require.Contains(t, errs[0].Error(), `steps ["get"] depend on each other in a cycle`)
+	require.Contains(t, errs[1].Error(), `"pin other" in "steps.0.action" retrieves undefined object "other"`)
+	require.Contains(t, errs[2].Error(), `invalid query "bogus((" in "steps.1.nodes"`)
+}
@@ -17,6 +17,7 @@ package scenarios
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/Netflix/p2plab"
 	"github.com/Netflix/p2plab/actions"
@@ -24,6 +25,7 @@ import (
 	"github.com/Netflix/p2plab/query"
 	"github.com/Netflix/p2plab/transformers"
 	cid "github.com/ipfs/go-cid"
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"golang.org/x/sync/errgroup"
 )
@@ -33,6 +35,25 @@ func Plan(ctx context.Context, sdef metadata.ScenarioDefinition, ts *transformer
 		Objects:   make(map[string]cid.Cid),
 		Seed:      make(map[string]metadata.Task),
 		Benchmark: make(map[string]metadata.Task),
+		Network:   sdef.Network,
+		GC:        sdef.GC,
+
+		MaxConcurrency: sdef.MaxConcurrency,
+	}
+
+	for _, d := range []struct {
+		name  string
+		value string
+		d     *time.Duration
+	}{
+		{"sample interval", sdef.SampleInterval, &plan.SampleInterval},
+		{"warmup", sdef.Warmup, &plan.Warmup},
+		{"cooldown", sdef.Cooldown, &plan.Cooldown},
+	} {
+		*d.d, err = metadata.ParseScenarioDuration(d.name, d.value)
+		if err != nil {
+			return plan, nil, err
+		}
 	}
 
 	objects, gctx := errgroup.WithContext(ctx)
@@ -47,15 +68,26 @@ func Plan(ctx context.Context, sdef metadata.ScenarioDefinition, ts *transformer
 				return err
 			}
 
-			opts := AddOptionsFromDefinition(odef)
-			c, err := t.Transform(gctx, peer, odef.Source, opts...)
+			var (
+				c     cid.Cid
+				files map[string]cid.Cid
+				opts  = AddOptionsFromDefinition(odef)
+			)
+			if ft, ok := t.(p2plab.FileTransformer); ok {
+				c, files, err = ft.TransformFiles(gctx, peer, odef.Source, opts...)
+			} else {
+				c, err = t.Transform(gctx, peer, odef.Source, opts...)
+			}
 			if err != nil {
 				return err
 			}
-			zerolog.Ctx(ctx).Debug().Str("type", odef.Type).Str("source", odef.Source).Str("cid", c.String()).Msg("Transformed object")
+			zerolog.Ctx(ctx).Debug().Str("type", odef.Type).Str("source", odef.Source).Str("cid", c.String()).Int("files", len(files)).Msg("Transformed object")
 
 			mu.Lock()
 			plan.Objects[name] = c
+			for path, fc := range files {
+				plan.Objects[ObjectFileName(name, path)] = fc
+			}
 			mu.Unlock()
 			return nil
 		})
@@ -66,6 +98,14 @@ func Plan(ctx context.Context, sdef metadata.ScenarioDefinition, ts *transformer
 		return plan, nil, err
 	}
 
+	if sdef.Seeding != nil {
+		zerolog.Ctx(ctx).Info().Msg("Planning scenario seeders")
+		plan.Seed, plan.Seeders, err = planSeeding(ctx, *sdef.Seeding, plan.Objects, lset)
+		if err != nil {
+			return plan, nil, err
+		}
+	}
+
 	zerolog.Ctx(ctx).Info().Msg("Planning scenario seed")
 	for q, a := range sdef.Seed {
 		qry, err := query.Parse(ctx, q)
@@ -99,7 +139,12 @@ func Plan(ctx context.Context, sdef metadata.ScenarioDefinition, ts *transformer
 			return plan, nil, err
 		}
 
-		plan.Seed = taskMap
+		if plan.Seed == nil {
+			plan.Seed = make(map[string]metadata.Task)
+		}
+		for id, task := range taskMap {
+			plan.Seed[id] = task
+		}
 	}
 
 	zerolog.Ctx(ctx).Info().Msg("Planning scenario benchmark")
@@ -141,9 +186,150 @@ func Plan(ctx context.Context, sdef metadata.ScenarioDefinition, ts *transformer
 		plan.Benchmark = taskMap
 	}
 
+	if sdef.Seeding != nil {
+		plan.Leechers = excludeSeeders(ctx, plan.Benchmark, queries, plan.Seeders)
+		err = metadata.ValidateSeeding(plan.Seeders, plan.Leechers)
+		if err != nil {
+			return plan, nil, err
+		}
+	}
+
+	if sdef.Objective != nil && sdef.Objective.Type == metadata.ObjectiveConnectivity {
+		ctask, err := sdef.Objective.ConnectivityTask(len(plan.Benchmark) - 1)
+		if err != nil {
+			return plan, nil, err
+		}
+
+		task, err := ctask.Task()
+		if err != nil {
+			return plan, nil, err
+		}
+
+		for id := range plan.Benchmark {
+			plan.Benchmark[id] = task
+		}
+		plan.Objective = sdef.Objective
+	}
+
+	if len(sdef.Steps) > 0 {
+		zerolog.Ctx(ctx).Info().Msg("Planning scenario steps")
+		plan.Steps, err = planSteps(ctx, plan.Objects, sdef.Steps, queries, lset)
+		if err != nil {
+			return plan, nil, err
+		}
+	}
+
+	if len(sdef.Teardown) > 0 {
+		zerolog.Ctx(ctx).Info().Msg("Planning scenario teardown")
+		plan.Teardown, err = planStage(ctx, plan.Objects, sdef.Teardown, lset)
+		if err != nil {
+			return plan, nil, err
+		}
+	}
+
 	return plan, queries, nil
 }
 
+// planStage returns the tasks of the nodes matched by the queries of a stage,
+// where nodes matched by several queries execute the task of the last one.
+func planStage(ctx context.Context, objects map[string]cid.Cid, stage map[string]string, lset p2plab.LabeledSet) (metadata.ScenarioStage, error) {
+	tasks := make(metadata.ScenarioStage)
+	for _, q := range sortedKeys(stage) {
+		ns, err := matchNodes(ctx, q, lset)
+		if err != nil {
+			return nil, err
+		}
+
+		action, err := actions.Parse(objects, stage[q])
+		if err != nil {
+			return nil, err
+		}
+
+		taskMap, err := action.Tasks(ctx, ns)
+		if err != nil {
+			return nil, err
+		}
+
+		for id, task := range taskMap {
+			tasks[id] = task
+		}
+	}
+	return tasks, nil
+}
+
+// planSteps returns the steps ordered by their dependencies, with the tasks of
+// the nodes matched by each step. The nodes matched are added to queries.
+func planSteps(ctx context.Context, objects map[string]cid.Cid, sdefs []metadata.StepDefinition, queries map[string][]string, lset p2plab.LabeledSet) ([]metadata.ScenarioStep, error) {
+	sdefs, err := metadata.SortSteps(sdefs)
+	if err != nil {
+		return nil, err
+	}
+
+	var steps []metadata.ScenarioStep
+	for _, sdef := range sdefs {
+		ns, err := matchNodes(ctx, sdef.Nodes, lset)
+		if err != nil {
+			return nil, errors.Wrapf(err, "step %q", sdef.Name)
+		}
+
+		var ids []string
+		for _, n := range ns {
+			ids = append(ids, n.ID())
+		}
+		zerolog.Ctx(ctx).Debug().Str("step", sdef.Name).Strs("ids", ids).Msg("Matched query")
+		queries[sdef.Nodes] = ids
+
+		step := metadata.ScenarioStep{
+			Name:      sdef.Name,
+			DependsOn: sdef.DependsOn,
+			Tasks:     make(metadata.ScenarioStage),
+		}
+		if sdef.Action == metadata.StepActionConnect {
+			step.Connect = true
+			for _, id := range ids {
+				step.Tasks[id] = metadata.Task{Type: metadata.TaskConnect}
+			}
+		} else {
+			action, err := actions.Parse(objects, sdef.Action)
+			if err != nil {
+				return nil, errors.Wrapf(err, "step %q", sdef.Name)
+			}
+
+			step.Tasks, err = action.Tasks(ctx, ns)
+			if err != nil {
+				return nil, errors.Wrapf(err, "step %q", sdef.Name)
+			}
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// matchNodes returns the nodes matched by the query q.
+func matchNodes(ctx context.Context, q string, lset p2plab.LabeledSet) ([]p2plab.Node, error) {
+	qry, err := query.Parse(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	mset, err := qry.Match(ctx, lset)
+	if err != nil {
+		return nil, err
+	}
+
+	var ns []p2plab.Node
+	for _, l := range mset.Slice() {
+		ns = append(ns, l.(p2plab.Node))
+	}
+	return ns, nil
+}
+
+// ObjectFileName returns the name of a file within an object, which can be
+// used as the subject of scenario actions.
+func ObjectFileName(object, path string) string {
+	return object + "/" + path
+}
+
 func AddOptionsFromDefinition(odef metadata.ObjectDefinition) []p2plab.AddOption {
 	var opts []p2plab.AddOption
 	if odef.Layout != "" {
@@ -158,5 +344,17 @@ func AddOptionsFromDefinition(odef metadata.ObjectDefinition) []p2plab.AddOption
 	if odef.HashFunc != "" {
 		opts = append(opts, p2plab.WithHashFunc(odef.HashFunc))
 	}
+	if odef.Platform != "" {
+		opts = append(opts, p2plab.WithPlatform(odef.Platform))
+	}
+	if len(odef.Exclude) > 0 {
+		opts = append(opts, p2plab.WithExclude(odef.Exclude...))
+	}
+	if odef.Size != "" {
+		opts = append(opts, p2plab.WithSize(odef.Size))
+	}
+	if odef.Seed != 0 {
+		opts = append(opts, p2plab.WithSeed(odef.Seed))
+	}
 	return opts
 }
@@ -0,0 +1,47 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scenarios
+
+import (
+	"context"
+
+	"github.com/Netflix/p2plab/metadata"
+)
+
+type progressKey struct{}
+
+// ProgressFunc is called on every transition of a node's task, with the
+// stage, node, node status and error of the event set.
+type ProgressFunc func(evt metadata.BenchmarkEvent)
+
+// WithProgress returns a context reporting the transitions of nodes' tasks
+// executed with it to fn.
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+func reportProgress(ctx context.Context, stage, id string, status metadata.BenchmarkNodeStatus, errMsg string) {
+	fn, ok := ctx.Value(progressKey{}).(ProgressFunc)
+	if !ok {
+		return
+	}
+
+	fn(metadata.BenchmarkEvent{
+		Stage:      stage,
+		Node:       id,
+		NodeStatus: status,
+		Error:      errMsg,
+	})
+}
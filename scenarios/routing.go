@@ -0,0 +1,58 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scenarios
+
+import (
+	"context"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/Netflix/p2plab/query"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// Routing resolves the routing queries of the scenario to the routing of each
+// matched node. A node matched by more than one query is an error.
+func Routing(ctx context.Context, sdef metadata.ScenarioDefinition, lset p2plab.LabeledSet) (map[string]metadata.RoutingDefinition, error) {
+	routing := make(map[string]metadata.RoutingDefinition)
+	matchedBy := make(map[string]string)
+	for q, rdef := range sdef.Routing {
+		qry, err := query.Parse(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+
+		mset, err := qry.Match(ctx, lset)
+		if err != nil {
+			return nil, err
+		}
+
+		var ids []string
+		for _, l := range mset.Slice() {
+			id := l.ID()
+			if other, ok := matchedBy[id]; ok {
+				return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "node %q is matched by routing queries %q and %q", id, other, qry.String())
+			}
+			matchedBy[id] = qry.String()
+			routing[id] = rdef
+			ids = append(ids, id)
+		}
+		zerolog.Ctx(ctx).Debug().Str("query", qry.String()).Str("routing", rdef.Mode).Strs("ids", ids).Msg("Matched routing query")
+	}
+
+	return routing, nil
+}
@@ -16,7 +16,9 @@ package scenarios
 
 import (
 	"context"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Netflix/p2plab"
@@ -25,29 +27,171 @@ import (
 	"github.com/Netflix/p2plab/nodes"
 	"github.com/Netflix/p2plab/pkg/logutil"
 	"github.com/Netflix/p2plab/pkg/traceutil"
+	cid "github.com/ipfs/go-cid"
 	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
-	"golang.org/x/sync/errgroup"
 )
 
 type Execution struct {
-	Start  time.Time
-	End    time.Time
-	Report map[string]metadata.ReportNode
-	Span   opentracing.Span
+	WarmupStart time.Time
+	Start       time.Time
+	End         time.Time
+	CooldownEnd time.Time
+	Report      map[string]metadata.ReportNode
+	Nodes       map[string]metadata.BenchmarkNode
+	Steps       []metadata.BenchmarkStep
+	Span        opentracing.Span
 }
 
+// Window returns the phases of the execution.
+func (e *Execution) Window() metadata.BenchmarkWindow {
+	return metadata.BenchmarkWindow{
+		WarmupStart: e.WarmupStart,
+		Start:       e.Start,
+		End:         e.End,
+		CooldownEnd: e.CooldownEnd,
+	}
+}
+
+// Failed returns the IDs of nodes that failed the execution.
+func (e *Execution) Failed() []string {
+	var ids []string
+	for id, result := range e.Nodes {
+		if result.Status != metadata.BenchmarkNodeDone {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Run executes the scenario plan. Nodes that fail don't stop the execution of
+// the other nodes, if any node fails then the execution is returned along with
+// an error.
 func Run(ctx context.Context, lset p2plab.LabeledSet, plan metadata.ScenarioPlan, seederAddrs []string) (*Execution, error) {
 	span, ctx := traceutil.StartSpanFromContext(ctx, "scenarios.Run")
 	defer span.Finish()
 
-	err := Seed(ctx, lset, plan.Seed, seederAddrs)
+	seeded := Seed(ctx, lset, plan.Seed, seederAddrs, plan.MaxConcurrency)
+
+	// Nodes that failed to seed are not benchmarked.
+	benchmark := make(metadata.ScenarioStage)
+	for id, task := range plan.Benchmark {
+		result, ok := seeded[id]
+		if ok && result.Status != metadata.BenchmarkNodeDone {
+			reportProgress(ctx, metadata.BenchmarkStageBenchmark, id, metadata.BenchmarkNodeSkipped, "")
+			continue
+		}
+		benchmark[id] = task
+	}
+
+	// Garbage is collected last, once the network is reset and the teardown
+	// executed.
+	if plan.GC != nil {
+		ns, err := LabeledSetToNodes(lset)
+		if err != nil {
+			return nil, err
+		}
+		defer collectGarbage(ctx, ns, plan)
+	}
+
+	if len(plan.Teardown) > 0 {
+		defer teardown(ctx, lset, plan.Teardown)
+	}
+
+	if plan.Network != nil {
+		ns, err := LabeledSetToNodes(lset)
+		if err != nil {
+			return nil, err
+		}
+
+		zerolog.Ctx(ctx).Info().Interface("spec", plan.Network).Msg("Shaping network")
+		err = nodes.ShapeNetwork(ctx, ns, *plan.Network)
+		defer resetNetwork(ctx, ns)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to shape network")
+		}
+	}
+
+	execution, err := Session(ctx, lset, plan, benchmark)
 	if err != nil {
 		return nil, err
 	}
 
-	return Session(ctx, lset, plan.Benchmark)
+	for id, result := range seeded {
+		if _, ok := execution.Nodes[id]; !ok || result.Status != metadata.BenchmarkNodeDone {
+			execution.Nodes[id] = result
+		}
+	}
+
+	failed := execution.Failed()
+	if len(failed) > 0 {
+		return execution, errors.Errorf("%d of %d nodes failed: %s", len(failed), len(execution.Nodes), strings.Join(failed, ","))
+	}
+
+	return execution, nil
+}
+
+// resetNetwork restores the network of the nodes to the baseline. It is not
+// bound to ctx so that canceled benchmarks are reset too.
+func resetNetwork(ctx context.Context, ns []p2plab.Node) {
+	zerolog.Ctx(ctx).Info().Msg("Resetting network")
+	rctx, cancel := context.WithTimeout(zerolog.Ctx(ctx).WithContext(context.Background()), time.Minute)
+	defer cancel()
+
+	err := nodes.ShapeNetwork(rctx, ns, metadata.NetworkSpec{})
+	if err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).Msg("Failed to reset network")
+	}
+}
+
+// teardown executes the teardown stage. Like resetNetwork, it is not bound to
+// ctx so that canceled benchmarks are torn down too, and failing nodes are
+// only logged.
+func teardown(ctx context.Context, lset p2plab.LabeledSet, stage metadata.ScenarioStage) {
+	zerolog.Ctx(ctx).Info().Int("nodes", len(stage)).Msg("Tearing down nodes")
+	tctx, cancel := context.WithTimeout(zerolog.Ctx(ctx).WithContext(context.Background()), 5*time.Minute)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for id, task := range stage {
+		n, ok := lset.Get(id).(p2plab.Node)
+		if !ok {
+			continue
+		}
+
+		wg.Add(1)
+		go func(task metadata.Task) {
+			defer wg.Done()
+			err := n.Run(tctx, task)
+			if err != nil {
+				zerolog.Ctx(ctx).Warn().Err(err).Str("node", n.ID()).Str("task", string(task.Type)).Msg("Failed to tear down node")
+			}
+		}(task)
+	}
+	wg.Wait()
+}
+
+// collectGarbage garbage collects the blockstores of the nodes, keeping the
+// objects of the plan that the GC keeps. Like resetNetwork, it is not bound to
+// ctx so that canceled benchmarks are torn down too.
+func collectGarbage(ctx context.Context, ns []p2plab.Node, plan metadata.ScenarioPlan) {
+	var roots []cid.Cid
+	for _, name := range plan.GC.Keep {
+		if c, ok := plan.Objects[name]; ok {
+			roots = append(roots, c)
+		}
+	}
+
+	zerolog.Ctx(ctx).Info().Int("keep", len(roots)).Msg("Collecting garbage")
+	gctx, cancel := context.WithTimeout(zerolog.Ctx(ctx).WithContext(context.Background()), 5*time.Minute)
+	defer cancel()
+
+	err := nodes.CollectGarbage(gctx, ns, roots)
+	if err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).Msg("Failed to collect garbage")
+	}
 }
 
 func LabeledSetToNodes(lset p2plab.LabeledSet) ([]p2plab.Node, error) {
@@ -62,87 +206,150 @@ func LabeledSetToNodes(lset p2plab.LabeledSet) ([]p2plab.Node, error) {
 	return ns, nil
 }
 
-func Seed(ctx context.Context, lset p2plab.LabeledSet, seed metadata.ScenarioStage, seederAddrs []string) error {
-	seeding, gctx := errgroup.WithContext(ctx)
+func Seed(ctx context.Context, lset p2plab.LabeledSet, seed metadata.ScenarioStage, seederAddrs []string, limit int) map[string]metadata.BenchmarkNode {
+	zerolog.Ctx(ctx).Info().Int("limit", limit).Msg("Seeding cluster")
+	ectx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go logutil.Elapsed(ectx, 20*time.Second, "Seeding cluster")
 
-	zerolog.Ctx(ctx).Info().Msg("Seeding cluster")
-	go logutil.Elapsed(gctx, 20*time.Second, "Seeding cluster")
-	for id, task := range seed {
-		id, task := id, task
-		seeding.Go(func() error {
-			labeled := lset.Get(id)
-			if labeled == nil {
-				return errors.Wrapf(errdefs.ErrNotFound, "could not find %q in labeled set", id)
-			}
-			logger := zerolog.Ctx(ctx).With().Str("node", id).Logger()
+	results := runStage(ctx, metadata.BenchmarkStageSeed, lset, seed, newSlots(limit), func(ctx context.Context, n p2plab.Node, task metadata.Task) error {
+		logger := zerolog.Ctx(ctx).With().Str("node", n.ID()).Logger()
 
-			n, ok := labeled.(p2plab.Node)
-			if !ok {
-				return errors.Wrap(errdefs.ErrInvalidArgument, "could not cast labeled to node")
-			}
-
-			logger.Debug().Strs("addrs", seederAddrs).Msg("Connecting to seeding peer")
-			err := n.Run(gctx, metadata.Task{
-				Type:    metadata.TaskConnect,
-				Subject: strings.Join(seederAddrs, ","),
-			})
-			if err != nil {
-				return errors.Wrap(err, "failed to connect to seeding peer")
-			}
-
-			logger.Debug().Str("task", string(task.Type)).Msg("Executing seeding task")
-			err = n.Run(gctx, task)
-			if err != nil {
-				return errors.Wrap(err, "failed to run seeding task")
-			}
+		logger.Debug().Strs("addrs", seederAddrs).Msg("Connecting to seeding peer")
+		err := n.Run(ctx, metadata.Task{
+			Type:    metadata.TaskConnect,
+			Subject: strings.Join(seederAddrs, ","),
+		})
+		if err != nil {
+			return errors.Wrap(err, "failed to connect to seeding peer")
+		}
 
-			logger.Debug().Strs("addrs", seederAddrs).Msg("Disconnecting from seeding peer")
-			err = n.Run(gctx, metadata.Task{
-				Type:    metadata.TaskDisconnect,
-				Subject: strings.Join(seederAddrs, ","),
-			})
-			if err != nil {
-				return errors.Wrap(err, "failed to disconnect from seeding peer")
-			}
+		logger.Debug().Str("task", string(task.Type)).Msg("Executing seeding task")
+		err = n.Run(ctx, task)
+		if err != nil {
+			return errors.Wrap(err, "failed to run seeding task")
+		}
 
-			return nil
+		logger.Debug().Strs("addrs", seederAddrs).Msg("Disconnecting from seeding peer")
+		err = n.Run(ctx, metadata.Task{
+			Type:    metadata.TaskDisconnect,
+			Subject: strings.Join(seederAddrs, ","),
 		})
-	}
+		if err != nil {
+			return errors.Wrap(err, "failed to disconnect from seeding peer")
+		}
 
-	err := seeding.Wait()
-	if err != nil {
-		return err
-	}
+		return nil
+	})
 
 	zerolog.Ctx(ctx).Info().Msg("Seeding completed")
-	return nil
+	return results
 }
 
-func Session(ctx context.Context, lset p2plab.LabeledSet, benchmark metadata.ScenarioStage) (*Execution, error) {
+// Session runs the benchmark stage or the steps of the plan in a traced
+// session and collects the reports of the nodes. Nodes are connected to each
+// other first, unless the plan has a connectivity objective in which case they
+// bootstrap from one node, or steps which connect nodes themselves. If the
+// plan has a sample interval, the resource usage of the nodes is sampled while
+// the stage runs. Only the window between the warmup and cooldown of the plan
+// is measured.
+func Session(ctx context.Context, lset p2plab.LabeledSet, plan metadata.ScenarioPlan, benchmark metadata.ScenarioStage) (*Execution, error) {
 	ns, err := LabeledSetToNodes(lset)
 	if err != nil {
 		return nil, err
 	}
 
+	connectivity := plan.Objective != nil && plan.Objective.Type == metadata.ObjectiveConnectivity
+
+	retrieves := make(map[string]bool)
+	for id, task := range benchmark {
+		retrieves[id] = task.Type == metadata.TaskGet
+	}
+	for _, step := range plan.Steps {
+		for id, task := range step.Tasks {
+			retrieves[id] = retrieves[id] || task.Type == metadata.TaskGet
+		}
+	}
+
 	var execution Execution
 	execution.Span, err = nodes.Session(ctx, ns, func(sctx context.Context) error {
-		err := nodes.Connect(ctx, ns)
+		var err error
+		if connectivity {
+			benchmark, err = bootstrapConnectivity(ctx, lset, benchmark)
+		} else if len(plan.Steps) == 0 {
+			err = nodes.Connect(ctx, ns)
+		}
 		if err != nil {
 			return err
 		}
 
-		execution.Start = time.Now()
-		err = Benchmark(sctx, lset, benchmark)
+		execution.WarmupStart = time.Now()
+		err = wait(ctx, plan.Warmup, "Warming up")
 		if err != nil {
 			return err
 		}
+
+		start, err := nodes.CollectReports(ctx, ns)
+		if err != nil {
+			return errors.Wrap(err, "failed to collect reports")
+		}
+
+		var stopSampling func() map[string]metadata.ResourceUsage
+		if plan.SampleInterval > 0 {
+			stopSampling = nodes.SampleResources(ctx, ns, plan.SampleInterval)
+		}
+
+		execution.Start = time.Now()
+		if len(plan.Steps) > 0 {
+			execution.Nodes, execution.Steps = Steps(sctx, lset, plan.Steps, plan.MaxConcurrency)
+		} else {
+			execution.Nodes = Benchmark(sctx, lset, benchmark, plan.MaxConcurrency)
+		}
 		execution.End = time.Now()
 
+		var usage map[string]metadata.ResourceUsage
+		if stopSampling != nil {
+			usage = stopSampling()
+		}
+
 		execution.Report, err = nodes.CollectReports(ctx, ns)
 		if err != nil {
 			return errors.Wrap(err, "failed to collect reports")
 		}
 
+		for id, result := range execution.Nodes {
+			if report, ok := execution.Report[id]; ok {
+				bitswap := report.Bitswap.Sub(start[id].Bitswap)
+				result.Bitswap = &bitswap
+				if connectivity {
+					result.Connectivity = report.Connectivity
+				}
+				if retrieves[id] {
+					result.Retrieval = report.Retrieval
+				}
+				result.CacheDrop = start[id].CacheDrop
+			}
+			if u, ok := usage[id]; ok {
+				result.Resources = &u
+			}
+			execution.Nodes[id] = result
+		}
+
+		// Activity during the warmup is discarded from the report.
+		if plan.Warmup > 0 {
+			for id, report := range execution.Report {
+				report.Bitswap = report.Bitswap.Sub(start[id].Bitswap)
+				report.Bandwidth = report.Bandwidth.Sub(start[id].Bandwidth)
+				execution.Report[id] = report
+			}
+		}
+
+		err = wait(ctx, plan.Cooldown, "Cooling down")
+		if err != nil {
+			return err
+		}
+		execution.CooldownEnd = time.Now()
+
 		return nil
 	})
 	if err != nil {
@@ -152,38 +359,124 @@ func Session(ctx context.Context, lset p2plab.LabeledSet, benchmark metadata.Sce
 	return &execution, nil
 }
 
-func Benchmark(ctx context.Context, lset p2plab.LabeledSet, benchmark metadata.ScenarioStage) error {
+// wait waits for d unless the context is done first.
+func wait(ctx context.Context, d time.Duration, msg string) error {
+	if d <= 0 {
+		return nil
+	}
+
+	zerolog.Ctx(ctx).Info().Str("duration", d.String()).Msg(msg)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+func Benchmark(ctx context.Context, lset p2plab.LabeledSet, benchmark metadata.ScenarioStage, limit int) map[string]metadata.BenchmarkNode {
 	span, ctx := traceutil.StartSpanFromContext(ctx, "scenarios.Benchmark")
 	defer span.Finish()
 
-	benchmarking, gctx := errgroup.WithContext(ctx)
+	zerolog.Ctx(ctx).Info().Int("limit", limit).Msg("Benchmarking cluster")
+	ectx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go logutil.Elapsed(ectx, 20*time.Second, "Benchmarking cluster")
 
-	zerolog.Ctx(ctx).Info().Msg("Benchmarking cluster")
-	go logutil.Elapsed(gctx, 20*time.Second, "Benchmarking cluster")
-	for id, task := range benchmark {
+	results := runStage(ctx, metadata.BenchmarkStageBenchmark, lset, benchmark, newSlots(limit), func(ctx context.Context, n p2plab.Node, task metadata.Task) error {
+		zerolog.Ctx(ctx).Debug().Str("node", n.ID()).Str("task", string(task.Type)).Msg("Executing benchmarking task")
+		return n.Run(ctx, task)
+	})
+
+	zerolog.Ctx(ctx).Info().Msg("Benchmark completed")
+	return results
+}
+
+// newSlots returns the slots limiting the nodes executing tasks at a time to
+// limit, or nil if limit is not positive.
+func newSlots(limit int) chan struct{} {
+	if limit <= 0 {
+		return nil
+	}
+	return make(chan struct{}, limit)
+}
+
+// runStage executes the tasks of a stage concurrently and returns the result
+// of each node. A failing node doesn't cancel the others, so that failed nodes
+// can be retried on their own. If slots is not nil, each node takes a slot
+// while it executes and the rest wait for a slot to free. Transitions of the
+// nodes' tasks are reported to the progress of the context.
+func runStage(ctx context.Context, name string, lset p2plab.LabeledSet, stage metadata.ScenarioStage, slots chan struct{}, fn func(context.Context, p2plab.Node, metadata.Task) error) map[string]metadata.BenchmarkNode {
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		results = make(map[string]metadata.BenchmarkNode)
+	)
+
+	for id, task := range stage {
 		id, task := id, task
-		benchmarking.Go(func() error {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if slots != nil {
+				slots <- struct{}{}
+				defer func() { <-slots }()
+			}
+			reportProgress(ctx, name, id, metadata.BenchmarkNodeRunning, "")
+
+			var err error
+			start := time.Now()
 			labeled := lset.Get(id)
 			if labeled == nil {
-				return errors.Wrapf(errdefs.ErrNotFound, "could not find %q in labeled set", id)
+				err = errors.Wrapf(errdefs.ErrNotFound, "could not find %q in labeled set", id)
+			} else if n, ok := labeled.(p2plab.Node); !ok {
+				err = errors.Wrap(errdefs.ErrInvalidArgument, "could not cast labeled to node")
+			} else {
+				err = fn(ctx, n, task)
 			}
-			logger := zerolog.Ctx(ctx).With().Str("node", id).Logger()
 
-			n, ok := labeled.(p2plab.Node)
-			if !ok {
-				return errors.Wrap(errdefs.ErrInvalidArgument, "could not cast labeled to node")
+			result := metadata.BenchmarkNode{
+				Status:   metadata.BenchmarkNodeDone,
+				Duration: time.Since(start),
 			}
+			if err != nil {
+				zerolog.Ctx(ctx).Warn().Err(err).Str("node", id).Msg("Task failed")
+				result.Status = metadata.BenchmarkNodeError
+				if isTimeout(err) {
+					result.Status = metadata.BenchmarkNodeTimeout
+				}
+				result.Error = err.Error()
+			}
+			reportProgress(ctx, name, id, result.Status, result.Error)
 
-			logger.Debug().Str("task", string(task.Type)).Msg("Executing benchmarking task")
-			return n.Run(gctx, task)
-		})
+			mu.Lock()
+			results[id] = result
+			mu.Unlock()
+		}()
 	}
+	wg.Wait()
 
-	err := benchmarking.Wait()
-	if err != nil {
-		return err
-	}
+	return results
+}
 
-	zerolog.Ctx(ctx).Info().Msg("Benchmark completed")
-	return nil
+func isTimeout(err error) bool {
+	for err != nil {
+		if err == context.DeadlineExceeded {
+			return true
+		}
+		if t, ok := err.(interface{ Timeout() bool }); ok && t.Timeout() {
+			return true
+		}
+
+		switch e := err.(type) {
+		case interface{ Cause() error }:
+			err = e.Cause()
+		case interface{ Unwrap() error }:
+			err = e.Unwrap()
+		default:
+			return false
+		}
+	}
+	return false
 }
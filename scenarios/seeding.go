@@ -0,0 +1,112 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scenarios
+
+import (
+	"context"
+	"sort"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/actions"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/Netflix/p2plab/query"
+	cid "github.com/ipfs/go-cid"
+	"github.com/rs/zerolog"
+)
+
+// planSeeding selects the seeders of the seeding definition and returns their
+// seed stage.
+func planSeeding(ctx context.Context, seeding metadata.SeedingDefinition, objects map[string]cid.Cid, lset p2plab.LabeledSet) (metadata.ScenarioStage, []string, error) {
+	var ids []string
+	if seeding.Query != "" {
+		qry, err := query.Parse(ctx, seeding.Query)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		mset, err := qry.Match(ctx, lset)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, l := range mset.Slice() {
+			ids = append(ids, l.ID())
+		}
+	} else {
+		var all []string
+		for _, l := range lset.Slice() {
+			all = append(all, l.ID())
+		}
+		ids = seeding.SelectPercent(all)
+	}
+	sort.Strings(ids)
+	zerolog.Ctx(ctx).Debug().Strs("ids", ids).Msg("Selected seeders")
+
+	action, err := actions.Parse(objects, seeding.Action)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var ns []p2plab.Node
+	for _, id := range ids {
+		ns = append(ns, lset.Get(id).(p2plab.Node))
+	}
+
+	stage, err := action.Tasks(ctx, ns)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return stage, ids, nil
+}
+
+// excludeSeeders removes the seeders from the benchmark stage and queries, so
+// that only nodes without the content are benchmarked. Returns the leechers.
+func excludeSeeders(ctx context.Context, benchmark metadata.ScenarioStage, queries map[string][]string, seeders []string) []string {
+	isSeeder := make(map[string]bool)
+	for _, id := range seeders {
+		isSeeder[id] = true
+	}
+
+	var excluded []string
+	for id := range benchmark {
+		if isSeeder[id] {
+			delete(benchmark, id)
+			excluded = append(excluded, id)
+		}
+	}
+
+	for q, ids := range queries {
+		var leechers []string
+		for _, id := range ids {
+			if !isSeeder[id] {
+				leechers = append(leechers, id)
+			}
+		}
+		queries[q] = leechers
+	}
+
+	if len(excluded) > 0 {
+		sort.Strings(excluded)
+		zerolog.Ctx(ctx).Warn().Strs("ids", excluded).Msg("Excluding seeders matched by benchmark queries")
+	}
+
+	var leechers []string
+	for id := range benchmark {
+		leechers = append(leechers, id)
+	}
+	sort.Strings(leechers)
+	return leechers
+}
@@ -0,0 +1,194 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scenarios
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/Netflix/p2plab/nodes"
+	"github.com/Netflix/p2plab/pkg/traceutil"
+	"github.com/rs/zerolog"
+)
+
+// Steps executes the steps of the plan as a graph, starting every step once
+// the steps it depends on are done. Steps depending on a step that failed on
+// any node are skipped. Steps running at the same time share the limit of
+// nodes executing tasks at a time. Returns the result of each step, and the
+// result of each node across the steps, which is its first step that didn't
+// succeed if any.
+func Steps(ctx context.Context, lset p2plab.LabeledSet, steps []metadata.ScenarioStep, limit int) (map[string]metadata.BenchmarkNode, []metadata.BenchmarkStep) {
+	span, ctx := traceutil.StartSpanFromContext(ctx, "scenarios.Steps")
+	defer span.Finish()
+
+	zerolog.Ctx(ctx).Info().Int("steps", len(steps)).Int("limit", limit).Msg("Executing steps")
+
+	var (
+		wg      sync.WaitGroup
+		index   = make(map[string]int)
+		done    = make([]chan struct{}, len(steps))
+		results = make([]metadata.BenchmarkStep, len(steps))
+		slots   = newSlots(limit)
+	)
+	for i, step := range steps {
+		index[step.Name] = i
+		done[i] = make(chan struct{})
+	}
+
+	for i, step := range steps {
+		i, step := i, step
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(done[i])
+
+			var failed string
+			for _, dep := range step.DependsOn {
+				<-done[index[dep]]
+				if failed == "" && stepFailed(results[index[dep]]) {
+					failed = dep
+				}
+			}
+
+			if failed != "" {
+				results[i] = skipStep(ctx, step, failed)
+				return
+			}
+			results[i] = runStep(ctx, lset, step, slots)
+		}()
+	}
+	wg.Wait()
+
+	// Steps are ordered by their dependencies, so a node's first failure is
+	// the one that caused the steps after it to be skipped.
+	nodeResults := make(map[string]metadata.BenchmarkNode)
+	for _, step := range results {
+		for id, result := range step.Nodes {
+			prev, ok := nodeResults[id]
+			if !ok {
+				nodeResults[id] = result
+				continue
+			}
+			if prev.Status != metadata.BenchmarkNodeDone {
+				continue
+			}
+			if result.Status == metadata.BenchmarkNodeDone {
+				result.Duration += prev.Duration
+			}
+			nodeResults[id] = result
+		}
+	}
+
+	zerolog.Ctx(ctx).Info().Msg("Steps completed")
+	return nodeResults, results
+}
+
+// runStep executes the tasks of a step on its nodes, or connects its nodes to
+// each other if it is a connect step.
+func runStep(ctx context.Context, lset p2plab.LabeledSet, step metadata.ScenarioStep, slots chan struct{}) metadata.BenchmarkStep {
+	span, ctx := traceutil.StartSpanFromContext(ctx, "scenarios.Step")
+	defer span.Finish()
+	span.SetTag("step", step.Name)
+
+	zerolog.Ctx(ctx).Info().Str("step", step.Name).Int("nodes", len(step.Tasks)).Msg("Executing step")
+	result := metadata.BenchmarkStep{
+		Name:  step.Name,
+		Start: time.Now(),
+	}
+
+	if step.Connect {
+		result.Nodes = connectStep(ctx, lset, step)
+	} else {
+		result.Nodes = runStage(ctx, step.Name, lset, step.Tasks, slots, func(ctx context.Context, n p2plab.Node, task metadata.Task) error {
+			zerolog.Ctx(ctx).Debug().Str("node", n.ID()).Str("step", step.Name).Str("task", string(task.Type)).Msg("Executing step task")
+			return n.Run(ctx, task)
+		})
+	}
+
+	result.End = time.Now()
+	zerolog.Ctx(ctx).Info().Str("step", step.Name).Str("elapsed", result.End.Sub(result.Start).String()).Msg("Step completed")
+	return result
+}
+
+// connectStep connects the nodes of a step to each other. They all fail
+// together, since the connections of one node depend on the others.
+func connectStep(ctx context.Context, lset p2plab.LabeledSet, step metadata.ScenarioStep) map[string]metadata.BenchmarkNode {
+	var ns []p2plab.Node
+	for id := range step.Tasks {
+		reportProgress(ctx, step.Name, id, metadata.BenchmarkNodeRunning, "")
+		if n, ok := lset.Get(id).(p2plab.Node); ok {
+			ns = append(ns, n)
+		}
+	}
+
+	start := time.Now()
+	err := nodes.Connect(ctx, ns)
+	result := metadata.BenchmarkNode{
+		Status:   metadata.BenchmarkNodeDone,
+		Duration: time.Since(start),
+	}
+	if err != nil {
+		zerolog.Ctx(ctx).Warn().Err(err).Str("step", step.Name).Msg("Failed to connect nodes")
+		result.Status = metadata.BenchmarkNodeError
+		if isTimeout(err) {
+			result.Status = metadata.BenchmarkNodeTimeout
+		}
+		result.Error = err.Error()
+	}
+
+	results := make(map[string]metadata.BenchmarkNode)
+	for id := range step.Tasks {
+		reportProgress(ctx, step.Name, id, result.Status, result.Error)
+		results[id] = result
+	}
+	return results
+}
+
+// skipStep reports the nodes of a step as skipped because the step it depends
+// on failed.
+func skipStep(ctx context.Context, step metadata.ScenarioStep, failed string) metadata.BenchmarkStep {
+	zerolog.Ctx(ctx).Warn().Str("step", step.Name).Str("failed", failed).Msg("Skipping step that depends on a failed step")
+	reason := fmt.Sprintf("depends on failed step %q", failed)
+
+	now := time.Now()
+	result := metadata.BenchmarkStep{
+		Name:  step.Name,
+		Start: now,
+		End:   now,
+		Nodes: make(map[string]metadata.BenchmarkNode),
+	}
+	for id := range step.Tasks {
+		reportProgress(ctx, step.Name, id, metadata.BenchmarkNodeSkipped, reason)
+		result.Nodes[id] = metadata.BenchmarkNode{
+			Status: metadata.BenchmarkNodeSkipped,
+			Error:  reason,
+		}
+	}
+	return result
+}
+
+// stepFailed returns whether any node of the step didn't succeed.
+func stepFailed(step metadata.BenchmarkStep) bool {
+	for _, result := range step.Nodes {
+		if result.Status != metadata.BenchmarkNodeDone {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scenarios
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/pkg/errors"
+)
+
+// LookupFunc returns the value of a template variable and whether it is
+// defined.
+type LookupFunc func(name string) (string, bool)
+
+var variableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Expand substitutes the variables of a templated scenario definition before
+// it is parsed. "${VAR}" is replaced by the value of VAR, and "${VAR:-default}"
+// by default when VAR is undefined. Since substitution is purely lexical,
+// values are inserted as is and a literal "$" must be escaped as "$$".
+func Expand(content []byte, lookup LookupFunc) ([]byte, error) {
+	var (
+		buf       bytes.Buffer
+		undefined = make(map[string]struct{})
+	)
+	for i := 0; i < len(content); i++ {
+		if content[i] != '$' || i+1 == len(content) {
+			buf.WriteByte(content[i])
+			continue
+		}
+
+		switch content[i+1] {
+		case '$':
+			buf.WriteByte('$')
+			i++
+			continue
+		case '{':
+		default:
+			buf.WriteByte(content[i])
+			continue
+		}
+
+		end := bytes.IndexByte(content[i:], '}')
+		if end < 0 {
+			return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "unterminated variable at offset %d", i)
+		}
+
+		expr := string(content[i+2 : i+end])
+		name, def, hasDefault := expr, "", false
+		if idx := strings.Index(expr, ":-"); idx >= 0 {
+			name, def, hasDefault = expr[:idx], expr[idx+2:], true
+		}
+		if !variableName.MatchString(name) {
+			return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "invalid variable name %q", name)
+		}
+
+		value, ok := lookup(name)
+		switch {
+		case ok:
+			buf.WriteString(value)
+		case hasDefault:
+			buf.WriteString(def)
+		default:
+			undefined[name] = struct{}{}
+		}
+		i += end
+	}
+
+	if len(undefined) > 0 {
+		var names []string
+		for name := range undefined {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "undefined variables %s, set them with --var or provide a default with ${VAR:-default}", strings.Join(names, ", "))
+	}
+
+	return buf.Bytes(), nil
+}
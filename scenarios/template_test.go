@@ -0,0 +1,56 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scenarios
+
+import (
+	"testing"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpand(t *testing.T) {
+	lookup := func(name string) (string, bool) {
+		value, ok := map[string]string{
+			"SIZE":  "1GB",
+			"EMPTY": "",
+		}[name]
+		return value, ok
+	}
+
+	for template, expected := range map[string]string{
+		`{"size": "${SIZE}"}`:               `{"size": "1GB"}`,
+		`{"size": "${SIZE:-2GB}"}`:          `{"size": "1GB"}`,
+		`{"seed": ${SEED:-42}}`:             `{"seed": 42}`,
+		`{"empty": "${EMPTY:-default}"}`:    `{"empty": ""}`,
+		`{"literal": "$${SIZE} and $$"}`:    `{"literal": "${SIZE} and $"}`,
+		`{"dollar": "$5 $SIZE"}`:            `{"dollar": "$5 $SIZE"}`,
+		`{"trailing": "$"}`:                 `{"trailing": "$"}`,
+		`{"nested": "${MISSING:-${SIZE}}"}`: `{"nested": "${SIZE}"}`,
+	} {
+		content, err := Expand([]byte(template), lookup)
+		require.NoError(t, err, template)
+		require.Equal(t, expected, string(content), template)
+	}
+
+	_, err := Expand([]byte(`{"a": "${B}", "c": "${A}", "d": "${B}"}`), lookup)
+	require.True(t, errdefs.IsInvalidArgument(err))
+	require.Contains(t, err.Error(), "undefined variables A, B")
+
+	for _, template := range []string{`${SIZE`, `${}`, `${1SIZE}`, `${SIZE-2GB}`} {
+		_, err = Expand([]byte(template), lookup)
+		require.True(t, errdefs.IsInvalidArgument(err), template)
+	}
+}
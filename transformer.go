@@ -25,3 +25,12 @@ type Transformer interface {
 
 	Close() error
 }
+
+// FileTransformer is a Transformer that preserves file boundaries and can
+// report the CID of each file it transformed, keyed by slash-separated path
+// relative to the root.
+type FileTransformer interface {
+	Transformer
+
+	TransformFiles(ctx context.Context, peer Peer, source string, opts ...AddOption) (cid.Cid, map[string]cid.Cid, error)
+}
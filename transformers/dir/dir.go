@@ -0,0 +1,286 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dir
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/pkg/traceutil"
+	cid "github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
+	unixfs "github.com/ipfs/go-unixfs"
+	multihash "github.com/multiformats/go-multihash"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+type transformer struct{}
+
+// New returns a transformer that converts a local directory or tar archive
+// into a UnixFS directory, preserving the file and directory structure.
+func New() p2plab.Transformer {
+	return &transformer{}
+}
+
+func (t *transformer) Close() error {
+	return nil
+}
+
+func (t *transformer) Transform(ctx context.Context, p p2plab.Peer, source string, opts ...p2plab.AddOption) (cid.Cid, error) {
+	c, _, err := t.TransformFiles(ctx, p, source, opts...)
+	return c, err
+}
+
+func (t *transformer) TransformFiles(ctx context.Context, p p2plab.Peer, source string, opts ...p2plab.AddOption) (cid.Cid, map[string]cid.Cid, error) {
+	span, ctx := traceutil.StartSpanFromContext(ctx, "transformer.Transform")
+	defer span.Finish()
+	span.SetTag("peer", p.Host().ID().String())
+	span.SetTag("source", source)
+
+	settings := p2plab.AddSettings{
+		HashFunc: "sha2-256",
+	}
+	for _, opt := range opts {
+		err := opt(&settings)
+		if err != nil {
+			return cid.Undef, nil, err
+		}
+	}
+
+	hashFuncCode, ok := multihash.Names[strings.ToLower(settings.HashFunc)]
+	if !ok {
+		return cid.Undef, nil, errors.Wrapf(errdefs.ErrInvalidArgument, "unrecognized hash function %q", settings.HashFunc)
+	}
+
+	b := &builder{
+		peer:     p,
+		opts:     opts,
+		excludes: NewExcludes(settings.Exclude),
+		root:     newEntry(),
+		files:    make(map[string]cid.Cid),
+	}
+
+	fi, err := os.Stat(source)
+	if err != nil {
+		return cid.Undef, nil, errors.Wrapf(err, "failed to stat %q", source)
+	}
+
+	if fi.IsDir() {
+		zerolog.Ctx(ctx).Info().Str("source", source).Msg("Adding directory tree")
+		err = b.addDir(ctx, source)
+	} else {
+		zerolog.Ctx(ctx).Info().Str("source", source).Msg("Adding tar archive")
+		err = b.addTar(ctx, source)
+	}
+	if err != nil {
+		return cid.Undef, nil, err
+	}
+
+	nd, err := b.buildDir(ctx, b.root, cid.V1Builder{MhType: hashFuncCode})
+	if err != nil {
+		return cid.Undef, nil, errors.Wrap(err, "failed to build unixfs directory")
+	}
+	zerolog.Ctx(ctx).Info().Str("cid", nd.Cid().String()).Int("files", len(b.files)).Msg("Transformed directory tree")
+
+	return nd.Cid(), b.files, nil
+}
+
+type entry struct {
+	node     ipld.Node
+	children map[string]*entry
+}
+
+func newEntry() *entry {
+	return &entry{children: make(map[string]*entry)}
+}
+
+type builder struct {
+	peer     p2plab.Peer
+	opts     []p2plab.AddOption
+	excludes *Excludes
+	root     *entry
+	files    map[string]cid.Cid
+}
+
+func (b *builder) addDir(ctx context.Context, root string) error {
+	return filepath.Walk(root, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+
+		if b.excludes.Match(rel, fi.IsDir()) {
+			if fi.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		switch {
+		case fi.IsDir():
+			b.mkdir(rel)
+		case fi.Mode().IsRegular():
+			f, err := os.Open(p)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			return b.addFile(ctx, rel, f)
+		}
+		return nil
+	})
+}
+
+func (b *builder) addTar(ctx context.Context, source string) error {
+	f, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(source, ".gz") || strings.HasSuffix(source, ".tgz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return errors.Wrapf(err, "failed to decompress %q", source)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to read tar archive %q", source)
+		}
+
+		rel := strings.Trim(path.Clean("/"+hdr.Name), "/")
+		if rel == "" || b.excluded(rel, hdr.Typeflag == tar.TypeDir) {
+			continue
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			b.mkdir(rel)
+		case tar.TypeReg, tar.TypeRegA:
+			err = b.addFile(ctx, rel, tr)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// excluded returns true if the path or any of its parent directories are
+// excluded. Unlike a directory walk, tar entries may appear in any order so
+// excluded directories cannot be skipped as a whole.
+func (b *builder) excluded(rel string, isDir bool) bool {
+	parts := strings.Split(rel, "/")
+	for i := 1; i < len(parts); i++ {
+		if b.excludes.Match(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	return b.excludes.Match(rel, isDir)
+}
+
+func (b *builder) mkdir(rel string) *entry {
+	e := b.root
+	for _, name := range strings.Split(rel, "/") {
+		child, ok := e.children[name]
+		if !ok {
+			child = newEntry()
+			e.children[name] = child
+		}
+		e = child
+	}
+	return e
+}
+
+func (b *builder) addFile(ctx context.Context, rel string, r io.Reader) error {
+	nd, err := b.peer.Add(ctx, r, b.opts...)
+	if err != nil {
+		return errors.Wrapf(err, "failed to add file %q", rel)
+	}
+
+	parent := b.root
+	dir, name := path.Split(rel)
+	if dir != "" {
+		parent = b.mkdir(strings.TrimSuffix(dir, "/"))
+	}
+	parent.children[name] = &entry{node: nd}
+
+	b.files[rel] = nd.Cid()
+	return nil
+}
+
+func (b *builder) buildDir(ctx context.Context, e *entry, builder cid.Builder) (ipld.Node, error) {
+	dir := unixfs.EmptyDirNode()
+	dir.SetCidBuilder(builder)
+
+	var names []string
+	for name := range e.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		child := e.children[name]
+
+		nd := child.node
+		if nd == nil {
+			var err error
+			nd, err = b.buildDir(ctx, child, builder)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		err := dir.AddNodeLink(name, nd)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := b.peer.DAGService().Add(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return dir, nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dir
+
+import (
+	"path"
+	"strings"
+)
+
+// Excludes matches slash-separated relative paths against gitignore-style
+// patterns. Patterns without a slash match the base name at any depth, a
+// leading slash anchors the pattern to the root, a trailing slash only matches
+// directories and a leading "!" re-includes a previously excluded path.
+type Excludes struct {
+	patterns []pattern
+}
+
+type pattern struct {
+	glob     string
+	anchored bool
+	dirOnly  bool
+	negate   bool
+}
+
+func NewExcludes(patterns []string) *Excludes {
+	var e Excludes
+	for _, p := range patterns {
+		p = strings.TrimSpace(p)
+		if p == "" || strings.HasPrefix(p, "#") {
+			continue
+		}
+
+		var pat pattern
+		if strings.HasPrefix(p, "!") {
+			pat.negate = true
+			p = p[1:]
+		}
+		if strings.HasSuffix(p, "/") {
+			pat.dirOnly = true
+			p = strings.TrimSuffix(p, "/")
+		}
+		p = strings.TrimPrefix(p, "**/")
+		if strings.Contains(p, "/") {
+			pat.anchored = true
+			p = strings.TrimPrefix(p, "/")
+		}
+		pat.glob = p
+
+		e.patterns = append(e.patterns, pat)
+	}
+	return &e
+}
+
+// Match returns true if the path is excluded. Later patterns take precedence
+// over earlier ones.
+func (e *Excludes) Match(rel string, isDir bool) bool {
+	excluded := false
+	for _, pat := range e.patterns {
+		if pat.dirOnly && !isDir {
+			continue
+		}
+
+		name := rel
+		if !pat.anchored {
+			name = path.Base(rel)
+		}
+
+		ok, err := path.Match(pat.glob, name)
+		if err != nil || !ok {
+			continue
+		}
+		excluded = !pat.negate
+	}
+	return excluded
+}
@@ -0,0 +1,49 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dir
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExcludes(t *testing.T) {
+	e := NewExcludes([]string{
+		"# comment",
+		"*.log",
+		"!keep.log",
+		"build/",
+		"/docs/*.md",
+	})
+
+	for _, tc := range []struct {
+		path     string
+		isDir    bool
+		excluded bool
+	}{
+		{"a.log", false, true},
+		{"sub/dir/b.log", false, true},
+		{"sub/keep.log", false, false},
+		{"build", true, true},
+		{"src/build", true, true},
+		{"build", false, false},
+		{"docs/README.md", false, true},
+		{"src/docs/README.md", false, false},
+		{"main.go", false, false},
+	} {
+		require.Equal(t, tc.excluded, e.Match(tc.path, tc.isDir), tc.path)
+	}
+}
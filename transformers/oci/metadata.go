@@ -30,9 +30,9 @@ var (
 	bucketKeySize      = []byte("size")
 )
 
-func (t *transformer) get(dgst digest.Digest) (desc ocispec.Descriptor, err error) {
+func (t *transformer) get(key string) (desc ocispec.Descriptor, err error) {
 	err = t.db.View(func(tx *bolt.Tx) error {
-		bkt := tx.Bucket([]byte(dgst.String()))
+		bkt := tx.Bucket([]byte(key))
 		if bkt == nil {
 			return errdefs.ErrNotFound
 		}
@@ -61,18 +61,18 @@ func (t *transformer) get(dgst digest.Digest) (desc ocispec.Descriptor, err erro
 	return desc, nil
 }
 
-func (t *transformer) put(dgst digest.Digest, desc ocispec.Descriptor) error {
+func (t *transformer) put(key string, desc ocispec.Descriptor) error {
 	return t.db.Update(func(tx *bolt.Tx) error {
-		bkt := tx.Bucket([]byte(dgst.String()))
+		bkt := tx.Bucket([]byte(key))
 		if bkt != nil {
-			err := tx.DeleteBucket([]byte(dgst.String()))
+			err := tx.DeleteBucket([]byte(key))
 			if err != nil {
 				return err
 			}
 		}
 
 		var err error
-		bkt, err = tx.CreateBucket([]byte(dgst.String()))
+		bkt, err = tx.CreateBucket([]byte(key))
 		if err != nil {
 			return err
 		}
@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"path/filepath"
@@ -86,6 +87,12 @@ func (t *transformer) Transform(ctx context.Context, p p2plab.Peer, source strin
 	span.SetTag("peer", p.Host().ID().String())
 	span.SetTag("source", source)
 
+	platform, err := platformFromOptions(opts...)
+	if err != nil {
+		return cid.Undef, err
+	}
+	span.SetTag("platform", platforms.Format(platform))
+
 	zerolog.Ctx(ctx).Info().Str("source", source).Msg("Resolving OCI reference")
 	name, desc, err := t.resolver.Resolve(ctx, source)
 	if err != nil {
@@ -93,7 +100,8 @@ func (t *transformer) Transform(ctx context.Context, p p2plab.Peer, source strin
 	}
 	zerolog.Ctx(ctx).Info().Str("source", source).Str("digest", desc.Digest.String()).Msg("Resolved reference to digest")
 
-	target, err := t.get(desc.Digest)
+	key := cacheKey(desc.Digest, platform)
+	target, err := t.get(key)
 	if err != nil && !errdefs.IsNotFound(err) {
 		return cid.Undef, errors.Wrapf(err, "failed to look for cached transform")
 	}
@@ -104,35 +112,67 @@ func (t *transformer) Transform(ctx context.Context, p p2plab.Peer, source strin
 			return cid.Undef, errors.Wrapf(err, "failed to create fetcher for %q", name)
 		}
 
-		zerolog.Ctx(ctx).Info().Str("digest", desc.Digest.String()).Msg("Converting manifest recursively to IPLD DAG")
+		zerolog.Ctx(ctx).Info().Str("digest", desc.Digest.String()).Str("platform", platforms.Format(platform)).Msg("Converting manifest recursively to IPLD DAG")
 		target, err = Convert(ctx, p, fetcher, t.store, desc, opts...)
 		if err != nil {
 			return cid.Undef, errors.Wrapf(err, "failed to convert %q", name)
 		}
 
-		err = t.put(desc.Digest, target)
+		err = t.put(key, target)
 		if err != nil {
 			return cid.Undef, errors.Wrapf(err, "failed to put cached transform")
 		}
 	}
 
 	zerolog.Ctx(ctx).Info().Str("target", target.Digest.String()).Msg("Constructing Unixfs directory over manifest blobs")
-	nd, err := ConstructDAGFromManifest(ctx, p, target, opts...)
+	nd, size, err := ConstructDAGFromManifest(ctx, p, target, opts...)
 	if err != nil {
 		return cid.Undef, err
 	}
+	zerolog.Ctx(ctx).Info().Str("cid", nd.Cid().String()).Int64("size", size).Msg("Transformed OCI image")
 
 	return nd.Cid(), nil
 }
 
+// platformFromOptions returns the platform pinned by the add options,
+// defaulting to linux/amd64.
+func platformFromOptions(opts ...p2plab.AddOption) (specs.Platform, error) {
+	var settings p2plab.AddSettings
+	for _, opt := range opts {
+		err := opt(&settings)
+		if err != nil {
+			return specs.Platform{}, err
+		}
+	}
+
+	if settings.Platform == "" {
+		return specs.Platform{
+			OS:           "linux",
+			Architecture: "amd64",
+		}, nil
+	}
+
+	platform, err := platforms.Parse(settings.Platform)
+	if err != nil {
+		return specs.Platform{}, errors.Wrapf(errdefs.ErrInvalidArgument, "invalid platform %q: %s", settings.Platform, err)
+	}
+	return platforms.Normalize(platform), nil
+}
+
+func cacheKey(dgst digest.Digest, platform specs.Platform) string {
+	return fmt.Sprintf("%s@%s", platforms.Format(platform), dgst)
+}
+
 func Convert(ctx context.Context, peer p2plab.Peer, fetcher remotes.Fetcher, store content.Store, desc ocispec.Descriptor, opts ...p2plab.AddOption) (target ocispec.Descriptor, err error) {
+	platform, err := platformFromOptions(opts...)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+
 	// Get all the children for a descriptor from a provider.
 	childrenHandler := images.ChildrenHandler(store)
 	// Filter manifests by platform.
-	childrenHandler = images.FilterPlatforms(childrenHandler, platforms.Only(specs.Platform{
-		OS:           "linux",
-		Architecture: "amd64",
-	}))
+	childrenHandler = images.FilterPlatforms(childrenHandler, platforms.Only(platform))
 	// Convert each child into a IPLD merkle tree.
 	childrenHandler = DispatchConvertHandler(childrenHandler, peer, fetcher, store, opts...)
 	// Build manifest from converted children.
@@ -184,7 +224,7 @@ func ConvertHandler(conversions map[digest.Digest]ocispec.Descriptor, peer p2pla
 		case images.MediaTypeDockerSchema2Manifest, ocispec.MediaTypeImageManifest,
 			images.MediaTypeDockerSchema2ManifestList, ocispec.MediaTypeImageIndex:
 
-			target, err = Convert(ctx, peer, fetcher, store, desc)
+			target, err = Convert(ctx, peer, fetcher, store, desc, opts...)
 
 		case images.MediaTypeDockerSchema2Layer, images.MediaTypeDockerSchema2LayerGzip,
 			images.MediaTypeDockerSchema2LayerForeign, images.MediaTypeDockerSchema2LayerForeignGzip,
@@ -292,24 +332,29 @@ func AddBlob(ctx context.Context, peer p2plab.Peer, r io.Reader, opts ...p2plab.
 	return dgst, nil
 }
 
-func ConstructDAGFromManifest(ctx context.Context, p p2plab.Peer, image ocispec.Descriptor, opts ...p2plab.AddOption) (ipld.Node, error) {
+// ConstructDAGFromManifest builds a Unixfs directory over the config and
+// layers of the manifest, returning the directory node and the total size of
+// the blobs beneath it.
+func ConstructDAGFromManifest(ctx context.Context, p p2plab.Peer, image ocispec.Descriptor, opts ...p2plab.AddOption) (ipld.Node, int64, error) {
 	settings := p2plab.AddSettings{
 		HashFunc: "sha2-256",
 	}
 	for _, opt := range opts {
 		err := opt(&settings)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 	}
 
+	platform, err := platformFromOptions(opts...)
+	if err != nil {
+		return nil, 0, err
+	}
+
 	provider := NewProvider(p)
-	manifest, err := images.Manifest(ctx, provider, image, platforms.Only(specs.Platform{
-		OS:           "linux",
-		Architecture: "amd64",
-	}))
+	manifest, err := images.Manifest(ctx, provider, image, platforms.Only(platform))
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	root := unixfs.EmptyDirNode()
@@ -321,27 +366,35 @@ func ConstructDAGFromManifest(ctx context.Context, p p2plab.Peer, image ocispec.
 	descs := []ocispec.Descriptor{manifest.Config}
 	descs = append(descs, manifest.Layers...)
 
+	var size int64
 	for _, desc := range descs {
+		size += desc.Size
+
 		c, err := digestconv.DigestToCid(desc.Digest)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 
 		nd, err := dserv.Get(ctx, c)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 
 		err = root.AddNodeLink(desc.Digest.String(), nd)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 	}
 
 	err = dserv.Add(ctx, root)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+
+	nd, err := e.Finalize(ctx, dserv)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	return e.Finalize(ctx, dserv)
+	return nd, size, nil
 }
@@ -0,0 +1,38 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oci
+
+import (
+	"testing"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/errdefs"
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlatformFromOptions(t *testing.T) {
+	platform, err := platformFromOptions()
+	require.NoError(t, err)
+	require.Equal(t, specs.Platform{OS: "linux", Architecture: "amd64"}, platform)
+
+	platform, err = platformFromOptions(p2plab.WithPlatform("linux/arm64/v8"))
+	require.NoError(t, err)
+	require.Equal(t, "linux", platform.OS)
+	require.Equal(t, "arm64", platform.Architecture)
+
+	_, err = platformFromOptions(p2plab.WithPlatform("linux/amd64/v1/extra"))
+	require.True(t, errdefs.IsInvalidArgument(err))
+}
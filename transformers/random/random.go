@@ -0,0 +1,74 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package random
+
+import (
+	"context"
+	"io"
+	"math/rand"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/pkg/traceutil"
+	cid "github.com/ipfs/go-cid"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+type transformer struct{}
+
+// New returns a transformer that generates deterministic pseudo-random
+// content of a given size and seed, ignoring the source.
+func New() p2plab.Transformer {
+	return &transformer{}
+}
+
+func (t *transformer) Close() error {
+	return nil
+}
+
+func (t *transformer) Transform(ctx context.Context, p p2plab.Peer, source string, opts ...p2plab.AddOption) (cid.Cid, error) {
+	span, ctx := traceutil.StartSpanFromContext(ctx, "transformer.Transform")
+	defer span.Finish()
+	span.SetTag("peer", p.Host().ID().String())
+
+	var settings p2plab.AddSettings
+	for _, opt := range opts {
+		err := opt(&settings)
+		if err != nil {
+			return cid.Undef, err
+		}
+	}
+
+	if settings.Size <= 0 {
+		return cid.Undef, errors.Wrap(errdefs.ErrInvalidArgument, "random object requires a positive size")
+	}
+	span.SetTag("size", settings.Size)
+	span.SetTag("seed", settings.Seed)
+
+	zerolog.Ctx(ctx).Info().Int64("size", settings.Size).Int64("seed", settings.Seed).Msg("Adding random content")
+	nd, err := p.Add(ctx, NewReader(settings.Size, settings.Seed), opts...)
+	if err != nil {
+		return cid.Undef, errors.Wrap(err, "failed to add random content")
+	}
+
+	return nd.Cid(), nil
+}
+
+// NewReader returns a reader of size pseudo-random bytes. The stream is
+// generated on demand and is identical for the same size and seed.
+func NewReader(size, seed int64) io.Reader {
+	return io.LimitReader(rand.New(rand.NewSource(seed)), size)
+}
@@ -0,0 +1,58 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package random
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewReader(t *testing.T) {
+	a, err := ioutil.ReadAll(NewReader(1<<20, 42))
+	require.NoError(t, err)
+	require.Len(t, a, 1<<20)
+
+	// Reading in differently sized chunks must not change the stream.
+	var b bytes.Buffer
+	r := NewReader(1<<20, 42)
+	buf := make([]byte, 1000)
+	for {
+		n, err := r.Read(buf)
+		b.Write(buf[:n])
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+	}
+	require.Equal(t, a, b.Bytes())
+
+	c, err := ioutil.ReadAll(NewReader(1<<20, 43))
+	require.NoError(t, err)
+	require.NotEqual(t, a, c)
+}
+
+func TestNewReaderStable(t *testing.T) {
+	// The generated content must not change between releases, otherwise
+	// benchmarks of the same object are no longer comparable.
+	p, err := ioutil.ReadAll(NewReader(1024, 42))
+	require.NoError(t, err)
+	require.Equal(t, "df9acbe20c2c9f0faeeaeacfd8ade4ce16980058e76529494b598a750a90b4e4", fmt.Sprintf("%x", sha256.Sum256(p)))
+}
@@ -17,13 +17,19 @@ package transformers
 import (
 	"net/http"
 	"path/filepath"
+	"strings"
 	"sync"
 
 	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/transformers/dir"
 	"github.com/Netflix/p2plab/transformers/oci"
+	"github.com/Netflix/p2plab/transformers/random"
 	"github.com/pkg/errors"
 )
 
+// ObjectTypes are the object types that have a transformer.
+var ObjectTypes = []string{"oci", "dir", "random"}
+
 type Transformers struct {
 	root   string
 	client *http.Client
@@ -70,7 +76,11 @@ func (t *Transformers) newTransformer(objectType string) (p2plab.Transformer, er
 	switch objectType {
 	case "oci":
 		return oci.New(root, t.client)
+	case "dir":
+		return dir.New(), nil
+	case "random":
+		return random.New(), nil
 	default:
-		return nil, errors.Errorf("unrecognized object type: %q", objectType)
+		return nil, errors.Errorf("unrecognized object type: %q, must be one of %s", objectType, strings.Join(ObjectTypes, ", "))
 	}
 }
@@ -14,6 +14,8 @@
 
 package version
 
+import "runtime"
+
 var (
 	// Package is filled at linking time
 	Package = "github.com/Netflix/p2plab"
@@ -25,3 +27,21 @@ var (
 	// the program at linking time.
 	Revision = ""
 )
+
+// Info describes the build of a binary.
+type Info struct {
+	Package   string `json:"package"`
+	Version   string `json:"version"`
+	Revision  string `json:"revision"`
+	GoVersion string `json:"goVersion"`
+}
+
+// Get returns the build info of the running binary.
+func Get() Info {
+	return Info{
+		Package:   Package,
+		Version:   Version,
+		Revision:  Revision,
+		GoVersion: runtime.Version(),
+	}
+}